@@ -43,6 +43,9 @@ type Config struct {
 	Name string `json: name`
 	// AutomanagedNamespaces is a number of automanaged namespaces.
 	AutomanagedNamespaces int32 `json: automanagedNamespaces`
+	// NamespaceTemplate optionally describes how automanaged namespaces are created,
+	// e.g. to emulate multi-tenant environments.
+	NamespaceTemplate *NamespaceTemplate `json: namespaceTemplate`
 	// Steps is a sequence of test steps executed in serial.
 	Steps []Step `json: steps`
 	// TuningSets is a collection of tuning sets that can be used by steps.
@@ -63,6 +66,61 @@ type Step struct {
 	// Name is an optional name for given step. If name is set,
 	// timer will be run for the step execution.
 	Name string `json: name`
+	// Marker is an optional name of a marker stamping the time the step starts.
+	// Measurements can use markers to evaluate time windows between them.
+	Marker string `json: marker`
+	// Reconfiguration is an optional change of cluster configuration objects
+	// (e.g. FlowSchemas, PriorityLevelConfigurations), executed before
+	// measurements and phases of the step.
+	Reconfiguration *Reconfiguration `json: reconfiguration`
+	// Upgrade is an optional cluster upgrade, executed in parallel with
+	// measurements and phases of the step.
+	Upgrade *Upgrade `json: upgrade`
+	// Cluster is an optional name of the cluster (see --clusters flag) the phases and
+	// measurements of the step are executed against. If empty, the tested cluster is used.
+	Cluster string `json: cluster`
+}
+
+// Upgrade defines how the cluster is upgraded.
+type Upgrade struct {
+	// Command is an external command performing the upgrade, e.g. ["./upgrade.sh", "--master"].
+	// If empty, the upgrader registered for the provider is used.
+	Command []string `json: command`
+	// Version is the version the cluster should be upgraded to. It's passed to the command
+	// in UPGRADE_VERSION environment variable.
+	Version string `json: version`
+	// Timeout is the maximum duration of the upgrade. If zero, the upgrade doesn't time out.
+	Timeout Duration `json: timeout`
+}
+
+// Reconfiguration defines cluster configuration objects that are applied
+// (created or replaced) mid-run and can be reverted later.
+type Reconfiguration struct {
+	// Name identifies the reconfiguration, so that it can be reverted by a later step.
+	Name string `json: name`
+	// ObjectTemplatePaths specifies paths to definitions of applied objects.
+	// Objects' names and namespaces are taken from the definitions.
+	ObjectTemplatePaths []string `json: objectTemplatePaths`
+	// TemplateFillMap specifies for each placeholder what value should it be replaced with.
+	TemplateFillMap map[string]interface{} `json: templateFillMap`
+	// Revert, if true, restores objects applied by the reconfiguration with given name
+	// to their previous state. Objects that didn't exist before are deleted.
+	// Reconfigurations that are not reverted explicitly are reverted at the end of the test.
+	Revert bool `json: revert`
+}
+
+// NamespaceTemplate defines metadata of automanaged namespaces and objects created in every one of them.
+type NamespaceTemplate struct {
+	// Labels are labels of namespaces, e.g. pod-security.kubernetes.io/enforce.
+	Labels map[string]string `json: labels`
+	// Annotations are annotations of namespaces.
+	Annotations map[string]string `json: annotations`
+	// ObjectTemplatePaths specifies paths to definitions of namespaced objects
+	// (e.g. ResourceQuota, LimitRange, NetworkPolicy) created in every namespace.
+	// Objects' names are taken from the definitions.
+	ObjectTemplatePaths []string `json: objectTemplatePaths`
+	// TemplateFillMap specifies for each placeholder what value should it be replaced with.
+	TemplateFillMap map[string]interface{} `json: templateFillMap`
 }
 
 // Phase is a structure that declaratively defines state of objects.
@@ -149,6 +207,9 @@ type Measurement struct {
 	Identifier string `json: identifier`
 	// Params is a map of {name: value} pairs which will be passed to the measurement method - allowing for injection of arbitrary parameters to it.
 	Params map[string]interface{} `json: params`
+	// Cluster is an optional name of the cluster the measurement is executed against.
+	// If empty, the cluster of the step is used.
+	Cluster string `json: cluster`
 }
 
 // QpsLoad defines a uniform load with a given QPS.
@@ -17,37 +17,44 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
-	ginkgoconfig "github.com/onsi/ginkgo/config"
-	ginkgoreporters "github.com/onsi/ginkgo/reporters"
-	ginkgotypes "github.com/onsi/ginkgo/types"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/perf-tests/clusterloader2/api"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/control"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
-	"k8s.io/perf-tests/clusterloader2/pkg/execservice"
 	"k8s.io/perf-tests/clusterloader2/pkg/flags"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	frameworkconfig "k8s.io/perf-tests/clusterloader2/pkg/framework/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/lint"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
 	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
+	"k8s.io/perf-tests/clusterloader2/pkg/publisher"
+	"k8s.io/perf-tests/clusterloader2/pkg/runner"
+	"k8s.io/perf-tests/clusterloader2/pkg/selfmetrics"
+	"k8s.io/perf-tests/clusterloader2/pkg/sink"
 	"k8s.io/perf-tests/clusterloader2/pkg/test"
-	"k8s.io/perf-tests/clusterloader2/pkg/util"
-
-	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common"
-	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/bundle"
-	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/probes"
-	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/slos"
+	"k8s.io/perf-tests/clusterloader2/pkg/virtualnodes"
 )
 
 const (
-	dashLine        = "--------------------------------------------------------------------------------"
-	nodesPerClients = 100
+	validateCommand         = "validate"
+	backfillCommand         = "backfill"
+	listMeasurementsCommand = "list-measurements"
 )
 
 var (
@@ -55,6 +62,23 @@ var (
 	testConfigPaths     []string
 	testOverridePaths   []string
 	testSuiteConfigPath string
+
+	backfillPrometheusURL string
+	backfillStart         string
+	backfillEnd           string
+	backfillMarkersPath   string
+
+	controlAPIAddress string
+
+	staleNamespacePolicy string
+	staleNamespaceTTL    string
+
+	clockSkewPolicy string
+	maxClockSkew    string
+
+	measurementGatherTimeout string
+
+	listMeasurementsFormat string
 )
 
 func initClusterFlags() {
@@ -67,33 +91,139 @@ func initClusterFlags() {
 	flags.StringSliceEnvVar(&clusterLoaderConfig.ClusterConfig.MasterInternalIPs, "master-internal-ip", "MASTER_INTERNAL_IP", nil /*defaultValue*/, "Cluster internal/private IP of the master vm, supports multiple values when separated by commas")
 	flags.StringEnvVar(&clusterLoaderConfig.ClusterConfig.KubemarkRootKubeConfigPath, "kubemark-root-kubeconfig", "KUBEMARK_ROOT_KUBECONFIG", "",
 		"Path the to kubemark root kubeconfig file, i.e. kubeconfig of the cluster where kubemark cluster is run. Ignored if provider != kubemark")
+	flags.IntEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.QPS, "client-qps", "CLIENT_QPS", frameworkconfig.QPS, "Queries per second limit of a single kubernetes client")
+	flags.IntEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.Burst, "client-burst", "CLIENT_BURST", frameworkconfig.Burst, "Burst of a single kubernetes client")
+	flags.IntEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.TotalQPS, "client-total-qps", "CLIENT_TOTAL_QPS", 0,
+		"Queries per second limit of all kubernetes clients of a single pool (e.g. clients creating objects) together, 0 disables the limit")
+	flags.IntEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.TotalBurst, "client-total-burst", "CLIENT_TOTAL_BURST", 0,
+		"Burst of all kubernetes clients of a single pool together, defaults to client-total-qps")
+	flags.StringEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.ContentType, "client-content-type", "CLIENT_CONTENT_TYPE", config.ClientContentTypeProtobuf,
+		"Wire format of built-in types used by kubernetes clients: protobuf or json. Dynamic clients always use json")
+	flags.BoolEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.DisableCompression, "client-disable-compression", "CLIENT_DISABLE_COMPRESSION", false,
+		"Whether to disable requesting gzip compressed responses by kubernetes clients")
+	flags.StringSliceEnvVar(&clusterLoaderConfig.ClusterConfig.Clusters, "clusters", "CLUSTERS", nil,
+		"Additional clusters, in name=kubeconfig format, that steps and measurements of tests can be targeted at, supports multiple values when separated by commas")
+	flags.BoolEnvVar(&clusterLoaderConfig.ClusterConfig.ClientConfig.DisableRetries, "disable-api-retries", "DISABLE_API_RETRIES", false,
+		"Whether to disable retrying of API calls failing with retryable errors, e.g. 429, timeouts or etcd leader changes")
 }
 
 func validateClusterFlags() *errors.ErrorList {
 	errList := errors.NewErrorList()
-	if clusterLoaderConfig.ClusterConfig.KubeConfigPath == "" {
+	// Without kubeconfig, in-cluster config is used when running in a pod.
+	if clusterLoaderConfig.ClusterConfig.KubeConfigPath == "" && os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
 		errList.Append(fmt.Errorf("no kubeconfig path specified"))
 	}
 	if clusterLoaderConfig.ClusterConfig.Provider == "kubemark" &&
-		clusterLoaderConfig.PrometheusConfig.EnableServer &&
+		(clusterLoaderConfig.PrometheusConfig.EnableServer || clusterLoaderConfig.PrometheusConfig.Endpoint != "") &&
 		clusterLoaderConfig.ClusterConfig.KubemarkRootKubeConfigPath == "" {
 		errList.Append(fmt.Errorf("no kubemark-root-kubeconfig path specified"))
 	}
+	if clusterLoaderConfig.VirtualNodesConfig.Enable &&
+		clusterLoaderConfig.VirtualNodesConfig.Count == 0 &&
+		clusterLoaderConfig.ClusterConfig.Nodes == 0 {
+		errList.Append(fmt.Errorf("number of virtual nodes not specified"))
+	}
+	switch staleNamespacePolicy {
+	case runner.StaleNamespacePolicyDelete, runner.StaleNamespacePolicyFail, runner.StaleNamespacePolicyIgnore:
+	default:
+		errList.Append(fmt.Errorf("unknown stale namespace policy %q, expected one of: %s, %s, %s",
+			staleNamespacePolicy, runner.StaleNamespacePolicyDelete, runner.StaleNamespacePolicyFail, runner.StaleNamespacePolicyIgnore))
+	}
+	if ttl, err := time.ParseDuration(staleNamespaceTTL); err != nil {
+		errList.Append(fmt.Errorf("incorrect stale namespace ttl: %v", err))
+	} else if ttl <= 0 {
+		errList.Append(fmt.Errorf("stale namespace ttl has to be positive, got %v", ttl))
+	}
+	switch clockSkewPolicy {
+	case runner.ClockSkewPolicyFail, runner.ClockSkewPolicyWarn, runner.ClockSkewPolicyIgnore:
+	default:
+		errList.Append(fmt.Errorf("unknown clock skew policy %q, expected one of: %s, %s, %s",
+			clockSkewPolicy, runner.ClockSkewPolicyFail, runner.ClockSkewPolicyWarn, runner.ClockSkewPolicyIgnore))
+	}
+	if _, err := time.ParseDuration(maxClockSkew); err != nil {
+		errList.Append(fmt.Errorf("incorrect max clock skew: %v", err))
+	}
+	if clusterLoaderConfig.ClusterConfig.ClientConfig.QPS <= 0 || clusterLoaderConfig.ClusterConfig.ClientConfig.Burst <= 0 {
+		errList.Append(fmt.Errorf("client qps and burst have to be positive"))
+	}
+	switch clusterLoaderConfig.ClusterConfig.ClientConfig.ContentType {
+	case config.ClientContentTypeProtobuf, config.ClientContentTypeJSON:
+	default:
+		errList.Append(fmt.Errorf("unknown client content type %q, expected one of: %s, %s",
+			clusterLoaderConfig.ClusterConfig.ClientConfig.ContentType, config.ClientContentTypeProtobuf, config.ClientContentTypeJSON))
+	}
+	if _, err := clusterLoaderConfig.ClusterConfig.GetClusterKubeConfigPaths(); err != nil {
+		errList.Append(err)
+	}
+	if selfMetricsConfig := clusterLoaderConfig.SelfMetricsConfig; selfMetricsConfig.Scrape {
+		if _, err := selfmetrics.Port(&selfMetricsConfig); err != nil {
+			errList.Append(fmt.Errorf("incorrect self-metrics address %q: %v", selfMetricsConfig.Address, err))
+		}
+		if net.ParseIP(selfMetricsConfig.IP) == nil {
+			errList.Append(fmt.Errorf("incorrect self-metrics ip %q", selfMetricsConfig.IP))
+		}
+	}
 	return errList
 }
 
 func initFlags() {
 	flags.StringVar(&clusterLoaderConfig.ReportDir, "report-dir", "", "Path to the directory where the reports should be saved. Default is empty, which cause reports being written to standard output.")
 	flags.BoolEnvVar(&clusterLoaderConfig.EnableExecService, "enable-exec-service", "ENABLE_EXEC_SERVICE", false, "Whether to enable exec service that allows executing arbitrary commands from a pod running in the cluster.")
+	flags.BoolEnvVar(&clusterLoaderConfig.EnableCapacityCheck, "enable-capacity-check", "ENABLE_CAPACITY_CHECK", false, "Whether to verify, before running the test, that pods created by the test fit into schedulable nodes of the cluster.")
+	flags.StringEnvVar(&clusterLoaderConfig.NamespaceConfig.NameTemplate, "namespace-name-template", "NAMESPACE_NAME_TEMPLATE", framework.DefaultNamespaceNameTemplate, "Go template of automanaged namespace names. {{.Prefix}} is replaced with automanaged namespace prefix unique for the test, {{.RandomSuffix}} with its random part and {{.Index}} with index of the namespace.")
+	flags.StringEnvVar(&clusterLoaderConfig.NamespaceConfig.Prefix, "namespace-prefix", "NAMESPACE_PREFIX", framework.DefaultNamespacePrefix, "Fixed part of automanaged namespace prefixes, followed by a random suffix unique for the test.")
+	flags.IntEnvVar(&clusterLoaderConfig.NamespaceConfig.IndexWidth, "namespace-index-width", "NAMESPACE_INDEX_WIDTH", 0, "Minimal width of indexes of automanaged namespaces, shorter indexes are padded with zeros.")
+	flags.IntEnvVar(&clusterLoaderConfig.NamespaceConfig.StartIndex, "namespace-start-index", "NAMESPACE_START_INDEX", 1, "Index of the first automanaged namespace.")
+	flags.BoolEnvVar(&clusterLoaderConfig.NamespaceConfig.KeepOnFailure, "keep-namespaces-on-failure", "KEEP_NAMESPACES_ON_FAILURE", false, "Whether to keep automanaged namespaces of failed tests for debugging.")
+	flags.IntEnvVar(&clusterLoaderConfig.NamespaceConfig.DeletionBatchSize, "namespace-deletion-batch-size", "NAMESPACE_DELETION_BATCH_SIZE", 0, "Number of automanaged namespaces deleted at once, the next batch is deleted once the previous one is gone. 0 means all namespaces are deleted at once.")
+	flags.IntEnvVar(&clusterLoaderConfig.NamespaceConfig.DeletionParallelism, "namespace-deletion-parallelism", "NAMESPACE_DELETION_PARALLELISM", framework.DefaultNamespaceDeletionParallelism, "Maximal number of concurrent namespace deletion requests.")
+	flags.StringEnvVar(&staleNamespacePolicy, "stale-namespace-policy", "STALE_NAMESPACE_POLICY", runner.StaleNamespacePolicyFail, "What to do with namespaces (e.g. probes, monitoring) left by previous, e.g. crashed, runs: delete them, fail before running tests or ignore them.")
+	flags.StringEnvVar(&staleNamespaceTTL, "stale-namespace-ttl", "STALE_NAMESPACE_TTL", runner.DefaultStaleNamespaceTTL.String(), "Minimal age of namespaces created by other runs to consider them stale. Younger namespaces may be in use by a concurrent run, so they are ignored. Has to be well above the duration of the longest test.")
+	flags.StringEnvVar(&clockSkewPolicy, "clock-skew-policy", "CLOCK_SKEW_POLICY", runner.ClockSkewPolicyWarn, "What to do if clock of apiserver or Prometheus is skewed from the local clock by more than max-clock-skew: fail before running tests, warn or ignore it.")
+	flags.StringEnvVar(&maxClockSkew, "max-clock-skew", "MAX_CLOCK_SKEW", "5s", "Maximal allowed skew between the local clock and clocks of apiserver and Prometheus.")
+	flags.StringEnvVar(&controlAPIAddress, "control-api-address", "CONTROL_API_ADDRESS", "", "Address (e.g. :8088) of the control API allowing to pause (POST /pause) and resume (POST /resume) load phases. The API is unauthenticated, so address without host is bound to localhost only. If empty, the load can be paused only with SIGUSR1 and resumed with SIGUSR2.")
+	flags.StringEnvVar(&clusterLoaderConfig.OperationJournalPath, "operation-journal", "OPERATION_JOURNAL", "", "Path to the file where every object operation performed by phases (kind, namespace, name, timestamp, latency, result) is appended as a line of JSON. If empty, operations are not recorded.")
+	flags.StringEnvVar(&clusterLoaderConfig.StateFile, "state-file", "STATE_FILE", "", "Path to the file where progress of the run (completed tests and steps, test namespaces and objects, started measurements) is persisted after every step, so that an interrupted run can be resumed. If empty, progress is not persisted.")
+	flags.BoolEnvVar(&clusterLoaderConfig.Resume, "resume", "RESUME", false, "Whether to resume the interrupted run from the progress persisted in the state file, skipping completed tests and steps.")
+	flags.IntEnvVar(&clusterLoaderConfig.EventsConfig.BufferSize, "events-buffer-size", "EVENTS_BUFFER_SIZE", 0, "Maximal number of the latest events collected during every test for measurements and failure dumps. 0 disables collecting events.")
+	flags.StringEnvVar(&clusterLoaderConfig.EventsConfig.Namespace, "events-namespace", "EVENTS_NAMESPACE", "", "Namespace events are collected from. If empty, events of all namespaces are collected.")
+	flags.BoolEnvVar(&clusterLoaderConfig.EventsConfig.DumpOnFailure, "dump-events-on-failure", "DUMP_EVENTS_ON_FAILURE", true, "Whether to write collected events to the reports of failed tests.")
+	flags.StringEnvVar(&measurementGatherTimeout, "measurement-gather-timeout", "MEASUREMENT_GATHER_TIMEOUT", "0s", "Maximal duration of gather calls of measurements that don't set callTimeout param. Measurements still running after the timeout fail, so that a single hung measurement doesn't stall the test. 0 means no limit.")
+	flags.BoolEnvVar(&clusterLoaderConfig.EnablePhaseFootprint, "enable-phase-footprint", "ENABLE_PHASE_FOOTPRINT", false, "Whether to attribute apiserver requests and etcd object growth to test phases. Requires Prometheus server.")
 	// TODO(https://github.com/kubernetes/perf-tests/issues/641): Remove testconfig and testoverrides flags when test suite is fully supported.
 	flags.StringArrayVar(&testConfigPaths, "testconfig", []string{}, "Paths to the test config files")
 	flags.StringArrayVar(&testOverridePaths, "testoverrides", []string{}, "Paths to the config overrides file. The latter overrides take precedence over changes in former files.")
 	flags.StringVar(&testSuiteConfigPath, "testsuite", "", "Path to the test suite config file")
 	initClusterFlags()
 	prometheus.InitFlags(&clusterLoaderConfig.PrometheusConfig)
+	virtualnodes.InitFlags(&clusterLoaderConfig.VirtualNodesConfig)
+	publisher.InitFlags(&clusterLoaderConfig.PublisherConfig)
+	sink.InitFlags(&clusterLoaderConfig.SummarySinkConfig)
+	notifier.InitFlags(&clusterLoaderConfig.NotifierConfig)
+	selfmetrics.InitFlags(&clusterLoaderConfig.SelfMetricsConfig)
+	credentials.InitFlags(&clusterLoaderConfig.CredentialSources)
+	initBackfillFlags()
+	initListMeasurementsFlags()
+}
+
+func initBackfillFlags() {
+	flags.StringVar(&backfillPrometheusURL, "backfill-prometheus-url", "", "URL of Prometheus (or a remote store implementing Prometheus HTTP API) with data of the test run, used by backfill command")
+	flags.StringVar(&backfillStart, "backfill-start", "", "Start of the test run in RFC3339 format, used by backfill command")
+	flags.StringVar(&backfillEnd, "backfill-end", "", "End of the test run in RFC3339 format, used by backfill command")
+	flags.StringVar(&backfillMarkersPath, "backfill-markers", "", "Path to the Markers summary of the test run, used by backfill command. Optional")
+}
+
+func initListMeasurementsFlags() {
+	flags.StringVar(&listMeasurementsFormat, "format", measurement.TextFormat, "Output format of list-measurements command: text, markdown or json")
 }
 
 func validateFlags() *errors.ErrorList {
+	errList := validateTestFlags()
+	errList.Concat(validateClusterFlags())
+	return errList
+}
+
+func validateTestFlags() *errors.ErrorList {
 	errList := errors.NewErrorList()
 	if len(testConfigPaths) == 0 && testSuiteConfigPath == "" {
 		errList.Append(fmt.Errorf("no test config path or test suite path specified"))
@@ -101,229 +231,238 @@ func validateFlags() *errors.ErrorList {
 	if len(testConfigPaths) > 0 && testSuiteConfigPath != "" {
 		errList.Append(fmt.Errorf("test config path and test suite path cannot be provided at the same time"))
 	}
-	errList.Concat(validateClusterFlags())
-	return errList
-}
-
-func completeConfig(m *framework.MultiClientSet) error {
-	if clusterLoaderConfig.ClusterConfig.Nodes == 0 {
-		nodes, err := util.GetSchedulableUntainedNodesNumber(m.GetClient())
-		if err != nil {
-			return fmt.Errorf("getting number of nodes error: %v", err)
-		}
-		clusterLoaderConfig.ClusterConfig.Nodes = nodes
-		logrus.Infof("ClusterConfig.Nodes set to %v", nodes)
-	}
-	if clusterLoaderConfig.ClusterConfig.MasterName == "" {
-		masterName, err := util.GetMasterName(m.GetClient())
-		if err == nil {
-			clusterLoaderConfig.ClusterConfig.MasterName = masterName
-			logrus.Infof("ClusterConfig.MasterName set to %v", masterName)
-		} else {
-			logrus.Errorf("Getting master name error: %v", err)
-		}
+	namespaceConfig := clusterLoaderConfig.NamespaceConfig
+	if _, err := framework.NewNamespaceNaming(namespaceConfig.NameTemplate, namespaceConfig.Prefix, namespaceConfig.IndexWidth, namespaceConfig.StartIndex); err != nil {
+		errList.Append(err)
 	}
-	if len(clusterLoaderConfig.ClusterConfig.MasterIPs) == 0 {
-		masterIPs, err := util.GetMasterIPs(m.GetClient(), corev1.NodeExternalIP)
-		if err == nil {
-			clusterLoaderConfig.ClusterConfig.MasterIPs = masterIPs
-			logrus.Infof("ClusterConfig.MasterIP set to %v", masterIPs)
-		} else {
-			logrus.Errorf("Getting master external ip error: %v", err)
-		}
+	if namespaceConfig.DeletionParallelism <= 0 {
+		errList.Append(fmt.Errorf("namespace deletion parallelism has to be positive"))
 	}
-	if len(clusterLoaderConfig.ClusterConfig.MasterInternalIPs) == 0 {
-		masterIPs, err := util.GetMasterIPs(m.GetClient(), corev1.NodeInternalIP)
-		if err == nil {
-			clusterLoaderConfig.ClusterConfig.MasterInternalIPs = masterIPs
-			logrus.Infof("ClusterConfig.MasterInternalIP set to %v", masterIPs)
-		} else {
-			logrus.Errorf("Getting master internal ip error: %v", err)
-		}
+	if clusterLoaderConfig.EventsConfig.BufferSize < 0 {
+		errList.Append(fmt.Errorf("events buffer size cannot be negative"))
 	}
-	return nil
-}
-
-func verifyCluster(c kubernetes.Interface) error {
-	numSchedulableNodes, err := util.GetSchedulableUntainedNodesNumber(c)
-	if err != nil {
-		return err
+	if timeout, err := time.ParseDuration(measurementGatherTimeout); err != nil || timeout < 0 {
+		errList.Append(fmt.Errorf("incorrect measurement gather timeout %q", measurementGatherTimeout))
 	}
-	if numSchedulableNodes == 0 {
-		return fmt.Errorf("no schedulable nodes in the cluster")
+	if clusterLoaderConfig.Resume && clusterLoaderConfig.StateFile == "" {
+		errList.Append(fmt.Errorf("no state file to resume from specified"))
 	}
-	return nil
-}
-
-func getClientsNumber(nodesNumber int) int {
-	return (nodesNumber + nodesPerClients - 1) / nodesPerClients
+	return errList
 }
 
-func createReportDir() error {
-	if clusterLoaderConfig.ReportDir != "" {
-		if _, err := os.Stat(clusterLoaderConfig.ReportDir); err != nil {
-			if !os.IsNotExist(err) {
-				return err
-			}
-			if err = os.MkdirAll(clusterLoaderConfig.ReportDir, 0755); err != nil {
-				return fmt.Errorf("report directory creation error: %v", err)
-			}
-		}
+func validateBackfillFlags() *errors.ErrorList {
+	errList := validateTestFlags()
+	if backfillPrometheusURL == "" {
+		errList.Append(fmt.Errorf("no backfill prometheus url specified"))
 	}
-	return nil
-}
-
-func printTestStart(name string) {
-	logrus.Infof(dashLine)
-	logrus.Infof("Running %v", name)
-	logrus.Infof(dashLine)
-}
-
-func printTestResult(name, status, errors string) {
-	logf := logrus.Infof
-	if errors != "" {
-		logf = logrus.Errorf
+	if _, err := time.Parse(time.RFC3339, backfillStart); err != nil {
+		errList.Append(fmt.Errorf("incorrect backfill start: %v", err))
 	}
-	logf(dashLine)
-	logf("Test Finished")
-	logf("  Test: %v", name)
-	logf("  Status: %v", status)
-	if errors != "" {
-		logf("  Errors: %v", errors)
+	if _, err := time.Parse(time.RFC3339, backfillEnd); err != nil {
+		errList.Append(fmt.Errorf("incorrect backfill end: %v", err))
 	}
-	logf(dashLine)
+	return errList
 }
 
 func main() {
+	var command string
+	if len(os.Args) > 1 && (os.Args[1] == validateCommand || os.Args[1] == backfillCommand || os.Args[1] == listMeasurementsCommand) {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 	initFlags()
 	if err := flags.Parse(); err != nil {
 		logrus.Fatalf("Flag parse failed: %v", err)
 	}
+	// Credentials are used by all commands, e.g. by summary sinks of backfill.
+	if err := credentials.Init(clusterLoaderConfig.CredentialSources); err != nil {
+		logrus.Fatalf("Credential sources error: %v", err)
+	}
+	switch command {
+	case validateCommand:
+		if errList := validateTestFlags(); !errList.IsEmpty() {
+			logrus.Fatalf("Parsing flags error: %v", errList.String())
+		}
+		if !validateTests() {
+			logrus.Fatalf("Test config validation failed")
+		}
+		return
+	case backfillCommand:
+		if errList := validateBackfillFlags(); !errList.IsEmpty() {
+			logrus.Fatalf("Parsing flags error: %v", errList.String())
+		}
+		if !backfillTests() {
+			logrus.Fatalf("Backfilling summaries failed")
+		}
+		return
+	case listMeasurementsCommand:
+		if err := listMeasurements(os.Stdout); err != nil {
+			logrus.Fatalf("Listing measurements error: %v", err)
+		}
+		return
+	}
 	if errList := validateFlags(); !errList.IsEmpty() {
 		logrus.Fatalf("Parsing flags error: %v", errList.String())
 	}
-
-	mclient, err := framework.NewMultiClientSet(clusterLoaderConfig.ClusterConfig.KubeConfigPath, 1)
-	if err != nil {
-		logrus.Fatalf("Client creation error: %v", err)
+	client.SetRetriesEnabled(!clusterLoaderConfig.ClusterConfig.ClientConfig.DisableRetries)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	controller := control.NewController()
+	controller.HandleSignals(stopCh)
+	if controlAPIAddress != "" {
+		controller.Serve(controlAPIAddress)
 	}
-
-	if err = completeConfig(mclient); err != nil {
-		logrus.Fatalf("Config completing error: %v", err)
-	}
-
-	logrus.Infof("Using config: %+v", clusterLoaderConfig)
-
-	if err = createReportDir(); err != nil {
-		logrus.Fatalf("Cannot create report directory: %v", err)
+	if clusterLoaderConfig.SelfMetricsConfig.Address != "" {
+		selfmetrics.Serve(clusterLoaderConfig.SelfMetricsConfig.Address)
 	}
 
-	if err = util.LogClusterNodes(mclient.GetClient()); err != nil {
-		logrus.Errorf("Nodes info logging error: %v", err)
+	// Flags are already validated.
+	ttl, _ := time.ParseDuration(staleNamespaceTTL)
+	skew, _ := time.ParseDuration(maxClockSkew)
+	clusterLoaderConfig.MeasurementGatherTimeout, _ = time.ParseDuration(measurementGatherTimeout)
+	// Pass overrides to prometheus controller
+	clusterLoaderConfig.TestScenario.OverridePaths = testOverridePaths
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnSignals(cancel)
+	result, err := runner.Run(ctx, &clusterLoaderConfig, runner.Options{
+		Scenarios:            getTestScenarios(),
+		StaleNamespacePolicy: staleNamespacePolicy,
+		StaleNamespaceTTL:    ttl,
+		ClockSkewPolicy:      clockSkewPolicy,
+		MaxClockSkew:         skew,
+		Controller:           controller,
+	})
+	if err != nil && result == nil {
+		logrus.Fatalf("Run error: %v", err)
 	}
-
-	if err = verifyCluster(mclient.GetClient()); err != nil {
-		logrus.Fatalf("Cluster verification error: %v", err)
+	if result.Failed > 0 {
+		category := test.GetCategory(result.Tests)
+		logrus.Errorf("%d tests have failed! Most severe failure: %s", result.Failed, category)
+		os.Exit(result.ExitCode())
 	}
-
-	f, err := framework.NewFramework(
-		&clusterLoaderConfig.ClusterConfig,
-		getClientsNumber(clusterLoaderConfig.ClusterConfig.Nodes),
-	)
 	if err != nil {
-		logrus.Fatalf("Framework creation error: %v", err)
+		logrus.Fatalf("Run error: %v", err)
 	}
+}
 
-	var prometheusController *prometheus.PrometheusController
-	var prometheusFramework *framework.Framework
-	if clusterLoaderConfig.PrometheusConfig.EnableServer {
-		// Pass overrides to prometheus controller
-		clusterLoaderConfig.TestScenario.OverridePaths = testOverridePaths
-		if prometheusController, err = prometheus.NewPrometheusController(&clusterLoaderConfig); err != nil {
-			logrus.Fatalf("Error while creating Prometheus Controller: %v", err)
-		}
-		prometheusFramework = prometheusController.GetFramework()
-		if err := prometheusController.SetUpPrometheusStack(); err != nil {
-			logrus.Fatalf("Error while setting up prometheus stack: %v", err)
+// cancelOnSignals cancels the run on the first SIGINT or SIGTERM, so that the running test
+// is cancelled, its partial results are gathered and the cluster is cleaned up.
+// The second signal terminates clusterloader immediately.
+func cancelOnSignals(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-signals
+		logrus.Warningf("Received %v, cancelling the run, send it again to exit without cleanup", s)
+		cancel()
+		s = <-signals
+		logrus.Fatalf("Received %v again, exiting without cleanup", s)
+	}()
+}
+
+// validateTests renders every test config with all templates it references, checks measurements
+// and their params and reports anti-patterns found by the linter. Access to the cluster is not
+// required, number of nodes is taken from the nodes flag.
+func validateTests() bool {
+	scenarios := getTestScenarios()
+
+	clientConfig := clusterLoaderConfig.ClusterConfig.ClientConfig
+	maxQPS := frameworkconfig.MaxQPS(runner.GetClientsNumber(clusterLoaderConfig.ClusterConfig.Nodes), clientConfig.QPS, clientConfig.TotalQPS)
+	valid := true
+	for i := range scenarios {
+		clusterLoaderConfig.TestScenario = scenarios[i]
+		testId := runner.GetTestID(scenarios[i])
+		if errList := test.Validate(&clusterLoaderConfig); !errList.IsEmpty() {
+			logrus.Errorf("%s: %v", testId, errList.String())
+			valid = false
+			continue
 		}
-	}
-	if clusterLoaderConfig.EnableExecService {
-		if err := execservice.SetUpExecService(f); err != nil {
-			logrus.Fatalf("Error while setting up exec service: %v", err)
+		mapping, errList := config.GetMapping(&clusterLoaderConfig)
+		if errList != nil {
+			logrus.Errorf("%s: %v", testId, errList.String())
+			valid = false
+			continue
 		}
-	}
-
-	suiteSummary := &ginkgotypes.SuiteSummary{
-		SuiteDescription:           "ClusterLoaderV2",
-		NumberOfSpecsThatWillBeRun: len(testConfigPaths),
-	}
-	junitReporter := ginkgoreporters.NewJUnitReporter(path.Join(clusterLoaderConfig.ReportDir, "junit.xml"))
-	junitReporter.SpecSuiteWillBegin(ginkgoconfig.GinkgoConfig, suiteSummary)
-	testsStart := time.Now()
-	if testSuiteConfigPath != "" {
-		testSuite, err := config.LoadTestSuite(testSuiteConfigPath)
+		templateProvider := config.NewTemplateProvider(filepath.Dir(scenarios[i].ConfigPath))
+		testConfig, err := templateProvider.TemplateToConfig(filepath.Base(scenarios[i].ConfigPath), mapping)
 		if err != nil {
-			logrus.Fatalf("Error while reading test suite: %v", err)
+			logrus.Errorf("%s: config reading error: %v", testId, err)
+			valid = false
+			continue
 		}
-		for i := range testSuite {
-			clusterLoaderConfig.TestScenario = testSuite[i]
-			runSingleTest(f, prometheusFramework, junitReporter, suiteSummary)
+		issues := lint.Lint(testConfig, maxQPS)
+		for _, issue := range issues {
+			logrus.Warningf("%s: %v", testId, issue)
 		}
-	} else {
-		for i := range testConfigPaths {
-			clusterLoaderConfig.TestScenario.ConfigPath = testConfigPaths[i]
-			clusterLoaderConfig.TestScenario.OverridePaths = testOverridePaths
-			runSingleTest(f, prometheusFramework, junitReporter, suiteSummary)
+		if len(issues) > 0 {
+			valid = false
+			continue
 		}
+		logrus.Infof("%s: no issues found", testId)
 	}
-	suiteSummary.RunTime = time.Since(testsStart)
-	junitReporter.SpecSuiteDidEnd(suiteSummary)
+	return valid
+}
 
-	if clusterLoaderConfig.PrometheusConfig.EnableServer && clusterLoaderConfig.PrometheusConfig.TearDownServer {
-		if err := prometheusController.TearDownPrometheusStack(); err != nil {
-			logrus.Errorf("Error while tearing down prometheus stack: %v", err)
-		}
+// backfillTests regenerates summaries of Prometheus-based measurements of every test
+// from data of a past test run, e.g. a restored Prometheus snapshot. Access to the cluster
+// is not required, number of nodes is taken from the nodes flag.
+func backfillTests() bool {
+	if err := runner.CreateReportDir(clusterLoaderConfig.ReportDir); err != nil {
+		logrus.Errorf("Cannot create report directory: %v", err)
+		return false
 	}
-	if clusterLoaderConfig.EnableExecService {
-		if err := execservice.TearDownExecService(f); err != nil {
-			logrus.Errorf("Error while tearing down exec service: %v", err)
+	var markers *measurement.Markers
+	if backfillMarkersPath != "" {
+		var err error
+		if markers, err = measurement.LoadMarkers(backfillMarkersPath); err != nil {
+			logrus.Errorf("Loading markers error: %v", err)
+			return false
 		}
 	}
-	if suiteSummary.NumberOfFailedSpecs > 0 {
-		logrus.Fatalf("%d tests have failed!", suiteSummary.NumberOfFailedSpecs)
+	// Flags are already validated.
+	startTime, _ := time.Parse(time.RFC3339, backfillStart)
+	endTime, _ := time.Parse(time.RFC3339, backfillEnd)
+	executor := measurementutil.NewURLQueryExecutor(backfillPrometheusURL, measurementutil.DefaultQueryRetryPolicy)
+
+	scenarios := getTestScenarios()
+	success := true
+	for i := range scenarios {
+		clusterLoaderConfig.TestScenario = scenarios[i]
+		testId := runner.GetTestID(scenarios[i])
+		if errList := test.Backfill(&clusterLoaderConfig, executor, startTime, endTime, markers); !errList.IsEmpty() {
+			logrus.Errorf("%s: %v", testId, errList.String())
+			success = false
+			continue
+		}
+		logrus.Infof("%s: summaries backfilled", testId)
 	}
+	return success
 }
 
-func runSingleTest(
-	f *framework.Framework,
-	prometheusFramework *framework.Framework,
-	junitReporter *ginkgoreporters.JUnitReporter,
-	suiteSummary *ginkgotypes.SuiteSummary,
-) {
-	testId := getTestId(clusterLoaderConfig.TestScenario)
-	testStart := time.Now()
-	specSummary := &ginkgotypes.SpecSummary{
-		ComponentTexts: []string{suiteSummary.SuiteDescription, testId},
-	}
-	printTestStart(testId)
-	if errList := test.RunTest(f, prometheusFramework, &clusterLoaderConfig); !errList.IsEmpty() {
-		suiteSummary.NumberOfFailedSpecs++
-		specSummary.State = ginkgotypes.SpecStateFailed
-		specSummary.Failure = ginkgotypes.SpecFailure{
-			Message: errList.String(),
-		}
-		printTestResult(testId, "Fail", errList.String())
-	} else {
-		specSummary.State = ginkgotypes.SpecStatePassed
-		printTestResult(testId, "Success", "")
+// listMeasurements writes all registered measurements with their descriptions, actions and params.
+func listMeasurements(w io.Writer) error {
+	infos, err := measurement.DescribeMethods()
+	if err != nil {
+		return err
 	}
-	specSummary.RunTime = time.Since(testStart)
-	junitReporter.SpecDidComplete(specSummary)
+	return measurement.WriteMethods(w, infos, listMeasurementsFormat)
 }
 
-func getTestId(ts api.TestScenario) string {
-	if ts.Identifier != "" {
-		return fmt.Sprintf("%s(%s)", ts.Identifier, ts.ConfigPath)
+// getTestScenarios returns scenarios of the test suite or, if it's not provided, of test config flags.
+func getTestScenarios() []api.TestScenario {
+	var scenarios []api.TestScenario
+	if testSuiteConfigPath != "" {
+		testSuite, err := config.LoadTestSuite(testSuiteConfigPath)
+		if err != nil {
+			logrus.Fatalf("Error while reading test suite: %v", err)
+		}
+		scenarios = testSuite
+	} else {
+		for i := range testConfigPaths {
+			scenarios = append(scenarios, api.TestScenario{ConfigPath: testConfigPaths[i], OverridePaths: testOverridePaths})
+		}
 	}
-	return ts.ConfigPath
+	return scenarios
 }
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command operator runs clusterloader tests described by ScaleTest custom resources
+// as Jobs in the cluster.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+	frameworkconfig "k8s.io/perf-tests/clusterloader2/pkg/framework/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/operator"
+)
+
+var (
+	kubeConfigPath string
+	namespace      string
+	image          string
+	workers        int
+)
+
+func initFlags() {
+	flags.StringEnvVar(&kubeConfigPath, "kubeconfig", "KUBECONFIG", "", "Path to the kubeconfig file. If empty, in-cluster config is used.")
+	flags.StringEnvVar(&namespace, "namespace", "NAMESPACE", "", "Namespace of ScaleTests to run. If empty, ScaleTests in all namespaces are run.")
+	flags.StringEnvVar(&image, "clusterloader-image", "CLUSTERLOADER_IMAGE", "", "Clusterloader image used by ScaleTests which don't specify one.")
+	flags.IntEnvVar(&workers, "workers", "WORKERS", 2, "Number of ScaleTests synced concurrently.")
+}
+
+func main() {
+	initFlags()
+	if err := flags.Parse(); err != nil {
+		logrus.Fatalf("Flag parse failed: %v", err)
+	}
+	if image == "" {
+		logrus.Fatalf("Parsing flags error: no clusterloader image specified")
+	}
+	config, err := frameworkconfig.PrepareConfig(kubeConfigPath)
+	if err != nil {
+		logrus.Fatalf("Client config error: %v", err)
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Client creation error: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Dynamic client creation error: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stopCh)
+	}()
+	if err := operator.NewController(client, dynamicClient, namespace, image).Run(workers, stopCh); err != nil {
+		logrus.Fatalf("Controller error: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodGroup represents a set of identical pods.
+type PodGroup struct {
+	Name string
+	// Count is the number of pods in the group or, if PerNode is set, the number of pods
+	// placed on every matching node.
+	Count int
+	// PerNode means that Count pods should be placed on every matching node, like for DaemonSets.
+	PerNode      bool
+	Requests     corev1.ResourceList
+	NodeSelector map[string]string
+	// Tolerations of pods of the group. Pods are placed only on nodes whose NoSchedule
+	// and NoExecute taints are tolerated.
+	Tolerations []corev1.Toleration
+}
+
+// Result represents the outcome of the capacity estimation.
+type Result struct {
+	// Unschedulable maps pod group name to the number of its pods that did not fit.
+	Unschedulable map[string]int
+}
+
+// Fits returns true if all pods have been placed.
+func (r *Result) Fits() bool {
+	return len(r.Unschedulable) == 0
+}
+
+// String returns string representation of unschedulable pods.
+func (r *Result) String() string {
+	names := make([]string, 0, len(r.Unschedulable))
+	for name := range r.Unschedulable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make([]string, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, fmt.Sprintf("%s: %d pods", name, r.Unschedulable[name]))
+	}
+	return fmt.Sprintf("unschedulable pods: %s", strings.Join(groups, ", "))
+}
+
+type nodeCapacity struct {
+	labels labels.Set
+	taints []corev1.Taint
+	cpu    int64
+	memory int64
+	pods   int64
+}
+
+// fit returns how many pods with given requests can be placed on the node.
+func (n *nodeCapacity) fit(cpu, memory int64) int64 {
+	fit := n.pods
+	if cpu > 0 && n.cpu/cpu < fit {
+		fit = n.cpu / cpu
+	}
+	if memory > 0 && n.memory/memory < fit {
+		fit = n.memory / memory
+	}
+	if fit < 0 {
+		return 0
+	}
+	return fit
+}
+
+// tolerates returns true if all NoSchedule and NoExecute taints of the node are tolerated.
+func (n *nodeCapacity) tolerates(tolerations []corev1.Toleration) bool {
+	for i := range n.taints {
+		taint := &n.taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *nodeCapacity) place(cpu, memory, count int64) {
+	n.cpu -= cpu * count
+	n.memory -= memory * count
+	n.pods -= count
+}
+
+// Estimate simulates scheduling of the pod groups on given nodes.
+// Resources requested by existing pods are subtracted from node allocatable.
+// Only resource requests (cpu, memory and pod count), node selectors and taints
+// tolerated by pods are taken into account. Per-node groups are placed first, then remaining groups are placed
+// in first-fit decreasing order, so the estimation is optimistic rather than exact.
+func Estimate(nodes []corev1.Node, existingPods []corev1.Pod, groups []PodGroup) *Result {
+	capacities := make([]*nodeCapacity, 0, len(nodes))
+	nodeIndex := make(map[string]*nodeCapacity)
+	sortedNodes := append([]corev1.Node(nil), nodes...)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].Name < sortedNodes[j].Name })
+	for i := range sortedNodes {
+		allocatable := sortedNodes[i].Status.Allocatable
+		capacity := &nodeCapacity{
+			labels: labels.Set(sortedNodes[i].Labels),
+			taints: sortedNodes[i].Spec.Taints,
+			cpu:    allocatable.Cpu().MilliValue(),
+			memory: allocatable.Memory().Value(),
+			pods:   allocatable.Pods().Value(),
+		}
+		capacities = append(capacities, capacity)
+		nodeIndex[sortedNodes[i].Name] = capacity
+	}
+	for i := range existingPods {
+		pod := &existingPods[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if capacity, ok := nodeIndex[pod.Spec.NodeName]; ok {
+			requests := GetPodRequests(&pod.Spec)
+			capacity.place(requests.Cpu().MilliValue(), requests.Memory().Value(), 1)
+		}
+	}
+
+	result := &Result{Unschedulable: make(map[string]int)}
+	sortedGroups := append([]PodGroup(nil), groups...)
+	sort.SliceStable(sortedGroups, func(i, j int) bool {
+		if sortedGroups[i].PerNode != sortedGroups[j].PerNode {
+			return sortedGroups[i].PerNode
+		}
+		cpuI, cpuJ := sortedGroups[i].Requests.Cpu().MilliValue(), sortedGroups[j].Requests.Cpu().MilliValue()
+		if cpuI != cpuJ {
+			return cpuI > cpuJ
+		}
+		return sortedGroups[i].Requests.Memory().Value() > sortedGroups[j].Requests.Memory().Value()
+	})
+	for i := range sortedGroups {
+		group := &sortedGroups[i]
+		cpu := group.Requests.Cpu().MilliValue()
+		memory := group.Requests.Memory().Value()
+		selector := labels.SelectorFromSet(labels.Set(group.NodeSelector))
+		remaining := int64(group.Count)
+		unschedulable := int64(0)
+		for _, capacity := range capacities {
+			if !selector.Matches(capacity.labels) || !capacity.tolerates(group.Tolerations) {
+				continue
+			}
+			if group.PerNode {
+				count := capacity.fit(cpu, memory)
+				if count > int64(group.Count) {
+					count = int64(group.Count)
+				}
+				capacity.place(cpu, memory, count)
+				unschedulable += int64(group.Count) - count
+				continue
+			}
+			if remaining == 0 {
+				break
+			}
+			count := capacity.fit(cpu, memory)
+			if count > remaining {
+				count = remaining
+			}
+			capacity.place(cpu, memory, count)
+			remaining -= count
+		}
+		if !group.PerNode {
+			unschedulable = remaining
+		}
+		if unschedulable > 0 {
+			result.Unschedulable[group.Name] += int(unschedulable)
+		}
+	}
+	return result
+}
+
+// GetPodRequests returns resources requested by the pod with given spec.
+// Init containers run sequentially, so only the largest of them is taken into account.
+func GetPodRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			if value, ok := requests[name]; ok {
+				value.Add(quantity)
+				requests[name] = value
+			} else {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	for _, container := range spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if value, ok := requests[name]; !ok || quantity.Cmp(value) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func createNode(name, cpu, memory string, pods int64, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   *resource.NewQuantity(pods, resource.DecimalSI),
+			},
+		},
+	}
+}
+
+func createRequests(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	nodes := []corev1.Node{
+		createNode("node-a", "1", "1Gi", 10, map[string]string{"pool": "a"}),
+		createNode("node-b", "1", "1Gi", 10, map[string]string{"pool": "b"}),
+	}
+	tainted := createNode("node-c", "1", "1Gi", 10, map[string]string{"pool": "c"})
+	tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "c", Effect: corev1.TaintEffectNoSchedule}}
+	nodes = append(nodes, tainted)
+	existing := []corev1.Pod{
+		{
+			Spec: corev1.PodSpec{
+				NodeName: "node-a",
+				Containers: []corev1.Container{
+					{Resources: corev1.ResourceRequirements{Requests: createRequests("500m", "0")}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		groups        []PodGroup
+		unschedulable map[string]int
+	}{
+		{
+			name:          "fits",
+			groups:        []PodGroup{{Name: "small", Count: 15, Requests: createRequests("100m", "10Mi")}},
+			unschedulable: map[string]int{},
+		},
+		{
+			name:          "pod count exceeded",
+			groups:        []PodGroup{{Name: "empty", Count: 21, Requests: corev1.ResourceList{}}},
+			unschedulable: map[string]int{"empty": 2},
+		},
+		{
+			name:          "cpu exceeded",
+			groups:        []PodGroup{{Name: "big", Count: 4, Requests: createRequests("400m", "0")}},
+			unschedulable: map[string]int{"big": 1},
+		},
+		{
+			name:          "node selector",
+			groups:        []PodGroup{{Name: "pool-b", Count: 3, Requests: createRequests("400m", "0"), NodeSelector: map[string]string{"pool": "b"}}},
+			unschedulable: map[string]int{"pool-b": 1},
+		},
+		{
+			name: "per node",
+			groups: []PodGroup{
+				{Name: "daemon", Count: 1, PerNode: true, Requests: createRequests("600m", "0")},
+				{Name: "other", Count: 1, Requests: createRequests("100m", "0")},
+			},
+			unschedulable: map[string]int{"daemon": 1},
+		},
+		{
+			name:          "per node count",
+			groups:        []PodGroup{{Name: "daemons", Count: 3, PerNode: true, Requests: createRequests("200m", "0")}},
+			unschedulable: map[string]int{"daemons": 1},
+		},
+		{
+			name:          "taint not tolerated",
+			groups:        []PodGroup{{Name: "untolerating", Count: 8, Requests: createRequests("300m", "0")}},
+			unschedulable: map[string]int{"untolerating": 4},
+		},
+		{
+			name: "taint tolerated",
+			groups: []PodGroup{{
+				Name:        "tolerating",
+				Count:       8,
+				Requests:    createRequests("300m", "0"),
+				Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			}},
+			unschedulable: map[string]int{"tolerating": 1},
+		},
+		{
+			name: "per node skips tainted node",
+			groups: []PodGroup{
+				{Name: "daemon", Count: 1, PerNode: true, Requests: createRequests("100m", "0")},
+			},
+			unschedulable: map[string]int{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Estimate(nodes, existing, tc.groups)
+			if len(result.Unschedulable) != len(tc.unschedulable) {
+				t.Fatalf("got unschedulable %v, want %v", result.Unschedulable, tc.unschedulable)
+			}
+			for name, count := range tc.unschedulable {
+				if result.Unschedulable[name] != count {
+					t.Errorf("group %s: got %d unschedulable pods, want %d", name, result.Unschedulable[name], count)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPodRequests(t *testing.T) {
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: createRequests("2", "10Mi")}},
+		},
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: createRequests("500m", "100Mi")}},
+			{Resources: corev1.ResourceRequirements{Requests: createRequests("250m", "100Mi")}},
+		},
+	}
+	requests := GetPodRequests(spec)
+	if cpu := requests.Cpu().MilliValue(); cpu != 2000 {
+		t.Errorf("got cpu %dm, want 2000m", cpu)
+	}
+	if memory := requests.Memory().Value(); memory != 200*1024*1024 {
+		t.Errorf("got memory %d, want %d", memory, 200*1024*1024)
+	}
+}
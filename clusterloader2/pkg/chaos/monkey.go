@@ -17,8 +17,11 @@ limitations under the License.
 package chaos
 
 import (
+	"sync"
+
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 )
 
 // Monkey simulates kubernetes component failures
@@ -26,24 +29,38 @@ type Monkey struct {
 	client     clientset.Interface
 	provider   string
 	nodeKiller *NodeKiller
+	annotator  *prometheus.GrafanaAnnotator
+	// running tracks simulations of failures, so that the Monkey can be waited for once stopped.
+	running sync.WaitGroup
 }
 
-// NewMonkey constructs a new Monkey object.
-func NewMonkey(client clientset.Interface, provider string) *Monkey {
-	return &Monkey{client: client, provider: provider}
+// NewMonkey constructs a new Monkey object. Annotator is used to annotate
+// simulated failures, it can be nil.
+func NewMonkey(client clientset.Interface, provider string, annotator *prometheus.GrafanaAnnotator) *Monkey {
+	return &Monkey{client: client, provider: provider, annotator: annotator}
 }
 
 // Init initializes Monkey with given config.
 // When stopCh is closed, the Monkey will stop simulating failures.
 func (m *Monkey) Init(config api.ChaosMonkeyConfig, stopCh <-chan struct{}) error {
 	if config.NodeFailure != nil {
-		nodeKiller, err := NewNodeKiller(*config.NodeFailure, m.client, m.provider)
+		nodeKiller, err := NewNodeKiller(*config.NodeFailure, m.client, m.provider, m.annotator)
 		if err != nil {
 			return err
 		}
 		m.nodeKiller = nodeKiller
-		go m.nodeKiller.Run(stopCh)
+		m.running.Add(1)
+		go func() {
+			defer m.running.Done()
+			m.nodeKiller.Run(stopCh)
+		}()
 	}
 
 	return nil
 }
+
+// Wait blocks until the Monkey stops simulating failures after stopCh passed to Init is closed,
+// i.e. until nodes failed by it are repaired.
+func (m *Monkey) Wait() {
+	m.running.Wait()
+}
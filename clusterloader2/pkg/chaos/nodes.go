@@ -24,6 +24,7 @@ import (
 
 	"k8s.io/perf-tests/clusterloader2/api"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
@@ -46,27 +47,33 @@ type NodeKiller struct {
 	provider string
 	// killedNodes stores names of the nodes that have been killed by NodeKiller.
 	killedNodes sets.String
+	annotator   *prometheus.GrafanaAnnotator
 }
 
 // NewNodeKiller creates new NodeKiller.
-func NewNodeKiller(config api.NodeFailureConfig, client clientset.Interface, provider string) (*NodeKiller, error) {
+func NewNodeKiller(config api.NodeFailureConfig, client clientset.Interface, provider string, annotator *prometheus.GrafanaAnnotator) (*NodeKiller, error) {
 	if provider != "gce" && provider != "gke" {
 		return nil, fmt.Errorf("provider %q is not supported by NodeKiller", provider)
 	}
-	return &NodeKiller{config, client, provider, sets.NewString()}, nil
+	return &NodeKiller{config, client, provider, sets.NewString(), annotator}, nil
 }
 
-// Run starts NodeKiller until stopCh is closed.
+// Run starts NodeKiller until stopCh is closed. Once stopCh is closed, simulated failures
+// in progress are cut short and Run returns after failed nodes are repaired.
 func (k *NodeKiller) Run(stopCh <-chan struct{}) {
 	// wait.JitterUntil starts work immediately, so wait first.
-	time.Sleep(wait.Jitter(time.Duration(k.config.Interval), k.config.JitterFactor))
+	select {
+	case <-stopCh:
+		return
+	case <-time.After(wait.Jitter(time.Duration(k.config.Interval), k.config.JitterFactor)):
+	}
 	wait.JitterUntil(func() {
 		nodes, err := k.pickNodes()
 		if err != nil {
 			logrus.Errorf("%s: Unable to pick nodes to kill: %v", k, err)
 			return
 		}
-		k.kill(nodes)
+		k.kill(nodes, stopCh)
 	}, time.Duration(k.config.Interval), k.config.JitterFactor, true, stopCh)
 }
 
@@ -105,7 +112,7 @@ func (k *NodeKiller) pickNodes() ([]v1.Node, error) {
 	return nodes, nil
 }
 
-func (k *NodeKiller) kill(nodes []v1.Node) {
+func (k *NodeKiller) kill(nodes []v1.Node, stopCh <-chan struct{}) {
 	wg := sync.WaitGroup{}
 	wg.Add(len(nodes))
 	for _, node := range nodes {
@@ -121,7 +128,13 @@ func (k *NodeKiller) kill(nodes []v1.Node) {
 				return
 			}
 
-			time.Sleep(time.Duration(k.config.SimulatedDowntime))
+			failureStart := time.Now()
+			select {
+			case <-stopCh:
+				logrus.Infof("%s: Stopped, cutting short simulated failure of %q", k, node.Name)
+			case <-time.After(time.Duration(k.config.SimulatedDowntime)):
+			}
+			k.annotator.AnnotateRange(failureStart, time.Now(), fmt.Sprintf("%s: simulated failure of %s", k, node.Name), "chaos")
 
 			logrus.Infof("%s: Rebooting %q to repair the node", k, node.Name)
 			err = util.SSH("sudo reboot", &node, nil)
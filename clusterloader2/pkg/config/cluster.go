@@ -17,16 +17,40 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"k8s.io/perf-tests/clusterloader2/api"
 )
 
 // ClusterLoaderConfig represents all single test run parameters used by CLusterLoader.
 type ClusterLoaderConfig struct {
-	ClusterConfig     ClusterConfig
-	ReportDir         string
-	EnableExecService bool
-	TestScenario      api.TestScenario
-	PrometheusConfig  PrometheusConfig
+	ClusterConfig        ClusterConfig
+	ReportDir            string
+	EnableExecService    bool
+	EnableCapacityCheck  bool
+	EnablePhaseFootprint bool
+	TestScenario         api.TestScenario
+	PrometheusConfig     PrometheusConfig
+	VirtualNodesConfig   VirtualNodesConfig
+	PublisherConfig      PublisherConfig
+	SummarySinkConfig    SummarySinkConfig
+	NotifierConfig       NotifierConfig
+	SelfMetricsConfig    SelfMetricsConfig
+	NamespaceConfig      NamespaceConfig
+	// CredentialSources are URLs of sources of credentials, see credentials.Init.
+	CredentialSources []string
+	// OperationJournalPath is a path to the file where object operations are recorded. Empty disables journal.
+	OperationJournalPath string
+	// StateFile is a path to the file where progress of the run is persisted. Empty disables persisting.
+	StateFile string
+	// Resume makes the run resume tests from the progress persisted in the state file.
+	Resume       bool
+	EventsConfig EventsConfig
+	// MeasurementGatherTimeout limits duration of gather calls of measurements that don't set timeout param.
+	// Zero means no limit.
+	MeasurementGatherTimeout time.Duration
 }
 
 // ClusterConfig is a structure that represents cluster description.
@@ -38,16 +62,239 @@ type ClusterConfig struct {
 	MasterInternalIPs          []string
 	MasterName                 string
 	KubemarkRootKubeConfigPath string
+	ClientConfig               ClientConfig
+	// Clusters are additional clusters, in name=kubeconfig format, that steps and measurements
+	// of tests can be targeted at by name.
+	Clusters []string
+	// RunID identifies the run, namespaces created by the run are labeled with it. It's set by the runner.
+	RunID string
+}
+
+// KubemarkRootClusterName is the name under which the root cluster of kubemark is available to tests.
+const KubemarkRootClusterName = "kubemark-root"
+
+// GetClusterKubeConfigPaths returns kubeconfig paths of additional clusters by their names.
+func (c *ClusterConfig) GetClusterKubeConfigPaths() (map[string]string, error) {
+	paths := make(map[string]string, len(c.Clusters))
+	for _, cluster := range c.Clusters {
+		kv := strings.SplitN(cluster, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("incorrect cluster %q, expected name=kubeconfig", cluster)
+		}
+		if kv[0] == KubemarkRootClusterName {
+			return nil, fmt.Errorf("cluster name %q is reserved for the kubemark root cluster", kv[0])
+		}
+		if _, exists := paths[kv[0]]; exists {
+			return nil, fmt.Errorf("duplicated cluster %q", kv[0])
+		}
+		paths[kv[0]] = kv[1]
+	}
+	return paths, nil
+}
+
+// ClientConfig represents all flags used to configure rate limiting of kubernetes clients.
+type ClientConfig struct {
+	// QPS and Burst limit every single client. Non-positive values mean the defaults of the framework.
+	QPS   int
+	Burst int
+	// TotalQPS and TotalBurst limit all clients of a single pool (e.g. all dynamic clients used for
+	// creating objects) together. Non-positive TotalQPS disables the limit.
+	TotalQPS   int
+	TotalBurst int
+	// ContentType is the wire format of built-in types, ClientContentTypeProtobuf (default) or ClientContentTypeJSON.
+	// Dynamic clients always use JSON.
+	ContentType string
+	// DisableCompression disables requesting gzip compressed responses.
+	DisableCompression bool
+	// DisableRetries disables retrying of API calls failing with retryable errors (e.g. 429 or timeouts).
+	DisableRetries bool
+}
+
+// Wire formats of clients.
+const (
+	ClientContentTypeProtobuf = "protobuf"
+	ClientContentTypeJSON     = "json"
+)
+
+// NamespaceConfig represents all flags used by automanaged namespaces naming.
+type NamespaceConfig struct {
+	// NameTemplate is a go template of namespace names, see framework.NewNamespaceNaming.
+	NameTemplate string
+	// Prefix is the fixed part of automanaged namespace prefixes, followed by a random suffix.
+	Prefix     string
+	IndexWidth int
+	StartIndex int
+	// KeepOnFailure keeps automanaged namespaces of failed tests for debugging.
+	KeepOnFailure bool
+	// DeletionBatchSize is the number of automanaged namespaces deleted at once, the next batch is deleted
+	// once the previous one is gone. Non-positive value means all namespaces are deleted at once.
+	DeletionBatchSize int
+	// DeletionParallelism is the maximal number of concurrent namespace deletion requests.
+	DeletionParallelism int
+}
+
+// EventsConfig represents all flags used by the events collector of tests.
+type EventsConfig struct {
+	// BufferSize is the maximal number of the latest events kept during every test. 0 disables collecting events.
+	BufferSize int
+	// Namespace is the namespace events are collected from, empty for all namespaces.
+	Namespace string
+	// DumpOnFailure enables writing collected events to the reports of failed tests.
+	DumpOnFailure bool
 }
 
 // PrometheusConfig represents all flags used by prometheus.
 type PrometheusConfig struct {
-	EnableServer       bool
-	TearDownServer     bool
-	ScrapeEtcd         bool
-	ScrapeNodeExporter bool
-	ScrapeKubelets     bool
-	ScrapeKubeProxy    bool
+	EnableServer            bool
+	TearDownServer          bool
+	ScrapeEtcd              bool
+	ScrapeNodeExporter      bool
+	ScrapeKubelets          bool
+	ScrapeKubeProxy         bool
+	ScrapeCNI               string
+	ScrapeIngressController string
+	// EnableGrafana enables deploying grafana with pre-built dashboards.
+	EnableGrafana bool
+	// EnableGrafanaAnnotations enables pushing annotations of test events to grafana.
+	EnableGrafanaAnnotations bool
+	// ThanosObjstoreConfig is a path to Thanos object storage config. If set, prometheus is deployed
+	// with Thanos sidecar archiving its data to the configured bucket.
+	ThanosObjstoreConfig string
+	// EnableKubeStateMetrics enables deploying kube-state-metrics exporting object-state metrics
+	// (e.g. pod phases) together with the prometheus server.
+	EnableKubeStateMetrics bool
+	// KubeStateMetricsShards is the number of kube-state-metrics shards. If not positive,
+	// it's computed based on the number of nodes.
+	KubeStateMetricsShards int
+	// AdditionalManifests is a list of globs of manifests (e.g. ServiceMonitors, PrometheusRules) applied
+	// together with the prometheus stack.
+	AdditionalManifests []string
+	// ManifestsDir is a directory with custom manifests of the prometheus stack. If empty, manifests
+	// embedded in the binary are used.
+	ManifestsDir string
+	// ProbesManifestsDir is a directory with custom manifests of probes. If empty, manifests
+	// embedded in the binary are used.
+	ProbesManifestsDir string
+	// Endpoint is the URL of an external, already running Prometheus server. If set, the prometheus
+	// stack isn't deployed and all queries are sent to this server.
+	Endpoint string
+	// Auth describes how to authenticate to the external Prometheus server.
+	Auth PrometheusAuthConfig
+	// Sizing overrides resources of the prometheus server computed based on the cluster scale.
+	Sizing PrometheusSizingConfig
+}
+
+// PrometheusSizingConfig represents all flags used to override prometheus server resources.
+// Empty values are computed based on the number of nodes and enabled scrape targets.
+type PrometheusSizingConfig struct {
+	CPURequest    string
+	MemoryRequest string
+	StorageSize   string
+	Retention     string
+}
+
+// PrometheusAuthConfig represents all flags used to authenticate to the external Prometheus server.
+type PrometheusAuthConfig struct {
+	BearerTokenFile string
+	Username        string
+	PasswordFile    string
+	// CertFile and KeyFile are client certificate and key used for mTLS.
+	CertFile           string
+	KeyFile            string
+	CAFile             string
+	InsecureSkipVerify bool
+}
+
+// VirtualNodesConfig represents all flags used by simulated (virtual-kubelet based) nodes.
+type VirtualNodesConfig struct {
+	Enable   bool
+	TearDown bool
+	Count    int
+	Image    string
+	CPU      string
+	Memory   string
+	MaxPods  int
+}
+
+// PublisherConfig represents all flags used by results publisher.
+type PublisherConfig struct {
+	Endpoint   string
+	AuthHeader string
+	Labels     []string
+	// PushgatewayURL is the URL of Prometheus Pushgateway that results are pushed to as metrics.
+	PushgatewayURL string
+	// BigqueryTable is the BigQuery table (project.dataset.table) that flattened results are inserted into.
+	BigqueryTable string
+	// SQLDriver, SQLDataSource and SQLTable describe SQL database that flattened results are inserted into.
+	SQLDriver     string
+	SQLDataSource string
+	SQLTable      string
+}
+
+// SummarySinkConfig represents all flags used by summary sinks.
+type SummarySinkConfig struct {
+	URLs       []string
+	AuthHeader string
+}
+
+// String returns the config with the auth header hidden, so that it can be safely logged.
+func (s SummarySinkConfig) String() string {
+	authHeader := ""
+	if s.AuthHeader != "" {
+		authHeader = "<hidden>"
+	}
+	return fmt.Sprintf("{URLs:%v AuthHeader:%s}", s.URLs, authHeader)
+}
+
+// SelfMetricsConfig represents all flags used to expose metrics of clusterloader itself.
+type SelfMetricsConfig struct {
+	// Address is the address self-metrics are served on, empty if they are not served.
+	Address string
+	// Scrape makes the prometheus server set up in the cluster scrape self-metrics.
+	Scrape bool
+	// IP is the IP of the machine running clusterloader, reachable from the cluster.
+	IP string
+}
+
+// NotifierConfig represents all flags used by notifiers.
+type NotifierConfig struct {
+	// SlackWebhookURL is the URL of Slack incoming webhook notifications are posted to.
+	SlackWebhookURL string
+	// WebhookURL is the URL notifications are posted to as JSON.
+	WebhookURL string
+	// ArtifactsURL is the URL the report directory is available at, used to link artifacts in notifications.
+	ArtifactsURL string
+	// RunName identifies the run in notifications, e.g. name of the job.
+	RunName string
+	// NotifyOnSuccess makes notifiers post the summary of the run even if all tests passed.
+	NotifyOnSuccess bool
+}
+
+// String returns the config with the webhook URLs hidden, as they contain secrets.
+func (n NotifierConfig) String() string {
+	slackWebhookURL, webhookURL := "", ""
+	if n.SlackWebhookURL != "" {
+		slackWebhookURL = "<hidden>"
+	}
+	if n.WebhookURL != "" {
+		webhookURL = "<hidden>"
+	}
+	return fmt.Sprintf("{SlackWebhookURL:%s WebhookURL:%s ArtifactsURL:%s RunName:%s NotifyOnSuccess:%t}",
+		slackWebhookURL, webhookURL, n.ArtifactsURL, n.RunName, n.NotifyOnSuccess)
+}
+
+// String returns the config with the auth header hidden, so that it can be safely logged.
+func (p PublisherConfig) String() string {
+	authHeader := ""
+	if p.AuthHeader != "" {
+		authHeader = "<hidden>"
+	}
+	sqlDataSource := ""
+	if p.SQLDataSource != "" {
+		sqlDataSource = "<hidden>"
+	}
+	return fmt.Sprintf("{Endpoint:%s AuthHeader:%s Labels:%v PushgatewayURL:%s BigqueryTable:%s SQLDriver:%s SQLDataSource:%s SQLTable:%s}",
+		p.Endpoint, authHeader, p.Labels, p.PushgatewayURL, p.BigqueryTable, p.SQLDriver, sqlDataSource, p.SQLTable)
 }
 
 // GetMasterIp returns the first master ip, added for backward compatibility.
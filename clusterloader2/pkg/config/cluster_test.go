@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetClusterKubeConfigPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		clusters []string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "no clusters",
+			want: map[string]string{},
+		},
+		{
+			name:     "multiple clusters",
+			clusters: []string{"member-1=/tmp/member-1", "member-2=/tmp/a=b"},
+			want:     map[string]string{"member-1": "/tmp/member-1", "member-2": "/tmp/a=b"},
+		},
+		{
+			name:     "missing kubeconfig",
+			clusters: []string{"member-1"},
+			wantErr:  true,
+		},
+		{
+			name:     "duplicated cluster",
+			clusters: []string{"member-1=/tmp/a", "member-1=/tmp/b"},
+			wantErr:  true,
+		},
+		{
+			name:     "reserved name",
+			clusters: []string{KubemarkRootClusterName + "=/tmp/root"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ClusterConfig{Clusters: tt.clusters}
+			got, err := c.GetClusterKubeConfigPaths()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetClusterKubeConfigPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetClusterKubeConfigPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
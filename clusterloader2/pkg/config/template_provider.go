@@ -20,7 +20,9 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -36,6 +38,8 @@ import (
 // are served by reading file from given path or by using cache if available.
 type TemplateProvider struct {
 	basepath string
+	// fsys is the filesystem templates are read from, the local filesystem if nil.
+	fsys fs.FS
 
 	binLock  sync.RWMutex
 	binCache map[string][]byte
@@ -53,6 +57,21 @@ func NewTemplateProvider(basepath string) *TemplateProvider {
 	}
 }
 
+// NewTemplateProviderFS creates new template provider reading templates from the directory of the filesystem,
+// e.g. manifests embedded in the binary.
+func NewTemplateProviderFS(fsys fs.FS, basepath string) *TemplateProvider {
+	tp := NewTemplateProvider(basepath)
+	tp.fsys = fsys
+	return tp
+}
+
+func (tp *TemplateProvider) readFile(name string) ([]byte, error) {
+	if tp.fsys != nil {
+		return fs.ReadFile(tp.fsys, path.Join(tp.basepath, name))
+	}
+	return ioutil.ReadFile(filepath.Join(tp.basepath, name))
+}
+
 func (tp *TemplateProvider) getRaw(path string) ([]byte, error) {
 	tp.binLock.RLock()
 	bin, exists := tp.binCache[path]
@@ -64,7 +83,7 @@ func (tp *TemplateProvider) getRaw(path string) ([]byte, error) {
 		bin, exists = tp.binCache[path]
 		if !exists {
 			var err error
-			bin, err = ioutil.ReadFile(filepath.Join(tp.basepath, path))
+			bin, err = tp.readFile(path)
 			if err != nil {
 				return []byte{}, fmt.Errorf("reading error: %v", err)
 			}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package control allows operators to control a running test, e.g. to pause the load
+// while investigating an anomaly.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PauseSignal pauses the load.
+	PauseSignal = syscall.SIGUSR1
+	// ResumeSignal resumes the load.
+	ResumeSignal = syscall.SIGUSR2
+)
+
+// Controller pauses and resumes issuing of new operations of load phases.
+// Operations already in flight are not interrupted and the test state is kept,
+// so that the load is held steady until it is resumed.
+type Controller struct {
+	lock     sync.Mutex
+	resumed  *sync.Cond
+	paused   bool
+	pausedAt time.Time
+}
+
+// Status describes the state of the controller.
+type Status struct {
+	Paused   bool      `json:"paused"`
+	PausedAt time.Time `json:"pausedAt,omitempty"`
+}
+
+// NewController creates controller that doesn't pause the load.
+func NewController() *Controller {
+	c := &Controller{}
+	c.resumed = sync.NewCond(&c.lock)
+	return c
+}
+
+// Pause makes callers of WaitIfPaused block until the load is resumed.
+func (c *Controller) Pause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.paused {
+		return
+	}
+	c.paused = true
+	c.pausedAt = time.Now()
+	logrus.Infof("Load paused, no new operations will be issued until it is resumed")
+}
+
+// Resume unblocks callers of WaitIfPaused.
+func (c *Controller) Resume() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	logrus.Infof("Load resumed after %v", time.Since(c.pausedAt))
+	c.pausedAt = time.Time{}
+	c.resumed.Broadcast()
+}
+
+// GetStatus returns the current state of the controller.
+func (c *Controller) GetStatus() Status {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return Status{Paused: c.paused, PausedAt: c.pausedAt}
+}
+
+// WaitIfPaused blocks while the load is paused. It returns ctx error if ctx is done,
+// so that operations of a cancelled test aren't issued after the load is resumed.
+func (c *Controller) WaitIfPaused(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.paused {
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		go func() {
+			select {
+			case <-ctx.Done():
+				c.lock.Lock()
+				c.resumed.Broadcast()
+				c.lock.Unlock()
+			case <-stopCh:
+			}
+		}()
+	}
+	for c.paused && ctx.Err() == nil {
+		c.resumed.Wait()
+	}
+	return ctx.Err()
+}
+
+// HandleSignals pauses the load on PauseSignal and resumes it on ResumeSignal, until stopCh is closed.
+func (c *Controller) HandleSignals(stopCh <-chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, PauseSignal, ResumeSignal)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case s := <-signals:
+				if s == PauseSignal {
+					c.Pause()
+				} else {
+					c.Resume()
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Handler returns control API handler. POST /pause pauses the load, POST /resume resumes it
+// and GET /status returns the state of the controller. All of them respond with the status.
+func (c *Controller) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pause", c.handle(http.MethodPost, c.Pause))
+	mux.HandleFunc("/resume", c.handle(http.MethodPost, c.Resume))
+	mux.HandleFunc("/status", c.handle(http.MethodGet, func() {}))
+	return mux
+}
+
+func (c *Controller) handle(method string, action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		action()
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.GetStatus()); err != nil {
+			logrus.Errorf("Writing control API response error: %v", err)
+		}
+	}
+}
+
+// Serve serves control API on the given address in the background. The API is unauthenticated,
+// so address without host (e.g. :8088) is bound to localhost only.
+func (c *Controller) Serve(address string) {
+	address = ListenAddress(address)
+	go func() {
+		logrus.Infof("Serving control API on %s", address)
+		if err := http.ListenAndServe(address, c.Handler()); err != nil {
+			logrus.Errorf("Control API server error: %v", err)
+		}
+	}()
+}
+
+// ListenAddress returns address with localhost as host if it has no host, e.g. ":8088".
+// Addresses with explicit host (e.g. "0.0.0.0:8088") are returned unchanged.
+func ListenAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
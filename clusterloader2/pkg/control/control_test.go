@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseResume(t *testing.T) {
+	c := NewController()
+	assert.NoError(t, c.WaitIfPaused(context.Background()))
+
+	c.Pause()
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, c.WaitIfPaused(context.Background()))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("operation issued while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.True(t, c.GetStatus().Paused)
+
+	c.Resume()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("operation not issued after resume")
+	}
+	assert.Equal(t, Status{}, c.GetStatus())
+}
+
+func TestWaitIfPausedCancelled(t *testing.T) {
+	c := NewController()
+	c.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		errCh <- c.WaitIfPaused(ctx)
+	}()
+	cancel()
+	select {
+	case err := <-errCh:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatalf("wait not cancelled")
+	}
+	assert.True(t, c.GetStatus().Paused)
+}
+
+func TestHandler(t *testing.T) {
+	c := NewController()
+	server := httptest.NewServer(c.Handler())
+	defer server.Close()
+
+	post := func(path string) Status {
+		response, err := http.Post(server.URL+path, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer response.Body.Close()
+		var status Status
+		if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return status
+	}
+	assert.True(t, post("/pause").Paused)
+	assert.True(t, c.GetStatus().Paused)
+	assert.False(t, post("/resume").Paused)
+
+	response, err := http.Get(server.URL + "/pause")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	assert.False(t, c.GetStatus().Paused)
+}
+
+func TestListenAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8088", ListenAddress(":8088"))
+	assert.Equal(t, "0.0.0.0:8088", ListenAddress("0.0.0.0:8088"))
+	assert.Equal(t, "10.0.0.1:8088", ListenAddress("10.0.0.1:8088"))
+}
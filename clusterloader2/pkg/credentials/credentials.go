@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+)
+
+// Names of credentials used by clusterloader. They are the same as names of environment
+// variables from which they were taken before credential sources were introduced.
+const (
+	AWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	AWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	AWSSessionToken    = "AWS_SESSION_TOKEN"
+	AzureTenantID      = "AZURE_TENANT_ID"
+	AzureClientID      = "AZURE_CLIENT_ID"
+	AzureClientSecret  = "AZURE_CLIENT_SECRET"
+	// GCPServiceAccountKey is a JSON key of GCP service account. If it isn't provided,
+	// application default credentials and the active gcloud account are used.
+	GCPServiceAccountKey = "GCP_SERVICE_ACCOUNT_KEY"
+	// SSHPrivateKey is a PEM encoded key used to SSH to nodes. If it isn't provided,
+	// the key is read from the file specified by KUBE_SSH_KEY_PATH or provider specific variables.
+	SSHPrivateKey = "SSH_PRIVATE_KEY"
+	// SummarySinkAuthHeader is used by http(s) summary sinks if summary-sink-auth-header isn't set.
+	SummarySinkAuthHeader = "SUMMARY_SINK_AUTH_HEADER"
+)
+
+// Source provides credentials by name.
+type Source interface {
+	// Get returns the credential. If the source doesn't have it, found is false.
+	Get(name string) (value string, found bool, err error)
+	String() string
+}
+
+var (
+	lock    sync.RWMutex
+	sources = []Source{envSource{}}
+)
+
+// InitFlags initializes credential flags.
+func InitFlags(s *[]string) {
+	flags.StringSliceEnvVar(s, "credential-sources", "CREDENTIAL_SOURCES", []string{"env"}, "Sources of credentials (e.g. cloud or SSH keys), tried in the given order. Supported sources are env (environment variables), file:///dir (files named as credentials, e.g. a mounted secret), gcpsm://project (GCP Secret Manager) and exec:///path (helper printing the credential given as its argument). Supports multiple values when separated by commas")
+}
+
+// Init sets sources of credentials. By default, credentials are taken from environment variables.
+func Init(urls []string) error {
+	var newSources []Source
+	for _, rawURL := range urls {
+		source, err := newSource(rawURL)
+		if err != nil {
+			return err
+		}
+		newSources = append(newSources, source)
+	}
+	if len(newSources) == 0 {
+		newSources = []Source{envSource{}}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	sources = newSources
+	return nil
+}
+
+func newSource(rawURL string) (Source, error) {
+	if rawURL == "env" {
+		return envSource{}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing credential source %q error: %v", rawURL, err)
+	}
+	switch u.Scheme {
+	case "env":
+		return envSource{}, nil
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("credential source %q: missing directory", rawURL)
+		}
+		return fileSource{dir: u.Path}, nil
+	case "gcpsm":
+		if u.Host == "" {
+			return nil, fmt.Errorf("credential source %q: missing project", rawURL)
+		}
+		return newGCPSecretManagerSource(u.Host), nil
+	case "exec":
+		if u.Path == "" {
+			return nil, fmt.Errorf("credential source %q: missing command", rawURL)
+		}
+		return execSource{command: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credential source %q", rawURL)
+	}
+}
+
+// Get returns the credential from the first source which has it,
+// or an empty string if none of them has.
+func Get(name string) (string, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	for _, source := range sources {
+		value, found, err := source.Get(name)
+		if err != nil {
+			return "", fmt.Errorf("getting %s from %v error: %v", name, source, err)
+		}
+		if found {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// envSource takes credentials from environment variables named as the credentials.
+type envSource struct{}
+
+func (envSource) Get(name string) (string, bool, error) {
+	value, found := os.LookupEnv(name)
+	return value, found && value != "", nil
+}
+
+func (envSource) String() string {
+	return "env"
+}
+
+// fileSource takes credentials from files named as the credentials in the directory,
+// e.g. a mounted Kubernetes secret. Trailing newline is removed.
+type fileSource struct {
+	dir string
+}
+
+func (f fileSource) Get(name string) (string, bool, error) {
+	content, err := ioutil.ReadFile(filepath.Join(f.dir, name))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(string(content), "\n"), true, nil
+}
+
+func (f fileSource) String() string {
+	return "file://" + f.dir
+}
+
+// execSource runs the command with the credential name as its argument. The command prints
+// the credential or nothing if it doesn't have it.
+type execSource struct {
+	command string
+}
+
+func (e execSource) Get(name string) (string, bool, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(e.command, name)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", false, fmt.Errorf("%v, stderr: %q", err, stderr.String())
+	}
+	value := strings.TrimSuffix(stdout.String(), "\n")
+	return value, value != "", nil
+}
+
+func (e execSource) String() string {
+	return "exec://" + e.command
+}
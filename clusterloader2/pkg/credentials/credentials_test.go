@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	defer Init(nil)
+	for _, rawURL := range []string{"vault://host", "file://", "gcpsm:///secrets", "exec://"} {
+		if err := Init([]string{rawURL}); err == nil {
+			t.Errorf("%s: expected error", rawURL)
+		}
+	}
+	assert.NoError(t, Init([]string{"env", "file:///var/run/secrets/clusterloader", "gcpsm://project", "exec:///usr/local/bin/helper"}))
+	assert.Equal(t, "[env file:///var/run/secrets/clusterloader gcpsm://project exec:///usr/local/bin/helper]", fmt.Sprint(sources))
+}
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, AWSAccessKeyID), []byte("from-file\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, AWSSecretAccessKey), []byte("secret-from-file"), 0600))
+	os.Setenv(AWSAccessKeyID, "from-env")
+	defer os.Unsetenv(AWSAccessKeyID)
+
+	defer Init(nil)
+	assert.NoError(t, Init([]string{"env", "file://" + dir}))
+	value, err := Get(AWSAccessKeyID)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+	value, err = Get(AWSSecretAccessKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-from-file", value)
+	value, err = Get(AzureClientSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "", value)
+
+	assert.NoError(t, Init([]string{"file://" + dir, "env"}))
+	value, err = Get(AWSAccessKeyID)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+}
+
+func TestExecSource(t *testing.T) {
+	source := execSource{command: "echo"}
+	value, found, err := source.Get("name")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "name", value)
+
+	_, _, err = execSource{command: "false"}.Get("name")
+	assert.Error(t, err)
+}
+
+func TestGCPSecretManagerSource(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if r.URL.Path != "/v1/projects/project/secrets/SSH_PRIVATE_KEY/versions/latest:access" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"name": "projects/1/secrets/SSH_PRIVATE_KEY/versions/2", "payload": {"data": "a2V5"}}`))
+	}))
+	defer server.Close()
+	source := &gcpSecretManagerSource{endpoint: server.URL, project: "project", client: server.Client()}
+
+	value, found, err := source.Get(SSHPrivateKey)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "key", value)
+	assert.Equal(t, "/v1/projects/project/secrets/SSH_PRIVATE_KEY/versions/latest:access", gotPath)
+
+	_, found, err = source.Get(AzureClientID)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
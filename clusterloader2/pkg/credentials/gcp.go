@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	secretManagerEndpoint = "https://secretmanager.googleapis.com"
+	cloudPlatformScope    = "https://www.googleapis.com/auth/cloud-platform"
+	secretManagerTimeout  = 30 * time.Second
+)
+
+// gcpSecretManagerSource takes credentials from the latest versions of secrets named as
+// the credentials in GCP Secret Manager. Application default credentials are used to access it.
+type gcpSecretManagerSource struct {
+	endpoint string
+	project  string
+	// client is created once, on the first use of the source, as sources are set up
+	// even if none of the credentials is used.
+	clientOnce sync.Once
+	client     *http.Client
+	clientErr  error
+}
+
+func newGCPSecretManagerSource(project string) *gcpSecretManagerSource {
+	return &gcpSecretManagerSource{endpoint: secretManagerEndpoint, project: project}
+}
+
+func (g *gcpSecretManagerSource) Get(name string) (string, bool, error) {
+	g.clientOnce.Do(func() {
+		if g.client != nil {
+			return
+		}
+		client, err := google.DefaultClient(context.Background(), cloudPlatformScope)
+		if err != nil {
+			g.clientErr = fmt.Errorf("creating Secret Manager client error: %v", err)
+			return
+		}
+		client.Timeout = secretManagerTimeout
+		g.client = client
+	})
+	if g.clientErr != nil {
+		return "", false, g.clientErr
+	}
+	accessURL := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/latest:access", g.endpoint, url.PathEscape(g.project), url.PathEscape(name))
+	response, err := g.client.Get(accessURL)
+	if err != nil {
+		return "", false, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return "", false, nil
+	case response.StatusCode != http.StatusOK:
+		return "", false, fmt.Errorf("status %d: %s", response.StatusCode, string(body))
+	}
+	var secret struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", false, fmt.Errorf("decoding response error: %v", err)
+	}
+	value, err := base64.StdEncoding.DecodeString(secret.Payload.Data)
+	if err != nil {
+		return "", false, fmt.Errorf("decoding secret error: %v", err)
+	}
+	return string(value), true, nil
+}
+
+func (g *gcpSecretManagerSource) String() string {
+	return "gcpsm://" + g.project
+}
+
+// GoogleClient returns HTTP client authorized with GCPServiceAccountKey or, if it isn't provided,
+// with application default credentials.
+func GoogleClient(ctx context.Context, scope ...string) (*http.Client, error) {
+	key, err := Get(GCPServiceAccountKey)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return google.DefaultClient(ctx, scope...)
+	}
+	credentials, err := google.CredentialsFromJSON(ctx, []byte(key), scope...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s error: %v", GCPServiceAccountKey, err)
+	}
+	return oauth2.NewClient(ctx, credentials.TokenSource), nil
+}
+
+// GCloudEnv returns environment of gcloud commands, which makes them use GCPServiceAccountKey.
+// If the key isn't provided, nil is returned, so that the commands inherit environment and use
+// the active gcloud account. Cleanup removes the key file and has to be called once the commands finish.
+func GCloudEnv() (env []string, cleanup func(), err error) {
+	key, err := Get(GCPServiceAccountKey)
+	if err != nil || key == "" {
+		return nil, func() {}, err
+	}
+	keyFile, err := ioutil.TempFile("", "gcp-key-*.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() { os.Remove(keyFile.Name()) }
+	_, err = keyFile.WriteString(key)
+	if closeErr := keyFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("writing %s error: %v", GCPServiceAccountKey, err)
+	}
+	return append(os.Environ(), "CLOUDSDK_AUTH_CREDENTIAL_FILE_OVERRIDE="+keyFile.Name()), cleanup, nil
+}
@@ -41,6 +41,13 @@ func (e *ErrorList) IsEmpty() bool {
 	return len(e.errors) == 0
 }
 
+// Len returns the number of errors in the list.
+func (e *ErrorList) Len() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.errors)
+}
+
 // Append adds errors to the list
 func (e *ErrorList) Append(errs ...error) {
 	e.lock.Lock()
@@ -58,6 +65,12 @@ func (e *ErrorList) Concat(e2 *ErrorList) {
 	e.errors = append(e.errors, e2.errors...)
 }
 
+func (e *ErrorList) getErrors() []error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]error(nil), e.errors...)
+}
+
 // String returns error list as a single string.
 func (e *ErrorList) String() string {
 	e.lock.Lock()
@@ -19,6 +19,9 @@ package errors
 type metricViolationError struct {
 	metric string
 	reason string
+	// observed and threshold are set only if the violation is a single value exceeding a threshold.
+	observed  *float64
+	threshold *float64
 }
 
 func (m *metricViolationError) Error() string {
@@ -33,8 +36,56 @@ func NewMetricViolationError(metric, reason string) error {
 	}
 }
 
-// IsMetricViolationError checks if given error is MetricViolation type.
+// NewMetricViolationErrorWithThreshold creates new metric violation error of a single observed value
+// exceeding the threshold. Both values should be in the same unit, e.g. seconds.
+func NewMetricViolationErrorWithThreshold(metric, reason string, observed, threshold float64) error {
+	return &metricViolationError{
+		metric:    metric,
+		reason:    reason,
+		observed:  &observed,
+		threshold: &threshold,
+	}
+}
+
+// IsMetricViolationError checks if given error is MetricViolation type
+// or a non-empty ErrorList of MetricViolation errors only.
 func IsMetricViolationError(err error) bool {
+	if errList, ok := err.(*ErrorList); ok {
+		violations := errList.getErrors()
+		for _, violation := range violations {
+			if _, ok := violation.(*metricViolationError); !ok {
+				return false
+			}
+		}
+		return len(violations) > 0
+	}
 	_, ok := err.(*metricViolationError)
 	return ok
 }
+
+// GetMetricViolations returns MetricViolation errors of given ErrorList or the error itself.
+func GetMetricViolations(err error) []error {
+	if errList, ok := err.(*ErrorList); ok {
+		return errList.getErrors()
+	}
+	return []error{err}
+}
+
+// GetViolatedMetric returns name of the metric violated according to given MetricViolation error.
+// Empty string is returned for other errors.
+func GetViolatedMetric(err error) string {
+	if m, ok := err.(*metricViolationError); ok {
+		return m.metric
+	}
+	return ""
+}
+
+// GetViolationThreshold returns the observed value and the threshold it exceeded according
+// to given MetricViolation error. False is returned if they are unknown.
+func GetViolationThreshold(err error) (observed, threshold float64, ok bool) {
+	m, isViolation := err.(*metricViolationError)
+	if !isViolation || m.observed == nil || m.threshold == nil {
+		return 0, 0, false
+	}
+	return *m.observed, *m.threshold, true
+}
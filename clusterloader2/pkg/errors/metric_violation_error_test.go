@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricViolations(t *testing.T) {
+	latency := NewMetricViolationError("latency", "too high")
+	errorRatio := NewMetricViolationError("error ratio", "too high")
+	other := fmt.Errorf("query error")
+
+	testCases := []struct {
+		name           string
+		err            error
+		wantViolation  bool
+		wantViolations []error
+	}{
+		{
+			name:           "single violation",
+			err:            latency,
+			wantViolation:  true,
+			wantViolations: []error{latency},
+		},
+		{
+			name:           "all violations of the list",
+			err:            NewErrorList(latency, errorRatio),
+			wantViolation:  true,
+			wantViolations: []error{latency, errorRatio},
+		},
+		{
+			name: "empty list",
+			err:  NewErrorList(),
+		},
+		{
+			name: "list with other error",
+			err:  NewErrorList(latency, other),
+		},
+		{
+			name: "other error",
+			err:  other,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantViolation, IsMetricViolationError(tc.err))
+			if tc.wantViolation {
+				assert.Equal(t, tc.wantViolations, GetMetricViolations(tc.err))
+			}
+		})
+	}
+}
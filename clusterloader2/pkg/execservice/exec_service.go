@@ -17,10 +17,8 @@ limitations under the License.
 package execservice
 
 import (
-	"bytes"
 	"fmt"
 	"math/rand"
-	"os/exec"
 	"sync"
 	"time"
 
@@ -64,7 +62,7 @@ func SetUpExecService(f *framework.Framework) error {
 	mapping["Name"] = execDeploymentName
 	mapping["Namespace"] = execDeploymentNamespace
 	mapping["Replicas"] = execPodReplicas
-	if err = client.CreateNamespace(f.GetClientSets().GetClient(), execDeploymentNamespace); err != nil {
+	if err = client.CreateNamespace(f.GetClientSets().GetClient(), execDeploymentNamespace, f.GetClusterConfig().RunID); err != nil {
 		return fmt.Errorf("namespace %s creation error: %v", execDeploymentNamespace, err)
 	}
 	if err = f.ApplyTemplatedManifests(
@@ -90,10 +88,10 @@ func SetUpExecService(f *framework.Framework) error {
 		CallerName:          execServiceName,
 		WaitForPodsInterval: execPodCheckInterval,
 	}
-	if err = measurementutil.WaitForPods(f.GetClientSets().GetClient(), stopCh, options); err != nil {
+	if err = measurementutil.WaitForPods(f.GetPodStores(), stopCh, options); err != nil {
 		return err
 	}
-	podStore, err = measurementutil.NewPodStore(f.GetClientSets().GetClient(), selector)
+	podStore, err = f.GetPodStores().Get(selector)
 	if err != nil {
 		return fmt.Errorf("pod store creation error: %v", err)
 	}
@@ -120,19 +118,16 @@ func TearDownExecService(f *framework.Framework) error {
 }
 
 // RunCommand executes given command on a pod in cluster.
-func RunCommand(cmd string) (string, error) {
+func RunCommand(f *framework.Framework, cmd string) (string, error) {
 	pod, err := getPod()
 	if err != nil {
 		return "", err
 	}
-
-	var stdout, stderr bytes.Buffer
-	c := exec.Command("kubectl", "exec", fmt.Sprintf("--namespace=%v", pod.Namespace), pod.Name, "--", "/bin/sh", "-x", "-c", cmd)
-	c.Stdout, c.Stderr = &stdout, &stderr
-	if err := c.Run(); err != nil {
-		return stderr.String(), err
+	stdout, stderr, err := f.ExecInPod(pod.Namespace, pod.Name, "", []string{"/bin/sh", "-x", "-c", cmd})
+	if err != nil {
+		return string(stderr), err
 	}
-	return stdout.String(), nil
+	return string(stdout), nil
 }
 
 func getPod() (*corev1.Pod, error) {
@@ -19,6 +19,8 @@ package client
 import (
 	"fmt"
 	"net"
+	"regexp"
+	"strconv"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -30,10 +32,10 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
 	"k8s.io/apimachinery/pkg/util/mergepatch"
-	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 const (
@@ -46,8 +48,20 @@ const (
 	// Parameters for namespace deletion operations.
 	defaultNamespaceDeletionTimeout  = 10 * time.Minute
 	defaultNamespaceDeletionInterval = 5 * time.Second
+
+	// bulkListPageSize is the number of objects listed in a single request by bulk operations.
+	bulkListPageSize = 500
+
+	// RunIDLabel is the label of namespaces created by ClusterLoader2. Its value identifies the run
+	// that created the namespace, so that leftovers of previous runs can be detected.
+	RunIDLabel = "clusterloader2.io/run-id"
 )
 
+// NewRunID returns a new identifier of a run of ClusterLoader2.
+func NewRunID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
 // RetryWithExponentialBackOff a utility for retrying the given function with exponential backoff.
 func RetryWithExponentialBackOff(fn wait.ConditionFunc) error {
 	backoff := wait.Backoff{
@@ -59,20 +73,6 @@ func RetryWithExponentialBackOff(fn wait.ConditionFunc) error {
 	return wait.ExponentialBackoff(backoff, fn)
 }
 
-// IsRetryableAPIError verifies whether the error is retryable.
-func IsRetryableAPIError(err error) bool {
-	// These errors may indicate a transient error that we can retry in tests.
-	if apierrs.IsInternalError(err) || apierrs.IsTimeout(err) || apierrs.IsServerTimeout(err) ||
-		apierrs.IsTooManyRequests(err) || utilnet.IsProbableEOF(err) || utilnet.IsConnectionReset(err) {
-		return true
-	}
-	// If the error sends the Retry-After header, we respect it as an explicit confirmation we should retry.
-	if _, shouldRetry := apierrs.SuggestsClientDelay(err); shouldRetry {
-		return true
-	}
-	return false
-}
-
 // IsRetryableNetError determines whether the error is a retryable net error.
 func IsRetryableNetError(err error) bool {
 	if netError, ok := err.(net.Error); ok {
@@ -98,7 +98,8 @@ func Retry(retryErrorPredicate func(error) bool) *ApiCallOptions {
 	return &ApiCallOptions{shouldRetryError: retryErrorPredicate}
 }
 
-// RetryFunction opaques given function into retryable function.
+// RetryFunction opaques given function into retryable function. Retryable errors (see CategorizeError)
+// are retried, unless retries are disabled, and counted by category.
 func RetryFunction(f func() error, options ...*ApiCallOptions) wait.ConditionFunc {
 	var shouldAllowErrorFuncs, shouldRetryErrorFuncs []func(error) bool
 	for _, option := range options {
@@ -114,7 +115,10 @@ func RetryFunction(f func() error, options ...*ApiCallOptions) wait.ConditionFun
 		if err == nil {
 			return true, nil
 		}
-		if IsRetryableAPIError(err) || IsRetryableNetError(err) {
+		if category := CategorizeError(err); category != "" && retriesEnabled() {
+			recordRetry(category)
+			// Retry-After delay is waited for in addition to the backoff.
+			waitRetryAfter(err)
 			return false, nil
 		}
 		for _, shouldAllowError := range shouldAllowErrorFuncs {
@@ -170,10 +174,29 @@ func ListNodesWithOptions(c clientset.Interface, listOpts metav1.ListOptions) ([
 	return nodes, nil
 }
 
-// CreateNamespace creates a single namespace with given name.
-func CreateNamespace(c clientset.Interface, namespace string) error {
+// CreateNamespace creates a single namespace with given name, labeled with the id of the run creating it.
+func CreateNamespace(c clientset.Interface, namespace, runID string) error {
+	return CreateNamespaceWithMetadata(c, namespace, runID, nil, nil)
+}
+
+// CreateNamespaceWithMetadata creates a single namespace with given name, labels and annotations.
+// The namespace is labeled with the id of the run creating it as well, unless it's empty.
+func CreateNamespaceWithMetadata(c clientset.Interface, namespace, runID string, labels, annotations map[string]string) error {
+	namespaceLabels := make(map[string]string)
+	if runID != "" {
+		namespaceLabels[RunIDLabel] = runID
+	}
+	for k, v := range labels {
+		if k != RunIDLabel {
+			namespaceLabels[k] = v
+		}
+	}
 	createFunc := func() error {
-		_, err := c.CoreV1().Namespaces().Create(&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+		_, err := c.CoreV1().Namespaces().Create(&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        namespace,
+			Labels:      namespaceLabels,
+			Annotations: annotations,
+		}})
 		return err
 	}
 	return RetryWithExponentialBackOff(RetryFunction(createFunc, Allow(apierrs.IsAlreadyExists)))
@@ -204,19 +227,56 @@ func ListNamespaces(c clientset.Interface) ([]apiv1.Namespace, error) {
 	return namespaces, nil
 }
 
+// ListStaleNamespaces returns namespaces created by runs of ClusterLoader2 other than runID that are older than ttl.
+// Namespaces without the run id label (e.g. created by older versions of ClusterLoader2) are considered created
+// by other runs if their names match unlabeledPattern, unless it's nil.
+func ListStaleNamespaces(c clientset.Interface, runID string, ttl time.Duration, unlabeledPattern *regexp.Regexp) ([]apiv1.Namespace, error) {
+	var namespaces []apiv1.Namespace
+	listFunc := func() error {
+		namespacesList, err := c.CoreV1().Namespaces().List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		namespaces = namespaces[:0]
+		for _, namespace := range namespacesList.Items {
+			if IsStaleNamespace(&namespace, runID, ttl, unlabeledPattern, time.Now()) {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+		return nil
+	}
+	if err := RetryWithExponentialBackOff(RetryFunction(listFunc)); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+// IsStaleNamespace returns true if the namespace was created by a run of ClusterLoader2 other than runID
+// more than ttl before now, see ListStaleNamespaces.
+func IsStaleNamespace(namespace *apiv1.Namespace, runID string, ttl time.Duration, unlabeledPattern *regexp.Regexp, now time.Time) bool {
+	if now.Sub(namespace.CreationTimestamp.Time) < ttl {
+		return false
+	}
+	if namespaceRunID, ok := namespace.Labels[RunIDLabel]; ok {
+		return namespaceRunID != runID
+	}
+	return unlabeledPattern != nil && unlabeledPattern.MatchString(namespace.Name)
+}
+
 // WaitForDeleteNamespace waits untils namespace is terminated.
 func WaitForDeleteNamespace(c clientset.Interface, namespace string) error {
-	retryWaitFunc := func() (bool, error) {
+	var deleted bool
+	getFunc := func() error {
 		_, err := c.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
-		if err != nil {
-			if apierrs.IsNotFound(err) {
-				return true, nil
-			}
-			if !IsRetryableAPIError(err) {
-				return false, err
-			}
+		deleted = apierrs.IsNotFound(err)
+		return err
+	}
+	retryGetFunc := RetryFunction(getFunc, Allow(apierrs.IsNotFound))
+	retryWaitFunc := func() (bool, error) {
+		if done, err := retryGetFunc(); !done || err != nil {
+			return false, err
 		}
-		return false, nil
+		return deleted, nil
 	}
 	return wait.PollImmediate(defaultNamespaceDeletionInterval, defaultNamespaceDeletionTimeout, retryWaitFunc)
 }
@@ -268,6 +328,19 @@ func PatchObject(dynamicClient dynamic.Interface, namespace string, name string,
 	return RetryWithExponentialBackOff(RetryFunction(updateFunc, options...))
 }
 
+// UpdateObject replaces object with given name, group, version and kind with given object description.
+// Object's resourceVersion is used as a precondition, if set.
+func UpdateObject(dynamicClient dynamic.Interface, namespace string, name string, obj *unstructured.Unstructured, options ...*ApiCallOptions) error {
+	gvk := obj.GroupVersionKind()
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	obj.SetName(name)
+	updateFunc := func() error {
+		_, err := dynamicClient.Resource(gvr).Namespace(namespace).Update(obj, metav1.UpdateOptions{})
+		return err
+	}
+	return RetryWithExponentialBackOff(RetryFunction(updateFunc, options...))
+}
+
 // DeleteObject deletes object with given name, group, version and kind.
 func DeleteObject(dynamicClient dynamic.Interface, gvk schema.GroupVersionKind, namespace string, name string, options ...*ApiCallOptions) error {
 	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
@@ -298,6 +371,66 @@ func GetObject(dynamicClient dynamic.Interface, gvk schema.GroupVersionKind, nam
 	return obj, nil
 }
 
+// ListObjects lists objects with given group, version and kind matching the label selector.
+// Objects are listed in pages, every page request is rate limited by the limiter (if not nil).
+func ListObjects(dynamicClient dynamic.Interface, limiter flowcontrol.RateLimiter, gvk schema.GroupVersionKind, namespace string, labelSelector string, options ...*ApiCallOptions) ([]unstructured.Unstructured, error) {
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	var objects []unstructured.Unstructured
+	listOpts := metav1.ListOptions{LabelSelector: labelSelector, Limit: bulkListPageSize}
+	for {
+		var list *unstructured.UnstructuredList
+		listFunc := func() error {
+			if limiter != nil {
+				limiter.Accept()
+			}
+			var err error
+			list, err = dynamicClient.Resource(gvr).Namespace(namespace).List(listOpts)
+			return err
+		}
+		if err := RetryWithExponentialBackOff(RetryFunction(listFunc, options...)); err != nil {
+			return nil, err
+		}
+		objects = append(objects, list.Items...)
+		if list.GetContinue() == "" {
+			return objects, nil
+		}
+		listOpts.Continue = list.GetContinue()
+	}
+}
+
+// DeleteCollection deletes all objects with given group, version and kind matching the label selector
+// with a single request. Resources not supporting collection deletion are listed and their objects are
+// deleted one by one. All requests are rate limited by the limiter (if not nil).
+func DeleteCollection(dynamicClient dynamic.Interface, limiter flowcontrol.RateLimiter, gvk schema.GroupVersionKind, namespace string, labelSelector string, options ...*ApiCallOptions) error {
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	deleteFunc := func() error {
+		if limiter != nil {
+			limiter.Accept()
+		}
+		// Delete operation removes objects with all of the dependants.
+		falseVar := false
+		deleteOption := &metav1.DeleteOptions{OrphanDependents: &falseVar}
+		return dynamicClient.Resource(gvr).Namespace(namespace).DeleteCollection(deleteOption, metav1.ListOptions{LabelSelector: labelSelector})
+	}
+	err := RetryWithExponentialBackOff(RetryFunction(deleteFunc, options...))
+	if !apierrs.IsMethodNotSupported(err) {
+		return err
+	}
+	objects, err := ListObjects(dynamicClient, limiter, gvk, namespace, labelSelector, options...)
+	if err != nil {
+		return err
+	}
+	for i := range objects {
+		if limiter != nil {
+			limiter.Accept()
+		}
+		if err := DeleteObject(dynamicClient, gvk, objects[i].GetNamespace(), objects[i].GetName(), options...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func createPatch(current, modified *unstructured.Unstructured) ([]byte, error) {
 	currentJson, err := current.MarshalJSON()
 	if err != nil {
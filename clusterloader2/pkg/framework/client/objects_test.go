@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsStaleNamespace(t *testing.T) {
+	now := time.Now()
+	pattern := regexp.MustCompile("^test-[a-z0-9]{6}-[0-9]+$")
+	testCases := []struct {
+		name   string
+		ns     string
+		labels map[string]string
+		age    time.Duration
+		want   bool
+	}{
+		{name: "other run", ns: "probes", labels: map[string]string{RunIDLabel: "other"}, age: 2 * time.Hour, want: true},
+		{name: "same run", ns: "probes", labels: map[string]string{RunIDLabel: "run"}, age: 2 * time.Hour, want: false},
+		{name: "other run too young", ns: "probes", labels: map[string]string{RunIDLabel: "other"}, age: time.Minute, want: false},
+		{name: "unlabeled automanaged", ns: "test-abc123-1", age: 2 * time.Hour, want: true},
+		{name: "unlabeled automanaged too young", ns: "test-abc123-1", age: time.Minute, want: false},
+		{name: "unlabeled other", ns: "kube-system", age: 2 * time.Hour, want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:              tc.ns,
+				Labels:            tc.labels,
+				CreationTimestamp: metav1.NewTime(now.Add(-tc.age)),
+			}}
+			assert.Equal(t, tc.want, IsStaleNamespace(namespace, "run", time.Hour, pattern, now))
+		})
+	}
+	unlabeled := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-abc123-1", CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))}}
+	assert.False(t, IsStaleNamespace(unlabeled, "run", time.Hour, nil, now))
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	apiv1 "k8s.io/api/core/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// GetPodLogs returns logs of the container of the pod.
+func GetPodLogs(c clientset.Interface, namespace, pod string, opts *apiv1.PodLogOptions, options ...*ApiCallOptions) ([]byte, error) {
+	var logs []byte
+	getFunc := func() error {
+		var err error
+		logs, err = c.CoreV1().Pods(namespace).GetLogs(pod, opts).DoRaw()
+		return err
+	}
+	if err := RetryWithExponentialBackOff(RetryFunction(getFunc, options...)); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// StreamPodLogs copies logs of the container of the pod to the writer until the stream ends,
+// e.g. when the container terminates if opts.Follow is set.
+func StreamPodLogs(c clientset.Interface, namespace, pod string, opts *apiv1.PodLogOptions, w io.Writer) error {
+	stream, err := c.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// ProxyGetPod performs GET request to the port of the pod through the apiserver proxy, e.g. to collect
+// pprof profiles or metrics of components running in pods without port-forwarding.
+func ProxyGetPod(c clientset.Interface, namespace, pod, scheme, port, path string, params map[string]string, options ...*ApiCallOptions) ([]byte, error) {
+	var body []byte
+	getFunc := func() error {
+		request := c.CoreV1().RESTClient().Get().
+			Namespace(namespace).
+			Resource("pods").
+			SubResource("proxy").
+			Name(utilnet.JoinSchemeNamePort(scheme, pod, port)).
+			Suffix(path)
+		for k, v := range params {
+			request = request.Param(k, v)
+		}
+		var err error
+		body, err = request.DoRaw()
+		return err
+	}
+	if err := RetryWithExponentialBackOff(RetryFunction(getFunc, options...)); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// IsExecExitError returns true if the error is returned by ExecInPod because the command exited with non-zero code.
+func IsExecExitError(err error) bool {
+	_, ok := err.(utilexec.ExitError)
+	return ok
+}
+
+// GetExecExitCode returns exit code of the command if the error is exec exit error, -1 otherwise.
+func GetExecExitCode(err error) int {
+	if e, ok := err.(utilexec.ExitError); ok {
+		return e.ExitStatus()
+	}
+	return -1
+}
+
+// ExecInPod executes command in the container of the pod and returns its standard output and standard error.
+// Container can be empty if the pod has a single container. Stdin and tty are not supported.
+// The call blocks until the command terminates.
+func ExecInPod(c clientset.Interface, config *rest.Config, namespace, pod, container string, command []string) ([]byte, []byte, error) {
+	request := c.CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&apiv1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("exec in pod %s/%s error: %v", namespace, pod, err)
+	}
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// PortForwarder forwards connections accepted on a local port to a port of a pod.
+type PortForwarder struct {
+	forwarder *portforward.PortForwarder
+	localPort uint16
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// PortForward starts forwarding connections to a random local port (see LocalAddress) to the port of the pod.
+// Forwarding lasts until Close is called.
+func PortForward(c clientset.Interface, config *rest.Config, namespace, pod string, port int) (*PortForwarder, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	request := c.CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", request.URL())
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	p := &PortForwarder{forwarder: forwarder, stopCh: stopCh, doneCh: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(p.doneCh)
+		if err := forwarder.ForwardPorts(); err != nil {
+			logrus.Warningf("Port forwarding to %s/%s:%d error: %v", namespace, pod, port, err)
+			errCh <- err
+		}
+	}()
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, err
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.localPort = ports[0].Local
+	return p, nil
+}
+
+// LocalAddress returns local address, e.g. 127.0.0.1:41234, forwarded to the port of the pod.
+func (p *PortForwarder) LocalAddress() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(int(p.localPort)))
+}
+
+// Close stops accepting new connections and waits until forwarding is stopped.
+func (p *PortForwarder) Close() error {
+	close(p.stopCh)
+	<-p.doneCh
+	return nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestExecExitError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		isExitErr bool
+		exitCode  int
+	}{
+		{
+			name:      "non-zero exit code",
+			err:       utilexec.CodeExitError{Err: errors.New("command terminated with exit code 3"), Code: 3},
+			isExitErr: true,
+			exitCode:  3,
+		},
+		{
+			name:     "other error",
+			err:      errors.New("container not found"),
+			exitCode: -1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.isExitErr, IsExecExitError(tc.err))
+			assert.Equal(t, tc.exitCode, GetExecExitCode(tc.err))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// Categories of retryable API call errors.
+const (
+	ErrorCategoryTooManyRequests   = "TooManyRequests"
+	ErrorCategoryTimeout           = "Timeout"
+	ErrorCategoryEtcdLeaderChanged = "EtcdLeaderChanged"
+	ErrorCategoryInternalError     = "InternalError"
+	ErrorCategoryConnection        = "Connection"
+	ErrorCategoryRetryAfter        = "RetryAfter"
+)
+
+// maxRetryAfter limits the delay requested by the apiserver with Retry-After header.
+const maxRetryAfter = 30 * time.Second
+
+var (
+	retriesLock     sync.Mutex
+	retriesDisabled bool
+	retryCounts     = make(map[string]int)
+)
+
+// SetRetriesEnabled enables or disables retrying of API calls failing with retryable errors.
+// Retries are enabled by default. Errors retried explicitly with Retry option are retried regardless.
+func SetRetriesEnabled(enabled bool) {
+	retriesLock.Lock()
+	defer retriesLock.Unlock()
+	retriesDisabled = !enabled
+}
+
+func retriesEnabled() bool {
+	retriesLock.Lock()
+	defer retriesLock.Unlock()
+	return !retriesDisabled
+}
+
+// CategorizeError returns the category of the retryable error, or empty string if the error isn't retryable.
+func CategorizeError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case apierrs.IsTooManyRequests(err):
+		return ErrorCategoryTooManyRequests
+	case apierrs.IsTimeout(err) || apierrs.IsServerTimeout(err):
+		return ErrorCategoryTimeout
+	case strings.Contains(err.Error(), "etcdserver: leader changed"):
+		return ErrorCategoryEtcdLeaderChanged
+	case apierrs.IsInternalError(err):
+		return ErrorCategoryInternalError
+	case utilnet.IsProbableEOF(err) || utilnet.IsConnectionReset(err) || IsRetryableNetError(err):
+		return ErrorCategoryConnection
+	}
+	if _, shouldRetry := apierrs.SuggestsClientDelay(err); shouldRetry {
+		return ErrorCategoryRetryAfter
+	}
+	return ""
+}
+
+// GetRetryCounts returns numbers of retries of API calls performed so far by category of the error.
+func GetRetryCounts() map[string]int {
+	retriesLock.Lock()
+	defer retriesLock.Unlock()
+	counts := make(map[string]int, len(retryCounts))
+	for category, count := range retryCounts {
+		counts[category] = count
+	}
+	return counts
+}
+
+func recordRetry(category string) {
+	retriesLock.Lock()
+	defer retriesLock.Unlock()
+	retryCounts[category]++
+}
+
+// waitRetryAfter waits for the delay requested by the apiserver in the error, if any.
+func waitRetryAfter(err error) {
+	seconds, ok := apierrs.SuggestsClientDelay(err)
+	if !ok || seconds <= 0 {
+		return
+	}
+	delay := time.Duration(seconds) * time.Second
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	time.Sleep(delay)
+}
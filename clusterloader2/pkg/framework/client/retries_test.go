@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCategorizeError(t *testing.T) {
+	resource := schema.GroupResource{Resource: "pods"}
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "too many requests", err: apierrs.NewTooManyRequests("throttled", 1), want: ErrorCategoryTooManyRequests},
+		{name: "timeout", err: apierrs.NewTimeoutError("timeout", 1), want: ErrorCategoryTimeout},
+		{name: "server timeout", err: apierrs.NewServerTimeout(resource, "list", 1), want: ErrorCategoryTimeout},
+		{name: "etcd leader changed", err: apierrs.NewInternalError(errors.New("etcdserver: leader changed")), want: ErrorCategoryEtcdLeaderChanged},
+		{name: "internal error", err: apierrs.NewInternalError(errors.New("boom")), want: ErrorCategoryInternalError},
+		{name: "eof", err: io.EOF, want: ErrorCategoryConnection},
+		{name: "not found", err: apierrs.NewNotFound(resource, "pod"), want: ""},
+		{name: "conflict", err: apierrs.NewConflict(resource, "pod", errors.New("conflict")), want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, CategorizeError(tc.err))
+		})
+	}
+}
+
+func TestRetryFunctionCountsRetries(t *testing.T) {
+	before := GetRetryCounts()
+	calls := 0
+	retryable := RetryFunction(func() error {
+		calls++
+		if calls == 1 {
+			return apierrs.NewTimeoutError("timeout", 0)
+		}
+		return nil
+	})
+	done, err := retryable()
+	assert.False(t, done)
+	assert.NoError(t, err)
+	done, err = retryable()
+	assert.True(t, done)
+	assert.NoError(t, err)
+	assert.Equal(t, before[ErrorCategoryTimeout]+1, GetRetryCounts()[ErrorCategoryTimeout])
+}
+
+func TestRetryFunctionRetriesDisabled(t *testing.T) {
+	SetRetriesEnabled(false)
+	defer SetRetriesEnabled(true)
+	timeoutErr := apierrs.NewTimeoutError("timeout", 0)
+	done, err := RetryFunction(func() error { return timeoutErr })()
+	assert.False(t, done)
+	assert.Equal(t, timeoutErr, err)
+
+	done, err = RetryFunction(func() error { return timeoutErr }, Retry(apierrs.IsTimeout))()
+	assert.False(t, done)
+	assert.NoError(t, err)
+}
@@ -27,15 +27,22 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/perf-tests/clusterloader2/pkg/selfmetrics"
 )
 
 const (
-	contentType = "application/vnd.kubernetes.protobuf"
-	qps         = 100
-	burst       = 200
+	contentType     = "application/vnd.kubernetes.protobuf"
+	jsonContentType = "application/json"
+
+	// QPS is the default queries per second limit of a single client.
+	QPS = 100
+	// Burst is the default burst of a single client.
+	Burst = 200
 )
 
-// PrepareConfig creates and initializes client config.
+// PrepareConfig creates and initializes client config. If path is empty,
+// in-cluster config of the pod's service account is used.
 func PrepareConfig(path string) (*restclient.Config, error) {
 	config, err := loadConfig(path)
 	if err != nil {
@@ -47,6 +54,95 @@ func PrepareConfig(path string) (*restclient.Config, error) {
 	return config, nil
 }
 
+// SetRateLimits overrides QPS and burst of the client config, if positive. If shared rate limiter
+// is not nil, requests of the client are additionally limited by it, e.g. to limit all clients of a pool together.
+func SetRateLimits(config *restclient.Config, qps, burst int, shared flowcontrol.RateLimiter) {
+	if qps > 0 {
+		config.QPS = float32(qps)
+	}
+	if burst > 0 {
+		config.Burst = burst
+	}
+	if shared != nil {
+		config.RateLimiter = &multiRateLimiter{
+			limiters: []flowcontrol.RateLimiter{flowcontrol.NewTokenBucketRateLimiter(config.QPS, config.Burst), shared},
+		}
+	}
+}
+
+// SetEncoding sets wire format of the client config to JSON if json is true (protobuf is used by default)
+// and disables requesting gzip compressed responses if disableCompression is true.
+func SetEncoding(config *restclient.Config, json, disableCompression bool) {
+	if json {
+		config.ContentType = jsonContentType
+		config.AcceptContentTypes = jsonContentType
+	}
+	if transport, ok := config.Transport.(*http.Transport); ok {
+		transport.DisableCompression = disableCompression
+	}
+}
+
+// NewSharedRateLimiter creates rate limiter shared by a pool of clients according to the total QPS and burst.
+// Nil is returned if total QPS is not positive. Non-positive burst defaults to the total QPS.
+func NewSharedRateLimiter(totalQPS, totalBurst int) flowcontrol.RateLimiter {
+	if totalQPS <= 0 {
+		return nil
+	}
+	if totalBurst <= 0 {
+		totalBurst = totalQPS
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(totalQPS), totalBurst)
+}
+
+// MaxQPS returns the maximal number of queries per second of a pool of clients with given limits.
+func MaxQPS(clients, qps, totalQPS int) float64 {
+	if qps <= 0 {
+		qps = QPS
+	}
+	maxQPS := float64(qps * clients)
+	if totalQPS > 0 && float64(totalQPS) < maxQPS {
+		maxQPS = float64(totalQPS)
+	}
+	return maxQPS
+}
+
+// multiRateLimiter accepts requests accepted by all of its limiters.
+type multiRateLimiter struct {
+	limiters []flowcontrol.RateLimiter
+}
+
+func (m *multiRateLimiter) TryAccept() bool {
+	// Tokens taken from limiters before the first rejecting one are lost, which is acceptable
+	// as clients don't use TryAccept.
+	for _, limiter := range m.limiters {
+		if !limiter.TryAccept() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *multiRateLimiter) Accept() {
+	for _, limiter := range m.limiters {
+		limiter.Accept()
+	}
+}
+
+func (m *multiRateLimiter) Stop() {
+	// Shared limiters are stopped by their owners.
+	m.limiters[0].Stop()
+}
+
+func (m *multiRateLimiter) QPS() float32 {
+	qps := m.limiters[0].QPS()
+	for _, limiter := range m.limiters[1:] {
+		if limiter.QPS() < qps {
+			qps = limiter.QPS()
+		}
+	}
+	return qps
+}
+
 func restclientConfig(path string) (*clientcmdapi.Config, error) {
 	c, err := clientcmd.LoadFromFile(path)
 	if err != nil {
@@ -56,6 +152,9 @@ func restclientConfig(path string) (*clientcmdapi.Config, error) {
 }
 
 func loadConfig(path string) (*restclient.Config, error) {
+	if path == "" {
+		return restclient.InClusterConfig()
+	}
 	c, err := restclientConfig(path)
 	if err != nil {
 		return nil, err
@@ -65,8 +164,10 @@ func loadConfig(path string) (*restclient.Config, error) {
 
 func initializeWithDefaults(config *restclient.Config) error {
 	config.ContentType = contentType
-	config.QPS = qps
-	config.Burst = burst
+	// JSON is accepted for resources not supporting protobuf.
+	config.AcceptContentTypes = contentType + "," + jsonContentType
+	config.QPS = QPS
+	config.Burst = Burst
 
 	// For the purpose of this test, we want to force that clients
 	// do not share underlying transport (which is a default behavior
@@ -93,6 +194,8 @@ func initializeWithDefaults(config *restclient.Config) error {
 	// Overwrite TLS-related fields from config to avoid collision with
 	// Transport field.
 	config.TLSClientConfig = restclient.TLSClientConfig{}
+	// Requests are recorded in self-metrics, so that driver-side bottlenecks can be told apart.
+	config.Wrap(selfmetrics.InstrumentRoundTripper)
 
 	return nil
 }
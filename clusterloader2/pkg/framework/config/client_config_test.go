@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	restclient "k8s.io/client-go/rest"
+)
+
+func TestSetRateLimits(t *testing.T) {
+	config := &restclient.Config{QPS: QPS, Burst: Burst}
+	SetRateLimits(config, 0, 0, nil)
+	assert.Equal(t, float32(QPS), config.QPS)
+	assert.Equal(t, Burst, config.Burst)
+	assert.Nil(t, config.RateLimiter)
+
+	SetRateLimits(config, 10, 20, nil)
+	assert.Equal(t, float32(10), config.QPS)
+	assert.Equal(t, 20, config.Burst)
+	assert.Nil(t, config.RateLimiter)
+
+	shared := NewSharedRateLimiter(5, 0)
+	SetRateLimits(config, 0, 0, shared)
+	if assert.NotNil(t, config.RateLimiter) {
+		assert.Equal(t, float32(5), config.RateLimiter.QPS())
+		// Burst of the shared limiter defaults to its QPS.
+		for i := 0; i < 5; i++ {
+			assert.True(t, config.RateLimiter.TryAccept())
+		}
+		assert.False(t, config.RateLimiter.TryAccept())
+	}
+}
+
+func TestMaxQPS(t *testing.T) {
+	assert.Equal(t, float64(10*QPS), MaxQPS(10, 0, 0))
+	assert.Equal(t, float64(200), MaxQPS(10, 20, 0))
+	assert.Equal(t, float64(150), MaxQPS(10, 20, 150))
+	assert.Equal(t, float64(200), MaxQPS(10, 20, 1000))
+	assert.Nil(t, NewSharedRateLimiter(0, 100))
+}
+
+func TestSetEncoding(t *testing.T) {
+	transport := &http.Transport{}
+	config := &restclient.Config{ContentType: contentType, Transport: transport}
+	SetEncoding(config, false, false)
+	assert.Equal(t, contentType, config.ContentType)
+	assert.False(t, transport.DisableCompression)
+
+	SetEncoding(config, true, true)
+	assert.Equal(t, "application/json", config.ContentType)
+	assert.Equal(t, "application/json", config.AcceptContentTypes)
+	assert.True(t, transport.DisableCompression)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const eventCollectorSyncTimeout = 2 * time.Minute
+
+// EventQuery selects events recorded by EventCollector. Empty fields match all events.
+type EventQuery struct {
+	Namespace          string
+	InvolvedObjectKind string
+	InvolvedObjectName string
+	Reason             string
+	// Source is the component that reported the event, e.g. default-scheduler.
+	Source string
+	// Type is the type of the event, Normal or Warning.
+	Type string
+	// Since skips events last seen before the time.
+	Since time.Time
+}
+
+func (q *EventQuery) matches(event *v1.Event) bool {
+	return (q.Namespace == "" || event.Namespace == q.Namespace) &&
+		(q.InvolvedObjectKind == "" || event.InvolvedObject.Kind == q.InvolvedObjectKind) &&
+		(q.InvolvedObjectName == "" || event.InvolvedObject.Name == q.InvolvedObjectName) &&
+		(q.Reason == "" || event.Reason == q.Reason) &&
+		(q.Source == "" || event.Source.Component == q.Source) &&
+		(q.Type == "" || event.Type == q.Type) &&
+		(q.Since.IsZero() || !EventLastSeen(event).Before(q.Since))
+}
+
+// EventLastSeen returns the time the event was last observed at.
+func EventLastSeen(event *v1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
+// eventRing is a store of the reflector keeping the latest events in a buffer of bounded size.
+// Every update of an event (e.g. with incremented count) is recorded as a new entry.
+type eventRing struct {
+	lock   sync.RWMutex
+	events []*v1.Event
+	// next is the index of the buffer the next event is stored at.
+	next int
+	full bool
+	// dropped is the number of events overwritten by newer ones.
+	dropped int
+}
+
+var _ cache.Store = &eventRing{}
+
+func newEventRing(size int) *eventRing {
+	return &eventRing{events: make([]*v1.Event, size)}
+}
+
+func (r *eventRing) Add(obj interface{}) error {
+	event, ok := obj.(*v1.Event)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.full {
+		r.dropped++
+	}
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+func (r *eventRing) Update(obj interface{}) error {
+	return r.Add(obj)
+}
+
+// Delete is a no-op, expired events are kept until overwritten.
+func (r *eventRing) Delete(obj interface{}) error {
+	return nil
+}
+
+// List returns buffered events, from the oldest to the newest.
+func (r *eventRing) List() []interface{} {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var list []interface{}
+	if r.full {
+		for _, event := range r.events[r.next:] {
+			list = append(list, event)
+		}
+	}
+	for _, event := range r.events[:r.next] {
+		list = append(list, event)
+	}
+	return list
+}
+
+func (r *eventRing) ListKeys() []string {
+	return nil
+}
+
+func (r *eventRing) Get(obj interface{}) (interface{}, bool, error) {
+	return nil, false, nil
+}
+
+func (r *eventRing) GetByKey(key string) (interface{}, bool, error) {
+	return nil, false, nil
+}
+
+// Replace is called with the result of relisting, which is always empty, see NewEventCollector.
+func (r *eventRing) Replace(list []interface{}, resourceVersion string) error {
+	for _, obj := range list {
+		if err := r.Add(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *eventRing) Resync() error {
+	return nil
+}
+
+func (r *eventRing) getDropped() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.dropped
+}
+
+// EventCollector watches events emitted since its start into a ring buffer of bounded size,
+// so that measurements can query them (e.g. reasons of scheduling failures) and they can be
+// dumped on test failure.
+type EventCollector struct {
+	namespace string
+	ring      *eventRing
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewEventCollector starts watching events of the namespace (all namespaces if empty),
+// keeping up to bufferSize latest events. The collector has to be stopped once it's no longer used.
+func NewEventCollector(c clientset.Interface, namespace string, bufferSize int) (*EventCollector, error) {
+	if bufferSize <= 0 {
+		return nil, fmt.Errorf("incorrect events buffer size %d", bufferSize)
+	}
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			// Events emitted before the start are not collected, so the list only provides
+			// the resource version the watch starts from. The list is served from etcd,
+			// as the limit is ignored by the watch cache.
+			options.ResourceVersion = ""
+			options.Limit = 1
+			list, err := c.CoreV1().Events(namespace).List(options)
+			if err != nil {
+				return nil, err
+			}
+			list.Items = nil
+			list.Continue = ""
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Events(namespace).Watch(options)
+		},
+	}
+	e := &EventCollector{
+		namespace: namespace,
+		ring:      newEventRing(bufferSize),
+		stopCh:    make(chan struct{}),
+	}
+	reflector := cache.NewNamedReflector("EventCollector", lw, &v1.Event{}, e.ring, 0)
+	go reflector.Run(e.stopCh)
+	if err := wait.PollImmediate(50*time.Millisecond, eventCollectorSyncTimeout, func() (bool, error) {
+		return len(reflector.LastSyncResourceVersion()) != 0, nil
+	}); err != nil {
+		e.Stop()
+		return nil, fmt.Errorf("couldn't start events collector: %v", err)
+	}
+	return e, nil
+}
+
+// Stop stops watching events. Collected events can still be queried.
+func (e *EventCollector) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopCh)
+	})
+}
+
+// GetNamespace returns the namespace events are collected from, empty for all namespaces.
+func (e *EventCollector) GetNamespace() string {
+	return e.namespace
+}
+
+// Covers returns true if events of the namespace (all namespaces if empty) are collected.
+func (e *EventCollector) Covers(namespace string) bool {
+	return e.namespace == "" || e.namespace == namespace
+}
+
+// Query returns collected events matching the query, from the oldest to the newest.
+// Returned events must not be modified.
+func (e *EventCollector) Query(query EventQuery) []*v1.Event {
+	var events []*v1.Event
+	for _, obj := range e.ring.List() {
+		if event := obj.(*v1.Event); query.matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// GetDropped returns the number of events dropped because the buffer was full.
+func (e *EventCollector) GetDropped() int {
+	return e.ring.getDropped()
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestEvent(name, namespace, reason string, lastSeen time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: name},
+		Reason:         reason,
+		Source:         v1.EventSource{Component: v1.DefaultSchedulerName},
+		LastTimestamp:  metav1.NewTime(lastSeen),
+	}
+}
+
+func eventNames(events []*v1.Event) []string {
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+func TestEventRing(t *testing.T) {
+	now := time.Now()
+	collector := &EventCollector{ring: newEventRing(3)}
+	assert.Empty(t, collector.Query(EventQuery{}))
+	for i, name := range []string{"a", "b"} {
+		assert.NoError(t, collector.ring.Add(newTestEvent(name, "ns", "Scheduled", now.Add(time.Duration(i)*time.Second))))
+	}
+	assert.Equal(t, []string{"a", "b"}, eventNames(collector.Query(EventQuery{})))
+	assert.Equal(t, 0, collector.GetDropped())
+
+	for i, name := range []string{"c", "d", "e"} {
+		assert.NoError(t, collector.ring.Update(newTestEvent(name, "ns", "FailedScheduling", now.Add(time.Duration(i+2)*time.Second))))
+	}
+	assert.Equal(t, []string{"c", "d", "e"}, eventNames(collector.Query(EventQuery{})))
+	assert.Equal(t, 2, collector.GetDropped())
+	assert.Error(t, collector.ring.Add(&v1.Pod{}))
+}
+
+func TestEventQuery(t *testing.T) {
+	now := time.Now()
+	collector := &EventCollector{ring: newEventRing(10)}
+	collector.ring.Add(newTestEvent("a", "ns-1", "Scheduled", now))
+	collector.ring.Add(newTestEvent("b", "ns-2", "Scheduled", now.Add(time.Second)))
+	collector.ring.Add(newTestEvent("c", "ns-1", "FailedScheduling", now.Add(2*time.Second)))
+
+	testCases := []struct {
+		query EventQuery
+		want  []string
+	}{
+		{query: EventQuery{Namespace: "ns-1"}, want: []string{"a", "c"}},
+		{query: EventQuery{Reason: "Scheduled"}, want: []string{"a", "b"}},
+		{query: EventQuery{InvolvedObjectKind: "Pod", InvolvedObjectName: "b"}, want: []string{"b"}},
+		{query: EventQuery{Source: v1.DefaultSchedulerName, Since: now.Add(time.Second)}, want: []string{"b", "c"}},
+		{query: EventQuery{Type: v1.EventTypeWarning}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, eventNames(collector.Query(tc.query)), "query %+v", tc.query)
+	}
+}
+
+func TestEventCollectorCovers(t *testing.T) {
+	assert.True(t, (&EventCollector{}).Covers("ns"))
+	assert.True(t, (&EventCollector{namespace: "ns"}).Covers("ns"))
+	assert.False(t, (&EventCollector{namespace: "ns"}).Covers(""))
+}
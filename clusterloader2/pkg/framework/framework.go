@@ -17,31 +17,111 @@ limitations under the License.
 package framework
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
+	"sync"
+	"time"
 
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
 	"github.com/sirupsen/logrus"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	frameworkconfig "k8s.io/perf-tests/clusterloader2/pkg/framework/config"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 
 	// ensure auth plugins are loaded
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
+// automanagedNamespaceCreationWorkers is the maximal number of automanaged namespaces created concurrently.
+// Requests are additionally rate limited by the QPS limit of the clients.
+const automanagedNamespaceCreationWorkers = 50
+
+// DefaultNamespaceDeletionParallelism is the default maximal number of concurrent namespace deletion requests.
+const DefaultNamespaceDeletionParallelism = 50
+
+// Rate limits of requests issued by bulk operations (ListObjects and DeleteCollection) of the framework.
+const (
+	bulkOperationQPS   = 20
+	bulkOperationBurst = 40
+)
+
+// NamespaceCreationStats describes creation of automanaged namespaces.
+type NamespaceCreationStats struct {
+	Count    int
+	Duration time.Duration
+	// Throughput is the number of namespaces created per second.
+	Throughput float64
+}
+
+// NamespaceDeletionOptions describe how automanaged namespaces are deleted.
+type NamespaceDeletionOptions struct {
+	// BatchSize is the number of namespaces deleted at once, the next batch is deleted
+	// once the previous one is gone. Non-positive value means all namespaces are deleted at once.
+	BatchSize int
+	// Parallelism is the maximal number of concurrent deletion requests. Non-positive value means no limit.
+	Parallelism int
+}
+
+// NamespaceDeletion describes deletion of a single automanaged namespace.
+type NamespaceDeletion struct {
+	Namespace string
+	// Start is the time of the delete call.
+	Start time.Time
+	// End is the time the namespace was observed as removed.
+	End time.Time
+}
+
+// SlowNamespaceDeletionHook is called for every namespace that is still terminating
+// after the delay given to SetSlowNamespaceDeletionHook.
+type SlowNamespaceDeletionHook func(namespace string)
+
+// NamespaceTemplate describes metadata of automanaged namespaces and objects
+// (e.g. ResourceQuotas, LimitRanges or NetworkPolicies) created in every one of them.
+type NamespaceTemplate struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	// Objects are created in every namespace, under their own names.
+	Objects []*unstructured.Unstructured
+}
+
 // Framework allows for interacting with Kubernetes cluster via
 // official Kubernetes client.
 type Framework struct {
 	automanagedNamespacePrefix string
+	automanagedNamespaceNaming *NamespaceNaming
 	automanagedNamespaceCount  int
+	namespaceDeletionOptions   NamespaceDeletionOptions
+	namespaceCreationStats     NamespaceCreationStats
+	// namespaceDeletions are deletions of automanaged namespaces by the last DeleteAutomanagedNamespaces call.
+	namespaceDeletionsLock     sync.Mutex
+	namespaceDeletions         []NamespaceDeletion
+	slowNamespaceDeletionDelay time.Duration
+	slowNamespaceDeletionHook  SlowNamespaceDeletionHook
 	clientSets                 *MultiClientSet
 	dynamicClients             *MultiDynamicClient
 	clusterConfig              *config.ClusterConfig
+	bulkRateLimiter            flowcontrol.RateLimiter
+	// restConfig is used by streaming operations (exec and port-forward).
+	restConfig *rest.Config
+	// eventCollector is nil unless events are collected, see StartEventCollector.
+	eventCollector *EventCollector
+	podStores      *measurementutil.SharedPodStores
 }
 
 // NewFramework creates new framework based on given clusterConfig.
@@ -59,18 +139,46 @@ func NewRootFramework(clusterConfig *config.ClusterConfig, clientsNumber int) (*
 	return newFramework(clusterConfig, clientsNumber, kubeConfigPath)
 }
 
+// NewClusterFrameworks creates frameworks of additional clusters by their names, see config.ClusterConfig.Clusters.
+// For kubemark, the root cluster is available under config.KubemarkRootClusterName.
+func NewClusterFrameworks(clusterConfig *config.ClusterConfig, clientsNumber int) (map[string]*Framework, error) {
+	paths, err := clusterConfig.GetClusterKubeConfigPaths()
+	if err != nil {
+		return nil, err
+	}
+	if clusterConfig.Provider == "kubemark" && clusterConfig.KubemarkRootKubeConfigPath != "" {
+		paths[config.KubemarkRootClusterName] = clusterConfig.KubemarkRootKubeConfigPath
+	}
+	frameworks := make(map[string]*Framework, len(paths))
+	for name, kubeConfigPath := range paths {
+		if frameworks[name], err = newFramework(clusterConfig, clientsNumber, kubeConfigPath); err != nil {
+			return nil, fmt.Errorf("cluster %s framework creation error: %v", name, err)
+		}
+	}
+	return frameworks, nil
+}
+
 func newFramework(clusterConfig *config.ClusterConfig, clientsNumber int, kubeConfigPath string) (*Framework, error) {
 	var err error
 	f := Framework{
 		automanagedNamespaceCount: 0,
+		namespaceDeletionOptions:  NamespaceDeletionOptions{Parallelism: DefaultNamespaceDeletionParallelism},
 		clusterConfig:             clusterConfig,
+		bulkRateLimiter:           flowcontrol.NewTokenBucketRateLimiter(bulkOperationQPS, bulkOperationBurst),
+	}
+	if f.automanagedNamespaceNaming, err = NewNamespaceNaming(DefaultNamespaceNameTemplate, DefaultNamespacePrefix, 0, 1); err != nil {
+		return nil, err
 	}
-	if f.clientSets, err = NewMultiClientSet(kubeConfigPath, clientsNumber); err != nil {
+	if f.clientSets, err = NewMultiClientSet(kubeConfigPath, clientsNumber, clusterConfig.ClientConfig); err != nil {
 		return nil, fmt.Errorf("multi client set creation error: %v", err)
 	}
-	if f.dynamicClients, err = NewMultiDynamicClient(kubeConfigPath, clientsNumber); err != nil {
+	if f.dynamicClients, err = NewMultiDynamicClient(kubeConfigPath, clientsNumber, clusterConfig.ClientConfig); err != nil {
 		return nil, fmt.Errorf("multi dynamic client creation error: %v", err)
 	}
+	if f.restConfig, err = frameworkconfig.PrepareConfig(kubeConfigPath); err != nil {
+		return nil, fmt.Errorf("config prepare failed: %v", err)
+	}
+	f.podStores = measurementutil.NewSharedPodStores(f.clientSets.GetClient())
 	return &f, nil
 }
 
@@ -84,11 +192,81 @@ func (f *Framework) SetAutomanagedNamespacePrefix(nsName string) {
 	f.automanagedNamespacePrefix = nsName
 }
 
+// SetAutomanagedNamespaceNaming sets naming of automanaged namespaces.
+func (f *Framework) SetAutomanagedNamespaceNaming(naming *NamespaceNaming) {
+	f.automanagedNamespaceNaming = naming
+}
+
+// SetNamespaceDeletionOptions sets how automanaged namespaces are deleted.
+func (f *Framework) SetNamespaceDeletionOptions(options NamespaceDeletionOptions) {
+	f.namespaceDeletionOptions = options
+}
+
+// SetSlowNamespaceDeletionHook sets the hook called for namespaces that are still terminating
+// after delay since their delete call. Nil hook disables it.
+func (f *Framework) SetSlowNamespaceDeletionHook(delay time.Duration, hook SlowNamespaceDeletionHook) {
+	f.slowNamespaceDeletionDelay = delay
+	f.slowNamespaceDeletionHook = hook
+}
+
+// GetAutomanagedNamespaceName returns name of i-th (counting from 1) automanaged namespace.
+func (f *Framework) GetAutomanagedNamespaceName(i int) string {
+	return f.automanagedNamespaceNaming.Name(f.automanagedNamespacePrefix, i)
+}
+
 // GetClientSets returns clientSet clients.
 func (f *Framework) GetClientSets() *MultiClientSet {
 	return f.clientSets
 }
 
+// NewUnthrottledClient creates a client without client-side rate limiting, so that latencies
+// of API calls measured with it don't include time spent waiting for the rate limiter.
+func (f *Framework) NewUnthrottledClient() (clientset.Interface, error) {
+	conf := rest.CopyConfig(f.restConfig)
+	conf.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	return clientset.NewForConfig(conf)
+}
+
+// NewAPIServerClients creates clients of every apiserver instance, keyed by its address.
+// Requests of other clients are load balanced, so they reach an arbitrary instance.
+// Instances are found in endpoints of the kubernetes service.
+func (f *Framework) NewAPIServerClients() (map[string]clientset.Interface, error) {
+	endpoints, err := f.clientSets.GetClient().CoreV1().Endpoints(metav1.NamespaceDefault).Get("kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting apiserver endpoints error: %v", err)
+	}
+	clients := make(map[string]clientset.Interface)
+	for _, subset := range endpoints.Subsets {
+		for _, port := range subset.Ports {
+			if port.Name != "https" {
+				continue
+			}
+			for _, address := range subset.Addresses {
+				host := net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port)))
+				conf := rest.CopyConfig(f.restConfig)
+				conf.Host = "https://" + host
+				// Serving certificates of apiservers aren't necessarily valid for their addresses,
+				// but they are valid for the name of the kubernetes service.
+				if conf.TLSClientConfig.ServerName == "" {
+					conf.TLSClientConfig.ServerName = "kubernetes.default.svc"
+				}
+				if clients[host], err = clientset.NewForConfig(conf); err != nil {
+					return nil, fmt.Errorf("creating client of apiserver %s error: %v", host, err)
+				}
+			}
+		}
+	}
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no apiserver endpoints found")
+	}
+	return clients, nil
+}
+
+// GetPodStores returns pod stores shared by all users of the framework.
+func (f *Framework) GetPodStores() *measurementutil.SharedPodStores {
+	return f.podStores
+}
+
 // GetDynamicClients returns dynamic clients.
 func (f *Framework) GetDynamicClients() *MultiDynamicClient {
 	return f.dynamicClients
@@ -99,21 +277,111 @@ func (f *Framework) GetClusterConfig() *config.ClusterConfig {
 	return f.clusterConfig
 }
 
+// StartEventCollector starts collecting up to bufferSize latest events of the namespace
+// (all namespaces if empty), replacing the previous collector.
+func (f *Framework) StartEventCollector(namespace string, bufferSize int) error {
+	f.StopEventCollector()
+	collector, err := NewEventCollector(f.clientSets.GetClient(), namespace, bufferSize)
+	if err != nil {
+		return err
+	}
+	f.eventCollector = collector
+	return nil
+}
+
+// GetEventCollector returns collector of events, nil if events are not collected.
+func (f *Framework) GetEventCollector() *EventCollector {
+	return f.eventCollector
+}
+
+// StopEventCollector stops collecting events.
+func (f *Framework) StopEventCollector() {
+	if f.eventCollector != nil {
+		f.eventCollector.Stop()
+		f.eventCollector = nil
+	}
+}
+
 // CreateAutomanagedNamespaces creates automanged namespaces.
-func (f *Framework) CreateAutomanagedNamespaces(namespaceCount int) error {
+// Namespaces are created concurrently, creation of each of them is retried on transient errors.
+// If namespace template is not nil, namespaces are created according to it.
+// Once ctx is done, remaining namespaces are not created and ctx error is returned.
+func (f *Framework) CreateAutomanagedNamespaces(ctx context.Context, namespaceCount int, namespaceTemplate *NamespaceTemplate) error {
 	if f.automanagedNamespaceCount != 0 {
 		return fmt.Errorf("automanaged namespaces already created")
 	}
-	for i := 1; i <= namespaceCount; i++ {
-		name := fmt.Sprintf("%v-%d", f.automanagedNamespacePrefix, i)
-		if err := client.CreateNamespace(f.clientSets.GetClient(), name); err != nil {
-			return err
+	if namespaceCount <= 0 {
+		return nil
+	}
+	// Namespaces that failed to be created are deleted as well, deletion of nonexistent namespace is no-op.
+	f.automanagedNamespaceCount = namespaceCount
+	errList := errors.NewErrorList()
+	start := time.Now()
+	createNamespace := func(i int) {
+		name := f.GetAutomanagedNamespaceName(i + 1)
+		if namespaceTemplate == nil {
+			if err := client.CreateNamespace(f.clientSets.GetClient(), name, f.clusterConfig.RunID); err != nil {
+				errList.Append(fmt.Errorf("namespace %s creation error: %v", name, err))
+			}
+			return
+		}
+		if err := client.CreateNamespaceWithMetadata(f.clientSets.GetClient(), name, f.clusterConfig.RunID, namespaceTemplate.Labels, namespaceTemplate.Annotations); err != nil {
+			errList.Append(fmt.Errorf("namespace %s creation error: %v", name, err))
+			return
+		}
+		for _, obj := range namespaceTemplate.Objects {
+			// Objects are shared by all workers.
+			nsObj := obj.DeepCopy()
+			nsObj.SetNamespace(name)
+			if err := f.CreateObject(name, obj.GetName(), nsObj); err != nil {
+				errList.Append(fmt.Errorf("namespace %s: %s %s creation error: %v", name, obj.GetKind(), obj.GetName(), err))
+			}
 		}
-		f.automanagedNamespaceCount++
 	}
+	workers := automanagedNamespaceCreationWorkers
+	if namespaceCount < workers {
+		workers = namespaceCount
+	}
+	workqueue.ParallelizeUntil(ctx, workers, namespaceCount, createNamespace)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	duration := time.Since(start)
+	f.namespaceCreationStats = NamespaceCreationStats{
+		Count:      namespaceCount,
+		Duration:   duration,
+		Throughput: float64(namespaceCount) / duration.Seconds(),
+	}
+	if !errList.IsEmpty() {
+		return errList
+	}
+	logrus.Infof("Created %d automanaged namespaces in %v (%.2f namespaces/s)", namespaceCount, duration, f.namespaceCreationStats.Throughput)
 	return nil
 }
 
+// ResumeAutomanagedNamespaces makes the framework manage automanaged namespaces created
+// by an interrupted run of the test, instead of creating them. All of them have to exist.
+func (f *Framework) ResumeAutomanagedNamespaces(namespaceCount int) error {
+	if f.automanagedNamespaceCount != 0 {
+		return fmt.Errorf("automanaged namespaces already created")
+	}
+	namespaces, err := f.ListAutomanagedNamespaces()
+	if err != nil {
+		return err
+	}
+	if len(namespaces) != namespaceCount {
+		return fmt.Errorf("found %d automanaged namespaces, expected %d", len(namespaces), namespaceCount)
+	}
+	f.automanagedNamespaceCount = namespaceCount
+	logrus.Infof("Resumed %d automanaged namespaces", namespaceCount)
+	return nil
+}
+
+// GetNamespaceCreationStats returns statistics of automanaged namespaces creation.
+func (f *Framework) GetNamespaceCreationStats() NamespaceCreationStats {
+	return f.namespaceCreationStats
+}
+
 // ListAutomanagedNamespaces returns all existing automanged namespace names.
 func (f *Framework) ListAutomanagedNamespaces() ([]string, error) {
 	var automanagedNamespacesList []string
@@ -133,28 +401,98 @@ func (f *Framework) ListAutomanagedNamespaces() ([]string, error) {
 	return automanagedNamespacesList, nil
 }
 
+// GetAutomanagedNamespaceNames returns names of all automanaged namespaces created by the framework.
+func (f *Framework) GetAutomanagedNamespaceNames() []string {
+	names := make([]string, 0, f.automanagedNamespaceCount)
+	for i := 1; i <= f.automanagedNamespaceCount; i++ {
+		names = append(names, f.GetAutomanagedNamespaceName(i))
+	}
+	return names
+}
+
 // DeleteAutomanagedNamespaces deletes all automanged namespaces.
+// Deletions of namespaces are recorded, see GetNamespaceDeletions.
 func (f *Framework) DeleteAutomanagedNamespaces() *errors.ErrorList {
-	var wg wait.Group
+	f.namespaceDeletionsLock.Lock()
+	f.namespaceDeletions = nil
+	f.namespaceDeletionsLock.Unlock()
+	errList := f.deleteNamespaces(f.GetAutomanagedNamespaceNames())
+	f.automanagedNamespaceCount = 0
+	return errList
+}
+
+// GetNamespaceDeletions returns deletions of namespaces, that were removed
+// by the last DeleteAutomanagedNamespaces call.
+func (f *Framework) GetNamespaceDeletions() []NamespaceDeletion {
+	f.namespaceDeletionsLock.Lock()
+	defer f.namespaceDeletionsLock.Unlock()
+	deletions := make([]NamespaceDeletion, len(f.namespaceDeletions))
+	copy(deletions, f.namespaceDeletions)
+	return deletions
+}
+
+// ForgetAutomanagedNamespaces makes the framework forget automanaged namespaces without deleting them,
+// e.g. to keep them for debugging.
+func (f *Framework) ForgetAutomanagedNamespaces() {
+	f.automanagedNamespaceCount = 0
+}
+
+// deleteNamespaces deletes namespaces in batches and waits until they are gone.
+func (f *Framework) deleteNamespaces(names []string) *errors.ErrorList {
 	errList := errors.NewErrorList()
-	for i := 1; i <= f.automanagedNamespaceCount; i++ {
-		clientSet := f.clientSets.GetClient()
-		name := fmt.Sprintf("%v-%d", f.automanagedNamespacePrefix, i)
-		wg.Start(func() {
-			if err := client.DeleteNamespace(clientSet, name); err != nil {
+	batchSize := f.namespaceDeletionOptions.BatchSize
+	if batchSize <= 0 || batchSize > len(names) {
+		batchSize = len(names)
+	}
+	for start := 0; start < len(names); start += batchSize {
+		end := start + batchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		batch := names[start:end]
+		workers := f.namespaceDeletionOptions.Parallelism
+		if workers <= 0 || workers > len(batch) {
+			workers = len(batch)
+		}
+		deleted := make([]bool, len(batch))
+		starts := make([]time.Time, len(batch))
+		timers := make([]*time.Timer, len(batch))
+		workqueue.ParallelizeUntil(context.TODO(), workers, len(batch), func(i int) {
+			starts[i] = time.Now()
+			if err := client.DeleteNamespace(f.clientSets.GetClient(), batch[i]); err != nil {
 				errList.Append(err)
 				return
 			}
-			if err := client.WaitForDeleteNamespace(clientSet, name); err != nil {
+			deleted[i] = true
+			if hook := f.slowNamespaceDeletionHook; hook != nil {
+				namespace := batch[i]
+				timers[i] = time.AfterFunc(f.slowNamespaceDeletionDelay, func() { hook(namespace) })
+			}
+		})
+		workqueue.ParallelizeUntil(context.TODO(), workers, len(batch), func(i int) {
+			if !deleted[i] {
+				return
+			}
+			err := client.WaitForDeleteNamespace(f.clientSets.GetClient(), batch[i])
+			if timers[i] != nil {
+				timers[i].Stop()
+			}
+			if err != nil {
 				errList.Append(err)
+				return
 			}
+			f.recordNamespaceDeletion(NamespaceDeletion{Namespace: batch[i], Start: starts[i], End: time.Now()})
 		})
 	}
-	wg.Wait()
-	f.automanagedNamespaceCount = 0
 	return errList
 }
 
+func (f *Framework) recordNamespaceDeletion(deletion NamespaceDeletion) {
+	f.namespaceDeletionsLock.Lock()
+	defer f.namespaceDeletionsLock.Unlock()
+	f.namespaceDeletions = append(f.namespaceDeletions, deletion)
+}
+
 // CreateObject creates object base on given object description.
 func (f *Framework) CreateObject(namespace string, name string, obj *unstructured.Unstructured, options ...*client.ApiCallOptions) error {
 	return client.CreateObject(f.dynamicClients.GetClient(), namespace, name, obj, options...)
@@ -165,6 +503,11 @@ func (f *Framework) PatchObject(namespace string, name string, obj *unstructured
 	return client.PatchObject(f.dynamicClients.GetClient(), namespace, name, obj)
 }
 
+// UpdateObject replaces object with given name using given object description.
+func (f *Framework) UpdateObject(namespace string, name string, obj *unstructured.Unstructured, options ...*client.ApiCallOptions) error {
+	return client.UpdateObject(f.dynamicClients.GetClient(), namespace, name, obj, options...)
+}
+
 // DeleteObject deletes object with given name and group-version-kind.
 func (f *Framework) DeleteObject(gvk schema.GroupVersionKind, namespace string, name string, options ...*client.ApiCallOptions) error {
 	return client.DeleteObject(f.dynamicClients.GetClient(), gvk, namespace, name)
@@ -175,16 +518,61 @@ func (f *Framework) GetObject(gvk schema.GroupVersionKind, namespace string, nam
 	return client.GetObject(f.dynamicClients.GetClient(), gvk, namespace, name)
 }
 
+// ListObjects lists objects with given group-version-kind matching the label selector.
+func (f *Framework) ListObjects(gvk schema.GroupVersionKind, namespace string, labelSelector string, options ...*client.ApiCallOptions) ([]unstructured.Unstructured, error) {
+	return client.ListObjects(f.dynamicClients.GetClient(), f.bulkRateLimiter, gvk, namespace, labelSelector, options...)
+}
+
+// DeleteCollection deletes all objects with given group-version-kind matching the label selector,
+// with a single request if the resource supports it.
+func (f *Framework) DeleteCollection(gvk schema.GroupVersionKind, namespace string, labelSelector string, options ...*client.ApiCallOptions) error {
+	return client.DeleteCollection(f.dynamicClients.GetClient(), f.bulkRateLimiter, gvk, namespace, labelSelector, options...)
+}
+
+// ExecInPod executes command in the container of the pod and returns its standard output and standard error.
+func (f *Framework) ExecInPod(namespace, pod, container string, command []string) ([]byte, []byte, error) {
+	return client.ExecInPod(f.clientSets.GetClient(), f.restConfig, namespace, pod, container, command)
+}
+
+// GetPodLogs returns logs of the container of the pod.
+func (f *Framework) GetPodLogs(namespace, pod string, opts *v1.PodLogOptions, options ...*client.ApiCallOptions) ([]byte, error) {
+	return client.GetPodLogs(f.clientSets.GetClient(), namespace, pod, opts, options...)
+}
+
+// StreamPodLogs copies logs of the container of the pod to the writer until the stream ends.
+func (f *Framework) StreamPodLogs(namespace, pod string, opts *v1.PodLogOptions, w io.Writer) error {
+	return client.StreamPodLogs(f.clientSets.GetClient(), namespace, pod, opts, w)
+}
+
+// PortForward starts forwarding connections to a local port to the port of the pod.
+// Returned forwarder has to be closed.
+func (f *Framework) PortForward(namespace, pod string, port int) (*client.PortForwarder, error) {
+	return client.PortForward(f.clientSets.GetClient(), f.restConfig, namespace, pod, port)
+}
+
 // ApplyTemplatedManifests finds and applies all manifest template files matching the provided
 // manifestGlob pattern. It substitutes the template placeholders using the templateMapping map.
 func (f *Framework) ApplyTemplatedManifests(manifestGlob string, templateMapping map[string]interface{}, options ...*client.ApiCallOptions) error {
-	// TODO(mm4tt): Consider using the out-of-the-box "kubectl create -f".
 	manifestGlob = os.ExpandEnv(manifestGlob)
-	templateProvider := config.NewTemplateProvider(filepath.Dir(manifestGlob))
 	manifests, err := filepath.Glob(manifestGlob)
 	if err != nil {
 		return err
 	}
+	return f.applyTemplatedManifests(config.NewTemplateProvider(filepath.Dir(manifestGlob)), manifests, templateMapping, options...)
+}
+
+// ApplyTemplatedManifestsFS is like ApplyTemplatedManifests, but manifests are read from the filesystem,
+// e.g. embedded in the binary. manifestGlob is a slash-separated pattern relative to the root of the filesystem.
+func (f *Framework) ApplyTemplatedManifestsFS(fsys fs.FS, manifestGlob string, templateMapping map[string]interface{}, options ...*client.ApiCallOptions) error {
+	manifests, err := fs.Glob(fsys, manifestGlob)
+	if err != nil {
+		return err
+	}
+	return f.applyTemplatedManifests(config.NewTemplateProviderFS(fsys, path.Dir(manifestGlob)), manifests, templateMapping, options...)
+}
+
+func (f *Framework) applyTemplatedManifests(templateProvider *config.TemplateProvider, manifests []string, templateMapping map[string]interface{}, options ...*client.ApiCallOptions) error {
+	// TODO(mm4tt): Consider using the out-of-the-box "kubectl create -f".
 	for _, manifest := range manifests {
 		logrus.Infof("Applying %s\n", manifest)
 		obj, err := templateProvider.TemplateToObject(filepath.Base(manifest), templateMapping)
@@ -214,5 +602,9 @@ func (f *Framework) ApplyTemplatedManifests(manifestGlob string, templateMapping
 }
 
 func (f *Framework) isAutomanagedNamespace(name string) (bool, error) {
-	return regexp.MatchString(f.automanagedNamespacePrefix+"-[1-9][0-9]*", name)
+	pattern, err := f.automanagedNamespaceNaming.Pattern(f.automanagedNamespacePrefix)
+	if err != nil {
+		return false, err
+	}
+	return pattern.MatchString(name), nil
 }
@@ -22,7 +22,8 @@ import (
 
 	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
-	"k8s.io/perf-tests/clusterloader2/pkg/framework/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	frameworkconfig "k8s.io/perf-tests/clusterloader2/pkg/framework/config"
 )
 
 // MultiClientSet is a set of kubernetes clients.
@@ -33,15 +34,19 @@ type MultiClientSet struct {
 }
 
 // NewMultiClientSet creates new MultiClientSet for given kubeconfig and number.
-func NewMultiClientSet(kubeconfigPath string, number int) (*MultiClientSet, error) {
+// Clients are rate limited according to the client config.
+func NewMultiClientSet(kubeconfigPath string, number int, clientConfig config.ClientConfig) (*MultiClientSet, error) {
 	m := MultiClientSet{
 		clients: make([]clientset.Interface, number),
 	}
+	shared := frameworkconfig.NewSharedRateLimiter(clientConfig.TotalQPS, clientConfig.TotalBurst)
 	for i := 0; i < number; i++ {
-		conf, err := config.PrepareConfig(kubeconfigPath)
+		conf, err := frameworkconfig.PrepareConfig(kubeconfigPath)
 		if err != nil {
 			return nil, fmt.Errorf("config prepare failed: %v", err)
 		}
+		frameworkconfig.SetRateLimits(conf, clientConfig.QPS, clientConfig.Burst, shared)
+		frameworkconfig.SetEncoding(conf, clientConfig.ContentType == config.ClientContentTypeJSON, clientConfig.DisableCompression)
 		if number < 1 {
 			return nil, fmt.Errorf("incorrect clients number")
 		}
@@ -69,15 +74,19 @@ type MultiDynamicClient struct {
 }
 
 // NewMultiDynamicClient creates new MultiDynamicClient for given kubeconfig and number.
-func NewMultiDynamicClient(kubeconfigPath string, number int) (*MultiDynamicClient, error) {
+// Clients are rate limited according to the client config.
+func NewMultiDynamicClient(kubeconfigPath string, number int, clientConfig config.ClientConfig) (*MultiDynamicClient, error) {
 	m := MultiDynamicClient{
 		clients: make([]dynamic.Interface, number),
 	}
+	shared := frameworkconfig.NewSharedRateLimiter(clientConfig.TotalQPS, clientConfig.TotalBurst)
 	for i := 0; i < number; i++ {
-		conf, err := config.PrepareConfig(kubeconfigPath)
+		conf, err := frameworkconfig.PrepareConfig(kubeconfigPath)
 		if err != nil {
 			return nil, fmt.Errorf("config prepare failed: %v", err)
 		}
+		frameworkconfig.SetRateLimits(conf, clientConfig.QPS, clientConfig.Burst, shared)
+		frameworkconfig.SetEncoding(conf, clientConfig.ContentType == config.ClientContentTypeJSON, clientConfig.DisableCompression)
 		if number < 1 {
 			return nil, fmt.Errorf("incorrect clients number")
 		}
@@ -96,3 +105,8 @@ func (m *MultiDynamicClient) GetClient() dynamic.Interface {
 	m.current = (m.current + 1) % len(m.clients)
 	return m.clients[m.current]
 }
+
+// GetClientsNumber returns the number of clients in the set.
+func (m *MultiDynamicClient) GetClientsNumber() int {
+	return len(m.clients)
+}
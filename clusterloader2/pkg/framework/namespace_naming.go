@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	// DefaultNamespaceNameTemplate is the template of automanaged namespace names used by default.
+	DefaultNamespaceNameTemplate = "{{.Prefix}}-{{.Index}}"
+	// DefaultNamespacePrefix is the fixed part of automanaged namespace prefixes used by default.
+	DefaultNamespacePrefix = "test"
+
+	// NamespaceRandomSuffixLength is the length of the random part of automanaged namespace prefixes.
+	NamespaceRandomSuffixLength = 6
+	// randomSuffixPattern matches random parts of automanaged namespace prefixes of all tests.
+	randomSuffixPattern = "[a-z0-9]{6}"
+
+	// indexMarker replaces index while building a pattern matching names created from a template.
+	indexMarker = "INDEXMARKER"
+	// prefixMarker replaces prefix while building a pattern matching names created with any prefix.
+	prefixMarker = "PREFIXMARKER"
+	// suffixMarker replaces random suffix while building a pattern matching names created with any prefix.
+	suffixMarker = "SUFFIXMARKER"
+)
+
+// NamespaceNaming describes how automanaged namespaces are named.
+type NamespaceNaming struct {
+	template *template.Template
+	// prefix is the fixed part of automanaged namespace prefixes, followed by a random suffix.
+	prefix string
+	// indexWidth is the minimal width of the index, shorter indexes are padded with zeros.
+	indexWidth int
+	// startIndex is the index of the first automanaged namespace.
+	startIndex int
+}
+
+type namespaceNameData struct {
+	// Prefix is the automanaged namespace prefix, which contains random part unique for every test.
+	Prefix string
+	// RandomSuffix is the random part of the prefix. It's stable during the run, as resumed tests reuse their prefixes.
+	RandomSuffix string
+	// Index is the (possibly zero-padded) index of the namespace.
+	Index string
+}
+
+// NewNamespacePrefix returns a new automanaged namespace prefix, i.e. the fixed prefix followed by a random suffix.
+func NewNamespacePrefix(prefix string) string {
+	if prefix == "" {
+		prefix = DefaultNamespacePrefix
+	}
+	return prefix + "-" + util.RandomDNS1123String(NamespaceRandomSuffixLength)
+}
+
+// NewNamespaceNaming creates naming of automanaged namespaces. The name template is a go template
+// that can use {{.Prefix}} (automanaged namespace prefix, e.g. test-abcdef), {{.RandomSuffix}}
+// (its random part, e.g. abcdef) and {{.Index}}, which is required.
+// Automanaged namespace prefixes consist of the given prefix (e.g. test) and a random suffix.
+// Indexes start from startIndex and are zero-padded to indexWidth digits.
+func NewNamespaceNaming(nameTemplate, prefix string, indexWidth, startIndex int) (*NamespaceNaming, error) {
+	tmpl, err := template.New("namespace").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing namespace name template error: %v", err)
+	}
+	if indexWidth < 0 {
+		return nil, fmt.Errorf("negative namespace index width: %d", indexWidth)
+	}
+	if startIndex < 0 {
+		return nil, fmt.Errorf("negative namespace start index: %d", startIndex)
+	}
+	if prefix == "" {
+		prefix = DefaultNamespacePrefix
+	}
+	n := &NamespaceNaming{template: tmpl, prefix: prefix, indexWidth: indexWidth, startIndex: startIndex}
+	pattern, err := n.execute(n.nameData(prefix+"-abcdef", indexMarker))
+	if err != nil {
+		return nil, err
+	}
+	if strings.Count(pattern, indexMarker) != 1 {
+		return nil, fmt.Errorf("namespace name template %q should use {{.Index}} exactly once", nameTemplate)
+	}
+	sample := strings.Replace(pattern, indexMarker, n.formatIndex(1), 1)
+	if errs := validation.IsDNS1123Label(sample); len(errs) > 0 {
+		return nil, fmt.Errorf("namespace name template %q creates invalid names, e.g. %q: %s", nameTemplate, sample, strings.Join(errs, ", "))
+	}
+	return n, nil
+}
+
+// Name returns name of i-th (counting from 1) automanaged namespace.
+func (n *NamespaceNaming) Name(prefix string, i int) string {
+	name, err := n.execute(n.nameData(prefix, n.formatIndex(n.startIndex+i-1)))
+	if err != nil {
+		// The template has been already executed successfully with the same data types.
+		panic(err)
+	}
+	return name
+}
+
+// Pattern returns regular expression matching names of all automanaged namespaces with the given prefix.
+func (n *NamespaceNaming) Pattern(prefix string) (*regexp.Regexp, error) {
+	name, err := n.execute(n.nameData(prefix, indexMarker))
+	if err != nil {
+		return nil, err
+	}
+	pattern := strings.Replace(regexp.QuoteMeta(name), indexMarker, "[0-9]+", 1)
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// AnyPrefixPattern returns regular expression matching names of automanaged namespaces of all tests,
+// i.e. with any random suffix of the prefix.
+func (n *NamespaceNaming) AnyPrefixPattern() (*regexp.Regexp, error) {
+	name, err := n.execute(namespaceNameData{Prefix: prefixMarker, RandomSuffix: suffixMarker, Index: indexMarker})
+	if err != nil {
+		return nil, err
+	}
+	pattern := strings.Replace(regexp.QuoteMeta(name), indexMarker, "[0-9]+", 1)
+	pattern = strings.Replace(pattern, prefixMarker, regexp.QuoteMeta(n.prefix)+"-"+randomSuffixPattern, -1)
+	pattern = strings.Replace(pattern, suffixMarker, randomSuffixPattern, -1)
+	return regexp.Compile("^" + pattern + "$")
+}
+
+// nameData returns data of the name template for the automanaged namespace prefix.
+func (n *NamespaceNaming) nameData(prefix, index string) namespaceNameData {
+	return namespaceNameData{
+		Prefix:       prefix,
+		RandomSuffix: prefix[strings.LastIndex(prefix, "-")+1:],
+		Index:        index,
+	}
+}
+
+func (n *NamespaceNaming) formatIndex(index int) string {
+	return fmt.Sprintf("%0*d", n.indexWidth, index)
+}
+
+func (n *NamespaceNaming) execute(data namespaceNameData) (string, error) {
+	var name bytes.Buffer
+	if err := n.template.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("executing namespace name template error: %v", err)
+	}
+	return name.String(), nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceNaming(t *testing.T) {
+	cases := []struct {
+		name       string
+		template   string
+		indexWidth int
+		startIndex int
+		want       string
+		matches    []string
+		notMatches []string
+	}{
+		{
+			name:       "default",
+			template:   DefaultNamespaceNameTemplate,
+			startIndex: 1,
+			want:       "test-abc-2",
+			matches:    []string{"test-abc-1", "test-abc-100"},
+			notMatches: []string{"test-abc-", "test-abcd-1", "test-abc-1-x", "x-test-abc-1"},
+		},
+		{
+			name:       "padded index and start index",
+			template:   "load-{{.Index}}-{{.Prefix}}",
+			indexWidth: 4,
+			startIndex: 100,
+			want:       "load-0101-test-abc",
+			matches:    []string{"load-0001-test-abc"},
+			notMatches: []string{"load-0001-test-abd"},
+		},
+		{
+			name:       "random suffix",
+			template:   "load-{{.RandomSuffix}}-{{.Index}}",
+			startIndex: 1,
+			want:       "load-abc-2",
+			matches:    []string{"load-abc-1"},
+			notMatches: []string{"load-test-abc-1", "load-abd-1"},
+		},
+	}
+	for _, tc := range cases {
+		naming, err := NewNamespaceNaming(tc.template, DefaultNamespacePrefix, tc.indexWidth, tc.startIndex)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		assert.Equal(t, tc.want, naming.Name("test-abc", 2), tc.name)
+		pattern, err := naming.Pattern("test-abc")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		for _, name := range tc.matches {
+			assert.True(t, pattern.MatchString(name), "%s: %s should match", tc.name, name)
+		}
+		for _, name := range tc.notMatches {
+			assert.False(t, pattern.MatchString(name), "%s: %s shouldn't match", tc.name, name)
+		}
+	}
+}
+
+func TestNewNamespaceNamingErrors(t *testing.T) {
+	for _, template := range []string{"{{.Prefix}}", "{{.Index}}-{{.Index}}", "{{.Prefix}}_{{.Index}}", "{{.Unknown}}-{{.Index}}", "{{.Index"} {
+		_, err := NewNamespaceNaming(template, DefaultNamespacePrefix, 0, 1)
+		assert.Error(t, err, template)
+	}
+	_, err := NewNamespaceNaming(DefaultNamespaceNameTemplate, DefaultNamespacePrefix, -1, 1)
+	assert.Error(t, err)
+	_, err = NewNamespaceNaming(DefaultNamespaceNameTemplate, "Load_", 0, 1)
+	assert.Error(t, err)
+}
+
+func TestNamespaceNamingAnyPrefixPattern(t *testing.T) {
+	cases := []struct {
+		template   string
+		prefix     string
+		matches    []string
+		notMatches []string
+	}{
+		{
+			template:   "load-{{.Prefix}}-{{.Index}}",
+			prefix:     DefaultNamespacePrefix,
+			matches:    []string{"load-test-abc123-1", "load-test-zzzzzz-42"},
+			notMatches: []string{"load-test-abc-1", "load-test-abc123-", "test-abc123-1", "load-test-ABC123-1"},
+		},
+		{
+			template:   "{{.Prefix}}-{{.Index}}",
+			prefix:     "perf-x",
+			matches:    []string{"perf-x-abc123-1"},
+			notMatches: []string{"test-abc123-1", "perf-y-abc123-1"},
+		},
+		{
+			template:   "load-{{.RandomSuffix}}-{{.Index}}",
+			prefix:     DefaultNamespacePrefix,
+			matches:    []string{"load-abc123-1"},
+			notMatches: []string{"load-test-abc123-1", "load-abc-1"},
+		},
+	}
+	for _, tc := range cases {
+		naming, err := NewNamespaceNaming(tc.template, tc.prefix, 0, 1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.template, err)
+		}
+		pattern, err := naming.AnyPrefixPattern()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.template, err)
+		}
+		// Names created by the naming are matched.
+		assert.True(t, pattern.MatchString(naming.Name(NewNamespacePrefix(tc.prefix), 1)), tc.template)
+		for _, name := range tc.matches {
+			assert.True(t, pattern.MatchString(name), "%s: %s should match", tc.template, name)
+		}
+		for _, name := range tc.notMatches {
+			assert.False(t, pattern.MatchString(name), "%s: %s shouldn't match", tc.template, name)
+		}
+	}
+}
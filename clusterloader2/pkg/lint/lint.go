@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/api"
+)
+
+// listBasedMeasurements are measurements that list and watch all matching objects.
+var listBasedMeasurements = map[string]bool{
+	"WaitForRunningPods":           true,
+	"WaitForControlledPodsRunning": true,
+	"WaitForBoundPVCs":             true,
+	"WaitForAvailablePVs":          true,
+}
+
+// Issue describes a single anti-pattern found in the test config.
+type Issue struct {
+	// Rule is a short name of the violated rule.
+	Rule string
+	// Message explains the problem and how to fix it.
+	Message string
+}
+
+// String returns string representation of the issue.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Rule, i.Message)
+}
+
+// Lint checks the test config for known scalability anti-patterns.
+// maxQPS is the total QPS limit of clients used for creating objects,
+// tuning sets requesting higher QPS are reported.
+func Lint(conf *api.Config, maxQPS float64) []Issue {
+	var issues []Issue
+	issues = append(issues, lintTuningSets(conf, maxQPS)...)
+	issues = append(issues, lintMeasurements(conf)...)
+	issues = append(issues, lintTeardown(conf)...)
+	return issues
+}
+
+func lintTuningSets(conf *api.Config, maxQPS float64) []Issue {
+	var issues []Issue
+	for _, ts := range conf.TuningSets {
+		var qps float64
+		switch {
+		case ts.QpsLoad != nil:
+			qps = ts.QpsLoad.Qps
+		case ts.RandomizedLoad != nil:
+			qps = ts.RandomizedLoad.AverageQps
+		case ts.SteppedLoad != nil && ts.SteppedLoad.StepDelay > 0:
+			qps = float64(ts.SteppedLoad.BurstSize) / time.Duration(ts.SteppedLoad.StepDelay).Seconds()
+		}
+		if maxQPS > 0 && qps > maxQPS {
+			issues = append(issues, Issue{
+				Rule:    "tuning-set-qps",
+				Message: fmt.Sprintf("tuning set %q requests %.1f qps, but clients are limited to %.1f qps; the load will be throttled on the client side, lower the qps", ts.Name, qps, maxQPS),
+			})
+		}
+	}
+	return issues
+}
+
+func lintMeasurements(conf *api.Config) []Issue {
+	var issues []Issue
+	// started maps method and identifier to the step in which the measurement was started.
+	started := make(map[string]int)
+	for i := range conf.Steps {
+		for _, m := range conf.Steps[i].Measurements {
+			key := fmt.Sprintf("%s (%s)", m.Method, m.Identifier)
+			action, _ := m.Params["action"].(string)
+			switch action {
+			case "start":
+				started[key] = i
+			case "gather":
+				delete(started, key)
+			}
+			if !listBasedMeasurements[m.Method] || action == "gather" {
+				continue
+			}
+			namespace, _ := m.Params["namespace"].(string)
+			labelSelector, _ := m.Params["labelSelector"].(string)
+			if namespace == "" && labelSelector == "" {
+				issues = append(issues, Issue{
+					Rule:    "unbounded-list",
+					Message: fmt.Sprintf("step %d: measurement %s lists objects from all namespaces without a label selector; set labelSelector or namespace", i, key),
+				})
+			}
+		}
+	}
+	keys := make([]string, 0, len(started))
+	for key := range started {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		issues = append(issues, Issue{
+			Rule:    "missing-gather",
+			Message: fmt.Sprintf("step %d: measurement %s is started but never gathered; add a step with action: gather", started[key], key),
+		})
+	}
+	return issues
+}
+
+// lintTeardown reports objects outside of automanaged namespaces that are not deleted by the test.
+// Objects in automanaged namespaces are removed together with their namespaces.
+func lintTeardown(conf *api.Config) []Issue {
+	var issues []Issue
+	replicas := make(map[string]int32)
+	var keys []string
+	for i := range conf.Steps {
+		for _, phase := range conf.Steps[i].Phases {
+			if phase.NamespaceRange != nil && phase.NamespaceRange.Basename == nil {
+				continue
+			}
+			scope := "cluster scope"
+			if phase.NamespaceRange != nil {
+				scope = fmt.Sprintf("namespaces %s-[%d-%d]", *phase.NamespaceRange.Basename, phase.NamespaceRange.Min, phase.NamespaceRange.Max)
+			}
+			for _, object := range phase.ObjectBundle {
+				key := fmt.Sprintf("%s in %s", object.Basename, scope)
+				if _, ok := replicas[key]; !ok {
+					keys = append(keys, key)
+				}
+				replicas[key] = phase.ReplicasPerNamespace
+			}
+		}
+	}
+	for _, key := range keys {
+		if replicas[key] > 0 {
+			issues = append(issues, Issue{
+				Rule:    "missing-teardown",
+				Message: fmt.Sprintf("objects %s are not deleted at the end of the test; add a phase with replicasPerNamespace: 0", key),
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"testing"
+
+	"k8s.io/perf-tests/clusterloader2/api"
+)
+
+func TestLint(t *testing.T) {
+	basename := "custom"
+	cases := []struct {
+		name  string
+		conf  *api.Config
+		rules []string
+	}{
+		{
+			name: "clean",
+			conf: &api.Config{
+				TuningSets: []api.TuningSet{{Name: "Uniform", QpsLoad: &api.QpsLoad{Qps: 10}}},
+				Steps: []api.Step{
+					{Measurements: []api.Measurement{{Method: "WaitForControlledPodsRunning", Identifier: "Deployments", Params: map[string]interface{}{"action": "start", "labelSelector": "group = load"}}}},
+					{Phases: []api.Phase{{NamespaceRange: &api.NamespaceRange{Min: 1, Max: 10}, ReplicasPerNamespace: 1, ObjectBundle: []api.Object{{Basename: "deployment"}}}}},
+					{Measurements: []api.Measurement{{Method: "WaitForControlledPodsRunning", Identifier: "Deployments", Params: map[string]interface{}{"action": "gather"}}}},
+				},
+			},
+		},
+		{
+			name: "tuning set qps",
+			conf: &api.Config{
+				TuningSets: []api.TuningSet{{Name: "Fast", RandomizedLoad: &api.RandomizedLoad{AverageQps: 500}}},
+			},
+			rules: []string{"tuning-set-qps"},
+		},
+		{
+			name: "unbounded list and missing gather",
+			conf: &api.Config{
+				Steps: []api.Step{
+					{Measurements: []api.Measurement{{Method: "WaitForRunningPods", Params: map[string]interface{}{"desiredPodCount": 10}}}},
+					{Measurements: []api.Measurement{{Method: "PodStartupLatency", Identifier: "PodStartupLatency", Params: map[string]interface{}{"action": "start"}}}},
+				},
+			},
+			rules: []string{"unbounded-list", "missing-gather"},
+		},
+		{
+			name: "missing teardown",
+			conf: &api.Config{
+				Steps: []api.Step{
+					{Phases: []api.Phase{
+						{ReplicasPerNamespace: 1, ObjectBundle: []api.Object{{Basename: "priority-class"}}},
+						{NamespaceRange: &api.NamespaceRange{Min: 1, Max: 1, Basename: &basename}, ReplicasPerNamespace: 2, ObjectBundle: []api.Object{{Basename: "configmap"}}},
+					}},
+					{Phases: []api.Phase{{ReplicasPerNamespace: 0, ObjectBundle: []api.Object{{Basename: "priority-class"}}}}},
+				},
+			},
+			rules: []string{"missing-teardown"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			issues := Lint(tc.conf, 100)
+			if len(issues) != len(tc.rules) {
+				t.Fatalf("got issues %v, want rules %v", issues, tc.rules)
+			}
+			for i := range issues {
+				if issues[i].Rule != tc.rules[i] {
+					t.Errorf("issue %d: got rule %s, want %s", i, issues[i].Rule, tc.rules[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"sync"
+	"time"
+)
+
+// APICall is an object operation performed by the test executor.
+type APICall struct {
+	// Operation is one of create, patch and delete.
+	Operation string
+	Kind      string
+	Namespace string
+	Start     time.Time
+	// Latency is the duration of the call, including retries.
+	Latency time.Duration
+	Failed  bool
+}
+
+// APICallObserver is notified about API calls performed by the test executor.
+type APICallObserver interface {
+	ObserveAPICall(call APICall)
+}
+
+// APICallObservers is a thread-safe set of observers of API calls.
+// Calls are not stored, so only observers registered at the time of the call are notified.
+type APICallObservers struct {
+	lock      sync.RWMutex
+	observers map[APICallObserver]bool
+}
+
+// NewAPICallObservers creates an empty set of observers.
+func NewAPICallObservers() *APICallObservers {
+	return &APICallObservers{observers: make(map[APICallObserver]bool)}
+}
+
+// Add registers the observer.
+func (o *APICallObservers) Add(observer APICallObserver) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.observers[observer] = true
+}
+
+// Remove unregisters the observer.
+func (o *APICallObservers) Remove(observer APICallObserver) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.observers, observer)
+}
+
+// Observe notifies all registered observers about the call. It is no-op for nil observers.
+func (o *APICallObservers) Observe(call APICall) {
+	if o == nil {
+		return
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	for observer := range o.observers {
+		observer.ObserveAPICall(call)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"fmt"
+	"os"
+)
+
+// Capability is a feature of the tested cluster (or of its monitoring setup)
+// that a measurement may require.
+type Capability string
+
+const (
+	// Prometheus means that the prometheus server is set up in the cluster.
+	Prometheus Capability = "Prometheus"
+	// SSH means that cluster machines can be accessed over SSH.
+	SSH Capability = "SSH"
+	// MasterAccess means that master machines are accessible, i.e. the control plane is not managed by the provider.
+	MasterAccess Capability = "MasterAccess"
+	// RealNodes means that nodes run real containers, i.e. the cluster is not a kubemark cluster.
+	RealNodes Capability = "RealNodes"
+	// KubeletMetrics means that the prometheus server scrapes kubelets.
+	KubeletMetrics Capability = "KubeletMetrics"
+	// KubeProxyMetrics means that the prometheus server scrapes kube-proxies.
+	KubeProxyMetrics Capability = "KubeProxyMetrics"
+	// CNIMetrics means that the prometheus server scrapes CNI agents.
+	CNIMetrics Capability = "CNIMetrics"
+	// IngressControllerMetrics means that the prometheus server scrapes the ingress controller.
+	IngressControllerMetrics Capability = "IngressControllerMetrics"
+	// KubeStateMetrics means that kube-state-metrics is deployed and scraped by the prometheus server.
+	KubeStateMetrics Capability = "KubeStateMetrics"
+)
+
+// CapabilityRequirer is implemented by measurements that can be executed only in clusters
+// having specific capabilities. If any of the required capabilities is missing, the measurement
+// is skipped and the reason is recorded in SkippedMeasurements summary.
+type CapabilityRequirer interface {
+	RequiredCapabilities(config *MeasurementConfig) []Capability
+}
+
+// sshProviders are providers for which SSH keys are known, see measurement/util/ssh.go.
+var sshProviders = map[string]bool{
+	"gce": true, "gke": true, "kubemark": true, "aws": true, "eks": true, "local": true, "vsphere": true, "skeleton": true,
+}
+
+// managedControlPlaneProviders are providers that don't expose master machines.
+var managedControlPlaneProviders = map[string]bool{
+	"gke": true, "eks": true, "aks": true,
+}
+
+// capabilityCheckers return the reason why a capability is missing, or an empty string if it's present.
+var capabilityCheckers = map[Capability]func(config *MeasurementConfig) string{
+	Prometheus: func(config *MeasurementConfig) string {
+		if config.PrometheusFramework == nil {
+			return "prometheus server is disabled"
+		}
+		return ""
+	},
+	SSH: func(config *MeasurementConfig) string {
+		if !sshProviders[config.CloudProvider] && os.Getenv("KUBE_SSH_KEY_PATH") == "" {
+			return fmt.Sprintf("SSH is not supported for provider %q and KUBE_SSH_KEY_PATH is not set", config.CloudProvider)
+		}
+		return ""
+	},
+	MasterAccess: func(config *MeasurementConfig) string {
+		if managedControlPlaneProviders[config.CloudProvider] {
+			return fmt.Sprintf("master machines are not accessible in %q", config.CloudProvider)
+		}
+		if config.ClusterLoaderConfig != nil && len(config.ClusterLoaderConfig.ClusterConfig.MasterIPs) == 0 {
+			return "master IP is unknown"
+		}
+		return ""
+	},
+	RealNodes: func(config *MeasurementConfig) string {
+		if config.CloudProvider == "kubemark" {
+			return "kubemark nodes don't run containers"
+		}
+		return ""
+	},
+	KubeletMetrics: func(config *MeasurementConfig) string {
+		if config.ClusterLoaderConfig == nil || !config.ClusterLoaderConfig.PrometheusConfig.ScrapeKubelets {
+			return "kubelets are not scraped (--prometheus-scrape-kubelets)"
+		}
+		return ""
+	},
+	KubeProxyMetrics: func(config *MeasurementConfig) string {
+		if config.ClusterLoaderConfig == nil || !config.ClusterLoaderConfig.PrometheusConfig.ScrapeKubeProxy {
+			return "kube-proxies are not scraped (--prometheus-scrape-kube-proxy)"
+		}
+		return ""
+	},
+	CNIMetrics: func(config *MeasurementConfig) string {
+		if config.ClusterLoaderConfig == nil || config.ClusterLoaderConfig.PrometheusConfig.ScrapeCNI == "" {
+			return "CNI agents are not scraped (--prometheus-scrape-cni)"
+		}
+		return ""
+	},
+	IngressControllerMetrics: func(config *MeasurementConfig) string {
+		if config.ClusterLoaderConfig == nil || config.ClusterLoaderConfig.PrometheusConfig.ScrapeIngressController == "" {
+			return "ingress controller is not scraped (--prometheus-scrape-ingress-controller)"
+		}
+		return ""
+	},
+	KubeStateMetrics: func(config *MeasurementConfig) string {
+		if config.ClusterLoaderConfig == nil || !config.ClusterLoaderConfig.PrometheusConfig.EnableKubeStateMetrics {
+			return "kube-state-metrics is not deployed (--enable-kube-state-metrics)"
+		}
+		return ""
+	},
+}
+
+// MissingCapability returns the reason why the first missing capability is not available,
+// or an empty string if the cluster has all of them.
+func MissingCapability(config *MeasurementConfig, capabilities []Capability) string {
+	for _, capability := range capabilities {
+		check, ok := capabilityCheckers[capability]
+		if !ok {
+			return fmt.Sprintf("unknown capability %s", capability)
+		}
+		if reason := check(config); reason != "" {
+			return fmt.Sprintf("missing %s capability: %s", capability, reason)
+		}
+	}
+	return ""
+}
+
+// SkippedMeasurement records a measurement that wasn't executed.
+type SkippedMeasurement struct {
+	Method     string `json:"method"`
+	Identifier string `json:"identifier"`
+	Reason     string `json:"reason"`
+}
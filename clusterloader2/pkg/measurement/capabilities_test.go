@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+type capabilityRequiringMeasurement struct {
+	executions int
+}
+
+func (c *capabilityRequiringMeasurement) RequiredCapabilities(config *MeasurementConfig) []Capability {
+	return []Capability{RealNodes, Prometheus}
+}
+
+func (c *capabilityRequiringMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) {
+	c.executions++
+	return nil, nil
+}
+
+func (c *capabilityRequiringMeasurement) Dispose() {}
+
+func (c *capabilityRequiringMeasurement) String() string {
+	return "CapabilityRequiringMeasurement"
+}
+
+func TestMissingCapability(t *testing.T) {
+	clusterLoaderConfig := &config.ClusterLoaderConfig{}
+	clusterLoaderConfig.ClusterConfig.MasterIPs = []string{"10.0.0.1"}
+	clusterLoaderConfig.PrometheusConfig.ScrapeKubelets = true
+	cases := []struct {
+		provider     string
+		capabilities []Capability
+		missing      bool
+	}{
+		{provider: "gce", capabilities: []Capability{SSH, MasterAccess, RealNodes, KubeletMetrics}},
+		{provider: "gke", capabilities: []Capability{MasterAccess}, missing: true},
+		{provider: "kubemark", capabilities: []Capability{SSH, RealNodes}, missing: true},
+		{provider: "gce", capabilities: []Capability{Prometheus}, missing: true},
+		{provider: "gce", capabilities: []Capability{KubeProxyMetrics}, missing: true},
+		{provider: "gce", capabilities: []Capability{"Unknown"}, missing: true},
+	}
+	for _, tc := range cases {
+		config := &MeasurementConfig{CloudProvider: tc.provider, ClusterLoaderConfig: clusterLoaderConfig}
+		reason := MissingCapability(config, tc.capabilities)
+		assert.Equal(t, tc.missing, reason != "", "%s %v: %s", tc.provider, tc.capabilities, reason)
+	}
+}
+
+func TestManagerSkipsMeasurement(t *testing.T) {
+	instance := &capabilityRequiringMeasurement{}
+	if err := Register(instance.String(), func() Measurement { return instance }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clusterLoaderConfig := &config.ClusterLoaderConfig{}
+	clusterLoaderConfig.ClusterConfig.Provider = "kubemark"
+	manager := CreateMeasurementManager(context.Background(), nil, nil, nil, nil, clusterLoaderConfig, nil)
+	for _, action := range []string{"start", "gather"} {
+		if err := manager.Execute(instance.String(), "test", map[string]interface{}{"action": action}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 0, instance.executions)
+
+	summaries := manager.GetSummaries()
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, "SkippedMeasurements", summaries[0].SummaryName())
+		var skipped []SkippedMeasurement
+		if err := json.Unmarshal([]byte(summaries[0].SummaryContent()), &skipped); err != nil {
+			t.Fatalf("error while decoding summary: %v", err)
+		}
+		assert.Equal(t, []SkippedMeasurement{{
+			Method:     "CapabilityRequiringMeasurement",
+			Identifier: "test",
+			Reason:     "missing RealNodes capability: kubemark nodes don't run containers",
+		}}, skipped)
+	}
+
+	results := manager.GetResults()
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "missing RealNodes capability: kubemark nodes don't run containers", results[0].SkipReason)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	apiAvailabilityName = "APIAvailability"
+
+	defaultAvailabilityPollInterval = time.Second
+	defaultAvailabilityProbeTimeout = 5 * time.Second
+)
+
+func init() {
+	if err := measurement.Register(apiAvailabilityName, createAPIAvailabilityMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", apiAvailabilityName, err)
+	}
+}
+
+func createAPIAvailabilityMeasurement() measurement.Measurement {
+	return &apiAvailabilityMeasurement{}
+}
+
+type apiAvailabilityMeasurement struct {
+	isRunning bool
+	stopCh    chan struct{}
+	startTime time.Time
+	lock      sync.Mutex
+	probes    []availabilityProbe
+}
+
+type availabilityProbe struct {
+	timestamp time.Time
+	available bool
+}
+
+// unavailabilityPeriod is a period in which all probes of the apiserver failed.
+// It ends with the first successful probe.
+type unavailabilityPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type apiAvailabilitySummary struct {
+	WindowStart            time.Time              `json:"windowStart"`
+	WindowEnd              time.Time              `json:"windowEnd"`
+	Probes                 int                    `json:"probes"`
+	FailedProbes           int                    `json:"failedProbes"`
+	AvailabilityPercentage float64                `json:"availabilityPercentage"`
+	LongestUnavailability  float64                `json:"longestUnavailabilitySeconds"`
+	Unavailability         []unavailabilityPeriod `json:"unavailability"`
+}
+
+// Execute supports two actions:
+// - start - starts probing /healthz endpoint of the apiserver every pollInterval.
+// - gather - stops probing and reports availability of the apiserver.
+// The availability can be scored in a window between markers given with startMarker
+// and endMarker params, e.g. during a cluster upgrade. If threshold param is set and
+// the availability percentage is lower, an error will be returned.
+func (a *apiAvailabilityMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		pollInterval, err := util.GetDurationOrDefault(config.Params, "pollInterval", defaultAvailabilityPollInterval)
+		if err != nil {
+			return nil, err
+		}
+		probeTimeout, err := util.GetDurationOrDefault(config.Params, "probeTimeout", defaultAvailabilityProbeTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return nil, a.start(config.ClusterFramework.GetClientSets().GetClient(), pollInterval, probeTimeout)
+	case "gather":
+		threshold, err := util.GetFloat64OrDefault(config.Params, "threshold", 0)
+		if err != nil {
+			return nil, err
+		}
+		return a.gather(config, threshold)
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (a *apiAvailabilityMeasurement) Dispose() {
+	a.stop()
+}
+
+// String returns string representation of this measurement.
+func (*apiAvailabilityMeasurement) String() string {
+	return apiAvailabilityName
+}
+
+// Description returns a short description of the measurement.
+func (*apiAvailabilityMeasurement) Description() string {
+	return "Probes /healthz endpoint of the apiserver and reports its availability."
+}
+
+func (a *apiAvailabilityMeasurement) start(c clientset.Interface, pollInterval, probeTimeout time.Duration) error {
+	if a.isRunning {
+		logrus.Infof("%s: measurement already running", a)
+		return nil
+	}
+	a.isRunning = true
+	a.stopCh = make(chan struct{})
+	a.startTime = time.Now()
+	a.probes = nil
+	logrus.Infof("%s: starting probing apiserver every %v", a, pollInterval)
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stopCh:
+				return
+			case now := <-ticker.C:
+				_, err := c.CoreV1().RESTClient().Get().AbsPath("/healthz").Timeout(probeTimeout).DoRaw()
+				if err != nil {
+					logrus.Warningf("%s: apiserver unavailable: %v", a, err)
+				}
+				a.lock.Lock()
+				a.probes = append(a.probes, availabilityProbe{timestamp: now, available: err == nil})
+				a.lock.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (a *apiAvailabilityMeasurement) stop() {
+	if a.isRunning {
+		close(a.stopCh)
+		a.isRunning = false
+	}
+}
+
+func (a *apiAvailabilityMeasurement) gather(config *measurement.MeasurementConfig, threshold float64) ([]measurement.Summary, error) {
+	if !a.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", apiAvailabilityName)
+	}
+	a.stop()
+	start, end, err := measurement.GetWindow(config, a.startTime, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s: gathering data", a)
+
+	summary := a.buildSummary(start, end)
+	logrus.Infof("%s: availability %.2f%% (%d of %d probes failed), longest unavailability: %.0fs",
+		a, summary.AvailabilityPercentage, summary.FailedProbes, summary.Probes, summary.LongestUnavailability)
+	var violation error
+	if threshold > 0 && summary.AvailabilityPercentage < threshold {
+		violation = errors.NewMetricViolationError("api availability",
+			fmt.Sprintf("availability %.2f%% is lower than %.2f%%", summary.AvailabilityPercentage, threshold))
+	}
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(apiAvailabilityName, "json", content)}, violation
+}
+
+// Checkpoint reports availability of the apiserver since the start, probing continues.
+func (a *apiAvailabilityMeasurement) Checkpoint(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	content, err := util.PrettyPrintJSON(a.buildSummary(a.startTime, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(apiAvailabilityName, "json", content)}, nil
+}
+
+func (a *apiAvailabilityMeasurement) buildSummary(start, end time.Time) *apiAvailabilitySummary {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return buildAPIAvailabilitySummary(a.probes, start, end)
+}
+
+// buildAPIAvailabilitySummary scores probes between start and end.
+func buildAPIAvailabilitySummary(probes []availabilityProbe, start, end time.Time) *apiAvailabilitySummary {
+	summary := &apiAvailabilitySummary{
+		WindowStart:            start,
+		WindowEnd:              end,
+		AvailabilityPercentage: 100,
+		Unavailability:         []unavailabilityPeriod{},
+	}
+	var period *unavailabilityPeriod
+	closePeriod := func(periodEnd time.Time) {
+		period.End = periodEnd
+		if seconds := period.End.Sub(period.Start).Seconds(); seconds > summary.LongestUnavailability {
+			summary.LongestUnavailability = seconds
+		}
+		summary.Unavailability = append(summary.Unavailability, *period)
+		period = nil
+	}
+	for _, probe := range probes {
+		if probe.timestamp.Before(start) || probe.timestamp.After(end) {
+			continue
+		}
+		summary.Probes++
+		if probe.available {
+			if period != nil {
+				closePeriod(probe.timestamp)
+			}
+			continue
+		}
+		summary.FailedProbes++
+		if period == nil {
+			period = &unavailabilityPeriod{Start: probe.timestamp}
+		}
+		period.End = probe.timestamp
+	}
+	if period != nil {
+		closePeriod(period.End)
+	}
+	if summary.Probes > 0 {
+		summary.AvailabilityPercentage = 100 * float64(summary.Probes-summary.FailedProbes) / float64(summary.Probes)
+	}
+	return summary
+}
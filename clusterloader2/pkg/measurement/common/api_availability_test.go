@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAPIAvailabilitySummary(t *testing.T) {
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return t0.Add(time.Duration(seconds) * time.Second) }
+	var probes []availabilityProbe
+	for i, available := range []bool{true, false, false, true, true, false, true, false, false} {
+		probes = append(probes, availabilityProbe{timestamp: at(i), available: available})
+	}
+
+	summary := buildAPIAvailabilitySummary(probes, at(1), at(7))
+	assert.Equal(t, 7, summary.Probes)
+	assert.Equal(t, 4, summary.FailedProbes)
+	assert.InDelta(t, 100*3.0/7, summary.AvailabilityPercentage, 1e-9)
+	assert.Equal(t, 2.0, summary.LongestUnavailability)
+	assert.Equal(t, []unavailabilityPeriod{
+		{Start: at(1), End: at(3)},
+		{Start: at(5), End: at(6)},
+		{Start: at(7), End: at(7)},
+	}, summary.Unavailability)
+
+	summary = buildAPIAvailabilitySummary(nil, at(0), at(1))
+	assert.Equal(t, 100.0, summary.AvailabilityPercentage)
+	assert.Empty(t, summary.Unavailability)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTarGz(t *testing.T) {
+	entries := []archiveEntry{
+		{path: "etcd/master-1_heap_20190101T000000Z.pprof", data: []byte("heap")},
+		{path: "kube-apiserver/apiserver_profile_20190101T000000Z.pprof", data: []byte("cpu")},
+	}
+	bundle, err := createTarGz(entries)
+	assert.NoError(t, err)
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(bundle))
+	assert.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	got := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(tarReader)
+		assert.NoError(t, err)
+		got[header.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"etcd/master-1_heap_20190101T000000Z.pprof":               "heap",
+		"kube-apiserver/apiserver_profile_20190101T000000Z.pprof": "cpu",
+	}, got)
+}
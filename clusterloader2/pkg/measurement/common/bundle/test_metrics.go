@@ -242,6 +242,11 @@ func (*testMetrics) String() string {
 	return testMetricsMeasurementName
 }
 
+// Description returns a short description of the measurement.
+func (*testMetrics) Description() string {
+	return "Bundle of measurements collected by the default tests: resource usage, etcd, scheduler and system pod metrics and profiles."
+}
+
 func createConfig(config *measurement.MeasurementConfig, overrides map[string]interface{}) *measurement.MeasurementConfig {
 	params := make(map[string]interface{})
 	for k, v := range config.Params {
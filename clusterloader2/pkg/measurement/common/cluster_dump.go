@@ -0,0 +1,401 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	clusterDumpName = "ClusterDump"
+
+	defaultDumpMaxLogBytes       = 1 << 20
+	defaultDumpMaxBytes          = 100 << 20
+	defaultDumpMaxEvents         = 10000
+	defaultDumpKubeletLogNodes   = 5
+	clusterDumpEventsListLimit   = 500
+	clusterDumpIndexFileName     = "index.json"
+	clusterDumpEventsFileName    = "events.json"
+	clusterDumpPodLogsDirName    = "pods"
+	clusterDumpKubeletLogDirName = "kubelet"
+)
+
+func init() {
+	if err := measurement.Register(clusterDumpName, createClusterDumpMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", clusterDumpName, err)
+	}
+}
+
+func createClusterDumpMeasurement() measurement.Measurement {
+	return &clusterDumpMeasurement{}
+}
+
+type clusterDumpMeasurement struct {
+	startTime time.Time
+}
+
+// clusterDumpIndex describes content of the dump.
+type clusterDumpIndex struct {
+	Since time.Time `json:"since"`
+	// Files lists collected files with their sizes in bytes.
+	Files map[string]int `json:"files"`
+	// Skipped lists files that weren't collected because of the size cap or errors, with reasons.
+	Skipped map[string]string `json:"skipped"`
+	// Truncated is set if some files weren't collected, or were collected only partially, because of the size cap.
+	Truncated bool `json:"truncated"`
+}
+
+type dumpedEvent struct {
+	Namespace      string      `json:"namespace"`
+	Object         string      `json:"object"`
+	Type           string      `json:"type"`
+	Reason         string      `json:"reason"`
+	Message        string      `json:"message"`
+	Count          int32       `json:"count"`
+	Source         string      `json:"source"`
+	FirstTimestamp metav1.Time `json:"firstTimestamp"`
+	LastTimestamp  metav1.Time `json:"lastTimestamp"`
+}
+
+// clusterDump accumulates files of the dump up to the size cap.
+type clusterDump struct {
+	maxBytes int
+	size     int
+	entries  []archiveEntry
+	index    *clusterDumpIndex
+}
+
+// add adds the file to the dump, unless it would exceed the size cap.
+func (d *clusterDump) add(path string, data []byte) {
+	if d.size+len(data) > d.maxBytes {
+		d.truncate(path)
+		return
+	}
+	d.size += len(data)
+	d.entries = append(d.entries, archiveEntry{path: path, data: data})
+	d.index.Files[path] = len(data)
+}
+
+func (d *clusterDump) skip(path, reason string) {
+	d.index.Skipped[path] = reason
+}
+
+// truncate records that the file wasn't collected because of the size cap.
+func (d *clusterDump) truncate(path string) {
+	d.index.Truncated = true
+	d.skip(path, fmt.Sprintf("size cap of %d bytes exceeded", d.maxBytes))
+}
+
+// limit returns how many bytes of the file at most should be fetched, so that the dump
+// doesn't exceed the size cap. False is returned and the file is recorded as truncated
+// if the size cap has been reached already, so that the file isn't fetched at all.
+func (d *clusterDump) limit(path string, maxFileBytes int64) (int64, bool) {
+	remaining := int64(d.maxBytes - d.size)
+	if remaining <= 0 {
+		d.truncate(path)
+		return 0, false
+	}
+	if remaining < maxFileBytes {
+		// The file may be cut, its size is checked once it's fetched.
+		d.index.Truncated = true
+		return remaining, true
+	}
+	return maxFileBytes, true
+}
+
+// Execute supports two actions:
+// - start - records the beginning of the dumped period.
+// - gather - collects logs of kube-system pods, kubelet journals of nodes and events of all
+// namespaces into a single tar.gz archive.
+// Logs and events since start (or all of them, if the measurement wasn't started) are collected.
+// Pods can be filtered with podSelector param, events with eventNamespace and eventTypes params.
+// Logs of each container and kubelet are limited to maxLogBytes, number of events to maxEvents
+// and size of the whole dump to maxBytes - once it's reached, remaining logs aren't fetched and the dump
+// is marked as truncated in its index. Kubelet journals are collected over SSH from at most
+// kubeletLogNodes nodes, not ready nodes first, if SSH is available. If onlyOnViolation param
+// is set, the dump is collected only if an SLO violation has been detected by the test.
+func (c *clusterDumpMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		c.startTime = time.Now()
+		return nil, nil
+	case "gather":
+		return c.gather(config)
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (*clusterDumpMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (*clusterDumpMeasurement) String() string {
+	return clusterDumpName
+}
+
+// Description returns a short description of the measurement.
+func (*clusterDumpMeasurement) Description() string {
+	return "Collects logs of system pods, kubelet journals and events into a single archive."
+}
+
+func (c *clusterDumpMeasurement) gather(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	onlyOnViolation, err := util.GetBoolOrDefault(config.Params, "onlyOnViolation", false)
+	if err != nil {
+		return nil, err
+	}
+	if onlyOnViolation && (config.HasViolations == nil || !config.HasViolations()) {
+		logrus.Infof("%s: no violations detected, skipping dump", c)
+		return nil, nil
+	}
+	podSelector, err := util.GetStringOrDefault(config.Params, "podSelector", "")
+	if err != nil {
+		return nil, err
+	}
+	eventNamespace, err := util.GetStringOrDefault(config.Params, "eventNamespace", metav1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err := util.GetStringOrDefault(config.Params, "eventTypes", "")
+	if err != nil {
+		return nil, err
+	}
+	maxLogBytes, err := util.GetIntOrDefault(config.Params, "maxLogBytes", defaultDumpMaxLogBytes)
+	if err != nil {
+		return nil, err
+	}
+	maxEvents, err := util.GetIntOrDefault(config.Params, "maxEvents", defaultDumpMaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	maxBytes, err := util.GetIntOrDefault(config.Params, "maxBytes", defaultDumpMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	kubeletLogNodes, err := util.GetIntOrDefault(config.Params, "kubeletLogNodes", defaultDumpKubeletLogNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet := config.ClusterFramework.GetClientSets().GetClient()
+	dump := &clusterDump{
+		maxBytes: maxBytes,
+		index:    &clusterDumpIndex{Since: c.startTime, Files: map[string]int{}, Skipped: map[string]string{}},
+	}
+	logrus.Infof("%s: collecting dump since %v", c, c.startTime)
+	// Events are collected first, as they are the most useful for postmortems.
+	if err := c.dumpEvents(clientSet, dump, eventNamespace, splitList(eventTypes), maxEvents); err != nil {
+		dump.skip(clusterDumpEventsFileName, err.Error())
+	}
+	if err := c.dumpPodLogs(clientSet, dump, podSelector, int64(maxLogBytes)); err != nil {
+		dump.skip(clusterDumpPodLogsDirName, err.Error())
+	}
+	if kubeletLogNodes > 0 {
+		if reason := measurement.MissingCapability(config, []measurement.Capability{measurement.SSH}); reason != "" {
+			dump.skip(clusterDumpKubeletLogDirName, reason)
+		} else if err := c.dumpKubeletLogs(clientSet, dump, config.ClusterFramework.GetClusterConfig().Provider, kubeletLogNodes, maxLogBytes); err != nil {
+			dump.skip(clusterDumpKubeletLogDirName, err.Error())
+		}
+	}
+
+	index, err := util.PrettyPrintJSON(dump.index)
+	if err != nil {
+		return nil, err
+	}
+	// Index is always included, so that it's known what is missing.
+	dump.entries = append(dump.entries, archiveEntry{path: clusterDumpIndexFileName, data: []byte(index)})
+	archive, err := createTarGz(dump.entries)
+	if err != nil {
+		return nil, fmt.Errorf("archiving dump error: %v", err)
+	}
+	logrus.Infof("%s: collected %d files (%d bytes), skipped %d", c, len(dump.index.Files), dump.size, len(dump.index.Skipped))
+	return []measurement.Summary{measurement.CreateSummary(clusterDumpName, "tar.gz", string(archive))}, nil
+}
+
+func (c *clusterDumpMeasurement) dumpEvents(clientSet clientset.Interface, dump *clusterDump, namespace string, types []string, maxEvents int) error {
+	events := []dumpedEvent{}
+	options := metav1.ListOptions{Limit: clusterDumpEventsListLimit}
+	for {
+		var list *corev1.EventList
+		err := client.RetryWithExponentialBackOff(client.RetryFunction(func() error {
+			var err error
+			list, err = clientSet.CoreV1().Events(namespace).List(options)
+			return err
+		}))
+		if err != nil {
+			return fmt.Errorf("listing events error: %v", err)
+		}
+		for i := range list.Items {
+			if event := &list.Items[i]; includeEvent(event, c.startTime, types) {
+				events = append(events, newDumpedEvent(event))
+			}
+		}
+		if options.Continue = list.Continue; options.Continue == "" {
+			break
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(&events[j].LastTimestamp)
+	})
+	if len(events) > maxEvents {
+		logrus.Warningf("%s: %d events found, dumping the latest %d", c, len(events), maxEvents)
+		events = events[len(events)-maxEvents:]
+	}
+	content, err := util.PrettyPrintJSON(events)
+	if err != nil {
+		return err
+	}
+	dump.add(clusterDumpEventsFileName, []byte(content))
+	return nil
+}
+
+// includeEvent returns true if the event was last seen after since and is of one of types.
+// All types are included if types are empty.
+func includeEvent(event *corev1.Event, since time.Time, types []string) bool {
+	lastTimestamp := event.LastTimestamp.Time
+	if lastTimestamp.IsZero() {
+		lastTimestamp = event.EventTime.Time
+	}
+	if lastTimestamp.Before(since) {
+		return false
+	}
+	if len(types) == 0 {
+		return true
+	}
+	for _, eventType := range types {
+		if event.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newDumpedEvent(event *corev1.Event) dumpedEvent {
+	return dumpedEvent{
+		Namespace:      event.Namespace,
+		Object:         fmt.Sprintf("%s/%s", event.InvolvedObject.Kind, event.InvolvedObject.Name),
+		Type:           event.Type,
+		Reason:         event.Reason,
+		Message:        event.Message,
+		Count:          event.Count,
+		Source:         strings.TrimSpace(event.Source.Component + " " + event.Source.Host),
+		FirstTimestamp: event.FirstTimestamp,
+		LastTimestamp:  event.LastTimestamp,
+	}
+}
+
+func (c *clusterDumpMeasurement) dumpPodLogs(clientSet clientset.Interface, dump *clusterDump, selector string, maxLogBytes int64) error {
+	pods, err := client.ListPodsWithOptions(clientSet, metav1.NamespaceSystem, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("listing pods error: %v", err)
+	}
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			path := fmt.Sprintf("%s/%s_%s.log", clusterDumpPodLogsDirName, pod.Name, container.Name)
+			limitBytes, ok := dump.limit(path, maxLogBytes)
+			if !ok {
+				continue
+			}
+			options := &corev1.PodLogOptions{Container: container.Name, LimitBytes: &limitBytes}
+			if !c.startTime.IsZero() {
+				options.SinceTime = &metav1.Time{Time: c.startTime}
+			}
+			data, err := clientSet.CoreV1().Pods(metav1.NamespaceSystem).GetLogs(pod.Name, options).DoRaw()
+			if err != nil {
+				dump.skip(path, err.Error())
+				continue
+			}
+			dump.add(path, data)
+		}
+	}
+	return nil
+}
+
+func (c *clusterDumpMeasurement) dumpKubeletLogs(clientSet clientset.Interface, dump *clusterDump, provider string, maxNodes, maxLogBytes int) error {
+	nodes, err := client.ListNodes(clientSet)
+	if err != nil {
+		return fmt.Errorf("listing nodes error: %v", err)
+	}
+	since := ""
+	if !c.startTime.IsZero() {
+		since = fmt.Sprintf(" --since '%s'", c.startTime.UTC().Format("2006-01-02 15:04:05 UTC"))
+	}
+	for _, node := range selectDumpedNodes(nodes, maxNodes) {
+		path := fmt.Sprintf("%s/%s.log", clusterDumpKubeletLogDirName, node.Name)
+		host := nodeAddress(&node)
+		if host == "" {
+			dump.skip(path, "node has no address")
+			continue
+		}
+		limitBytes, ok := dump.limit(path, int64(maxLogBytes))
+		if !ok {
+			continue
+		}
+		command := fmt.Sprintf("sudo journalctl -u kubelet --no-pager%s | tail -c %d", since, limitBytes)
+		sshResult, err := measurementutil.SSH(command, host+":22", provider)
+		if err != nil {
+			dump.skip(path, err.Error())
+			continue
+		}
+		dump.add(path, []byte(sshResult.Stdout))
+	}
+	return nil
+}
+
+// selectDumpedNodes returns at most maxNodes nodes, not ready nodes first.
+func selectDumpedNodes(nodes []corev1.Node, maxNodes int) []corev1.Node {
+	selected := append([]corev1.Node(nil), nodes...)
+	sort.SliceStable(selected, func(i, j int) bool {
+		readyI, readyJ := util.IsNodeReady(&selected[i]), util.IsNodeReady(&selected[j])
+		if readyI != readyJ {
+			return !readyI
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	if len(selected) > maxNodes {
+		selected = selected[:maxNodes]
+	}
+	return selected
+}
+
+// nodeAddress returns external address of the node or, if it doesn't have one, internal address.
+func nodeAddress(node *corev1.Node) string {
+	for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType && address.Address != "" {
+				return address.Address
+			}
+		}
+	}
+	return ""
+}
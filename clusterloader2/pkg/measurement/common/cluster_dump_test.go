@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIncludeEvent(t *testing.T) {
+	since := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	warning := &corev1.Event{Type: corev1.EventTypeWarning, LastTimestamp: metav1.NewTime(since.Add(time.Minute))}
+	old := &corev1.Event{Type: corev1.EventTypeWarning, LastTimestamp: metav1.NewTime(since.Add(-time.Minute))}
+	eventTime := &corev1.Event{Type: corev1.EventTypeNormal, EventTime: metav1.NewMicroTime(since.Add(time.Minute))}
+
+	assert.True(t, includeEvent(warning, since, nil))
+	assert.False(t, includeEvent(old, since, nil))
+	assert.True(t, includeEvent(eventTime, since, nil))
+	assert.False(t, includeEvent(eventTime, since, []string{corev1.EventTypeWarning}))
+	assert.True(t, includeEvent(warning, since, []string{corev1.EventTypeWarning}))
+}
+
+func TestSelectDumpedNodes(t *testing.T) {
+	node := func(name string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	nodes := []corev1.Node{
+		node("node-c", corev1.ConditionTrue),
+		node("node-b", corev1.ConditionFalse),
+		node("node-a", corev1.ConditionTrue),
+	}
+	var names []string
+	for _, n := range selectDumpedNodes(nodes, 2) {
+		names = append(names, n.Name)
+	}
+	assert.Equal(t, []string{"node-b", "node-a"}, names)
+}
+
+func TestClusterDumpSizeCap(t *testing.T) {
+	dump := &clusterDump{
+		maxBytes: 10,
+		index:    &clusterDumpIndex{Files: map[string]int{}, Skipped: map[string]string{}},
+	}
+	dump.add("a", []byte("123456"))
+	dump.add("b", []byte("123456"))
+	dump.add("c", []byte("1234"))
+	assert.Equal(t, map[string]int{"a": 6, "c": 4}, dump.index.Files)
+	assert.Contains(t, dump.index.Skipped, "b")
+	assert.True(t, dump.index.Truncated)
+	assert.Equal(t, 10, dump.size)
+}
+
+func TestClusterDumpLimit(t *testing.T) {
+	dump := &clusterDump{
+		maxBytes: 10,
+		index:    &clusterDumpIndex{Files: map[string]int{}, Skipped: map[string]string{}},
+	}
+	limit, ok := dump.limit("a", 6)
+	assert.True(t, ok)
+	assert.Equal(t, int64(6), limit)
+	assert.False(t, dump.index.Truncated)
+	dump.add("a", []byte("123456"))
+
+	limit, ok = dump.limit("b", 6)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), limit)
+	assert.True(t, dump.index.Truncated)
+	dump.add("b", []byte("1234"))
+
+	_, ok = dump.limit("c", 6)
+	assert.False(t, ok)
+	assert.Contains(t, dump.index.Skipped, "c")
+	assert.NotContains(t, dump.index.Files, "c")
+}
@@ -54,6 +54,15 @@ type etcdMetricsMeasurement struct {
 	metrics   *etcdMetrics
 }
 
+// RequiredCapabilities returns capabilities required by the measurement.
+// Etcd metrics are read over SSH, from the master unless host param is provided.
+func (e *etcdMetricsMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if _, ok := config.Params["host"]; ok {
+		return []measurement.Capability{measurement.SSH}
+	}
+	return []measurement.Capability{measurement.SSH, measurement.MasterAccess}
+}
+
 // Execute supports two actions:
 // - start - Starts collecting etcd metrics.
 // - gather - Gathers and prints etcd metrics summary.
@@ -0,0 +1,377 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	externalServiceLatencyName = "ExternalServiceLatency"
+
+	defaultExternalServicePollInterval   = time.Second
+	defaultExternalServiceProbeTimeout   = 5 * time.Second
+	defaultExternalServiceAddressTimeout = 10 * time.Minute
+	externalServiceAddressInterval       = 5 * time.Second
+)
+
+func init() {
+	if err := measurement.Register(externalServiceLatencyName, createExternalServiceLatencyMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", externalServiceLatencyName, err)
+	}
+}
+
+func createExternalServiceLatencyMeasurement() measurement.Measurement {
+	return &externalServiceLatencyMeasurement{}
+}
+
+// externalServiceLatencyMeasurement probes a NodePort or LoadBalancer service from the test driver,
+// i.e. from outside of the cluster.
+type externalServiceLatencyMeasurement struct {
+	isRunning bool
+	stopCh    chan struct{}
+	lock      sync.Mutex
+	probes    []externalServiceProbe
+}
+
+type externalServiceProbe struct {
+	latency time.Duration
+	failed  bool
+}
+
+func (p externalServiceProbe) GetLatency() time.Duration {
+	return p.latency
+}
+
+// Execute supports two actions:
+// - start - waits for the address of the service and starts probing it every pollInterval.
+// - gather - stops probing and reports latency and error ratio of probes.
+// The service is given with namespace, serviceName and port (the first port by default) params.
+// With scheme http (default) the probe is an HTTP request to path, with tcp it's a TCP connect.
+// Nodes of NodePort services are probed round-robin. If threshold or maxErrorRatio params
+// are set and exceeded, an error will be returned.
+func (e *externalServiceLatencyMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		return nil, e.start(config)
+	case "gather":
+		threshold, err := util.GetDurationOrDefault(config.Params, "threshold", 0)
+		if err != nil {
+			return nil, err
+		}
+		maxErrorRatio, err := util.GetFloat64OrDefault(config.Params, "maxErrorRatio", 0)
+		if err != nil {
+			return nil, err
+		}
+		return e.gather(threshold, maxErrorRatio)
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (e *externalServiceLatencyMeasurement) Dispose() {
+	e.stop()
+}
+
+// String returns string representation of this measurement.
+func (*externalServiceLatencyMeasurement) String() string {
+	return externalServiceLatencyName
+}
+
+// Description returns a short description of the measurement.
+func (*externalServiceLatencyMeasurement) Description() string {
+	return "Probes a service from outside of the cluster and reports latency and error ratio."
+}
+
+func (e *externalServiceLatencyMeasurement) start(config *measurement.MeasurementConfig) error {
+	if e.isRunning {
+		logrus.Infof("%s: measurement already running", e)
+		return nil
+	}
+	namespace, err := util.GetString(config.Params, "namespace")
+	if err != nil {
+		return err
+	}
+	serviceName, err := util.GetString(config.Params, "serviceName")
+	if err != nil {
+		return err
+	}
+	port, err := util.GetIntOrDefault(config.Params, "port", 0)
+	if err != nil {
+		return err
+	}
+	scheme, err := util.GetStringOrDefault(config.Params, "scheme", "http")
+	if err != nil {
+		return err
+	}
+	path, err := util.GetStringOrDefault(config.Params, "path", "/")
+	if err != nil {
+		return err
+	}
+	pollInterval, err := util.GetDurationOrDefault(config.Params, "pollInterval", defaultExternalServicePollInterval)
+	if err != nil {
+		return err
+	}
+	probeTimeout, err := util.GetDurationOrDefault(config.Params, "probeTimeout", defaultExternalServiceProbeTimeout)
+	if err != nil {
+		return err
+	}
+	addressTimeout, err := util.GetDurationOrDefault(config.Params, "addressTimeout", defaultExternalServiceAddressTimeout)
+	if err != nil {
+		return err
+	}
+	probe, err := newExternalServiceProbeFunc(scheme, path, probeTimeout)
+	if err != nil {
+		return err
+	}
+	addresses, err := waitForServiceAddresses(config.ClusterFramework.GetClientSets().GetClient(), namespace, serviceName, port, addressTimeout)
+	if err != nil {
+		return err
+	}
+
+	e.isRunning = true
+	e.stopCh = make(chan struct{})
+	e.probes = nil
+	logrus.Infof("%s: starting probing %s/%s at %v every %v", e, namespace, serviceName, addresses, pollInterval)
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-e.stopCh:
+				return
+			case <-ticker.C:
+				address := addresses[i%len(addresses)]
+				startTime := time.Now()
+				err := probe(address)
+				latency := time.Since(startTime)
+				if err != nil {
+					logrus.Warningf("%s: probing %s failed: %v", e, address, err)
+				}
+				e.lock.Lock()
+				e.probes = append(e.probes, externalServiceProbe{latency: latency, failed: err != nil})
+				e.lock.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *externalServiceLatencyMeasurement) stop() {
+	if e.isRunning {
+		close(e.stopCh)
+		e.isRunning = false
+	}
+}
+
+func (e *externalServiceLatencyMeasurement) gather(threshold time.Duration, maxErrorRatio float64) ([]measurement.Summary, error) {
+	if !e.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", externalServiceLatencyName)
+	}
+	e.stop()
+	return e.summarize(threshold, maxErrorRatio)
+}
+
+// Checkpoint reports probes sent since the start, probing continues.
+func (e *externalServiceLatencyMeasurement) Checkpoint(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	threshold, err := util.GetDurationOrDefault(config.Params, "threshold", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxErrorRatio, err := util.GetFloat64OrDefault(config.Params, "maxErrorRatio", 0)
+	if err != nil {
+		return nil, err
+	}
+	return e.summarize(threshold, maxErrorRatio)
+}
+
+func (e *externalServiceLatencyMeasurement) summarize(threshold time.Duration, maxErrorRatio float64) ([]measurement.Summary, error) {
+	e.lock.Lock()
+	latency, errorRatio := scoreExternalServiceProbes(e.probes)
+	probes := len(e.probes)
+	e.lock.Unlock()
+
+	logrus.Infof("%s: got %v, error ratio %.4f (%d probes)", e, latency, errorRatio, probes)
+	var violation error
+	if threshold > 0 {
+		if err := latency.VerifyThreshold(threshold); err != nil {
+			violation = errors.NewMetricViolationError(externalServiceLatencyName, err.Error())
+		}
+	}
+	if maxErrorRatio > 0 && errorRatio > maxErrorRatio {
+		violation = errors.NewMetricViolationError(externalServiceLatencyName,
+			fmt.Sprintf("error ratio %.4f is higher than %.4f", errorRatio, maxErrorRatio))
+	}
+	content, err := util.PrettyPrintJSON(&measurementutil.PerfData{
+		Version: "v1",
+		DataItems: []measurementutil.DataItem{
+			latency.ToPerfData(externalServiceLatencyName),
+			{
+				Data:   map[string]float64{"Ratio": errorRatio},
+				Unit:   "ratio",
+				Labels: map[string]string{"Metric": externalServiceLatencyName + "Errors"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(externalServiceLatencyName, "json", content)}, violation
+}
+
+// scoreExternalServiceProbes returns latency of successful probes and ratio of failed ones.
+func scoreExternalServiceProbes(probes []externalServiceProbe) (measurementutil.LatencyMetric, float64) {
+	var latencies measurementutil.LatencySlice
+	for _, probe := range probes {
+		if !probe.failed {
+			latencies = append(latencies, probe)
+		}
+	}
+	sort.Sort(latencies)
+	errorRatio := 0.0
+	if len(probes) > 0 {
+		errorRatio = float64(len(probes)-len(latencies)) / float64(len(probes))
+	}
+	return measurementutil.NewLatencyMetric(latencies), errorRatio
+}
+
+// waitForServiceAddresses waits until the service is reachable from outside of the cluster,
+// i.e. the load balancer is provisioned, and returns its addresses.
+func waitForServiceAddresses(c clientset.Interface, namespace, name string, port int, timeout time.Duration) ([]string, error) {
+	var addresses []string
+	err := wait.PollImmediate(externalServiceAddressInterval, timeout, func() (bool, error) {
+		service, err := c.CoreV1().Services(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		var nodes []corev1.Node
+		if service.Spec.Type == corev1.ServiceTypeNodePort {
+			if nodes, err = util.GetSchedulableUntainedNodes(c); err != nil {
+				return false, err
+			}
+		}
+		addresses, err = getServiceAddresses(service, port, nodes)
+		return len(addresses) > 0, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for address of service %s/%s error: %v", namespace, name, err)
+	}
+	return addresses, nil
+}
+
+// getServiceAddresses returns addresses (host:port) the service is reachable at from outside
+// of the cluster: ingress points of LoadBalancer service or external IPs of nodes (internal
+// if nodes have none) of NodePort service. Port is the port of the service, the first one if 0.
+func getServiceAddresses(service *corev1.Service, port int, nodes []corev1.Node) ([]string, error) {
+	var servicePort *corev1.ServicePort
+	for i := range service.Spec.Ports {
+		if port == 0 || int(service.Spec.Ports[i].Port) == port {
+			servicePort = &service.Spec.Ports[i]
+			break
+		}
+	}
+	if servicePort == nil {
+		return nil, fmt.Errorf("service %s/%s has no port %d", service.Namespace, service.Name, port)
+	}
+	var addresses []string
+	switch service.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			host := ingress.IP
+			if host == "" {
+				host = ingress.Hostname
+			}
+			addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(int(servicePort.Port))))
+		}
+	case corev1.ServiceTypeNodePort:
+		for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+			for i := range nodes {
+				for _, address := range nodes[i].Status.Addresses {
+					if address.Type == addressType {
+						addresses = append(addresses, net.JoinHostPort(address.Address, strconv.Itoa(int(servicePort.NodePort))))
+					}
+				}
+			}
+			if len(addresses) > 0 {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("service %s/%s is of type %s, expected %s or %s", service.Namespace, service.Name,
+			service.Spec.Type, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer)
+	}
+	return addresses, nil
+}
+
+// newExternalServiceProbeFunc returns a function sending a single probe to the address.
+func newExternalServiceProbeFunc(scheme, path string, timeout time.Duration) (func(address string) error, error) {
+	switch scheme {
+	case "tcp":
+		return func(address string) error {
+			conn, err := net.DialTimeout("tcp", address, timeout)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, nil
+	case "http":
+		// Connections are not reused, so that every probe is load balanced.
+		client := &http.Client{
+			Transport: &http.Transport{DisableKeepAlives: true},
+			Timeout:   timeout,
+		}
+		return func(address string) error {
+			resp, err := client.Get("http://" + address + path)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+				return err
+			}
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown scheme %q", scheme)
+	}
+}
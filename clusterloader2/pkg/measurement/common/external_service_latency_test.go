@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetServiceAddresses(t *testing.T) {
+	ports := []corev1.ServicePort{{Port: 80, NodePort: 30080}, {Port: 443, NodePort: 30443}}
+	nodes := []corev1.Node{
+		{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}, {Type: corev1.NodeExternalIP, Address: "35.0.0.1"}}}},
+		{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}}}},
+	}
+	testCases := []struct {
+		name    string
+		service corev1.Service
+		port    int
+		nodes   []corev1.Node
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "node port",
+			service: corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: ports}},
+			port:    443,
+			nodes:   nodes,
+			want:    []string{"35.0.0.1:30443"},
+		},
+		{
+			name:    "node port without external IPs",
+			service: corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: ports}},
+			nodes:   nodes[1:],
+			want:    []string{"10.0.0.2:30080"},
+		},
+		{
+			name:    "pending load balancer",
+			service: corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: ports}},
+		},
+		{
+			name: "load balancer",
+			service: corev1.Service{
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: ports},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+					Ingress: []corev1.LoadBalancerIngress{{IP: "35.0.0.100"}, {Hostname: "lb.example.com"}},
+				}},
+			},
+			want: []string{"35.0.0.100:80", "lb.example.com:80"},
+		},
+		{
+			name:    "unknown port",
+			service: corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: ports}},
+			port:    8080,
+			wantErr: true,
+		},
+		{
+			name:    "cluster IP",
+			service: corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, Ports: ports}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addresses, err := getServiceAddresses(&tc.service, tc.port, tc.nodes)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, addresses)
+		})
+	}
+}
+
+func TestScoreExternalServiceProbes(t *testing.T) {
+	latency, errorRatio := scoreExternalServiceProbes(nil)
+	assert.Equal(t, time.Duration(0), latency.Perc99)
+	assert.Equal(t, 0.0, errorRatio)
+
+	var probes []externalServiceProbe
+	for i := 100; i > 0; i-- {
+		probes = append(probes, externalServiceProbe{latency: time.Duration(i) * time.Millisecond})
+	}
+	probes = append(probes, externalServiceProbe{latency: 5 * time.Second, failed: true})
+	latency, errorRatio = scoreExternalServiceProbes(probes)
+	assert.True(t, latency.Perc99 <= 100*time.Millisecond && latency.Perc99 >= 99*time.Millisecond, "perc99 %v", latency.Perc99)
+	assert.InDelta(t, 1.0/101, errorRatio, 1e-9)
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/kubernetes/test/e2e/framework/metrics"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/informer"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/runtimeobjects"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	garbageCollectorLatencyName             = "GarbageCollectorLatency"
+	garbageCollectorMetricsName             = "GarbageCollectorMetrics"
+	defaultGarbageCollectorLatencyThreshold = 30 * time.Second
+	defaultGarbageCollectorTimeout          = 10 * time.Minute
+	defaultGarbageCollectorDeleteWorkers    = 10
+	garbageCollectorCheckInterval           = time.Second
+	garbageCollectorMetricsPrefix           = "garbage_collector_"
+	ownerUIDIndex                           = "ownerUID"
+
+	ownerDeletePhase        = "owner_delete"
+	dependentsGonePhase     = "dependents_gone"
+	cascadeDeleteTransition = "cascade_delete"
+)
+
+func init() {
+	if err := measurement.Register(garbageCollectorLatencyName, createGarbageCollectorLatencyMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", garbageCollectorLatencyName, err)
+	}
+}
+
+func createGarbageCollectorLatencyMeasurement() measurement.Measurement {
+	return &garbageCollectorLatencyMeasurement{
+		selector: measurementutil.NewObjectSelector(),
+	}
+}
+
+type garbageCollectorLatencyMeasurement struct {
+	selector *measurementutil.ObjectSelector
+}
+
+// gcOwner describes single controlling object deleted by the measurement.
+type gcOwner struct {
+	key       string
+	name      string
+	namespace string
+	// uids are UIDs of the owner and of its intermediate dependents owning pods (e.g. replica sets of a deployment).
+	uids []types.UID
+}
+
+// Execute deletes all controlling objects of the given kind that satisfy selectors
+// and measures the time until all of their pods are removed by the garbage collector.
+// Controlling objects can be specified by field and/or label selectors.
+// If namespace is not passed by parameter, all-namespace scope is assumed.
+// Garbage collector workqueue metrics are grabbed from the controller manager
+// once all dependents are gone.
+func (g *garbageCollectorLatencyMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	apiVersion, err := util.GetString(config.Params, "apiVersion")
+	if err != nil {
+		return nil, err
+	}
+	kind, err := util.GetString(config.Params, "kind")
+	if err != nil {
+		return nil, err
+	}
+	if err = g.selector.Parse(config.Params); err != nil {
+		return nil, err
+	}
+	threshold, err := util.GetDurationOrDefault(config.Params, "threshold", defaultGarbageCollectorLatencyThreshold)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := util.GetDurationOrDefault(config.Params, "timeout", defaultGarbageCollectorTimeout)
+	if err != nil {
+		return nil, err
+	}
+	workers, err := util.GetIntOrDefault(config.Params, "deleteWorkers", defaultGarbageCollectorDeleteWorkers)
+	if err != nil {
+		return nil, err
+	}
+	gatherMetrics, err := util.GetBoolOrDefault(config.Params, "gatherGCMetrics", true)
+	if err != nil {
+		return nil, err
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	transitionTimes, err := g.deleteAndWait(config.ClusterFramework, gv.WithKind(kind), workers, timeout)
+	if err != nil {
+		return nil, err
+	}
+	latency := transitionTimes.CalculateTransitionsLatency(map[string]measurementutil.Transition{
+		cascadeDeleteTransition: {
+			From:      ownerDeletePhase,
+			To:        dependentsGonePhase,
+			Threshold: threshold,
+		},
+	})
+
+	var violation error
+	if slosErr := latency[cascadeDeleteTransition].VerifyThreshold(threshold); slosErr != nil {
+		violation = errors.NewMetricViolationErrorWithThreshold("garbage collector latency", slosErr.Error(), latency[cascadeDeleteTransition].Perc99.Seconds(), threshold.Seconds())
+		logrus.Errorf("%s: %v", g, violation)
+	}
+
+	content, err := util.PrettyPrintJSON(measurementutil.LatencyMapToPerfData(latency))
+	if err != nil {
+		return nil, err
+	}
+	summaries := []measurement.Summary{measurement.CreateSummary(fmt.Sprintf("%s_%s", garbageCollectorLatencyName, config.Identifier), "json", content)}
+
+	if gatherMetrics {
+		gcMetrics, err := grabGarbageCollectorMetrics(config.ClusterFramework)
+		if err != nil {
+			// Controller manager metrics are not available on every provider.
+			logrus.Errorf("%s: failed to grab garbage collector metrics: %v", g, err)
+		} else {
+			content, err := util.PrettyPrintJSON(gcMetrics)
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, measurement.CreateSummary(fmt.Sprintf("%s_%s", garbageCollectorMetricsName, config.Identifier), "json", content))
+		}
+	}
+	return summaries, violation
+}
+
+// Dispose cleans up after the measurement.
+func (*garbageCollectorLatencyMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (g *garbageCollectorLatencyMeasurement) String() string {
+	return garbageCollectorLatencyName + ": " + g.selector.String()
+}
+
+// Description returns a short description of the measurement.
+func (g *garbageCollectorLatencyMeasurement) Description() string {
+	return "Deletes controlling objects and measures the time until their pods are removed by the garbage collector."
+}
+
+func (g *garbageCollectorLatencyMeasurement) deleteAndWait(f *framework.Framework, gvk schema.GroupVersionKind, workers int, timeout time.Duration) (*measurementutil.ObjectTransitionTimes, error) {
+	owners, err := g.listOwners(f, gvk.Kind)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s: deleting %d %ss", g, len(owners), gvk.Kind)
+
+	// Dependents are observed through a single pod informer indexed by owner UID, so waiting for
+	// cascading deletion does not generate additional api calls.
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	podInformer := newPodsByOwnerInformer(f, g.selector.Namespace)
+	if err := informer.StartAndSync(podInformer, stopCh, informerSyncTimeout); err != nil {
+		return nil, fmt.Errorf("pod informer error: %v", err)
+	}
+	pods := podInformer.GetIndexer()
+
+	transitionTimes := measurementutil.NewObjectTransitionTimes(garbageCollectorLatencyName)
+	errList := errors.NewErrorList()
+	var lock sync.Mutex
+	deleteOwner := func(i int) {
+		transitionTimes.Set(owners[i].key, ownerDeletePhase, time.Now())
+		if err := f.DeleteObject(gvk, owners[i].namespace, owners[i].name); err != nil {
+			lock.Lock()
+			defer lock.Unlock()
+			errList.Append(fmt.Errorf("deleting %s error: %v", owners[i].key, err))
+		}
+	}
+	workqueue.ParallelizeUntil(context.TODO(), workers, len(owners), deleteOwner)
+	if !errList.IsEmpty() {
+		return nil, fmt.Errorf("owners deletion error: %v", errList.String())
+	}
+
+	pending := make(map[string]*gcOwner, len(owners))
+	for i := range owners {
+		pending[owners[i].key] = &owners[i]
+	}
+	cond := func() (bool, error) {
+		now := time.Now()
+		for key, o := range pending {
+			if !hasDependents(pods, o) {
+				transitionTimes.Set(key, dependentsGonePhase, now)
+				delete(pending, key)
+			}
+		}
+		return len(pending) == 0, nil
+	}
+	if err := wait.PollImmediate(garbageCollectorCheckInterval, timeout, cond); err != nil {
+		remaining := make([]string, 0, len(pending))
+		for key := range pending {
+			remaining = append(remaining, key)
+		}
+		return nil, fmt.Errorf("timed out waiting for dependents of %d %ss to be deleted: %s", len(pending), gvk.Kind, strings.Join(remaining, ", "))
+	}
+	return transitionTimes, nil
+}
+
+func (g *garbageCollectorLatencyMeasurement) listOwners(f *framework.Framework, kind string) ([]gcOwner, error) {
+	objects, err := runtimeobjects.ListRuntimeObjectsForKind(f.GetClientSets().GetClient(),
+		kind, g.selector.Namespace, g.selector.LabelSelector, g.selector.FieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("listing objects error: %v", err)
+	}
+	// Pods of deployments are owned by their replica sets.
+	var replicaSetUIDs map[types.UID][]types.UID
+	if kind == "Deployment" {
+		if replicaSetUIDs, err = listReplicaSetUIDsByOwner(f, g.selector.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	owners := make([]gcOwner, 0, len(objects))
+	for _, obj := range objects {
+		key, err := runtimeobjects.CreateMetaNamespaceKey(obj)
+		if err != nil {
+			return nil, fmt.Errorf("meta key creation error: %v", err)
+		}
+		name, err := runtimeobjects.GetNameFromRuntimeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		namespace, err := runtimeobjects.GetNamespaceFromRuntimeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		uid, err := runtimeobjects.GetUIDFromRuntimeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		uids := append([]types.UID{uid}, replicaSetUIDs[uid]...)
+		owners = append(owners, gcOwner{key: key, name: name, namespace: namespace, uids: uids})
+	}
+	return owners, nil
+}
+
+// listReplicaSetUIDsByOwner returns UIDs of replica sets by UIDs of their owners.
+func listReplicaSetUIDsByOwner(f *framework.Framework, namespace string) (map[types.UID][]types.UID, error) {
+	replicaSets, err := f.GetClientSets().GetClient().AppsV1().ReplicaSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing replica sets error: %v", err)
+	}
+	uids := make(map[types.UID][]types.UID)
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		for _, ref := range replicaSet.OwnerReferences {
+			uids[ref.UID] = append(uids[ref.UID], replicaSet.UID)
+		}
+	}
+	return uids, nil
+}
+
+func newPodsByOwnerInformer(f *framework.Framework, namespace string) cache.SharedIndexInformer {
+	listWatch := cache.NewListWatchFromClient(f.GetClientSets().GetClient().CoreV1().RESTClient(), "pods", namespace, fields.Everything())
+	return cache.NewSharedIndexInformer(listWatch, &corev1.Pod{}, 0, cache.Indexers{ownerUIDIndex: podOwnerUIDs})
+}
+
+// podOwnerUIDs indexes pods by UIDs of their owners.
+func podOwnerUIDs(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	uids := make([]string, 0, len(pod.OwnerReferences))
+	for _, ref := range pod.OwnerReferences {
+		uids = append(uids, string(ref.UID))
+	}
+	return uids, nil
+}
+
+// hasDependents checks whether any pod is still owned by the owner, pods are matched
+// by owner references rather than labels, as owners may share labels and orphaned pods
+// aren't deleted by the garbage collector.
+func hasDependents(pods cache.Indexer, o *gcOwner) bool {
+	for _, uid := range o.uids {
+		if dependents, err := pods.ByIndex(ownerUIDIndex, string(uid)); err != nil || len(dependents) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func grabGarbageCollectorMetrics(f *framework.Framework) (metrics.ControllerManagerMetrics, error) {
+	grabber, err := metrics.NewMetricsGrabber(
+		f.GetClientSets().GetClient(),
+		nil,   /*external client*/
+		false, /*grab metrics from kubelets*/
+		false, /*grab metrics from scheduler*/
+		true,  /*grab metrics from controller manager*/
+		false, /*grab metrics from apiserver*/
+		false /*grab metrics from cluster autoscaler*/)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MetricsGrabber: %v", err)
+	}
+	received, err := grabber.GrabFromControllerManager()
+	if err != nil {
+		return nil, err
+	}
+	gcMetrics := make(metrics.ControllerManagerMetrics)
+	for _, metric := range interestingControllerManagerMetricsLabels {
+		if strings.HasPrefix(metric, garbageCollectorMetricsPrefix) {
+			gcMetrics[metric] = received[metric]
+		}
+	}
+	return gcMetrics, nil
+}
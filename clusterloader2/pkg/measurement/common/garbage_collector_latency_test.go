@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestHasDependents(t *testing.T) {
+	labels := map[string]string{"app": "test"}
+	newPod := func(name string, owners ...types.UID) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels}}
+		for _, owner := range owners {
+			pod.OwnerReferences = append(pod.OwnerReferences, metav1.OwnerReference{UID: owner})
+		}
+		return pod
+	}
+	tests := []struct {
+		name  string
+		pods  []*corev1.Pod
+		owner gcOwner
+		want  bool
+	}{
+		{
+			name:  "owned-pod",
+			pods:  []*corev1.Pod{newPod("pod-1", "rc-1")},
+			owner: gcOwner{uids: []types.UID{"rc-1"}},
+			want:  true,
+		},
+		{
+			name:  "pods-of-owner-sharing-labels",
+			pods:  []*corev1.Pod{newPod("pod-1", "rc-1")},
+			owner: gcOwner{uids: []types.UID{"rc-2"}},
+			want:  false,
+		},
+		{
+			name:  "orphaned-pod",
+			pods:  []*corev1.Pod{newPod("pod-1")},
+			owner: gcOwner{uids: []types.UID{"rc-1"}},
+			want:  false,
+		},
+		{
+			name:  "pod-owned-by-replica-set",
+			pods:  []*corev1.Pod{newPod("pod-1", "rs-2")},
+			owner: gcOwner{uids: []types.UID{"deployment-1", "rs-1", "rs-2"}},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{ownerUIDIndex: podOwnerUIDs})
+			for _, pod := range tt.pods {
+				if err := pods.Add(pod); err != nil {
+					t.Fatalf("adding pod error: %v", err)
+				}
+			}
+			if got := hasDependents(pods, &tt.owner); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
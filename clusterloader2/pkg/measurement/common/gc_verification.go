@@ -0,0 +1,368 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	gcVerificationName           = "GarbageCollectionVerification"
+	defaultGCVerificationTimeout = 5 * time.Minute
+	defaultGCVerificationKinds   = "Pod,ReplicaSet,Endpoints"
+	gcVerificationCheckInterval  = 5 * time.Second
+	// maxGCStragglersPerKind bounds the number of stragglers listed in the summary.
+	maxGCStragglersPerKind = 20
+
+	// Stragglers being deleted or whose owners are gone will be eventually collected.
+	gcReasonTerminating  = "terminating"
+	gcReasonOwnerDeleted = "ownerDeleted"
+	// Stragglers without owners or with existing owners won't be collected.
+	gcReasonNoOwner     = "noOwner"
+	gcReasonOwnerExists = "ownerExists"
+)
+
+// gcObjectListers list objects of supported kinds. Endpoints don't have owner references,
+// they are removed by the endpoints controller once their service is deleted. Events are removed
+// once their TTL expires (1h by default), their involved object is considered their owner.
+var gcObjectListers = map[string]func(c clientset.Interface, namespace string, options metav1.ListOptions) ([]gcObject, error){
+	"Pod": func(c clientset.Interface, namespace string, options metav1.ListOptions) ([]gcObject, error) {
+		list, err := c.CoreV1().Pods(namespace).List(options)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]gcObject, 0, len(list.Items))
+		for i := range list.Items {
+			objects = append(objects, newGCObject("Pod", &list.Items[i].ObjectMeta))
+		}
+		return objects, nil
+	},
+	"ReplicaSet": func(c clientset.Interface, namespace string, options metav1.ListOptions) ([]gcObject, error) {
+		list, err := c.AppsV1().ReplicaSets(namespace).List(options)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]gcObject, 0, len(list.Items))
+		for i := range list.Items {
+			objects = append(objects, newGCObject("ReplicaSet", &list.Items[i].ObjectMeta))
+		}
+		return objects, nil
+	},
+	"Endpoints": func(c clientset.Interface, namespace string, options metav1.ListOptions) ([]gcObject, error) {
+		list, err := c.CoreV1().Endpoints(namespace).List(options)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]gcObject, 0, len(list.Items))
+		for i := range list.Items {
+			object := newGCObject("Endpoints", &list.Items[i].ObjectMeta)
+			object.owners = []gcOwnerRef{{apiVersion: "v1", kind: "Service", namespace: object.namespace, name: object.name}}
+			objects = append(objects, object)
+		}
+		return objects, nil
+	},
+	"Event": func(c clientset.Interface, namespace string, options metav1.ListOptions) ([]gcObject, error) {
+		list, err := c.CoreV1().Events(namespace).List(options)
+		if err != nil {
+			return nil, err
+		}
+		objects := make([]gcObject, 0, len(list.Items))
+		for i := range list.Items {
+			event := &list.Items[i]
+			object := newGCObject("Event", &event.ObjectMeta)
+			object.owners = []gcOwnerRef{{
+				apiVersion: event.InvolvedObject.APIVersion,
+				kind:       event.InvolvedObject.Kind,
+				namespace:  event.InvolvedObject.Namespace,
+				name:       event.InvolvedObject.Name,
+				uid:        event.InvolvedObject.UID,
+			}}
+			objects = append(objects, object)
+		}
+		return objects, nil
+	},
+}
+
+func init() {
+	if err := measurement.Register(gcVerificationName, createGCVerificationMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", gcVerificationName, err)
+	}
+}
+
+func createGCVerificationMeasurement() measurement.Measurement {
+	return &gcVerificationMeasurement{
+		selector: measurementutil.NewObjectSelector(),
+	}
+}
+
+type gcVerificationMeasurement struct {
+	selector *measurementutil.ObjectSelector
+}
+
+// gcObject is an object expected to be garbage collected.
+type gcObject struct {
+	kind      string
+	namespace string
+	name      string
+	deleting  bool
+	owners    []gcOwnerRef
+}
+
+// gcOwnerRef identifies an owner of an object. Empty uid matches any owner with the given name.
+type gcOwnerRef struct {
+	apiVersion string
+	kind       string
+	namespace  string
+	name       string
+	uid        types.UID
+}
+
+func newGCObject(kind string, meta *metav1.ObjectMeta) gcObject {
+	object := gcObject{
+		kind:      kind,
+		namespace: meta.Namespace,
+		name:      meta.Name,
+		deleting:  meta.DeletionTimestamp != nil,
+	}
+	for _, ref := range meta.OwnerReferences {
+		object.owners = append(object.owners, gcOwnerRef{
+			apiVersion: ref.APIVersion,
+			kind:       ref.Kind,
+			namespace:  meta.Namespace,
+			name:       ref.Name,
+			uid:        ref.UID,
+		})
+	}
+	return object
+}
+
+// gcStraggler is an object that wasn't collected before the deadline.
+type gcStraggler struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+type gcKindSummary struct {
+	// CollectedAfter is the time after which all objects of the kind were gone,
+	// empty if there were stragglers at the deadline.
+	CollectedAfter string `json:"collectedAfter,omitempty"`
+	Stragglers     int    `json:"stragglers"`
+	// SlowGC is the number of stragglers that will be eventually collected.
+	SlowGC int `json:"slowGC"`
+	// Leaked is the number of stragglers that won't be collected.
+	Leaked int `json:"leaked"`
+	// Reasons maps straggler reason to the number of stragglers.
+	Reasons map[string]int `json:"reasons"`
+	// Examples lists up to 20 stragglers.
+	Examples []gcStraggler `json:"examples"`
+}
+
+type gcVerificationSummary struct {
+	Timeout string                    `json:"timeout"`
+	Kinds   map[string]*gcKindSummary `json:"kinds"`
+}
+
+// Execute waits until all objects of given kinds (Pod, ReplicaSet and Endpoints by default,
+// Event is supported as well) are gone and reports objects that remain after the timeout
+// with the reason, distinguishing slow garbage collection from leaked objects.
+// It's intended to be called after delete phases. Objects can be specified by namespace,
+// label and field selectors. If namespace is not passed by parameter,
+// objects in automanaged namespaces are verified.
+// If any objects remain after the timeout, an error will be returned.
+func (g *gcVerificationMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	if err := g.selector.Parse(config.Params); err != nil {
+		return nil, err
+	}
+	timeout, err := util.GetDurationOrDefault(config.Params, "timeout", defaultGCVerificationTimeout)
+	if err != nil {
+		return nil, err
+	}
+	kindsParam, err := util.GetStringOrDefault(config.Params, "kinds", defaultGCVerificationKinds)
+	if err != nil {
+		return nil, err
+	}
+	var kinds []string
+	for _, kind := range strings.Split(kindsParam, ",") {
+		kind = strings.TrimSpace(kind)
+		if _, ok := gcObjectListers[kind]; !ok {
+			return nil, fmt.Errorf("unsupported kind %q", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	namespaces := []string{g.selector.Namespace}
+	if g.selector.Namespace == metav1.NamespaceAll {
+		if namespaces, err = config.ClusterFramework.ListAutomanagedNamespaces(); err != nil {
+			return nil, fmt.Errorf("automanaged namespaces listing error: %v", err)
+		}
+	}
+
+	summary := g.verify(config.ClusterFramework, kinds, namespaces, timeout)
+	var stragglers []string
+	for _, kind := range kinds {
+		kindSummary := summary.Kinds[kind]
+		if kindSummary.Stragglers == 0 {
+			logrus.Infof("%s: all %s objects collected after %s", g, kind, kindSummary.CollectedAfter)
+			continue
+		}
+		stragglers = append(stragglers, fmt.Sprintf("%s: %d (slow GC: %d, leaked: %d)", kind, kindSummary.Stragglers, kindSummary.SlowGC, kindSummary.Leaked))
+	}
+	var violation error
+	if len(stragglers) > 0 {
+		violation = errors.NewMetricViolationError("garbage collection", fmt.Sprintf("objects not collected within %v: %s", timeout, strings.Join(stragglers, ", ")))
+		logrus.Errorf("%s: %v", g, violation)
+	}
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(fmt.Sprintf("%s_%s", gcVerificationName, config.Identifier), "json", content)}, violation
+}
+
+// Dispose cleans up after the measurement.
+func (*gcVerificationMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (g *gcVerificationMeasurement) String() string {
+	return gcVerificationName + ": " + g.selector.String()
+}
+
+// Description returns a short description of the measurement.
+func (g *gcVerificationMeasurement) Description() string {
+	return "Waits until deleted objects are gone and reports objects leaked or slowly garbage collected."
+}
+
+// verify waits until there are no objects of given kinds or timeout passes,
+// and classifies the remaining objects.
+func (g *gcVerificationMeasurement) verify(f *framework.Framework, kinds, namespaces []string, timeout time.Duration) *gcVerificationSummary {
+	c := f.GetClientSets().GetClient()
+	options := metav1.ListOptions{LabelSelector: g.selector.LabelSelector, FieldSelector: g.selector.FieldSelector}
+	summary := &gcVerificationSummary{Timeout: timeout.String(), Kinds: make(map[string]*gcKindSummary)}
+	remaining := make(map[string][]gcObject)
+	for _, kind := range kinds {
+		summary.Kinds[kind] = &gcKindSummary{Reasons: make(map[string]int), Examples: []gcStraggler{}}
+		remaining[kind] = nil
+	}
+
+	start := time.Now()
+	cond := func() (bool, error) {
+		for kind := range remaining {
+			var objects []gcObject
+			for _, namespace := range namespaces {
+				namespaceObjects, err := gcObjectListers[kind](c, namespace, options)
+				if err != nil {
+					// Listing will be retried in the next check.
+					logrus.Warningf("%s: listing %s objects error: %v", g, kind, err)
+					return false, nil
+				}
+				objects = append(objects, namespaceObjects...)
+			}
+			if len(objects) == 0 {
+				summary.Kinds[kind].CollectedAfter = time.Since(start).String()
+				delete(remaining, kind)
+				continue
+			}
+			remaining[kind] = objects
+		}
+		return len(remaining) == 0, nil
+	}
+	if err := wait.PollImmediate(gcVerificationCheckInterval, timeout, cond); err == nil {
+		return summary
+	}
+
+	ownerExists := newGCOwnerChecker(f)
+	for kind, objects := range remaining {
+		kindSummary := summary.Kinds[kind]
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].namespace+"/"+objects[i].name < objects[j].namespace+"/"+objects[j].name
+		})
+		for _, object := range objects {
+			reason := classifyGCStraggler(object, ownerExists)
+			kindSummary.Stragglers++
+			kindSummary.Reasons[reason]++
+			if reason == gcReasonTerminating || reason == gcReasonOwnerDeleted {
+				kindSummary.SlowGC++
+			} else {
+				kindSummary.Leaked++
+			}
+			if len(kindSummary.Examples) < maxGCStragglersPerKind {
+				kindSummary.Examples = append(kindSummary.Examples, gcStraggler{Namespace: object.namespace, Name: object.name, Reason: reason})
+			}
+		}
+	}
+	return summary
+}
+
+// classifyGCStraggler returns the reason why the object wasn't collected.
+func classifyGCStraggler(object gcObject, ownerExists func(gcOwnerRef) bool) string {
+	if object.deleting {
+		return gcReasonTerminating
+	}
+	if len(object.owners) == 0 {
+		return gcReasonNoOwner
+	}
+	for _, owner := range object.owners {
+		if ownerExists(owner) {
+			return gcReasonOwnerExists
+		}
+	}
+	return gcReasonOwnerDeleted
+}
+
+// newGCOwnerChecker returns function checking whether the owner exists. Results are cached,
+// as owners are usually shared by many objects. Owners that can't be checked are considered existing.
+func newGCOwnerChecker(f *framework.Framework) func(gcOwnerRef) bool {
+	cache := make(map[gcOwnerRef]bool)
+	return func(owner gcOwnerRef) bool {
+		if exists, ok := cache[owner]; ok {
+			return exists
+		}
+		exists := true
+		gv, err := schema.ParseGroupVersion(owner.apiVersion)
+		if err == nil {
+			var obj metav1.Object
+			obj, err = f.GetObject(gv.WithKind(owner.kind), owner.namespace, owner.name)
+			switch {
+			case apierrs.IsNotFound(err):
+				exists = false
+			case err == nil:
+				exists = owner.uid == "" || obj.GetUID() == owner.uid
+			}
+		}
+		if err != nil && !apierrs.IsNotFound(err) {
+			logrus.Warningf("%s: checking owner %s %s/%s error: %v", gcVerificationName, owner.kind, owner.namespace, owner.name, err)
+		}
+		cache[owner] = exists
+		return exists
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClassifyGCStraggler(t *testing.T) {
+	existingOwner := gcOwnerRef{apiVersion: "apps/v1", kind: "ReplicaSet", namespace: "test", name: "existing"}
+	deletedOwner := gcOwnerRef{apiVersion: "apps/v1", kind: "ReplicaSet", namespace: "test", name: "deleted"}
+	ownerExists := func(owner gcOwnerRef) bool { return owner == existingOwner }
+
+	now := metav1.Now()
+	cases := []struct {
+		name   string
+		object gcObject
+		want   string
+	}{
+		{name: "terminating", object: gcObject{deleting: true, owners: []gcOwnerRef{existingOwner}}, want: gcReasonTerminating},
+		{name: "no owner", object: gcObject{}, want: gcReasonNoOwner},
+		{name: "owner exists", object: gcObject{owners: []gcOwnerRef{deletedOwner, existingOwner}}, want: gcReasonOwnerExists},
+		{name: "owner deleted", object: gcObject{owners: []gcOwnerRef{deletedOwner}}, want: gcReasonOwnerDeleted},
+		{
+			name:   "from object meta",
+			object: newGCObject("Pod", &metav1.ObjectMeta{Namespace: "test", Name: "pod", DeletionTimestamp: &now}),
+			want:   gcReasonTerminating,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, classifyGCStraggler(tc.object, ownerExists))
+		})
+	}
+}
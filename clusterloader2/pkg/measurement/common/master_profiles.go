@@ -0,0 +1,295 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	masterProfilesName = "MasterProfiles"
+
+	defaultMasterProfilesComponents = "kube-apiserver,etcd,kube-scheduler,kube-controller-manager"
+	defaultMasterProfilesKinds      = "profile,heap,mutex"
+
+	// masterProfilesAccessSSH reads profiles with curl executed over SSH on the master.
+	masterProfilesAccessSSH = "ssh"
+	// masterProfilesAccessProxy reads profiles through the apiserver proxy to component pods.
+	masterProfilesAccessProxy = "proxy"
+)
+
+func init() {
+	if err := measurement.Register(masterProfilesName, createMasterProfilesMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", masterProfilesName, err)
+	}
+}
+
+func createMasterProfilesMeasurement() measurement.Measurement {
+	return &masterProfilesMeasurement{}
+}
+
+type masterProfilesMeasurement struct {
+	components []string
+	kinds      []string
+	access     string
+	provider   string
+	host       string
+
+	isRunning bool
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	lock      sync.Mutex
+	profiles  []archiveEntry
+	failures  int
+}
+
+// RequiredCapabilities returns capabilities required by the measurement.
+// Profiles of components other than kube-apiserver are read over SSH from the master,
+// unless they are read through the apiserver proxy.
+func (m *masterProfilesMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if access, _ := config.Params["access"].(string); access == masterProfilesAccessProxy {
+		return nil
+	}
+	components, _ := config.Params["components"].(string)
+	if components == "kube-apiserver" {
+		return nil
+	}
+	if _, ok := config.Params["host"]; ok {
+		return []measurement.Capability{measurement.SSH}
+	}
+	return []measurement.Capability{measurement.SSH, measurement.MasterAccess}
+}
+
+// Execute supports two actions:
+// - start - starts periodic collection of pprof profiles of master components.
+// - gather - stops the collection and bundles all profiles into a single tar.gz archive.
+// By default, cpu, heap and mutex profiles of kube-apiserver, etcd, kube-scheduler and
+// kube-controller-manager are collected. kube-apiserver profiles are read through the apiserver. Profiles of other components are read
+// over SSH from the master (access: ssh) or through the apiserver proxy to pods of the components
+// in kube-system namespace (access: proxy), which covers all masters of HA clusters.
+func (m *masterProfilesMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		if m.isRunning {
+			logrus.Infof("%s: measurement already running", m)
+			return nil, nil
+		}
+		return nil, m.start(config)
+	case "gather":
+		m.stop()
+		return m.gather()
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (m *masterProfilesMeasurement) Dispose() {
+	m.stop()
+}
+
+// String returns string representation of this measurement.
+func (*masterProfilesMeasurement) String() string {
+	return masterProfilesName
+}
+
+// Description returns a short description of the measurement.
+func (*masterProfilesMeasurement) Description() string {
+	return "Periodically collects pprof profiles of master components into a single archive."
+}
+
+func (m *masterProfilesMeasurement) start(config *measurement.MeasurementConfig) error {
+	components, err := util.GetStringOrDefault(config.Params, "components", defaultMasterProfilesComponents)
+	if err != nil {
+		return err
+	}
+	kinds, err := util.GetStringOrDefault(config.Params, "kinds", defaultMasterProfilesKinds)
+	if err != nil {
+		return err
+	}
+	if m.access, err = util.GetStringOrDefault(config.Params, "access", masterProfilesAccessSSH); err != nil {
+		return err
+	}
+	if m.access != masterProfilesAccessSSH && m.access != masterProfilesAccessProxy {
+		return fmt.Errorf("unknown access %q, expected %s or %s", m.access, masterProfilesAccessSSH, masterProfilesAccessProxy)
+	}
+	if m.provider, err = util.GetStringOrDefault(config.Params, "provider", config.ClusterFramework.GetClusterConfig().Provider); err != nil {
+		return err
+	}
+	if m.host, err = util.GetStringOrDefault(config.Params, "host", config.ClusterFramework.GetClusterConfig().GetMasterIp()); err != nil {
+		return err
+	}
+	// By default, profiles are collected as often as by CPUProfile and MemoryProfile measurements.
+	numNodes := config.ClusterFramework.GetClusterConfig().Nodes
+	interval, err := util.GetDurationOrDefault(config.Params, "interval", time.Duration(5+numNodes/250)*time.Minute)
+	if err != nil {
+		return err
+	}
+	m.components = splitList(components)
+	m.kinds = splitList(kinds)
+	for _, component := range m.components {
+		if component == "kube-apiserver" {
+			continue
+		}
+		if _, err := getPortForComponent(component); err != nil {
+			return err
+		}
+	}
+
+	m.profiles = nil
+	m.failures = 0
+	m.isRunning = true
+	m.stopCh = make(chan struct{})
+	m.wg.Add(1)
+	c := config.ClusterFramework.GetClientSets().GetClient()
+	logrus.Infof("%s: collecting %v profiles of %v every %v", m, m.kinds, m.components, interval)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				m.collect(c)
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *masterProfilesMeasurement) stop() {
+	if !m.isRunning {
+		return
+	}
+	close(m.stopCh)
+	m.wg.Wait()
+	m.isRunning = false
+}
+
+// collect collects profiles of all kinds of all components.
+func (m *masterProfilesMeasurement) collect(c clientset.Interface) {
+	timestamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, component := range m.components {
+		for _, kind := range m.kinds {
+			profiles, err := m.getProfiles(c, component, kind)
+			m.lock.Lock()
+			if err != nil {
+				m.failures++
+				logrus.Errorf("%s: failed to gather %s profile of %s: %v", m, kind, component, err)
+			}
+			for instance, data := range profiles {
+				m.profiles = append(m.profiles, archiveEntry{
+					path: fmt.Sprintf("%s/%s_%s_%s.pprof", component, instance, kind, timestamp),
+					data: data,
+				})
+			}
+			m.lock.Unlock()
+		}
+	}
+}
+
+// getProfiles returns profiles of the component keyed by its instance (master host or pod).
+func (m *masterProfilesMeasurement) getProfiles(c clientset.Interface, component, kind string) (map[string][]byte, error) {
+	if component == "kube-apiserver" {
+		data, err := c.CoreV1().RESTClient().Get().AbsPath("/debug/pprof/" + kind).DoRaw()
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{"apiserver": data}, nil
+	}
+	port, err := getPortForComponent(component)
+	if err != nil {
+		return nil, err
+	}
+	if m.access == masterProfilesAccessSSH {
+		getCommand := fmt.Sprintf("curl -s localhost:%v/debug/pprof/%s", port, kind)
+		sshResult, err := measurementutil.SSH(getCommand, m.host+":22", m.provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute curl command on master through SSH: %v", err)
+		}
+		return map[string][]byte{m.host: []byte(sshResult.Stdout)}, nil
+	}
+
+	pods, err := c.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{LabelSelector: "component=" + component})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s pods error: %v", component, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no pods with component=%s label found", component)
+	}
+	profiles := make(map[string][]byte)
+	var errs []string
+	for _, pod := range pods.Items {
+		data, err := c.CoreV1().RESTClient().Get().
+			Namespace(metav1.NamespaceSystem).
+			Resource("pods").
+			Name(fmt.Sprintf("%v:%v", pod.Name, port)).
+			SubResource("proxy").
+			Suffix("debug/pprof/" + kind).
+			Do().Raw()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", pod.Name, err))
+			continue
+		}
+		profiles[pod.Name] = data
+	}
+	if len(errs) > 0 {
+		return profiles, fmt.Errorf("proxy errors: %s", strings.Join(errs, "; "))
+	}
+	return profiles, nil
+}
+
+func (m *masterProfilesMeasurement) gather() ([]measurement.Summary, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	logrus.Infof("%s: collected %d profiles, %d failed", m, len(m.profiles), m.failures)
+	if len(m.profiles) == 0 {
+		return nil, nil
+	}
+	bundle, err := createTarGz(m.profiles)
+	if err != nil {
+		return nil, fmt.Errorf("bundling profiles error: %v", err)
+	}
+	return []measurement.Summary{measurement.CreateSummary(masterProfilesName, "tar.gz", string(bundle))}, nil
+}
+
+// splitList splits comma-separated list, skipping empty elements.
+func splitList(list string) []string {
+	var result []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			result = append(result, element)
+		}
+	}
+	return result
+}
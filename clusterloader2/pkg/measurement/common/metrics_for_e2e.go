@@ -126,6 +126,11 @@ func (*metricsForE2EMeasurement) String() string {
 	return metricsForE2EName
 }
 
+// Description returns a short description of the measurement.
+func (*metricsForE2EMeasurement) Description() string {
+	return "Gathers metrics of the apiserver, controller manager, scheduler and, optionally, kubelets."
+}
+
 func filterMetrics(m *metrics.MetricsCollection) {
 	interestingApiServerMetrics := make(metrics.ApiServerMetrics)
 	for _, metric := range interestingApiServerMetricsLabels {
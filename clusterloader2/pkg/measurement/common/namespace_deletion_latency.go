@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	namespaceDeletionLatencyName               = "NamespaceDeletionLatency"
+	defaultNamespaceDeletionLatencyThreshold   = 5 * time.Minute
+	defaultNamespaceDeletionBlockingCheckDelay = 30 * time.Second
+	defaultNamespaceDeletionSlowestCount       = 10
+)
+
+// namespacedResources lists resources that are checked for remaining
+// instances while namespace deletion is in progress.
+var namespacedResources = []schema.GroupVersionResource{
+	{Group: "", Version: "v1", Resource: "pods"},
+	{Group: "", Version: "v1", Resource: "services"},
+	{Group: "", Version: "v1", Resource: "endpoints"},
+	{Group: "", Version: "v1", Resource: "configmaps"},
+	{Group: "", Version: "v1", Resource: "secrets"},
+	{Group: "", Version: "v1", Resource: "replicationcontrollers"},
+	{Group: "", Version: "v1", Resource: "persistentvolumeclaims"},
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "replicasets"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+}
+
+func init() {
+	if err := measurement.Register(namespaceDeletionLatencyName, createNamespaceDeletionLatencyMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", namespaceDeletionLatencyName, err)
+	}
+}
+
+func createNamespaceDeletionLatencyMeasurement() measurement.Measurement {
+	return &namespaceDeletionLatencyMeasurement{}
+}
+
+type namespaceDeletionLatencyMeasurement struct{}
+
+type namespaceDeletion struct {
+	Namespace string        `json:"namespace"`
+	Duration  time.Duration `json:"duration"`
+	// BlockingResources lists resources that still had instances
+	// in the namespace when it was observed as slow to delete.
+	BlockingResources []string `json:"blockingResources,omitempty"`
+}
+
+// GetLatency returns namespace deletion duration.
+func (n *namespaceDeletion) GetLatency() time.Duration {
+	return n.Duration
+}
+
+type namespaceDeletionSummary struct {
+	Latency measurementutil.LatencyMetric `json:"latency"`
+	Slowest []*namespaceDeletion          `json:"slowest"`
+}
+
+// Execute deletes all automanaged namespaces the way they are deleted after the test
+// and reports, for each of them, the duration from the delete call until the namespace
+// is fully removed, as recorded by the framework.
+// Namespaces that are not removed within blockingCheckDelay are inspected
+// for remaining resources, which are reported as blocking the finalization.
+// The measurement should be executed as the last step of the test.
+func (n *namespaceDeletionLatencyMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	threshold, err := util.GetDurationOrDefault(config.Params, "threshold", defaultNamespaceDeletionLatencyThreshold)
+	if err != nil {
+		return nil, err
+	}
+	blockingCheckDelay, err := util.GetDurationOrDefault(config.Params, "blockingCheckDelay", defaultNamespaceDeletionBlockingCheckDelay)
+	if err != nil {
+		return nil, err
+	}
+	slowestCount, err := util.GetIntOrDefault(config.Params, "slowestCount", defaultNamespaceDeletionSlowestCount)
+	if err != nil {
+		return nil, err
+	}
+
+	f := config.ClusterFramework
+	dynamicClient := f.GetDynamicClients().GetClient()
+	var lock sync.Mutex
+	blockingResources := make(map[string][]string)
+	f.SetSlowNamespaceDeletionHook(blockingCheckDelay, func(namespace string) {
+		remaining := getRemainingResources(dynamicClient, namespace)
+		logrus.Infof("%s: namespace %s still terminating, remaining resources: %v", n, namespace, remaining)
+		lock.Lock()
+		defer lock.Unlock()
+		blockingResources[namespace] = remaining
+	})
+	defer f.SetSlowNamespaceDeletionHook(0, nil)
+
+	logrus.Infof("%s: deleting %d namespaces", n, len(f.GetAutomanagedNamespaceNames()))
+	if errList := f.DeleteAutomanagedNamespaces(); !errList.IsEmpty() {
+		return nil, fmt.Errorf("namespaces deletion error: %v", errList.String())
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	var deletions []measurementutil.LatencyData
+	for _, d := range f.GetNamespaceDeletions() {
+		deletions = append(deletions, &namespaceDeletion{
+			Namespace:         d.Namespace,
+			Duration:          d.End.Sub(d.Start),
+			BlockingResources: blockingResources[d.Namespace],
+		})
+	}
+
+	sort.Sort(sort.Reverse(measurementutil.LatencySlice(deletions)))
+	summary := namespaceDeletionSummary{Slowest: []*namespaceDeletion{}}
+	for i := 0; i < len(deletions) && i < slowestCount; i++ {
+		summary.Slowest = append(summary.Slowest, deletions[i].(*namespaceDeletion))
+	}
+	sort.Sort(measurementutil.LatencySlice(deletions))
+	summary.Latency = measurementutil.NewLatencyMetric(deletions)
+	logrus.Infof("%s: namespace deletion latency: %v", n, summary.Latency)
+
+	var violation error
+	if slosErr := summary.Latency.VerifyThreshold(threshold); slosErr != nil {
+		violation = errors.NewMetricViolationErrorWithThreshold("namespace deletion latency", slosErr.Error(), summary.Latency.Perc99.Seconds(), threshold.Seconds())
+		logrus.Errorf("%s: %v", n, violation)
+	}
+
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(namespaceDeletionLatencyName, "json", content)}, violation
+}
+
+// Dispose cleans up after the measurement.
+func (*namespaceDeletionLatencyMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (*namespaceDeletionLatencyMeasurement) String() string {
+	return namespaceDeletionLatencyName
+}
+
+// Description returns a short description of the measurement.
+func (*namespaceDeletionLatencyMeasurement) Description() string {
+	return "Deletes automanaged namespaces and measures the time until they are removed, reporting resources blocking the finalization."
+}
+
+func getRemainingResources(dc dynamic.Interface, namespace string) []string {
+	var remaining []string
+	for _, gvr := range namespacedResources {
+		list, err := dc.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{Limit: 1})
+		if err != nil {
+			logrus.Warningf("%s: listing %s in %s error: %v", namespaceDeletionLatencyName, gvr.Resource, namespace, err)
+			continue
+		}
+		if len(list.Items) > 0 {
+			remaining = append(remaining, gvr.GroupResource().String())
+		}
+	}
+	return remaining
+}
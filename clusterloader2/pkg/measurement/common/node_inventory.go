@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/pkg/util/system"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	nodeInventoryName = "NodeInventory"
+
+	unknownNodeAttribute = "unknown"
+)
+
+// machineTypeLabels are node labels holding the machine type, in order of preference.
+var machineTypeLabels = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"}
+
+func init() {
+	if err := measurement.Register(nodeInventoryName, createNodeInventoryMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", nodeInventoryName, err)
+	}
+}
+
+func createNodeInventoryMeasurement() measurement.Measurement {
+	return &nodeInventoryMeasurement{nodes: make(map[string]nodeAttributes)}
+}
+
+type nodeInventoryMeasurement struct {
+	// nodes contains attributes of every non-master node seen during the run.
+	nodes map[string]nodeAttributes
+}
+
+type nodeAttributes struct {
+	OSImage                 string `json:"osImage"`
+	KernelVersion           string `json:"kernelVersion"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
+	KubeletVersion          string `json:"kubeletVersion"`
+	MachineType             string `json:"machineType"`
+}
+
+// nodeInventory is the summary of the measurement. Distributions map attribute name
+// to the number of nodes having given value of the attribute.
+type nodeInventory struct {
+	NodeCount int `json:"nodeCount"`
+	// HeterogeneousAttributes lists attributes having more than one value across nodes.
+	HeterogeneousAttributes []string                  `json:"heterogeneousAttributes"`
+	Distributions           map[string]map[string]int `json:"distributions"`
+	Nodes                   map[string]nodeAttributes `json:"nodes"`
+}
+
+// Execute supports two actions. Start records attributes of current nodes.
+// Gather records attributes of current nodes and creates a summary with distribution
+// of OS images, kernel versions, container runtime versions, kubelet versions
+// and machine types of all nodes present during the run. Gather can be called
+// without start, in which case only current nodes are inventoried.
+func (n *nodeInventoryMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		return nil, n.record(config.ClusterFramework.GetClientSets().GetClient())
+	case "gather":
+		if err := n.record(config.ClusterFramework.GetClientSets().GetClient()); err != nil {
+			return nil, err
+		}
+		return n.gather()
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (*nodeInventoryMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (*nodeInventoryMeasurement) String() string {
+	return nodeInventoryName
+}
+
+// Description returns a short description of the measurement.
+func (*nodeInventoryMeasurement) Description() string {
+	return "Reports distribution of OS images, kernel, container runtime and kubelet versions and machine types of nodes."
+}
+
+func (n *nodeInventoryMeasurement) record(c clientset.Interface) error {
+	nodes, err := client.ListNodes(c)
+	if err != nil {
+		return fmt.Errorf("listing nodes error: %v", err)
+	}
+	for i := range nodes {
+		if system.IsMasterNode(nodes[i].Name) {
+			continue
+		}
+		n.nodes[nodes[i].Name] = getNodeAttributes(&nodes[i])
+	}
+	return nil
+}
+
+func (n *nodeInventoryMeasurement) gather() ([]measurement.Summary, error) {
+	inventory := buildNodeInventory(n.nodes)
+	if len(inventory.HeterogeneousAttributes) > 0 {
+		logrus.Warningf("%s: nodes differ in %v", n, inventory.HeterogeneousAttributes)
+	}
+	content, err := util.PrettyPrintJSON(inventory)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(nodeInventoryName, "json", content)}, nil
+}
+
+func getNodeAttributes(node *corev1.Node) nodeAttributes {
+	info := node.Status.NodeInfo
+	attributes := nodeAttributes{
+		OSImage:                 valueOrUnknown(info.OSImage),
+		KernelVersion:           valueOrUnknown(info.KernelVersion),
+		ContainerRuntimeVersion: valueOrUnknown(info.ContainerRuntimeVersion),
+		KubeletVersion:          valueOrUnknown(info.KubeletVersion),
+		MachineType:             unknownNodeAttribute,
+	}
+	for _, label := range machineTypeLabels {
+		if machineType, ok := node.Labels[label]; ok && machineType != "" {
+			attributes.MachineType = machineType
+			break
+		}
+	}
+	return attributes
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return unknownNodeAttribute
+	}
+	return value
+}
+
+func buildNodeInventory(nodes map[string]nodeAttributes) *nodeInventory {
+	inventory := &nodeInventory{
+		NodeCount:               len(nodes),
+		HeterogeneousAttributes: []string{},
+		Distributions: map[string]map[string]int{
+			"osImage":                 {},
+			"kernelVersion":           {},
+			"containerRuntimeVersion": {},
+			"kubeletVersion":          {},
+			"machineType":             {},
+		},
+		Nodes: nodes,
+	}
+	for _, attributes := range nodes {
+		inventory.Distributions["osImage"][attributes.OSImage]++
+		inventory.Distributions["kernelVersion"][attributes.KernelVersion]++
+		inventory.Distributions["containerRuntimeVersion"][attributes.ContainerRuntimeVersion]++
+		inventory.Distributions["kubeletVersion"][attributes.KubeletVersion]++
+		inventory.Distributions["machineType"][attributes.MachineType]++
+	}
+	for attribute, distribution := range inventory.Distributions {
+		if len(distribution) > 1 {
+			inventory.HeterogeneousAttributes = append(inventory.HeterogeneousAttributes, attribute)
+		}
+	}
+	sort.Strings(inventory.HeterogeneousAttributes)
+	return inventory
+}
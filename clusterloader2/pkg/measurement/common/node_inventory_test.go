@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildNodeInventory(t *testing.T) {
+	newNode := func(name, kernel, machineTypeLabel string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		node.Status.NodeInfo = corev1.NodeSystemInfo{
+			OSImage:                 "Container-Optimized OS from Google",
+			KernelVersion:           kernel,
+			ContainerRuntimeVersion: "containerd://1.2.8",
+			KubeletVersion:          "v1.16.0",
+		}
+		if machineTypeLabel != "" {
+			node.Labels[machineTypeLabel] = "n1-standard-1"
+		}
+		return node
+	}
+	nodes := map[string]nodeAttributes{
+		"node-a": getNodeAttributes(newNode("node-a", "4.14.127+", "beta.kubernetes.io/instance-type")),
+		"node-b": getNodeAttributes(newNode("node-b", "4.14.127+", "node.kubernetes.io/instance-type")),
+		"node-c": getNodeAttributes(newNode("node-c", "4.19.76+", "")),
+	}
+
+	inventory := buildNodeInventory(nodes)
+	assert.Equal(t, 3, inventory.NodeCount)
+	assert.Equal(t, []string{"kernelVersion", "machineType"}, inventory.HeterogeneousAttributes)
+	assert.Equal(t, map[string]int{"4.14.127+": 2, "4.19.76+": 1}, inventory.Distributions["kernelVersion"])
+	assert.Equal(t, map[string]int{"n1-standard-1": 2, "unknown": 1}, inventory.Distributions["machineType"])
+	assert.Equal(t, map[string]int{"containerd://1.2.8": 3}, inventory.Distributions["containerRuntimeVersion"])
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/pkg/util/system"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	nodeUtilizationHeatmapName            = "NodeUtilizationHeatmap"
+	defaultNodeUtilizationHeatmapInterval = 30 * time.Second
+)
+
+func init() {
+	if err := measurement.Register(nodeUtilizationHeatmapName, createNodeUtilizationHeatmapMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", nodeUtilizationHeatmapName, err)
+	}
+}
+
+func createNodeUtilizationHeatmapMeasurement() measurement.Measurement {
+	return &nodeUtilizationHeatmapMeasurement{}
+}
+
+type nodeUtilizationHeatmapMeasurement struct {
+	isRunning bool
+	stopCh    chan struct{}
+	lock      sync.Mutex
+	samples   []nodeUtilizationSample
+}
+
+// nodeUtilization represents requested resources of a single node,
+// relative to its allocatable resources.
+type nodeUtilization struct {
+	cpu    float64
+	memory float64
+	pods   float64
+}
+
+type nodeUtilizationSample struct {
+	timestamp time.Time
+	nodes     map[string]nodeUtilization
+}
+
+// nodeUtilizationHeatmap presents utilization as matrices,
+// where rows correspond to nodes and columns correspond to timestamps.
+// Null value means that the node did not exist at given time.
+type nodeUtilizationHeatmap struct {
+	Timestamps []time.Time  `json:"timestamps"`
+	Nodes      []string     `json:"nodes"`
+	CPU        [][]*float64 `json:"cpu"`
+	Memory     [][]*float64 `json:"memory"`
+	Pods       [][]*float64 `json:"pods"`
+}
+
+// Execute supports two actions:
+// - start - starts periodic sampling of requested cpu, memory and pod count
+//   of every non-master node, relative to its allocatable resources.
+// - gather - stops sampling and creates heatmap summaries in json and csv format.
+func (n *nodeUtilizationHeatmapMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		interval, err := util.GetDurationOrDefault(config.Params, "interval", defaultNodeUtilizationHeatmapInterval)
+		if err != nil {
+			return nil, err
+		}
+		return nil, n.start(config.ClusterFramework.GetClientSets().GetClient(), config.ClusterFramework.GetPodStores(), interval)
+	case "gather":
+		return n.gather()
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (n *nodeUtilizationHeatmapMeasurement) Dispose() {
+	n.stop()
+}
+
+// String returns string representation of this measurement.
+func (*nodeUtilizationHeatmapMeasurement) String() string {
+	return nodeUtilizationHeatmapName
+}
+
+// Description returns a short description of the measurement.
+func (*nodeUtilizationHeatmapMeasurement) Description() string {
+	return "Samples requested cpu, memory and pods of every node relative to its allocatable resources."
+}
+
+func (n *nodeUtilizationHeatmapMeasurement) start(c clientset.Interface, podStores *measurementutil.SharedPodStores, interval time.Duration) error {
+	if n.isRunning {
+		logrus.Infof("%s: measurement already running", n)
+		return nil
+	}
+	ps, err := podStores.Get(measurementutil.NewObjectSelector())
+	if err != nil {
+		return fmt.Errorf("pod store creation error: %v", err)
+	}
+	n.isRunning = true
+	n.stopCh = make(chan struct{})
+	logrus.Infof("%s: starting collecting node utilization data", n)
+
+	go func() {
+		defer ps.Stop()
+		for {
+			select {
+			case <-n.stopCh:
+				return
+			case <-time.After(interval):
+				nodes, err := client.ListNodes(c)
+				if err != nil {
+					logrus.Errorf("%s: listing nodes error: %v", n, err)
+					continue
+				}
+				sample := computeNodeUtilization(nodes, ps.List())
+				n.lock.Lock()
+				n.samples = append(n.samples, sample)
+				n.lock.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+func (n *nodeUtilizationHeatmapMeasurement) stop() {
+	if n.isRunning {
+		close(n.stopCh)
+		n.isRunning = false
+	}
+}
+
+func (n *nodeUtilizationHeatmapMeasurement) gather() ([]measurement.Summary, error) {
+	if !n.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", nodeUtilizationHeatmapName)
+	}
+	n.stop()
+	logrus.Infof("%s: gathering data", n)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	heatmap := buildNodeUtilizationHeatmap(n.samples)
+	content, err := util.PrettyPrintJSON(heatmap)
+	if err != nil {
+		return nil, err
+	}
+	csvContent, err := heatmap.toCSV()
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{
+		measurement.CreateSummary(nodeUtilizationHeatmapName, "json", content),
+		measurement.CreateSummary(nodeUtilizationHeatmapName, "csv", csvContent),
+	}, nil
+}
+
+func computeNodeUtilization(nodes []corev1.Node, pods []*corev1.Pod) nodeUtilizationSample {
+	var cpuRequests, memoryRequests, podCounts = map[string]int64{}, map[string]int64{}, map[string]int64{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podCounts[pod.Spec.NodeName]++
+		for _, container := range pod.Spec.Containers {
+			cpuRequests[pod.Spec.NodeName] += container.Resources.Requests.Cpu().MilliValue()
+			memoryRequests[pod.Spec.NodeName] += container.Resources.Requests.Memory().Value()
+		}
+	}
+
+	sample := nodeUtilizationSample{
+		timestamp: time.Now(),
+		nodes:     make(map[string]nodeUtilization),
+	}
+	for _, node := range nodes {
+		if system.IsMasterNode(node.Name) {
+			continue
+		}
+		allocatable := node.Status.Allocatable
+		sample.nodes[node.Name] = nodeUtilization{
+			cpu:    utilizationRatio(cpuRequests[node.Name], allocatable.Cpu().MilliValue()),
+			memory: utilizationRatio(memoryRequests[node.Name], allocatable.Memory().Value()),
+			pods:   utilizationRatio(podCounts[node.Name], allocatable.Pods().Value()),
+		}
+	}
+	return sample
+}
+
+func utilizationRatio(value, capacity int64) float64 {
+	if capacity == 0 {
+		return 0
+	}
+	return float64(value) / float64(capacity)
+}
+
+func buildNodeUtilizationHeatmap(samples []nodeUtilizationSample) *nodeUtilizationHeatmap {
+	heatmap := &nodeUtilizationHeatmap{
+		Timestamps: []time.Time{},
+		Nodes:      []string{},
+	}
+	nodeSet := make(map[string]bool)
+	for _, sample := range samples {
+		heatmap.Timestamps = append(heatmap.Timestamps, sample.timestamp)
+		for node := range sample.nodes {
+			nodeSet[node] = true
+		}
+	}
+	for node := range nodeSet {
+		heatmap.Nodes = append(heatmap.Nodes, node)
+	}
+	sort.Strings(heatmap.Nodes)
+
+	for _, node := range heatmap.Nodes {
+		cpuRow := make([]*float64, len(samples))
+		memoryRow := make([]*float64, len(samples))
+		podsRow := make([]*float64, len(samples))
+		for i := range samples {
+			utilization, ok := samples[i].nodes[node]
+			if !ok {
+				continue
+			}
+			cpuRow[i] = &utilization.cpu
+			memoryRow[i] = &utilization.memory
+			podsRow[i] = &utilization.pods
+		}
+		heatmap.CPU = append(heatmap.CPU, cpuRow)
+		heatmap.Memory = append(heatmap.Memory, memoryRow)
+		heatmap.Pods = append(heatmap.Pods, podsRow)
+	}
+	return heatmap
+}
+
+// toCSV renders heatmap in a long format: one row per node and timestamp.
+func (h *nodeUtilizationHeatmap) toCSV() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"timestamp", "node", "cpu", "memory", "pods"}); err != nil {
+		return "", err
+	}
+	for i, node := range h.Nodes {
+		for j, timestamp := range h.Timestamps {
+			if h.CPU[i][j] == nil {
+				continue
+			}
+			record := []string{
+				timestamp.Format(time.RFC3339),
+				node,
+				strconv.FormatFloat(*h.CPU[i][j], 'f', 4, 64),
+				strconv.FormatFloat(*h.Memory[i][j], 'f', 4, 64),
+				strconv.FormatFloat(*h.Pods[i][j], 'f', 4, 64),
+			}
+			if err := w.Write(record); err != nil {
+				return "", err
+			}
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
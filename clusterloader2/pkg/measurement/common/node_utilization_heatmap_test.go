@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildNodeUtilizationHeatmap(t *testing.T) {
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	samples := []nodeUtilizationSample{
+		{
+			timestamp: t0,
+			nodes: map[string]nodeUtilization{
+				"node-b": {cpu: 0.5, memory: 0.25, pods: 0.1},
+			},
+		},
+		{
+			timestamp: t1,
+			nodes: map[string]nodeUtilization{
+				"node-a": {cpu: 1, memory: 0.5, pods: 0.2},
+				"node-b": {cpu: 0.75, memory: 0.5, pods: 0.3},
+			},
+		},
+	}
+
+	heatmap := buildNodeUtilizationHeatmap(samples)
+	assert.Equal(t, []string{"node-a", "node-b"}, heatmap.Nodes)
+	assert.Equal(t, []time.Time{t0, t1}, heatmap.Timestamps)
+	assert.Nil(t, heatmap.CPU[0][0])
+	assert.Equal(t, 1.0, *heatmap.CPU[0][1])
+	assert.Equal(t, 0.5, *heatmap.CPU[1][0])
+	assert.Equal(t, 0.3, *heatmap.Pods[1][1])
+
+	content, err := heatmap.toCSV()
+	assert.NoError(t, err)
+	expected := "timestamp,node,cpu,memory,pods\n" +
+		"2019-01-01T00:01:00Z,node-a,1.0000,0.5000,0.2000\n" +
+		"2019-01-01T00:00:00Z,node-b,0.5000,0.2500,0.1000\n" +
+		"2019-01-01T00:01:00Z,node-b,0.7500,0.5000,0.3000\n"
+	assert.Equal(t, expected, content)
+}
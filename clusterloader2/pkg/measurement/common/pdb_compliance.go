@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/informer"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	pdbComplianceName = "PodDisruptionBudgetCompliance"
+
+	// evictionWindow is the time after an eviction within which drops of healthy pods are attributed to it.
+	// It matches the timeout after which the disruption controller removes evicted pods from the status.
+	evictionWindow = 2 * time.Minute
+)
+
+func init() {
+	if err := measurement.Register(pdbComplianceName, createPDBComplianceMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", pdbComplianceName, err)
+	}
+}
+
+func createPDBComplianceMeasurement() measurement.Measurement {
+	return &pdbComplianceMeasurement{
+		selector:     measurementutil.NewObjectSelector(),
+		violations:   make(map[string]*pdbViolation),
+		lastEviction: make(map[string]time.Time),
+	}
+}
+
+type pdbComplianceMeasurement struct {
+	selector   *measurementutil.ObjectSelector
+	isRunning  bool
+	stopCh     chan struct{}
+	lock       sync.Mutex
+	violations map[string]*pdbViolation
+	// lastEviction is the time when an eviction of a pod covered by the budget was last observed.
+	lastEviction map[string]time.Time
+}
+
+// pdbViolation describes disruptions of a single workload
+// that brought the number of healthy pods below its budget.
+type pdbViolation struct {
+	PodDisruptionBudget string    `json:"podDisruptionBudget"`
+	DesiredHealthy      int32     `json:"desiredHealthy"`
+	LowestHealthy       int32     `json:"lowestHealthy"`
+	Count               int       `json:"count"`
+	FirstViolation      time.Time `json:"firstViolation"`
+}
+
+type pdbComplianceSummary struct {
+	Violations []*pdbViolation `json:"violations"`
+}
+
+// Execute supports two actions:
+// - start - starts observing pod disruption budgets.
+//   Budgets can be specified by field and/or label selectors.
+//   If namespace is not passed by parameter, all-namespace scope is assumed.
+// - gather - reports every budget that was violated between start and gather.
+// A budget is considered violated when its healthy pods count drops below
+// the desired healthy count while the number of expected pods does not shrink,
+// shortly after pods were evicted (i.e. listed in status.disruptedPods).
+// Involuntary disruptions, e.g. crashes or node failures, are not reported.
+func (p *pdbComplianceMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		if err := p.selector.Parse(config.Params); err != nil {
+			return nil, err
+		}
+		return nil, p.start(config.ClusterFramework.GetClientSets().GetClient())
+	case "gather":
+		return p.gather()
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (p *pdbComplianceMeasurement) Dispose() {
+	p.stop()
+}
+
+// String returns string representation of this measurement.
+func (p *pdbComplianceMeasurement) String() string {
+	return pdbComplianceName + ": " + p.selector.String()
+}
+
+// Description returns a short description of the measurement.
+func (p *pdbComplianceMeasurement) Description() string {
+	return "Reports pod disruption budgets violated during the test."
+}
+
+func (p *pdbComplianceMeasurement) start(c clientset.Interface) error {
+	if p.isRunning {
+		logrus.Infof("%s: measurement already running", p)
+		return nil
+	}
+	logrus.Infof("%s: starting pod disruption budget observation...", p)
+	p.isRunning = true
+	p.stopCh = make(chan struct{})
+	i := informer.NewInformerForRESTClient(
+		c.PolicyV1beta1().RESTClient(),
+		"poddisruptionbudgets",
+		p.selector,
+		p.checkPDB,
+	)
+	return informer.StartAndSync(i, p.stopCh, informerSyncTimeout)
+}
+
+func (p *pdbComplianceMeasurement) stop() {
+	if p.isRunning {
+		p.isRunning = false
+		close(p.stopCh)
+	}
+}
+
+func (p *pdbComplianceMeasurement) gather() ([]measurement.Summary, error) {
+	if !p.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", pdbComplianceName)
+	}
+	p.stop()
+	logrus.Infof("%s: gathering pod disruption budget violations", p)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	summary := pdbComplianceSummary{Violations: []*pdbViolation{}}
+	for _, violation := range p.violations {
+		summary.Violations = append(summary.Violations, violation)
+	}
+	sort.Slice(summary.Violations, func(i, j int) bool {
+		return summary.Violations[i].PodDisruptionBudget < summary.Violations[j].PodDisruptionBudget
+	})
+
+	var err error
+	if len(summary.Violations) > 0 {
+		err = errors.NewMetricViolationError("pod disruption budget", fmt.Sprintf("%d budgets violated", len(summary.Violations)))
+		logrus.Errorf("%s: %v", p, err)
+	}
+	content, jsonErr := util.PrettyPrintJSON(summary)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	return []measurement.Summary{measurement.CreateSummary(pdbComplianceName, "json", content)}, err
+}
+
+func (p *pdbComplianceMeasurement) checkPDB(oldObj, newObj interface{}) {
+	if oldObj == nil || newObj == nil {
+		return
+	}
+	oldPDB, ok := oldObj.(*policyv1beta1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+	newPDB, ok := newObj.(*policyv1beta1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+
+	key := createMetaNamespaceKey(newPDB.Namespace, newPDB.Name)
+	now := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if hasNewEvictions(&oldPDB.Status, &newPDB.Status) {
+		p.lastEviction[key] = now
+	}
+	if !isPDBViolated(&oldPDB.Status, &newPDB.Status) {
+		return
+	}
+	if lastEviction, ok := p.lastEviction[key]; !ok || now.Sub(lastEviction) > evictionWindow {
+		logrus.Infof("%s: %s has %d healthy pods, %d desired, but no pods were evicted", p, key, newPDB.Status.CurrentHealthy, newPDB.Status.DesiredHealthy)
+		return
+	}
+	violation, exists := p.violations[key]
+	if !exists {
+		violation = &pdbViolation{
+			PodDisruptionBudget: key,
+			LowestHealthy:       newPDB.Status.CurrentHealthy,
+			FirstViolation:      time.Now(),
+		}
+		p.violations[key] = violation
+		logrus.Warningf("%s: %s violated: %d healthy pods, %d desired", p, key, newPDB.Status.CurrentHealthy, newPDB.Status.DesiredHealthy)
+	}
+	violation.Count++
+	violation.DesiredHealthy = newPDB.Status.DesiredHealthy
+	if newPDB.Status.CurrentHealthy < violation.LowestHealthy {
+		violation.LowestHealthy = newPDB.Status.CurrentHealthy
+	}
+}
+
+// isPDBViolated checks whether status change corresponds to pods being
+// disrupted below the budget.
+func isPDBViolated(oldStatus, newStatus *policyv1beta1.PodDisruptionBudgetStatus) bool {
+	return newStatus.CurrentHealthy < oldStatus.CurrentHealthy &&
+		newStatus.CurrentHealthy < newStatus.DesiredHealthy &&
+		newStatus.ExpectedPods >= oldStatus.ExpectedPods
+}
+
+// hasNewEvictions checks whether pods were evicted, i.e. the eviction api added pods to disrupted pods.
+func hasNewEvictions(oldStatus, newStatus *policyv1beta1.PodDisruptionBudgetStatus) bool {
+	for pod := range newStatus.DisruptedPods {
+		if _, ok := oldStatus.DisruptedPods[pod]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func createMetaNamespaceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsPDBViolated(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldStatus policyv1beta1.PodDisruptionBudgetStatus
+		newStatus policyv1beta1.PodDisruptionBudgetStatus
+		want      bool
+	}{
+		{
+			name:      "disrupted-below-budget",
+			oldStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 3, DesiredHealthy: 3, ExpectedPods: 4},
+			newStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 2, DesiredHealthy: 3, ExpectedPods: 4},
+			want:      true,
+		},
+		{
+			name:      "disrupted-within-budget",
+			oldStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 4, DesiredHealthy: 3, ExpectedPods: 4},
+			newStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 3, DesiredHealthy: 3, ExpectedPods: 4},
+			want:      false,
+		},
+		{
+			name:      "pods-starting",
+			oldStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 1, DesiredHealthy: 3, ExpectedPods: 4},
+			newStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 2, DesiredHealthy: 3, ExpectedPods: 4},
+			want:      false,
+		},
+		{
+			name:      "scaled-down",
+			oldStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 4, DesiredHealthy: 3, ExpectedPods: 4},
+			newStatus: policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: 1, DesiredHealthy: 2, ExpectedPods: 2},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPDBViolated(&tt.oldStatus, &tt.newStatus); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckPDB(t *testing.T) {
+	newPDB := func(currentHealthy int32, disruptedPods ...string) *policyv1beta1.PodDisruptionBudget {
+		pdb := &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pdb"},
+			Status:     policyv1beta1.PodDisruptionBudgetStatus{CurrentHealthy: currentHealthy, DesiredHealthy: 3, ExpectedPods: 4},
+		}
+		if len(disruptedPods) > 0 {
+			pdb.Status.DisruptedPods = make(map[string]metav1.Time)
+			for _, pod := range disruptedPods {
+				pdb.Status.DisruptedPods[pod] = metav1.Now()
+			}
+		}
+		return pdb
+	}
+	tests := []struct {
+		name          string
+		updates       []*policyv1beta1.PodDisruptionBudget
+		wantViolation bool
+	}{
+		{
+			name:          "evicted-below-budget",
+			updates:       []*policyv1beta1.PodDisruptionBudget{newPDB(3), newPDB(3, "pod-1"), newPDB(2, "pod-1")},
+			wantViolation: true,
+		},
+		{
+			// The disruption controller removes evicted pods from the status once they are deleted.
+			name:          "evicted-pod-deleted",
+			updates:       []*policyv1beta1.PodDisruptionBudget{newPDB(3), newPDB(3, "pod-1"), newPDB(3), newPDB(2)},
+			wantViolation: true,
+		},
+		{
+			name:    "evicted-within-budget",
+			updates: []*policyv1beta1.PodDisruptionBudget{newPDB(4), newPDB(4, "pod-1"), newPDB(3, "pod-1")},
+		},
+		{
+			name:    "involuntary-disruption",
+			updates: []*policyv1beta1.PodDisruptionBudget{newPDB(3), newPDB(2), newPDB(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := createPDBComplianceMeasurement().(*pdbComplianceMeasurement)
+			for i := 1; i < len(tt.updates); i++ {
+				p.checkPDB(tt.updates[i-1], tt.updates[i])
+			}
+			if _, got := p.violations["default/pdb"]; got != tt.wantViolation {
+				t.Errorf("want violation %v, got %v", tt.wantViolation, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+// breakdownLabel maps a label of samples of a breakdown query to a label of data items.
+type breakdownLabel struct {
+	Sample   model.LabelName
+	DataItem string
+}
+
+// breakdownQuery returns latencies broken down by labels, e.g. by pair of zones.
+type breakdownQuery struct {
+	Query  string
+	Labels []breakdownLabel
+}
+
+// labeledLatency is a latency of a single combination of values of breakdown labels.
+type labeledLatency struct {
+	// values are values of breakdown labels, in the order of the labels.
+	values  []string
+	labels  []breakdownLabel
+	latency *measurementutil.LatencyMetric
+}
+
+// getLatencyBreakdown groups samples of the breakdown query by values of the labels.
+// Latencies are sorted by the values.
+func getLatencyBreakdown(samples []*model.Sample, labels []breakdownLabel) ([]labeledLatency, error) {
+	grouped := make(map[string][]*model.Sample)
+	var keys []string
+	for _, sample := range samples {
+		var values []string
+		for _, label := range labels {
+			values = append(values, string(sample.Metric[label.Sample]))
+		}
+		key := strings.Join(values, "\x00")
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], sample)
+	}
+	sort.Strings(keys)
+	var latencies []labeledLatency
+	for _, key := range keys {
+		latency, err := measurementutil.NewLatencyMetricPrometheus(grouped[key])
+		if err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, labeledLatency{values: strings.Split(key, "\x00"), labels: labels, latency: latency})
+	}
+	return latencies, nil
+}
+
+// String returns values of breakdown labels, e.g. "SourceZone=a TargetZone=b".
+func (l *labeledLatency) String() string {
+	var parts []string
+	for i, label := range l.labels {
+		parts = append(parts, label.DataItem+"="+l.values[i])
+	}
+	return strings.Join(parts, " ")
+}
+
+// toPerfData converts the latency into data item labeled with values of breakdown labels.
+func (l *labeledLatency) toPerfData(name string) measurementutil.DataItem {
+	dataItem := l.latency.ToPerfData(name)
+	for i, label := range l.labels {
+		dataItem.Labels[label.DataItem] = l.values[i]
+	}
+	return dataItem
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// customProbeName is the name of the measurement running probes defined in the test config.
+const customProbeName = "CustomProbe"
+
+func createCustomProber() *probesMeasurement {
+	return &probesMeasurement{
+		config: proberConfig{Name: customProbeName, Description: "Runs probes defined by manifests and queries given in the test config."},
+		custom: true,
+	}
+}
+
+// parseCustomProberConfig creates config of the probe defined by params of the measurement.
+// Relative manifests paths are resolved against configDir, the directory of the test config.
+func parseCustomProberConfig(params map[string]interface{}, configDir string) (proberConfig, error) {
+	var config proberConfig
+	var err error
+	if config.Name, err = util.GetString(params, "name"); err != nil {
+		return config, err
+	}
+	if config.Query, err = util.GetString(params, "query"); err != nil {
+		return config, fmt.Errorf("probe %s: %v", config.Name, err)
+	}
+	manifests, err := util.GetString(params, "manifests")
+	if err != nil {
+		return config, fmt.Errorf("probe %s: %v", config.Name, err)
+	}
+	manifests = os.ExpandEnv(manifests)
+	if !filepath.IsAbs(manifests) {
+		manifests = filepath.Join(configDir, manifests)
+	}
+	config.Manifests = manifests
+	config.LocalManifests = true
+	if config.ProbeLabelValues, err = getStringList(params, "jobs"); err != nil {
+		return config, fmt.Errorf("probe %s: %v", config.Name, err)
+	}
+	if len(config.ProbeLabelValues) == 0 {
+		return config, fmt.Errorf("probe %s: jobs param is missing", config.Name)
+	}
+	if config.MetricVersion, err = util.GetStringOrDefault(params, "metricVersion", "v1"); err != nil {
+		return config, fmt.Errorf("probe %s: %v", config.Name, err)
+	}
+	return config, nil
+}
+
+func getStringList(params map[string]interface{}, key string) ([]string, error) {
+	raw, ok := params[key]
+	if !ok {
+		return nil, nil
+	}
+	rawList, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s param: type assertion error: %v is not a list", key, raw)
+	}
+	var values []string
+	for _, rawValue := range rawList {
+		value, ok := rawValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s param: type assertion error: %v is not a string", key, rawValue)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// getTemplateFillMap returns templateFillMap param, which is added to the mapping of manifests of custom probes.
+func getTemplateFillMap(params map[string]interface{}) (map[string]interface{}, error) {
+	raw, ok := params["templateFillMap"]
+	if !ok {
+		return nil, nil
+	}
+	fillMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("templateFillMap param: type assertion error: %v is not a map", raw)
+	}
+	return fillMap, nil
+}
+
+// validateCustomManifests renders manifests of the custom probe with given mapping.
+func validateCustomManifests(probeConfig proberConfig, templateMapping map[string]interface{}) error {
+	manifests, err := filepath.Glob(probeConfig.Manifests)
+	if err != nil {
+		return fmt.Errorf("probe %s: %v", probeConfig.Name, err)
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("probe %s: no manifests match %s", probeConfig.Name, probeConfig.Manifests)
+	}
+	templateProvider := config.NewTemplateProvider(filepath.Dir(probeConfig.Manifests))
+	for _, manifest := range manifests {
+		if _, err := templateProvider.TemplateToObject(filepath.Base(manifest), templateMapping); err != nil && err != config.ErrorEmptyFile {
+			return fmt.Errorf("probe %s: reading manifest (%s) error: %v", probeConfig.Name, manifest, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCustomProberConfig(t *testing.T) {
+	params := map[string]interface{}{
+		"name":      "EgressLatency",
+		"query":     "quantile_over_time(0.99, probes:egress_latency:histogram_quantile[%v])",
+		"manifests": "probes/egress/*.yaml",
+		"jobs":      []interface{}{"egress-prober"},
+	}
+	config, err := parseCustomProberConfig(params, "/testing/load")
+	assert.NoError(t, err)
+	assert.Equal(t, proberConfig{
+		Name:             "EgressLatency",
+		MetricVersion:    "v1",
+		Query:            "quantile_over_time(0.99, probes:egress_latency:histogram_quantile[%v])",
+		Manifests:        "/testing/load/probes/egress/*.yaml",
+		LocalManifests:   true,
+		ProbeLabelValues: []string{"egress-prober"},
+	}, config)
+
+	params["manifests"] = "/opt/probes/*.yaml"
+	config, err = parseCustomProberConfig(params, "/testing/load")
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/probes/*.yaml", config.Manifests)
+
+	for _, missing := range []string{"name", "query", "manifests", "jobs"} {
+		incomplete := make(map[string]interface{})
+		for k, v := range params {
+			if k != missing {
+				incomplete[k] = v
+			}
+		}
+		_, err := parseCustomProberConfig(incomplete, "/testing/load")
+		assert.Error(t, err, "missing %s", missing)
+	}
+
+	params["jobs"] = []interface{}{"egress-prober", 1}
+	_, err = parseCustomProberConfig(params, "/testing/load")
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+// embeddedManifests are manifests of probes, so that the binary can be run outside of the source tree.
+//
+//go:embed manifests
+var embeddedManifests embed.FS
+
+// manifestsFS returns filesystem with manifests of probes. Manifests are read from the directory
+// if it's not empty, so that they can be customized without rebuilding the binary.
+func manifestsFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embeddedManifests, "manifests")
+}
@@ -18,11 +18,14 @@ package probes
 
 import (
 	"fmt"
-	"path"
+	"math"
+	"path/filepath"
 	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
@@ -35,8 +38,6 @@ import (
 const (
 	probesNamespace = "probes"
 
-	manifestsPathPrefix = "$GOPATH/src/k8s.io/perf-tests/clusterloader2/pkg/measurement/common/probes/manifests/"
-
 	checkProbesReadyInterval = 15 * time.Second
 	checkProbesReadyTimeout  = 5 * time.Minute
 )
@@ -44,19 +45,49 @@ const (
 var (
 	networkLatencyConfig = proberConfig{
 		Name:             "InClusterNetworkLatency",
+		Description:      "Measures in-cluster network latency between probe pods.",
 		MetricVersion:    "v1",
 		Query:            "quantile_over_time(0.99, probes:in_cluster_network_latency:histogram_quantile[%v])",
 		Manifests:        "*.yaml",
 		ProbeLabelValues: []string{"ping-client", "ping-server"},
+		ZoneQuery:        "quantile_over_time(0.99, probes:in_cluster_network_latency_by_zone:histogram_quantile[%v])",
+		ZoneManifests:    "zones/*.yaml",
+		ProtocolQuery:    "quantile_over_time(0.99, probes:in_cluster_network_latency_by_protocol:histogram_quantile[%v])",
 	}
 
 	dnsLookupConfig = proberConfig{
 		Name:             "DnsLookupLatency",
+		Description:      "Measures latency of DNS lookups of in-cluster names.",
 		MetricVersion:    "v1",
 		Query:            "quantile_over_time(0.99, probes:dns_lookup_latency:histogram_quantile[%v])",
 		Manifests:        "dnsLookup/*yaml",
 		ProbeLabelValues: []string{"dns"},
 	}
+
+	egressLatencyConfig = proberConfig{
+		Name:             "EgressLatency",
+		Description:      "Measures latency and error ratio of requests to a target outside of the cluster.",
+		MetricVersion:    "v1",
+		Query:            "quantile_over_time(0.99, probes:egress_latency:histogram_quantile[%v])",
+		ErrorRatioQuery:  `sum(increase(probes_egress_error{namespace="probes"}[%[1]v])) / sum(increase(probes_egress_request_count{namespace="probes"}[%[1]v]))`,
+		Manifests:        "egress/*.yaml",
+		ProbeLabelValues: []string{"egress"},
+		TemplateParams:   map[string]string{"target": "Target"},
+	}
+
+	nodeLatencyConfig = proberConfig{
+		Name:          "NodeLatency",
+		Description:   "Measures pod IP assignment, image pull and container start latency on every node, as observed through the apiserver.",
+		MetricVersion: "v1",
+		Queries: []proberQuery{
+			{Name: "PodIPObserved", Query: "quantile_over_time(0.99, probes:node_pod_ip_observed_latency:histogram_quantile[%v])"},
+			{Name: "ImagePull", Query: "quantile_over_time(0.99, probes:node_image_pull_latency:histogram_quantile[%v])"},
+			{Name: "ContainerStart", Query: "quantile_over_time(0.99, probes:node_container_start_latency:histogram_quantile[%v])"},
+		},
+		Manifests:        "nodeLatency/*.yaml",
+		ProbeLabelValues: []string{"node-latency"},
+		RunOnEveryNode:   true,
+	}
 )
 
 func init() {
@@ -68,14 +99,52 @@ func init() {
 	if err := measurement.Register(dnsLookupConfig.Name, create); err != nil {
 		logrus.Errorf("cannot register %s: %v", dnsLookupConfig.Name, err)
 	}
+	create = func() measurement.Measurement { return createProber(egressLatencyConfig) }
+	if err := measurement.Register(egressLatencyConfig.Name, create); err != nil {
+		logrus.Errorf("cannot register %s: %v", egressLatencyConfig.Name, err)
+	}
+	create = func() measurement.Measurement { return createProber(nodeLatencyConfig) }
+	if err := measurement.Register(nodeLatencyConfig.Name, create); err != nil {
+		logrus.Errorf("cannot register %s: %v", nodeLatencyConfig.Name, err)
+	}
+	create = func() measurement.Measurement { return createCustomProber() }
+	if err := measurement.Register(customProbeName, create); err != nil {
+		logrus.Errorf("cannot register %s: %v", customProbeName, err)
+	}
 }
 
 type proberConfig struct {
-	Name             string
-	MetricVersion    string
-	Query            string
-	Manifests        string
+	Name string
+	// Description is a short description of what the probe measures.
+	Description   string
+	MetricVersion string
+	Query         string
+	// Queries are used instead of Query if the probe reports more than one latency.
+	Queries []proberQuery
+	// ErrorRatioQuery returns the ratio of failed requests of the probe. If empty, errors are not reported.
+	ErrorRatioQuery string
+	Manifests       string
+	// TemplateParams maps required params of the measurement to keys of the mapping of manifests.
+	TemplateParams map[string]string
+	// LocalManifests is set if Manifests is a glob of the local filesystem rather than of probes manifests.
+	LocalManifests   bool
 	ProbeLabelValues []string
+	// RunOnEveryNode is set if probes are run by a DaemonSet, i.e. a single replica on every node.
+	RunOnEveryNode bool
+	// ZoneQuery returns latencies broken down by source_zone and target_zone labels. If empty,
+	// zone breakdown isn't supported by the probe.
+	ZoneQuery string
+	// ZoneManifests create probes of every zone (ping-servers) and pair of zones (ping-clients)
+	// in zone breakdown mode, in addition to Manifests.
+	ZoneManifests string
+	// ProtocolQuery returns latencies broken down by protocol, target and payload_size labels.
+	// If empty, the protocol matrix isn't supported by the probe.
+	ProtocolQuery string
+}
+
+type proberQuery struct {
+	Name  string
+	Query string
 }
 
 func createProber(config proberConfig) measurement.Measurement {
@@ -86,26 +155,86 @@ func createProber(config proberConfig) measurement.Measurement {
 
 type probesMeasurement struct {
 	config proberConfig
+	// custom is set if config is defined by params of the measurement, see CustomProbe.
+	custom bool
 
 	framework        *framework.Framework
-	replicasPerProbe int
-	templateMapping  map[string]interface{}
-	startTime        time.Time
+	prometheusConfig *config.PrometheusConfig
+	expectedTargets  int
+	// zones are set in zone breakdown mode.
+	zones []probeZone
+	// breakdowns are queries of latencies reported in addition to the aggregated ones.
+	breakdowns      []breakdownQuery
+	threshold       time.Duration
+	maxErrorRatio   float64
+	templateMapping map[string]interface{}
+	startTime       time.Time
+}
+
+// RequiredCapabilities returns capabilities required by the measurement.
+// Probes cannot work in kubemark, as hollow nodes don't run containers.
+func (p *probesMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return []measurement.Capability{measurement.RealNodes, measurement.Prometheus}
+}
+
+// Validate checks params of the start call. Manifests of custom probes are rendered
+// with the params, so that errors in them are found before the test is run.
+func (p *probesMeasurement) Validate(config *measurement.MeasurementConfig) error {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "start":
+	case "gather":
+		return nil
+	default:
+		return fmt.Errorf("unknown action %v", action)
+	}
+	if !p.custom {
+		if !p.config.RunOnEveryNode {
+			if _, err := util.GetInt(config.Params, "replicasPerProbe"); err != nil {
+				return err
+			}
+		}
+		for param := range p.config.TemplateParams {
+			if _, err := util.GetString(config.Params, param); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	configDir := ""
+	if config.ClusterLoaderConfig != nil {
+		configDir = filepath.Dir(config.ClusterLoaderConfig.TestScenario.ConfigPath)
+	}
+	customConfig, err := parseCustomProberConfig(config.Params, configDir)
+	if err != nil {
+		return err
+	}
+	replicasPerProbe, err := util.GetIntOrDefault(config.Params, "replicasPerProbe", 1)
+	if err != nil {
+		return err
+	}
+	trackImagePulls, err := util.GetBoolOrDefault(config.Params, "trackImagePulls", false)
+	if err != nil {
+		return err
+	}
+	fillMap, err := getTemplateFillMap(config.Params)
+	if err != nil {
+		return err
+	}
+	templateMapping := map[string]interface{}{"Replicas": replicasPerProbe, "TrackImagePulls": trackImagePulls}
+	for k, v := range fillMap {
+		templateMapping[k] = v
+	}
+	return validateCustomManifests(customConfig, templateMapping)
 }
 
 // Execute supports two actions:
 // - start - starts probes and sets up monitoring
 // - gather - Gathers and prints metrics.
 func (p *probesMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
-	if config.CloudProvider == "kubemark" {
-		logrus.Infof("%s: Probes cannot work in Kubemark, skipping the measurement!", p)
-		return nil, nil
-	}
-	if config.PrometheusFramework == nil {
-		logrus.Warningf("%s: Prometheus is disabled, skipping the measurement!", p)
-		return nil, nil
-	}
-
 	action, err := util.GetString(config.Params, "action")
 	if err != nil {
 		return nil, err
@@ -145,14 +274,126 @@ func (p *probesMeasurement) String() string {
 	return p.config.Name
 }
 
+// Description returns a short description of the measurement.
+func (p *probesMeasurement) Description() string {
+	return p.config.Description
+}
+
 func (p *probesMeasurement) initialize(config *measurement.MeasurementConfig) error {
-	replicasPerProbe, err := util.GetInt(config.Params, "replicasPerProbe")
+	if p.custom {
+		configDir := ""
+		if config.ClusterLoaderConfig != nil {
+			configDir = filepath.Dir(config.ClusterLoaderConfig.TestScenario.ConfigPath)
+		}
+		customConfig, err := parseCustomProberConfig(config.Params, configDir)
+		if err != nil {
+			return err
+		}
+		p.config = customConfig
+	}
+	var replicasPerProbe int
+	var err error
+	switch {
+	case p.config.RunOnEveryNode:
+		replicasPerProbe, err = util.GetSchedulableUntainedNodesNumber(config.ClusterFramework.GetClientSets().GetClient())
+	case p.custom:
+		replicasPerProbe, err = util.GetIntOrDefault(config.Params, "replicasPerProbe", 1)
+	default:
+		replicasPerProbe, err = util.GetInt(config.Params, "replicasPerProbe")
+	}
+	if err != nil {
+		return err
+	}
+	zoneBreakdown, err := util.GetBoolOrDefault(config.Params, "zoneBreakdown", false)
+	if err != nil {
+		return err
+	}
+	protocols, err := getProtocols(config.Params)
+	if err != nil {
+		return err
+	}
+	payloadSizes, err := getPayloadSizes(config.Params, "64")
+	if err != nil {
+		return err
+	}
+	expectedTargets := replicasPerProbe * len(p.config.ProbeLabelValues)
+	var breakdowns []breakdownQuery
+	var zones []probeZone
+	var zoneLabel string
+	if zoneBreakdown {
+		if p.config.ZoneQuery == "" {
+			return fmt.Errorf("zone breakdown is not supported by %s", p)
+		}
+		if zoneLabel, err = util.GetStringOrDefault(config.Params, "zoneLabel", defaultZoneLabel); err != nil {
+			return err
+		}
+		nodes, err := util.GetSchedulableUntainedNodes(config.ClusterFramework.GetClientSets().GetClient())
+		if err != nil {
+			return err
+		}
+		if zones, err = getProbeZones(nodes, zoneLabel); err != nil {
+			return err
+		}
+		// Probes of every zone and of every pair of zones.
+		expectedTargets = replicasPerProbe * (len(zones) + len(zones)*len(zones))
+		breakdowns = append(breakdowns, breakdownQuery{Query: p.config.ZoneQuery, Labels: zoneBreakdownLabels})
+	}
+	if protocols != "" {
+		if p.config.ProtocolQuery == "" {
+			return fmt.Errorf("protocol matrix is not supported by %s", p)
+		}
+		if zoneBreakdown {
+			return fmt.Errorf("protocol matrix cannot be combined with zone breakdown")
+		}
+		breakdowns = append(breakdowns, breakdownQuery{Query: p.config.ProtocolQuery, Labels: protocolBreakdownLabels})
+	}
+	if expectedTargets, err = util.GetIntOrDefault(config.Params, "expectedTargets", expectedTargets); err != nil {
+		return err
+	}
+	threshold, err := util.GetDurationOrDefault(config.Params, "threshold", 0)
 	if err != nil {
 		return err
 	}
+	maxErrorRatio, err := util.GetFloat64OrDefault(config.Params, "maxErrorRatio", 0)
+	if err != nil {
+		return err
+	}
+	trackImagePulls, err := util.GetBoolOrDefault(config.Params, "trackImagePulls", false)
+	if err != nil {
+		return err
+	}
+	templateMapping := map[string]interface{}{"Replicas": replicasPerProbe, "TrackImagePulls": trackImagePulls}
+	if zoneBreakdown {
+		templateMapping["Zones"] = zones
+		templateMapping["ZonePairs"] = getProbeZonePairs(zones)
+		templateMapping["ZoneLabel"] = zoneLabel
+	}
+	for param, key := range p.config.TemplateParams {
+		if templateMapping[key], err = util.GetString(config.Params, param); err != nil {
+			return err
+		}
+	}
+	if protocols != "" {
+		templateMapping["Protocols"] = protocols
+		templateMapping["PayloadSizes"] = payloadSizes
+	}
+	if p.custom {
+		fillMap, err := getTemplateFillMap(config.Params)
+		if err != nil {
+			return err
+		}
+		for k, v := range fillMap {
+			templateMapping[k] = v
+		}
+	}
 	p.framework = config.ClusterFramework
-	p.replicasPerProbe = replicasPerProbe
-	p.templateMapping = map[string]interface{}{"Replicas": replicasPerProbe}
+	p.prometheusConfig = config.GetPrometheusConfig()
+	p.expectedTargets = expectedTargets
+	p.zones = zones
+	p.breakdowns = breakdowns
+	p.threshold = threshold
+	p.maxErrorRatio = maxErrorRatio
+	p.templateMapping = templateMapping
 	return nil
 }
 
@@ -165,7 +406,7 @@ func (p *probesMeasurement) start(config *measurement.MeasurementConfig) error {
 		return err
 	}
 	k8sClient := p.framework.GetClientSets().GetClient()
-	if err := client.CreateNamespace(k8sClient, probesNamespace); err != nil {
+	if err := client.CreateNamespace(k8sClient, probesNamespace, p.framework.GetClusterConfig().RunID); err != nil {
 		return err
 	}
 	if err := p.createProbesObjects(); err != nil {
@@ -183,44 +424,123 @@ func (p *probesMeasurement) gather(params map[string]interface{}) (measurement.S
 	if p.startTime.IsZero() {
 		return nil, fmt.Errorf("measurement %s has not been started", p)
 	}
-	threshold, err := util.GetDurationOrDefault(params, "threshold", 0)
+	threshold, err := util.GetDurationOrDefault(params, "threshold", p.threshold)
 	if err != nil {
 		return nil, err
 	}
-	measurementEnd := time.Now()
-
-	query := prepareQuery(p.config.Query, p.startTime, measurementEnd)
-	executor := measurementutil.NewQueryExecutor(p.framework.GetClientSets().GetClient())
-	samples, err := executor.Query(query, measurementEnd)
+	maxErrorRatio, err := util.GetFloat64OrDefault(params, "maxErrorRatio", p.maxErrorRatio)
 	if err != nil {
 		return nil, err
 	}
+	measurementEnd := time.Now()
 
-	latency, err := measurementutil.NewLatencyMetricPrometheus(samples)
+	queries := p.config.Queries
+	if len(queries) == 0 {
+		queries = []proberQuery{{Name: p.String(), Query: p.config.Query}}
+	}
+	executor, err := measurementutil.GetQueryExecutor(p.framework.GetClientSets().GetClient(), p.prometheusConfig, measurementutil.DefaultQueryRetryPolicy)
 	if err != nil {
 		return nil, err
 	}
+	var dataItems []measurementutil.DataItem
+	violations := errors.NewErrorList()
+	for _, q := range queries {
+		query := prepareQuery(q.Query, p.startTime, measurementEnd)
+		samples, err := executor.Query(query, measurementEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		latency, err := measurementutil.NewLatencyMetricPrometheus(samples)
+		if err != nil {
+			return nil, err
+		}
 
-	var violation error
-	prefix, suffix := "", ""
-	if threshold > 0 {
-		suffix = fmt.Sprintf(", expected perc99 <= %v", threshold)
-		if err := latency.VerifyThreshold(threshold); err != nil {
-			violation = errors.NewMetricViolationError(p.String(), err.Error())
+		prefix, suffix := "", ""
+		if threshold > 0 {
+			suffix = fmt.Sprintf(", expected perc99 <= %v", threshold)
+			if err := latency.VerifyThreshold(threshold); err != nil {
+				violations.Append(errors.NewMetricViolationError(q.Name, err.Error()))
+				prefix = " WARNING"
+			}
+		}
+		logrus.Infof("%s:%s got %v%s", q.Name, prefix, latency, suffix)
+		dataItems = append(dataItems, latency.ToPerfData(q.Name))
+	}
+	for _, b := range p.breakdowns {
+		samples, err := executor.Query(prepareQuery(b.Query, p.startTime, measurementEnd), measurementEnd)
+		if err != nil {
+			return nil, err
+		}
+		latencies, err := getLatencyBreakdown(samples, b.Labels)
+		if err != nil {
+			return nil, err
+		}
+		for i := range latencies {
+			l := &latencies[i]
+			name := fmt.Sprintf("%s %s", p, l)
+			prefix := ""
+			if threshold > 0 {
+				if err := l.latency.VerifyThreshold(threshold); err != nil {
+					violations.Append(errors.NewMetricViolationError(name, err.Error()))
+					prefix = " WARNING"
+				}
+			}
+			logrus.Infof("%s:%s got %v", name, prefix, l.latency)
+			dataItems = append(dataItems, l.toPerfData(p.String()))
+		}
+	}
+	if p.config.ErrorRatioQuery != "" {
+		samples, err := executor.Query(prepareQuery(p.config.ErrorRatioQuery, p.startTime, measurementEnd), measurementEnd)
+		if err != nil {
+			return nil, err
+		}
+		name := p.String() + "Errors"
+		ratio := getErrorRatio(samples)
+		prefix := ""
+		if maxErrorRatio > 0 && ratio > maxErrorRatio {
+			violations.Append(errors.NewMetricViolationError(name, fmt.Sprintf("error ratio %.4f is higher than %.4f", ratio, maxErrorRatio)))
 			prefix = " WARNING"
 		}
+		logrus.Infof("%s:%s got error ratio %.4f", name, prefix, ratio)
+		dataItems = append(dataItems, measurementutil.DataItem{
+			Data:   map[string]float64{"Ratio": ratio},
+			Unit:   "ratio",
+			Labels: map[string]string{"Metric": name},
+		})
 	}
-	logrus.Infof("%s:%s got %v%s", p, prefix, latency, suffix)
 
-	summary, err := p.createSummary(*latency)
+	summary, err := p.createSummary(dataItems)
 	if err != nil {
 		return nil, err
 	}
-	return summary, violation
+	if !violations.IsEmpty() {
+		return summary, violations
+	}
+	return summary, nil
+}
+
+// Checkpoint gathers metrics of probes since the start, probes keep running.
+func (p *probesMeasurement) Checkpoint(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	summary, err := p.gather(config.Params)
+	return []measurement.Summary{summary}, err
 }
 
 func (p *probesMeasurement) createProbesObjects() error {
-	return p.framework.ApplyTemplatedManifests(path.Join(manifestsPathPrefix, p.config.Manifests), p.templateMapping)
+	if p.config.LocalManifests {
+		return p.framework.ApplyTemplatedManifests(p.config.Manifests, p.templateMapping)
+	}
+	manifests, err := manifestsFS(p.prometheusConfig.ProbesManifestsDir)
+	if err != nil {
+		return fmt.Errorf("probes manifests error: %v", err)
+	}
+	if err := p.framework.ApplyTemplatedManifestsFS(manifests, p.config.Manifests, p.templateMapping); err != nil {
+		return err
+	}
+	if len(p.zones) > 0 {
+		return p.framework.ApplyTemplatedManifestsFS(manifests, p.config.ZoneManifests, p.templateMapping)
+	}
+	return nil
 }
 
 func (p *probesMeasurement) waitForProbesReady() error {
@@ -240,15 +560,14 @@ func (p *probesMeasurement) checkProbesReady() (bool, error) {
 		}
 		return false
 	}
-	expectedTargets := p.replicasPerProbe * len(p.config.ProbeLabelValues)
 	return prometheus.CheckAllTargetsReady(
-		p.framework.GetClientSets().GetClient(), selector, expectedTargets)
+		p.framework.GetClientSets().GetClient(), selector, p.expectedTargets)
 }
 
-func (p *probesMeasurement) createSummary(latency measurementutil.LatencyMetric) (measurement.Summary, error) {
+func (p *probesMeasurement) createSummary(dataItems []measurementutil.DataItem) (measurement.Summary, error) {
 	content, err := util.PrettyPrintJSON(&measurementutil.PerfData{
 		Version:   p.config.MetricVersion,
-		DataItems: []measurementutil.DataItem{latency.ToPerfData(p.String())},
+		DataItems: dataItems,
 	})
 	if err != nil {
 		return nil, err
@@ -256,6 +575,15 @@ func (p *probesMeasurement) createSummary(latency measurementutil.LatencyMetric)
 	return measurement.CreateSummary(p.String(), "json", content), nil
 }
 
+// getErrorRatio returns the value of the error ratio query. The ratio is 0 if there were no requests
+// (the query returns no samples or NaN).
+func getErrorRatio(samples []*model.Sample) float64 {
+	if len(samples) == 0 || math.IsNaN(float64(samples[0].Value)) {
+		return 0
+	}
+	return float64(samples[0].Value)
+}
+
 func prepareQuery(queryTemplate string, startTime, endTime time.Time) string {
 	measurementDuration := endTime.Sub(startTime)
 	return fmt.Sprintf(queryTemplate, measurementutil.ToPrometheusTime(measurementDuration))
@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func TestGetErrorRatio(t *testing.T) {
+	assert.Equal(t, 0.0, getErrorRatio(nil))
+	assert.Equal(t, 0.0, getErrorRatio([]*model.Sample{{Value: model.SampleValue(math.NaN())}}))
+	assert.Equal(t, 0.25, getErrorRatio([]*model.Sample{{Value: 0.25}}))
+}
+
+func TestEgressManifests(t *testing.T) {
+	manifests, err := manifestsFS("")
+	assert.NoError(t, err)
+	provider := config.NewTemplateProviderFS(manifests, "egress")
+	obj, err := provider.TemplateToObject("egress-prober-deployment.yaml", map[string]interface{}{"Replicas": 1, "Target": "http://example.com/"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if !assert.NoError(t, err) || !assert.Len(t, containers, 1) {
+		return
+	}
+	assert.Contains(t, containers[0].(map[string]interface{})["args"], "--egress-target=http://example.com/")
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pingProtocols are protocols supported by ping-client's protocol matrix.
+var pingProtocols = map[string]bool{"http": true, "tcp": true, "udp": true}
+
+// protocolBreakdownLabels are labels of samples of protocol matrix queries.
+var protocolBreakdownLabels = []breakdownLabel{
+	{Sample: "protocol", DataItem: "Protocol"},
+	{Sample: "target", DataItem: "Target"},
+	{Sample: "payload_size", DataItem: "PayloadSize"},
+}
+
+// getProtocols returns protocols param as a comma-separated list passed to ping-clients.
+// Empty string is returned if the param is not set.
+func getProtocols(params map[string]interface{}) (string, error) {
+	protocols, err := getStringList(params, "protocols")
+	if err != nil {
+		return "", err
+	}
+	for _, protocol := range protocols {
+		if !pingProtocols[protocol] {
+			return "", fmt.Errorf("protocols param: unknown protocol %q", protocol)
+		}
+	}
+	return strings.Join(protocols, ","), nil
+}
+
+// getPayloadSizes returns payloadSizes param as a comma-separated list passed to ping-clients.
+func getPayloadSizes(params map[string]interface{}, defaultValue string) (string, error) {
+	raw, ok := params["payloadSizes"]
+	if !ok {
+		return defaultValue, nil
+	}
+	rawList, ok := raw.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("payloadSizes param: type assertion error: %v is not a list", raw)
+	}
+	var sizes []string
+	for _, rawSize := range rawList {
+		var size int
+		switch v := rawSize.(type) {
+		case int:
+			size = v
+		case float64:
+			size = int(v)
+		default:
+			return "", fmt.Errorf("payloadSizes param: type assertion error: %v is not an int", rawSize)
+		}
+		if size <= 0 {
+			return "", fmt.Errorf("payloadSizes param: incorrect payload size %d", size)
+		}
+		sizes = append(sizes, strconv.Itoa(size))
+	}
+	return strings.Join(sizes, ","), nil
+}
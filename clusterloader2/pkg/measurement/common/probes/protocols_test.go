@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func TestGetProtocols(t *testing.T) {
+	testCases := []struct {
+		params  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{params: map[string]interface{}{}, want: ""},
+		{params: map[string]interface{}{"protocols": []interface{}{"udp", "tcp", "http"}}, want: "udp,tcp,http"},
+		{params: map[string]interface{}{"protocols": []interface{}{"icmp"}}, wantErr: true},
+		{params: map[string]interface{}{"protocols": "udp"}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := getProtocols(tc.params)
+		assert.Equal(t, tc.wantErr, err != nil, "params %v", tc.params)
+		assert.Equal(t, tc.want, got, "params %v", tc.params)
+	}
+}
+
+func TestGetPayloadSizes(t *testing.T) {
+	testCases := []struct {
+		params  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{params: map[string]interface{}{}, want: "64"},
+		{params: map[string]interface{}{"payloadSizes": []interface{}{64, float64(1400)}}, want: "64,1400"},
+		{params: map[string]interface{}{"payloadSizes": []interface{}{0}}, wantErr: true},
+		{params: map[string]interface{}{"payloadSizes": []interface{}{"64"}}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := getPayloadSizes(tc.params, "64")
+		assert.Equal(t, tc.wantErr, err != nil, "params %v", tc.params)
+		assert.Equal(t, tc.want, got, "params %v", tc.params)
+	}
+}
+
+func TestProtocolManifests(t *testing.T) {
+	manifests, err := manifestsFS("")
+	assert.NoError(t, err)
+	provider := config.NewTemplateProviderFS(manifests, ".")
+	mapping := map[string]interface{}{"Replicas": 2, "Protocols": "http,udp", "PayloadSizes": "64"}
+	for _, file := range []string{"ping-client-deployment.yaml", "ping-server-deployment.yaml", "ping-server-pods-service.yaml"} {
+		_, err := provider.TemplateToObject(file, mapping)
+		assert.NoError(t, err, file)
+	}
+
+	// Headless service is created for the protocol matrix only.
+	_, err = provider.TemplateToObject("ping-server-pods-service.yaml", map[string]interface{}{"Replicas": 2})
+	assert.Equal(t, config.ErrorEmptyFile, err)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+	// maxZoneIDLength limits IDs of zones, so that names of objects of zone pairs are valid.
+	maxZoneIDLength = 20
+)
+
+// zoneBreakdownLabels are labels of samples of zone breakdown queries, taken from pod labels of probes.
+var zoneBreakdownLabels = []breakdownLabel{
+	{Sample: "source_zone", DataItem: "SourceZone"},
+	{Sample: "target_zone", DataItem: "TargetZone"},
+}
+
+// probeZone is a zone, or any other group of nodes (e.g. a node pool), probes are scheduled in.
+type probeZone struct {
+	// Name is the value of the zone label of nodes.
+	Name string
+	// ID is the name sanitized to be used in names of objects.
+	ID string
+}
+
+// probeZonePair is a pair of zones, probes of the source zone probe the target zone.
+type probeZonePair struct {
+	Source probeZone
+	Target probeZone
+}
+
+// getProbeZones returns sorted zones of the nodes, based on the zone label.
+func getProbeZones(nodes []corev1.Node, zoneLabel string) ([]probeZone, error) {
+	names := make(map[string]bool)
+	for i := range nodes {
+		if name, ok := nodes[i].Labels[zoneLabel]; ok && name != "" {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no schedulable nodes with %s label", zoneLabel)
+	}
+	var zones []probeZone
+	ids := make(map[string]string)
+	for name := range names {
+		id := zoneID(name)
+		if other, exists := ids[id]; exists {
+			return nil, fmt.Errorf("zones %q and %q cannot be distinguished in object names", name, other)
+		}
+		ids[id] = name
+		zones = append(zones, probeZone{Name: name, ID: id})
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+	return zones, nil
+}
+
+// zoneID converts the zone name into a lowercase DNS label.
+func zoneID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	if len(id) > maxZoneIDLength {
+		id = id[len(id)-maxZoneIDLength:]
+	}
+	return strings.Trim(id, "-")
+}
+
+// getProbeZonePairs returns all ordered pairs of zones, including pairs of the same zone.
+func getProbeZonePairs(zones []probeZone) []probeZonePair {
+	var pairs []probeZonePair
+	for _, source := range zones {
+		for _, target := range zones {
+			pairs = append(pairs, probeZonePair{Source: source, Target: target})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func zoneNode(zone string) corev1.Node {
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{}}}
+	if zone != "" {
+		node.Labels[defaultZoneLabel] = zone
+	}
+	return node
+}
+
+func TestGetProbeZones(t *testing.T) {
+	zones, err := getProbeZones([]corev1.Node{zoneNode("us-east1-c"), zoneNode("us-east1-b"), zoneNode(""), zoneNode("us-east1-c")}, defaultZoneLabel)
+	assert.NoError(t, err)
+	assert.Equal(t, []probeZone{{Name: "us-east1-b", ID: "us-east1-b"}, {Name: "us-east1-c", ID: "us-east1-c"}}, zones)
+	assert.Len(t, getProbeZonePairs(zones), 4)
+
+	_, err = getProbeZones([]corev1.Node{zoneNode("")}, defaultZoneLabel)
+	assert.Error(t, err)
+	_, err = getProbeZones([]corev1.Node{zoneNode("pool_a"), zoneNode("pool.a")}, defaultZoneLabel)
+	assert.Error(t, err)
+}
+
+func TestZoneID(t *testing.T) {
+	assert.Equal(t, "default-pool", zoneID("Default_Pool"))
+	// Long names are truncated from the beginning, as suffixes (e.g. -a or -1) distinguish zones and pools.
+	assert.Equal(t, "ery-long-node-pool-1", zoneID("a-very-very-long-node-pool-1"))
+	assert.Equal(t, "a", zoneID("-a."))
+}
+
+func TestGetZoneLatencies(t *testing.T) {
+	sample := func(source, target, quantile string, value float64) *model.Sample {
+		return &model.Sample{
+			Metric: model.Metric{"source_zone": model.LabelValue(source), "target_zone": model.LabelValue(target), "quantile": model.LabelValue(quantile)},
+			Value:  model.SampleValue(value),
+		}
+	}
+	latencies, err := getLatencyBreakdown([]*model.Sample{
+		sample("b", "a", "0.99", 0.2),
+		sample("a", "b", "0.99", 0.1),
+		sample("a", "b", "0.50", 0.05),
+	}, zoneBreakdownLabels)
+	assert.NoError(t, err)
+	assert.Len(t, latencies, 2)
+	dataItem := latencies[0].toPerfData("InClusterNetworkLatency")
+	assert.Equal(t, map[string]string{"Metric": "InClusterNetworkLatency", "SourceZone": "a", "TargetZone": "b"}, dataItem.Labels)
+	assert.Equal(t, 100.0, dataItem.Data["Perc99"])
+	assert.Equal(t, 50.0, dataItem.Data["Perc50"])
+	assert.Equal(t, "SourceZone=b TargetZone=a", latencies[1].String())
+}
+
+func TestZoneManifests(t *testing.T) {
+	manifests, err := manifestsFS("")
+	assert.NoError(t, err)
+	zones := []probeZone{{Name: "zone-a", ID: "zone-a"}, {Name: "zone-b", ID: "zone-b"}}
+	mapping := map[string]interface{}{
+		"Replicas":  2,
+		"Zones":     zones,
+		"ZonePairs": getProbeZonePairs(zones),
+		"ZoneLabel": defaultZoneLabel,
+	}
+	zoneProvider := config.NewTemplateProviderFS(manifests, "zones")
+	for file, items := range map[string]int{"ping-server-deployments.yaml": 4, "ping-client-deployments.yaml": 4} {
+		obj, err := zoneProvider.TemplateToObject(file, mapping)
+		if !assert.NoError(t, err, file) {
+			continue
+		}
+		list, err := obj.ToList()
+		assert.NoError(t, err, file)
+		assert.Len(t, list.Items, items, file)
+	}
+
+	// Single zone deployments are skipped in zone breakdown mode.
+	provider := config.NewTemplateProviderFS(manifests, ".")
+	_, err = provider.TemplateToObject("ping-client-deployment.yaml", mapping)
+	assert.Equal(t, config.ErrorEmptyFile, err)
+	_, err = provider.TemplateToObject("ping-client-deployment.yaml", map[string]interface{}{"Replicas": 2})
+	assert.NoError(t, err)
+}
@@ -129,6 +129,18 @@ func (p *profileMeasurement) stop() {
 	p.wg.Wait()
 }
 
+// RequiredCapabilities returns capabilities required by the measurement.
+// Profiles of components other than kube-apiserver are read over SSH from the master, unless host param is provided.
+func (p *profileMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if componentName, _ := config.Params["componentName"].(string); componentName == "kube-apiserver" {
+		return nil
+	}
+	if _, ok := config.Params["host"]; ok {
+		return []measurement.Capability{measurement.SSH}
+	}
+	return []measurement.Capability{measurement.SSH, measurement.MasterAccess}
+}
+
 // Execute gathers memory profile of a given component.
 func (p *profileMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
 	action, err := util.GetString(config.Params, "action")
@@ -159,6 +171,15 @@ func (p *profileMeasurement) String() string {
 	return p.name
 }
 
+// Description returns a short description of the measurement.
+func (p *profileMeasurement) Description() string {
+	kind := p.config.kind
+	if kind == "profile" {
+		kind = "cpu"
+	}
+	return fmt.Sprintf("Periodically collects pprof %s profiles of a master component.", kind)
+}
+
 func (p *profileMeasurement) gatherProfile(c clientset.Interface) (measurement.Summary, error) {
 	profilePrefix := fmt.Sprintf("%s_%s", p.config.componentName, p.name)
 	if p.config.componentName == "kube-apiserver" {
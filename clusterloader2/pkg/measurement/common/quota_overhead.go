@@ -0,0 +1,229 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	quotaOverheadName         = "QuotaAdmissionOverhead"
+	defaultQuotaOverheadCount = 100
+	// Pods are never scheduled, so they do not consume any node resources.
+	quotaOverheadSchedulerName = "quota-overhead-nonexistent-scheduler"
+	quotaOverheadImage         = "k8s.gcr.io/pause:3.1"
+	quotaStatusTimeout         = time.Minute
+)
+
+func init() {
+	if err := measurement.Register(quotaOverheadName, createQuotaOverheadMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", quotaOverheadName, err)
+	}
+}
+
+func createQuotaOverheadMeasurement() measurement.Measurement {
+	return &quotaOverheadMeasurement{}
+}
+
+type quotaOverheadMeasurement struct{}
+
+type writeLatency time.Duration
+
+// GetLatency returns latency of a single write.
+func (w writeLatency) GetLatency() time.Duration {
+	return time.Duration(w)
+}
+
+// Execute creates two namespaces, one of which contains ResourceQuota and LimitRange objects,
+// and measures the latency of pod creation calls in both of them.
+// Creations are interleaved between namespaces, so both samples are affected
+// by the same cluster load. Pods are never scheduled and they are created with a client
+// that isn't rate limited, so that latencies don't include client-side throttling.
+// Namespaces are named after the automanaged namespace prefix and the id of the run,
+// so that concurrent tests don't share them.
+// If maxOverhead is set, an error is returned when the difference between
+// 99th percentiles exceeds it. Namespaces are deleted once the measurement is done.
+func (q *quotaOverheadMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	count, err := util.GetIntOrDefault(config.Params, "count", defaultQuotaOverheadCount)
+	if err != nil {
+		return nil, err
+	}
+	maxOverhead, err := util.GetDurationOrDefault(config.Params, "maxOverhead", 0)
+	if err != nil {
+		return nil, err
+	}
+	c := config.ClusterFramework.GetClientSets().GetClient()
+	unthrottled, err := config.ClusterFramework.NewUnthrottledClient()
+	if err != nil {
+		return nil, fmt.Errorf("unthrottled client creation error: %v", err)
+	}
+	runID := config.ClusterFramework.GetClusterConfig().RunID
+	prefix := config.ClusterFramework.GetAutomanagedNamespacePrefix()
+	baselineNs := fmt.Sprintf("%s-quota-baseline-%s", prefix, runID)
+	quotaNs := fmt.Sprintf("%s-quota-%s", prefix, runID)
+
+	defer q.cleanup(c, baselineNs, quotaNs)
+	if err := q.setUp(c, runID, baselineNs, quotaNs, count); err != nil {
+		return nil, err
+	}
+
+	baseline := make([]measurementutil.LatencyData, 0, count)
+	quota := make([]measurementutil.LatencyData, 0, count)
+	for i := 0; i < count; i++ {
+		latency, err := timePodCreation(unthrottled, baselineNs, i)
+		if err != nil {
+			return nil, err
+		}
+		baseline = append(baseline, latency)
+		latency, err = timePodCreation(unthrottled, quotaNs, i)
+		if err != nil {
+			return nil, err
+		}
+		quota = append(quota, latency)
+	}
+	sort.Sort(measurementutil.LatencySlice(baseline))
+	sort.Sort(measurementutil.LatencySlice(quota))
+	baselineMetric := measurementutil.NewLatencyMetric(baseline)
+	quotaMetric := measurementutil.NewLatencyMetric(quota)
+	overhead := measurementutil.LatencyMetric{
+		Perc50: quotaMetric.Perc50 - baselineMetric.Perc50,
+		Perc90: quotaMetric.Perc90 - baselineMetric.Perc90,
+		Perc99: quotaMetric.Perc99 - baselineMetric.Perc99,
+	}
+	logrus.Infof("%s: baseline: %v; with quota: %v", q, baselineMetric, quotaMetric)
+
+	var violation error
+	if maxOverhead > 0 && overhead.Perc99 > maxOverhead {
+		violation = errors.NewMetricViolationErrorWithThreshold("quota admission overhead", fmt.Sprintf("99th percentile overhead %v exceeds %v", overhead.Perc99, maxOverhead), overhead.Perc99.Seconds(), maxOverhead.Seconds())
+		logrus.Errorf("%s: %v", q, violation)
+	}
+
+	content, err := util.PrettyPrintJSON(measurementutil.LatencyMapToPerfData(map[string]*measurementutil.LatencyMetric{
+		"create_without_quota": &baselineMetric,
+		"create_with_quota":    &quotaMetric,
+		"quota_overhead":       &overhead,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(quotaOverheadName, "json", content)}, violation
+}
+
+// Dispose cleans up after the measurement.
+func (*quotaOverheadMeasurement) Dispose() {}
+
+// String returns string representation of this measurement.
+func (*quotaOverheadMeasurement) String() string {
+	return quotaOverheadName
+}
+
+// Description returns a short description of the measurement.
+func (*quotaOverheadMeasurement) Description() string {
+	return "Measures the latency overhead of pod creation caused by ResourceQuota and LimitRange objects."
+}
+
+func (q *quotaOverheadMeasurement) setUp(c clientset.Interface, runID, baselineNs, quotaNs string, count int) error {
+	for _, ns := range []string{baselineNs, quotaNs} {
+		if err := client.CreateNamespace(c, ns, runID); err != nil {
+			return fmt.Errorf("namespace %s creation error: %v", ns, err)
+		}
+	}
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: quotaOverheadName},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{
+				corev1.ResourcePods:           *resource.NewQuantity(int64(count), resource.DecimalSI),
+				corev1.ResourceRequestsCPU:    *resource.NewMilliQuantity(int64(count)*10, resource.DecimalSI),
+				corev1.ResourceRequestsMemory: *resource.NewQuantity(int64(count)*10*1024*1024, resource.BinarySI),
+			},
+		},
+	}
+	if _, err := c.CoreV1().ResourceQuotas(quotaNs).Create(quota); err != nil {
+		return fmt.Errorf("resource quota creation error: %v", err)
+	}
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: quotaOverheadName},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{
+				{
+					Type: corev1.LimitTypeContainer,
+					DefaultRequest: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("10m"),
+						corev1.ResourceMemory: resource.MustParse("10Mi"),
+					},
+				},
+			},
+		},
+	}
+	if _, err := c.CoreV1().LimitRanges(quotaNs).Create(limitRange); err != nil {
+		return fmt.Errorf("limit range creation error: %v", err)
+	}
+	// Quota admission rejects writes until the quota controller computes the usage.
+	return wait.PollImmediate(time.Second, quotaStatusTimeout, func() (bool, error) {
+		current, err := c.CoreV1().ResourceQuotas(quotaNs).Get(quotaOverheadName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return len(current.Status.Hard) > 0, nil
+	})
+}
+
+func (q *quotaOverheadMeasurement) cleanup(c clientset.Interface, namespaces ...string) {
+	for _, ns := range namespaces {
+		if err := client.DeleteNamespace(c, ns); err != nil {
+			logrus.Errorf("%s: namespace %s deletion error: %v", q, ns, err)
+			continue
+		}
+		if err := client.WaitForDeleteNamespace(c, ns); err != nil {
+			logrus.Errorf("%s: waiting for namespace %s deletion error: %v", q, ns, err)
+		}
+	}
+}
+
+func timePodCreation(c clientset.Interface, namespace string, index int) (writeLatency, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("quota-overhead-%d", index)},
+		Spec: corev1.PodSpec{
+			SchedulerName: quotaOverheadSchedulerName,
+			Containers: []corev1.Container{
+				{
+					Name:  "pause",
+					Image: quotaOverheadImage,
+				},
+			},
+		},
+	}
+	start := time.Now()
+	if _, err := c.CoreV1().Pods(namespace).Create(pod); err != nil {
+		return 0, fmt.Errorf("pod creation in %s error: %v", namespace, err)
+	}
+	return writeLatency(time.Since(start)), nil
+}
@@ -19,19 +19,34 @@ package common
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/gatherers"
+	"k8s.io/perf-tests/clusterloader2/pkg/sink"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	resourceUsageMetricName = "ResourceUsageSummary"
+	resourceConstraintsName = "ResourceConstraints"
+	resourceTimeSeriesName  = "ResourceUsageTimeSeries"
+
+	defaultConstraintsHeadroom = 0.2
+	// Generated constraints are never zero, as zero constraint means no constraint.
+	minCPUConstraint    = 0.001
+	minMemoryConstraint = 1024 * 1024
+
+	kubeletResourceUsageBackend    = "kubelet"
+	prometheusResourceUsageBackend = "prometheus"
 )
 
 func init() {
@@ -46,14 +61,40 @@ func createResourceUsageMetricMeasurement() measurement.Measurement {
 	}
 }
 
+// resourceUsageGatherer gathers resource usage of containers.
+type resourceUsageGatherer interface {
+	StartGatheringData()
+	Summarize(percentiles []int) (*gatherers.ResourceUsageSummary, error)
+	StopAndSummarize(percentiles []int) (*gatherers.ResourceUsageSummary, error)
+	TimeSeries(interval time.Duration) (*gatherers.ResourceUsageTimeSeries, error)
+	Dispose()
+}
+
 type resourceUsageMetricMeasurement struct {
-	gatherer            *gatherers.ContainerResourceGatherer
+	gatherer            resourceUsageGatherer
 	resourceConstraints map[string]*measurementutil.ResourceConstraint
+	// stopIntermediateCh stops writing intermediate summaries, it's nil if they aren't written.
+	stopIntermediateCh chan struct{}
+	// restartCounts are restart counts of containers when gathering started, nil if they aren't tracked.
+	restartCounts map[string]int32
+	nodesSet      gatherers.NodesSet
 }
 
 // Execute supports two actions:
 // - start - Starts resource metrics collecting.
 // - gather - Gathers and prints current resource usage metrics.
+// If generateConstraints param is set, gather additionally emits ResourceConstraints summary - resource constraints
+// file with p99 usage of every container increased by constraintsHeadroom (0.2 by default), ready to be passed
+// as resourceConstraints param in future runs.
+// If intermediateSummaryInterval param is set, summaries of the data collected so far are periodically
+// written to the report directory, so that resource data of long runs is available even if gather is never executed.
+// If timeSeriesInterval param is set, gather additionally emits ResourceUsageTimeSeries summary with the highest
+// usage of every container in consecutive intervals and its peak usage together with the time it was observed,
+// so that transient spikes are visible.
+// Besides cpu and memory, constraints may limit network throughput (of the pod of the container), disk
+// throughput, IOPS, ephemeral storage and number of restarts of the container between start and gather.
+// Usage is gathered by polling kubelets, unless backend param is set to prometheus - then it's computed from
+// cadvisor metrics of kube-system containers already scraped by Prometheus, which scales to large clusters.
 func (e *resourceUsageMetricMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
 	action, err := util.GetString(config.Params, "action")
 	if err != nil {
@@ -78,19 +119,17 @@ func (e *resourceUsageMetricMeasurement) Execute(config *measurement.Measurement
 		if err != nil {
 			return nil, err
 		}
+		intermediateSummaryInterval, err := util.GetDurationOrDefault(config.Params, "intermediateSummaryInterval", 0)
+		if err != nil {
+			return nil, err
+		}
+		backend, err := util.GetStringOrDefault(config.Params, "backend", kubeletResourceUsageBackend)
+		if err != nil {
+			return nil, err
+		}
 		if constraintsPath != "" {
-			mapping := make(map[string]interface{})
-			mapping["Nodes"] = config.ClusterFramework.GetClusterConfig().Nodes
-			if err = config.TemplateProvider.TemplateInto(constraintsPath, mapping, &e.resourceConstraints); err != nil {
-				return nil, fmt.Errorf("resource constraints reading error: %v", err)
-			}
-			for _, constraint := range e.resourceConstraints {
-				if constraint.CPUConstraint == 0 {
-					constraint.CPUConstraint = math.MaxFloat64
-				}
-				if constraint.MemoryConstraint == 0 {
-					constraint.MemoryConstraint = math.MaxUint64
-				}
+			if e.resourceConstraints, err = readResourceConstraints(config.TemplateProvider, constraintsPath, config.ClusterFramework.GetClusterConfig().Nodes); err != nil {
+				return nil, err
 			}
 		}
 		var nodesSet gatherers.NodesSet
@@ -103,24 +142,54 @@ func (e *resourceUsageMetricMeasurement) Execute(config *measurement.Measurement
 			nodesSet = gatherers.AllNodes
 		}
 
-		logrus.Infof("%s: starting resource usage collecting...", e)
-		e.gatherer, err = gatherers.NewResourceUsageGatherer(config.ClusterFramework.GetClientSets().GetClient(), host, provider, gatherers.ResourceGathererOptions{
+		logrus.Infof("%s: starting resource usage collecting with %s backend...", e, backend)
+		options := gatherers.ResourceGathererOptions{
 			InKubemark:                        strings.ToLower(provider) == "kubemark",
 			Nodes:                             nodesSet,
 			ResourceDataGatheringPeriod:       60 * time.Second,
 			MasterResourceDataGatheringPeriod: 10 * time.Second,
 			PrintVerboseLogs:                  false,
-		}, nil)
+		}
+		gatherer, err := newResourceUsageGatherer(config, backend, host, provider, options)
 		if err != nil {
 			return nil, err
 		}
+		// Restarts of kubemark master components can't be tracked, as they don't run in pods.
+		e.nodesSet = nodesSet
+		if !options.InKubemark {
+			if e.restartCounts, err = gatherers.ContainerRestartCounts(config.ClusterFramework.GetClientSets().GetClient(), nodesSet); err != nil {
+				gatherer.Dispose()
+				return nil, err
+			}
+		}
+		e.gatherer = gatherer
 		go e.gatherer.StartGatheringData()
+		if intermediateSummaryInterval > 0 {
+			e.stopIntermediateCh = make(chan struct{})
+			go e.writeIntermediateSummaries(config, intermediateSummaryInterval, e.stopIntermediateCh)
+		}
 		return nil, nil
 	case "gather":
 		if e.gatherer == nil {
 			logrus.Errorf("%s: gatherer not initialized", e)
 			return nil, nil
 		}
+		e.stopIntermediateSummaries()
+		generateConstraints, err := util.GetBoolOrDefault(config.Params, "generateConstraints", false)
+		if err != nil {
+			return nil, err
+		}
+		headroom, err := util.GetFloat64OrDefault(config.Params, "constraintsHeadroom", defaultConstraintsHeadroom)
+		if err != nil {
+			return nil, err
+		}
+		if headroom < 0 {
+			return nil, fmt.Errorf("constraintsHeadroom has to be non-negative, got %v", headroom)
+		}
+		timeSeriesInterval, err := util.GetDurationOrDefault(config.Params, "timeSeriesInterval", 0)
+		if err != nil {
+			return nil, err
+		}
 		logrus.Infof("%s: gathering resource usage...", e)
 		summary, err := e.gatherer.StopAndSummarize([]int{50, 90, 99, 100})
 		if err != nil {
@@ -130,16 +199,160 @@ func (e *resourceUsageMetricMeasurement) Execute(config *measurement.Measurement
 		if err != nil {
 			return nil, err
 		}
-		resourceSummary := measurement.CreateSummary(resourceUsageMetricName, "json", content)
-		return []measurement.Summary{resourceSummary}, e.verifySummary(summary)
+		summaries := []measurement.Summary{measurement.CreateSummary(resourceUsageMetricName, "json", content)}
+		if generateConstraints {
+			constraints, err := yaml.Marshal(generateResourceConstraints(summary, headroom))
+			if err != nil {
+				return nil, fmt.Errorf("resource constraints marshaling error: %v", err)
+			}
+			summaries = append(summaries, measurement.CreateSummary(resourceConstraintsName, "yaml", string(constraints)))
+		}
+		if timeSeriesInterval > 0 {
+			timeSeries, err := e.gatherer.TimeSeries(timeSeriesInterval)
+			if err != nil {
+				return nil, err
+			}
+			content, err := util.PrettyPrintJSON(timeSeries)
+			if err != nil {
+				return nil, err
+			}
+			summaries = append(summaries, measurement.CreateSummary(resourceTimeSeriesName, "json", content))
+		}
+		restarts, err := e.getRestarts(config)
+		if err != nil {
+			return summaries, err
+		}
+		var podNodeLabels map[string]labels.Set
+		if e.hasNodeGroups() {
+			if podNodeLabels, err = gatherers.PodNodeLabels(config.ClusterFramework.GetClientSets().GetClient()); err != nil {
+				return summaries, err
+			}
+		}
+		return summaries, e.verifySummary(summary, restarts, podNodeLabels)
 
 	default:
 		return nil, fmt.Errorf("unknown action %v", action)
 	}
 }
 
+// RequiredCapabilities returns capabilities required by the measurement.
+// The prometheus backend computes usage from cadvisor metrics, which are exposed by kubelets.
+func (e *resourceUsageMetricMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if backend, err := util.GetStringOrDefault(config.Params, "backend", kubeletResourceUsageBackend); err == nil && backend == prometheusResourceUsageBackend {
+		return []measurement.Capability{measurement.Prometheus, measurement.KubeletMetrics}
+	}
+	return nil
+}
+
+func newResourceUsageGatherer(config *measurement.MeasurementConfig, backend, host, provider string, options gatherers.ResourceGathererOptions) (resourceUsageGatherer, error) {
+	c := config.ClusterFramework.GetClientSets().GetClient()
+	switch backend {
+	case kubeletResourceUsageBackend:
+		// Typed nil can't be returned on error, as it wouldn't be nil as the interface.
+		gatherer, err := gatherers.NewResourceUsageGatherer(c, host, provider, options, nil)
+		if err != nil {
+			return nil, err
+		}
+		return gatherer, nil
+	case prometheusResourceUsageBackend:
+		if config.PrometheusFramework == nil {
+			return nil, fmt.Errorf("%s backend requires Prometheus server", prometheusResourceUsageBackend)
+		}
+		if options.InKubemark {
+			return nil, fmt.Errorf("%s backend doesn't support kubemark", prometheusResourceUsageBackend)
+		}
+		executor, err := config.QueryExecutors.Get(config.PrometheusFramework.GetClientSets().GetClient(), config.GetPrometheusConfig(), measurementutil.DefaultQueryRetryPolicy)
+		if err != nil {
+			return nil, err
+		}
+		gatherer, err := gatherers.NewPrometheusResourceGatherer(c, executor, options)
+		if err != nil {
+			return nil, err
+		}
+		return gatherer, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q, expected %s or %s", backend, kubeletResourceUsageBackend, prometheusResourceUsageBackend)
+	}
+}
+
+// DeclaredParams returns params accepted by the measurement.
+func (e *resourceUsageMetricMeasurement) DeclaredParams() []measurement.Param {
+	start := []string{"start"}
+	gather := []string{"gather"}
+	return []measurement.Param{
+		measurement.ActionParam("start", "gather"),
+		{Name: "provider", Type: measurement.StringParam, Actions: start, Description: "provider of the cluster, defaults to the provider of the test"},
+		{Name: "host", Type: measurement.StringParam, Actions: start, Description: "master host, defaults to the master IP of the cluster"},
+		{Name: "nodeMode", Type: measurement.StringParam, Actions: start, Description: "nodes which containers are monitored: master, masteranddns or all (default)"},
+		{Name: "resourceConstraints", Type: measurement.StringParam, Actions: start, Description: "path of the resource constraints file, templated with Nodes"},
+		{Name: "intermediateSummaryInterval", Type: measurement.DurationParam, Actions: start, Description: "interval of writing intermediate summaries, disabled if zero"},
+		{Name: "backend", Type: measurement.StringParam, Actions: start, Default: kubeletResourceUsageBackend, Values: []string{kubeletResourceUsageBackend, prometheusResourceUsageBackend}, Description: "source of usage data"},
+		{Name: "generateConstraints", Type: measurement.BoolParam, Actions: gather, Default: false, Description: "whether to emit ResourceConstraints summary generated from the usage"},
+		{Name: "constraintsHeadroom", Type: measurement.FloatParam, Actions: gather, Default: defaultConstraintsHeadroom, Description: "headroom added to p99 usage in generated constraints"},
+		{Name: "timeSeriesInterval", Type: measurement.DurationParam, Actions: gather, Description: "resolution of ResourceUsageTimeSeries summary, disabled if zero"},
+	}
+}
+
+// Validate checks params of the measurement and renders resource constraints file, if it's provided.
+func (e *resourceUsageMetricMeasurement) Validate(config *measurement.MeasurementConfig) error {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "start":
+		backend, err := util.GetStringOrDefault(config.Params, "backend", kubeletResourceUsageBackend)
+		if err != nil {
+			return err
+		}
+		if backend != kubeletResourceUsageBackend && backend != prometheusResourceUsageBackend {
+			return fmt.Errorf("unknown backend %q", backend)
+		}
+		if _, err := util.GetDurationOrDefault(config.Params, "intermediateSummaryInterval", 0); err != nil {
+			return err
+		}
+		constraintsPath, err := util.GetStringOrDefault(config.Params, "resourceConstraints", "")
+		if err != nil || constraintsPath == "" {
+			return err
+		}
+		_, err = readResourceConstraints(config.TemplateProvider, constraintsPath, config.ClusterLoaderConfig.ClusterConfig.Nodes)
+		return err
+	case "gather":
+		if _, err := util.GetBoolOrDefault(config.Params, "generateConstraints", false); err != nil {
+			return err
+		}
+		headroom, err := util.GetFloat64OrDefault(config.Params, "constraintsHeadroom", defaultConstraintsHeadroom)
+		if err != nil {
+			return err
+		}
+		if headroom < 0 {
+			return fmt.Errorf("constraintsHeadroom has to be non-negative, got %v", headroom)
+		}
+		_, err = util.GetDurationOrDefault(config.Params, "timeSeriesInterval", 0)
+		return err
+	default:
+		return fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// readResourceConstraints renders resource constraints file for the cluster of given size.
+func readResourceConstraints(templateProvider *config.TemplateProvider, path string, nodes int) (map[string]*measurementutil.ResourceConstraint, error) {
+	constraints := make(map[string]*measurementutil.ResourceConstraint)
+	mapping := map[string]interface{}{"Nodes": nodes}
+	if err := templateProvider.TemplateInto(path, mapping, &constraints); err != nil {
+		return nil, fmt.Errorf("resource constraints reading error: %v", err)
+	}
+	for name, constraint := range constraints {
+		if err := completeResourceConstraint(constraint); err != nil {
+			return nil, fmt.Errorf("resource constraint of %s error: %v", name, err)
+		}
+	}
+	return constraints, nil
+}
+
 // Dispose cleans up after the measurement.
 func (e *resourceUsageMetricMeasurement) Dispose() {
+	e.stopIntermediateSummaries()
 	if e.gatherer != nil {
 		e.gatherer.Dispose()
 	}
@@ -150,11 +363,237 @@ func (*resourceUsageMetricMeasurement) String() string {
 	return resourceUsageMetricName
 }
 
-func (e *resourceUsageMetricMeasurement) verifySummary(summary *gatherers.ResourceUsageSummary) error {
+// Description returns a short description of the measurement.
+func (*resourceUsageMetricMeasurement) Description() string {
+	return "Measures cpu and memory usage of system containers and verifies resource constraints."
+}
+
+// writeIntermediateSummaries periodically writes summary of the resource usage collected so far
+// to summary sinks, overwriting the previous one, until stopCh is closed.
+func (e *resourceUsageMetricMeasurement) writeIntermediateSummaries(config *measurement.MeasurementConfig, interval time.Duration, stopCh chan struct{}) {
+	clusterLoaderConfig := config.ClusterLoaderConfig
+	if clusterLoaderConfig == nil {
+		logrus.Errorf("%s: cluster loader config not available, intermediate summaries won't be written", e)
+		return
+	}
+	sinks, err := sink.NewSinks(clusterLoaderConfig.ReportDir, &clusterLoaderConfig.SummarySinkConfig)
+	if err != nil {
+		logrus.Errorf("%s: summary sinks creation error, intermediate summaries won't be written: %v", e, err)
+		return
+	}
+	nameParts := []string{resourceUsageMetricName, "intermediate"}
+	if clusterLoaderConfig.TestScenario.Identifier != "" {
+		nameParts = append(nameParts, clusterLoaderConfig.TestScenario.Identifier)
+	}
+	if config.Identifier != "" {
+		nameParts = append(nameParts, config.Identifier)
+	}
+	fileName := strings.Join(nameParts, "_") + ".json"
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			summaries, err := e.Checkpoint(config)
+			if err != nil {
+				logrus.Errorf("%s: intermediate summary error: %v", e, err)
+				continue
+			}
+			for _, s := range sinks {
+				if err := s.Write(fileName, summaries[0]); err != nil {
+					logrus.Errorf("%s: writing intermediate summary to %v error: %v", e, s, err)
+				}
+			}
+			logrus.Infof("%s: intermediate summary written to %s", e, fileName)
+		}
+	}
+}
+
+// Checkpoint returns summary of the resource usage collected so far, without stopping gathering.
+func (e *resourceUsageMetricMeasurement) Checkpoint(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	if e.gatherer == nil {
+		return nil, fmt.Errorf("gatherer not initialized")
+	}
+	summary, err := e.gatherer.Summarize([]int{50, 90, 99, 100})
+	if err != nil {
+		return nil, err
+	}
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(resourceUsageMetricName, "json", content)}, nil
+}
+
+func (e *resourceUsageMetricMeasurement) stopIntermediateSummaries() {
+	if e.stopIntermediateCh != nil {
+		close(e.stopIntermediateCh)
+		e.stopIntermediateCh = nil
+	}
+}
+
+// generateResourceConstraints creates constraints of all containers from their p99 usage increased by headroom.
+// Constraints are keyed by container name, so the maximum usage of containers with the same name is used.
+func generateResourceConstraints(summary *gatherers.ResourceUsageSummary, headroom float64) map[string]*measurementutil.ResourceConstraint {
+	constraints := make(map[string]*measurementutil.ResourceConstraint)
+	for _, containerSummary := range summary.Get("99") {
+		parts := strings.Split(containerSummary.Name, "/")
+		containerName := parts[len(parts)-1]
+		cpu := math.Max(math.Ceil(containerSummary.Cpu*(1+headroom)*1000)/1000, minCPUConstraint)
+		memory := uint64(math.Max(math.Ceil(float64(containerSummary.Mem)*(1+headroom)/minMemoryConstraint)*minMemoryConstraint, minMemoryConstraint))
+		// Other resources are constrained only if they were observed, as they aren't reported by all backends.
+		network := math.Ceil(containerSummary.Network * (1 + headroom))
+		disk := math.Ceil(containerSummary.Disk * (1 + headroom))
+		iops := math.Ceil(containerSummary.IOPS * (1 + headroom))
+		storage := uint64(math.Ceil(float64(containerSummary.EphemeralStorage)*(1+headroom)/minMemoryConstraint) * minMemoryConstraint)
+		constraint, ok := constraints[containerName]
+		if !ok {
+			constraints[containerName] = &measurementutil.ResourceConstraint{
+				CPUConstraint:              cpu,
+				MemoryConstraint:           memory,
+				NetworkConstraint:          network,
+				DiskConstraint:             disk,
+				IOPSConstraint:             iops,
+				EphemeralStorageConstraint: storage,
+			}
+			continue
+		}
+		constraint.CPUConstraint = math.Max(constraint.CPUConstraint, cpu)
+		if memory > constraint.MemoryConstraint {
+			constraint.MemoryConstraint = memory
+		}
+		constraint.NetworkConstraint = math.Max(constraint.NetworkConstraint, network)
+		constraint.DiskConstraint = math.Max(constraint.DiskConstraint, disk)
+		constraint.IOPSConstraint = math.Max(constraint.IOPSConstraint, iops)
+		if storage > constraint.EphemeralStorageConstraint {
+			constraint.EphemeralStorageConstraint = storage
+		}
+	}
+	return constraints
+}
+
+// completeResourceConstraint validates node groups of the constraint, fills constraints not set in them
+// with the ones of the container and replaces constraints that aren't set with unlimited ones.
+func completeResourceConstraint(constraint *measurementutil.ResourceConstraint) error {
+	for i := range constraint.NodeGroups {
+		group := &constraint.NodeGroups[i]
+		if _, err := labels.Parse(group.NodeSelector); err != nil {
+			return fmt.Errorf("node group %q: %v", group.NodeSelector, err)
+		}
+		if group.CPUConstraint == 0 {
+			group.CPUConstraint = constraint.CPUConstraint
+		}
+		if group.MemoryConstraint == 0 {
+			group.MemoryConstraint = constraint.MemoryConstraint
+		}
+		if group.NetworkConstraint == 0 {
+			group.NetworkConstraint = constraint.NetworkConstraint
+		}
+		if group.DiskConstraint == 0 {
+			group.DiskConstraint = constraint.DiskConstraint
+		}
+		if group.IOPSConstraint == 0 {
+			group.IOPSConstraint = constraint.IOPSConstraint
+		}
+		if group.EphemeralStorageConstraint == 0 {
+			group.EphemeralStorageConstraint = constraint.EphemeralStorageConstraint
+		}
+		if group.RestartCountConstraint == nil {
+			group.RestartCountConstraint = constraint.RestartCountConstraint
+		}
+		setUnlimited(&group.ResourceConstraint)
+	}
+	setUnlimited(constraint)
+	return nil
+}
+
+func setUnlimited(constraint *measurementutil.ResourceConstraint) {
+	if constraint.CPUConstraint == 0 {
+		constraint.CPUConstraint = math.MaxFloat64
+	}
+	if constraint.MemoryConstraint == 0 {
+		constraint.MemoryConstraint = math.MaxUint64
+	}
+	if constraint.NetworkConstraint == 0 {
+		constraint.NetworkConstraint = math.MaxFloat64
+	}
+	if constraint.DiskConstraint == 0 {
+		constraint.DiskConstraint = math.MaxFloat64
+	}
+	if constraint.IOPSConstraint == 0 {
+		constraint.IOPSConstraint = math.MaxFloat64
+	}
+	if constraint.EphemeralStorageConstraint == 0 {
+		constraint.EphemeralStorageConstraint = math.MaxUint64
+	}
+}
+
+func (e *resourceUsageMetricMeasurement) hasNodeGroups() bool {
+	for _, constraint := range e.resourceConstraints {
+		if len(constraint.NodeGroups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// constraintFor returns constraint of the container (in pod/container format) running on node with given
+// labels (nil if unknown), i.e. the one of the first matching node group or of the container name otherwise.
+func (e *resourceUsageMetricMeasurement) constraintFor(name string, nodeLabels labels.Set) (*measurementutil.ResourceConstraint, bool) {
+	constraint, ok := e.resourceConstraints[strings.Split(name, "/")[1]]
+	if !ok || nodeLabels == nil {
+		return constraint, ok
+	}
+	for i := range constraint.NodeGroups {
+		// Selectors are validated by the start action.
+		selector, err := labels.Parse(constraint.NodeGroups[i].NodeSelector)
+		if err == nil && selector.Matches(nodeLabels) {
+			return &constraint.NodeGroups[i].ResourceConstraint, true
+		}
+	}
+	return constraint, true
+}
+
+// getRestarts returns number of restarts of containers since the start action, nil if they aren't tracked.
+func (e *resourceUsageMetricMeasurement) getRestarts(config *measurement.MeasurementConfig) (map[string]int32, error) {
+	if e.restartCounts == nil {
+		return nil, nil
+	}
+	restartCounts, err := gatherers.ContainerRestartCounts(config.ClusterFramework.GetClientSets().GetClient(), e.nodesSet)
+	if err != nil {
+		return nil, err
+	}
+	restarts := make(map[string]int32)
+	for name, count := range restartCounts {
+		// Containers created since the start action may have restarted only during the test.
+		if restarts[name] = count - e.restartCounts[name]; restarts[name] < 0 {
+			restarts[name] = count
+		}
+	}
+	return restarts, nil
+}
+
+// verifySummary checks usage and restarts of containers against constraints. podNodeLabels contains labels
+// of nodes of kube-system pods, which are used to select constraints of node groups.
+func (e *resourceUsageMetricMeasurement) verifySummary(summary *gatherers.ResourceUsageSummary, restarts map[string]int32, podNodeLabels map[string]labels.Set) error {
 	violatedConstraints := make([]string, 0)
+	names := make([]string, 0, len(restarts))
+	for name := range restarts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		count := restarts[name]
+		constraint, ok := e.constraintFor(name, podNodeLabels[strings.Split(name, "/")[0]])
+		if ok && constraint.RestartCountConstraint != nil && count > *constraint.RestartCountConstraint {
+			violatedConstraints = append(violatedConstraints, fmt.Sprintf("container %v restarted %d/%d times", name, count, *constraint.RestartCountConstraint))
+		}
+	}
 	for _, containerSummary := range summary.Get("99") {
-		containerName := strings.Split(containerSummary.Name, "/")[1]
-		if constraint, ok := e.resourceConstraints[containerName]; ok {
+		podName := strings.Split(containerSummary.Name, "/")[0]
+		if constraint, ok := e.constraintFor(containerSummary.Name, podNodeLabels[podName]); ok {
 			if containerSummary.Cpu > constraint.CPUConstraint {
 				violatedConstraints = append(
 					violatedConstraints,
@@ -175,6 +614,24 @@ func (e *resourceUsageMetricMeasurement) verifySummary(summary *gatherers.Resour
 					),
 				)
 			}
+			if containerSummary.Network > constraint.NetworkConstraint {
+				violatedConstraints = append(violatedConstraints, fmt.Sprintf("container %v is using %v/%v B/s of network",
+					containerSummary.Name, containerSummary.Network, constraint.NetworkConstraint))
+			}
+			if containerSummary.Disk > constraint.DiskConstraint {
+				violatedConstraints = append(violatedConstraints, fmt.Sprintf("container %v is using %v/%v B/s of disk",
+					containerSummary.Name, containerSummary.Disk, constraint.DiskConstraint))
+			}
+			if containerSummary.IOPS > constraint.IOPSConstraint {
+				violatedConstraints = append(violatedConstraints, fmt.Sprintf("container %v is using %v/%v IOPS",
+					containerSummary.Name, containerSummary.IOPS, constraint.IOPSConstraint))
+			}
+			if containerSummary.EphemeralStorage > constraint.EphemeralStorageConstraint {
+				violatedConstraints = append(violatedConstraints, fmt.Sprintf("container %v is using %v/%v MB of ephemeral storage",
+					containerSummary.Name,
+					float64(containerSummary.EphemeralStorage)/(1024*1024),
+					float64(constraint.EphemeralStorageConstraint)/(1024*1024)))
+			}
 		}
 	}
 	if len(violatedConstraints) > 0 {
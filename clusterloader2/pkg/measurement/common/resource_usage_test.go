@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/labels"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/gatherers"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateResourceConstraints(t *testing.T) {
+	const mb = 1024 * 1024
+	summary := gatherers.ResourceUsageSummary{
+		"99": {
+			{Name: "kube-apiserver-master/kube-apiserver", Cpu: 1.5, Mem: 1000 * mb},
+			{Name: "kube-proxy-node-1/kube-proxy", Cpu: 0.01, Mem: 20 * mb},
+			{Name: "kube-proxy-node-2/kube-proxy", Cpu: 0.02, Mem: 10 * mb, Network: 1000, EphemeralStorage: 10 * mb},
+			{Name: "pause-node-1/pause", Cpu: 0, Mem: 0},
+		},
+	}
+	constraints := generateResourceConstraints(&summary, 0.2)
+	assert.Equal(t, map[string]*measurementutil.ResourceConstraint{
+		"kube-apiserver": {CPUConstraint: 1.8, MemoryConstraint: 1200 * mb},
+		"kube-proxy":     {CPUConstraint: 0.024, MemoryConstraint: 24 * mb, NetworkConstraint: 1200, EphemeralStorageConstraint: 12 * mb},
+		"pause":          {CPUConstraint: 0.001, MemoryConstraint: mb},
+	}, constraints)
+
+	// Generated file has to be readable as resourceConstraints param.
+	content, err := yaml.Marshal(constraints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]*measurementutil.ResourceConstraint
+	assert.NoError(t, yaml.Unmarshal(content, &decoded))
+	assert.Equal(t, constraints, decoded)
+	assert.Contains(t, string(content), "cpuConstraint: 1.8")
+}
+
+func TestVerifySummary(t *testing.T) {
+	noRestarts := int32(0)
+	e := &resourceUsageMetricMeasurement{
+		resourceConstraints: map[string]*measurementutil.ResourceConstraint{
+			"etcd": {
+				CPUConstraint:              math.MaxFloat64,
+				MemoryConstraint:           math.MaxUint64,
+				NetworkConstraint:          1000,
+				DiskConstraint:             math.MaxFloat64,
+				IOPSConstraint:             100,
+				EphemeralStorageConstraint: math.MaxUint64,
+				RestartCountConstraint:     &noRestarts,
+			},
+		},
+	}
+	summary := gatherers.ResourceUsageSummary{
+		"99": {{Name: "etcd-master/etcd", Cpu: 1, Mem: 100, Network: 500, IOPS: 50}},
+	}
+	assert.NoError(t, e.verifySummary(&summary, map[string]int32{"etcd-master/etcd": 0}, nil))
+	assert.NoError(t, e.verifySummary(&summary, nil, nil))
+
+	err := e.verifySummary(&summary, map[string]int32{"etcd-master/etcd": 1}, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "restarted 1/0 times")
+	}
+	summary["99"][0].IOPS = 150
+	err = e.verifySummary(&summary, nil, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "150/100 IOPS")
+	}
+}
+
+func TestNodeGroupConstraints(t *testing.T) {
+	content := `
+kube-proxy:
+  cpuConstraint: 0.1
+  memoryConstraint: 104857600
+  nodeGroups:
+  - nodeSelector: kubernetes.io/os=windows
+    cpuConstraint: 0.5
+`
+	var constraints map[string]*measurementutil.ResourceConstraint
+	assert.NoError(t, yaml.Unmarshal([]byte(content), &constraints))
+	assert.NoError(t, completeResourceConstraint(constraints["kube-proxy"]))
+	group := constraints["kube-proxy"].NodeGroups[0]
+	assert.Equal(t, 0.5, group.CPUConstraint)
+	assert.Equal(t, uint64(104857600), group.MemoryConstraint)
+	assert.Equal(t, math.MaxFloat64, group.IOPSConstraint)
+
+	e := &resourceUsageMetricMeasurement{resourceConstraints: constraints}
+	assert.True(t, e.hasNodeGroups())
+	summary := gatherers.ResourceUsageSummary{
+		"99": {
+			{Name: "kube-proxy-linux/kube-proxy", Cpu: 0.05},
+			{Name: "kube-proxy-windows/kube-proxy", Cpu: 0.3},
+		},
+	}
+	podNodeLabels := map[string]labels.Set{
+		"kube-proxy-linux":   {"kubernetes.io/os": "linux"},
+		"kube-proxy-windows": {"kubernetes.io/os": "windows"},
+	}
+	assert.NoError(t, e.verifySummary(&summary, nil, podNodeLabels))
+	// Without labels of nodes, the constraint of the container is used.
+	assert.Error(t, e.verifySummary(&summary, nil, nil))
+
+	assert.Error(t, completeResourceConstraint(&measurementutil.ResourceConstraint{
+		NodeGroups: []measurementutil.NodeGroupConstraint{{NodeSelector: "a=(b"}},
+	}))
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/pkg/util/system"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	schedulableCapacityName            = "SchedulableCapacity"
+	defaultSchedulableCapacityInterval = 30 * time.Second
+)
+
+func init() {
+	if err := measurement.Register(schedulableCapacityName, createSchedulableCapacityMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", schedulableCapacityName, err)
+	}
+}
+
+func createSchedulableCapacityMeasurement() measurement.Measurement {
+	return &schedulableCapacityMeasurement{}
+}
+
+type schedulableCapacityMeasurement struct {
+	isRunning    bool
+	stopCh       chan struct{}
+	dipThreshold float64
+	lock         sync.Mutex
+	samples      []capacitySample
+}
+
+// capacitySample represents allocatable resources of ready, schedulable and untainted
+// nodes at given time.
+type capacitySample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Nodes     int       `json:"nodes"`
+	// CPU is expressed in cores.
+	CPU float64 `json:"cpu"`
+	// Memory is expressed in bytes.
+	Memory int64 `json:"memory"`
+}
+
+// capacityDip represents a period in which schedulable capacity was below the peak
+// capacity observed before the period.
+type capacityDip struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// MinCPURatio and MinMemoryRatio are the lowest capacity in the period, relative to the peak.
+	MinCPURatio    float64 `json:"minCpuRatio"`
+	MinMemoryRatio float64 `json:"minMemoryRatio"`
+	MinNodes       int     `json:"minNodes"`
+}
+
+type schedulableCapacitySummary struct {
+	// AverageCPU and AverageMemory allow normalizing throughput against
+	// the capacity that was actually available.
+	AverageCPU    float64          `json:"averageCpu"`
+	AverageMemory float64          `json:"averageMemory"`
+	Dips          []capacityDip    `json:"dips"`
+	Samples       []capacitySample `json:"samples"`
+}
+
+// Execute supports two actions:
+// - start - starts periodic sampling of allocatable cpu and memory of schedulable nodes.
+// - gather - stops sampling and creates summary with capacity dips.
+func (s *schedulableCapacityMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		interval, err := util.GetDurationOrDefault(config.Params, "interval", defaultSchedulableCapacityInterval)
+		if err != nil {
+			return nil, err
+		}
+		s.dipThreshold, err = util.GetFloat64OrDefault(config.Params, "dipThreshold", 0)
+		if err != nil {
+			return nil, err
+		}
+		if s.dipThreshold < 0 || s.dipThreshold >= 1 {
+			return nil, fmt.Errorf("dipThreshold should be in [0, 1) range, got %v", s.dipThreshold)
+		}
+		return nil, s.start(config.ClusterFramework.GetClientSets().GetClient(), interval)
+	case "gather":
+		return s.gather()
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (s *schedulableCapacityMeasurement) Dispose() {
+	s.stop()
+}
+
+// String returns string representation of this measurement.
+func (*schedulableCapacityMeasurement) String() string {
+	return schedulableCapacityName
+}
+
+// Description returns a short description of the measurement.
+func (*schedulableCapacityMeasurement) Description() string {
+	return "Samples allocatable cpu and memory of schedulable nodes and reports capacity dips."
+}
+
+func (s *schedulableCapacityMeasurement) start(c clientset.Interface, interval time.Duration) error {
+	if s.isRunning {
+		logrus.Infof("%s: measurement already running", s)
+		return nil
+	}
+	s.isRunning = true
+	s.stopCh = make(chan struct{})
+	s.samples = nil
+	logrus.Infof("%s: starting collecting schedulable capacity data", s)
+
+	sample := func() {
+		nodes, err := client.ListNodes(c)
+		if err != nil {
+			logrus.Errorf("%s: listing nodes error: %v", s, err)
+			return
+		}
+		s.lock.Lock()
+		s.samples = append(s.samples, computeSchedulableCapacity(nodes))
+		s.lock.Unlock()
+	}
+	sample()
+	go func() {
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-time.After(interval):
+				sample()
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *schedulableCapacityMeasurement) stop() {
+	if s.isRunning {
+		close(s.stopCh)
+		s.isRunning = false
+	}
+}
+
+func (s *schedulableCapacityMeasurement) gather() ([]measurement.Summary, error) {
+	if !s.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", schedulableCapacityName)
+	}
+	s.stop()
+	logrus.Infof("%s: gathering data", s)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	summary := buildSchedulableCapacitySummary(s.samples, s.dipThreshold)
+	for _, dip := range summary.Dips {
+		logrus.Infof("%s: capacity dip from %v to %v, cpu: %.2f, memory: %.2f of the peak, nodes: %d",
+			s, dip.Start, dip.End, dip.MinCPURatio, dip.MinMemoryRatio, dip.MinNodes)
+	}
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(schedulableCapacityName, "json", content)}, nil
+}
+
+func computeSchedulableCapacity(nodes []corev1.Node) capacitySample {
+	sample := capacitySample{Timestamp: time.Now()}
+	for i := range nodes {
+		if system.IsMasterNode(nodes[i].Name) || !util.IsNodeSchedulableAndUntainted(&nodes[i]) {
+			continue
+		}
+		sample.Nodes++
+		sample.CPU += float64(nodes[i].Status.Allocatable.Cpu().MilliValue()) / 1000
+		sample.Memory += nodes[i].Status.Allocatable.Memory().Value()
+	}
+	return sample
+}
+
+// buildSchedulableCapacitySummary finds periods in which cpu or memory capacity was lower than
+// (1 - dipThreshold) of the peak observed so far. A dip ends with the first sample above it.
+func buildSchedulableCapacitySummary(samples []capacitySample, dipThreshold float64) *schedulableCapacitySummary {
+	summary := &schedulableCapacitySummary{
+		Dips:    []capacityDip{},
+		Samples: samples,
+	}
+	if len(samples) == 0 {
+		return summary
+	}
+	var peakCPU float64
+	var peakMemory int64
+	var dip *capacityDip
+	for _, sample := range samples {
+		summary.AverageCPU += sample.CPU
+		summary.AverageMemory += float64(sample.Memory)
+		cpuRatio := capacityRatio(sample.CPU, peakCPU)
+		memoryRatio := capacityRatio(float64(sample.Memory), float64(peakMemory))
+		if cpuRatio < 1-dipThreshold || memoryRatio < 1-dipThreshold {
+			if dip == nil {
+				dip = &capacityDip{Start: sample.Timestamp, MinCPURatio: 1, MinMemoryRatio: 1, MinNodes: sample.Nodes}
+			}
+			dip.End = sample.Timestamp
+			dip.MinCPURatio = minFloat64(dip.MinCPURatio, cpuRatio)
+			dip.MinMemoryRatio = minFloat64(dip.MinMemoryRatio, memoryRatio)
+			if sample.Nodes < dip.MinNodes {
+				dip.MinNodes = sample.Nodes
+			}
+			continue
+		}
+		if dip != nil {
+			dip.End = sample.Timestamp
+			summary.Dips = append(summary.Dips, *dip)
+			dip = nil
+		}
+		if sample.CPU > peakCPU {
+			peakCPU = sample.CPU
+		}
+		if sample.Memory > peakMemory {
+			peakMemory = sample.Memory
+		}
+	}
+	if dip != nil {
+		summary.Dips = append(summary.Dips, *dip)
+	}
+	summary.AverageCPU /= float64(len(samples))
+	summary.AverageMemory /= float64(len(samples))
+	return summary
+}
+
+func capacityRatio(value, peak float64) float64 {
+	if peak == 0 {
+		return 1
+	}
+	return value / peak
+}
+
+func minFloat64(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
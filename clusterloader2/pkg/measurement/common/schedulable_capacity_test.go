@@ -0,0 +1,49 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSchedulableCapacitySummary(t *testing.T) {
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	samples := []capacitySample{
+		{Timestamp: at(0), Nodes: 4, CPU: 8, Memory: 400},
+		{Timestamp: at(1), Nodes: 3, CPU: 6, Memory: 300},
+		{Timestamp: at(2), Nodes: 2, CPU: 4, Memory: 200},
+		{Timestamp: at(3), Nodes: 4, CPU: 8, Memory: 400},
+		{Timestamp: at(4), Nodes: 4, CPU: 7.8, Memory: 400},
+		{Timestamp: at(5), Nodes: 3, CPU: 6, Memory: 300},
+	}
+
+	summary := buildSchedulableCapacitySummary(samples, 0.05)
+	assert.Equal(t, []capacityDip{
+		{Start: at(1), End: at(3), MinCPURatio: 0.5, MinMemoryRatio: 0.5, MinNodes: 2},
+		{Start: at(5), End: at(5), MinCPURatio: 0.75, MinMemoryRatio: 0.75, MinNodes: 3},
+	}, summary.Dips)
+	assert.InDelta(t, 6.633, summary.AverageCPU, 0.001)
+	assert.InDelta(t, 333.333, summary.AverageMemory, 0.001)
+
+	// Without threshold, a small cpu decrease is a dip as well.
+	assert.Len(t, buildSchedulableCapacitySummary(samples, 0).Dips, 2)
+	assert.Equal(t, at(4), buildSchedulableCapacitySummary(samples, 0).Dips[1].Start)
+}
@@ -93,6 +93,11 @@ func (*schedulerLatencyMeasurement) String() string {
 	return schedulerLatencyMetricName
 }
 
+// Description returns a short description of the measurement.
+func (*schedulerLatencyMeasurement) Description() string {
+	return "Gathers scheduling latency metrics of the scheduler."
+}
+
 func (s *schedulerLatencyMeasurement) resetSchedulerMetrics(c clientset.Interface, host, provider, masterName string) error {
 	_, err := s.sendRequestToScheduler(c, "DELETE", host, provider, masterName)
 	if err != nil {
@@ -18,19 +18,24 @@ package common
 
 import (
 	"fmt"
-	"math"
 	"sort"
+	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	"github.com/sirupsen/logrus"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/informer"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/stats"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
 const (
 	schedulingThroughputMeasurementName = "SchedulingThroughput"
+
+	defaultSustainedThroughputWindow = time.Minute
 )
 
 func init() {
@@ -44,9 +49,23 @@ func createSchedulingThroughputMeasurement() measurement.Measurement {
 }
 
 type schedulingThroughputMeasurement struct {
-	schedulingThroughputs []float64
-	isRunning             bool
-	stopCh                chan struct{}
+	isRunning bool
+	stopCh    chan struct{}
+	startTime time.Time
+	interval  time.Duration
+
+	// lock guards counts, which are updated by the informer.
+	lock sync.Mutex
+	// counts contains numbers of pods scheduled in consecutive intervals since the start, keyed
+	// by index of the interval. Only intervals with scheduled pods are stored, so that memory
+	// doesn't grow with the duration of the test.
+	counts map[throughputBucket]map[int]int
+}
+
+// throughputBucket groups pods whose throughput is reported together.
+type throughputBucket struct {
+	namespace     string
+	schedulerName string
 }
 
 // Execute supports two actions:
@@ -54,6 +73,10 @@ type schedulingThroughputMeasurement struct {
 //   Pods can be specified by field and/or label selectors.
 //   If namespace is not passed by parameter, all-namespace scope is assumed.
 // - gather - creates summary for observed values.
+// Pods are counted when their binding is observed by the watch. Throughput is computed in consecutive
+// intervals (interval param of start, 5s by default) since the start action. Besides the global summary,
+// gather emits the time series of throughput, the highest throughput sustained over sustainedWindow
+// (1m by default) and summaries per scheduler name and, if perNamespace param is set, per namespace.
 func (s *schedulingThroughputMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
 	action, err := util.GetString(config.Params, "action")
 	if err != nil {
@@ -69,11 +92,26 @@ func (s *schedulingThroughputMeasurement) Execute(config *measurement.Measuremen
 		if err := selector.Parse(config.Params); err != nil {
 			return nil, err
 		}
+		interval, err := util.GetDurationOrDefault(config.Params, "interval", defaultWaitForPodsInterval)
+		if err != nil {
+			return nil, err
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval has to be positive, got %v", interval)
+		}
 
 		s.stopCh = make(chan struct{})
-		return nil, s.start(config.ClusterFramework.GetClientSets().GetClient(), selector)
+		return nil, s.start(config.ClusterFramework.GetClientSets().GetClient(), selector, interval)
 	case "gather":
-		return s.gather()
+		window, err := util.GetDurationOrDefault(config.Params, "sustainedWindow", defaultSustainedThroughputWindow)
+		if err != nil {
+			return nil, err
+		}
+		perNamespace, err := util.GetBoolOrDefault(config.Params, "perNamespace", false)
+		if err != nil {
+			return nil, err
+		}
+		return s.gather(window, perNamespace)
 	default:
 		return nil, fmt.Errorf("unknown action %v", action)
 	}
@@ -89,53 +127,94 @@ func (*schedulingThroughputMeasurement) String() string {
 	return schedulingThroughputMeasurementName
 }
 
-func (s *schedulingThroughputMeasurement) start(clientSet clientset.Interface, selector *measurementutil.ObjectSelector) error {
-	ps, err := measurementutil.NewPodStore(clientSet, selector)
-	if err != nil {
-		return fmt.Errorf("pod store creation error: %v", err)
+// Description returns a short description of the measurement.
+func (*schedulingThroughputMeasurement) Description() string {
+	return "Measures the number of pods scheduled per second."
+}
+
+func (s *schedulingThroughputMeasurement) start(clientSet clientset.Interface, selector *measurementutil.ObjectSelector, interval time.Duration) error {
+	s.startTime = time.Now()
+	s.interval = interval
+	s.counts = make(map[throughputBucket]map[int]int)
+	i := informer.NewInformer(clientSet, "pods", selector, s.checkPod)
+	if err := informer.StartAndSync(i, s.stopCh, informerSyncTimeout); err != nil {
+		close(s.stopCh)
+		return err
 	}
 	s.isRunning = true
 	logrus.Infof("%s: starting collecting throughput data", s)
-
-	go func() {
-		defer ps.Stop()
-		lastScheduledCount := 0
-		for {
-			select {
-			case <-s.stopCh:
-				return
-			case <-time.After(defaultWaitForPodsInterval):
-				pods := ps.List()
-				podsStatus := measurementutil.ComputePodsStartupStatus(pods, 0)
-				throughput := float64(podsStatus.Scheduled-lastScheduledCount) / float64(defaultWaitForPodsInterval/time.Second)
-				s.schedulingThroughputs = append(s.schedulingThroughputs, throughput)
-				lastScheduledCount = podsStatus.Scheduled
-				logrus.Infof("%v: %s: %d pods scheduled", s, selector.String(), lastScheduledCount)
-			}
-		}
-	}()
 	return nil
 }
 
-func (s *schedulingThroughputMeasurement) gather() ([]measurement.Summary, error) {
+// checkPod records the pod if the event is its binding. Pods listed by the informer are counted
+// only if they were created after the start, as older ones were scheduled before it.
+func (s *schedulingThroughputMeasurement) checkPod(oldObj, newObj interface{}) {
+	newPod, ok := newObj.(*corev1.Pod)
+	if !ok || newPod.Spec.NodeName == "" {
+		return
+	}
+	if oldPod, ok := oldObj.(*corev1.Pod); ok {
+		if oldPod.Spec.NodeName != "" {
+			return
+		}
+	} else if newPod.CreationTimestamp.Time.Before(s.startTime.Truncate(time.Second)) {
+		return
+	}
+	s.countScheduled(throughputBucket{namespace: newPod.Namespace, schedulerName: newPod.Spec.SchedulerName}, time.Now())
+}
+
+func (s *schedulingThroughputMeasurement) countScheduled(bucket throughputBucket, scheduleTime time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.counts[bucket] == nil {
+		s.counts[bucket] = make(map[int]int)
+	}
+	s.counts[bucket][int(scheduleTime.Sub(s.startTime)/s.interval)]++
+}
+
+func (s *schedulingThroughputMeasurement) gather(window time.Duration, perNamespace bool) ([]measurement.Summary, error) {
 	if !s.isRunning {
 		logrus.Errorf("%s: measurementis nor running", s)
 		return nil, fmt.Errorf("measurement is not running")
 	}
 	s.stop()
+	// The last, incomplete interval is skipped, as its throughput would be inaccurate.
+	intervals := int(time.Since(s.startTime) / s.interval)
 	logrus.Infof("%s: gathering data", s)
 
-	throughputSummary := &schedulingThroughput{}
-	if length := len(s.schedulingThroughputs); length > 0 {
-		sort.Float64s(s.schedulingThroughputs)
-		sum := 0.0
-		for i := range s.schedulingThroughputs {
-			sum += s.schedulingThroughputs[i]
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	total := make(map[int]int)
+	byScheduler := make(map[string]map[int]int)
+	byNamespace := make(map[string]map[int]int)
+	scheduled := 0
+	for bucket, counts := range s.counts {
+		if byScheduler[bucket.schedulerName] == nil {
+			byScheduler[bucket.schedulerName] = make(map[int]int)
+		}
+		if byNamespace[bucket.namespace] == nil {
+			byNamespace[bucket.namespace] = make(map[int]int)
+		}
+		for i, count := range counts {
+			total[i] += count
+			byScheduler[bucket.schedulerName][i] += count
+			byNamespace[bucket.namespace][i] += count
+			scheduled += count
+		}
+	}
+	logrus.Infof("%s: %d pods scheduled", s, scheduled)
+
+	throughputSummary := summarizeSchedulingThroughput(total, s.startTime, intervals, s.interval, window)
+	throughputSummary.TimeSeries = throughputTimeSeries(total, s.startTime, intervals, s.interval)
+	throughputSummary.PerScheduler = make(map[string]*schedulingThroughput)
+	for schedulerName, counts := range byScheduler {
+		throughputSummary.PerScheduler[schedulerName] = summarizeSchedulingThroughput(counts, s.startTime, intervals, s.interval, window)
+	}
+	if perNamespace {
+		throughputSummary.PerNamespace = make(map[string]*schedulingThroughput)
+		for namespace, counts := range byNamespace {
+			throughputSummary.PerNamespace[namespace] = summarizeSchedulingThroughput(counts, s.startTime, intervals, s.interval, window)
 		}
-		throughputSummary.Average = sum / float64(length)
-		throughputSummary.Perc50 = s.schedulingThroughputs[int(math.Ceil(float64(length*50)/100))-1]
-		throughputSummary.Perc90 = s.schedulingThroughputs[int(math.Ceil(float64(length*90)/100))-1]
-		throughputSummary.Perc99 = s.schedulingThroughputs[int(math.Ceil(float64(length*99)/100))-1]
 	}
 	content, err := util.PrettyPrintJSON(throughputSummary)
 	if err != nil {
@@ -152,9 +231,101 @@ func (s *schedulingThroughputMeasurement) stop() {
 	}
 }
 
+// summarizeSchedulingThroughput computes throughput in the given number of intervals since start
+// from numbers of pods scheduled in them, keyed by index of the interval.
+func summarizeSchedulingThroughput(counts map[int]int, start time.Time, intervals int, interval, window time.Duration) *schedulingThroughput {
+	sketch := stats.NewSketch(stats.DefaultSketchAccuracy)
+	busyIntervals := 0
+	for i, count := range counts {
+		if i < intervals {
+			sketch.Add(float64(count) / interval.Seconds())
+			busyIntervals++
+		}
+	}
+	sketch.AddN(0, uint64(intervals-busyIntervals))
+	throughputStats := sketch.Summary()
+	return &schedulingThroughput{
+		Average:      throughputStats.Mean,
+		Perc50:       throughputStats.Perc50,
+		Perc90:       throughputStats.Perc90,
+		Perc99:       throughputStats.Perc99,
+		Min:          throughputStats.Min,
+		Max:          throughputStats.Max,
+		MaxSustained: maxSustainedThroughput(counts, start, intervals, interval, window),
+	}
+}
+
+// throughputTimeSeries returns throughput in the given number of intervals since start.
+func throughputTimeSeries(counts map[int]int, start time.Time, intervals int, interval time.Duration) []throughputSample {
+	timeSeries := make([]throughputSample, intervals)
+	for i := range timeSeries {
+		timeSeries[i] = throughputSample{
+			Time:       start.Add(time.Duration(i) * interval),
+			Throughput: float64(counts[i]) / interval.Seconds(),
+		}
+	}
+	return timeSeries
+}
+
+// maxSustainedThroughput returns the highest average throughput over window, or nil if the window
+// is longer than the given number of intervals. Windows are aligned to intervals. Only windows starting
+// with a busy interval (or the last window) are considered, as any other window is at most as busy
+// as the one starting with its first busy interval.
+func maxSustainedThroughput(counts map[int]int, start time.Time, intervals int, interval, window time.Duration) *sustainedThroughput {
+	length := int(window / interval)
+	if length < 1 {
+		length = 1
+	}
+	if length > intervals {
+		return nil
+	}
+	busy := make([]int, 0, len(counts))
+	for i := range counts {
+		if i < intervals {
+			busy = append(busy, i)
+		}
+	}
+	sort.Ints(busy)
+	best := &sustainedThroughput{Window: window.String(), Start: start}
+	for first := range busy {
+		windowStart := busy[first]
+		if windowStart > intervals-length {
+			windowStart = intervals - length
+		}
+		sum := 0
+		for j := sort.SearchInts(busy, windowStart); j < len(busy) && busy[j] < windowStart+length; j++ {
+			sum += counts[busy[j]]
+		}
+		if throughput := float64(sum) / (float64(length) * interval.Seconds()); throughput > best.Throughput {
+			best.Start = start.Add(time.Duration(windowStart) * interval)
+			best.Throughput = throughput
+		}
+	}
+	return best
+}
+
 type schedulingThroughput struct {
 	Average float64 `json:"average"`
 	Perc50  float64 `json:"perc50"`
 	Perc90  float64 `json:"perc90"`
 	Perc99  float64 `json:"perc99"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	// MaxSustained is the highest throughput sustained over the window, nil if the test was shorter.
+	MaxSustained *sustainedThroughput             `json:"maxSustained,omitempty"`
+	TimeSeries   []throughputSample               `json:"timeSeries,omitempty"`
+	PerScheduler map[string]*schedulingThroughput `json:"perScheduler,omitempty"`
+	PerNamespace map[string]*schedulingThroughput `json:"perNamespace,omitempty"`
+}
+
+type sustainedThroughput struct {
+	Window     string    `json:"window"`
+	Start      time.Time `json:"start"`
+	Throughput float64   `json:"throughput"`
+}
+
+// throughputSample is throughput in the interval starting at Time.
+type throughputSample struct {
+	Time       time.Time `json:"time"`
+	Throughput float64   `json:"throughput"`
 }
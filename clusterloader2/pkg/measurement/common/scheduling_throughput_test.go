@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarizeSchedulingThroughput(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 5, 20, 10 and 0 pods are scheduled in consecutive 5s intervals, the last pod is after the end.
+	counts := map[int]int{0: 5, 1: 20, 2: 10, 4: 1}
+	summary := summarizeSchedulingThroughput(counts, start, 4, 5*time.Second, 10*time.Second)
+	assert.Equal(t, 0.0, summary.Min)
+	assert.Equal(t, 4.0, summary.Max)
+	assert.InDelta(t, 1.75, summary.Average, 1e-9)
+	assert.InEpsilon(t, 1.5, summary.Perc50, 0.01)
+	assert.Equal(t, &sustainedThroughput{Window: "10s", Start: start.Add(5 * time.Second), Throughput: 3}, summary.MaxSustained)
+
+	assert.Nil(t, summarizeSchedulingThroughput(counts, start, 4, 5*time.Second, time.Minute).MaxSustained)
+	// The window ending with the last busy interval has to be aligned with the end.
+	assert.Equal(t, &sustainedThroughput{Window: "15s", Start: start.Add(5 * time.Second), Throughput: 2}, maxSustainedThroughput(map[int]int{3: 30}, start, 4, 5*time.Second, 15*time.Second))
+
+	timeSeries := throughputTimeSeries(counts, start, 4, 5*time.Second)
+	assert.Equal(t, 4, len(timeSeries))
+	assert.Equal(t, throughputSample{Time: start.Add(5 * time.Second), Throughput: 4}, timeSeries[1])
+	assert.Equal(t, throughputSample{Time: start.Add(15 * time.Second), Throughput: 0}, timeSeries[3])
+}
+
+func TestSchedulingThroughputCheckPod(t *testing.T) {
+	s := &schedulingThroughputMeasurement{startTime: time.Now(), interval: time.Minute, counts: make(map[throughputBucket]map[int]int)}
+	unscheduled := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", CreationTimestamp: metav1.Now()}}
+	scheduled := unscheduled.DeepCopy()
+	scheduled.Spec.NodeName = "node"
+	scheduled.Spec.SchedulerName = "custom-scheduler"
+	old := scheduled.DeepCopy()
+	old.CreationTimestamp = metav1.NewTime(s.startTime.Add(-time.Hour))
+
+	s.checkPod(nil, unscheduled)
+	s.checkPod(unscheduled, scheduled)
+	s.checkPod(scheduled, scheduled)
+	s.checkPod(nil, old)
+	s.checkPod(scheduled, nil)
+	assert.Equal(t, map[throughputBucket]map[int]int{{namespace: "a", schedulerName: "custom-scheduler"}: {0: 1}}, s.counts)
+}
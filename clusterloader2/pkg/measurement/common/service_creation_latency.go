@@ -27,6 +27,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/perf-tests/clusterloader2/pkg/execservice"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/checker"
@@ -69,6 +70,7 @@ type serviceCreationLatencyMeasurement struct {
 	isRunning     bool
 	queue         workerqueue.Interface
 	client        clientset.Interface
+	framework     *framework.Framework
 	creationTimes *measurementutil.ObjectTransitionTimes
 	pingCheckers  checker.CheckerMap
 }
@@ -82,6 +84,7 @@ type serviceCreationLatencyMeasurement struct {
 // This measurement only works for services with LoadBalancer type.
 func (s *serviceCreationLatencyMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
 	s.client = config.ClusterFramework.GetClientSets().GetClient()
+	s.framework = config.ClusterFramework
 	action, err := util.GetString(config.Params, "action")
 	if err != nil {
 		return nil, err
@@ -121,6 +124,11 @@ func (s *serviceCreationLatencyMeasurement) String() string {
 	return serviceCreationLatencyName + ": " + s.selector.String()
 }
 
+// Description returns a short description of the measurement.
+func (s *serviceCreationLatencyMeasurement) Description() string {
+	return "Measures the time until LoadBalancer services are reachable."
+}
+
 func (s *serviceCreationLatencyMeasurement) start() error {
 	if s.isRunning {
 		logrus.Infof("%s: service creation latency measurement already running", s)
@@ -251,6 +259,7 @@ func (s *serviceCreationLatencyMeasurement) updateObject(svc *corev1.Service) er
 
 	pc := &pingChecker{
 		callerName:    s.String(),
+		framework:     s.framework,
 		svc:           svc,
 		creationTimes: s.creationTimes,
 		stopCh:        make(chan struct{}),
@@ -263,6 +272,7 @@ func (s *serviceCreationLatencyMeasurement) updateObject(svc *corev1.Service) er
 
 type pingChecker struct {
 	callerName    string
+	framework     *framework.Framework
 	svc           *corev1.Service
 	creationTimes *measurementutil.ObjectTransitionTimes
 	stopCh        chan struct{}
@@ -285,7 +295,7 @@ func (p *pingChecker) run() {
 			}
 			// TODO(#679): Current implementation handles only load balancers.
 			// TODO(#685): Make ping checks less communication heavy.
-			_, err := execservice.RunCommand(
+			_, err := execservice.RunCommand(p.framework,
 				fmt.Sprintf("curl %s:%d", p.svc.Status.LoadBalancer.Ingress[0].IP, p.svc.Spec.Ports[0].Port))
 			if err != nil {
 				success = 0
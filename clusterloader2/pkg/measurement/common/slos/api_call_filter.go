@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// apiCallLabels are labels by which API calls can be filtered.
+var apiCallLabels = []string{"resource", "subresource", "verb", "scope"}
+
+// apiCallFilter selects API calls taken into account by the measurement.
+// A call is selected if, for every label, its value is allowed (or no values are allowed
+// explicitly) and is not denied.
+type apiCallFilter struct {
+	allowed map[string][]string
+	denied  map[string][]string
+}
+
+// parseAPICallFilter parses allow and deny params. Both are maps from label
+// (one of resource, subresource, verb, scope) to a list of values,
+// e.g. {resource: [nodes], subresource: [proxy]}.
+func parseAPICallFilter(params map[string]interface{}) (*apiCallFilter, error) {
+	allowed, err := parseAPICallLabelValues(params, "allow")
+	if err != nil {
+		return nil, err
+	}
+	denied, err := parseAPICallLabelValues(params, "deny")
+	if err != nil {
+		return nil, err
+	}
+	return &apiCallFilter{allowed: allowed, denied: denied}, nil
+}
+
+func parseAPICallLabelValues(params map[string]interface{}, key string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	raw, ok := params[key]
+	if !ok {
+		return result, nil
+	}
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s param: type assertion error: %v is not a map", key, raw)
+	}
+	for label, rawValues := range rawMap {
+		if !contains(apiCallLabels, label) {
+			return nil, fmt.Errorf("%s param: unknown label %q, expected one of %v", key, label, apiCallLabels)
+		}
+		values, ok := rawValues.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s param: %s: type assertion error: %v is not a list", key, label, rawValues)
+		}
+		for _, rawValue := range values {
+			value, ok := rawValue.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s param: %s: type assertion error: %v is not a string", key, label, rawValue)
+			}
+			result[label] = append(result[label], value)
+		}
+	}
+	return result, nil
+}
+
+// selectors returns prometheus label matchers, prefixed with a comma,
+// that should be appended to the filters of queries.
+func (f *apiCallFilter) selectors() string {
+	var matchers []string
+	matchers = append(matchers, labelMatchers(f.allowed, "=~")...)
+	matchers = append(matchers, labelMatchers(f.denied, "!~")...)
+	if len(matchers) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(matchers, ", ")
+}
+
+func labelMatchers(values map[string][]string, operator string) []string {
+	var matchers []string
+	for _, label := range apiCallLabels {
+		if len(values[label]) == 0 {
+			continue
+		}
+		var quoted []string
+		for _, value := range values[label] {
+			quoted = append(quoted, regexp.QuoteMeta(value))
+		}
+		sort.Strings(quoted)
+		matchers = append(matchers, fmt.Sprintf("%s%s%s", label, operator, strconv.Quote(strings.Join(quoted, "|"))))
+	}
+	return matchers
+}
+
+// matches returns true if the call is selected by the filter.
+func (f *apiCallFilter) matches(call *apiCall) bool {
+	values := map[string]string{
+		"resource":    call.Resource,
+		"subresource": call.Subresource,
+		"verb":        call.Verb,
+		"scope":       call.Scope,
+	}
+	for label, value := range values {
+		if allowed := f.allowed[label]; len(allowed) > 0 && !contains(allowed, value) {
+			return false
+		}
+		if contains(f.denied[label], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
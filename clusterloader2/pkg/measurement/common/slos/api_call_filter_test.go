@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPICallFilter(t *testing.T) {
+	filter, err := parseAPICallFilter(map[string]interface{}{
+		"allow": map[string]interface{}{"verb": []interface{}{"LIST", "GET"}},
+		"deny":  map[string]interface{}{"resource": []interface{}{"metrics.k8s.io"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, `, verb=~"GET|LIST", resource!~"metrics\\.k8s\\.io"`, filter.selectors())
+	assert.True(t, filter.matches(&apiCall{Resource: "pods", Verb: "LIST", Scope: "cluster"}))
+	assert.False(t, filter.matches(&apiCall{Resource: "pods", Verb: "POST", Scope: "namespace"}))
+	assert.False(t, filter.matches(&apiCall{Resource: "metrics.k8s.io", Verb: "GET", Scope: "resource"}))
+
+	empty, err := parseAPICallFilter(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "", empty.selectors())
+	assert.True(t, empty.matches(&apiCall{Resource: "pods", Verb: "POST"}))
+
+	_, err = parseAPICallFilter(map[string]interface{}{"deny": map[string]interface{}{"group": []interface{}{"apps"}}})
+	assert.Error(t, err)
+	_, err = parseAPICallFilter(map[string]interface{}{"deny": map[string]interface{}{"verb": "GET"}})
+	assert.Error(t, err)
+}
@@ -94,6 +94,11 @@ func (*apiResponsivenessMeasurement) String() string {
 	return apiResponsivenessMeasurementName
 }
 
+// Description returns a short description of the measurement.
+func (*apiResponsivenessMeasurement) Description() string {
+	return "Gathers latency of apiserver API calls from apiserver metrics and verifies the API call latency SLO."
+}
+
 func (a *apiResponsivenessMeasurement) apiserverMetricsGather(c clientset.Interface, nodeCount int) (measurement.Summary, error) {
 	metrics, err := readLatencyMetrics(c)
 	if err != nil {
@@ -209,6 +214,20 @@ type apiCall struct {
 	Scope       string                        `json:"scope"`
 	Latency     measurementutil.LatencyMetric `json:"latency"`
 	Count       int                           `json:"count"`
+	// ServerErrorCount is the number of calls that failed with 5xx code.
+	ServerErrorCount int `json:"serverErrorCount,omitempty"`
+	// ThrottledCount is the number of calls rejected with 429 code.
+	ThrottledCount int `json:"throttledCount,omitempty"`
+	// TopClients are clients that issued the highest number of calls, sorted by count.
+	TopClients []clientCount `json:"topClients,omitempty"`
+}
+
+// errorRatio returns ratio of failed (5xx) and throttled (429) calls.
+func (a *apiCall) errorRatio() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return float64(a.ServerErrorCount+a.ThrottledCount) / float64(a.Count)
 }
 
 type apiResponsiveness struct {
@@ -275,6 +294,15 @@ func apiCallToPerfData(apicalls *apiResponsiveness) *measurementutil.PerfData {
 				"Count":       fmt.Sprintf("%v", apicall.Count),
 			},
 		}
+		// Error counts are reported only by APIResponsivenessPrometheus.
+		if apicall.ServerErrorCount > 0 || apicall.ThrottledCount > 0 {
+			item.Labels["ServerErrorCount"] = fmt.Sprintf("%v", apicall.ServerErrorCount)
+			item.Labels["ThrottledCount"] = fmt.Sprintf("%v", apicall.ThrottledCount)
+			item.Labels["ErrorRatio"] = fmt.Sprintf("%.4f", apicall.errorRatio())
+		}
+		if len(apicall.TopClients) > 0 {
+			item.Labels["TopClients"] = fmt.Sprintf("%v", apicall.TopClients)
+		}
 		perfData.DataItems = append(perfData.DataItems, item)
 	}
 	return perfData
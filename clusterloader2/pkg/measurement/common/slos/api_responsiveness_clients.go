@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// Apiserver metrics don't carry client identity, so the breakdown of api calls by client
+// is computed from apiserver audit logs read from masters.
+const (
+	defaultAuditLogPath = "/var/log/kube-apiserver-audit.log"
+	defaultTopClients   = 3
+
+	clientIdentityUserAgent = "userAgent"
+	clientIdentityUsername  = "username"
+)
+
+// auditVerbs maps verbs of audit events to verbs of apiserver metrics.
+var auditVerbs = map[string]string{
+	"get":              "GET",
+	"list":             "LIST",
+	"watch":            "WATCH",
+	"create":           "POST",
+	"update":           "PUT",
+	"patch":            "PATCH",
+	"delete":           "DELETE",
+	"deletecollection": "DELETECOLLECTION",
+}
+
+type clientCount struct {
+	Client string `json:"client"`
+	Count  int    `json:"count"`
+}
+
+func (c clientCount) String() string {
+	return fmt.Sprintf("%s: %d", c.Client, c.Count)
+}
+
+// withTopClients appends clients issuing most of the api calls to the violation message.
+func withTopClients(message string, call *apiCall) string {
+	if len(call.TopClients) == 0 {
+		return message
+	}
+	return fmt.Sprintf("%s; top clients: %v", message, call.TopClients)
+}
+
+type auditEvent struct {
+	Stage string `json:"stage"`
+	Verb  string `json:"verb"`
+	User  struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	UserAgent string `json:"userAgent"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+}
+
+// metricKey returns key of the api call of the event, as computed by getMetricKey from metric labels.
+// Scope is computed the same way as by the apiserver. Empty key is returned for non-resource requests.
+func (e *auditEvent) metricKey() string {
+	verb, ok := auditVerbs[e.Verb]
+	if !ok || e.ObjectRef == nil || e.ObjectRef.Resource == "" {
+		return ""
+	}
+	scope := "cluster"
+	switch {
+	case e.ObjectRef.Name != "" || e.Verb == "create":
+		scope = "resource"
+	case e.ObjectRef.Namespace != "":
+		scope = "namespace"
+	}
+	return getMetricKey(e.ObjectRef.Resource, e.ObjectRef.Subresource, verb, scope)
+}
+
+// client returns the client of the event, i.e. the username or the program name of the user agent,
+// e.g. "kube-controller-manager" for "kube-controller-manager/v1.16.0 (linux/amd64) ...".
+func (e *auditEvent) client(identity string) string {
+	client := e.User.Username
+	if identity == clientIdentityUserAgent {
+		client = strings.SplitN(e.UserAgent, "/", 2)[0]
+	}
+	if client == "" {
+		return "unknown"
+	}
+	return client
+}
+
+// countCallsByClient counts completed api calls received within the time range by api call key and client.
+// Lines which aren't audit events are skipped.
+func countCallsByClient(auditLog io.Reader, startTime, endTime time.Time, identity string, counts map[string]map[string]int) error {
+	reader := bufio.NewReader(auditLog)
+	for {
+		// Audit events may exceed the default line limit of bufio.Scanner.
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var event auditEvent
+			if json.Unmarshal(line, &event) == nil && event.Stage == "ResponseComplete" &&
+				!event.RequestReceivedTimestamp.Before(startTime) && !event.RequestReceivedTimestamp.After(endTime) {
+				if key := event.metricKey(); key != "" {
+					if counts[key] == nil {
+						counts[key] = make(map[string]int)
+					}
+					counts[key][event.client(identity)]++
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// addTopClients sets clients issuing most of each api call, in descending order of call count.
+func addTopClients(apiCalls []apiCall, counts map[string]map[string]int, topClients int) {
+	for i := range apiCalls {
+		call := &apiCalls[i]
+		var clients []clientCount
+		for client, count := range counts[getMetricKey(call.Resource, call.Subresource, call.Verb, call.Scope)] {
+			clients = append(clients, clientCount{Client: client, Count: count})
+		}
+		sort.Slice(clients, func(i, j int) bool {
+			if clients[i].Count != clients[j].Count {
+				return clients[i].Count > clients[j].Count
+			}
+			return clients[i].Client < clients[j].Client
+		})
+		if len(clients) > topClients {
+			clients = clients[:topClients]
+		}
+		call.TopClients = clients
+	}
+}
+
+// readAuditLogs returns completed audit events logged by apiservers of all masters.
+func readAuditLogs(config *measurement.MeasurementConfig, auditLogPath string) ([]string, error) {
+	clusterConfig := config.ClusterFramework.GetClusterConfig()
+	provider, err := util.GetStringOrDefault(config.Params, "provider", clusterConfig.Provider)
+	if err != nil {
+		return nil, err
+	}
+	// Filtering on masters significantly reduces the size of transferred logs.
+	cmd := fmt.Sprintf(`sudo grep -h '"stage":"ResponseComplete"' %s`, auditLogPath)
+	var logs []string
+	for _, host := range clusterConfig.MasterIPs {
+		sshResult, err := measurementutil.SSH(cmd, host+":22", provider)
+		// grep exits with 1 if no line matches.
+		if err != nil || (sshResult.Code != 0 && sshResult.Code != 1) {
+			return nil, fmt.Errorf("unexpected error (code: %d) while reading audit log on master %s: %v", sshResult.Code, host, err)
+		}
+		logs = append(logs, sshResult.Stdout)
+	}
+	return logs, nil
+}
+
+// addClientBreakdown sets top clients of api calls from audit logs, if requested by the breakdownByClient param.
+func (a *apiResponsivenessGatherer) addClientBreakdown(apiCalls []apiCall, startTime, endTime time.Time, config *measurement.MeasurementConfig) error {
+	breakdownByClient, err := util.GetBoolOrDefault(config.Params, "breakdownByClient", false)
+	if err != nil || !breakdownByClient {
+		return err
+	}
+	auditLogPath, err := util.GetStringOrDefault(config.Params, "auditLogPath", defaultAuditLogPath)
+	if err != nil {
+		return err
+	}
+	identity, err := util.GetStringOrDefault(config.Params, "clientIdentity", clientIdentityUserAgent)
+	if err != nil {
+		return err
+	}
+	if identity != clientIdentityUserAgent && identity != clientIdentityUsername {
+		return fmt.Errorf("unknown client identity %q, expected %q or %q", identity, clientIdentityUserAgent, clientIdentityUsername)
+	}
+	topClients, err := util.GetIntOrDefault(config.Params, "topClients", defaultTopClients)
+	if err != nil {
+		return err
+	}
+
+	read := a.readAuditLogs
+	if read == nil {
+		read = readAuditLogs
+	}
+	logs, err := read(config, auditLogPath)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]map[string]int)
+	for _, log := range logs {
+		if err := countCallsByClient(strings.NewReader(log), startTime, endTime, identity, counts); err != nil {
+			return err
+		}
+	}
+	if len(counts) == 0 {
+		logrus.Warningf("%s: no audit events found in %s, api calls are not broken down by client", apiResponsivenessMeasurementName, auditLogPath)
+	}
+	addTopClients(apiCalls, counts, topClients)
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/stats"
+)
+
+const (
+	requestDurationBucketMetric = "apiserver_request_duration_seconds_bucket"
+	requestTotalMetric          = "apiserver_request_total"
+)
+
+// StartDirect records current values of request metrics of all apiservers.
+func (a *apiResponsivenessGatherer) StartDirect(f *framework.Framework) error {
+	scrape, err := scrapeAPIServerRequestMetrics(f)
+	if err != nil {
+		return err
+	}
+	a.startScrape = scrape
+	a.startTime = time.Now()
+	return nil
+}
+
+// GatherDirect computes api call latencies and counts from the increase of request metrics
+// of all apiservers since StartDirect, the same way as queries to the prometheus server do.
+func (a *apiResponsivenessGatherer) GatherDirect(f *framework.Framework, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	if a.startScrape == nil {
+		logrus.Warningf("%s: measurement hasn't been started, metrics since apiserver start will be used", a)
+	}
+	callFilter, err := parseAPICallFilter(config.Params)
+	if err != nil {
+		return nil, err
+	}
+	endScrape, err := scrapeAPIServerRequestMetrics(f)
+	if err != nil {
+		return nil, err
+	}
+	apiCalls := a.convertDirectSamplesToAPICalls(increaseSince(a.startScrape, endScrape))
+	var selected []apiCall
+	for i := range apiCalls {
+		if callFilter.matches(&apiCalls[i]) {
+			selected = append(selected, apiCalls[i])
+		}
+	}
+	if err := a.addClientBreakdown(selected, a.startTime, time.Now(), config); err != nil {
+		return nil, err
+	}
+	return a.createSummary(selected, config)
+}
+
+func (a *apiResponsivenessGatherer) convertDirectSamplesToAPICalls(samples []*model.Sample) []apiCall {
+	ignoredResources := sets.NewString("events")
+	// TODO: figure out why we're getting non-capitalized proxy and fix this.
+	ignoredVerbs := sets.NewString("WATCH", "WATCHLIST", "PROXY", "proxy", "CONNECT")
+
+	apiCalls := make(map[string]*apiCall)
+	buckets := make(map[string]map[float64]float64)
+	counts := make(map[string]float64)
+	for _, sample := range samples {
+		resource := string(sample.Metric["resource"])
+		subresource := string(sample.Metric["subresource"])
+		verb := string(sample.Metric["verb"])
+		scope := string(sample.Metric["scope"])
+		if ignoredResources.Has(resource) || ignoredVerbs.Has(verb) {
+			continue
+		}
+		value := float64(sample.Value)
+		key := getMetricKey(resource, subresource, verb, scope)
+		getAPICall(apiCalls, resource, subresource, verb, scope)
+
+		switch sample.Metric[model.MetricNameLabel] {
+		case requestDurationBucketMetric:
+			le, err := strconv.ParseFloat(string(sample.Metric["le"]), 64)
+			if err != nil {
+				logrus.Warningf("%s: invalid bucket %v: %v", a, sample.Metric, err)
+				continue
+			}
+			if buckets[key] == nil {
+				buckets[key] = make(map[float64]float64)
+			}
+			buckets[key][le] += value
+		case requestTotalMetric:
+			counts[key] += value
+			code := string(sample.Metric["code"])
+			if strings.HasPrefix(code, "5") || code == "429" {
+				addErrorCount(apiCalls, resource, subresource, verb, scope, code, int(math.Round(value)))
+			}
+		}
+	}
+
+	var result []apiCall
+	for key, call := range apiCalls {
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			latency := stats.HistogramQuantile(q, buckets[key])
+			call.Latency.SetQuantile(q, time.Duration(latency*float64(time.Second)))
+		}
+		addCount(apiCalls, call.Resource, call.Subresource, call.Verb, call.Scope, int(math.Round(counts[key])))
+		if call.Count == 0 {
+			continue
+		}
+		result = append(result, *call)
+	}
+	return result
+}
+
+// increaseSince returns the increase of samples scraped from each apiserver at the end
+// since the start scrape of the same apiserver.
+func increaseSince(startScrape, endScrape map[string][]*model.Sample) []*model.Sample {
+	var result []*model.Sample
+	for apiserver, samples := range endScrape {
+		startValues := make(map[model.Fingerprint]float64)
+		for _, sample := range startScrape[apiserver] {
+			startValues[sample.Metric.Fingerprint()] = float64(sample.Value)
+		}
+		for _, sample := range samples {
+			value := float64(sample.Value)
+			// New apiservers, new series and counter resets are counted from zero.
+			if start, ok := startValues[sample.Metric.Fingerprint()]; ok && start <= value {
+				value -= start
+			}
+			result = append(result, &model.Sample{Metric: sample.Metric, Value: model.SampleValue(value), Timestamp: sample.Timestamp})
+		}
+	}
+	return result
+}
+
+// scrapeAPIServerRequestMetrics returns request metrics of each apiserver, keyed by its address.
+func scrapeAPIServerRequestMetrics(f *framework.Framework) (map[string][]*model.Sample, error) {
+	clients, err := f.NewAPIServerClients()
+	if err != nil {
+		return nil, err
+	}
+	scrape := make(map[string][]*model.Sample, len(clients))
+	for apiserver, c := range clients {
+		body, err := getMetrics(c)
+		if err != nil {
+			return nil, fmt.Errorf("scraping apiserver %s error: %v", apiserver, err)
+		}
+		samples, err := measurementutil.ExtractMetricSamples(body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing metrics of apiserver %s error: %v", apiserver, err)
+		}
+		for _, sample := range samples {
+			name := sample.Metric[model.MetricNameLabel]
+			if name == requestDurationBucketMetric || name == requestTotalMetric {
+				scrape[apiserver] = append(scrape[apiserver], sample)
+			}
+		}
+	}
+	return scrape, nil
+}
@@ -33,6 +33,7 @@ import (
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
@@ -56,12 +57,33 @@ const (
 	// countQuery %v should be replaced with (1) filters and (2) query window size.
 	countQuery = "sum(increase(apiserver_request_duration_seconds_count{%v}[%v])) by (resource, subresource, scope, verb)"
 
+	// errorCountQuery counts calls that failed (5xx) or were throttled (429). Such calls are often fast,
+	// so they are not visible in latency.
+	//
+	// errorCountQuery: %v should be replaced with (1) filters and (2) query window size.
+	errorCountQuery = `sum(increase(apiserver_request_total{%v, code=~"5..|429"}[%v])) by (resource, subresource, scope, verb, code)`
+
 	latencyWindowSize = 5 * time.Minute
 
 	// Number of metrics with highest latency to print. If the latency exceeeds SLO threshold, a metric is printed regardless.
 	topToPrint = 5
 )
 
+// latencyRecordingRules are recording rules queried by latencyQuery.
+var latencyRecordingRules = []prometheus.RecordingRule{
+	newLatencyRecordingRule(0.99),
+	newLatencyRecordingRule(0.9),
+	newLatencyRecordingRule(0.5),
+}
+
+func newLatencyRecordingRule(quantile float64) prometheus.RecordingRule {
+	return prometheus.RecordingRule{
+		Record: "apiserver:apiserver_request_latency_1m:histogram_quantile",
+		Expr:   fmt.Sprintf("histogram_quantile(%v, sum(rate(apiserver_request_duration_seconds_bucket[1m])) by (resource,  subresource, verb, scope, le))", quantile),
+		Labels: map[string]string{"quantile": fmt.Sprintf("%.2f", quantile)},
+	}
+}
+
 func init() {
 	create := func() measurement.Measurement { return createPrometheusMeasurement(&apiResponsivenessGatherer{}) }
 	if err := measurement.Register(apiResponsivenessPrometheusMeasurementName, create); err != nil {
@@ -69,14 +91,47 @@ func init() {
 	}
 }
 
-type apiResponsivenessGatherer struct{}
+type apiResponsivenessGatherer struct {
+	// startScrape contains metrics of each apiserver scraped at the start of the measurement,
+	// used when metrics are scraped directly from apiservers.
+	startScrape map[string][]*model.Sample
+	startTime   time.Time
+	// readAuditLogs reads audit logs of apiservers, overridden in tests.
+	readAuditLogs func(config *measurement.MeasurementConfig, auditLogPath string) ([]string, error)
+}
 
-func (a *apiResponsivenessGatherer) Gather(executor QueryExecutor, startTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
-	apiCalls, err := a.gatherAPICalls(executor, startTime, config)
+// DeclaredParams returns params of the gatherer.
+func (a *apiResponsivenessGatherer) DeclaredParams() []measurement.Param {
+	gather := []string{"gather"}
+	return []measurement.Param{
+		{Name: "errorBudget", Type: measurement.FloatParam, Actions: gather, Description: "maximal ratio of failed and throttled calls, disabled if negative or unset"},
+		{Name: "summaryName", Type: measurement.StringParam, Actions: gather, Default: apiResponsivenessPrometheusMeasurementName, Description: "name of the summary"},
+		{Name: "useSimpleLatencyQuery", Type: measurement.BoolParam, Default: false, Description: "whether latency is computed from raw histograms instead of recording rules"},
+		{Name: "allow", Type: measurement.MapParam, Actions: gather, Description: "api call labels (e.g. verb, resource) to values of calls taken into account"},
+		{Name: "deny", Type: measurement.MapParam, Actions: gather, Description: "api call labels (e.g. verb, resource) to values of calls ignored"},
+	}
+}
+
+func (a *apiResponsivenessGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	apiCalls, err := a.gatherAPICalls(executor, startTime, endTime, config)
 	if err != nil {
 		logrus.Errorf("%s: samples gathering error: %v", apiResponsivenessMeasurementName, err)
 		return nil, err
 	}
+	if err := a.addClientBreakdown(apiCalls, startTime, endTime, config); err != nil {
+		logrus.Errorf("%s: breaking down api calls by client error: %v", apiResponsivenessMeasurementName, err)
+		return nil, err
+	}
+	return a.createSummary(apiCalls, config)
+}
+
+// createSummary verifies api calls against SLO and error budget and creates the summary.
+func (a *apiResponsivenessGatherer) createSummary(apiCalls []apiCall, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	// errorBudget is the maximum allowed ratio of failed and throttled calls, negative value disables the check.
+	errorBudget, err := util.GetFloat64OrDefault(config.Params, "errorBudget", -1)
+	if err != nil {
+		return nil, err
+	}
 
 	metrics := &apiResponsiveness{ApiCalls: apiCalls}
 	sort.Sort(sort.Reverse(metrics))
@@ -87,7 +142,7 @@ func (a *apiResponsivenessGatherer) Gather(executor QueryExecutor, startTime tim
 		sloThreshold := getSLOThreshold(apiCall.Verb, apiCall.Scope)
 		if err := apiCall.Latency.VerifyThreshold(sloThreshold); err != nil {
 			isBad = true
-			badMetrics = append(badMetrics, err.Error())
+			badMetrics = append(badMetrics, withTopClients(err.Error(), &apiCall))
 		}
 		if top > 0 || isBad {
 			top--
@@ -98,6 +153,18 @@ func (a *apiResponsivenessGatherer) Gather(executor QueryExecutor, startTime tim
 			logrus.Infof("%s: %vTop latency metric: %+v; threshold: %v", apiResponsivenessMeasurementName, prefix, apiCall, sloThreshold)
 		}
 	}
+	var errorBudgetViolations []string
+	for i := range metrics.ApiCalls {
+		apiCall := &metrics.ApiCalls[i]
+		if ratio := apiCall.errorRatio(); ratio > 0 {
+			logrus.Infof("%s: %s %s/%s (%s): %d server errors, %d throttled out of %d calls", apiResponsivenessMeasurementName,
+				apiCall.Verb, apiCall.Resource, apiCall.Subresource, apiCall.Scope, apiCall.ServerErrorCount, apiCall.ThrottledCount, apiCall.Count)
+			if errorBudget >= 0 && ratio > errorBudget {
+				violation := fmt.Sprintf("%s %s/%s (%s): error ratio %.4f", apiCall.Verb, apiCall.Resource, apiCall.Subresource, apiCall.Scope, ratio)
+				errorBudgetViolations = append(errorBudgetViolations, withTopClients(violation, apiCall))
+			}
+		}
+	}
 
 	content, err := util.PrettyPrintJSON(apiCallToPerfData(metrics))
 	if err != nil {
@@ -110,8 +177,16 @@ func (a *apiResponsivenessGatherer) Gather(executor QueryExecutor, startTime tim
 	}
 
 	summary := measurement.CreateSummary(summaryName, "json", content)
+	// Both latency and error budget violations are reported, as either may hide the other.
+	violations := errors.NewErrorList()
 	if len(badMetrics) > 0 {
-		return summary, errors.NewMetricViolationError("top latency metric", fmt.Sprintf("there should be no high-latency requests, but: %v", badMetrics))
+		violations.Append(errors.NewMetricViolationError("top latency metric", fmt.Sprintf("there should be no high-latency requests, but: %v", badMetrics)))
+	}
+	if len(errorBudgetViolations) > 0 {
+		violations.Append(errors.NewMetricViolationError("error ratio", fmt.Sprintf("error budget of %v exceeded: %v", errorBudget, errorBudgetViolations)))
+	}
+	if !violations.IsEmpty() {
+		return summary, violations
 	}
 	return summary, nil
 }
@@ -120,34 +195,47 @@ func (a *apiResponsivenessGatherer) String() string {
 	return apiResponsivenessPrometheusMeasurementName
 }
 
-func (a *apiResponsivenessGatherer) IsEnabled(config *measurement.MeasurementConfig) bool {
-	return true
+// Description returns a short description of the gatherer.
+func (a *apiResponsivenessGatherer) Description() string {
+	return "Measures latency of apiserver API calls with Prometheus and verifies the API call latency SLO."
 }
 
-func (a *apiResponsivenessGatherer) gatherAPICalls(executor QueryExecutor, startTime time.Time, config *measurement.MeasurementConfig) ([]apiCall, error) {
-	measurementEnd := time.Now()
-	measurementDuration := measurementEnd.Sub(startTime)
+// RequiredCapabilities returns capabilities needed to read audit logs from masters, if api calls are broken down by client.
+func (a *apiResponsivenessGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if breakdownByClient, err := util.GetBoolOrDefault(config.Params, "breakdownByClient", false); err != nil || !breakdownByClient {
+		return nil
+	}
+	return []measurement.Capability{measurement.SSH, measurement.MasterAccess}
+}
+
+// RequiredRecordingRules returns recording rules queried by latencyQuery, unless simple latency query is used.
+func (a *apiResponsivenessGatherer) RequiredRecordingRules(config *measurement.MeasurementConfig) []prometheus.RecordingRule {
+	if useSimple, err := util.GetBoolOrDefault(config.Params, "useSimpleLatencyQuery", false); err != nil || useSimple {
+		return nil
+	}
+	return latencyRecordingRules
+}
+
+func (a *apiResponsivenessGatherer) gatherAPICalls(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) ([]apiCall, error) {
+	measurementDuration := endTime.Sub(startTime)
 
 	useSimple, err := util.GetBoolOrDefault(config.Params, "useSimpleLatencyQuery", false)
 	if err != nil {
 		return nil, err
 	}
+	callFilter, err := parseAPICallFilter(config.Params)
+	if err != nil {
+		return nil, err
+	}
+	filters := filters + callFilter.selectors()
 
-	var latencySamples []*model.Sample
+	// Queries are executed concurrently, the last two are always the count and the error count queries.
+	var queries []string
+	quantiles := []float64{0.5, 0.9, 0.99}
 	if useSimple {
 		promDuration := measurementutil.ToPrometheusTime(measurementDuration)
-		quantiles := []float64{0.5, 0.9, 0.99}
 		for _, q := range quantiles {
-			query := fmt.Sprintf(simpleLatencyQuery, q, filters, promDuration)
-			samples, err := executor.Query(query, measurementEnd)
-			if err != nil {
-				return nil, err
-			}
-			// Underlying code assumes presence of 'quantile' label, so adding it manually.
-			for _, sample := range samples {
-				sample.Metric["quantile"] = model.LabelValue(fmt.Sprintf("%.2f", q))
-			}
-			latencySamples = append(latencySamples, samples...)
+			queries = append(queries, fmt.Sprintf(simpleLatencyQuery, q, filters, promDuration))
 		}
 	} else {
 		// Latency measurement is based on 5m window aggregation,
@@ -157,23 +245,45 @@ func (a *apiResponsivenessGatherer) gatherAPICalls(executor QueryExecutor, start
 			latencyMeasurementDuration = time.Minute
 		}
 		promDuration := measurementutil.ToPrometheusTime(latencyMeasurementDuration)
+		queries = append(queries, fmt.Sprintf(latencyQuery, filters, promDuration))
+	}
+	promMeasurementDuration := measurementutil.ToPrometheusTime(measurementDuration)
+	queries = append(queries, fmt.Sprintf(countQuery, filters, promMeasurementDuration))
+	queries = append(queries, fmt.Sprintf(errorCountQuery, filters, promMeasurementDuration))
 
-		query := fmt.Sprintf(latencyQuery, filters, promDuration)
-		latencySamples, err = executor.Query(query, measurementEnd)
-		if err != nil {
-			return nil, err
+	results, err := measurementutil.QueryAll(executor, queries, endTime)
+	if err != nil {
+		return nil, err
+	}
+	var latencySamples []*model.Sample
+	if useSimple {
+		for i, q := range quantiles {
+			// Underlying code assumes presence of 'quantile' label, so adding it manually.
+			for _, sample := range results[i] {
+				sample.Metric["quantile"] = model.LabelValue(fmt.Sprintf("%.2f", q))
+			}
+			latencySamples = append(latencySamples, results[i]...)
 		}
+	} else {
+		latencySamples = results[0]
 	}
-
-	timeBoundedCountQuery := fmt.Sprintf(countQuery, filters, measurementutil.ToPrometheusTime(measurementDuration))
-	countSamples, err := executor.Query(timeBoundedCountQuery, measurementEnd)
+	countSamples := results[len(results)-2]
+	errorCountSamples := results[len(results)-1]
+	apiCalls, err := a.convertToAPICalls(latencySamples, countSamples, errorCountSamples)
 	if err != nil {
 		return nil, err
 	}
-	return a.convertToAPICalls(latencySamples, countSamples)
+	// The filter is applied to query results as well, so that only selected calls are verified against SLO.
+	var selected []apiCall
+	for i := range apiCalls {
+		if callFilter.matches(&apiCalls[i]) {
+			selected = append(selected, apiCalls[i])
+		}
+	}
+	return selected, nil
 }
 
-func (a *apiResponsivenessGatherer) convertToAPICalls(latencySamples, countSamples []*model.Sample) ([]apiCall, error) {
+func (a *apiResponsivenessGatherer) convertToAPICalls(latencySamples, countSamples, errorCountSamples []*model.Sample) ([]apiCall, error) {
 	apiCalls := make(map[string]*apiCall)
 
 	for _, sample := range latencySamples {
@@ -200,6 +310,17 @@ func (a *apiResponsivenessGatherer) convertToAPICalls(latencySamples, countSampl
 		addCount(apiCalls, resource, subresource, verb, scope, count)
 	}
 
+	for _, sample := range errorCountSamples {
+		resource := string(sample.Metric["resource"])
+		subresource := string(sample.Metric["subresource"])
+		verb := string(sample.Metric["verb"])
+		scope := string(sample.Metric["scope"])
+		code := string(sample.Metric["code"])
+
+		count := int(math.Round(float64(sample.Value)))
+		addErrorCount(apiCalls, resource, subresource, verb, scope, code, count)
+	}
+
 	var result []apiCall
 	for _, call := range apiCalls {
 		result = append(result, *call)
@@ -235,6 +356,18 @@ func addCount(apiCalls map[string]*apiCall, resource, subresource, verb, scope s
 	call.Count = count
 }
 
+func addErrorCount(apiCalls map[string]*apiCall, resource, subresource, verb, scope, code string, count int) {
+	if count == 0 {
+		return
+	}
+	call := getAPICall(apiCalls, resource, subresource, verb, scope)
+	if code == "429" {
+		call.ThrottledCount += count
+	} else {
+		call.ServerErrorCount += count
+	}
+}
+
 func getMetricKey(resource, subresource, verb, scope string) string {
 	return fmt.Sprintf("%s|%s|%s|%s", resource, subresource, verb, scope)
 }
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+func TestAPIResponsivenessErrorBudget(t *testing.T) {
+	podsList := model.Metric{"resource": "pods", "verb": "LIST", "scope": "namespace"}
+	withCode := func(code model.LabelValue) model.Metric {
+		metric := podsList.Clone()
+		metric["code"] = code
+		return metric
+	}
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"histogram_quantile(": {{Metric: podsList.Clone(), Value: 0.1}},
+		"sum(increase(apiserver_request_duration_seconds_count": {{Metric: podsList.Clone(), Value: 100}},
+		"sum(increase(apiserver_request_total": {
+			{Metric: withCode("500"), Value: 2},
+			{Metric: withCode("503"), Value: 1},
+			{Metric: withCode("429"), Value: 2},
+		},
+	}}
+	newConfig := func(errorBudget float64) *measurement.MeasurementConfig {
+		return &measurement.MeasurementConfig{Params: map[string]interface{}{
+			"useSimpleLatencyQuery": true,
+			"errorBudget":           errorBudget,
+		}}
+	}
+	g := &apiResponsivenessGatherer{}
+
+	summary, err := g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig(0.1))
+	assert.NoError(t, err)
+	var data measurementutil.PerfData
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	if assert.Len(t, data.DataItems, 1) {
+		labels := data.DataItems[0].Labels
+		assert.Equal(t, "3", labels["ServerErrorCount"])
+		assert.Equal(t, "2", labels["ThrottledCount"])
+		assert.Equal(t, "0.0500", labels["ErrorRatio"])
+	}
+
+	_, err = g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig(0.01))
+	assert.True(t, errors.IsMetricViolationError(err))
+	assert.Len(t, errors.GetMetricViolations(err), 1)
+
+	// Latency violation doesn't hide the exceeded error budget.
+	executor.samples["histogram_quantile("] = []*model.Sample{{Metric: podsList.Clone(), Value: 45}}
+	_, err = g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig(0.01))
+	assert.True(t, errors.IsMetricViolationError(err))
+	if violations := errors.GetMetricViolations(err); assert.Len(t, violations, 2) {
+		assert.Equal(t, "top latency metric", errors.GetViolatedMetric(violations[0]))
+		assert.Equal(t, "error ratio", errors.GetViolatedMetric(violations[1]))
+	}
+}
+
+func TestAPIResponsivenessTopClients(t *testing.T) {
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Hour)
+	podsList := model.Metric{"resource": "pods", "verb": "LIST", "scope": "namespace"}
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"histogram_quantile(": {{Metric: podsList.Clone(), Value: 45}},
+		"sum(increase(apiserver_request_duration_seconds_count": {{Metric: podsList.Clone(), Value: 100}},
+		"sum(increase(apiserver_request_total":                  {},
+	}}
+	auditEvent := func(verb, namespace, name, username, userAgent string, received time.Time) string {
+		return fmt.Sprintf(`{"stage":"ResponseComplete","verb":%q,"user":{"username":%q},"userAgent":%q,`+
+			`"objectRef":{"resource":"pods","namespace":%q,"name":%q},"requestReceivedTimestamp":%q}`+"\n",
+			verb, username, userAgent, namespace, name, received.Format(time.RFC3339Nano))
+	}
+	during := startTime.Add(time.Minute)
+	controllerManager := auditEvent("list", "default", "", "system:kube-controller-manager", "kube-controller-manager/v1.16.0 (linux/amd64)", during)
+	kubelet := auditEvent("list", "default", "", "system:node:node-1", "kubelet/v1.16.0 (linux/amd64)", during)
+	logs := []string{
+		strings.Repeat(controllerManager, 3) + kubelet + "not an audit event\n" +
+			// Calls of other scopes, outside of the measurement or not completed aren't counted.
+			auditEvent("list", "", "", "scheduler", "kube-scheduler/v1.16.0", during) +
+			auditEvent("get", "default", "pod", "scheduler", "kube-scheduler/v1.16.0", during) +
+			auditEvent("list", "default", "", "scheduler", "kube-scheduler/v1.16.0", startTime.Add(-time.Minute)) +
+			strings.Replace(auditEvent("list", "default", "", "scheduler", "kube-scheduler/v1.16.0", during), "ResponseComplete", "RequestReceived", 1),
+		kubelet + strings.TrimSuffix(kubelet, "\n"),
+	}
+
+	testCases := []struct {
+		identity       string
+		wantTopClients string
+	}{
+		{
+			identity:       "userAgent",
+			wantTopClients: "[kube-controller-manager: 3 kubelet: 3]",
+		},
+		{
+			identity:       "username",
+			wantTopClients: "[system:kube-controller-manager: 3 system:node:node-1: 3]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.identity, func(t *testing.T) {
+			config := &measurement.MeasurementConfig{Params: map[string]interface{}{
+				"useSimpleLatencyQuery": true,
+				"breakdownByClient":     true,
+				"clientIdentity":        tc.identity,
+				"topClients":            2,
+			}}
+			g := &apiResponsivenessGatherer{
+				readAuditLogs: func(*measurement.MeasurementConfig, string) ([]string, error) { return logs, nil },
+			}
+
+			summary, err := g.Gather(executor, startTime, endTime, config)
+			assert.True(t, errors.IsMetricViolationError(err))
+			assert.Contains(t, err.Error(), "top clients: "+tc.wantTopClients)
+			var data measurementutil.PerfData
+			if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+				t.Fatalf("error while decoding summary: %v", err)
+			}
+			if assert.Len(t, data.DataItems, 1) {
+				assert.Equal(t, tc.wantTopClients, data.DataItems[0].Labels["TopClients"])
+			}
+		})
+	}
+}
+
+func TestAPIResponsivenessDirectSamples(t *testing.T) {
+	newSample := func(name string, labels model.Metric, value float64) *model.Sample {
+		metric := model.Metric{model.MetricNameLabel: model.LabelValue(name), "resource": "pods", "verb": "LIST", "scope": "namespace"}
+		for k, v := range labels {
+			metric[k] = v
+		}
+		return &model.Sample{Metric: metric, Value: model.SampleValue(value)}
+	}
+	bucket := func(le string, value float64) *model.Sample {
+		return newSample(requestDurationBucketMetric, model.Metric{"le": model.LabelValue(le)}, value)
+	}
+	total := func(code string, value float64) *model.Sample {
+		return newSample(requestTotalMetric, model.Metric{"code": model.LabelValue(code)}, value)
+	}
+	g := &apiResponsivenessGatherer{}
+	startScrape := map[string][]*model.Sample{
+		"10.0.0.1:443": {bucket("1", 10), bucket("+Inf", 10), total("200", 10)},
+		"10.0.0.2:443": {bucket("1", 50), bucket("+Inf", 50), total("200", 50)},
+	}
+	endScrape := map[string][]*model.Sample{
+		"10.0.0.1:443": {
+			bucket("1", 110), bucket("+Inf", 110),
+			total("200", 105), total("503", 5),
+			newSample(requestTotalMetric, model.Metric{"code": "200", "resource": "events"}, 1000),
+		},
+	}
+
+	apiCalls := g.convertDirectSamplesToAPICalls(increaseSince(startScrape, endScrape))
+	if assert.Len(t, apiCalls, 1) {
+		assert.Equal(t, 100, apiCalls[0].Count)
+		assert.Equal(t, 5, apiCalls[0].ServerErrorCount)
+		assert.Equal(t, 990*time.Millisecond, apiCalls[0].Latency.Perc99)
+	}
+
+	// Increases of all apiservers are summed up, apiservers started during the measurement are counted from zero.
+	endScrape["10.0.0.2:443"] = []*model.Sample{bucket("1", 50), bucket("+Inf", 150), total("200", 150)}
+	endScrape["10.0.0.3:443"] = []*model.Sample{bucket("1", 10), bucket("+Inf", 10), total("200", 10)}
+	apiCalls = g.convertDirectSamplesToAPICalls(increaseSince(startScrape, endScrape))
+	if assert.Len(t, apiCalls, 1) {
+		assert.Equal(t, 210, apiCalls[0].Count)
+		assert.Equal(t, 5, apiCalls[0].ServerErrorCount)
+		assert.Equal(t, time.Second, apiCalls[0].Latency.Perc99)
+	}
+}
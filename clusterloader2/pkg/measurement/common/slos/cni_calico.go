@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+const (
+	// Queries below aggregate calico felix metrics per node over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	// Felix exports dataplane apply time as a summary, so the maximum of reported 99th percentiles is used.
+	calicoDataplaneApplyTimeQuery  = `max by (node) (max_over_time(felix_int_dataplane_apply_time_seconds{quantile="0.99"}[%v]))`
+	calicoDataplaneFailuresQuery   = `sum by (node) (increase(felix_int_dataplane_failures_total[%v]))`
+	calicoIptablesRestoreErrsQuery = `sum by (node) (increase(felix_iptables_restore_errors[%v]))`
+)
+
+func init() {
+	registerCNIModule(&calicoModule{})
+}
+
+// calicoModule reports dataplane sync performance of calico felix agents.
+type calicoModule struct{}
+
+func (c *calicoModule) Name() string {
+	return "calico"
+}
+
+func (c *calicoModule) Description() string {
+	return "Dataplane apply latency, dataplane failures and iptables-restore errors of calico felix agents, per node."
+}
+
+func (c *calicoModule) Queries() []*genericQuery {
+	return []*genericQuery{
+		{name: "DataplaneApplyTimePerc99", query: calicoDataplaneApplyTimeQuery},
+		{name: "DataplaneFailures", query: calicoDataplaneFailuresQuery, threshold: mustParseThreshold("== 0"), allowEmpty: true},
+		{name: "IptablesRestoreErrors", query: calicoIptablesRestoreErrsQuery},
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+const (
+	// Queries below aggregate cilium agent metrics per node over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	ciliumEndpointRegenerationQuery = `histogram_quantile(0.99, sum by (node, le) (increase(cilium_endpoint_regeneration_time_stats_seconds_bucket{scope="total"}[%v])))`
+	ciliumPolicyRegenerationQuery   = `histogram_quantile(0.99, sum by (node, le) (increase(cilium_policy_regeneration_time_stats_seconds_bucket{scope="total"}[%v])))`
+	ciliumFailedRegenerationsQuery  = `sum by (node) (increase(cilium_endpoint_regenerations_total{outcome="fail"}[%v]))`
+)
+
+func init() {
+	registerCNIModule(&ciliumModule{})
+}
+
+// ciliumModule reports endpoint and policy regeneration performance of cilium agents.
+// Endpoint regeneration is on the critical path of pod network readiness.
+type ciliumModule struct{}
+
+func (c *ciliumModule) Name() string {
+	return "cilium"
+}
+
+func (c *ciliumModule) Description() string {
+	return "Endpoint and policy regeneration latency and failed endpoint regenerations of cilium agents, per node."
+}
+
+func (c *ciliumModule) Queries() []*genericQuery {
+	return []*genericQuery{
+		{name: "EndpointRegenerationTimePerc99", query: ciliumEndpointRegenerationQuery},
+		{name: "PolicyRegenerationTimePerc99", query: ciliumPolicyRegenerationQuery},
+		{name: "FailedEndpointRegenerations", query: ciliumFailedRegenerationsQuery, threshold: mustParseThreshold("== 0"), allowEmpty: true},
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+const (
+	cniPerformanceName = "CNIPerformance"
+)
+
+// cniModules contains registered CNI modules, keyed by CNI name.
+var cniModules = make(map[string]metricsModule)
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(newCNIPerformanceGatherer()) }
+	if err := measurement.Register(cniPerformanceName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", cniPerformanceName, err)
+	}
+}
+
+// newCNIPerformanceGatherer creates gatherer reporting, per node, internal performance metrics
+// of the CNI agent selected with prometheus-scrape-cni flag.
+func newCNIPerformanceGatherer() Gatherer {
+	return &modularGatherer{
+		name:           cniPerformanceName,
+		description:    "Reports per-node performance metrics of the CNI agent selected with prometheus-scrape-cni flag.",
+		moduleLabel:    "CNI",
+		modules:        cniModules,
+		capability:     measurement.CNIMetrics,
+		selectedModule: func(c *config.ClusterLoaderConfig) string { return c.PrometheusConfig.ScrapeCNI },
+	}
+}
+
+func registerCNIModule(module metricsModule) {
+	cniModules[module.Name()] = module
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	genericQueryName = "GenericPrometheusQuery"
+
+	// windowPlaceholder in the query is replaced with the duration of the measurement.
+	windowPlaceholder = "%v"
+)
+
+// thresholdOperators are ordered so that two-character operators are matched first.
+var thresholdOperators = []string{"<=", ">=", "==", "<", ">"}
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&genericQueryGatherer{}) }
+	if err := measurement.Register(genericQueryName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", genericQueryName, err)
+	}
+}
+
+type genericQueryGatherer struct{}
+
+type genericQuery struct {
+	name      string
+	query     string
+	threshold *threshold
+	// allowEmpty allows the query with threshold to return no samples, e.g. a counter of failures
+	// which isn't exported until the first failure. Otherwise no samples violate the threshold.
+	allowEmpty bool
+}
+
+// threshold represents a condition that value returned by a query has to satisfy.
+type threshold struct {
+	expression string
+	operator   string
+	value      float64
+}
+
+// parseThreshold parses expressions like "<= 0.5s", "> 100" or ">= 99%".
+// Durations are converted to seconds and percentages to ratios,
+// which are the units used by Prometheus.
+func parseThreshold(expression string) (*threshold, error) {
+	expr := strings.TrimSpace(expression)
+	t := &threshold{expression: expression}
+	for _, operator := range thresholdOperators {
+		if strings.HasPrefix(expr, operator) {
+			t.operator = operator
+			expr = strings.TrimSpace(strings.TrimPrefix(expr, operator))
+			break
+		}
+	}
+	if t.operator == "" {
+		return nil, fmt.Errorf("threshold %q: missing comparison operator, one of %v expected", expression, thresholdOperators)
+	}
+	if strings.HasSuffix(expr, "%") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(expr, "%")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("threshold %q: parsing percentage error: %v", expression, err)
+		}
+		t.value = value / 100
+		return t, nil
+	}
+	if value, err := strconv.ParseFloat(expr, 64); err == nil {
+		t.value = value
+		return t, nil
+	}
+	duration, err := time.ParseDuration(expr)
+	if err != nil {
+		return nil, fmt.Errorf("threshold %q: value is neither a number, a percentage nor a duration", expression)
+	}
+	t.value = duration.Seconds()
+	return t, nil
+}
+
+// isSatisfied returns true if the value satisfies the threshold.
+func (t *threshold) isSatisfied(value float64) bool {
+	switch t.operator {
+	case "<=":
+		return value <= t.value
+	case ">=":
+		return value >= t.value
+	case "==":
+		return value == t.value
+	case "<":
+		return value < t.value
+	case ">":
+		return value > t.value
+	}
+	return false
+}
+
+func (g *genericQueryGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return nil
+}
+
+// Gather executes queries provided in the config at the end of the measurement.
+// Every sample returned by a query is verified against the query threshold, if specified.
+// A query with threshold returning no samples violates it, unless allowEmpty is set.
+// Results are presented as PerfData, with one data item per distinct set of sample labels,
+// containing values of all queries that returned a sample with these labels.
+func (g *genericQueryGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	metricName, err := util.GetString(config.Params, "metricName")
+	if err != nil {
+		return nil, err
+	}
+	metricVersion, err := util.GetStringOrDefault(config.Params, "metricVersion", "v1")
+	if err != nil {
+		return nil, err
+	}
+	unit, err := util.GetStringOrDefault(config.Params, "unit", "")
+	if err != nil {
+		return nil, err
+	}
+	queries, err := parseGenericQueries(config.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	perfData, violations, err := executeGenericQueries(executor, queries, startTime, endTime, metricName, metricVersion, unit)
+	if err != nil {
+		return nil, err
+	}
+	content, err := util.PrettyPrintJSON(perfData)
+	if err != nil {
+		return nil, err
+	}
+	summary := measurement.CreateSummary(fmt.Sprintf("%s_%s", genericQueryName, metricName), "json", content)
+	if len(violations) > 0 {
+		err := errors.NewMetricViolationError(metricName, strings.Join(violations, "; "))
+		logrus.Errorf("%s: %v", g, err)
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (g *genericQueryGatherer) String() string {
+	return genericQueryName
+}
+
+// Description returns a short description of the gatherer.
+func (g *genericQueryGatherer) Description() string {
+	return "Executes Prometheus queries given in the config and verifies their thresholds."
+}
+
+// executeGenericQueries executes queries at the end of the measurement and verifies
+// returned samples against query thresholds. Results are presented as PerfData, with one
+// data item per distinct set of sample labels. Violated thresholds are returned as well.
+func executeGenericQueries(executor QueryExecutor, queries []*genericQuery, startTime, endTime time.Time, metricName, metricVersion, unit string) (*measurementutil.PerfData, []string, error) {
+	window := measurementutil.ToPrometheusTime(endTime.Sub(startTime))
+	dataItems := make(map[string]*measurementutil.DataItem)
+	var keys, violations []string
+	var promQueries []string
+	for _, q := range queries {
+		promQueries = append(promQueries, strings.Replace(q.query, windowPlaceholder, window, -1))
+	}
+	results, err := measurementutil.QueryAll(executor, promQueries, endTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, q := range queries {
+		if len(results[i]) == 0 && q.threshold != nil && !q.allowEmpty {
+			violations = append(violations, fmt.Sprintf("%s: no data, expected %s", q.name, q.threshold.expression))
+		}
+		for _, sample := range results[i] {
+			key := model.LabelSet(sample.Metric).String()
+			if _, ok := dataItems[key]; !ok {
+				dataItems[key] = &measurementutil.DataItem{
+					Data:   make(map[string]float64),
+					Unit:   unit,
+					Labels: dataItemLabels(metricName, sample.Metric),
+				}
+				keys = append(keys, key)
+			}
+			value := float64(sample.Value)
+			dataItems[key].Data[q.name] = value
+			if q.threshold != nil && !q.threshold.isSatisfied(value) {
+				violations = append(violations, fmt.Sprintf("%s%s: %v, expected %s", q.name, key, value, q.threshold.expression))
+			}
+		}
+	}
+
+	perfData := &measurementutil.PerfData{
+		Version:   metricVersion,
+		DataItems: []measurementutil.DataItem{},
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		perfData.DataItems = append(perfData.DataItems, *dataItems[key])
+	}
+	return perfData, violations, nil
+}
+
+// parseGenericQueries parses queries param, which is a list of
+// {name, query, threshold, allowEmpty} maps, where threshold and allowEmpty are optional.
+func parseGenericQueries(params map[string]interface{}) ([]*genericQuery, error) {
+	rawQueries, ok := params["queries"].([]interface{})
+	if !ok || len(rawQueries) == 0 {
+		return nil, fmt.Errorf("queries param is missing or is not a list")
+	}
+	var queries []*genericQuery
+	for i, rawQuery := range rawQueries {
+		queryParams, ok := rawQuery.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("query %d: type assertion error: %v is not a map", i, rawQuery)
+		}
+		q := &genericQuery{}
+		var err error
+		if q.name, err = util.GetString(queryParams, "name"); err != nil {
+			return nil, fmt.Errorf("query %d: %v", i, err)
+		}
+		if q.query, err = util.GetString(queryParams, "query"); err != nil {
+			return nil, fmt.Errorf("query %s: %v", q.name, err)
+		}
+		thresholdExpression, err := util.GetStringOrDefault(queryParams, "threshold", "")
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %v", q.name, err)
+		}
+		if thresholdExpression != "" {
+			if q.threshold, err = parseThreshold(thresholdExpression); err != nil {
+				return nil, fmt.Errorf("query %s: %v", q.name, err)
+			}
+		}
+		if q.allowEmpty, err = util.GetBoolOrDefault(queryParams, "allowEmpty", false); err != nil {
+			return nil, fmt.Errorf("query %s: %v", q.name, err)
+		}
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+// dataItemLabels returns labels of the sample, together with the metric name.
+func dataItemLabels(metricName string, metric model.Metric) map[string]string {
+	labels := map[string]string{"Metric": metricName}
+	for name, value := range metric {
+		labels[string(name)] = string(value)
+	}
+	return labels
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+func TestParseThreshold(t *testing.T) {
+	cases := []struct {
+		expression string
+		value      float64
+		satisfied  bool
+		wantErr    bool
+	}{
+		{expression: "<= 0.5s", value: 0.5, satisfied: true},
+		{expression: "<=500ms", value: 0.6, satisfied: false},
+		{expression: ">= 99%", value: 0.995, satisfied: true},
+		{expression: ">= 99%", value: 0.98, satisfied: false},
+		{expression: "< 100", value: 100, satisfied: false},
+		{expression: "> 1m", value: 61, satisfied: true},
+		{expression: "== 0", value: 0, satisfied: true},
+		{expression: "100", wantErr: true},
+		{expression: "<= fast", wantErr: true},
+	}
+	for _, tc := range cases {
+		threshold, err := parseThreshold(tc.expression)
+		if tc.wantErr {
+			assert.Error(t, err, tc.expression)
+			continue
+		}
+		if assert.NoError(t, err, tc.expression) {
+			assert.Equal(t, tc.satisfied, threshold.isSatisfied(tc.value), tc.expression)
+		}
+	}
+}
+
+func TestGenericQueryGather(t *testing.T) {
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"histogram_quantile": {
+			{Metric: model.Metric{"verb": "GET"}, Value: 0.2},
+			{Metric: model.Metric{"verb": "LIST"}, Value: 1.5},
+		},
+		"sum(rate": {
+			{Metric: model.Metric{"verb": "GET"}, Value: 100},
+		},
+	}}
+	config := &measurement.MeasurementConfig{Params: map[string]interface{}{
+		"metricName": "RequestLatency",
+		"unit":       "s",
+		"queries": []interface{}{
+			map[string]interface{}{"name": "Perc99", "query": "histogram_quantile(0.99, rate(latency_bucket[%v]))", "threshold": "<= 1s"},
+			map[string]interface{}{"name": "Rate", "query": "sum(rate(requests_total[%v])) by (verb)"},
+		},
+	}}
+	g := &genericQueryGatherer{}
+	summary, err := g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), config)
+	assert.True(t, errors.IsMetricViolationError(err))
+	assert.Equal(t, "GenericPrometheusQuery_RequestLatency", summary.SummaryName())
+
+	var data measurementutil.PerfData
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Equal(t, []measurementutil.DataItem{
+		{
+			Data:   map[string]float64{"Perc99": 0.2, "Rate": 100},
+			Unit:   "s",
+			Labels: map[string]string{"Metric": "RequestLatency", "verb": "GET"},
+		},
+		{
+			Data:   map[string]float64{"Perc99": 1.5},
+			Unit:   "s",
+			Labels: map[string]string{"Metric": "RequestLatency", "verb": "LIST"},
+		},
+	}, data.DataItems)
+}
+
+func TestGenericQueryEmptyResult(t *testing.T) {
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"sum(increase(failures_total": {},
+	}}
+	cases := []struct {
+		name          string
+		query         map[string]interface{}
+		wantViolation bool
+	}{
+		{
+			name:          "threshold",
+			query:         map[string]interface{}{"name": "Failures", "query": "sum(increase(failures_total[%v]))", "threshold": "== 0"},
+			wantViolation: true,
+		},
+		{
+			name:  "threshold allowing empty result",
+			query: map[string]interface{}{"name": "Failures", "query": "sum(increase(failures_total[%v]))", "threshold": "== 0", "allowEmpty": true},
+		},
+		{
+			name:  "no threshold",
+			query: map[string]interface{}{"name": "Failures", "query": "sum(increase(failures_total[%v]))"},
+		},
+	}
+	for _, tc := range cases {
+		config := &measurement.MeasurementConfig{Params: map[string]interface{}{
+			"metricName": "Failures",
+			"queries":    []interface{}{tc.query},
+		}}
+		g := &genericQueryGatherer{}
+		_, err := g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), config)
+		if tc.wantViolation {
+			assert.True(t, errors.IsMetricViolationError(err), tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
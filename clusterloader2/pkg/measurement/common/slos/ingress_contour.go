@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+const (
+	// Queries below aggregate contour and envoy metrics over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	// Contour exports xDS cache update time as a summary, so the maximum of reported 99th percentiles is used.
+	contourConfigUpdateLatencyQuery = `max(max_over_time(contour_cachehandler_onupdate_duration_seconds{quantile="0.99"}[%v]))`
+	contourDAGRebuildsQuery         = `sum(increase(contour_dagrebuild_total[%v]))`
+	contourConnectFailuresQuery     = `sum(increase(envoy_cluster_upstream_cx_connect_fail[%v]))`
+	contourDroppedRequestsQuery     = `sum(increase(envoy_cluster_upstream_rq_xx{envoy_response_code_class="5"}[%v]))`
+	// Envoy reports request time in milliseconds.
+	contourRequestLatencyQuery = `histogram_quantile(0.99, sum by (le) (increase(envoy_cluster_upstream_rq_time_bucket[%v]))) / 1000`
+)
+
+func init() {
+	registerIngressModule(&contourModule{})
+}
+
+// contourModule reports config update latency of contour and dropped connections of envoy proxies.
+type contourModule struct{}
+
+func (c *contourModule) Name() string {
+	return "contour"
+}
+
+func (c *contourModule) Description() string {
+	return "Config update latency and DAG rebuilds of contour, connect failures, dropped requests and request latency of envoy."
+}
+
+func (c *contourModule) Queries() []*genericQuery {
+	return []*genericQuery{
+		{name: "ConfigUpdateLatencyPerc99", query: contourConfigUpdateLatencyQuery},
+		{name: "DAGRebuilds", query: contourDAGRebuildsQuery},
+		{name: "ConnectFailures", query: contourConnectFailuresQuery},
+		{name: "DroppedRequests", query: contourDroppedRequestsQuery},
+		{name: "RequestLatencyPerc99", query: contourRequestLatencyQuery},
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+const (
+	// Queries below aggregate ingress-nginx controller metrics over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	nginxReloadsQuery         = `sum(increase(nginx_ingress_controller_success[%v]))`
+	nginxFailedReloadsQuery   = `sum(increase(nginx_ingress_controller_errors[%v]))`
+	nginxDroppedRequestsQuery = `sum(increase(nginx_ingress_controller_requests{status=~"502|503|504"}[%v]))`
+	nginxRequestLatencyQuery  = `histogram_quantile(0.99, sum by (le) (increase(nginx_ingress_controller_request_duration_seconds_bucket[%v])))`
+)
+
+func init() {
+	registerIngressModule(&nginxModule{})
+}
+
+// nginxModule reports config reloads and dropped requests of ingress-nginx controller.
+type nginxModule struct{}
+
+func (n *nginxModule) Name() string {
+	return "nginx"
+}
+
+// Description states that reload latency is not reported, as ingress-nginx exports only
+// the number of reloads and the time of the last one, not their duration.
+func (n *nginxModule) Description() string {
+	return "Config reloads, failed reloads, dropped requests and request latency of ingress-nginx. " +
+		"Reload latency is not reported, as ingress-nginx doesn't export duration of reloads."
+}
+
+func (n *nginxModule) Queries() []*genericQuery {
+	return []*genericQuery{
+		{name: "Reloads", query: nginxReloadsQuery},
+		{name: "FailedReloads", query: nginxFailedReloadsQuery, threshold: mustParseThreshold("== 0"), allowEmpty: true},
+		{name: "DroppedRequests", query: nginxDroppedRequestsQuery},
+		{name: "RequestLatencyPerc99", query: nginxRequestLatencyQuery},
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+const (
+	ingressPerformanceName = "IngressPerformance"
+)
+
+// ingressModules contains registered ingress controller modules, keyed by controller name.
+var ingressModules = make(map[string]metricsModule)
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(newIngressPerformanceGatherer()) }
+	if err := measurement.Register(ingressPerformanceName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", ingressPerformanceName, err)
+	}
+}
+
+// newIngressPerformanceGatherer creates gatherer reporting config reload performance and dropped
+// requests of the ingress controller selected with prometheus-scrape-ingress-controller flag.
+// The measurement is meant to be started before and gathered after backend churn phases.
+func newIngressPerformanceGatherer() Gatherer {
+	return &modularGatherer{
+		name:           ingressPerformanceName,
+		description:    "Reports config reload performance and dropped requests of the ingress controller.",
+		moduleLabel:    "IngressController",
+		modules:        ingressModules,
+		capability:     measurement.IngressControllerMetrics,
+		selectedModule: func(c *config.ClusterLoaderConfig) string { return c.PrometheusConfig.ScrapeIngressController },
+	}
+}
+
+func registerIngressModule(module metricsModule) {
+	ingressModules[module.Name()] = module
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	kubeletPodDensityName = "KubeletPodDensity"
+
+	defaultMaxPodsPerNode       = 110
+	defaultMaxPLEGRelistLatency = time.Second
+
+	// Queries below aggregate kubelet metrics per node over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	maxRunningPodsQuery       = "max by (node, instance) (max_over_time(kubelet_running_pod_count[%v]))"
+	plegRelistLatencyQuery    = "histogram_quantile(0.99, sum by (node, instance, le) (rate(kubelet_pleg_relist_duration_seconds_bucket[%v])))"
+	runtimeOperationErrsQuery = "sum by (node, instance) (increase(kubelet_runtime_operations_errors_total[%v]))"
+)
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&kubeletPodDensityGatherer{}) }
+	if err := measurement.Register(kubeletPodDensityName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", kubeletPodDensityName, err)
+	}
+}
+
+type kubeletPodDensityGatherer struct{}
+
+// nodeDensity describes kubelet health of a single node.
+type nodeDensity struct {
+	Node                    string  `json:"node"`
+	MaxRunningPods          float64 `json:"maxRunningPods"`
+	PLEGRelistLatencyPerc99 float64 `json:"plegRelistLatencyPerc99"`
+	RuntimeOperationErrors  float64 `json:"runtimeOperationErrors"`
+}
+
+type kubeletPodDensitySummary struct {
+	Nodes []*nodeDensity `json:"nodes"`
+}
+
+func (k *kubeletPodDensityGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return []measurement.Capability{measurement.KubeletMetrics}
+}
+
+// Gather reports per-node pod counts, PLEG relist latency and runtime operation errors.
+// If any node exceeds maxPodsPerNode or maxPLEGRelistLatency, a metric violation error is returned.
+func (k *kubeletPodDensityGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	maxPods, err := util.GetIntOrDefault(config.Params, "maxPodsPerNode", defaultMaxPodsPerNode)
+	if err != nil {
+		return nil, err
+	}
+	maxPLEGLatency, err := util.GetDurationOrDefault(config.Params, "maxPLEGRelistLatency", defaultMaxPLEGRelistLatency)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := k.query(executor, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, node := range nodes {
+		if node.MaxRunningPods > float64(maxPods) {
+			violations = append(violations, fmt.Sprintf("%s: %v pods (max %d)", node.Node, node.MaxRunningPods, maxPods))
+		}
+		if latency := time.Duration(node.PLEGRelistLatencyPerc99 * float64(time.Second)); latency > maxPLEGLatency {
+			violations = append(violations, fmt.Sprintf("%s: PLEG relist latency %v (max %v)", node.Node, latency, maxPLEGLatency))
+		}
+	}
+	var violation error
+	if len(violations) > 0 {
+		violation = errors.NewMetricViolationError("kubelet pod density", strings.Join(violations, "; "))
+		logrus.Errorf("%s: %v", k, violation)
+	}
+
+	content, err := util.PrettyPrintJSON(&kubeletPodDensitySummary{Nodes: nodes})
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(kubeletPodDensityName, "json", content), violation
+}
+
+func (k *kubeletPodDensityGatherer) String() string {
+	return kubeletPodDensityName
+}
+
+// Description returns a short description of the gatherer.
+func (k *kubeletPodDensityGatherer) Description() string {
+	return "Reports per-node pod counts, PLEG relist latency and runtime operation errors."
+}
+
+func (k *kubeletPodDensityGatherer) query(executor QueryExecutor, startTime, endTime time.Time) ([]*nodeDensity, error) {
+	window := measurementutil.ToPrometheusTime(endTime.Sub(startTime))
+
+	nodes := make(map[string]*nodeDensity)
+	setters := map[string]func(*nodeDensity, float64){
+		maxRunningPodsQuery:       func(n *nodeDensity, v float64) { n.MaxRunningPods = v },
+		plegRelistLatencyQuery:    func(n *nodeDensity, v float64) { n.PLEGRelistLatencyPerc99 = v },
+		runtimeOperationErrsQuery: func(n *nodeDensity, v float64) { n.RuntimeOperationErrors = v },
+	}
+	var queries []string
+	var queriesSetters []func(*nodeDensity, float64)
+	for query, set := range setters {
+		queries = append(queries, fmt.Sprintf(query, window))
+		queriesSetters = append(queriesSetters, set)
+	}
+	results, err := measurementutil.QueryAll(executor, queries, endTime)
+	if err != nil {
+		return nil, err
+	}
+	for i, samples := range results {
+		set := queriesSetters[i]
+		for _, sample := range samples {
+			if math.IsNaN(float64(sample.Value)) {
+				// histogram_quantile returns NaN if there were no observations.
+				continue
+			}
+			name := nodeName(sample.Metric)
+			if _, ok := nodes[name]; !ok {
+				nodes[name] = &nodeDensity{Node: name}
+			}
+			set(nodes[name], float64(sample.Value))
+		}
+	}
+
+	result := make([]*nodeDensity, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, node)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Node < result[j].Node })
+	return result, nil
+}
+
+// nodeName returns node name of the sample, falling back to the scraped instance.
+func nodeName(metric model.Metric) string {
+	if node, ok := metric["node"]; ok {
+		return string(node)
+	}
+	return string(metric["instance"])
+}
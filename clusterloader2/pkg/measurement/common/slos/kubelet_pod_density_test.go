@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+type queryMatchingExecutor struct {
+	samples map[string][]*model.Sample
+	streams map[string][]*model.SampleStream
+}
+
+func (q *queryMatchingExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	for prefix, samples := range q.samples {
+		if strings.HasPrefix(query, prefix) {
+			return samples, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+func (q *queryMatchingExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	for prefix, streams := range q.streams {
+		if strings.HasPrefix(query, prefix) {
+			return streams, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected range query: %s", query)
+}
+
+func createNodeSample(node string, value float64) *model.Sample {
+	return &model.Sample{
+		Value:  model.SampleValue(value),
+		Metric: model.Metric{"node": model.LabelValue(node)},
+	}
+}
+
+func TestKubeletPodDensityGather(t *testing.T) {
+	cases := []struct {
+		name          string
+		pods          []*model.Sample
+		pleg          []*model.Sample
+		wantViolation bool
+	}{
+		{
+			name: "healthy",
+			pods: []*model.Sample{createNodeSample("node-1", 30), createNodeSample("node-2", 50)},
+			pleg: []*model.Sample{createNodeSample("node-1", 0.2), createNodeSample("node-2", math.NaN())},
+		},
+		{
+			name:          "too-many-pods",
+			pods:          []*model.Sample{createNodeSample("node-1", 120)},
+			pleg:          []*model.Sample{createNodeSample("node-1", 0.2)},
+			wantViolation: true,
+		},
+		{
+			name:          "slow-pleg",
+			pods:          []*model.Sample{createNodeSample("node-1", 30)},
+			pleg:          []*model.Sample{createNodeSample("node-1", 2)},
+			wantViolation: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+				"max by":             tc.pods,
+				"histogram_quantile": tc.pleg,
+				"sum by":             {createNodeSample("node-1", 3)},
+			}}
+			g := &kubeletPodDensityGatherer{}
+			summary, err := g.Gather(executor, time.Now().Add(-time.Minute), time.Now(), &measurement.MeasurementConfig{Params: map[string]interface{}{}})
+			if tc.wantViolation {
+				assert.True(t, errors.IsMetricViolationError(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, kubeletPodDensityName, summary.SummaryName())
+		})
+	}
+}
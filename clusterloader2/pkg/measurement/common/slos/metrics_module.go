@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// metricsModule describes performance metrics exported by a specific implementation
+// of a cluster component, e.g. a CNI agent or an ingress controller.
+type metricsModule interface {
+	// Name returns name of the implementation, as passed in the corresponding prometheus-scrape flag.
+	Name() string
+	// Description returns a short description of reported metrics, including ones the implementation
+	// doesn't export.
+	Description() string
+	// Queries returns queries aggregating exported metrics, together with default thresholds.
+	// %v in a query is replaced with the duration of the measurement.
+	Queries() []*genericQuery
+}
+
+// modularGatherer reports metrics of the module selected in the config.
+type modularGatherer struct {
+	name        string
+	description string
+	// moduleLabel is the label of data items containing name of the module.
+	moduleLabel string
+	modules     map[string]metricsModule
+	// capability is the capability of the cluster required to scrape metrics of modules.
+	capability measurement.Capability
+	// selectedModule returns name of the module selected in the config, empty if scraping is disabled.
+	selectedModule func(c *config.ClusterLoaderConfig) string
+}
+
+func (m *modularGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return []measurement.Capability{m.capability, measurement.RealNodes}
+}
+
+// Gather executes queries of the selected module at the end of the measurement.
+// Default thresholds of the module can be overridden with thresholds param, a map from query name
+// to threshold expression (an empty expression disables the threshold).
+func (m *modularGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	name := m.selectedModule(config.ClusterLoaderConfig)
+	module, ok := m.modules[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s %q, supported: %v", m.moduleLabel, name, m.moduleNames())
+	}
+	queries, err := applyThresholdOverrides(module.Queries(), config.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	perfData, violations, err := executeGenericQueries(executor, queries, startTime, endTime, m.name, "v1", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range perfData.DataItems {
+		perfData.DataItems[i].Labels[m.moduleLabel] = name
+	}
+	content, err := util.PrettyPrintJSON(perfData)
+	if err != nil {
+		return nil, err
+	}
+	summary := measurement.CreateSummary(fmt.Sprintf("%s_%s", m.name, name), "json", content)
+	if len(violations) > 0 {
+		err := errors.NewMetricViolationError(fmt.Sprintf("%s performance", name), strings.Join(violations, "; "))
+		logrus.Errorf("%s: %v", m, err)
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (m *modularGatherer) String() string {
+	return m.name
+}
+
+// Description returns a short description of the gatherer, followed by descriptions of its modules.
+func (m *modularGatherer) Description() string {
+	descriptions := []string{m.description}
+	for _, name := range m.moduleNames() {
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", name, m.modules[name].Description()))
+	}
+	return strings.Join(descriptions, " ")
+}
+
+func (m *modularGatherer) moduleNames() []string {
+	var names []string
+	for name := range m.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyThresholdOverrides returns copies of queries with thresholds replaced by the ones
+// provided in thresholds param.
+func applyThresholdOverrides(queries []*genericQuery, params map[string]interface{}) ([]*genericQuery, error) {
+	overrides := make(map[string]interface{})
+	if rawOverrides, ok := params["thresholds"]; ok {
+		if overrides, ok = rawOverrides.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("thresholds param: type assertion error: %v is not a map", rawOverrides)
+		}
+	}
+	known := make(map[string]bool)
+	result := make([]*genericQuery, 0, len(queries))
+	for _, q := range queries {
+		known[q.name] = true
+		query := *q
+		if _, ok := overrides[q.name]; ok {
+			expression, err := util.GetString(overrides, q.name)
+			if err != nil {
+				return nil, fmt.Errorf("thresholds param: %v", err)
+			}
+			query.threshold = nil
+			if expression != "" {
+				if query.threshold, err = parseThreshold(expression); err != nil {
+					return nil, fmt.Errorf("query %s: %v", q.name, err)
+				}
+			}
+		}
+		result = append(result, &query)
+	}
+	for name := range overrides {
+		if !known[name] {
+			return nil, fmt.Errorf("thresholds param: unknown query %s", name)
+		}
+	}
+	return result, nil
+}
+
+// mustParseThreshold parses threshold of a built-in query.
+func mustParseThreshold(expression string) *threshold {
+	t, err := parseThreshold(expression)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+func TestCNIPerformanceGather(t *testing.T) {
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"histogram_quantile(0.99, sum by (node, le) (increase(cilium_endpoint": {
+			{Metric: model.Metric{"node": "node-1"}, Value: 0.8},
+		},
+		"histogram_quantile(0.99, sum by (node, le) (increase(cilium_policy": {},
+		"sum by (node) (increase(cilium_endpoint_regenerations_total": {
+			{Metric: model.Metric{"node": "node-1"}, Value: 2},
+		},
+	}}
+	newConfig := func(cni string, params map[string]interface{}) *measurement.MeasurementConfig {
+		return &measurement.MeasurementConfig{
+			ClusterLoaderConfig: &config.ClusterLoaderConfig{PrometheusConfig: config.PrometheusConfig{ScrapeCNI: cni}},
+			Params:              params,
+		}
+	}
+	g := newCNIPerformanceGatherer()
+
+	summary, err := g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig("cilium", map[string]interface{}{}))
+	assert.True(t, errors.IsMetricViolationError(err))
+	assert.Equal(t, "CNIPerformance_cilium", summary.SummaryName())
+	var data measurementutil.PerfData
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Equal(t, []measurementutil.DataItem{
+		{
+			Data:   map[string]float64{"EndpointRegenerationTimePerc99": 0.8, "FailedEndpointRegenerations": 2},
+			Labels: map[string]string{"Metric": "CNIPerformance", "CNI": "cilium", "node": "node-1"},
+		},
+	}, data.DataItems)
+
+	params := map[string]interface{}{"thresholds": map[string]interface{}{
+		"FailedEndpointRegenerations":    "",
+		"EndpointRegenerationTimePerc99": "<= 1s",
+	}}
+	_, err = g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig("cilium", params))
+	assert.NoError(t, err)
+
+	params = map[string]interface{}{"thresholds": map[string]interface{}{"DataplaneFailures": "== 0"}}
+	_, err = g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig("cilium", params))
+	assert.Error(t, err)
+
+	_, err = g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), newConfig("flannel", nil))
+	assert.Error(t, err)
+}
+
+func TestIngressPerformanceGather(t *testing.T) {
+	executor := &queryMatchingExecutor{samples: map[string][]*model.Sample{
+		"sum(increase(nginx_ingress_controller_success":  {{Value: 12}},
+		"sum(increase(nginx_ingress_controller_errors":   {{Value: 0}},
+		"sum(increase(nginx_ingress_controller_requests": {{Value: 3}},
+		"histogram_quantile(0.99":                        {{Value: 0.05}},
+	}}
+	measurementConfig := &measurement.MeasurementConfig{
+		ClusterLoaderConfig: &config.ClusterLoaderConfig{PrometheusConfig: config.PrometheusConfig{ScrapeIngressController: "nginx"}},
+		Params:              map[string]interface{}{"thresholds": map[string]interface{}{"DroppedRequests": "== 0"}},
+	}
+	g := newIngressPerformanceGatherer()
+	assert.Contains(t, g.(measurement.Describer).Description(), "nginx: Config reloads, failed reloads, dropped requests and request latency of ingress-nginx. Reload latency is not reported")
+	assert.Equal(t, "", measurement.MissingCapability(measurementConfig, g.RequiredCapabilities(measurementConfig)))
+	summary, err := g.Gather(executor, time.Now().Add(-time.Hour), time.Now(), measurementConfig)
+	assert.True(t, errors.IsMetricViolationError(err))
+	assert.Equal(t, "IngressPerformance_nginx", summary.SummaryName())
+	var data measurementutil.PerfData
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Equal(t, []measurementutil.DataItem{
+		{
+			Data:   map[string]float64{"Reloads": 12, "FailedReloads": 0, "DroppedRequests": 3, "RequestLatencyPerc99": 0.05},
+			Labels: map[string]string{"Metric": "IngressPerformance", "IngressController": "nginx"},
+		},
+	}, data.DataItems)
+}
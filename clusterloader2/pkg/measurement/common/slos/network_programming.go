@@ -21,8 +21,10 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
@@ -36,8 +38,26 @@ const (
 	// This measurement assumes, that there is no data points for the rest of the cluster-day.
 	// Definition: https://github.com/kubernetes/community/blob/master/sig-scalability/slos/network_programming_latency.md
 	query = "quantile_over_time(0.99, kubeproxy:kubeproxy_network_programming_duration:histogram_quantile{}[%v])"
+
+	// defaultNetProgThreshold is the SLO threshold of 99th percentile of network programming latency.
+	defaultNetProgThreshold = 30 * time.Second
 )
 
+// netProgRecordingRules are recording rules queried by query.
+var netProgRecordingRules = []prometheus.RecordingRule{
+	newNetProgRecordingRule(0.99),
+	newNetProgRecordingRule(0.9),
+	newNetProgRecordingRule(0.5),
+}
+
+func newNetProgRecordingRule(quantile float64) prometheus.RecordingRule {
+	return prometheus.RecordingRule{
+		Record: "kubeproxy:kubeproxy_network_programming_duration:histogram_quantile",
+		Expr:   fmt.Sprintf("histogram_quantile(%v, sum(rate(kubeproxy_network_programming_duration_seconds_bucket[5m])) by (le))", quantile),
+		Labels: map[string]string{"quantile": fmt.Sprintf("%.2f", quantile)},
+	}
+}
+
 func init() {
 	create := func() measurement.Measurement { return createPrometheusMeasurement(&netProgGatherer{}) }
 	if err := measurement.Register(netProg, create); err != nil {
@@ -47,35 +67,61 @@ func init() {
 
 type netProgGatherer struct{}
 
-func (n *netProgGatherer) IsEnabled(config *measurement.MeasurementConfig) bool {
-	// Disable NetworkProgrammingLatency measurement if scraping kube-proxy is disabled.
-	if !config.ClusterLoaderConfig.PrometheusConfig.ScrapeKubeProxy {
-		return false
-	}
-	return config.CloudProvider != "kubemark"
+func (n *netProgGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return []measurement.Capability{measurement.KubeProxyMetrics, measurement.RealNodes}
+}
+
+// RequiredRecordingRules returns recording rules queried by query.
+func (n *netProgGatherer) RequiredRecordingRules(config *measurement.MeasurementConfig) []prometheus.RecordingRule {
+	return netProgRecordingRules
 }
 
-func (n *netProgGatherer) Gather(executor QueryExecutor, startTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
-	latency, err := n.query(executor, startTime)
+// Gather returns network programming latency. If any percentile exceeds threshold param
+// (SLO threshold of 30s by default) and enableViolations param is set, a metric violation
+// error is returned as well.
+func (n *netProgGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	threshold := defaultNetProgThreshold
+	enableViolations := false
+	if config != nil {
+		var err error
+		if threshold, err = util.GetDurationOrDefault(config.Params, "threshold", defaultNetProgThreshold); err != nil {
+			return nil, err
+		}
+		if enableViolations, err = util.GetBoolOrDefault(config.Params, "enableViolations", false); err != nil {
+			return nil, err
+		}
+	}
+	latency, err := n.query(executor, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
 
-	logrus.Infof("%s: got %v", netProg, latency)
-	return n.createSummary(latency)
+	logrus.Infof("%s: got %v, threshold: %v", netProg, latency, threshold)
+	summary, err := n.createSummary(latency)
+	if err != nil {
+		return nil, err
+	}
+	if err := latency.VerifyThreshold(threshold); err != nil && enableViolations {
+		return summary, errors.NewMetricViolationErrorWithThreshold("network programming latency", err.Error(), latency.Perc99.Seconds(), threshold.Seconds())
+	}
+	return summary, nil
 }
 
 func (n *netProgGatherer) String() string {
 	return netProg
 }
 
-func (n *netProgGatherer) query(executor QueryExecutor, startTime time.Time) (*measurementutil.LatencyMetric, error) {
-	end := time.Now()
-	duration := end.Sub(startTime)
+// Description returns a short description of the gatherer.
+func (n *netProgGatherer) Description() string {
+	return "Measures network programming latency and verifies the network programming SLO."
+}
+
+func (n *netProgGatherer) query(executor QueryExecutor, startTime, endTime time.Time) (*measurementutil.LatencyMetric, error) {
+	duration := endTime.Sub(startTime)
 
 	boundedQuery := fmt.Sprintf(query, measurementutil.ToPrometheusTime(duration))
 
-	samples, err := executor.Query(boundedQuery, end)
+	samples, err := executor.Query(boundedQuery, endTime)
 	if err != nil {
 		return nil, err
 	}
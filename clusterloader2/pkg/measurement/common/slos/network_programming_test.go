@@ -24,18 +24,30 @@ import (
 
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
+	clerrors "k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 )
 
 func TestGather(t *testing.T) {
 	cases := []struct {
-		samples   []*model.Sample
-		err       error
-		wantData  *measurementutil.PerfData
-		wantError error
+		samples       []*model.Sample
+		err           error
+		params        map[string]interface{}
+		wantData      *measurementutil.PerfData
+		wantError     error
+		wantViolation bool
 	}{{
 		samples:  []*model.Sample{createSample("0.9", 200.5), createSample("0.5", 100.5), createSample("0.99", 300.5)},
 		wantData: createPerfData([]float64{100500, 200500, 300500}),
+	}, {
+		samples:  []*model.Sample{createSample("0.9", 2), createSample("0.5", 1), createSample("0.99", 3)},
+		wantData: createPerfData([]float64{1000, 2000, 3000}),
+	}, {
+		samples:       []*model.Sample{createSample("0.9", 20), createSample("0.5", 10), createSample("0.99", 31)},
+		params:        map[string]interface{}{"enableViolations": true},
+		wantData:      createPerfData([]float64{10000, 20000, 31000}),
+		wantViolation: true,
 	}, {
 		samples:   []*model.Sample{{Value: 1}},
 		wantError: errors.New("got unexpected number of samples: 1"),
@@ -46,14 +58,18 @@ func TestGather(t *testing.T) {
 
 	for _, v := range cases {
 		fakeExecutor := &fakeExecutor{samples: v.samples, err: v.err}
-		testGatherer(t, fakeExecutor, v.wantData, v.wantError)
+		config := &measurement.MeasurementConfig{Params: v.params}
+		testGatherer(t, fakeExecutor, config, v.wantData, v.wantError, v.wantViolation)
 	}
 }
 
-func testGatherer(t *testing.T, executor QueryExecutor, wantData *measurementutil.PerfData, wantError error) {
+func testGatherer(t *testing.T, executor QueryExecutor, config *measurement.MeasurementConfig, wantData *measurementutil.PerfData, wantError error, wantViolation bool) {
 	g := &netProgGatherer{}
-	summary, err := g.Gather(executor, time.Now(), nil)
-	if err != nil {
+	summary, err := g.Gather(executor, time.Now().Add(-time.Minute), time.Now(), config)
+	switch {
+	case wantViolation:
+		assert.True(t, clerrors.IsMetricViolationError(err), "got error %v", err)
+	case err != nil:
 		if wantError != nil {
 			assert.Equal(t, wantError, err)
 			return
@@ -84,6 +100,10 @@ func (f *fakeExecutor) Query(query string, queryTime time.Time) ([]*model.Sample
 	return f.samples, nil
 }
 
+func (f *fakeExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	return nil, errors.New("range queries are not supported")
+}
+
 func createSample(p string, l float64) *model.Sample {
 	lset := make(model.LabelSet, 1)
 	lset["quantile"] = model.LabelValue(p)
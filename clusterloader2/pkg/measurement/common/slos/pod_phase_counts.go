@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	podPhaseCountsName = "PodPhaseCounts"
+
+	defaultPodPhaseCountsResolution = time.Minute
+
+	// podPhaseCountsQuery is evaluated as a range query with resolution step.
+	// %v should be replaced with (1) filters and (2) the step size.
+	podPhaseCountsQuery = "max_over_time(sum by (phase) (kube_pod_status_phase{%v})[%v:])"
+)
+
+var podPhases = []string{"Pending", "Running", "Succeeded", "Failed", "Unknown"}
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&podPhaseCountsGatherer{}) }
+	if err := measurement.Register(podPhaseCountsName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", podPhaseCountsName, err)
+	}
+}
+
+type podPhaseCountsGatherer struct{}
+
+type podPhaseCountsSummary struct {
+	// Phases maps pod phase to the maximum number of pods in this phase in each step.
+	Phases map[string][]measurementutil.TimeSeriesPoint `json:"phases"`
+	// MaxPods maps pod phase to the maximum number of pods in this phase during the test.
+	MaxPods map[string]float64 `json:"maxPods"`
+}
+
+func (p *podPhaseCountsGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return []measurement.Capability{measurement.KubeStateMetrics}
+}
+
+// Gather collects the number of pods in each phase over time with given resolution,
+// based on metrics exported by kube-state-metrics. Pods can be restricted to namespaces
+// matching namespace param (a regular expression).
+func (p *podPhaseCountsGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	resolution, err := util.GetDurationOrDefault(config.Params, "resolution", defaultPodPhaseCountsResolution)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := util.GetStringOrDefault(config.Params, "namespace", "")
+	if err != nil {
+		return nil, err
+	}
+	resolution = adjustResolution(endTime.Sub(startTime), resolution)
+
+	filters := ""
+	if namespace != "" {
+		filters = fmt.Sprintf("namespace=~%q", namespace)
+	}
+	query := fmt.Sprintf(podPhaseCountsQuery, filters, measurementutil.ToPrometheusTime(resolution))
+	streams, err := executor.QueryRange(query, startTime, endTime, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &podPhaseCountsSummary{
+		Phases:  make(map[string][]measurementutil.TimeSeriesPoint),
+		MaxPods: make(map[string]float64),
+	}
+	for _, phase := range podPhases {
+		summary.Phases[phase] = []measurementutil.TimeSeriesPoint{}
+		summary.MaxPods[phase] = 0
+	}
+	for _, series := range measurementutil.NewTimeSeries(streams) {
+		phase := series.Labels["phase"]
+		summary.Phases[phase] = series.Points
+		for _, point := range series.Points {
+			if point.Value > summary.MaxPods[phase] {
+				summary.MaxPods[phase] = point.Value
+			}
+		}
+	}
+	logrus.Infof("%s: max pods in each phase: %v", p, summary.MaxPods)
+
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(podPhaseCountsName, "json", content), nil
+}
+
+func (p *podPhaseCountsGatherer) String() string {
+	return podPhaseCountsName
+}
+
+// Description returns a short description of the gatherer.
+func (p *podPhaseCountsGatherer) Description() string {
+	return "Reports the number of pods in each phase over time."
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestPodPhaseCountsGather(t *testing.T) {
+	start := time.Now().Add(-3 * time.Minute)
+	executor := &queryMatchingExecutor{
+		streams: map[string][]*model.SampleStream{
+			"max_over_time(sum by (phase) (kube_pod_status_phase{namespace=~\"test-.*\"})": {
+				createSampleStream(model.Metric{"phase": "Pending"}, start, 10, 3, 0),
+				createSampleStream(model.Metric{"phase": "Running"}, start, 0, 7, 10),
+			},
+		},
+	}
+	g := &podPhaseCountsGatherer{}
+	config := &measurement.MeasurementConfig{Params: map[string]interface{}{"namespace": "test-.*"}}
+	summary, err := g.Gather(executor, start, time.Now(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var data podPhaseCountsSummary
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Len(t, data.Phases["Pending"], 3)
+	assert.Len(t, data.Phases["Failed"], 0)
+	assert.Equal(t, map[string]float64{"Pending": 10, "Running": 10, "Succeeded": 0, "Failed": 0, "Unknown": 0}, data.MaxPods)
+}
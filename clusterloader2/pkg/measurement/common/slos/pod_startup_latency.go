@@ -26,6 +26,7 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	"github.com/sirupsen/logrus"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/informer"
@@ -85,13 +86,22 @@ func (p *podStartupLatencyMeasurement) Execute(config *measurement.MeasurementCo
 		}
 		return nil, p.start(config.ClusterFramework.GetClientSets().GetClient())
 	case "gather":
-		return p.gather(config.ClusterFramework.GetClientSets().GetClient(), config.Identifier)
+		return p.gather(config.ClusterFramework.GetClientSets().GetClient(), config.ClusterFramework.GetEventCollector(), config.Identifier)
 	default:
 		return nil, fmt.Errorf("unknown action %v", action)
 	}
 
 }
 
+// DeclaredParams returns params accepted by the measurement.
+func (p *podStartupLatencyMeasurement) DeclaredParams() []measurement.Param {
+	params := []measurement.Param{
+		measurement.ActionParam("start", "gather"),
+		{Name: "threshold", Type: measurement.DurationParam, Actions: []string{"start"}, Default: defaultPodStartupLatencyThreshold.String(), Description: "SLO threshold of pod startup latency"},
+	}
+	return append(params, measurement.SelectorParams("start")...)
+}
+
 // Dispose cleans up after the measurement.
 func (p *podStartupLatencyMeasurement) Dispose() {
 	p.stop()
@@ -102,6 +112,11 @@ func (p *podStartupLatencyMeasurement) String() string {
 	return podStartupLatencyMeasurementName + ": " + p.selector.String()
 }
 
+// Description returns a short description of the measurement.
+func (p *podStartupLatencyMeasurement) Description() string {
+	return "Measures pod startup latency and verifies the pod startup SLO."
+}
+
 func (p *podStartupLatencyMeasurement) start(c clientset.Interface) error {
 	if p.isRunning {
 		logrus.Infof("%s: pod startup latancy measurement already running", p)
@@ -126,7 +141,7 @@ func (p *podStartupLatencyMeasurement) stop() {
 	}
 }
 
-func (p *podStartupLatencyMeasurement) gather(c clientset.Interface, identifier string) ([]measurement.Summary, error) {
+func (p *podStartupLatencyMeasurement) gather(c clientset.Interface, collector *framework.EventCollector, identifier string) ([]measurement.Summary, error) {
 	logrus.Infof("%s: gathering pod startup latency measurement...", p)
 	if !p.isRunning {
 		return nil, fmt.Errorf("metric %s has not been started", podStartupLatencyMeasurementName)
@@ -134,7 +149,7 @@ func (p *podStartupLatencyMeasurement) gather(c clientset.Interface, identifier
 
 	p.stop()
 
-	if err := p.gatherScheduleTimes(c); err != nil {
+	if err := p.gatherScheduleTimes(c, collector); err != nil {
 		return nil, err
 	}
 
@@ -164,7 +179,7 @@ func (p *podStartupLatencyMeasurement) gather(c clientset.Interface, identifier
 
 	var err error
 	if slosErr := podStartupLatency["pod_startup"].VerifyThreshold(p.threshold); slosErr != nil {
-		err = errors.NewMetricViolationError("pod startup", slosErr.Error())
+		err = errors.NewMetricViolationErrorWithThreshold("pod startup", slosErr.Error(), podStartupLatency["pod_startup"].Perc99.Seconds(), p.threshold.Seconds())
 		logrus.Errorf("%s: %v", p, err)
 	}
 
@@ -176,17 +191,31 @@ func (p *podStartupLatencyMeasurement) gather(c clientset.Interface, identifier
 	return []measurement.Summary{summary}, err
 }
 
-func (p *podStartupLatencyMeasurement) gatherScheduleTimes(c clientset.Interface) error {
-	selector := fields.Set{
-		"involvedObject.kind": "Pod",
-		"source":              corev1.DefaultSchedulerName,
-	}.AsSelector().String()
-	options := metav1.ListOptions{FieldSelector: selector}
-	schedEvents, err := c.CoreV1().Events(p.selector.Namespace).List(options)
-	if err != nil {
-		return err
+// gatherScheduleTimes sets schedule times of pods based on events of the scheduler. Events are taken
+// from the events collector of the test if it collects them, as listed events may have already expired.
+func (p *podStartupLatencyMeasurement) gatherScheduleTimes(c clientset.Interface, collector *framework.EventCollector) error {
+	var schedEvents []*corev1.Event
+	if collector != nil && collector.Covers(p.selector.Namespace) {
+		schedEvents = collector.Query(framework.EventQuery{
+			Namespace:          p.selector.Namespace,
+			InvolvedObjectKind: "Pod",
+			Source:             corev1.DefaultSchedulerName,
+		})
+	} else {
+		selector := fields.Set{
+			"involvedObject.kind": "Pod",
+			"source":              corev1.DefaultSchedulerName,
+		}.AsSelector().String()
+		options := metav1.ListOptions{FieldSelector: selector}
+		eventList, err := c.CoreV1().Events(p.selector.Namespace).List(options)
+		if err != nil {
+			return err
+		}
+		for i := range eventList.Items {
+			schedEvents = append(schedEvents, &eventList.Items[i])
+		}
 	}
-	for _, event := range schedEvents.Items {
+	for _, event := range schedEvents {
 		key := createMetaNamespaceKey(event.InvolvedObject.Namespace, event.InvolvedObject.Name)
 		if _, exists := p.podStartupEntries.Get(key, createPhase); exists {
 			if !event.EventTime.IsZero() {
@@ -17,14 +17,18 @@ limitations under the License.
 package slos
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/prometheus/common/model"
 	"github.com/sirupsen/logrus"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
@@ -37,34 +41,73 @@ func createPrometheusMeasurement(gatherer Gatherer) measurement.Measurement {
 // QueryExecutor is an interface for queryning Prometheus server.
 type QueryExecutor interface {
 	Query(query string, queryTime time.Time) ([]*model.Sample, error)
+	QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error)
 }
 
 // Gatherer is an interface for measurements based on Prometheus metrics. Those measurments don't require any preparation.
 // It's assumed Prometheus is up, running and instructed to scrape required metrics in the test cluster
 // (please see clusterloader2/pkg/prometheus/manifests).
 type Gatherer interface {
-	Gather(executor QueryExecutor, startTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error)
-	IsEnabled(config *measurement.MeasurementConfig) bool
+	// Gather evaluates metrics in time window between startTime and endTime.
+	Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error)
+	// RequiredCapabilities returns capabilities required by the gatherer, besides the prometheus server.
+	RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability
 	String() string
 }
 
+// DirectGatherer is implemented by gatherers that can fall back to scraping metrics of components
+// directly when the prometheus server is not available. As metrics are scraped only at the start
+// and at the end of the measurement, markers and periodic evaluation are not supported.
+type DirectGatherer interface {
+	StartDirect(f *framework.Framework) error
+	GatherDirect(f *framework.Framework, config *measurement.MeasurementConfig) (measurement.Summary, error)
+}
+
 type prometheusMeasurement struct {
 	gatherer Gatherer
 
 	startTime time.Time
+	// stopCh stops periodic evaluation, if it's running.
+	stopCh chan struct{}
 }
 
-func (m *prometheusMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
-	if config.PrometheusFramework == nil {
-		logrus.Warningf("%s: Prometheus is disabled, skipping the measurement!", m)
-		return nil, nil
+// RequiredCapabilities returns capabilities required by the measurement.
+func (m *prometheusMeasurement) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	if m.useDirectGatherer(config) {
+		return m.gatherer.RequiredCapabilities(config)
 	}
+	return append([]measurement.Capability{measurement.Prometheus}, m.gatherer.RequiredCapabilities(config)...)
+}
 
-	if !m.gatherer.IsEnabled(config) {
-		logrus.Warningf("%s: disabled, skipping the measuerment!", m)
-		return nil, nil
+// RequiredRecordingRules returns recording rules queried by the gatherer. Rules are required only
+// by calls querying the prometheus server, i.e. gathering results or starting periodic evaluation.
+func (m *prometheusMeasurement) RequiredRecordingRules(config *measurement.MeasurementConfig) []prometheus.RecordingRule {
+	requirer, ok := m.gatherer.(measurement.RecordingRuleRequirer)
+	if !ok || m.useDirectGatherer(config) {
+		return nil
+	}
+	action, _ := util.GetStringOrDefault(config.Params, "action", "")
+	interval, _ := util.GetDurationOrDefault(config.Params, "evaluationInterval", 0)
+	if action != "gather" && interval <= 0 {
+		return nil
 	}
+	return requirer.RequiredRecordingRules(config)
+}
+
+// useDirectGatherer returns true if the prometheus server is not available,
+// but the gatherer can scrape metrics directly.
+func (m *prometheusMeasurement) useDirectGatherer(config *measurement.MeasurementConfig) bool {
+	_, ok := m.gatherer.(DirectGatherer)
+	return ok && config.PrometheusFramework == nil
+}
 
+// IsResumable returns true unless metrics are scraped directly, as Prometheus keeps data
+// of the interrupted run and the start time of the interrupted run is used.
+func (m *prometheusMeasurement) IsResumable(config *measurement.MeasurementConfig) bool {
+	return !m.useDirectGatherer(config)
+}
+
+func (m *prometheusMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
 	action, err := util.GetString(config.Params, "action")
 	if err != nil {
 		return nil, err
@@ -73,19 +116,20 @@ func (m *prometheusMeasurement) Execute(config *measurement.MeasurementConfig) (
 	switch action {
 	case "start":
 		logrus.Infof("%s has started", m)
-		m.startTime = time.Now()
-		return nil, nil
+		m.startTime = config.GetStartTime()
+		if m.useDirectGatherer(config) {
+			logrus.Warningf("%s: prometheus server is not available, scraping metrics directly", m)
+			return nil, m.gatherer.(DirectGatherer).StartDirect(config.ClusterFramework)
+		}
+		return nil, m.startEvaluation(config)
 	case "gather":
+		m.stopEvaluation()
 		logrus.Infof("%s gathering results", m)
 		enableViolations, err := util.GetBoolOrDefault(config.Params, "enableViolations", false)
 		if err != nil {
 			return nil, err
 		}
-
-		c := config.PrometheusFramework.GetClientSets().GetClient()
-		executor := measurementutil.NewQueryExecutor(c)
-
-		summary, err := m.gatherer.Gather(executor, m.startTime, config)
+		summary, err := m.gather(config)
 		if err != nil {
 			if !errors.IsMetricViolationError(err) {
 				return nil, err
@@ -100,8 +144,272 @@ func (m *prometheusMeasurement) Execute(config *measurement.MeasurementConfig) (
 	}
 }
 
-func (m *prometheusMeasurement) Dispose() {}
+func (m *prometheusMeasurement) gather(config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	if m.useDirectGatherer(config) {
+		return m.gatherer.(DirectGatherer).GatherDirect(config.ClusterFramework, config)
+	}
+	startTime, endTime, err := measurement.GetWindow(config, m.startTime, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	executor, finish, err := m.createExecutor(config.GetContext(), config)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := m.gatherer.Gather(executor, startTime, endTime, config)
+	return finish(summary), err
+}
+
+// Checkpoint evaluates the measurement since its start, without stopping periodic evaluation.
+func (m *prometheusMeasurement) Checkpoint(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	if m.useDirectGatherer(config) {
+		return nil, fmt.Errorf("%s: checkpoints are not supported when scraping metrics directly", m)
+	}
+	executor, finish, err := m.createExecutor(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := m.gatherer.Gather(executor, m.startTime, time.Now(), config)
+	return []measurement.Summary{finish(summary)}, err
+}
+
+// createExecutor creates query executor according to params. Queries fail once ctx is done
+// (with allowPartialResults param, results of such queries are empty). Returned function
+// should be called with the summary computed once all queries are executed, it marks
+// the summary as partial if any query failed.
+func (m *prometheusMeasurement) createExecutor(ctx context.Context, config *measurement.MeasurementConfig) (QueryExecutor, func(measurement.Summary) measurement.Summary, error) {
+	var err error
+	retryPolicy := measurementutil.DefaultQueryRetryPolicy
+	if retryPolicy.Retries, err = util.GetIntOrDefault(config.Params, "queryRetries", retryPolicy.Retries); err != nil {
+		return nil, nil, err
+	}
+	allowPartialResults, err := util.GetBoolOrDefault(config.Params, "allowPartialResults", false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := config.PrometheusFramework.GetClientSets().GetClient()
+	batchExecutor, err := config.QueryExecutors.Get(c, config.GetPrometheusConfig(), retryPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+	var executor QueryExecutor = &contextExecutor{ctx: ctx, executor: batchExecutor}
+	if !allowPartialResults {
+		return executor, func(summary measurement.Summary) measurement.Summary { return summary }, nil
+	}
+	partialExecutor := &partialResultsExecutor{executor: executor}
+	return partialExecutor, func(summary measurement.Summary) measurement.Summary {
+		return partialExecutor.finish(m.String(), summary)
+	}, nil
+}
+
+// startEvaluation starts periodic evaluation of the measurement during the test,
+// if evaluationInterval param is set. Every evaluation covers the time since the start
+// of the measurement or, if evaluationWindow param is set, the last evaluationWindow.
+// Violations are logged as warnings or, if failFast param is set, fail the test.
+// Notifiers are notified about the first violation detected by the evaluation.
+func (m *prometheusMeasurement) startEvaluation(config *measurement.MeasurementConfig) error {
+	interval, err := util.GetDurationOrDefault(config.Params, "evaluationInterval", 0)
+	if err != nil || interval <= 0 {
+		return err
+	}
+	window, err := util.GetDurationOrDefault(config.Params, "evaluationWindow", 0)
+	if err != nil {
+		return err
+	}
+	failFast, err := util.GetBoolOrDefault(config.Params, "failFast", false)
+	if err != nil {
+		return err
+	}
+	// Evaluation outlives the start call, so it doesn't use its context.
+	executor, finish, err := m.createExecutor(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	m.stopEvaluation()
+	m.stopCh = make(chan struct{})
+	logrus.Infof("%s: evaluating every %v", m, interval)
+	go func(stopCh chan struct{}) {
+		defer finish(nil)
+		m.evaluate(executor, config, interval, window, failFast, stopCh)
+	}(m.stopCh)
+	return nil
+}
+
+func (m *prometheusMeasurement) evaluate(executor QueryExecutor, config *measurement.MeasurementConfig, interval, window time.Duration, failFast bool, stopCh chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	notified := false
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			startTime := m.startTime
+			if window > 0 && now.Add(-window).After(startTime) {
+				startTime = now.Add(-window)
+			}
+			_, err := m.gatherer.Gather(executor, startTime, now, config)
+			if err == nil {
+				continue
+			}
+			if !errors.IsMetricViolationError(err) {
+				logrus.Errorf("%s: evaluation error: %v", m, err)
+				continue
+			}
+			if !notified && config.NotifyViolation != nil {
+				config.NotifyViolation(err)
+				notified = true
+			}
+			if !failFast {
+				logrus.Warningf("%s: SLO violated during the test: %v", m, err)
+				continue
+			}
+			logrus.Errorf("%s: SLO violated during the test, failing fast: %v", m, err)
+			if config.FailTest != nil {
+				config.FailTest(fmt.Errorf("%s: %v", m, err))
+			}
+			return
+		}
+	}
+}
+
+func (m *prometheusMeasurement) stopEvaluation() {
+	if m.stopCh != nil {
+		close(m.stopCh)
+		m.stopCh = nil
+	}
+}
+
+// DeclaredParams returns params accepted by the measurement, nil if the gatherer doesn't declare its params.
+func (m *prometheusMeasurement) DeclaredParams() []measurement.Param {
+	declarer, ok := m.gatherer.(measurement.ParamsDeclarer)
+	if !ok {
+		return nil
+	}
+	gathererParams := declarer.DeclaredParams()
+	if gathererParams == nil {
+		return nil
+	}
+	start := []string{"start"}
+	gather := []string{"gather"}
+	params := []measurement.Param{
+		measurement.ActionParam("start", "gather"),
+		{Name: "enableViolations", Type: measurement.BoolParam, Actions: gather, Default: false, Description: "whether SLO violations fail the test"},
+		{Name: "queryRetries", Type: measurement.IntParam, Description: "number of retries of failed Prometheus queries"},
+		{Name: "allowPartialResults", Type: measurement.BoolParam, Default: false, Description: "whether partial results of queries are accepted"},
+		{Name: "evaluationInterval", Type: measurement.DurationParam, Actions: start, Description: "interval of evaluating the SLO during the test, disabled if zero"},
+		{Name: "evaluationWindow", Type: measurement.DurationParam, Actions: start, Description: "window of periodic evaluation, since the start if zero"},
+		{Name: "failFast", Type: measurement.BoolParam, Actions: start, Default: false, Description: "whether violation detected by periodic evaluation fails the test immediately"},
+		{Name: "startMarker", Type: measurement.StringParam, Actions: gather, Description: "marker the evaluated window starts at, the start of the measurement by default"},
+		{Name: "endMarker", Type: measurement.StringParam, Actions: gather, Description: "marker the evaluated window ends at, the gather call by default"},
+	}
+	return append(params, gathererParams...)
+}
+
+func (m *prometheusMeasurement) Dispose() {
+	m.stopEvaluation()
+}
+
+// Description returns description of the gatherer, if it has one.
+func (m *prometheusMeasurement) Description() string {
+	if describer, ok := m.gatherer.(measurement.Describer); ok {
+		return describer.Description()
+	}
+	return ""
+}
 
 func (m *prometheusMeasurement) String() string {
 	return m.gatherer.String()
 }
+
+// IsPrometheusMeasurement returns true if the measurement registered under given method
+// is based on Prometheus metrics and can be backfilled.
+func IsPrometheusMeasurement(method string) bool {
+	m, err := measurement.CreateMeasurement(method)
+	if err != nil {
+		return false
+	}
+	_, ok := m.(*prometheusMeasurement)
+	return ok
+}
+
+// Backfill evaluates Prometheus-based measurement registered under given method in time window
+// between startTime and endTime, or between markers given in params, using provided executor.
+// It allows regenerating summaries from Prometheus data (e.g. a restored snapshot) after the test.
+func Backfill(method string, executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	m, err := measurement.CreateMeasurement(method)
+	if err != nil {
+		return nil, err
+	}
+	pm, ok := m.(*prometheusMeasurement)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a Prometheus-based measurement", method)
+	}
+	startTime, endTime, err = measurement.GetWindow(config, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s: backfilling from %v to %v", pm, startTime, endTime)
+	return pm.gatherer.Gather(executor, startTime, endTime, config)
+}
+
+// contextExecutor executes queries with the context, so that they are cancelled once it's done,
+// e.g. when the call of the measurement timed out.
+type contextExecutor struct {
+	ctx      context.Context
+	executor *measurementutil.BatchQueryExecutor
+}
+
+func (c *contextExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	return c.executor.QueryContext(c.ctx, query, queryTime)
+}
+
+func (c *contextExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	return c.executor.QueryRangeContext(c.ctx, query, start, end, step)
+}
+
+// partialResultsExecutor returns empty results of queries that failed despite retries,
+// so that a single flaky query doesn't fail the whole measurement.
+type partialResultsExecutor struct {
+	executor QueryExecutor
+
+	lock          sync.Mutex
+	failedQueries []string
+}
+
+func (p *partialResultsExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	samples, err := p.executor.Query(query, queryTime)
+	if err != nil {
+		p.recordFailure(query, err)
+		return nil, nil
+	}
+	return samples, nil
+}
+
+func (p *partialResultsExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	streams, err := p.executor.QueryRange(query, start, end, step)
+	if err != nil {
+		p.recordFailure(query, err)
+		return nil, nil
+	}
+	return streams, nil
+}
+
+func (p *partialResultsExecutor) recordFailure(query string, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	logrus.Warningf("Ignoring failed query %q: %v", query, err)
+	p.failedQueries = append(p.failedQueries, query)
+}
+
+// finish logs failed queries and marks the summary computed from their results as partial, if any query failed.
+func (p *partialResultsExecutor) finish(name string, summary measurement.Summary) measurement.Summary {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.failedQueries) == 0 {
+		return summary
+	}
+	logrus.Warningf("%s: %d queries failed, results are partial", name, len(p.failedQueries))
+	return measurement.PartialSummary(summary)
+}
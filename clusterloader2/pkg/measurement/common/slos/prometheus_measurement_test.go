@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestPartialResultsExecutor(t *testing.T) {
+	summary := measurement.CreateSummary("APIResponsiveness", "json", "{}")
+	executor := &partialResultsExecutor{executor: &fakeExecutor{}}
+	_, err := executor.Query("up", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "APIResponsiveness", executor.finish("Test", summary).SummaryName())
+
+	executor = &partialResultsExecutor{executor: &fakeExecutor{err: fmt.Errorf("timeout")}}
+	samples, err := executor.Query("up", time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 0)
+	assert.Equal(t, []string{"up"}, executor.failedQueries)
+	assert.Equal(t, "APIResponsivenessPartial", executor.finish("Test", summary).SummaryName())
+	assert.Nil(t, executor.finish("Test", nil))
+}
+
+type violatingGatherer struct {
+	lock       sync.Mutex
+	startTimes []time.Time
+}
+
+func (v *violatingGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.startTimes = append(v.startTimes, startTime)
+	if len(v.startTimes) < 2 {
+		return nil, nil
+	}
+	return nil, errors.NewMetricViolationError("latency", "too high")
+}
+
+func (v *violatingGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return nil
+}
+
+func (v *violatingGatherer) String() string {
+	return "Violating"
+}
+
+func TestPeriodicEvaluationFailFast(t *testing.T) {
+	gatherer := &violatingGatherer{}
+	m := &prometheusMeasurement{gatherer: gatherer, startTime: time.Now().Add(-time.Hour)}
+	failures := make(chan error, 1)
+	config := &measurement.MeasurementConfig{FailTest: func(err error) { failures <- err }}
+
+	done := make(chan struct{})
+	go func() {
+		m.evaluate(&fakeExecutor{}, config, time.Millisecond, time.Minute, true, make(chan struct{}))
+		close(done)
+	}()
+	select {
+	case err := <-failures:
+		assert.Equal(t, "Violating: latency: too high", err.Error())
+	case <-time.After(10 * time.Second):
+		t.Fatalf("violation wasn't reported")
+	}
+	<-done
+
+	gatherer.lock.Lock()
+	defer gatherer.lock.Unlock()
+	assert.Len(t, gatherer.startTimes, 2)
+	for _, startTime := range gatherer.startTimes {
+		assert.True(t, startTime.After(m.startTime), "evaluation window should be limited to a minute")
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	gatherer := &violatingGatherer{}
+	if err := measurement.Register("BackfillTest", func() measurement.Measurement { return createPrometheusMeasurement(gatherer) }); err != nil {
+		t.Fatalf("registering measurement error: %v", err)
+	}
+	assert.True(t, IsPrometheusMeasurement("BackfillTest"))
+	assert.False(t, IsPrometheusMeasurement("Unknown"))
+
+	start := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	markers := measurement.NewMarkers()
+	assert.NoError(t, markers.Add("scale-up", start.Add(10*time.Minute)))
+	config := &measurement.MeasurementConfig{
+		Params:  map[string]interface{}{"startMarker": "scale-up"},
+		Markers: markers,
+	}
+	_, err := Backfill("BackfillTest", &fakeExecutor{}, start, start.Add(time.Hour), config)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start.Add(10 * time.Minute)}, gatherer.startTimes)
+
+	_, err = Backfill("BackfillTest", &fakeExecutor{}, start, start.Add(time.Hour), &measurement.MeasurementConfig{})
+	assert.True(t, errors.IsMetricViolationError(err))
+	_, err = Backfill("BackfillTest", &fakeExecutor{}, start, start, &measurement.MeasurementConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	schedulerQueueMetricsName = "SchedulerQueueMetrics"
+
+	defaultSchedulerQueueResolution = time.Minute
+	// maxSchedulerQueuePoints bounds the number of points of a single time series.
+	maxSchedulerQueuePoints = 240
+
+	// Queries below are evaluated as range queries with resolution step.
+	// %v should be replaced with the step size.
+	pendingPodsQuery        = "max by (queue) (max_over_time(scheduler_pending_pods[%v]))"
+	preemptionAttemptsQuery = "sum(increase(scheduler_total_preemption_attempts[%v]))"
+
+	// Queries below aggregate metrics over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	totalPreemptionAttemptsQuery = "sum(increase(scheduler_total_preemption_attempts[%v]))"
+	scorePluginLatencyQuery      = "histogram_quantile(%v, sum by (le) (rate(scheduler_framework_extension_point_duration_seconds_bucket{extension_point=\"Score\"}[%v])))"
+)
+
+var schedulingQueues = []string{"active", "backoff", "unschedulable"}
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&schedulerQueueGatherer{}) }
+	if err := measurement.Register(schedulerQueueMetricsName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", schedulerQueueMetricsName, err)
+	}
+}
+
+type schedulerQueueGatherer struct{}
+
+type schedulerQueueSummary struct {
+	// PendingPods maps scheduling queue name to the maximum number of pending pods in each step.
+	PendingPods map[string][]measurementutil.TimeSeriesPoint `json:"pendingPods"`
+	// PreemptionAttempts contains the number of preemption attempts in each step.
+	PreemptionAttempts      []measurementutil.TimeSeriesPoint `json:"preemptionAttempts"`
+	TotalPreemptionAttempts float64                           `json:"totalPreemptionAttempts"`
+	ScorePluginLatency      *measurementutil.LatencyMetric    `json:"scorePluginLatency"`
+}
+
+func (s *schedulerQueueGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return nil
+}
+
+// Gather collects scheduling queue sizes and preemption attempts over time
+// with given resolution, together with latency of score plugins over the whole test.
+func (s *schedulerQueueGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	resolution, err := util.GetDurationOrDefault(config.Params, "resolution", defaultSchedulerQueueResolution)
+	if err != nil {
+		return nil, err
+	}
+	resolution = adjustResolution(endTime.Sub(startTime), resolution)
+
+	summary := &schedulerQueueSummary{
+		PendingPods:        make(map[string][]measurementutil.TimeSeriesPoint),
+		PreemptionAttempts: []measurementutil.TimeSeriesPoint{},
+	}
+	for _, queue := range schedulingQueues {
+		summary.PendingPods[queue] = []measurementutil.TimeSeriesPoint{}
+	}
+	step := measurementutil.ToPrometheusTime(resolution)
+	streams, err := executor.QueryRange(fmt.Sprintf(pendingPodsQuery, step), startTime, endTime, resolution)
+	if err != nil {
+		return nil, err
+	}
+	for _, series := range measurementutil.NewTimeSeries(streams) {
+		summary.PendingPods[series.Labels["queue"]] = series.Points
+	}
+	streams, err = executor.QueryRange(fmt.Sprintf(preemptionAttemptsQuery, step), startTime, endTime, resolution)
+	if err != nil {
+		return nil, err
+	}
+	if series := measurementutil.NewTimeSeries(streams); len(series) > 0 {
+		summary.PreemptionAttempts = series[0].Points
+	}
+
+	window := measurementutil.ToPrometheusTime(endTime.Sub(startTime))
+	samples, err := executor.Query(fmt.Sprintf(totalPreemptionAttemptsQuery, window), endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) > 0 {
+		summary.TotalPreemptionAttempts = float64(samples[0].Value)
+	}
+	summary.ScorePluginLatency = &measurementutil.LatencyMetric{}
+	for _, quantile := range []float64{0.5, 0.9, 0.99} {
+		samples, err := executor.Query(fmt.Sprintf(scorePluginLatencyQuery, quantile, window), endTime)
+		if err != nil {
+			return nil, err
+		}
+		if len(samples) > 0 {
+			summary.ScorePluginLatency.SetQuantile(quantile, time.Duration(float64(samples[0].Value)*float64(time.Second)))
+		}
+	}
+	logrus.Infof("%s: %v preemption attempts, score plugins latency: %v", s, summary.TotalPreemptionAttempts, summary.ScorePluginLatency)
+
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(schedulerQueueMetricsName, "json", content), nil
+}
+
+func (s *schedulerQueueGatherer) String() string {
+	return schedulerQueueMetricsName
+}
+
+// Description returns a short description of the gatherer.
+func (s *schedulerQueueGatherer) Description() string {
+	return "Reports scheduling queue sizes, preemption attempts and latency of score plugins."
+}
+
+// adjustResolution increases resolution, so that the time series
+// for given duration has at most maxSchedulerQueuePoints points.
+// Resolution is rounded up to full seconds, or full minutes if it exceeds one minute,
+// as this is the precision of Prometheus time representation.
+func adjustResolution(duration, resolution time.Duration) time.Duration {
+	if minResolution := duration / maxSchedulerQueuePoints; resolution < minResolution {
+		resolution = minResolution
+	}
+	unit := time.Second
+	if resolution > time.Minute {
+		unit = time.Minute
+	}
+	if resolution < unit {
+		return unit
+	}
+	return ((resolution + unit - 1) / unit) * unit
+}
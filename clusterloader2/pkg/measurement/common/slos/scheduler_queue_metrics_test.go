@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func createSampleStream(metric model.Metric, start time.Time, values ...float64) *model.SampleStream {
+	stream := &model.SampleStream{Metric: metric}
+	for i, value := range values {
+		stream.Values = append(stream.Values, model.SamplePair{
+			Timestamp: model.TimeFromUnixNano(start.Add(time.Duration(i) * time.Minute).UnixNano()),
+			Value:     model.SampleValue(value),
+		})
+	}
+	return stream
+}
+
+func TestSchedulerQueueGather(t *testing.T) {
+	start := time.Now().Add(-3 * time.Minute)
+	executor := &queryMatchingExecutor{
+		samples: map[string][]*model.Sample{
+			"sum(increase":       {{Value: 5}},
+			"histogram_quantile": {{Value: 0.01}},
+		},
+		streams: map[string][]*model.SampleStream{
+			"max by (queue)": {
+				createSampleStream(model.Metric{"queue": "active"}, start, 10, 20, 5),
+				createSampleStream(model.Metric{"queue": "unschedulable"}, start, 2, 2, 0),
+			},
+			"sum(increase": {createSampleStream(model.Metric{}, start, 0, 5, 0)},
+		},
+	}
+	g := &schedulerQueueGatherer{}
+	summary, err := g.Gather(executor, start, time.Now(), &measurement.MeasurementConfig{Params: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var data schedulerQueueSummary
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Len(t, data.PendingPods["active"], 3)
+	assert.Equal(t, 20.0, data.PendingPods["active"][1].Value)
+	assert.Len(t, data.PendingPods["backoff"], 0)
+	assert.Len(t, data.PendingPods["unschedulable"], 3)
+	assert.Len(t, data.PreemptionAttempts, 3)
+	assert.Equal(t, 5.0, data.TotalPreemptionAttempts)
+	assert.Equal(t, 10*time.Millisecond, data.ScorePluginLatency.Perc99)
+}
+
+func TestAdjustResolution(t *testing.T) {
+	cases := []struct {
+		duration   time.Duration
+		resolution time.Duration
+		want       time.Duration
+	}{
+		{duration: time.Hour, resolution: time.Minute, want: time.Minute},
+		{duration: time.Hour, resolution: 0, want: 15 * time.Second},
+		{duration: time.Hour, resolution: 1500 * time.Millisecond, want: 15 * time.Second},
+		{duration: 10 * time.Hour, resolution: time.Minute, want: 3 * time.Minute},
+		{duration: 5 * time.Minute, resolution: 90 * time.Second, want: 2 * time.Minute},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, adjustResolution(tc.duration, tc.resolution))
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	secretVolumeLoadName = "SecretVolumeLoad"
+
+	secretVolumeFilters = `resource=~"secrets|configmaps", verb=~"GET|LIST|WATCH|WATCHLIST"`
+
+	// Queries below aggregate metrics over the whole test.
+	// %v should be replaced with (1) filters and (2) query window size (duration of the test).
+	secretRequestCountQuery   = "sum by (resource, verb) (increase(apiserver_request_total{%v}[%v]))"
+	secretMaxRequestRateQuery = "max_over_time(sum by (resource, verb) (rate(apiserver_request_total{%v}[1m]))[%v:1m])"
+	// secretLatencyQuery: %v should be replaced with (1) quantile (2) filters and (3) query window size.
+	secretLatencyQuery = "histogram_quantile(%.2f, sum by (resource, verb, le) (rate(apiserver_request_duration_seconds_bucket{%v, verb!~\"WATCH|WATCHLIST\"}[%v])))"
+	// secretMaxWatchersQuery: %v should be replaced with query window size.
+	secretMaxWatchersQuery = "max_over_time(sum by (kind) (apiserver_registered_watchers{kind=~\"Secret|ConfigMap\"})[%v:1m])"
+)
+
+// watchedKinds maps kinds reported by apiserver_registered_watchers to resources.
+var watchedKinds = map[string]string{"Secret": "secrets", "ConfigMap": "configmaps"}
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&secretVolumeLoadGatherer{}) }
+	if err := measurement.Register(secretVolumeLoadName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", secretVolumeLoadName, err)
+	}
+}
+
+type secretVolumeLoadGatherer struct{}
+
+// resourceLoad describes load generated on the apiserver by kubelets fetching and watching
+// secrets or configmaps mounted as pod volumes.
+type resourceLoad struct {
+	// Requests maps verb to the number of requests during the test.
+	Requests map[string]float64 `json:"requests"`
+	// MaxRequestRate maps verb to the maximum number of requests per second (in 1m windows).
+	MaxRequestRate map[string]float64 `json:"maxRequestRate"`
+	// Latency maps verb (GET or LIST) to request latency.
+	Latency     map[string]*measurementutil.LatencyMetric `json:"latency"`
+	MaxWatchers float64                                   `json:"maxWatchers"`
+}
+
+type secretVolumeLoadSummary struct {
+	// Resources maps resource (secrets or configmaps) to its load.
+	Resources map[string]*resourceLoad `json:"resources"`
+}
+
+func (s *secretVolumeLoadGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return nil
+}
+
+// Gather reports the number, maximum rate and latency of requests for secrets and configmaps
+// together with the number of registered watchers. If maxListRate param is set and the rate
+// of LIST requests for any of the resources exceeds it, a metric violation error is returned.
+func (s *secretVolumeLoadGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	maxListRate, err := util.GetFloat64OrDefault(config.Params, "maxListRate", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.query(executor, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, resource := range []string{"configmaps", "secrets"} {
+		load := summary.Resources[resource]
+		logrus.Infof("%s: %s requests: %v, max request rate: %v, max watchers: %v", s, resource, load.Requests, load.MaxRequestRate, load.MaxWatchers)
+		if maxListRate > 0 && load.MaxRequestRate["LIST"] > maxListRate {
+			violations = append(violations, fmt.Sprintf("%s: %.2f LIST requests per second (max %.2f)", resource, load.MaxRequestRate["LIST"], maxListRate))
+		}
+	}
+	var violation error
+	if len(violations) > 0 {
+		violation = errors.NewMetricViolationError("secret volume load", strings.Join(violations, "; "))
+		logrus.Errorf("%s: %v", s, violation)
+	}
+
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(secretVolumeLoadName, "json", content), violation
+}
+
+func (s *secretVolumeLoadGatherer) String() string {
+	return secretVolumeLoadName
+}
+
+// Description returns a short description of the gatherer.
+func (s *secretVolumeLoadGatherer) Description() string {
+	return "Reports rate and latency of secret and configmap requests and number of their watchers."
+}
+
+func (s *secretVolumeLoadGatherer) query(executor QueryExecutor, startTime, endTime time.Time) (*secretVolumeLoadSummary, error) {
+	window := measurementutil.ToPrometheusTime(endTime.Sub(startTime))
+	summary := &secretVolumeLoadSummary{Resources: make(map[string]*resourceLoad)}
+	for _, resource := range watchedKinds {
+		summary.Resources[resource] = &resourceLoad{
+			Requests:       make(map[string]float64),
+			MaxRequestRate: make(map[string]float64),
+			Latency:        make(map[string]*measurementutil.LatencyMetric),
+		}
+	}
+
+	setters := map[string]func(*resourceLoad, string, float64){
+		secretRequestCountQuery:   func(l *resourceLoad, verb string, v float64) { l.Requests[verb] = v },
+		secretMaxRequestRateQuery: func(l *resourceLoad, verb string, v float64) { l.MaxRequestRate[verb] = v },
+	}
+	for query, set := range setters {
+		samples, err := executor.Query(fmt.Sprintf(query, secretVolumeFilters, window), endTime)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			if load, ok := summary.Resources[string(sample.Metric["resource"])]; ok {
+				set(load, string(sample.Metric["verb"]), float64(sample.Value))
+			}
+		}
+	}
+
+	for _, quantile := range []float64{0.5, 0.9, 0.99} {
+		samples, err := executor.Query(fmt.Sprintf(secretLatencyQuery, quantile, secretVolumeFilters, window), endTime)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			load, ok := summary.Resources[string(sample.Metric["resource"])]
+			if !ok {
+				continue
+			}
+			verb := string(sample.Metric["verb"])
+			if _, ok := load.Latency[verb]; !ok {
+				load.Latency[verb] = &measurementutil.LatencyMetric{}
+			}
+			load.Latency[verb].SetQuantile(quantile, time.Duration(float64(sample.Value)*float64(time.Second)))
+		}
+	}
+
+	samples, err := executor.Query(fmt.Sprintf(secretMaxWatchersQuery, window), endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		if resource, ok := watchedKinds[string(sample.Metric["kind"])]; ok {
+			summary.Resources[resource].MaxWatchers = float64(sample.Value)
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func createResourceVerbSample(resource, verb string, value float64) *model.Sample {
+	return &model.Sample{
+		Value:  model.SampleValue(value),
+		Metric: model.Metric{"resource": model.LabelValue(resource), "verb": model.LabelValue(verb)},
+	}
+}
+
+func TestSecretVolumeLoadGather(t *testing.T) {
+	executor := &queryMatchingExecutor{
+		samples: map[string][]*model.Sample{
+			"sum by (resource, verb)": {
+				createResourceVerbSample("secrets", "GET", 1000),
+				createResourceVerbSample("secrets", "LIST", 300),
+				createResourceVerbSample("configmaps", "WATCH", 50),
+			},
+			"max_over_time(sum by (resource, verb)": {
+				createResourceVerbSample("secrets", "LIST", 12.5),
+				createResourceVerbSample("configmaps", "LIST", 0.5),
+			},
+			"histogram_quantile": {createResourceVerbSample("secrets", "LIST", 0.2)},
+			"max_over_time(sum by (kind)": {
+				{Value: 500, Metric: model.Metric{"kind": "Secret"}},
+				{Value: 10, Metric: model.Metric{"kind": "ConfigMap"}},
+			},
+		},
+	}
+	g := &secretVolumeLoadGatherer{}
+	start := time.Now().Add(-10 * time.Minute)
+	summary, err := g.Gather(executor, start, time.Now(), &measurement.MeasurementConfig{Params: map[string]interface{}{"maxListRate": 10.0}})
+	assert.True(t, errors.IsMetricViolationError(err), "expected violation, got %v", err)
+
+	var data secretVolumeLoadSummary
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Equal(t, 300.0, data.Resources["secrets"].Requests["LIST"])
+	assert.Equal(t, 50.0, data.Resources["configmaps"].Requests["WATCH"])
+	assert.Equal(t, 12.5, data.Resources["secrets"].MaxRequestRate["LIST"])
+	assert.Equal(t, 200*time.Millisecond, data.Resources["secrets"].Latency["LIST"].Perc99)
+	assert.Equal(t, 500.0, data.Resources["secrets"].MaxWatchers)
+	assert.Equal(t, 10.0, data.Resources["configmaps"].MaxWatchers)
+
+	_, err = g.Gather(executor, start, time.Now(), &measurement.MeasurementConfig{Params: map[string]interface{}{}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	storageLatencyAttributionName = "StorageLatencyAttribution"
+
+	defaultStorageLatencyResolution = time.Minute
+
+	// Queries below are evaluated as range queries with resolution step.
+	// %v should be replaced with the step size.
+	apiserverTimeRateQuery     = "sum(rate(apiserver_request_duration_seconds_sum{verb!~\"WATCH|CONNECT\"}[%v]))"
+	apiserverRequestsRateQuery = "sum(rate(apiserver_request_duration_seconds_count{verb!~\"WATCH|CONNECT\"}[%v]))"
+	etcdTimeRateQuery          = "sum(rate(etcd_request_duration_seconds_sum[%v]))"
+	etcdRequestsRateQuery      = "sum(rate(etcd_request_duration_seconds_count[%v]))"
+
+	// Queries below aggregate metrics over the whole test.
+	// %v should be replaced with query window size (duration of the test).
+	apiserverTimeByVerbQuery = "sum by (verb) (increase(apiserver_request_duration_seconds_sum{verb!~\"WATCH|CONNECT\"}[%v]))"
+	etcdTimeByOperationQuery = "sum by (operation) (increase(etcd_request_duration_seconds_sum[%v]))"
+
+	readRequests  = "read"
+	writeRequests = "write"
+)
+
+// apiserverVerbKinds and etcdOperationKinds classify requests as reads or writes.
+// The classification is approximate, e.g. updates read the current object from etcd first.
+var (
+	apiserverVerbKinds = map[string]string{
+		"GET": readRequests, "LIST": readRequests,
+		"POST": writeRequests, "PUT": writeRequests, "PATCH": writeRequests,
+		"DELETE": writeRequests, "DELETECOLLECTION": writeRequests,
+	}
+	etcdOperationKinds = map[string]string{
+		"get": readRequests, "list": readRequests, "listWithCount": readRequests, "getToList": readRequests,
+		"create": writeRequests, "update": writeRequests, "delete": writeRequests,
+	}
+)
+
+func init() {
+	create := func() measurement.Measurement { return createPrometheusMeasurement(&storageLatencyGatherer{}) }
+	if err := measurement.Register(storageLatencyAttributionName, create); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", storageLatencyAttributionName, err)
+	}
+}
+
+type storageLatencyGatherer struct{}
+
+// storageTime compares time spent by apiserver serving requests with time spent waiting for etcd.
+type storageTime struct {
+	APIServerSeconds float64 `json:"apiserverSeconds"`
+	EtcdSeconds      float64 `json:"etcdSeconds"`
+	// StorageFraction is the fraction of apiserver request latency attributable to etcd.
+	StorageFraction float64 `json:"storageFraction"`
+}
+
+type storageLatencySummary struct {
+	Total storageTime `json:"total"`
+	// RequestKinds maps request kind (read or write) to time spent serving such requests.
+	RequestKinds map[string]*storageTime `json:"requestKinds"`
+	// Correlation is the Pearson correlation coefficient between mean apiserver and mean etcd
+	// request latency in each step. Values close to 1 mean that apiserver latency follows etcd latency.
+	Correlation float64 `json:"correlation"`
+	// APIServerLatency and EtcdLatency contain mean request latency (in seconds) in each step.
+	APIServerLatency []measurementutil.TimeSeriesPoint `json:"apiserverLatency"`
+	EtcdLatency      []measurementutil.TimeSeriesPoint `json:"etcdLatency"`
+	// StorageFraction contains the fraction of apiserver request latency attributable to etcd in each step.
+	StorageFraction []measurementutil.TimeSeriesPoint `json:"storageFraction"`
+}
+
+func (s *storageLatencyGatherer) RequiredCapabilities(config *measurement.MeasurementConfig) []measurement.Capability {
+	return nil
+}
+
+// Gather correlates apiserver request latency with etcd request latency in each step with given
+// resolution and attributes the time spent by apiserver serving requests to etcd over the whole test.
+func (s *storageLatencyGatherer) Gather(executor QueryExecutor, startTime, endTime time.Time, config *measurement.MeasurementConfig) (measurement.Summary, error) {
+	resolution, err := util.GetDurationOrDefault(config.Params, "resolution", defaultStorageLatencyResolution)
+	if err != nil {
+		return nil, err
+	}
+	resolution = adjustResolution(endTime.Sub(startTime), resolution)
+
+	step := measurementutil.ToPrometheusTime(resolution)
+	var rates [4]map[time.Time]float64
+	for i, query := range []string{apiserverTimeRateQuery, apiserverRequestsRateQuery, etcdTimeRateQuery, etcdRequestsRateQuery} {
+		streams, err := executor.QueryRange(fmt.Sprintf(query, step), startTime, endTime, resolution)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = make(map[time.Time]float64)
+		if series := measurementutil.NewTimeSeries(streams); len(series) > 0 {
+			for _, point := range series[0].Points {
+				rates[i][point.Timestamp] = point.Value
+			}
+		}
+	}
+	summary := &storageLatencySummary{
+		RequestKinds: map[string]*storageTime{readRequests: {}, writeRequests: {}},
+	}
+	summary.APIServerLatency, summary.EtcdLatency, summary.StorageFraction = correlateStorageLatency(rates[0], rates[1], rates[2], rates[3])
+	summary.Correlation = pearsonCorrelation(summary.APIServerLatency, summary.EtcdLatency)
+
+	window := measurementutil.ToPrometheusTime(endTime.Sub(startTime))
+	samples, err := executor.Query(fmt.Sprintf(apiserverTimeByVerbQuery, window), endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		value := float64(sample.Value)
+		summary.Total.APIServerSeconds += value
+		if kind, ok := apiserverVerbKinds[string(sample.Metric["verb"])]; ok {
+			summary.RequestKinds[kind].APIServerSeconds += value
+		}
+	}
+	samples, err = executor.Query(fmt.Sprintf(etcdTimeByOperationQuery, window), endTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		value := float64(sample.Value)
+		summary.Total.EtcdSeconds += value
+		if kind, ok := etcdOperationKinds[string(sample.Metric["operation"])]; ok {
+			summary.RequestKinds[kind].EtcdSeconds += value
+		}
+	}
+	summary.Total.StorageFraction = ratio(summary.Total.EtcdSeconds, summary.Total.APIServerSeconds)
+	for _, kind := range summary.RequestKinds {
+		kind.StorageFraction = ratio(kind.EtcdSeconds, kind.APIServerSeconds)
+	}
+	logrus.Infof("%s: %.2f of apiserver request latency attributable to etcd (reads: %.2f, writes: %.2f), correlation: %.2f",
+		s, summary.Total.StorageFraction, summary.RequestKinds[readRequests].StorageFraction,
+		summary.RequestKinds[writeRequests].StorageFraction, summary.Correlation)
+
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(storageLatencyAttributionName, "json", content), nil
+}
+
+func (s *storageLatencyGatherer) String() string {
+	return storageLatencyAttributionName
+}
+
+// Description returns a short description of the gatherer.
+func (s *storageLatencyGatherer) Description() string {
+	return "Attributes latency of apiserver requests to etcd requests."
+}
+
+// correlateStorageLatency computes mean apiserver and etcd request latency and the fraction
+// of apiserver latency attributable to etcd in steps present in all time series.
+func correlateStorageLatency(apiserverTime, apiserverRequests, etcdTime, etcdRequests map[time.Time]float64) (apiserverLatency, etcdLatency, storageFraction []measurementutil.TimeSeriesPoint) {
+	apiserverLatency = []measurementutil.TimeSeriesPoint{}
+	etcdLatency = []measurementutil.TimeSeriesPoint{}
+	storageFraction = []measurementutil.TimeSeriesPoint{}
+	var timestamps []time.Time
+	for timestamp := range apiserverTime {
+		_, hasRequests := apiserverRequests[timestamp]
+		_, hasEtcdTime := etcdTime[timestamp]
+		_, hasEtcdRequests := etcdRequests[timestamp]
+		if hasRequests && hasEtcdTime && hasEtcdRequests && apiserverRequests[timestamp] > 0 && etcdRequests[timestamp] > 0 {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	for _, timestamp := range timestamps {
+		apiserverLatency = append(apiserverLatency, measurementutil.TimeSeriesPoint{
+			Timestamp: timestamp, Value: apiserverTime[timestamp] / apiserverRequests[timestamp]})
+		etcdLatency = append(etcdLatency, measurementutil.TimeSeriesPoint{
+			Timestamp: timestamp, Value: etcdTime[timestamp] / etcdRequests[timestamp]})
+		storageFraction = append(storageFraction, measurementutil.TimeSeriesPoint{
+			Timestamp: timestamp, Value: ratio(etcdTime[timestamp], apiserverTime[timestamp])})
+	}
+	return apiserverLatency, etcdLatency, storageFraction
+}
+
+// pearsonCorrelation returns the Pearson correlation coefficient of values of two time series
+// of equal length, or 0 if it's undefined (e.g. one of the series is constant).
+func pearsonCorrelation(x, y []measurementutil.TimeSeriesPoint) float64 {
+	n := float64(len(x))
+	if len(x) < 2 || len(x) != len(y) {
+		return 0
+	}
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i].Value
+		sumY += y[i].Value
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		dx, dy := x[i].Value-meanX, y[i].Value-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+func ratio(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total
+}
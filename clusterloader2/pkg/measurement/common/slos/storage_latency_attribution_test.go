@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestStorageLatencyGather(t *testing.T) {
+	start := time.Now().Add(-3 * time.Minute).Truncate(time.Second)
+	executor := &queryMatchingExecutor{
+		samples: map[string][]*model.Sample{
+			"sum by (verb)": {
+				{Metric: model.Metric{"verb": "LIST"}, Value: 40},
+				{Metric: model.Metric{"verb": "POST"}, Value: 50},
+				{Metric: model.Metric{"verb": "PROXY"}, Value: 10},
+			},
+			"sum by (operation)": {
+				{Metric: model.Metric{"operation": "list"}, Value: 10},
+				{Metric: model.Metric{"operation": "create"}, Value: 40},
+			},
+		},
+		streams: map[string][]*model.SampleStream{
+			"sum(rate(apiserver_request_duration_seconds_sum":   {createSampleStream(model.Metric{}, start, 1, 2, 4)},
+			"sum(rate(apiserver_request_duration_seconds_count": {createSampleStream(model.Metric{}, start, 10, 10, 10)},
+			"sum(rate(etcd_request_duration_seconds_sum":        {createSampleStream(model.Metric{}, start, 0.5, 1, 3)},
+			"sum(rate(etcd_request_duration_seconds_count":      {createSampleStream(model.Metric{}, start, 10, 10, 10)},
+		},
+	}
+	g := &storageLatencyGatherer{}
+	summary, err := g.Gather(executor, start, time.Now(), &measurement.MeasurementConfig{Params: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var data storageLatencySummary
+	if err := json.Unmarshal([]byte(summary.SummaryContent()), &data); err != nil {
+		t.Fatalf("error while decoding summary: %v", err)
+	}
+	assert.Equal(t, storageTime{APIServerSeconds: 100, EtcdSeconds: 50, StorageFraction: 0.5}, data.Total)
+	assert.Equal(t, 0.25, data.RequestKinds[readRequests].StorageFraction)
+	assert.Equal(t, 0.8, data.RequestKinds[writeRequests].StorageFraction)
+	assert.Len(t, data.APIServerLatency, 3)
+	assert.Equal(t, 0.4, data.APIServerLatency[2].Value)
+	assert.Equal(t, 0.3, data.EtcdLatency[2].Value)
+	assert.Equal(t, 0.5, data.StorageFraction[0].Value)
+	assert.InDelta(t, 0.98, data.Correlation, 0.01)
+}
@@ -188,3 +188,8 @@ func (m *systemPodMetricsMeasurement) Dispose() {}
 func (*systemPodMetricsMeasurement) String() string {
 	return systemPodMetricsName
 }
+
+// Description returns a short description of the measurement.
+func (*systemPodMetricsMeasurement) Description() string {
+	return "Gathers container restart counts of system pods."
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/informer"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	systemStabilityName = "SystemStability"
+
+	defaultCriticalComponents = "kube-apiserver,etcd,kube-scheduler,kube-controller-manager,kube-dns,coredns"
+	oomKilledReason           = "OOMKilled"
+)
+
+func init() {
+	if err := measurement.Register(systemStabilityName, createSystemStabilityMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", systemStabilityName, err)
+	}
+}
+
+func createSystemStabilityMeasurement() measurement.Measurement {
+	return &systemStabilityMeasurement{}
+}
+
+type systemStabilityMeasurement struct {
+	namespace string
+	isRunning bool
+	stopCh    chan struct{}
+	lock      sync.Mutex
+	restarts  []containerRestart
+	// notReady contains periods in which nodes were not ready, in order of their start.
+	notReady []*nodeNotReadyPeriod
+}
+
+// containerRestart describes restarts of a container observed in a single pod update.
+type containerRestart struct {
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	Component string    `json:"component"`
+	Restarts  int32     `json:"restarts"`
+	Reason    string    `json:"reason"`
+	ExitCode  int32     `json:"exitCode"`
+	Time      time.Time `json:"time"`
+}
+
+// nodeNotReadyPeriod is a period in which the node was not ready. End is nil if the node
+// didn't become ready again before gather.
+type nodeNotReadyPeriod struct {
+	Node  string     `json:"node"`
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+type systemStabilitySummary struct {
+	Restarts         []containerRestart    `json:"restarts"`
+	OOMKills         int                   `json:"oomKills"`
+	NodeNotReady     []*nodeNotReadyPeriod `json:"nodeNotReady"`
+	CriticalRestarts []string              `json:"criticalRestarts"`
+}
+
+// Execute supports two actions:
+// - start - starts observing container restarts of pods in the namespace (kube-system by default)
+// and ready condition of all nodes.
+// - gather - reports container restarts (with OOMKills counted separately) and periods in which
+// nodes were not ready between start and gather.
+// If any container of a critical component restarted, an error will be returned. Component of
+// a pod is its component or k8s-app label, or its name. Critical components can be specified
+// with criticalComponents param (by default, control plane components and cluster DNS).
+func (s *systemStabilityMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		if s.namespace, err = util.GetStringOrDefault(config.Params, "namespace", systemNamespace); err != nil {
+			return nil, err
+		}
+		return nil, s.start(config.ClusterFramework.GetClientSets().GetClient())
+	case "gather":
+		criticalComponents, err := util.GetStringOrDefault(config.Params, "criticalComponents", defaultCriticalComponents)
+		if err != nil {
+			return nil, err
+		}
+		return s.gather(splitList(criticalComponents))
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (s *systemStabilityMeasurement) Dispose() {
+	s.stop()
+}
+
+// String returns string representation of this measurement.
+func (*systemStabilityMeasurement) String() string {
+	return systemStabilityName
+}
+
+// Description returns a short description of the measurement.
+func (*systemStabilityMeasurement) Description() string {
+	return "Reports container restarts of system pods and periods in which nodes were not ready."
+}
+
+func (s *systemStabilityMeasurement) start(c clientset.Interface) error {
+	if s.isRunning {
+		logrus.Infof("%s: measurement already running", s)
+		return nil
+	}
+	logrus.Infof("%s: starting observing restarts in %s namespace and node readiness...", s, s.namespace)
+	s.isRunning = true
+	s.stopCh = make(chan struct{})
+	s.restarts = nil
+	s.notReady = nil
+	podSelector := measurementutil.NewObjectSelector()
+	podSelector.Namespace = s.namespace
+	pods := informer.NewInformer(c, "pods", podSelector, s.checkPod)
+	if err := informer.StartAndSync(pods, s.stopCh, informerSyncTimeout); err != nil {
+		return err
+	}
+	nodes := informer.NewInformer(c, "nodes", measurementutil.NewObjectSelector(), s.checkNode)
+	return informer.StartAndSync(nodes, s.stopCh, informerSyncTimeout)
+}
+
+func (s *systemStabilityMeasurement) stop() {
+	if s.isRunning {
+		s.isRunning = false
+		close(s.stopCh)
+	}
+}
+
+func (s *systemStabilityMeasurement) gather(criticalComponents []string) ([]measurement.Summary, error) {
+	if !s.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", systemStabilityName)
+	}
+	s.stop()
+	logrus.Infof("%s: gathering restarts and node readiness", s)
+
+	s.lock.Lock()
+	summary := buildSystemStabilitySummary(s.restarts, s.notReady, criticalComponents)
+	s.lock.Unlock()
+	logrus.Infof("%s: %d restarts (%d OOMKills), %d node not ready periods",
+		s, len(summary.Restarts), summary.OOMKills, len(summary.NodeNotReady))
+
+	var err error
+	if len(summary.CriticalRestarts) > 0 {
+		err = errors.NewMetricViolationError("system stability", fmt.Sprintf("critical components restarted: %v", summary.CriticalRestarts))
+		logrus.Errorf("%s: %v", s, err)
+	}
+	content, jsonErr := util.PrettyPrintJSON(summary)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	return []measurement.Summary{measurement.CreateSummary(systemStabilityName, "json", content)}, err
+}
+
+func (s *systemStabilityMeasurement) checkPod(oldObj, newObj interface{}) {
+	if oldObj == nil || newObj == nil {
+		return
+	}
+	oldPod, ok := oldObj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	restarts := findContainerRestarts(oldPod, newPod, time.Now())
+	if len(restarts) == 0 {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, restart := range restarts {
+		logrus.Warningf("%s: container %s of %s restarted: %s (exit code %d)", s, restart.Container, restart.Pod, restart.Reason, restart.ExitCode)
+		s.restarts = append(s.restarts, restart)
+	}
+}
+
+func (s *systemStabilityMeasurement) checkNode(oldObj, newObj interface{}) {
+	if oldObj == nil || newObj == nil {
+		return
+	}
+	oldNode, ok := oldObj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	newNode, ok := newObj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	wasReady, isReady := util.IsNodeReady(oldNode), util.IsNodeReady(newNode)
+	if wasReady == isReady {
+		return
+	}
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !isReady {
+		logrus.Warningf("%s: node %s became not ready", s, newNode.Name)
+		s.notReady = append(s.notReady, &nodeNotReadyPeriod{Node: newNode.Name, Start: now})
+		return
+	}
+	for i := len(s.notReady) - 1; i >= 0; i-- {
+		if period := s.notReady[i]; period.Node == newNode.Name && period.End == nil {
+			period.End = &now
+			logrus.Infof("%s: node %s became ready after %v", s, newNode.Name, now.Sub(period.Start))
+			return
+		}
+	}
+}
+
+// findContainerRestarts returns restarts of containers of the pod between its two versions.
+func findContainerRestarts(oldPod, newPod *corev1.Pod, now time.Time) []containerRestart {
+	oldCounts := make(map[string]int32)
+	for _, status := range oldPod.Status.ContainerStatuses {
+		oldCounts[status.Name] = status.RestartCount
+	}
+	var restarts []containerRestart
+	for _, status := range newPod.Status.ContainerStatuses {
+		oldCount, ok := oldCounts[status.Name]
+		if !ok || status.RestartCount <= oldCount {
+			continue
+		}
+		restart := containerRestart{
+			Pod:       newPod.Name,
+			Container: status.Name,
+			Component: podComponent(newPod),
+			Restarts:  status.RestartCount - oldCount,
+			Time:      now,
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			restart.Reason = terminated.Reason
+			restart.ExitCode = terminated.ExitCode
+			if !terminated.FinishedAt.IsZero() {
+				restart.Time = terminated.FinishedAt.Time
+			}
+		}
+		restarts = append(restarts, restart)
+	}
+	return restarts
+}
+
+// podComponent returns component the pod belongs to.
+func podComponent(pod *corev1.Pod) string {
+	for _, label := range []string{"component", "k8s-app"} {
+		if component, ok := pod.Labels[label]; ok {
+			return component
+		}
+	}
+	return pod.Name
+}
+
+func buildSystemStabilitySummary(restarts []containerRestart, notReady []*nodeNotReadyPeriod, criticalComponents []string) *systemStabilitySummary {
+	summary := &systemStabilitySummary{
+		Restarts:         append([]containerRestart{}, restarts...),
+		NodeNotReady:     append([]*nodeNotReadyPeriod{}, notReady...),
+		CriticalRestarts: []string{},
+	}
+	critical := make(map[string]bool)
+	for _, component := range criticalComponents {
+		critical[component] = true
+	}
+	restartedCritical := make(map[string]bool)
+	for _, restart := range restarts {
+		if restart.Reason == oomKilledReason {
+			summary.OOMKills += int(restart.Restarts)
+		}
+		if critical[restart.Component] {
+			restartedCritical[fmt.Sprintf("%s/%s", restart.Pod, restart.Container)] = true
+		}
+	}
+	for container := range restartedCritical {
+		summary.CriticalRestarts = append(summary.CriticalRestarts, container)
+	}
+	sort.Strings(summary.CriticalRestarts)
+	return summary
+}
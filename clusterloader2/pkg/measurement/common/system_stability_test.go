@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindContainerRestarts(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	finishedAt := now.Add(-time.Second)
+	pod := func(restarts int32, terminated *corev1.ContainerStateTerminated) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcd-master", Labels: map[string]string{"component": "etcd"}},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "etcd", RestartCount: restarts, LastTerminationState: corev1.ContainerState{Terminated: terminated}},
+					{Name: "sidecar"},
+				},
+			},
+		}
+	}
+
+	assert.Empty(t, findContainerRestarts(pod(1, nil), pod(1, nil), now))
+	restarts := findContainerRestarts(pod(1, nil), pod(3, &corev1.ContainerStateTerminated{
+		Reason:     oomKilledReason,
+		ExitCode:   137,
+		FinishedAt: metav1.NewTime(finishedAt),
+	}), now)
+	assert.Equal(t, []containerRestart{{
+		Pod:       "etcd-master",
+		Container: "etcd",
+		Component: "etcd",
+		Restarts:  2,
+		Reason:    oomKilledReason,
+		ExitCode:  137,
+		Time:      finishedAt,
+	}}, restarts)
+}
+
+func TestBuildSystemStabilitySummary(t *testing.T) {
+	restarts := []containerRestart{
+		{Pod: "etcd-master", Container: "etcd", Component: "etcd", Restarts: 2, Reason: oomKilledReason},
+		{Pod: "etcd-master", Container: "etcd", Component: "etcd", Restarts: 1, Reason: "Error"},
+		{Pod: "fluentd-abc", Container: "fluentd", Component: "fluentd", Restarts: 1, Reason: oomKilledReason},
+	}
+	summary := buildSystemStabilitySummary(restarts, nil, []string{"etcd", "kube-apiserver"})
+	assert.Equal(t, 3, summary.OOMKills)
+	assert.Equal(t, []string{"etcd-master/etcd"}, summary.CriticalRestarts)
+	assert.Len(t, summary.Restarts, 3)
+	assert.Empty(t, summary.NodeNotReady)
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/stats"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	tenantFairnessName = "TenantFairness"
+	// tenantFairnessResolution is the length of intervals in which calls are aggregated.
+	// Calls are scored in the window with start and end rounded down to the resolution.
+	tenantFairnessResolution = time.Second
+)
+
+func init() {
+	if err := measurement.Register(tenantFairnessName, createTenantFairnessMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", tenantFairnessName, err)
+	}
+}
+
+func createTenantFairnessMeasurement() measurement.Measurement {
+	return &tenantFairnessMeasurement{}
+}
+
+type tenantFairnessMeasurement struct {
+	isRunning   bool
+	apiCalls    *measurement.APICallObservers
+	tenantRegex *regexp.Regexp
+	startTime   time.Time
+	lock        sync.Mutex
+	calls       tenantCallsByInterval
+}
+
+// tenantInterval identifies calls of the tenant started in the interval of tenantFairnessResolution.
+type tenantInterval struct {
+	tenant   string
+	interval int64
+}
+
+// tenantCalls aggregates calls, so that memory doesn't grow with their number.
+type tenantCalls struct {
+	calls       int
+	failedCalls int
+	latencies   *stats.Sketch
+}
+
+type tenantCallsByInterval map[tenantInterval]*tenantCalls
+
+func intervalOf(t time.Time) int64 {
+	return t.UnixNano() / int64(tenantFairnessResolution)
+}
+
+func (c tenantCallsByInterval) add(tenant string, start time.Time, latency time.Duration, failed bool) {
+	key := tenantInterval{tenant: tenant, interval: intervalOf(start)}
+	calls, ok := c[key]
+	if !ok {
+		calls = &tenantCalls{latencies: stats.NewSketch(stats.DefaultSketchAccuracy)}
+		c[key] = calls
+	}
+	calls.calls++
+	if failed {
+		calls.failedCalls++
+	}
+	calls.latencies.Add(latency.Seconds())
+}
+
+type tenantStats struct {
+	Tenant      string `json:"tenant"`
+	Calls       int    `json:"calls"`
+	FailedCalls int    `json:"failedCalls"`
+	// Throughput is the number of successful calls per second.
+	Throughput     float64       `json:"throughput"`
+	LatencySeconds stats.Summary `json:"latencySeconds"`
+}
+
+type tenantFairnessSummary struct {
+	WindowStart time.Time `json:"windowStart"`
+	WindowEnd   time.Time `json:"windowEnd"`
+	// ThroughputFairness is Jain's index over throughput of tenants.
+	ThroughputFairness float64 `json:"throughputFairness"`
+	// LatencyFairness is Jain's index over inverse of mean latency of tenants.
+	LatencyFairness float64       `json:"latencyFairness"`
+	Tenants         []tenantStats `json:"tenants"`
+	StarvedTenants  []string      `json:"starvedTenants,omitempty"`
+}
+
+// Execute supports two actions:
+// - start - starts observing object operations performed by the test, grouped by tenant.
+// - gather - stops observing and reports throughput and latency of tenants together with
+// Jain's fairness indices over them.
+// By default, every namespace is a tenant. If tenantRegex param is set, namespaces matching it
+// are grouped by the first submatch of the regex (or the whole match) and operations in other
+// namespaces are ignored. If minThroughputFairness or minLatencyFairness param is set and
+// the index is lower, an error will be returned. If starvationRatio param is set, an error
+// will be returned as well for tenants with throughput lower than starvationRatio of mean
+// throughput, or mean latency higher than mean of all tenants divided by starvationRatio.
+// Fairness can be scored in a window between markers given with startMarker and endMarker params.
+func (t *tenantFairnessMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	action, err := util.GetString(config.Params, "action")
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case "start":
+		return nil, t.start(config)
+	case "gather":
+		return t.gather(config)
+	default:
+		return nil, fmt.Errorf("unknown action %v", action)
+	}
+}
+
+// Dispose cleans up after the measurement.
+func (t *tenantFairnessMeasurement) Dispose() {
+	t.stop()
+}
+
+// String returns string representation of this measurement.
+func (*tenantFairnessMeasurement) String() string {
+	return tenantFairnessName
+}
+
+// Description returns a short description of the measurement.
+func (*tenantFairnessMeasurement) Description() string {
+	return "Measures throughput and latency of object operations per tenant and Jain's fairness indices over tenants."
+}
+
+// ObserveAPICall records the call if it was performed in a namespace of a tenant.
+func (t *tenantFairnessMeasurement) ObserveAPICall(call measurement.APICall) {
+	tenant, ok := tenantOf(t.tenantRegex, call.Namespace)
+	if !ok {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.calls.add(tenant, call.Start, call.Latency, call.Failed)
+}
+
+func (t *tenantFairnessMeasurement) start(config *measurement.MeasurementConfig) error {
+	if t.isRunning {
+		logrus.Infof("%s: measurement already running", t)
+		return nil
+	}
+	if config.APICalls == nil {
+		return fmt.Errorf("%s: object operations can't be observed outside of the test", t)
+	}
+	tenantRegex, err := util.GetStringOrDefault(config.Params, "tenantRegex", "")
+	if err != nil {
+		return err
+	}
+	t.tenantRegex = nil
+	if tenantRegex != "" {
+		if t.tenantRegex, err = regexp.Compile(tenantRegex); err != nil {
+			return fmt.Errorf("tenantRegex param: %v", err)
+		}
+	}
+	t.lock.Lock()
+	t.calls = make(tenantCallsByInterval)
+	t.lock.Unlock()
+	t.startTime = time.Now()
+	t.apiCalls = config.APICalls
+	t.apiCalls.Add(t)
+	t.isRunning = true
+	return nil
+}
+
+func (t *tenantFairnessMeasurement) stop() {
+	if t.isRunning {
+		t.apiCalls.Remove(t)
+		t.isRunning = false
+	}
+}
+
+func (t *tenantFairnessMeasurement) gather(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	if !t.isRunning {
+		return nil, fmt.Errorf("metric %s has not been started", tenantFairnessName)
+	}
+	t.stop()
+	minThroughputFairness, err := util.GetFloat64OrDefault(config.Params, "minThroughputFairness", 0)
+	if err != nil {
+		return nil, err
+	}
+	minLatencyFairness, err := util.GetFloat64OrDefault(config.Params, "minLatencyFairness", 0)
+	if err != nil {
+		return nil, err
+	}
+	starvationRatio, err := util.GetFloat64OrDefault(config.Params, "starvationRatio", 0)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := measurement.GetWindow(config, t.startTime, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	summary := buildTenantFairnessSummary(t.calls, start, end, starvationRatio)
+	t.lock.Unlock()
+	logrus.Infof("%s: %d tenants, throughput fairness: %.3f, latency fairness: %.3f",
+		t, len(summary.Tenants), summary.ThroughputFairness, summary.LatencyFairness)
+
+	var violations []string
+	if minThroughputFairness > 0 && summary.ThroughputFairness < minThroughputFairness {
+		violations = append(violations, fmt.Sprintf("throughput fairness %.3f is lower than %.3f", summary.ThroughputFairness, minThroughputFairness))
+	}
+	if minLatencyFairness > 0 && summary.LatencyFairness < minLatencyFairness {
+		violations = append(violations, fmt.Sprintf("latency fairness %.3f is lower than %.3f", summary.LatencyFairness, minLatencyFairness))
+	}
+	if len(summary.StarvedTenants) > 0 {
+		violations = append(violations, fmt.Sprintf("starved tenants: %v", summary.StarvedTenants))
+	}
+	var violation error
+	if len(violations) > 0 {
+		violation = errors.NewMetricViolationError("tenant fairness", strings.Join(violations, "; "))
+	}
+	content, err := util.PrettyPrintJSON(summary)
+	if err != nil {
+		return nil, err
+	}
+	return []measurement.Summary{measurement.CreateSummary(tenantFairnessName, "json", content)}, violation
+}
+
+// tenantOf returns tenant owning the namespace. Cluster-scoped calls don't belong to any tenant.
+func tenantOf(tenantRegex *regexp.Regexp, namespace string) (string, bool) {
+	if namespace == "" {
+		return "", false
+	}
+	if tenantRegex == nil {
+		return namespace, true
+	}
+	match := tenantRegex.FindStringSubmatch(namespace)
+	switch {
+	case match == nil:
+		return "", false
+	case len(match) > 1:
+		return match[1], true
+	default:
+		return match[0], true
+	}
+}
+
+// buildTenantFairnessSummary scores calls started in intervals between start and end. If starvationRatio
+// is positive, starved tenants are reported.
+func buildTenantFairnessSummary(calls tenantCallsByInterval, start, end time.Time, starvationRatio float64) *tenantFairnessSummary {
+	firstInterval, lastInterval := intervalOf(start), intervalOf(end)
+	latencies := make(map[string]*stats.Sketch)
+	tenants := make(map[string]*tenantStats)
+	for key, c := range calls {
+		if key.interval < firstInterval || key.interval > lastInterval {
+			continue
+		}
+		s, ok := tenants[key.tenant]
+		if !ok {
+			s = &tenantStats{Tenant: key.tenant}
+			tenants[key.tenant] = s
+			latencies[key.tenant] = stats.NewSketch(stats.DefaultSketchAccuracy)
+		}
+		s.Calls += c.calls
+		s.FailedCalls += c.failedCalls
+		latencies[key.tenant].Merge(c.latencies)
+	}
+
+	summary := &tenantFairnessSummary{
+		WindowStart: start,
+		WindowEnd:   end,
+		Tenants:     []tenantStats{},
+	}
+	seconds := end.Sub(start).Seconds()
+	var throughputs, responsiveness, meanLatencies []float64
+	for tenant, s := range tenants {
+		s.Throughput = float64(s.Calls-s.FailedCalls) / seconds
+		s.LatencySeconds = latencies[tenant].Summary()
+		summary.Tenants = append(summary.Tenants, *s)
+	}
+	sort.Slice(summary.Tenants, func(i, j int) bool {
+		return summary.Tenants[i].Tenant < summary.Tenants[j].Tenant
+	})
+	for _, s := range summary.Tenants {
+		throughputs = append(throughputs, s.Throughput)
+		meanLatencies = append(meanLatencies, s.LatencySeconds.Mean)
+		if s.LatencySeconds.Mean > 0 {
+			responsiveness = append(responsiveness, 1/s.LatencySeconds.Mean)
+		}
+	}
+	summary.ThroughputFairness = stats.JainIndex(throughputs)
+	summary.LatencyFairness = stats.JainIndex(responsiveness)
+
+	if starvationRatio > 0 {
+		meanThroughput, meanLatency := stats.Mean(throughputs), stats.Mean(meanLatencies)
+		for _, s := range summary.Tenants {
+			if s.Throughput < starvationRatio*meanThroughput || s.LatencySeconds.Mean*starvationRatio > meanLatency {
+				summary.StarvedTenants = append(summary.StarvedTenants, s.Tenant)
+			}
+		}
+	}
+	return summary
+}
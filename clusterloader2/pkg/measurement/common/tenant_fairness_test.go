@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantOf(t *testing.T) {
+	tenant, ok := tenantOf(nil, "test-abc-1")
+	assert.True(t, ok)
+	assert.Equal(t, "test-abc-1", tenant)
+	_, ok = tenantOf(nil, "")
+	assert.False(t, ok)
+
+	tenantRegex := regexp.MustCompile(`^(tenant-[a-z]+)-\d+$`)
+	tenant, ok = tenantOf(tenantRegex, "tenant-a-12")
+	assert.True(t, ok)
+	assert.Equal(t, "tenant-a", tenant)
+	_, ok = tenantOf(tenantRegex, "kube-system")
+	assert.False(t, ok)
+}
+
+func TestBuildTenantFairnessSummary(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Second)
+	calls := make(tenantCallsByInterval)
+	for i := 0; i < 10; i++ {
+		calls.add("a", start.Add(time.Duration(i)*time.Second), 100*time.Millisecond, false)
+		calls.add("b", start.Add(time.Duration(i)*time.Second), 100*time.Millisecond, false)
+	}
+	fairCalls := make(tenantCallsByInterval)
+	for key, c := range calls {
+		fairCalls[key] = c
+	}
+	// Tenant c is starved: a single call, slow and failing half of the time.
+	calls.add("c", start.Add(time.Second), time.Second, false)
+	calls.add("c", start.Add(2*time.Second), time.Second, true)
+	// Calls outside of the window are ignored.
+	calls.add("c", end.Add(time.Second), time.Second, false)
+
+	summary := buildTenantFairnessSummary(calls, start, end, 0.5)
+	assert.Len(t, summary.Tenants, 3)
+	assert.Equal(t, "c", summary.Tenants[2].Tenant)
+	assert.Equal(t, 2, summary.Tenants[2].Calls)
+	assert.Equal(t, 1, summary.Tenants[2].FailedCalls)
+	assert.InDelta(t, 0.1, summary.Tenants[2].Throughput, 1e-9)
+	assert.InDelta(t, 1.0, summary.Tenants[0].Throughput, 1e-9)
+	// Jain's index over throughputs 1, 1 and 0.1.
+	assert.InDelta(t, 4.41/6.03, summary.ThroughputFairness, 1e-9)
+	// Jain's index over responsiveness 10, 10 and 1.
+	assert.InDelta(t, 441.0/603, summary.LatencyFairness, 1e-9)
+	assert.Equal(t, []string{"c"}, summary.StarvedTenants)
+
+	summary = buildTenantFairnessSummary(fairCalls, start, end, 0.5)
+	assert.Equal(t, 1.0, summary.ThroughputFairness)
+	assert.Empty(t, summary.StarvedTenants)
+}
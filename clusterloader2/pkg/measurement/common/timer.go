@@ -105,6 +105,14 @@ func (t *timer) Execute(config *measurement.MeasurementConfig) ([]measurement.Su
 	return nil, nil
 }
 
+// DeclaredParams returns params accepted by the measurement.
+func (t *timer) DeclaredParams() []measurement.Param {
+	return []measurement.Param{
+		measurement.ActionParam("start", "stop", "gather"),
+		{Name: "label", Type: measurement.StringParam, Required: true, Actions: []string{"start", "stop"}, Description: "name of the timed phase"},
+	}
+}
+
 // Dispose cleans up after the measurement.
 func (t *timer) Dispose() {}
 
@@ -112,3 +120,8 @@ func (t *timer) Dispose() {}
 func (*timer) String() string {
 	return timerMeasurementName
 }
+
+// Description returns a short description of the measurement.
+func (*timer) Description() string {
+	return "Measures durations between start and stop calls with given labels."
+}
@@ -17,15 +17,23 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/jsonpath"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
@@ -46,6 +54,8 @@ const (
 	informerSyncTimeout              = time.Minute
 	waitForControlledPodsRunningName = "WaitForControlledPodsRunning"
 	waitForControlledPodsWorkers     = 10
+	// defaultReplicasPath is a JSONPath of the number of pods of objects other than built-in controllers.
+	defaultReplicasPath = "{.spec.replicas}"
 )
 
 func init() {
@@ -67,6 +77,7 @@ type waitForControlledPodsRunningMeasurement struct {
 	kind              string
 	selector          *measurementutil.ObjectSelector
 	operationTimeout  time.Duration
+	stuckPodTimeout   time.Duration
 	stopCh            chan struct{}
 	isRunning         bool
 	queue             workerqueue.Interface
@@ -76,6 +87,10 @@ type waitForControlledPodsRunningMeasurement struct {
 	gvr               schema.GroupVersionResource
 	checkerMap        checker.CheckerMap
 	clusterFramework  *framework.Framework
+	// replicasPath and ownerResolver are used for kinds other than built-in controllers,
+	// whose pods are found by owner references instead of a label selector.
+	replicasPath  *jsonpath.JSONPath
+	ownerResolver *runtimeobjects.OwnerResolver
 }
 
 // Execute waits until all specified controlling objects have all pods running or until timeout happens.
@@ -108,18 +123,45 @@ func (w *waitForControlledPodsRunningMeasurement) Execute(config *measurement.Me
 		if err != nil {
 			return nil, err
 		}
+		w.stuckPodTimeout, err = util.GetDurationOrDefault(config.Params, "stuckPodTimeout", 0)
+		if err != nil {
+			return nil, err
+		}
+		replicasPath, err := util.GetStringOrDefault(config.Params, "replicasPath", defaultReplicasPath)
+		if err != nil {
+			return nil, err
+		}
+		w.replicasPath = jsonpath.New("replicas")
+		if err = w.replicasPath.Parse(replicasPath); err != nil {
+			return nil, fmt.Errorf("replicas path %q parsing error: %v", replicasPath, err)
+		}
 		return nil, w.start()
 	case "gather":
 		syncTimeout, err := util.GetDurationOrDefault(config.Params, "syncTimeout", defaultSyncTimeout)
 		if err != nil {
 			return nil, err
 		}
-		return nil, w.gather(syncTimeout)
+		return w.gather(config.GetContext(), syncTimeout, config.Identifier)
 	default:
 		return nil, fmt.Errorf("unknown action %v", action)
 	}
 }
 
+// DeclaredParams returns params accepted by the measurement.
+func (w *waitForControlledPodsRunningMeasurement) DeclaredParams() []measurement.Param {
+	start := []string{"start"}
+	params := []measurement.Param{
+		measurement.ActionParam("start", "gather"),
+		{Name: "apiVersion", Type: measurement.StringParam, Required: true, Actions: start, Description: "api version of controlling objects"},
+		{Name: "kind", Type: measurement.StringParam, Required: true, Actions: start, Description: "kind of controlling objects"},
+		{Name: "operationTimeout", Type: measurement.DurationParam, Actions: start, Default: defaultOperationTimeout.String(), Description: "timeout of waiting for pods of a single object"},
+		{Name: "stuckPodTimeout", Type: measurement.DurationParam, Actions: start, Description: "time after which a pending pod is reported as stuck, disabled if zero"},
+		{Name: "replicasPath", Type: measurement.StringParam, Actions: start, Default: defaultReplicasPath, Description: "jsonpath of the number of replicas of an object"},
+		{Name: "syncTimeout", Type: measurement.DurationParam, Actions: []string{"gather"}, Default: defaultSyncTimeout.String(), Description: "timeout of waiting for all objects to have their pods running"},
+	}
+	return append(params, measurement.SelectorParams("start")...)
+}
+
 // Dispose cleans up after the measurement.
 func (w *waitForControlledPodsRunningMeasurement) Dispose() {
 	if !w.isRunning {
@@ -138,6 +180,11 @@ func (*waitForControlledPodsRunningMeasurement) String() string {
 	return waitForControlledPodsRunningName
 }
 
+// Description returns a short description of the measurement.
+func (*waitForControlledPodsRunningMeasurement) Description() string {
+	return "Waits until all pods of observed controlling objects are running."
+}
+
 func (w *waitForControlledPodsRunningMeasurement) start() error {
 	if w.isRunning {
 		logrus.Infof("%v: wait for controlled pods measurement already running", w)
@@ -153,6 +200,7 @@ func (w *waitForControlledPodsRunningMeasurement) start() error {
 
 	w.isRunning = true
 	w.stopCh = make(chan struct{})
+	w.ownerResolver = runtimeobjects.NewOwnerResolver(w.clusterFramework.GetDynamicClients().GetClient())
 	i := informer.NewDynamicInformer(
 		w.clusterFramework.GetDynamicClients().GetClient(),
 		w.gvr,
@@ -167,28 +215,89 @@ func (w *waitForControlledPodsRunningMeasurement) start() error {
 	return informer.StartAndSync(i, w.stopCh, informerSyncTimeout)
 }
 
-func (w *waitForControlledPodsRunningMeasurement) gather(syncTimeout time.Duration) error {
+// gather waits for controlling objects. Once ctx is done, waiting for them is stopped
+// and statuses determined so far are reported.
+func (w *waitForControlledPodsRunningMeasurement) gather(ctx context.Context, syncTimeout time.Duration, identifier string) ([]measurement.Summary, error) {
 	logrus.Infof("%v: waiting for controlled pods measurement...", w)
 	if !w.isRunning {
-		return fmt.Errorf("metric %s has not been started", w)
+		return nil, fmt.Errorf("metric %s has not been started", w)
 	}
 	desiredCount, maxResourceVersion, err := w.getObjectCountAndMaxVersion()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	cond := func() (bool, error) {
 		return w.opResourceVersion >= maxResourceVersion, nil
 	}
-	if err := wait.Poll(checkControlledPodsInterval, syncTimeout, cond); err != nil {
-		return fmt.Errorf("timed out while waiting for controlled pods")
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	if err := wait.PollUntil(checkControlledPodsInterval, cond, syncCtx.Done()); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("waiting for controlled pods cancelled: %v", ctx.Err())
+		}
+		return nil, fmt.Errorf("timed out while waiting for controlled pods")
 	}
 
+	waitDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.stopCheckers()
+		case <-waitDone:
+		}
+	}()
 	w.handlingGroup.Wait()
+	close(waitDone)
 	w.lock.Lock()
 	defer w.lock.Unlock()
-	var numberRunning, numberDeleted, numberTimeout, numberUnknown int
+	content, err := util.PrettyPrintJSON(w.ownersSummaryLocked())
+	if err != nil {
+		return nil, err
+	}
+	summary := measurement.CreateSummary(fmt.Sprintf("%s_%s", waitForControlledPodsRunningName, identifier), "json", content)
+	if ctx.Err() != nil {
+		return []measurement.Summary{summary}, fmt.Errorf("waiting for controlled pods cancelled: %v", ctx.Err())
+	}
+	return []measurement.Summary{summary}, w.verifyStatusesLocked(desiredCount)
+}
+
+// stopCheckers stops waiting for all controlling objects, leaving their statuses unchanged.
+func (w *waitForControlledPodsRunningMeasurement) stopCheckers() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, checker := range w.checkerMap {
+		checker.Stop()
+	}
+}
+
+// ownersSummaryLocked returns statuses of all observed controlling objects.
+func (w *waitForControlledPodsRunningMeasurement) ownersSummaryLocked() *controlledPodsSummary {
+	summary := &controlledPodsSummary{Kind: w.kind, Owners: make([]ownerStatus, 0, len(w.checkerMap))}
+	for _, checker := range w.checkerMap {
+		objChecker := checker.(*objectChecker)
+		status, err := objChecker.getStatus()
+		owner := ownerStatus{
+			Key:         objChecker.key,
+			Status:      status.String(),
+			DesiredPods: objChecker.replicas,
+		}
+		if err != nil {
+			owner.Error = err.Error()
+		}
+		summary.Owners = append(summary.Owners, owner)
+	}
+	sort.Slice(summary.Owners, func(i, j int) bool {
+		return summary.Owners[i].Key < summary.Owners[j].Key
+	})
+	return summary
+}
+
+// verifyStatusesLocked checks that desired number of controlling objects have all pods running.
+func (w *waitForControlledPodsRunningMeasurement) verifyStatusesLocked(desiredCount int) error {
+	var numberRunning, numberDeleted, numberTimeout, numberFailed, numberUnknown int
 	unknowStatusErrList := errors.NewErrorList()
+	failedErrList := errors.NewErrorList()
 	timedOutObjects := []string{}
 	for _, checker := range w.checkerMap {
 		objChecker := checker.(*objectChecker)
@@ -201,6 +310,9 @@ func (w *waitForControlledPodsRunningMeasurement) gather(syncTimeout time.Durati
 		case timeout:
 			timedOutObjects = append(timedOutObjects, objChecker.key)
 			numberTimeout++
+		case failed:
+			numberFailed++
+			failedErrList.Append(err)
 		default:
 			numberUnknown++
 			if err != nil {
@@ -208,11 +320,15 @@ func (w *waitForControlledPodsRunningMeasurement) gather(syncTimeout time.Durati
 			}
 		}
 	}
-	logrus.Infof("%s: running %d, deleted %d, timeout: %d, unknown: %d", w, numberRunning, numberDeleted, numberTimeout, numberUnknown)
+	logrus.Infof("%s: running %d, deleted %d, timeout: %d, failed: %d, unknown: %d", w, numberRunning, numberDeleted, numberTimeout, numberFailed, numberUnknown)
 	if numberTimeout > 0 {
 		logrus.Errorf("Timed out %ss: %s", w.kind, strings.Join(timedOutObjects, ", "))
 		return fmt.Errorf("%d objects timed out: %ss: %s", numberTimeout, w.kind, strings.Join(timedOutObjects, ", "))
 	}
+	if numberFailed > 0 {
+		logrus.Errorf("%s: %d %ss have stuck pods: %s", w, numberFailed, w.kind, failedErrList.String())
+		return fmt.Errorf("%d objects have stuck pods: %v", numberFailed, failedErrList.String())
+	}
 	if desiredCount != numberRunning {
 		logrus.Errorf("%s: incorrect objects number: %d/%d %ss are running with all pods", w, numberRunning, desiredCount, w.kind)
 		return fmt.Errorf("incorrect objects number: %d/%d %ss are running with all pods", numberRunning, desiredCount, w.kind)
@@ -278,11 +394,11 @@ func (w *waitForControlledPodsRunningMeasurement) handleObject(oldObj, newObj in
 }
 
 func (w *waitForControlledPodsRunningMeasurement) checkScaledown(oldObj, newObj runtime.Object) (bool, error) {
-	oldReplicas, err := runtimeobjects.GetReplicasFromRuntimeObject(w.clusterFramework.GetClientSets().GetClient(), oldObj)
+	oldReplicas, err := w.getReplicas(oldObj)
 	if err != nil {
 		return false, err
 	}
-	newReplicas, err := runtimeobjects.GetReplicasFromRuntimeObject(w.clusterFramework.GetClientSets().GetClient(), newObj)
+	newReplicas, err := w.getReplicas(newObj)
 	if err != nil {
 		return false, err
 	}
@@ -360,8 +476,8 @@ func (w *waitForControlledPodsRunningMeasurement) updateOpResourceVersion(runtim
 func (w *waitForControlledPodsRunningMeasurement) getObjectCountAndMaxVersion() (int, uint64, error) {
 	var desiredCount int
 	var maxResourceVersion uint64
-	objects, err := runtimeobjects.ListRuntimeObjectsForKind(w.clusterFramework.GetClientSets().GetClient(),
-		w.kind, w.selector.Namespace, w.selector.LabelSelector, w.selector.FieldSelector)
+	objects, err := runtimeobjects.ListRuntimeObjectsForResource(w.clusterFramework.GetDynamicClients().GetClient(),
+		w.gvr, w.selector.Namespace, w.selector.LabelSelector, w.selector.FieldSelector)
 	if err != nil {
 		return desiredCount, maxResourceVersion, fmt.Errorf("listing objects error: %v", err)
 	}
@@ -390,16 +506,28 @@ func (w *waitForControlledPodsRunningMeasurement) waitForRuntimeObject(obj runti
 	if err != nil {
 		return nil, err
 	}
-	runtimeObjectSelector, err := runtimeobjects.GetSelectorFromRuntimeObject(obj)
-	if err != nil {
-		return nil, err
-	}
-	runtimeObjectReplicas, err := runtimeobjects.GetReplicasFromRuntimeObject(w.clusterFramework.GetClientSets().GetClient(), obj)
-	if err != nil {
-		return nil, err
+	runtimeObjectSelector := labels.Everything()
+	var podFilter func(*v1.Pod) bool
+	var runtimeObjectReplicas int32
+	if runtimeobjects.HasPodSelector(w.kind) {
+		runtimeObjectSelector, err = runtimeobjects.GetSelectorFromRuntimeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Pods of other kinds, e.g. custom resources, are found through owner references.
+		uid, err := runtimeobjects.GetUIDFromRuntimeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		podFilter = func(pod *v1.Pod) bool {
+			return w.ownerResolver.IsOwnedBy(pod.Namespace, pod.OwnerReferences, uid)
+		}
 	}
-	if isDeleted {
-		runtimeObjectReplicas = 0
+	if !isDeleted {
+		if runtimeObjectReplicas, err = w.getReplicas(obj); err != nil {
+			return nil, err
+		}
 	}
 	key, err := runtimeobjects.CreateMetaNamespaceKey(obj)
 	if err != nil {
@@ -407,6 +535,7 @@ func (w *waitForControlledPodsRunningMeasurement) waitForRuntimeObject(obj runti
 	}
 
 	o := newObjectChecker(key)
+	o.replicas = int(runtimeObjectReplicas)
 	o.lock.Lock()
 	defer o.lock.Unlock()
 	w.handlingGroup.Start(func() {
@@ -420,10 +549,12 @@ func (w *waitForControlledPodsRunningMeasurement) waitForRuntimeObject(obj runti
 			EnableLogging:       true,
 			CallerName:          w.String(),
 			WaitForPodsInterval: defaultWaitForPodsInterval,
+			StuckPodTimeout:     w.stuckPodTimeout,
+			PodFilter:           podFilter,
 		}
 		// This function sets the status (and error message) for the object checker.
 		// The handling of bad statuses and errors is done by gather() function of the measurement.
-		err = measurementutil.WaitForPods(w.clusterFramework.GetClientSets().GetClient(), o.stopCh, options)
+		err = measurementutil.WaitForPods(w.clusterFramework.GetPodStores(), o.stopCh, options)
 		o.lock.Lock()
 		defer o.lock.Unlock()
 		if err != nil {
@@ -431,6 +562,9 @@ func (w *waitForControlledPodsRunningMeasurement) waitForRuntimeObject(obj runti
 				// Log error only if checker wasn't terminated.
 				logrus.Errorf("%s: error for %v: %v", w, key, err)
 				o.err = fmt.Errorf("%s: %v", key, err)
+				if measurementutil.IsStuckPodsError(err) {
+					o.status = failed
+				}
 			}
 			if o.status == timeout {
 				logrus.Errorf("%s: %s timed out", w, key)
@@ -455,14 +589,45 @@ const (
 	running
 	deleted
 	timeout
+	failed
 )
 
+func (s objectStatus) String() string {
+	switch s {
+	case running:
+		return "Running"
+	case deleted:
+		return "Deleted"
+	case timeout:
+		return "Timeout"
+	case failed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// controlledPodsSummary is the summary of the measurement listing statuses of all controlling objects.
+type controlledPodsSummary struct {
+	Kind   string        `json:"kind"`
+	Owners []ownerStatus `json:"owners"`
+}
+
+type ownerStatus struct {
+	Key         string `json:"key"`
+	Status      string `json:"status"`
+	DesiredPods int    `json:"desiredPods"`
+	Error       string `json:"error,omitempty"`
+}
+
 type objectChecker struct {
 	lock      sync.Mutex
 	isRunning bool
 	stopCh    chan struct{}
 	status    objectStatus
 	err       error
+	// replicas is the desired number of pods of the object.
+	replicas int
 	// key of the object being checked. In the current implementation it's a namespaced name, but it
 	// may change in the future.
 	key string
@@ -498,3 +663,27 @@ func (o *objectChecker) terminate(hasTimedOut bool) {
 		}
 	}
 }
+
+// getReplicas returns the desired number of pods of the object. For kinds other than built-in
+// controllers, it's the value of the replicas path.
+func (w *waitForControlledPodsRunningMeasurement) getReplicas(obj runtime.Object) (int32, error) {
+	if obj == nil {
+		return 0, nil
+	}
+	if runtimeobjects.HasPodSelector(w.kind) {
+		return runtimeobjects.GetReplicasFromRuntimeObject(w.clusterFramework.GetClientSets().GetClient(), obj)
+	}
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return 0, fmt.Errorf("unsupported object type %T", obj)
+	}
+	buf := &bytes.Buffer{}
+	if err := w.replicasPath.Execute(buf, unstructuredObj.Object); err != nil {
+		return 0, fmt.Errorf("evaluating replicas path error: %v", err)
+	}
+	replicas, err := strconv.ParseInt(buf.String(), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parsing replicas %q error: %v", buf.String(), err)
+	}
+	return int32(replicas), nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	defaultWaitForGenericObjectsTimeout  = 60 * time.Second
+	defaultWaitForGenericObjectsInterval = 5 * time.Second
+	waitForGenericObjectsMeasurementName = "WaitForGenericObjects"
+)
+
+func init() {
+	if err := measurement.Register(waitForGenericObjectsMeasurementName, createWaitForGenericObjectsMeasurement); err != nil {
+		logrus.Fatalf("Cannot register %s: %v", waitForGenericObjectsMeasurementName, err)
+	}
+}
+
+func createWaitForGenericObjectsMeasurement() measurement.Measurement {
+	return &waitForGenericObjectsMeasurement{}
+}
+
+type waitForGenericObjectsMeasurement struct{}
+
+// Execute waits until desired number of objects of given kind are ready or until timeout happens.
+// Readiness of an object is verified by comparing value of readyPath JSONPath with readyValue,
+// by default the status of the Ready condition is expected to be True.
+// Objects can be specified by field and/or label selectors.
+// If namespace is not passed by parameter, all-namespace scope is assumed.
+func (w *waitForGenericObjectsMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	apiVersion, err := util.GetString(config.Params, "apiVersion")
+	if err != nil {
+		return nil, err
+	}
+	kind, err := util.GetString(config.Params, "kind")
+	if err != nil {
+		return nil, err
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gv.WithKind(kind))
+	desiredObjectCount, err := util.GetInt(config.Params, "desiredObjectCount")
+	if err != nil {
+		return nil, err
+	}
+	selector := measurementutil.NewObjectSelector()
+	if err := selector.Parse(config.Params); err != nil {
+		return nil, err
+	}
+	readyPath, err := util.GetStringOrDefault(config.Params, "readyPath", measurementutil.DefaultReadyPath)
+	if err != nil {
+		return nil, err
+	}
+	readyValue, err := util.GetStringOrDefault(config.Params, "readyValue", measurementutil.DefaultReadyValue)
+	if err != nil {
+		return nil, err
+	}
+	readinessCheck, err := measurementutil.NewReadinessCheck(readyPath, readyValue)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := util.GetDurationOrDefault(config.Params, "timeout", defaultWaitForGenericObjectsTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	stopCh := make(chan struct{})
+	time.AfterFunc(timeout, func() {
+		close(stopCh)
+	})
+	options := &measurementutil.WaitForGenericObjectsOptions{
+		GroupVersionResource: gvr,
+		Selector:             selector,
+		ReadinessCheck:       readinessCheck,
+		DesiredObjectCount:   desiredObjectCount,
+		EnableLogging:        true,
+		CallerName:           w.String(),
+		WaitInterval:         defaultWaitForGenericObjectsInterval,
+	}
+	return nil, measurementutil.WaitForGenericObjects(config.ClusterFramework.GetDynamicClients().GetClient(), stopCh, options)
+}
+
+// Dispose cleans up after the measurement.
+func (*waitForGenericObjectsMeasurement) Dispose() {}
+
+// String returns a string representation of the measurement.
+func (*waitForGenericObjectsMeasurement) String() string {
+	return waitForGenericObjectsMeasurementName
+}
+
+// Description returns a short description of the measurement.
+func (*waitForGenericObjectsMeasurement) Description() string {
+	return "Waits until the desired number of objects of given kind are ready."
+}
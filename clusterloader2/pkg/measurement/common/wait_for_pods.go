@@ -17,6 +17,7 @@ limitations under the License.
 package common
 
 import (
+	"context"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -60,18 +61,33 @@ func (w *waitForRunningPodsMeasurement) Execute(config *measurement.MeasurementC
 		return nil, err
 	}
 
-	stopCh := make(chan struct{})
-	time.AfterFunc(timeout, func() {
-		close(stopCh)
-	})
+	stuckPodTimeout, err := util.GetDurationOrDefault(config.Params, "stuckPodTimeout", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Waiting stops after timeout or once the call is cancelled.
+	ctx, cancel := context.WithTimeout(config.GetContext(), timeout)
+	defer cancel()
 	options := &measurementutil.WaitForPodOptions{
 		Selector:            selector,
 		DesiredPodCount:     desiredPodCount,
 		EnableLogging:       true,
 		CallerName:          w.String(),
 		WaitForPodsInterval: defaultWaitForPodsInterval,
+		StuckPodTimeout:     stuckPodTimeout,
 	}
-	return nil, measurementutil.WaitForPods(config.ClusterFramework.GetClientSets().GetClient(), stopCh, options)
+	return nil, measurementutil.WaitForPods(config.ClusterFramework.GetPodStores(), ctx.Done(), options)
+}
+
+// DeclaredParams returns params accepted by the measurement.
+func (*waitForRunningPodsMeasurement) DeclaredParams() []measurement.Param {
+	params := []measurement.Param{
+		{Name: "desiredPodCount", Type: measurement.IntParam, Required: true, Description: "number of running pods to wait for"},
+		{Name: "timeout", Type: measurement.DurationParam, Default: defaultWaitForPodsTimeout.String(), Description: "timeout of waiting"},
+		{Name: "stuckPodTimeout", Type: measurement.DurationParam, Description: "time after which a pending pod is reported as stuck, disabled if zero"},
+	}
+	return append(params, measurement.SelectorParams()...)
 }
 
 // Dispose cleans up after the measurement.
@@ -81,3 +97,8 @@ func (*waitForRunningPodsMeasurement) Dispose() {}
 func (*waitForRunningPodsMeasurement) String() string {
 	return waitForRunningPodsMeasurementName
 }
+
+// Description returns a short description of the measurement.
+func (*waitForRunningPodsMeasurement) Description() string {
+	return "Waits until the desired number of pods are running."
+}
@@ -81,3 +81,8 @@ func (*waitForBoundPVCsMeasurement) Dispose() {}
 func (*waitForBoundPVCsMeasurement) String() string {
 	return waitForBoundPVCsMeasurementName
 }
+
+// Description returns a short description of the measurement.
+func (*waitForBoundPVCsMeasurement) Description() string {
+	return "Waits until the desired number of PVCs are bound."
+}
@@ -80,3 +80,8 @@ func (*waitForAvailablePVsMeasurement) Dispose() {}
 func (*waitForAvailablePVsMeasurement) String() string {
 	return waitForAvailablePVsMeasurementName
 }
+
+// Description returns a short description of the measurement.
+func (*waitForAvailablePVsMeasurement) Description() string {
+	return "Waits until the desired number of PVs are Available."
+}
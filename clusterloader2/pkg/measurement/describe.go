@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// TextFormat lists measurements in human readable plain text.
+	TextFormat = "text"
+	// MarkdownFormat lists measurements as markdown, e.g. for docs.
+	MarkdownFormat = "markdown"
+	// JSONFormat lists measurements as json.
+	JSONFormat = "json"
+)
+
+// Describer is implemented by measurements describing what they measure.
+type Describer interface {
+	Description() string
+}
+
+// MethodInfo describes a registered measurement method.
+type MethodInfo struct {
+	Method      string `json:"method"`
+	Description string `json:"description,omitempty"`
+	// Actions are values of action param, empty if calls of the measurement have no action
+	// or if its params aren't declared.
+	Actions []string `json:"actions,omitempty"`
+	// ParamsDeclared is set if the measurement implements ParamsDeclarer.
+	ParamsDeclared bool    `json:"paramsDeclared"`
+	Params         []Param `json:"params,omitempty"`
+}
+
+// GetRegisteredMethods returns sorted names of all registered measurement methods.
+func GetRegisteredMethods() []string {
+	return factory.getMethods()
+}
+
+func (mc *measurementFactory) getMethods() []string {
+	mc.lock.RLock()
+	defer mc.lock.RUnlock()
+	methods := make([]string, 0, len(mc.createFuncs))
+	for method := range mc.createFuncs {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// DescribeMethods returns descriptions of all registered measurement methods, sorted by method name.
+// Measurements are only created, so no cluster access is required.
+func DescribeMethods() ([]MethodInfo, error) {
+	var infos []MethodInfo
+	for _, method := range factory.getMethods() {
+		instance, err := factory.createMeasurement(method)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, describe(method, instance))
+	}
+	return infos, nil
+}
+
+func describe(method string, instance Measurement) MethodInfo {
+	info := MethodInfo{Method: method}
+	if describer, ok := instance.(Describer); ok {
+		info.Description = describer.Description()
+	}
+	if info.Params = getDeclaredParams(instance); info.Params != nil {
+		info.ParamsDeclared = true
+		for _, param := range info.Params {
+			if param.Name == "action" {
+				info.Actions = param.Values
+			}
+		}
+	}
+	return info
+}
+
+// WriteMethods writes descriptions of measurement methods in given format.
+func WriteMethods(w io.Writer, infos []MethodInfo, format string) error {
+	switch format {
+	case TextFormat:
+		return writeMethodsText(w, infos)
+	case MarkdownFormat:
+		return writeMethodsMarkdown(w, infos)
+	case JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	default:
+		return fmt.Errorf("unknown format %q, supported: %s, %s, %s", format, TextFormat, MarkdownFormat, JSONFormat)
+	}
+}
+
+func writeMethodsText(w io.Writer, infos []MethodInfo) error {
+	var b strings.Builder
+	for _, info := range infos {
+		fmt.Fprintf(&b, "%s\n", info.Method)
+		if info.Description != "" {
+			fmt.Fprintf(&b, "  %s\n", info.Description)
+		}
+		if !info.ParamsDeclared {
+			fmt.Fprintf(&b, "  Params: not declared\n\n")
+			continue
+		}
+		if len(info.Actions) > 0 {
+			fmt.Fprintf(&b, "  Actions: %s\n", strings.Join(info.Actions, ", "))
+		}
+		fmt.Fprintf(&b, "  Params:\n")
+		for _, param := range info.Params {
+			fmt.Fprintf(&b, "    %s (%s%s)", param.Name, param.Type, paramAttributes(param))
+			if param.Description != "" {
+				fmt.Fprintf(&b, ": %s", param.Description)
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMethodsMarkdown(w io.Writer, infos []MethodInfo) error {
+	var b strings.Builder
+	for _, info := range infos {
+		fmt.Fprintf(&b, "### %s\n\n", info.Method)
+		if info.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", info.Description)
+		}
+		if !info.ParamsDeclared {
+			fmt.Fprintf(&b, "Params are not declared.\n\n")
+			continue
+		}
+		if len(info.Actions) > 0 {
+			fmt.Fprintf(&b, "Actions: `%s`\n\n", strings.Join(info.Actions, "`, `"))
+		}
+		fmt.Fprintf(&b, "| Param | Type | Required | Default | Actions | Description |\n")
+		fmt.Fprintf(&b, "|---|---|---|---|---|---|\n")
+		for _, param := range info.Params {
+			var defaultValue string
+			if param.Default != nil {
+				defaultValue = fmt.Sprintf("`%v`", param.Default)
+			}
+			description := param.Description
+			if len(param.Values) > 0 {
+				description = strings.TrimSpace(fmt.Sprintf("%s (one of `%s`)", description, strings.Join(param.Values, "`, `")))
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %t | %s | %s | %s |\n", param.Name, param.Type, param.Required, defaultValue,
+				strings.Join(param.Actions, ", "), strings.Replace(description, "|", `\|`, -1))
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// paramAttributes returns attributes of the param besides its type, e.g. ", required, default: 5s".
+func paramAttributes(param Param) string {
+	var attributes []string
+	if param.Required {
+		attributes = append(attributes, "required")
+	}
+	if param.Default != nil {
+		attributes = append(attributes, fmt.Sprintf("default: %v", param.Default))
+	}
+	if len(param.Values) > 0 {
+		attributes = append(attributes, fmt.Sprintf("one of: %s", strings.Join(param.Values, ", ")))
+	}
+	if len(param.Actions) > 0 {
+		attributes = append(attributes, fmt.Sprintf("actions: %s", strings.Join(param.Actions, ", ")))
+	}
+	if len(attributes) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(attributes, ", ")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type describedMeasurement struct{}
+
+func (*describedMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) { return nil, nil }
+func (*describedMeasurement) Dispose()                                             {}
+func (*describedMeasurement) String() string                                       { return "Described" }
+func (*describedMeasurement) Description() string                                  { return "Measures something." }
+func (*describedMeasurement) DeclaredParams() []Param {
+	return []Param{
+		ActionParam("start", "gather"),
+		{Name: "threshold", Type: DurationParam, Default: "5s", Actions: []string{"gather"}, Description: "maximal latency"},
+	}
+}
+
+type undescribedMeasurement struct{}
+
+func (*undescribedMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) { return nil, nil }
+func (*undescribedMeasurement) Dispose()                                             {}
+func (*undescribedMeasurement) String() string                                       { return "Undescribed" }
+
+func TestWriteMethods(t *testing.T) {
+	infos := []MethodInfo{
+		describe("Described", &describedMeasurement{}),
+		describe("Undescribed", &undescribedMeasurement{}),
+	}
+	assert.Equal(t, []string{"start", "gather"}, infos[0].Actions)
+	assert.False(t, infos[1].ParamsDeclared)
+
+	testCases := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: TextFormat,
+			want: []string{
+				"Described\n  Measures something.\n  Actions: start, gather\n",
+				"    threshold (duration, default: 5s, actions: gather): maximal latency\n",
+				"Undescribed\n  Params: not declared\n",
+			},
+		},
+		{
+			format: MarkdownFormat,
+			want: []string{
+				"### Described\n\nMeasures something.\n\nActions: `start`, `gather`\n",
+				"| `action` | string | true |  |  | action of the call (one of `start`, `gather`) |\n",
+				"| `threshold` | duration | false | `5s` | gather | maximal latency |\n",
+				"### Undescribed\n\nParams are not declared.\n",
+			},
+		},
+		{
+			format: JSONFormat,
+			want:   []string{`"method": "Described"`, `"name": "threshold"`, `"paramsDeclared": false`},
+		},
+	}
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		if err := WriteMethods(&b, infos, tc.format); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		for _, want := range tc.want {
+			assert.Contains(t, b.String(), want, tc.format)
+		}
+	}
+	assert.Error(t, WriteMethods(&bytes.Buffer{}, infos, "yaml"))
+}
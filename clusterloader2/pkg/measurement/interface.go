@@ -17,10 +17,12 @@ limitations under the License.
 package measurement
 
 import (
+	"context"
 	"time"
 
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
 )
 
 // MeasurementConfig provides client and parameters required for the measurement execution.
@@ -39,6 +41,56 @@ type MeasurementConfig struct {
 	// Identifier identifies this instance of measurement.
 	Identifier    string
 	CloudProvider string
+	// FailTest reports an error detected in background, e.g. an SLO violation,
+	// that should fail the test without waiting for its end.
+	FailTest func(err error)
+	// NotifyViolation notifies about an SLO violation detected in background, e.g. by continuous
+	// evaluation, as soon as it's detected. It doesn't fail the test. It is nil if the measurement
+	// isn't executed within a test.
+	NotifyViolation func(err error)
+	// QueryExecutors are Prometheus query executors shared by measurements of the test.
+	// It is nil if the measurement isn't executed within a test.
+	QueryExecutors *measurementutil.BatchQueryExecutors
+	// Markers contains named points in time recorded so far by marker steps.
+	Markers *Markers
+	// APICalls notifies registered observers about object operations of the test executor.
+	// It is nil if the measurement isn't executed within a test, e.g. during backfill.
+	APICalls *APICallObservers
+	// HasViolations reports whether any measurement of the test has detected an SLO violation so far.
+	// It is nil if the measurement isn't executed within a test.
+	HasViolations func() bool
+	// ResumedStartTime is set if the call repeats the start call made at that time by an interrupted
+	// run of the test, see GetStartTime.
+	ResumedStartTime time.Time
+	// Context is cancelled once the call of the measurement times out, see callTimeout param,
+	// or the test is cancelled. Long running calls should stop once it's done. Use GetContext,
+	// as it can be nil.
+	Context context.Context
+}
+
+// GetContext returns the context of the call of the measurement.
+func (c *MeasurementConfig) GetContext() context.Context {
+	if c.Context == nil {
+		return context.Background()
+	}
+	return c.Context
+}
+
+// GetStartTime returns the time the measurement started by the call is considered started at:
+// the time of the original start call if the call resumes the measurement, the current time otherwise.
+func (c *MeasurementConfig) GetStartTime() time.Time {
+	if !c.ResumedStartTime.IsZero() {
+		return c.ResumedStartTime
+	}
+	return time.Now()
+}
+
+// GetPrometheusConfig returns prometheus config of the test or nil if the test config is unknown.
+func (c *MeasurementConfig) GetPrometheusConfig() *config.PrometheusConfig {
+	if c.ClusterLoaderConfig == nil {
+		return nil
+	}
+	return &c.ClusterLoaderConfig.PrometheusConfig
 }
 
 // Measurement is an common interface for all measurements methods. It should be implemented by the user to
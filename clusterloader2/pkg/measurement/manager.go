@@ -17,75 +17,599 @@ limitations under the License.
 package measurement
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
+	"k8s.io/perf-tests/clusterloader2/pkg/selfmetrics"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
+const skippedMeasurementsName = "SkippedMeasurements"
+
+// partialSuffix is appended to names of summaries of measurements resumed without data collected
+// by the interrupted run, so that they are not mistaken for results covering the whole test.
+const partialSuffix = "Partial"
+
+// abandonedCallsJoinTimeout is how long summaries and disposal of measurements wait for calls
+// abandoned after their timeout. Contexts of such calls are cancelled, so they should return soon.
+const abandonedCallsJoinTimeout = time.Minute
+
 // MeasurementManager manages all measurement executions.
 type MeasurementManager struct {
+	// ctx is the context of the test, contexts of all calls of measurements are derived from it.
+	ctx                 context.Context
 	clusterFramework    *framework.Framework
 	clusterLoaderConfig *config.ClusterLoaderConfig
 	prometheusFramework *framework.Framework
 	templateProvider    *config.TemplateProvider
+	annotator           *prometheus.GrafanaAnnotator
+	// clusterFrameworks are frameworks of additional clusters by their names.
+	clusterFrameworks map[string]*framework.Framework
 
 	lock sync.Mutex
 	// map from method type and identifier to measurement instance.
 	measurements map[string]map[string]Measurement
-	summaries    []Summary
+	// instanceClusters maps method type and identifier to the cluster the measurement instance is executed against.
+	instanceClusters map[string]string
+	summaries        []Summary
+	// skipped contains measurements skipped because of missing capabilities, keyed by method and identifier.
+	skipped map[string]*SkippedMeasurement
+	// failures contains errors reported by measurements in background.
+	failures *errors.ErrorList
+	markers  *Markers
+	apiCalls *APICallObservers
+	// results contains outcomes of measurements, in order of their first call.
+	results []*MeasurementResult
+	// periodicGathers are periodic gathers of measurement instances, keyed by method and identifier.
+	periodicGathers map[string]*periodicGather
+	// queryExecutors are Prometheus query executors shared by measurements of the test.
+	queryExecutors   *measurementutil.BatchQueryExecutors
+	checkpointWriter func(summaries []Summary) error
+	// started are measurement instances started, but not gathered yet, keyed by method and identifier.
+	started map[string]*startedMeasurement
+	// violationNotifier is notified about SLO violations detected by measurements in background.
+	violationNotifier func(methodName, identifier string, err error)
+	// abandonedCalls are calls that didn't finish within their timeout, keyed by method and identifier.
+	// Channels are closed once the calls return.
+	abandonedCalls map[string]chan struct{}
+	// partial are measurement instances resumed without data collected by the interrupted run,
+	// keyed by method and identifier, see Resumer.
+	partial map[string]bool
+}
+
+// Resumer is implemented by measurements that can be resumed by a run continuing the run that
+// started them, because their results don't depend on data collected in memory since the start,
+// e.g. measurements computing their results from data stored by Prometheus.
+type Resumer interface {
+	// IsResumable returns true if the measurement started with given config can be resumed.
+	IsResumable(config *MeasurementConfig) bool
+}
+
+// startedMeasurement is a measurement instance together with its start call.
+type startedMeasurement struct {
+	instance Measurement
+	config   *MeasurementConfig
+	call     StartedMeasurement
+}
+
+// StartedMeasurement describes the start call of a measurement that wasn't gathered yet.
+type StartedMeasurement struct {
+	Method     string                 `json:"method"`
+	Identifier string                 `json:"identifier"`
+	Cluster    string                 `json:"cluster,omitempty"`
+	Params     map[string]interface{} `json:"params"`
+	StartTime  time.Time              `json:"startTime"`
+}
+
+// MeasurementResult is an outcome of all calls of a single measurement instance.
+type MeasurementResult struct {
+	Method     string
+	Identifier string
+	// Duration is the total time spent in measurement calls.
+	Duration time.Duration
+	// Errors contains errors returned by measurement calls or reported in background, other than SLO violations.
+	Errors []error
+	// Violations contains SLO violations returned by measurement calls.
+	Violations []error
+	// SkipReason is set if the measurement was skipped because of missing capabilities.
+	SkipReason string
 }
 
 // CreateMeasurementManager creates new instance of MeasurementManager.
-func CreateMeasurementManager(clusterFramework, prometheusFramework *framework.Framework,
-	templateProvider *config.TemplateProvider, config *config.ClusterLoaderConfig) *MeasurementManager {
+// Once ctx is done, calls of measurements are cancelled.
+// Cluster frameworks are frameworks of additional clusters by their names, they can be nil.
+// Annotator is used to annotate violations, it can be nil.
+func CreateMeasurementManager(ctx context.Context, clusterFramework, prometheusFramework *framework.Framework, clusterFrameworks map[string]*framework.Framework,
+	templateProvider *config.TemplateProvider, config *config.ClusterLoaderConfig, annotator *prometheus.GrafanaAnnotator) *MeasurementManager {
 	return &MeasurementManager{
+		ctx:                 ctx,
 		clusterFramework:    clusterFramework,
 		clusterLoaderConfig: config,
 		prometheusFramework: prometheusFramework,
 		templateProvider:    templateProvider,
+		annotator:           annotator,
+		clusterFrameworks:   clusterFrameworks,
 		measurements:        make(map[string]map[string]Measurement),
+		instanceClusters:    make(map[string]string),
 		summaries:           make([]Summary, 0),
+		skipped:             make(map[string]*SkippedMeasurement),
+		failures:            errors.NewErrorList(),
+		markers:             NewMarkers(),
+		apiCalls:            NewAPICallObservers(),
+		periodicGathers:     make(map[string]*periodicGather),
+		started:             make(map[string]*startedMeasurement),
+		queryExecutors:      measurementutil.NewBatchQueryExecutors(),
+		abandonedCalls:      make(map[string]chan struct{}),
+		partial:             make(map[string]bool),
 	}
 }
 
 // Execute executes measurement based on provided identifier, methodName and params.
 func (mm *MeasurementManager) Execute(methodName string, identifier string, params map[string]interface{}) error {
-	measurementInstance, err := mm.getMeasurementInstance(methodName, identifier)
+	return mm.ExecuteOnCluster("", methodName, identifier, params)
+}
+
+// ExecuteOnCluster executes measurement against the named cluster. Empty name means the tested cluster.
+// All calls of a measurement instance (i.e. with the same methodName and identifier) have to target the same cluster.
+func (mm *MeasurementManager) ExecuteOnCluster(cluster string, methodName string, identifier string, params map[string]interface{}) error {
+	return mm.executeOnCluster(cluster, methodName, identifier, params, time.Time{})
+}
+
+// ResumeMeasurement repeats the start call of the measurement started by an interrupted run of the test,
+// so that the measurement can be gathered by the resumed test. Resumable measurements (see Resumer) take
+// the original start time into account. Other measurements are started again and their summaries are
+// named with partialSuffix, as they don't cover the interrupted part of the test.
+func (mm *MeasurementManager) ResumeMeasurement(started StartedMeasurement) error {
+	instance, err := mm.getMeasurementInstance(started.Method, started.Identifier, started.Cluster)
 	if err != nil {
 		return err
 	}
-	config := &MeasurementConfig{
-		ClusterFramework:    mm.clusterFramework,
+	config := mm.createConfig(mm.clusterFramework, started.Method, started.Identifier, started.Params)
+	if resumer, ok := instance.(Resumer); !ok || !resumer.IsResumable(config) {
+		logrus.Warningf("%s (%s): started at %v, but can't be resumed, starting again - its results will be partial",
+			started.Method, started.Identifier, started.StartTime)
+		mm.lock.Lock()
+		mm.partial[started.Method+"/"+started.Identifier] = true
+		mm.lock.Unlock()
+		return mm.executeOnCluster(started.Cluster, started.Method, started.Identifier, started.Params, time.Time{})
+	}
+	logrus.Infof("%s (%s): resuming measurement started at %v", started.Method, started.Identifier, started.StartTime)
+	return mm.executeOnCluster(started.Cluster, started.Method, started.Identifier, started.Params, started.StartTime)
+}
+
+// GetStartedMeasurements returns start calls of measurements that were started, but not gathered yet,
+// sorted by method and identifier.
+func (mm *MeasurementManager) GetStartedMeasurements() []StartedMeasurement {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	started := make([]StartedMeasurement, 0, len(mm.started))
+	for _, s := range mm.started {
+		started = append(started, s.call)
+	}
+	sort.Slice(started, func(i, j int) bool {
+		if started[i].Method != started[j].Method {
+			return started[i].Method < started[j].Method
+		}
+		return started[i].Identifier < started[j].Identifier
+	})
+	return started
+}
+
+func (mm *MeasurementManager) executeOnCluster(cluster string, methodName string, identifier string, params map[string]interface{}, resumedStartTime time.Time) error {
+	clusterFramework := mm.clusterFramework
+	if cluster != "" {
+		var ok bool
+		if clusterFramework, ok = mm.clusterFrameworks[cluster]; !ok {
+			return fmt.Errorf("unknown cluster %q", cluster)
+		}
+	}
+	measurementInstance, err := mm.getMeasurementInstance(methodName, identifier, cluster)
+	if err != nil {
+		return err
+	}
+	config := mm.createConfig(clusterFramework, methodName, identifier, params)
+	config.ResumedStartTime = resumedStartTime
+	if requirer, ok := measurementInstance.(CapabilityRequirer); ok {
+		if reason := MissingCapability(config, requirer.RequiredCapabilities(config)); reason != "" {
+			mm.recordSkipped(methodName, identifier, reason)
+			return nil
+		}
+	}
+	key := methodName + "/" + identifier
+	gatherInterval, err := getGatherInterval(params)
+	if err != nil {
+		return err
+	}
+	action, _ := util.GetStringOrDefault(params, "action", "")
+	if action == "gather" {
+		mm.stopPeriodicGather(key)
+	}
+	timeout, err := mm.getCallTimeout(params)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	summaries, err := mm.execute(methodName, key, measurementInstance, config, timeout)
+	mm.recordResult(methodName, identifier, time.Since(start), err)
+	mm.lock.Lock()
+	if mm.partial[key] {
+		summaries = partialSummaries(summaries)
+	}
+	mm.summaries = append(mm.summaries, summaries...)
+	switch {
+	case action == "start" && err == nil:
+		call := StartedMeasurement{Method: methodName, Identifier: identifier, Cluster: cluster, Params: params, StartTime: start}
+		if !resumedStartTime.IsZero() {
+			call.StartTime = resumedStartTime
+		}
+		mm.started[key] = &startedMeasurement{instance: measurementInstance, config: config, call: call}
+	case action == "gather":
+		delete(mm.started, key)
+	}
+	mm.lock.Unlock()
+	if err == nil && gatherInterval > 0 {
+		err = mm.startPeriodicGather(key, measurementInstance, config, gatherInterval)
+	}
+	if errors.IsMetricViolationError(err) {
+		mm.annotator.Annotate(time.Now(), fmt.Sprintf("%s (%s) violation: %v", methodName, identifier, err), "violation")
+	}
+	return err
+}
+
+// getCallTimeout returns callTimeout param or, for gather calls, the default gather timeout.
+func (mm *MeasurementManager) getCallTimeout(params map[string]interface{}) (time.Duration, error) {
+	defaultTimeout := time.Duration(0)
+	if action, _ := util.GetStringOrDefault(params, "action", ""); action == "gather" && mm.clusterLoaderConfig != nil {
+		defaultTimeout = mm.clusterLoaderConfig.MeasurementGatherTimeout
+	}
+	return util.GetDurationOrDefault(params, "callTimeout", defaultTimeout)
+}
+
+// execute calls the measurement, giving up after timeout if it's positive. The context of the call
+// is cancelled then, so that measurements respecting it stop. If the call returns after the timeout,
+// its summaries are still recorded, so that partial results are not lost. Calls of the same measurement
+// instance are not run concurrently - a call waits for the abandoned one to return first.
+func (mm *MeasurementManager) execute(methodName, key string, instance Measurement, config *MeasurementConfig, timeout time.Duration) ([]Summary, error) {
+	ctx := config.GetContext()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		config.Context = ctx
+	}
+	mm.lock.Lock()
+	abandoned := mm.abandonedCalls[key]
+	mm.lock.Unlock()
+	if abandoned != nil {
+		logrus.Infof("%s: waiting for the previous call abandoned after its timeout", key)
+		select {
+		case <-abandoned:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: previous call abandoned after its timeout didn't return: %v", key, ctx.Err())
+		}
+	}
+
+	call := func() ([]Summary, error) {
+		action, _ := util.GetStringOrDefault(config.Params, "action", "")
+		defer selfmetrics.MeasurementCallStarted(methodName, action)()
+		if p := mm.getPeriodicGather(key); p != nil {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+		}
+		return instance.Execute(config)
+	}
+	if timeout <= 0 {
+		return call()
+	}
+
+	type result struct {
+		summaries []Summary
+		err       error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		summaries, err := call()
+		resultCh <- result{summaries: summaries, err: err}
+	}()
+	select {
+	case r := <-resultCh:
+		return r.summaries, r.err
+	case <-ctx.Done():
+		done := make(chan struct{})
+		mm.lock.Lock()
+		mm.abandonedCalls[key] = done
+		mm.lock.Unlock()
+		go func() {
+			r := <-resultCh
+			logrus.Warningf("%s: call returned after the timeout, error: %v", key, r.err)
+			mm.lock.Lock()
+			mm.summaries = append(mm.summaries, r.summaries...)
+			delete(mm.abandonedCalls, key)
+			mm.lock.Unlock()
+			close(done)
+		}()
+		return nil, fmt.Errorf("%s: call didn't finish within %v: %v", key, timeout, ctx.Err())
+	}
+}
+
+// RequiredRecordingRules returns Prometheus recording rules required by the measurement
+// called with given params. Measurements that would be skipped because of missing capabilities
+// don't require any rules.
+func (mm *MeasurementManager) RequiredRecordingRules(methodName string, identifier string, params map[string]interface{}) ([]prometheus.RecordingRule, error) {
+	measurementInstance, err := factory.createMeasurement(methodName)
+	if err != nil {
+		return nil, err
+	}
+	requirer, ok := measurementInstance.(RecordingRuleRequirer)
+	if !ok {
+		return nil, nil
+	}
+	config := mm.createConfig(mm.clusterFramework, methodName, identifier, params)
+	if capabilityRequirer, ok := measurementInstance.(CapabilityRequirer); ok {
+		if MissingCapability(config, capabilityRequirer.RequiredCapabilities(config)) != "" {
+			return nil, nil
+		}
+	}
+	return requirer.RequiredRecordingRules(config), nil
+}
+
+func (mm *MeasurementManager) createConfig(clusterFramework *framework.Framework, methodName string, identifier string, params map[string]interface{}) *MeasurementConfig {
+	return &MeasurementConfig{
+		ClusterFramework:    clusterFramework,
 		PrometheusFramework: mm.prometheusFramework,
 		Params:              params,
 		TemplateProvider:    mm.templateProvider,
 		Identifier:          identifier,
 		CloudProvider:       mm.clusterLoaderConfig.ClusterConfig.Provider,
 		ClusterLoaderConfig: mm.clusterLoaderConfig,
+		FailTest:            mm.failFunc(methodName, identifier),
+		NotifyViolation:     mm.notifyFunc(methodName, identifier),
+		QueryExecutors:      mm.queryExecutors,
+		Markers:             mm.markers,
+		APICalls:            mm.apiCalls,
+		HasViolations:       mm.hasViolations,
+		Context:             mm.ctx,
+	}
+}
+
+// failFunc returns function failing the test because of an error reported by given measurement.
+func (mm *MeasurementManager) failFunc(methodName, identifier string) func(err error) {
+	return func(err error) {
+		mm.recordResult(methodName, identifier, 0, err)
+		mm.annotator.Annotate(time.Now(), err.Error(), "violation")
+		mm.failures.Append(err)
+	}
+}
+
+// SetViolationNotifier sets function notified about SLO violations detected by measurements
+// in background, see MeasurementConfig.NotifyViolation.
+func (mm *MeasurementManager) SetViolationNotifier(notify func(methodName, identifier string, err error)) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	mm.violationNotifier = notify
+}
+
+// notifyFunc returns function notifying about an SLO violation detected by given measurement.
+func (mm *MeasurementManager) notifyFunc(methodName, identifier string) func(err error) {
+	return func(err error) {
+		mm.lock.Lock()
+		notify := mm.violationNotifier
+		mm.lock.Unlock()
+		if notify != nil {
+			notify(methodName, identifier, err)
+		}
 	}
-	summaries, err := measurementInstance.Execute(config)
-	mm.summaries = append(mm.summaries, summaries...)
-	return err
 }
 
-// GetSummaries returns collected summaries.
+// GetSummaries returns collected summaries. If any marker was recorded, Markers summary
+// is included. If any measurement was skipped, SkippedMeasurements summary listing reasons
+// is included as well.
 func (mm *MeasurementManager) GetSummaries() []Summary {
-	return mm.summaries
+	mm.joinAbandonedCalls()
+	mm.lock.Lock()
+	summaries := mm.summaries[:len(mm.summaries):len(mm.summaries)]
+	skipped := make([]*SkippedMeasurement, 0, len(mm.skipped))
+	for _, s := range mm.skipped {
+		skipped = append(skipped, s)
+	}
+	mm.lock.Unlock()
+	if markers := mm.markers.List(); len(markers) > 0 {
+		content, err := util.PrettyPrintJSON(markers)
+		if err != nil {
+			logrus.Errorf("Printing markers error: %v", err)
+		} else {
+			summaries = append(summaries, CreateSummary(markersName, "json", content))
+		}
+	}
+	if len(skipped) == 0 {
+		return summaries
+	}
+	sort.Slice(skipped, func(i, j int) bool {
+		if skipped[i].Method != skipped[j].Method {
+			return skipped[i].Method < skipped[j].Method
+		}
+		return skipped[i].Identifier < skipped[j].Identifier
+	})
+	content, err := util.PrettyPrintJSON(skipped)
+	if err != nil {
+		logrus.Errorf("Printing skipped measurements error: %v", err)
+		return summaries
+	}
+	return append(summaries, CreateSummary(skippedMeasurementsName, "json", content))
+}
+
+// GetMarkers returns markers recorded during the test.
+func (mm *MeasurementManager) GetMarkers() *Markers {
+	return mm.markers
+}
+
+// GetAPICallObservers returns observers of API calls performed by the test executor.
+func (mm *MeasurementManager) GetAPICallObservers() *APICallObservers {
+	return mm.apiCalls
+}
+
+func (mm *MeasurementManager) recordSkipped(methodName, identifier, reason string) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	key := methodName + "/" + identifier
+	if _, exists := mm.skipped[key]; !exists {
+		logrus.Warningf("%s (%s): skipped because of %s", methodName, identifier, reason)
+		mm.skipped[key] = &SkippedMeasurement{Method: methodName, Identifier: identifier, Reason: reason}
+		mm.getResult(methodName, identifier).SkipReason = reason
+	}
+}
+
+func (mm *MeasurementManager) recordResult(methodName, identifier string, duration time.Duration, err error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	result := mm.getResult(methodName, identifier)
+	result.Duration += duration
+	switch {
+	case err == nil:
+	case errors.IsMetricViolationError(err):
+		result.Violations = append(result.Violations, errors.GetMetricViolations(err)...)
+	default:
+		result.Errors = append(result.Errors, err)
+	}
+}
+
+// hasViolations returns true if any measurement has returned an SLO violation or reported it in background.
+func (mm *MeasurementManager) hasViolations() bool {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	for _, result := range mm.results {
+		if len(result.Violations) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getResult returns result of the measurement, creating it if needed. Lock has to be held.
+func (mm *MeasurementManager) getResult(methodName, identifier string) *MeasurementResult {
+	for _, result := range mm.results {
+		if result.Method == methodName && result.Identifier == identifier {
+			return result
+		}
+	}
+	result := &MeasurementResult{Method: methodName, Identifier: identifier}
+	mm.results = append(mm.results, result)
+	return result
 }
 
-// Dispose disposes measurement instances.
+// GetResults returns outcomes of all measurements called so far, in order of their first call.
+func (mm *MeasurementManager) GetResults() []MeasurementResult {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	results := make([]MeasurementResult, 0, len(mm.results))
+	for _, result := range mm.results {
+		results = append(results, *result)
+	}
+	return results
+}
+
+// GetFailures returns errors reported by measurements in background
+// that should fail the test as soon as possible.
+func (mm *MeasurementManager) GetFailures() *errors.ErrorList {
+	return mm.failures
+}
+
+// GatherPartialSummaries gathers summaries of data collected so far by measurements that were
+// started, but not gathered, e.g. because the test was cancelled. They are gathered as checkpoints,
+// measurements that don't support checkpoints are not gathered.
+func (mm *MeasurementManager) GatherPartialSummaries() {
+	mm.lock.Lock()
+	started := mm.started
+	mm.started = make(map[string]*startedMeasurement)
+	mm.lock.Unlock()
+	keys := make([]string, 0, len(started))
+	for key := range started {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		mm.stopPeriodicGather(key)
+		checkpointer, ok := started[key].instance.(Checkpointer)
+		if !ok {
+			logrus.Warningf("%s: not gathered, partial results are not supported", key)
+			continue
+		}
+		logrus.Infof("%s: gathering partial results", key)
+		summaries := checkpoint(checkpointer, started[key].config)
+		mm.lock.Lock()
+		mm.summaries = append(mm.summaries, summaries...)
+		mm.lock.Unlock()
+	}
+}
+
+// Dispose disposes measurement instances. Instances with calls still running are not disposed.
 func (mm *MeasurementManager) Dispose() {
-	for _, instances := range mm.measurements {
-		for _, measurement := range instances {
+	for _, key := range mm.periodicGatherKeys() {
+		mm.stopPeriodicGather(key)
+	}
+	running := mm.joinAbandonedCalls()
+	for methodName, instances := range mm.measurements {
+		for identifier, measurement := range instances {
+			if key := methodName + "/" + identifier; running[key] {
+				logrus.Warningf("%s: not disposed, the call abandoned after its timeout is still running", key)
+				continue
+			}
 			measurement.Dispose()
 		}
 	}
 }
 
-func (mm *MeasurementManager) getMeasurementInstance(methodName string, identifier string) (Measurement, error) {
+// joinAbandonedCalls waits up to abandonedCallsJoinTimeout for calls abandoned after their timeout,
+// so that their summaries are recorded. It returns keys of calls still running.
+func (mm *MeasurementManager) joinAbandonedCalls() map[string]bool {
+	mm.lock.Lock()
+	abandoned := make(map[string]chan struct{}, len(mm.abandonedCalls))
+	for key, done := range mm.abandonedCalls {
+		abandoned[key] = done
+	}
+	mm.lock.Unlock()
+	running := make(map[string]bool)
+	deadline := time.NewTimer(abandonedCallsJoinTimeout)
+	defer deadline.Stop()
+	timedOut := false
+	for key, done := range abandoned {
+		if !timedOut {
+			select {
+			case <-done:
+				continue
+			case <-deadline.C:
+				timedOut = true
+			}
+		}
+		select {
+		case <-done:
+		default:
+			running[key] = true
+		}
+	}
+	for key := range running {
+		logrus.Warningf("%s: call abandoned after its timeout didn't return within %v, its summaries are lost", key, abandonedCallsJoinTimeout)
+	}
+	return running
+}
+
+func (mm *MeasurementManager) getMeasurementInstance(methodName string, identifier string, cluster string) (Measurement, error) {
 	mm.lock.Lock()
 	defer mm.lock.Unlock()
+	key := methodName + "/" + identifier
+	if instanceCluster, exists := mm.instanceClusters[key]; exists && instanceCluster != cluster {
+		return nil, fmt.Errorf("%s (%s) was executed against cluster %q, it can't be executed against cluster %q, use different identifier",
+			methodName, identifier, instanceCluster, cluster)
+	}
 	if _, exists := mm.measurements[methodName]; !exists {
 		mm.measurements[methodName] = make(map[string]Measurement)
 	}
@@ -95,6 +619,46 @@ func (mm *MeasurementManager) getMeasurementInstance(methodName string, identifi
 			return nil, err
 		}
 		mm.measurements[methodName][identifier] = measurementInstance
+		mm.instanceClusters[key] = cluster
 	}
 	return mm.measurements[methodName][identifier], nil
 }
+
+func (mm *MeasurementManager) periodicGatherKeys() []string {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	var keys []string
+	for key := range mm.periodicGathers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// partialSummary is a summary that doesn't cover all data, e.g. of a measurement resumed without data
+// collected by the interrupted run, named after the original one with partialSuffix.
+type partialSummary struct {
+	Summary
+}
+
+// PartialSummary marks the summary as partial, i.e. not covering all data, by naming it after
+// the original one with partialSuffix. Nil summary is returned as is.
+func PartialSummary(summary Summary) Summary {
+	if summary == nil {
+		return nil
+	}
+	return &partialSummary{Summary: summary}
+}
+
+func (p *partialSummary) SummaryName() string {
+	return p.Summary.SummaryName() + partialSuffix
+}
+
+func partialSummaries(summaries []Summary) []Summary {
+	partial := make([]Summary, 0, len(summaries))
+	for _, summary := range summaries {
+		if summary != nil {
+			partial = append(partial, PartialSummary(summary))
+		}
+	}
+	return partial
+}
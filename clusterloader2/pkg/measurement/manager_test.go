@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+// blockingMeasurement returns a summary once released, after the context of the call is done.
+type blockingMeasurement struct {
+	release chan struct{}
+}
+
+func (b *blockingMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) {
+	<-config.GetContext().Done()
+	<-b.release
+	return []Summary{CreateSummary("Blocking", "json", "{}")}, nil
+}
+
+func (b *blockingMeasurement) Dispose() {}
+
+func (b *blockingMeasurement) String() string {
+	return "Blocking"
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	mm := &MeasurementManager{markers: NewMarkers(), periodicGathers: make(map[string]*periodicGather), abandonedCalls: make(map[string]chan struct{})}
+	m := &blockingMeasurement{release: make(chan struct{})}
+	_, err := mm.execute("Blocking", "Blocking/test", m, &MeasurementConfig{}, 10*time.Millisecond)
+	assert.Error(t, err)
+
+	// The next call of the instance isn't run until the abandoned call returns.
+	_, err = mm.execute("Blocking", "Blocking/test", m, &MeasurementConfig{}, 10*time.Millisecond)
+	assert.Error(t, err)
+
+	// Summaries of the call returning after the timeout are recorded before summaries are returned.
+	time.AfterFunc(10*time.Millisecond, func() { close(m.release) })
+	assert.Len(t, mm.GetSummaries(), 1)
+	assert.Empty(t, mm.abandonedCalls)
+}
+
+func TestGetCallTimeout(t *testing.T) {
+	mm := &MeasurementManager{clusterLoaderConfig: &config.ClusterLoaderConfig{MeasurementGatherTimeout: time.Minute}}
+	testCases := []struct {
+		params map[string]interface{}
+		want   time.Duration
+	}{
+		{params: map[string]interface{}{"action": "start"}},
+		{params: map[string]interface{}{"action": "gather"}, want: time.Minute},
+		{params: map[string]interface{}{"action": "gather", "callTimeout": "10m"}, want: 10 * time.Minute},
+		{params: map[string]interface{}{"action": "start", "callTimeout": "10s"}, want: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		got, err := mm.getCallTimeout(tc.params)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got, "params %v", tc.params)
+	}
+}
+
+type checkpointingMeasurement struct {
+	fakeCheckpointer
+}
+
+func (c *checkpointingMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) {
+	return nil, nil
+}
+
+func (c *checkpointingMeasurement) Dispose() {}
+
+func (c *checkpointingMeasurement) String() string {
+	return "Checkpointing"
+}
+
+func TestGatherPartialSummaries(t *testing.T) {
+	mm := &MeasurementManager{
+		markers:         NewMarkers(),
+		periodicGathers: make(map[string]*periodicGather),
+		started: map[string]*startedMeasurement{
+			"Blocking/test":      {instance: &blockingMeasurement{}, config: &MeasurementConfig{}},
+			"Checkpointing/test": {instance: &checkpointingMeasurement{}, config: &MeasurementConfig{}},
+		},
+	}
+	mm.GatherPartialSummaries()
+	summaries := mm.GetSummaries()
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, "FakeCheckpoint", summaries[0].SummaryName())
+	}
+	assert.Empty(t, mm.started)
+}
+
+// summarizingMeasurement returns a summary on gather, it's resumable if resumable is set.
+type summarizingMeasurement struct {
+	name      string
+	resumable bool
+}
+
+func (s *summarizingMeasurement) Execute(config *MeasurementConfig) ([]Summary, error) {
+	if config.Params["action"] == "gather" {
+		return []Summary{CreateSummary(s.name, "json", "{}")}, nil
+	}
+	return nil, nil
+}
+
+func (s *summarizingMeasurement) IsResumable(config *MeasurementConfig) bool {
+	return s.resumable
+}
+
+func (s *summarizingMeasurement) Dispose() {}
+
+func (s *summarizingMeasurement) String() string {
+	return s.name
+}
+
+func TestResumeMeasurement(t *testing.T) {
+	for _, instance := range []*summarizingMeasurement{{name: "InMemory"}, {name: "Resumable", resumable: true}} {
+		instance := instance
+		if err := Register(instance.name, func() Measurement { return instance }); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	manager := CreateMeasurementManager(context.Background(), nil, nil, nil, nil, &config.ClusterLoaderConfig{}, nil)
+	start := time.Now().Add(-time.Hour)
+	for _, method := range []string{"InMemory", "Resumable"} {
+		started := StartedMeasurement{Method: method, Identifier: "test", Params: map[string]interface{}{"action": "start"}, StartTime: start}
+		if err := manager.ResumeMeasurement(started); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := manager.Execute(method, "test", map[string]interface{}{"action": "gather"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	var names []string
+	for _, summary := range manager.GetSummaries() {
+		names = append(names, summary.SummaryName())
+	}
+	assert.Equal(t, []string{"InMemoryPartial", "Resumable"}, names)
+}
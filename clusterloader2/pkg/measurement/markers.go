@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const markersName = "Markers"
+
+// Marker is a named point in time of the test.
+type Marker struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// Markers is a thread-safe, ordered collection of markers recorded during the test.
+type Markers struct {
+	lock    sync.Mutex
+	markers []Marker
+}
+
+// NewMarkers creates an empty collection of markers.
+func NewMarkers() *Markers {
+	return &Markers{}
+}
+
+// LoadMarkers reads markers from the file with Markers summary of a previous test run.
+func LoadMarkers(path string) (*Markers, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading markers file error: %v", err)
+	}
+	var markers []Marker
+	if err := json.Unmarshal(data, &markers); err != nil {
+		return nil, fmt.Errorf("parsing markers file error: %v", err)
+	}
+	m := NewMarkers()
+	for _, marker := range markers {
+		if err := m.Add(marker.Name, marker.Time); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add records a marker with given name at given time. Marker names have to be unique.
+func (m *Markers) Add(name string, t time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for i := range m.markers {
+		if m.markers[i].Name == name {
+			return fmt.Errorf("marker %q already recorded", name)
+		}
+	}
+	m.markers = append(m.markers, Marker{Name: name, Time: t})
+	return nil
+}
+
+// Get returns time of the marker with given name.
+func (m *Markers) Get(name string) (time.Time, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for i := range m.markers {
+		if m.markers[i].Name == name {
+			return m.markers[i].Time, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("marker %q has not been recorded", name)
+}
+
+// List returns all markers in the order they were recorded.
+func (m *Markers) List() []Marker {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]Marker(nil), m.markers...)
+}
+
+// GetWindow returns the time window between markers given with startMarker and endMarker
+// params. If a param is not set, given default start or end is used instead.
+func GetWindow(config *MeasurementConfig, defaultStart, defaultEnd time.Time) (time.Time, time.Time, error) {
+	start, err := getMarkerTimeOrDefault(config, "startMarker", defaultStart)
+	if err != nil {
+		return start, defaultEnd, err
+	}
+	end, err := getMarkerTimeOrDefault(config, "endMarker", defaultEnd)
+	if err != nil {
+		return start, end, err
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("empty time window: %v - %v", start, end)
+	}
+	return start, end, nil
+}
+
+func getMarkerTimeOrDefault(config *MeasurementConfig, key string, defaultTime time.Time) (time.Time, error) {
+	name, err := util.GetStringOrDefault(config.Params, key, "")
+	if err != nil || name == "" {
+		return defaultTime, err
+	}
+	if config.Markers == nil {
+		return defaultTime, fmt.Errorf("%s: markers are not available", key)
+	}
+	return config.Markers.Get(name)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWindow(t *testing.T) {
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	markers := NewMarkers()
+	assert.NoError(t, markers.Add("warmup-done", t0.Add(5*time.Minute)))
+	assert.NoError(t, markers.Add("teardown", t0.Add(time.Hour)))
+	assert.Error(t, markers.Add("teardown", t0.Add(2*time.Hour)))
+
+	newConfig := func(params map[string]interface{}) *MeasurementConfig {
+		return &MeasurementConfig{Params: params, Markers: markers}
+	}
+	start, end, err := GetWindow(newConfig(map[string]interface{}{}), t0, t0.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, t0, start)
+	assert.Equal(t, t0.Add(2*time.Hour), end)
+
+	start, end, err = GetWindow(newConfig(map[string]interface{}{"startMarker": "warmup-done", "endMarker": "teardown"}), t0, t0.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, t0.Add(5*time.Minute), start)
+	assert.Equal(t, t0.Add(time.Hour), end)
+
+	_, _, err = GetWindow(newConfig(map[string]interface{}{"startMarker": "unknown"}), t0, t0.Add(2*time.Hour))
+	assert.Error(t, err)
+	_, _, err = GetWindow(newConfig(map[string]interface{}{"startMarker": "teardown", "endMarker": "warmup-done"}), t0, t0.Add(2*time.Hour))
+	assert.Error(t, err)
+}
+
+func TestLoadMarkers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "markers")
+	if err != nil {
+		t.Fatalf("creating temp dir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "Markers.json")
+	content := `[{"name": "warmup-done", "time": "2019-01-01T00:05:00Z"}, {"name": "teardown", "time": "2019-01-01T01:00:00Z"}]`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing markers error: %v", err)
+	}
+	markers, err := LoadMarkers(path)
+	assert.NoError(t, err)
+	teardown, err := markers.Get("teardown")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 1, 1, 1, 0, 0, 0, time.UTC), teardown)
+
+	_, err = LoadMarkers(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
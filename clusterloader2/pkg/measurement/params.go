@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// ParamType is the type of the value of a measurement param.
+type ParamType string
+
+const (
+	StringParam   ParamType = "string"
+	IntParam      ParamType = "int"
+	FloatParam    ParamType = "float"
+	BoolParam     ParamType = "bool"
+	DurationParam ParamType = "duration"
+	ListParam     ParamType = "list"
+	MapParam      ParamType = "map"
+	// AnyParam values aren't checked.
+	AnyParam ParamType = "any"
+)
+
+// Param describes a param accepted by a measurement.
+type Param struct {
+	Name string    `json:"name"`
+	Type ParamType `json:"type"`
+	// Required params have to be set in calls of the actions of the param.
+	Required bool `json:"required,omitempty"`
+	// Default is the value used if the param isn't set, nil if there is none.
+	Default interface{} `json:"default,omitempty"`
+	// Values are allowed values of the param, any value of the type is allowed if it's empty.
+	Values []string `json:"values,omitempty"`
+	// Actions are actions of calls the param is used by, e.g. start, all calls if it's empty.
+	Actions []string `json:"actions,omitempty"`
+	// Description explains the meaning of the param.
+	Description string `json:"description,omitempty"`
+}
+
+// ParamsDeclarer is implemented by measurements declaring params they accept. Params of calls
+// of such measurements are validated against the declaration before the test is run: unknown
+// params, missing required params and values of wrong types are reported. Measurements which
+// can't declare their params (e.g. wrappers of other measurements) return nil.
+type ParamsDeclarer interface {
+	DeclaredParams() []Param
+}
+
+// ActionParam declares action param with given allowed actions.
+func ActionParam(actions ...string) Param {
+	return Param{Name: "action", Type: StringParam, Required: true, Values: actions, Description: "action of the call"}
+}
+
+// SelectorParams declares params of objects selector (see measurementutil.ObjectSelector)
+// used by calls of given actions.
+func SelectorParams(actions ...string) []Param {
+	return []Param{
+		{Name: "namespace", Type: StringParam, Actions: actions, Description: "namespace of objects, all namespaces if empty"},
+		{Name: "labelSelector", Type: StringParam, Actions: actions, Description: "label selector of objects"},
+		{Name: "fieldSelector", Type: StringParam, Actions: actions, Description: "field selector of objects"},
+	}
+}
+
+// commonParams are params handled by the measurement manager, accepted by every measurement.
+var commonParams = []Param{
+	{
+		Name:        "callTimeout",
+		Type:        DurationParam,
+		Description: "timeout of the call, gather calls default to --measurement-gather-timeout",
+	},
+	{
+		Name:        "gatherInterval",
+		Type:        DurationParam,
+		Actions:     []string{"start"},
+		Description: "interval of gathering checkpoint summaries of measurements supporting periodic gather",
+	},
+}
+
+// GetDeclaredParams returns params declared by the measurement method, including params
+// accepted by every measurement. False is returned if the measurement doesn't declare its params.
+func GetDeclaredParams(methodName string) ([]Param, bool, error) {
+	instance, err := factory.createMeasurement(methodName)
+	if err != nil {
+		return nil, false, err
+	}
+	params := getDeclaredParams(instance)
+	return params, params != nil, nil
+}
+
+func getDeclaredParams(instance Measurement) []Param {
+	declarer, ok := instance.(ParamsDeclarer)
+	if !ok {
+		return nil
+	}
+	declared := declarer.DeclaredParams()
+	if declared == nil {
+		return nil
+	}
+	return append(append([]Param{}, declared...), commonParams...)
+}
+
+// validateParams checks params of the call against declared params.
+func validateParams(declared []Param, params map[string]interface{}) error {
+	byName := make(map[string]*Param, len(declared))
+	for i := range declared {
+		byName[declared[i].Name] = &declared[i]
+	}
+	action, _ := util.GetStringOrDefault(params, "action", "")
+	errList := errors.NewErrorList()
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		param, ok := byName[name]
+		if !ok {
+			if similar := findSimilarParam(name, declared); similar != "" {
+				errList.Append(fmt.Errorf("unknown param %q, did you mean %q?", name, similar))
+			} else {
+				errList.Append(fmt.Errorf("unknown param %q", name))
+			}
+			continue
+		}
+		if err := param.check(params); err != nil {
+			errList.Append(err)
+		}
+	}
+	for i := range declared {
+		param := &declared[i]
+		if _, ok := params[param.Name]; ok || !param.Required {
+			continue
+		}
+		if len(param.Actions) == 0 || contains(param.Actions, action) {
+			errList.Append(fmt.Errorf("missing required param %q", param.Name))
+		}
+	}
+	if !errList.IsEmpty() {
+		return errList
+	}
+	return nil
+}
+
+// check checks that the value of the param is of the declared type.
+func (p *Param) check(params map[string]interface{}) error {
+	var err error
+	switch p.Type {
+	case StringParam:
+		var value string
+		if value, err = util.GetString(params, p.Name); err == nil && len(p.Values) > 0 && !contains(p.Values, value) {
+			err = fmt.Errorf("unsupported value %q, expected one of %v", value, p.Values)
+		}
+	case IntParam:
+		_, err = util.GetInt(params, p.Name)
+	case FloatParam:
+		_, err = util.GetFloat64(params, p.Name)
+	case BoolParam:
+		_, err = util.GetBool(params, p.Name)
+	case DurationParam:
+		_, err = util.GetDuration(params, p.Name)
+	case ListParam:
+		if _, ok := params[p.Name].([]interface{}); !ok {
+			err = fmt.Errorf("%v is not a list", params[p.Name])
+		}
+	case MapParam:
+		if _, ok := params[p.Name].(map[string]interface{}); !ok {
+			err = fmt.Errorf("%v is not a map", params[p.Name])
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("param %q: %v", p.Name, err)
+	}
+	return nil
+}
+
+// findSimilarParam returns the declared param the name is likely a typo of, empty string if there is none.
+func findSimilarParam(name string, declared []Param) string {
+	similar, minDistance := "", 3
+	for _, param := range declared {
+		if strings.EqualFold(param.Name, name) {
+			return param.Name
+		}
+		if distance := editDistance(param.Name, name); distance < minDistance {
+			similar, minDistance = param.Name, distance
+		}
+	}
+	return similar
+}
+
+// editDistance returns Levenshtein distance between the strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateParams(t *testing.T) {
+	declared := append([]Param{
+		ActionParam("start", "gather"),
+		{Name: "kind", Type: StringParam, Required: true, Actions: []string{"start"}},
+		{Name: "threshold", Type: DurationParam},
+		{Name: "replicas", Type: IntParam},
+		{Name: "jobs", Type: ListParam},
+	}, commonParams...)
+	testCases := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "valid start",
+			params: map[string]interface{}{"action": "start", "kind": "Deployment", "threshold": "5s", "replicas": 3, "jobs": []interface{}{"a"}, "callTimeout": "1m"},
+		},
+		{
+			name:   "required param of other action",
+			params: map[string]interface{}{"action": "gather"},
+		},
+		{
+			name:    "unknown action",
+			params:  map[string]interface{}{"action": "stop"},
+			wantErr: `param "action": unsupported value "stop", expected one of [start gather]`,
+		},
+		{
+			name:    "missing required param",
+			params:  map[string]interface{}{"action": "start"},
+			wantErr: `missing required param "kind"`,
+		},
+		{
+			name:    "typo",
+			params:  map[string]interface{}{"action": "gather", "treshold": "5s"},
+			wantErr: `unknown param "treshold", did you mean "threshold"?`,
+		},
+		{
+			name:    "unknown param",
+			params:  map[string]interface{}{"action": "gather", "podCount": 5},
+			wantErr: `unknown param "podCount"`,
+		},
+		{
+			name:    "wrong type",
+			params:  map[string]interface{}{"action": "gather", "jobs": "a"},
+			wantErr: `param "jobs": a is not a list`,
+		},
+	}
+	for _, tc := range testCases {
+		err := validateParams(declared, tc.params)
+		if tc.wantErr == "" {
+			assert.NoError(t, err, tc.name)
+			continue
+		}
+		if assert.Error(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.wantErr, tc.name)
+		}
+	}
+}
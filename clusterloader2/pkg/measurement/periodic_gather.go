@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// checkpointSuffix is appended to names of summaries of periodic gathers, so that they are not
+// mistaken for final results.
+const checkpointSuffix = "Checkpoint"
+
+// Checkpointer is implemented by start/gather measurements that can report data collected so far
+// without stopping. Such measurements support periodic gather: if the start call has gatherInterval
+// param set, checkpoint summaries are gathered every gatherInterval until the measurement is gathered.
+type Checkpointer interface {
+	// Checkpoint returns summaries of data collected since the start of the measurement,
+	// given the config of the start call.
+	Checkpoint(config *MeasurementConfig) ([]Summary, error)
+}
+
+// periodicGather gathers checkpoint summaries of a measurement instance in background.
+type periodicGather struct {
+	// lock serializes checkpoints with calls of the measurement.
+	lock   sync.Mutex
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// getGatherInterval returns gatherInterval param of the start call, 0 if it's not set
+// or the call isn't a start call.
+func getGatherInterval(params map[string]interface{}) (time.Duration, error) {
+	if action, _ := util.GetStringOrDefault(params, "action", ""); action != "start" {
+		return 0, nil
+	}
+	return util.GetDurationOrDefault(params, "gatherInterval", 0)
+}
+
+// startPeriodicGather starts gathering checkpoints of the measurement instance every interval.
+func (mm *MeasurementManager) startPeriodicGather(key string, instance Measurement, config *MeasurementConfig, interval time.Duration) error {
+	checkpointer, ok := instance.(Checkpointer)
+	if !ok {
+		return fmt.Errorf("%s doesn't support periodic gather", instance)
+	}
+	mm.stopPeriodicGather(key)
+	p := &periodicGather{stopCh: make(chan struct{}), doneCh: make(chan struct{})}
+	mm.lock.Lock()
+	mm.periodicGathers[key] = p
+	mm.lock.Unlock()
+	logrus.Infof("%s: gathering checkpoints every %v", key, interval)
+	go func() {
+		defer close(p.doneCh)
+		p.run(checkpointer, config, interval, func(summaries []Summary) {
+			mm.writeCheckpoint(key, summaries)
+		})
+	}()
+	return nil
+}
+
+func (p *periodicGather) run(checkpointer Checkpointer, config *MeasurementConfig, interval time.Duration, write func([]Summary)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			checkpoints := checkpoint(checkpointer, config)
+			p.lock.Unlock()
+			if len(checkpoints) > 0 {
+				write(checkpoints)
+			}
+		}
+	}
+}
+
+// checkpoint returns checkpoint summaries of the measurement. Errors are only logged,
+// as they don't affect results of the measurement.
+func checkpoint(checkpointer Checkpointer, config *MeasurementConfig) []Summary {
+	summaries, err := checkpointer.Checkpoint(config)
+	switch {
+	case err == nil:
+	case errors.IsMetricViolationError(err):
+		logrus.Warningf("%s: SLO violated at checkpoint: %v", checkpointer, err)
+	default:
+		logrus.Errorf("%s: checkpoint error: %v", checkpointer, err)
+	}
+	var checkpoints []Summary
+	for _, summary := range summaries {
+		if summary != nil {
+			checkpoints = append(checkpoints, &checkpointSummary{Summary: summary})
+		}
+	}
+	return checkpoints
+}
+
+// getPeriodicGather returns periodic gather of the measurement instance, nil if there is none.
+func (mm *MeasurementManager) getPeriodicGather(key string) *periodicGather {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	return mm.periodicGathers[key]
+}
+
+// stopPeriodicGather stops periodic gather of the measurement instance, if it's running,
+// and waits for the checkpoint in progress.
+func (mm *MeasurementManager) stopPeriodicGather(key string) {
+	mm.lock.Lock()
+	p, exists := mm.periodicGathers[key]
+	delete(mm.periodicGathers, key)
+	mm.lock.Unlock()
+	if exists {
+		close(p.stopCh)
+		<-p.doneCh
+	}
+}
+
+// SetCheckpointWriter sets function writing checkpoint summaries as soon as they are gathered,
+// so that they survive a crash of the test. Without the writer, checkpoints are returned with
+// other summaries at the end of the test.
+func (mm *MeasurementManager) SetCheckpointWriter(write func(summaries []Summary) error) {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+	mm.checkpointWriter = write
+}
+
+func (mm *MeasurementManager) writeCheckpoint(key string, summaries []Summary) {
+	mm.lock.Lock()
+	write := mm.checkpointWriter
+	if write == nil {
+		mm.summaries = append(mm.summaries, summaries...)
+	}
+	mm.lock.Unlock()
+	if write == nil {
+		return
+	}
+	if err := write(summaries); err != nil {
+		logrus.Errorf("%s: writing checkpoint error: %v", key, err)
+	}
+}
+
+// checkpointSummary is a summary gathered periodically, named after the original one with checkpointSuffix.
+type checkpointSummary struct {
+	Summary
+}
+
+func (c *checkpointSummary) SummaryName() string {
+	return c.Summary.SummaryName() + checkpointSuffix
+}
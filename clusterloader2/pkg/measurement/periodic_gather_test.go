@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+)
+
+type fakeCheckpointer struct {
+	lock  sync.Mutex
+	calls int
+}
+
+func (f *fakeCheckpointer) Checkpoint(config *MeasurementConfig) ([]Summary, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls++
+	if f.calls%2 == 0 {
+		return nil, fmt.Errorf("checkpoint %d failed", f.calls)
+	}
+	return []Summary{CreateSummary("Fake", "json", fmt.Sprintf("%d", f.calls))}, errors.NewMetricViolationError("fake", "violated")
+}
+
+func TestPeriodicGather(t *testing.T) {
+	p := &periodicGather{stopCh: make(chan struct{}), doneCh: make(chan struct{})}
+	checkpoints := make(chan []Summary, 10)
+	go func() {
+		defer close(p.doneCh)
+		p.run(&fakeCheckpointer{}, &MeasurementConfig{}, 10*time.Millisecond, func(summaries []Summary) {
+			checkpoints <- summaries
+		})
+	}()
+	// Summaries of failed checkpoints are skipped, summaries with violations are written.
+	for _, want := range []string{"1", "3"} {
+		summaries := <-checkpoints
+		if assert.Len(t, summaries, 1) {
+			assert.Equal(t, "FakeCheckpoint", summaries[0].SummaryName())
+			assert.Equal(t, want, summaries[0].SummaryContent())
+		}
+	}
+	close(p.stopCh)
+	<-p.doneCh
+}
+
+func TestGetGatherInterval(t *testing.T) {
+	testCases := []struct {
+		params  map[string]interface{}
+		want    time.Duration
+		wantErr bool
+	}{
+		{params: map[string]interface{}{"action": "start", "gatherInterval": "10m"}, want: 10 * time.Minute},
+		{params: map[string]interface{}{"action": "start"}},
+		{params: map[string]interface{}{"action": "gather", "gatherInterval": "10m"}},
+		{params: map[string]interface{}{"action": "start", "gatherInterval": "never"}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := getGatherInterval(tc.params)
+		assert.Equal(t, tc.wantErr, err != nil, "params %v", tc.params)
+		assert.Equal(t, tc.want, got, "params %v", tc.params)
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
+)
+
+// RecordingRuleRequirer is implemented by measurements that query Prometheus recording rules.
+// Required rules are verified before the test starts and installed if they are missing,
+// so that the test fails early instead of reporting empty results at its end.
+type RecordingRuleRequirer interface {
+	RequiredRecordingRules(config *MeasurementConfig) []prometheus.RecordingRule
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+const (
+	// DefaultQueryWorkers is the default number of queries executed concurrently by BatchQueryExecutor.
+	DefaultQueryWorkers = 10
+	// QueryTimeResolution is the resolution instant queries are deduplicated at by BatchQueryExecutor.
+	// Queries issued at nearly the same time share the result of the one executed first.
+	QueryTimeResolution = 5 * time.Second
+)
+
+type executorKey struct {
+	client   clientset.Interface
+	endpoint string
+	policy   QueryRetryPolicy
+}
+
+type instantQueryExecutor interface {
+	Query(query string, queryTime time.Time) ([]*model.Sample, error)
+}
+
+type queryExecutor interface {
+	QueryContext(ctx context.Context, query string, queryTime time.Time) ([]*model.Sample, error)
+	QueryRangeContext(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error)
+}
+
+// queryResult is a result of a single query, shared by all callers issuing the same query.
+type queryResult struct {
+	done    chan struct{}
+	samples []*model.Sample
+	streams []*model.SampleStream
+	err     error
+	// cancelled is set if the query failed because the context of the caller executing it was done.
+	cancelled bool
+}
+
+// BatchQueryExecutor executes Prometheus queries concurrently, using a bounded number of workers.
+// Identical queries (the same query evaluated at the same time, truncated to QueryTimeResolution
+// or to the step of range queries) are executed only once and their results are shared.
+// Truncated times are used only to identify identical queries, queries are sent with the times
+// passed by the caller issuing them first.
+// Failed queries are not cached. Results are kept as long as the executor, so it should be
+// scoped to a single test, see BatchQueryExecutors.
+type BatchQueryExecutor struct {
+	executor queryExecutor
+	workers  chan struct{}
+
+	lock    sync.Mutex
+	results map[string]*queryResult
+}
+
+// NewBatchQueryExecutor creates BatchQueryExecutor executing at most workers queries at the same time.
+func NewBatchQueryExecutor(executor queryExecutor, workers int) *BatchQueryExecutor {
+	if workers < 1 {
+		workers = 1
+	}
+	return &BatchQueryExecutor{
+		executor: executor,
+		workers:  make(chan struct{}, workers),
+		results:  make(map[string]*queryResult),
+	}
+}
+
+// BatchQueryExecutors are BatchQueryExecutors of a single test, shared by its measurements, so that identical
+// queries issued by different measurements are deduplicated. Cached results are released together with them.
+type BatchQueryExecutors struct {
+	lock      sync.Mutex
+	executors map[executorKey]*BatchQueryExecutor
+}
+
+// NewBatchQueryExecutors creates empty BatchQueryExecutors.
+func NewBatchQueryExecutors() *BatchQueryExecutors {
+	return &BatchQueryExecutors{executors: make(map[executorKey]*BatchQueryExecutor)}
+}
+
+// Get returns BatchQueryExecutor querying Prometheus with the given client (or the external Prometheus
+// server, if configured) and retry policy. If e is nil, e.g. outside of a test, a new executor is returned.
+func (e *BatchQueryExecutors) Get(c clientset.Interface, prometheusConfig *config.PrometheusConfig, policy QueryRetryPolicy) (*BatchQueryExecutor, error) {
+	if e == nil {
+		executor, err := GetQueryExecutor(c, prometheusConfig, policy)
+		if err != nil {
+			return nil, err
+		}
+		return NewBatchQueryExecutor(executor, DefaultQueryWorkers), nil
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	key := executorKey{client: c, policy: policy}
+	if prometheusConfig != nil && prometheusConfig.Endpoint != "" {
+		key = executorKey{endpoint: prometheusConfig.Endpoint, policy: policy}
+	}
+	if _, ok := e.executors[key]; !ok {
+		executor, err := GetQueryExecutor(c, prometheusConfig, policy)
+		if err != nil {
+			return nil, err
+		}
+		e.executors[key] = NewBatchQueryExecutor(executor, DefaultQueryWorkers)
+	}
+	return e.executors[key], nil
+}
+
+// Query executes given prometheus query at given point in time. If the same query was issued
+// at a time within the same QueryTimeResolution period, its result is returned instead.
+// Returned samples are copies, so they can be modified by the caller.
+func (b *BatchQueryExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	return b.QueryContext(context.Background(), query, queryTime)
+}
+
+// QueryContext is Query cancelled once ctx is done.
+func (b *BatchQueryExecutor) QueryContext(ctx context.Context, query string, queryTime time.Time) ([]*model.Sample, error) {
+	key := fmt.Sprintf("query:%s@%d", query, queryTime.Truncate(QueryTimeResolution).Unix())
+	result := b.execute(ctx, key, func(ctx context.Context, r *queryResult) {
+		r.samples, r.err = b.executor.QueryContext(ctx, query, queryTime)
+	})
+	if result.err != nil {
+		return nil, result.err
+	}
+	samples := make([]*model.Sample, 0, len(result.samples))
+	for _, sample := range result.samples {
+		samples = append(samples, &model.Sample{
+			Metric:    sample.Metric.Clone(),
+			Value:     sample.Value,
+			Timestamp: sample.Timestamp,
+		})
+	}
+	return samples, nil
+}
+
+// QueryRange executes given prometheus query over given time range with given resolution step.
+// If the same query was issued over a time range equal after truncation to the step, its result
+// is returned instead.
+// Returned sample streams are copies, so they can be modified by the caller.
+func (b *BatchQueryExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	return b.QueryRangeContext(context.Background(), query, start, end, step)
+}
+
+// QueryRangeContext is QueryRange cancelled once ctx is done.
+func (b *BatchQueryExecutor) QueryRangeContext(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	keyStart, keyEnd := start, end
+	if step > 0 {
+		keyStart, keyEnd = start.Truncate(step), end.Truncate(step)
+	}
+	key := fmt.Sprintf("range:%s@%d-%d/%d", query, keyStart.UnixNano(), keyEnd.UnixNano(), step)
+	result := b.execute(ctx, key, func(ctx context.Context, r *queryResult) {
+		r.streams, r.err = b.executor.QueryRangeContext(ctx, query, start, end, step)
+	})
+	if result.err != nil {
+		return nil, result.err
+	}
+	streams := make([]*model.SampleStream, 0, len(result.streams))
+	for _, stream := range result.streams {
+		streams = append(streams, &model.SampleStream{
+			Metric: stream.Metric.Clone(),
+			Values: append([]model.SamplePair(nil), stream.Values...),
+		})
+	}
+	return streams, nil
+}
+
+func (b *BatchQueryExecutor) execute(ctx context.Context, key string, run func(context.Context, *queryResult)) *queryResult {
+	for {
+		b.lock.Lock()
+		if result, ok := b.results[key]; ok {
+			b.lock.Unlock()
+			select {
+			case <-result.done:
+			case <-ctx.Done():
+				return &queryResult{err: ctx.Err()}
+			}
+			// The query was cancelled together with the caller executing it, it's executed again by this caller.
+			if result.cancelled && ctx.Err() == nil {
+				continue
+			}
+			return result
+		}
+		result := &queryResult{done: make(chan struct{})}
+		b.results[key] = result
+		b.lock.Unlock()
+
+		select {
+		case b.workers <- struct{}{}:
+			run(ctx, result)
+			<-b.workers
+		case <-ctx.Done():
+			result.err = ctx.Err()
+		}
+
+		if result.err != nil {
+			result.cancelled = ctx.Err() != nil
+			b.lock.Lock()
+			delete(b.results, key)
+			b.lock.Unlock()
+		}
+		close(result.done)
+		return result
+	}
+}
+
+// QueryAll executes given queries concurrently at given point in time, by at most DefaultQueryWorkers
+// workers. Results are returned in the order of queries.
+func QueryAll(executor instantQueryExecutor, queries []string, queryTime time.Time) ([][]*model.Sample, error) {
+	results := make([][]*model.Sample, len(queries))
+	indexes := make(chan int, len(queries))
+	for i := range queries {
+		indexes <- i
+	}
+	close(indexes)
+	workers := DefaultQueryWorkers
+	if len(queries) < workers {
+		workers = len(queries)
+	}
+	var g errgroup.Group
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for i := range indexes {
+				samples, err := executor.Query(queries[i], queryTime)
+				if err != nil {
+					return err
+				}
+				results[i] = samples
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingExecutor struct {
+	lock     sync.Mutex
+	calls    map[string]int
+	running  int
+	maxRun   int
+	failures map[string]int
+	times    map[string][]time.Time
+}
+
+func (c *countingExecutor) QueryContext(ctx context.Context, query string, queryTime time.Time) ([]*model.Sample, error) {
+	c.lock.Lock()
+	c.calls[query]++
+	if c.times == nil {
+		c.times = make(map[string][]time.Time)
+	}
+	c.times[query] = append(c.times[query], queryTime)
+	c.running++
+	if c.running > c.maxRun {
+		c.maxRun = c.running
+	}
+	fail := c.failures[query] > 0
+	if fail {
+		c.failures[query]--
+	}
+	c.lock.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.lock.Lock()
+	c.running--
+	c.lock.Unlock()
+	if fail {
+		return nil, errors.New("query failed")
+	}
+	return []*model.Sample{{Metric: model.Metric{"query": model.LabelValue(query)}, Value: 1}}, nil
+}
+
+func (c *countingExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	return c.QueryContext(context.Background(), query, queryTime)
+}
+
+func (c *countingExecutor) QueryRangeContext(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls[query]++
+	if c.times == nil {
+		c.times = make(map[string][]time.Time)
+	}
+	c.times[query] = append(c.times[query], start, end)
+	return nil, nil
+}
+
+func TestBatchQueryExecutor(t *testing.T) {
+	fake := &countingExecutor{calls: make(map[string]int), failures: map[string]int{"failing": 1}}
+	executor := NewBatchQueryExecutor(fake, 2)
+	now := time.Now()
+
+	queries := []string{"a", "b", "a", "c", "a", "b"}
+	results, err := QueryAll(executor, queries, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, q := range queries {
+		assert.Equal(t, model.LabelValue(q), results[i][0].Metric["query"])
+	}
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, fake.calls)
+	assert.True(t, fake.maxRun <= 2, "at most 2 queries should be executed concurrently, got %d", fake.maxRun)
+
+	// Returned samples are copies, modifying them doesn't affect other callers.
+	results[0][0].Metric["quantile"] = "0.99"
+	samples, err := executor.Query("a", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.NotContains(t, samples[0].Metric, model.LabelName("quantile"))
+
+	// Failed queries are not cached.
+	_, err = executor.Query("failing", now)
+	assert.Error(t, err)
+	_, err = executor.Query("failing", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.calls["failing"])
+
+	// Queries issued at nearly the same time are deduplicated.
+	queryTime := time.Date(2019, 1, 1, 0, 0, 1, 0, time.UTC)
+	for _, offset := range []time.Duration{0, time.Second, 3 * time.Second} {
+		if _, err := executor.Query("d", queryTime.Add(offset)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, fake.calls["d"])
+	// The query is sent with the time of the first caller, not the truncated one.
+	assert.Equal(t, []time.Time{queryTime}, fake.times["d"])
+
+	// Range queries are deduplicated over ranges equal after truncation to the step.
+	start, end := queryTime.Add(-time.Hour), queryTime
+	for _, offset := range []time.Duration{0, 10 * time.Second} {
+		if _, err := executor.QueryRange("r", start.Add(offset), end.Add(offset), time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, fake.calls["r"])
+	assert.Equal(t, []time.Time{start, end}, fake.times["r"])
+}
+
+func TestQueryAllWorkers(t *testing.T) {
+	fake := &countingExecutor{calls: make(map[string]int)}
+	var queries []string
+	for i := 0; i < 3*DefaultQueryWorkers; i++ {
+		queries = append(queries, fmt.Sprintf("q%d", i))
+	}
+	results, err := QueryAll(fake, queries, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Len(t, results, len(queries))
+	assert.True(t, fake.maxRun <= DefaultQueryWorkers, "at most %d queries should be executed concurrently, got %d", DefaultQueryWorkers, fake.maxRun)
+}
+
+func TestBatchQueryExecutorCancelled(t *testing.T) {
+	fake := &countingExecutor{calls: make(map[string]int)}
+	executor := NewBatchQueryExecutor(fake, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Workers are busy, so the cancelled query isn't executed.
+	executor.workers <- struct{}{}
+	_, err := executor.QueryContext(ctx, "a", time.Now())
+	assert.Error(t, err)
+	<-executor.workers
+
+	// Cancelled queries are not cached.
+	samples, err := executor.Query("a", time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, samples, 1)
+	assert.Equal(t, 1, fake.calls["a"])
+}
@@ -25,6 +25,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	clientset "k8s.io/client-go/kubernetes"
 	"github.com/sirupsen/logrus"
 	"k8s.io/kubernetes/pkg/util/system"
@@ -103,11 +104,9 @@ func NewResourceUsageGatherer(c clientset.Interface, host, provider string, opti
 			}
 		}
 		dnsNodes := make(map[string]bool)
-		for _, pod := range pods.Items {
-			if (options.Nodes == MasterNodes) && !system.IsMasterNode(pod.Spec.NodeName) {
-				continue
-			}
-			if (options.Nodes == MasterAndDNSNodes) && !system.IsMasterNode(pod.Spec.NodeName) && pod.Labels["k8s-app"] != "kube-dns" {
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if !isTrackedPod(pod, options.Nodes) {
 				continue
 			}
 			for _, container := range pod.Status.InitContainerStatuses {
@@ -190,19 +189,37 @@ func (g *ContainerResourceGatherer) StopAndSummarize(percentiles []int) (*Resour
 		logrus.Infof("Timed out while waiting for waitgroup, some workers failed to finish: %v", unfinished)
 	}
 
+	return g.summarize(percentiles, true)
+}
+
+// Summarize generates resource summary for the passed-in percentiles from the stats collected so far,
+// without stopping stat gathering workers.
+func (g *ContainerResourceGatherer) Summarize(percentiles []int) (*ResourceUsageSummary, error) {
+	return g.summarize(percentiles, false)
+}
+
+func (g *ContainerResourceGatherer) summarize(percentiles []int, onlyFinished bool) (*ResourceUsageSummary, error) {
 	if len(percentiles) == 0 {
 		logrus.Infof("Warning! Empty percentile list for stopAndPrintData.")
 		return &ResourceUsageSummary{}, fmt.Errorf("failed to get any resource usage data")
 	}
 	data := make(map[int]util.ResourceUsagePerContainer)
 	for i := range g.workers {
-		if g.workers[i].finished {
-			stats := util.ComputePercentiles(g.workers[i].dataSeries, percentiles)
+		if !onlyFinished || g.workers[i].finished {
+			stats := util.ComputePercentiles(g.workers[i].getDataSeries(), percentiles)
 			data = util.LeftMergeData(stats, data)
 		}
 	}
+	return percentilesToSummary(data, percentiles), nil
+}
 
-	// Workers has been stopped. We need to gather data stored in them.
+// summarizeDataSeries generates resource summary for the passed-in percentiles from the data series.
+func summarizeDataSeries(dataSeries []util.ResourceUsagePerContainer, percentiles []int) *ResourceUsageSummary {
+	return percentilesToSummary(util.ComputePercentiles(dataSeries, percentiles), percentiles)
+}
+
+func percentilesToSummary(data map[int]util.ResourceUsagePerContainer, percentiles []int) *ResourceUsageSummary {
+	// Containers are sorted, so that the summary is deterministic.
 	sortedKeys := []string{}
 	for name := range data[percentiles[0]] {
 		sortedKeys = append(sortedKeys, name)
@@ -213,13 +230,82 @@ func (g *ContainerResourceGatherer) StopAndSummarize(percentiles []int) (*Resour
 		for _, name := range sortedKeys {
 			usage := data[perc][name]
 			summary[strconv.Itoa(perc)] = append(summary[strconv.Itoa(perc)], util.SingleContainerSummary{
-				Name: name,
-				Cpu:  usage.CPUUsageInCores,
-				Mem:  usage.MemoryWorkingSetInBytes,
+				Name:             name,
+				Cpu:              usage.CPUUsageInCores,
+				Mem:              usage.MemoryWorkingSetInBytes,
+				Network:          usage.NetworkBytesPerSecond,
+				Disk:             usage.DiskBytesPerSecond,
+				IOPS:             usage.DiskIOPS,
+				EphemeralStorage: usage.EphemeralStorageInBytes,
 			})
 		}
 	}
-	return &summary, nil
+	return &summary
+}
+
+// isTrackedPod returns whether usage of containers of the kube-system pod is tracked for the nodes set.
+func isTrackedPod(pod *corev1.Pod, nodes NodesSet) bool {
+	switch nodes {
+	case MasterNodes:
+		return system.IsMasterNode(pod.Spec.NodeName)
+	case MasterAndDNSNodes:
+		return system.IsMasterNode(pod.Spec.NodeName) || pod.Labels["k8s-app"] == "kube-dns"
+	default:
+		return true
+	}
+}
+
+// ContainerRestartCounts returns restart counts of containers of tracked kube-system pods,
+// keyed by pod/container name, the same as used in resource usage summaries.
+func ContainerRestartCounts(c clientset.Interface, nodes NodesSet) (map[string]int32, error) {
+	pods, err := c.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods error: %v", err)
+	}
+	restartCounts := make(map[string]int32)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isTrackedPod(pod, nodes) {
+			continue
+		}
+		for _, container := range pod.Status.ContainerStatuses {
+			restartCounts[pod.Name+"/"+container.Name] = container.RestartCount
+		}
+	}
+	return restartCounts, nil
+}
+
+// PodNodeLabels returns labels of nodes on which kube-system pods run, keyed by pod name,
+// so that usage of containers can be bucketed by groups of nodes.
+func PodNodeLabels(c clientset.Interface) (map[string]labels.Set, error) {
+	nodes, err := c.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes error: %v", err)
+	}
+	nodeLabels := make(map[string]labels.Set, len(nodes.Items))
+	for i := range nodes.Items {
+		nodeLabels[nodes.Items[i].Name] = labels.Set(nodes.Items[i].Labels)
+	}
+	pods, err := c.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods error: %v", err)
+	}
+	podNodeLabels := make(map[string]labels.Set, len(pods.Items))
+	for i := range pods.Items {
+		if set, ok := nodeLabels[pods.Items[i].Spec.NodeName]; ok {
+			podNodeLabels[pods.Items[i].Name] = set
+		}
+	}
+	return podNodeLabels, nil
+}
+
+// TimeSeries returns usage of containers gathered so far, aggregated into intervals.
+func (g *ContainerResourceGatherer) TimeSeries(interval time.Duration) (*ResourceUsageTimeSeries, error) {
+	var dataSeries []util.ResourceUsagePerContainer
+	for i := range g.workers {
+		dataSeries = append(dataSeries, g.workers[i].getDataSeries()...)
+	}
+	return buildTimeSeries(dataSeries, interval), nil
 }
 
 // Dispose disposes container resource gatherer.
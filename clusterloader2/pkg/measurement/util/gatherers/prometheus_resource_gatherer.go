@@ -0,0 +1,270 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatherers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+const (
+	// Usage of containers is taken from cadvisor metrics scraped from kubelets.
+	containerCPUQuery    = `sum(rate(container_cpu_usage_seconds_total{namespace="kube-system", container!="", container!="POD"}[1m])) by (pod, container)`
+	containerMemoryQuery = `sum(container_memory_working_set_bytes{namespace="kube-system", container!="", container!="POD"}) by (pod, container)`
+	containerDiskQuery   = `sum(rate(container_fs_reads_bytes_total{namespace="kube-system", container!="", container!="POD"}[1m])) by (pod, container) + ` +
+		`sum(rate(container_fs_writes_bytes_total{namespace="kube-system", container!="", container!="POD"}[1m])) by (pod, container)`
+	containerIOPSQuery = `sum(rate(container_fs_reads_total{namespace="kube-system", container!="", container!="POD"}[1m])) by (pod, container) + ` +
+		`sum(rate(container_fs_writes_total{namespace="kube-system", container!="", container!="POD"}[1m])) by (pod, container)`
+	containerStorageQuery = `sum(container_fs_usage_bytes{namespace="kube-system", container!="", container!="POD"}) by (pod, container)`
+	// Network usage is reported per pod, so it's attributed to all containers of the pod.
+	podNetworkQuery = `sum(rate(container_network_receive_bytes_total{namespace="kube-system"}[1m])) by (pod) + ` +
+		`sum(rate(container_network_transmit_bytes_total{namespace="kube-system"}[1m])) by (pod)`
+)
+
+// containerStreams contains results of queries of usage of containers.
+type containerStreams struct {
+	cpu, memory, disk, iops, storage, network []*model.SampleStream
+}
+
+// RangeQueryExecutor executes Prometheus range queries.
+type RangeQueryExecutor interface {
+	QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error)
+}
+
+// PrometheusResourceGatherer computes resource usage of kube-system containers from metrics
+// already scraped by Prometheus, instead of polling kubelets.
+type PrometheusResourceGatherer struct {
+	executor RangeQueryExecutor
+	// listPods lists kube-system pods.
+	listPods func() ([]corev1.Pod, error)
+	nodes    NodesSet
+
+	podsLock sync.Mutex
+	// pods are names of tracked pods, listed at start or whenever data is queried.
+	pods map[string]bool
+
+	step      time.Duration
+	startTime time.Time
+	endTime   time.Time
+	now       func() time.Time
+}
+
+// NewPrometheusResourceGatherer creates gatherer of usage of kube-system containers on the nodes
+// selected by options, sampled every options.ResourceDataGatheringPeriod since now.
+func NewPrometheusResourceGatherer(c clientset.Interface, executor RangeQueryExecutor, options ResourceGathererOptions) (*PrometheusResourceGatherer, error) {
+	g := &PrometheusResourceGatherer{
+		executor: executor,
+		listPods: func() ([]corev1.Pod, error) {
+			pods, err := c.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return pods.Items, nil
+		},
+		nodes: options.Nodes,
+		pods:  make(map[string]bool),
+		step:  options.ResourceDataGatheringPeriod,
+		now:   time.Now,
+	}
+	if _, err := g.trackedPods(); err != nil {
+		return nil, err
+	}
+	g.startTime = g.now()
+	return g, nil
+}
+
+// trackedPods returns names of kube-system pods on the tracked nodes, listed at start or now.
+// Pods are listed again whenever data is queried, so that pods recreated during the test
+// (e.g. static pods of restarted masters) are tracked, as well as pods deleted in the meantime.
+func (g *PrometheusResourceGatherer) trackedPods() (map[string]bool, error) {
+	pods, err := g.listPods()
+	if err != nil {
+		return nil, fmt.Errorf("listing pods error: %v", err)
+	}
+	g.podsLock.Lock()
+	defer g.podsLock.Unlock()
+	tracked := make(map[string]bool, len(g.pods))
+	for i := range pods {
+		if isTrackedPod(&pods[i], g.nodes) {
+			g.pods[pods[i].Name] = true
+		}
+	}
+	for name := range g.pods {
+		tracked[name] = true
+	}
+	return tracked, nil
+}
+
+// StartGatheringData does nothing, as data is gathered by Prometheus.
+func (g *PrometheusResourceGatherer) StartGatheringData() {}
+
+// StopAndSummarize generates resource summary for the passed-in percentiles from data
+// since the creation of the gatherer.
+func (g *PrometheusResourceGatherer) StopAndSummarize(percentiles []int) (*ResourceUsageSummary, error) {
+	if g.endTime.IsZero() {
+		g.endTime = g.now()
+	}
+	return g.summarize(percentiles, g.endTime)
+}
+
+// Summarize generates resource summary for the passed-in percentiles from data gathered so far.
+func (g *PrometheusResourceGatherer) Summarize(percentiles []int) (*ResourceUsageSummary, error) {
+	return g.summarize(percentiles, g.end())
+}
+
+// end returns end of data of the gatherer, which is now unless it has been stopped.
+func (g *PrometheusResourceGatherer) end() time.Time {
+	if g.endTime.IsZero() {
+		return g.now()
+	}
+	return g.endTime
+}
+
+// TimeSeries returns usage of containers gathered so far, aggregated into intervals.
+func (g *PrometheusResourceGatherer) TimeSeries(interval time.Duration) (*ResourceUsageTimeSeries, error) {
+	dataSeries, err := g.queryDataSeries(g.end())
+	if err != nil {
+		return nil, err
+	}
+	return buildTimeSeries(dataSeries, interval), nil
+}
+
+// Dispose does nothing, as the gatherer doesn't own any resources.
+func (g *PrometheusResourceGatherer) Dispose() {}
+
+func (g *PrometheusResourceGatherer) summarize(percentiles []int, end time.Time) (*ResourceUsageSummary, error) {
+	if len(percentiles) == 0 {
+		return &ResourceUsageSummary{}, fmt.Errorf("failed to get any resource usage data")
+	}
+	dataSeries, err := g.queryDataSeries(end)
+	if err != nil {
+		return nil, err
+	}
+	// An empty summary would pass every resource constraint, so missing data has to fail the measurement.
+	if len(dataSeries) == 0 {
+		return nil, fmt.Errorf("no resource usage series of tracked containers returned by Prometheus, check that kubelets are scraped")
+	}
+	logrus.Infof("Resource usage of containers computed from %d Prometheus samples", len(dataSeries))
+	return summarizeDataSeries(dataSeries, percentiles), nil
+}
+
+func (g *PrometheusResourceGatherer) queryDataSeries(end time.Time) ([]util.ResourceUsagePerContainer, error) {
+	pods, err := g.trackedPods()
+	if err != nil {
+		return nil, err
+	}
+	var streams containerStreams
+	for _, q := range []struct {
+		resource string
+		query    string
+		result   *[]*model.SampleStream
+	}{
+		{"cpu", containerCPUQuery, &streams.cpu},
+		{"memory", containerMemoryQuery, &streams.memory},
+		{"disk", containerDiskQuery, &streams.disk},
+		{"iops", containerIOPSQuery, &streams.iops},
+		{"ephemeral storage", containerStorageQuery, &streams.storage},
+		{"network", podNetworkQuery, &streams.network},
+	} {
+		result, err := g.executor.QueryRange(q.query, g.startTime, end, g.step)
+		if err != nil {
+			return nil, fmt.Errorf("querying %s usage error: %v", q.resource, err)
+		}
+		*q.result = result
+	}
+	return toDataSeries(streams, pods), nil
+}
+
+// toDataSeries converts streams of containers of tracked pods into usage data series ordered by time.
+func toDataSeries(streams containerStreams, pods map[string]bool) []util.ResourceUsagePerContainer {
+	byTime := make(map[model.Time]util.ResourceUsagePerContainer)
+	usage := func(name string, t model.Time) *util.ContainerResourceUsage {
+		data, ok := byTime[t]
+		if !ok {
+			data = make(util.ResourceUsagePerContainer)
+			byTime[t] = data
+		}
+		if data[name] == nil {
+			data[name] = &util.ContainerResourceUsage{Name: name, Timestamp: t.Time()}
+		}
+		return data[name]
+	}
+	forEachSample := func(streams []*model.SampleStream, set func(*util.ContainerResourceUsage, model.SampleValue)) {
+		for _, stream := range streams {
+			if name, ok := containerName(stream.Metric, pods); ok {
+				for _, sample := range stream.Values {
+					set(usage(name, sample.Timestamp), sample.Value)
+				}
+			}
+		}
+	}
+	forEachSample(streams.cpu, func(u *util.ContainerResourceUsage, v model.SampleValue) { u.CPUUsageInCores = float64(v) })
+	forEachSample(streams.memory, func(u *util.ContainerResourceUsage, v model.SampleValue) {
+		u.MemoryWorkingSetInBytes = toBytes(v)
+		u.MemoryUsageInBytes = toBytes(v)
+	})
+	forEachSample(streams.disk, func(u *util.ContainerResourceUsage, v model.SampleValue) { u.DiskBytesPerSecond = float64(v) })
+	forEachSample(streams.iops, func(u *util.ContainerResourceUsage, v model.SampleValue) { u.DiskIOPS = float64(v) })
+	forEachSample(streams.storage, func(u *util.ContainerResourceUsage, v model.SampleValue) { u.EphemeralStorageInBytes = toBytes(v) })
+	for _, stream := range streams.network {
+		pod := string(stream.Metric["pod"])
+		if !pods[pod] {
+			continue
+		}
+		for _, sample := range stream.Values {
+			for name, containerUsage := range byTime[sample.Timestamp] {
+				if strings.HasPrefix(name, pod+"/") {
+					containerUsage.NetworkBytesPerSecond = float64(sample.Value)
+				}
+			}
+		}
+	}
+	times := make([]model.Time, 0, len(byTime))
+	for t := range byTime {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	dataSeries := make([]util.ResourceUsagePerContainer, 0, len(times))
+	for _, t := range times {
+		dataSeries = append(dataSeries, byTime[t])
+	}
+	return dataSeries
+}
+
+func toBytes(value model.SampleValue) uint64 {
+	return uint64(math.Max(float64(value), 0))
+}
+
+// containerName returns name of the container in pod/container format, the same as used by kubelet stats.
+func containerName(metric model.Metric, pods map[string]bool) (string, bool) {
+	pod, container := string(metric["pod"]), string(metric["container"])
+	if !pods[pod] || container == "" {
+		return "", false
+	}
+	return pod + "/" + container, true
+}
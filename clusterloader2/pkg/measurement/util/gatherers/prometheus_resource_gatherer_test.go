@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatherers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+type fakeRangeQueryExecutor map[string][]*model.SampleStream
+
+func (f fakeRangeQueryExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	return f[query], nil
+}
+
+func newStream(pod, container string, values ...float64) *model.SampleStream {
+	stream := &model.SampleStream{Metric: model.Metric{"pod": model.LabelValue(pod), "container": model.LabelValue(container)}}
+	for i, value := range values {
+		stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.Time(i * 60000), Value: model.SampleValue(value)})
+	}
+	return stream
+}
+
+// listPods returns a function listing pods of the given names, the next list of names on each call.
+// The last list of names is returned once lists are exhausted.
+func listPods(names ...[]string) func() ([]corev1.Pod, error) {
+	return func() ([]corev1.Pod, error) {
+		var pods []corev1.Pod
+		for _, name := range names[0] {
+			pods = append(pods, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"}})
+		}
+		if len(names) > 1 {
+			names = names[1:]
+		}
+		return pods, nil
+	}
+}
+
+func TestPrometheusResourceGathererSummarize(t *testing.T) {
+	executor := fakeRangeQueryExecutor{
+		containerCPUQuery: {
+			newStream("etcd-master", "etcd", 0.1, 0.3, 0.2),
+			newStream("kube-proxy-node", "kube-proxy", 0.5),
+		},
+		containerMemoryQuery: {
+			newStream("etcd-master", "etcd", 100, 300, 200),
+		},
+	}
+	g := &PrometheusResourceGatherer{
+		executor: executor,
+		listPods: listPods([]string{"etcd-master"}),
+		pods:     make(map[string]bool),
+		step:     time.Minute,
+		now:      time.Now,
+	}
+	summary, err := g.StopAndSummarize([]int{50, 100})
+	assert.NoError(t, err)
+	assert.Equal(t, []util.SingleContainerSummary{{Name: "etcd-master/etcd", Cpu: 0.2, Mem: 200}}, summary.Get("50"))
+	assert.Equal(t, []util.SingleContainerSummary{{Name: "etcd-master/etcd", Cpu: 0.3, Mem: 300}}, summary.Get("100"))
+}
+
+func TestPrometheusResourceGathererNetworkAndDisk(t *testing.T) {
+	executor := fakeRangeQueryExecutor{
+		containerCPUQuery: {
+			newStream("etcd-master", "etcd", 0.1),
+			newStream("etcd-master", "sidecar", 0.01),
+		},
+		containerDiskQuery:    {newStream("etcd-master", "etcd", 4096)},
+		containerIOPSQuery:    {newStream("etcd-master", "etcd", 10)},
+		containerStorageQuery: {newStream("etcd-master", "sidecar", 1024)},
+		podNetworkQuery:       {newStream("etcd-master", "", 2048)},
+	}
+	g := &PrometheusResourceGatherer{
+		executor: executor,
+		listPods: listPods([]string{"etcd-master"}),
+		pods:     make(map[string]bool),
+		step:     time.Minute,
+		now:      time.Now,
+	}
+	summary, err := g.StopAndSummarize([]int{100})
+	assert.NoError(t, err)
+	assert.Equal(t, []util.SingleContainerSummary{
+		{Name: "etcd-master/etcd", Cpu: 0.1, Network: 2048, Disk: 4096, IOPS: 10},
+		{Name: "etcd-master/sidecar", Cpu: 0.01, Network: 2048, EphemeralStorage: 1024},
+	}, summary.Get("100"))
+}
+
+func TestPrometheusResourceGathererNoSeries(t *testing.T) {
+	executor := fakeRangeQueryExecutor{
+		containerCPUQuery: {newStream("kube-proxy-node", "kube-proxy", 0.5)},
+	}
+	g := &PrometheusResourceGatherer{
+		executor: executor,
+		listPods: listPods([]string{"etcd-master"}),
+		pods:     make(map[string]bool),
+		step:     time.Minute,
+		now:      time.Now,
+	}
+	_, err := g.StopAndSummarize([]int{100})
+	assert.Error(t, err)
+}
+
+func TestPrometheusResourceGathererRecreatedPods(t *testing.T) {
+	executor := fakeRangeQueryExecutor{
+		containerCPUQuery: {
+			newStream("kube-proxy-node-1", "kube-proxy", 0.5),
+			newStream("kube-proxy-node-2", "kube-proxy", 0.2),
+			newStream("coredns-1", "coredns", 0.1),
+		},
+	}
+	g := &PrometheusResourceGatherer{
+		executor: executor,
+		// kube-proxy-node-1 is recreated as kube-proxy-node-2 during the measurement.
+		listPods: listPods([]string{"kube-proxy-node-1", "coredns-1"}, []string{"kube-proxy-node-2", "coredns-1"}),
+		pods:     make(map[string]bool),
+		step:     time.Minute,
+		now:      time.Now,
+	}
+	_, err := g.trackedPods()
+	assert.NoError(t, err)
+	summary, err := g.StopAndSummarize([]int{100})
+	assert.NoError(t, err)
+	assert.Equal(t, []util.SingleContainerSummary{
+		{Name: "coredns-1/coredns", Cpu: 0.1},
+		{Name: "kube-proxy-node-1/kube-proxy", Cpu: 0.5},
+		{Name: "kube-proxy-node-2/kube-proxy", Cpu: 0.2},
+	}, summary.Get("100"))
+}
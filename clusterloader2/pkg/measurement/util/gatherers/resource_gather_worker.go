@@ -41,9 +41,15 @@ type resourceGatherWorker struct {
 	printVerboseLogs            bool
 	host                        string
 	provider                    string
+
+	// dataSeriesLock guards dataSeries, as it may be read by intermediate summaries while gathering.
+	dataSeriesLock sync.Mutex
+	// lastUsage is the usage observed by the previous probe, used to compute rates of cumulative counters.
+	lastUsage util.ResourceUsagePerContainer
 }
 
 func (w *resourceGatherWorker) singleProbe() {
+	probeTime := time.Now()
 	data := make(util.ResourceUsagePerContainer)
 	if w.inKubemark {
 		kubemarkData := kubemark.GetKubemarkMasterComponentsResourceUsage(w.host, w.provider)
@@ -70,9 +76,26 @@ func (w *resourceGatherWorker) singleProbe() {
 			}
 		}
 	}
+	// Usage is timestamped with the time of the probe, so that it can be presented over time.
+	for name, usage := range data {
+		usage.Timestamp = probeTime
+		if last, ok := w.lastUsage[name]; ok && usage.NetworkBytes >= last.NetworkBytes && usage.NetworkTime.After(last.NetworkTime) {
+			usage.NetworkBytesPerSecond = float64(usage.NetworkBytes-last.NetworkBytes) / usage.NetworkTime.Sub(last.NetworkTime).Seconds()
+		}
+	}
+	w.lastUsage = data
+	w.dataSeriesLock.Lock()
+	defer w.dataSeriesLock.Unlock()
 	w.dataSeries = append(w.dataSeries, data)
 }
 
+// getDataSeries returns data gathered so far.
+func (w *resourceGatherWorker) getDataSeries() []util.ResourceUsagePerContainer {
+	w.dataSeriesLock.Lock()
+	defer w.dataSeriesLock.Unlock()
+	return w.dataSeries[:len(w.dataSeries):len(w.dataSeries)]
+}
+
 func (w *resourceGatherWorker) gather(initialSleep time.Duration) {
 	defer utilruntime.HandleCrash()
 	defer w.wg.Done()
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatherers
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+// ResourceUsageTimeSeries represents resource usage of containers over time.
+type ResourceUsageTimeSeries struct {
+	// Interval is the length of intervals in which usage is aggregated.
+	Interval string `json:"interval"`
+	// Containers contains usage of every container in consecutive intervals with any samples.
+	Containers map[string][]ResourceUsageSample `json:"containers"`
+	// Peaks contains the highest observed usage of every container, sorted by container name.
+	Peaks []ResourceUsagePeak `json:"peaks"`
+}
+
+// ResourceUsageSample is the highest usage of a container within an interval starting at Time.
+type ResourceUsageSample struct {
+	Time time.Time `json:"time"`
+	Cpu  float64   `json:"cpu"`
+	Mem  uint64    `json:"mem"`
+}
+
+// ResourceUsagePeak is the highest usage of a container together with the time it was observed.
+type ResourceUsagePeak struct {
+	Name    string    `json:"name"`
+	Cpu     float64   `json:"cpu"`
+	CpuTime time.Time `json:"cpuTime"`
+	Mem     uint64    `json:"mem"`
+	MemTime time.Time `json:"memTime"`
+}
+
+// buildTimeSeries aggregates usage from data series (in any order) into intervals.
+// Usage in an interval is the highest one observed in it, so that short spikes are not lost.
+func buildTimeSeries(dataSeries []util.ResourceUsagePerContainer, interval time.Duration) *ResourceUsageTimeSeries {
+	timeSeries := &ResourceUsageTimeSeries{
+		Interval:   interval.String(),
+		Containers: make(map[string][]ResourceUsageSample),
+		Peaks:      []ResourceUsagePeak{},
+	}
+	buckets := make(map[string]map[time.Time]*ResourceUsageSample)
+	peaks := make(map[string]*ResourceUsagePeak)
+	for _, data := range dataSeries {
+		for name, usage := range data {
+			if usage == nil {
+				continue
+			}
+			if buckets[name] == nil {
+				buckets[name] = make(map[time.Time]*ResourceUsageSample)
+				peaks[name] = &ResourceUsagePeak{Name: name, CpuTime: usage.Timestamp, MemTime: usage.Timestamp}
+			}
+			start := usage.Timestamp.Truncate(interval)
+			sample, ok := buckets[name][start]
+			if !ok {
+				sample = &ResourceUsageSample{Time: start}
+				buckets[name][start] = sample
+			}
+			if usage.CPUUsageInCores > sample.Cpu {
+				sample.Cpu = usage.CPUUsageInCores
+			}
+			if usage.MemoryWorkingSetInBytes > sample.Mem {
+				sample.Mem = usage.MemoryWorkingSetInBytes
+			}
+			peak := peaks[name]
+			if usage.CPUUsageInCores > peak.Cpu {
+				peak.Cpu, peak.CpuTime = usage.CPUUsageInCores, usage.Timestamp
+			}
+			if usage.MemoryWorkingSetInBytes > peak.Mem {
+				peak.Mem, peak.MemTime = usage.MemoryWorkingSetInBytes, usage.Timestamp
+			}
+		}
+	}
+	for name, containerBuckets := range buckets {
+		samples := make([]ResourceUsageSample, 0, len(containerBuckets))
+		for _, sample := range containerBuckets {
+			samples = append(samples, *sample)
+		}
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+		timeSeries.Containers[name] = samples
+		timeSeries.Peaks = append(timeSeries.Peaks, *peaks[name])
+	}
+	sort.Slice(timeSeries.Peaks, func(i, j int) bool { return timeSeries.Peaks[i].Name < timeSeries.Peaks[j].Name })
+	return timeSeries
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatherers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+func TestBuildTimeSeries(t *testing.T) {
+	start := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	usage := func(offset time.Duration, cpu float64, mem uint64) util.ResourceUsagePerContainer {
+		return util.ResourceUsagePerContainer{
+			"etcd-master/etcd": {Timestamp: start.Add(offset), CPUUsageInCores: cpu, MemoryWorkingSetInBytes: mem},
+		}
+	}
+	dataSeries := []util.ResourceUsagePerContainer{
+		usage(70*time.Second, 0.5, 100),
+		usage(10*time.Second, 0.1, 300),
+		usage(80*time.Second, 0.9, 200),
+		{"kube-proxy-node/kube-proxy": {Timestamp: start, CPUUsageInCores: 0.2, MemoryWorkingSetInBytes: 50}},
+	}
+	timeSeries := buildTimeSeries(dataSeries, time.Minute)
+	assert.Equal(t, "1m0s", timeSeries.Interval)
+	assert.Equal(t, []ResourceUsageSample{
+		{Time: start, Cpu: 0.1, Mem: 300},
+		{Time: start.Add(time.Minute), Cpu: 0.9, Mem: 200},
+	}, timeSeries.Containers["etcd-master/etcd"])
+	assert.Equal(t, []ResourceUsagePeak{
+		{Name: "etcd-master/etcd", Cpu: 0.9, CpuTime: start.Add(80 * time.Second), Mem: 300, MemTime: start.Add(10 * time.Second)},
+		{Name: "kube-proxy-node/kube-proxy", Cpu: 0.2, CpuTime: start, Mem: 50, MemTime: start},
+	}, timeSeries.Peaks)
+}
@@ -37,12 +37,24 @@ func NewInformer(
 	kind string,
 	selector *measurementutil.ObjectSelector,
 	handleObj func(interface{}, interface{}),
+) cache.SharedInformer {
+	return NewInformerForRESTClient(c.CoreV1().RESTClient(), kind, selector, handleObj)
+}
+
+// NewInformerForRESTClient creates a new informer for given kind, namespace,
+// fieldSelector and labelSelector using provided REST client.
+// It allows observing kinds served outside of the core API group.
+func NewInformerForRESTClient(
+	restClient cache.Getter,
+	kind string,
+	selector *measurementutil.ObjectSelector,
+	handleObj func(interface{}, interface{}),
 ) cache.SharedInformer {
 	optionsModifier := func(options *metav1.ListOptions) {
 		options.FieldSelector = selector.FieldSelector
 		options.LabelSelector = selector.LabelSelector
 	}
-	listerWatcher := cache.NewFilteredListWatchFromClient(c.CoreV1().RESTClient(), kind, selector.Namespace, optionsModifier)
+	listerWatcher := cache.NewFilteredListWatchFromClient(restClient, kind, selector.Namespace, optionsModifier)
 	informer := cache.NewSharedInformer(listerWatcher, nil, 0)
 	addEventHandler(informer, handleObj)
 
@@ -41,11 +41,11 @@ func GetOneTimeResourceUsageOnNode(c clientset.Interface, nodeName string, conta
 		return nil, err
 	}
 
-	f := func(name string, newStats *stats.ContainerStats) *util.ContainerResourceUsage {
+	f := func(name string, newStats *stats.ContainerStats, podStats *stats.PodStats) *util.ContainerResourceUsage {
 		if newStats == nil || newStats.CPU == nil || newStats.Memory == nil {
 			return nil
 		}
-		return &util.ContainerResourceUsage{
+		usage := &util.ContainerResourceUsage{
 			Name:                    name,
 			Timestamp:               newStats.StartTime.Time,
 			CPUUsageInCores:         float64(removeUint64Ptr(newStats.CPU.UsageNanoCores)) / 1000000000,
@@ -54,12 +54,31 @@ func GetOneTimeResourceUsageOnNode(c clientset.Interface, nodeName string, conta
 			MemoryRSSInBytes:        removeUint64Ptr(newStats.Memory.RSSBytes),
 			CPUInterval:             0,
 		}
+		if newStats.Rootfs != nil {
+			usage.EphemeralStorageInBytes += removeUint64Ptr(newStats.Rootfs.UsedBytes)
+		}
+		if newStats.Logs != nil {
+			usage.EphemeralStorageInBytes += removeUint64Ptr(newStats.Logs.UsedBytes)
+		}
+		// Network stats are collected per pod, so they are attributed to all its containers.
+		// Kubelet reports only cumulative values, the rate is computed from consecutive samples.
+		if network := podStats.Network; network != nil {
+			for _, i := range network.Interfaces {
+				usage.NetworkBytes += removeUint64Ptr(i.RxBytes) + removeUint64Ptr(i.TxBytes)
+			}
+			if len(network.Interfaces) == 0 {
+				usage.NetworkBytes = removeUint64Ptr(network.RxBytes) + removeUint64Ptr(network.TxBytes)
+			}
+			usage.NetworkTime = network.Time.Time
+		}
+		return usage
 	}
 	// Process container infos that are relevant to us.
 	containers := containerNames()
 	usageMap := make(util.ResourceUsagePerContainer, len(containers))
 	observedContainers := []string{}
-	for _, pod := range summary.Pods {
+	for i := range summary.Pods {
+		pod := &summary.Pods[i]
 		for _, container := range pod.Containers {
 			isInteresting := false
 			for _, interestingContainerName := range containers {
@@ -72,7 +91,7 @@ func GetOneTimeResourceUsageOnNode(c clientset.Interface, nodeName string, conta
 			if !isInteresting {
 				continue
 			}
-			if usage := f(pod.PodRef.Name+"/"+container.Name, &container); usage != nil {
+			if usage := f(pod.PodRef.Name+"/"+container.Name, &container, pod); usage != nil {
 				usageMap[pod.PodRef.Name+"/"+container.Name] = usage
 			}
 		}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/prometheus/common/model"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/stats"
 )
 
 // LatencyMetric represent 50th, 90th and 99th duration quantiles.
@@ -90,7 +91,7 @@ func (l LatencySlice) Len() int           { return len(l) }
 func (l LatencySlice) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
 func (l LatencySlice) Less(i, j int) bool { return l[i].GetLatency() < l[j].GetLatency() }
 
-// NewLatencyMetric converts latency data array to latency metric.
+// NewLatencyMetric converts latency data array, sorted in increasing order, to latency metric.
 func NewLatencyMetric(latencies []LatencyData) LatencyMetric {
 	length := len(latencies)
 	if length == 0 {
@@ -98,10 +99,14 @@ func NewLatencyMetric(latencies []LatencyData) LatencyMetric {
 		// but 0 is the best we can get for time.Duration type.
 		return LatencyMetric{Perc50: 0, Perc90: 0, Perc99: 0}
 	}
-	perc50 := latencies[int(math.Ceil(float64(length*50)/100))-1].GetLatency()
-	perc90 := latencies[int(math.Ceil(float64(length*90)/100))-1].GetLatency()
-	perc99 := latencies[int(math.Ceil(float64(length*99)/100))-1].GetLatency()
-	return LatencyMetric{Perc50: perc50, Perc90: perc90, Perc99: perc99}
+	values := make([]float64, length)
+	for i := range latencies {
+		values[i] = float64(latencies[i].GetLatency())
+	}
+	quantile := func(q float64) time.Duration {
+		return time.Duration(math.Round(stats.Quantile(values, q)))
+	}
+	return LatencyMetric{Perc50: quantile(0.5), Perc90: quantile(0.9), Perc99: quantile(0.99)}
 }
 
 // NewLatencyMetricPrometheus tries to parse latency data from results of Prometheus query.
@@ -0,0 +1,36 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type latency time.Duration
+
+func (l latency) GetLatency() time.Duration {
+	return time.Duration(l)
+}
+
+func TestNewLatencyMetric(t *testing.T) {
+	metric := NewLatencyMetric([]LatencyData{latency(time.Second), latency(2 * time.Second), latency(3 * time.Second)})
+	assert.Equal(t, LatencyMetric{Perc50: 2 * time.Second, Perc90: 2800 * time.Millisecond, Perc99: 2980 * time.Millisecond}, metric)
+	assert.Equal(t, LatencyMetric{}, NewLatencyMetric(nil))
+}
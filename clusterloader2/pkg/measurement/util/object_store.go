@@ -22,17 +22,23 @@ with slight changes regarding labelSelector and flagSelector applied.
 package util
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
 )
 
 // ObjectStore is a convenient wrapper around cache.Store.
@@ -70,18 +76,40 @@ func (s *ObjectStore) Stop() {
 	close(s.stopCh)
 }
 
+// podStorePageSize is the number of pods listed in a single request of the initial list of pod stores.
+const podStorePageSize = 500
+
 // PodStore is a convenient wrapper around cache.Store.
 type PodStore struct {
 	*ObjectStore
+
+	// release is set for stores provided by SharedPodStores, it is called instead of stopping the store.
+	release  func()
+	stopOnce sync.Once
 }
 
 // NewPodStore creates PodStore based on given object selector.
+// Use SharedPodStores to share the store with other users of the same selector.
 func NewPodStore(c clientset.Interface, selector *ObjectSelector) (*PodStore, error) {
+	objectStore, err := newPodObjectStore(c, selector)
+	if err != nil {
+		return nil, err
+	}
+	return &PodStore{ObjectStore: objectStore}, nil
+}
+
+func newPodObjectStore(c clientset.Interface, selector *ObjectSelector) (*ObjectStore, error) {
 	lw := &cache.ListWatch{
 		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 			options.LabelSelector = selector.LabelSelector
 			options.FieldSelector = selector.FieldSelector
-			return c.CoreV1().Pods(selector.Namespace).List(options)
+			// Lists served from etcd are split in pages, all pinned by continue tokens
+			// to the snapshot of the first page, instead of a single huge response.
+			listPager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+				return c.CoreV1().Pods(selector.Namespace).List(opts)
+			}))
+			listPager.PageSize = podStorePageSize
+			return listPager.List(context.Background(), options)
 		},
 		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 			options.LabelSelector = selector.LabelSelector
@@ -89,11 +117,7 @@ func NewPodStore(c clientset.Interface, selector *ObjectSelector) (*PodStore, er
 			return c.CoreV1().Pods(selector.Namespace).Watch(options)
 		},
 	}
-	objectStore, err := newObjectStore(&v1.Pod{}, lw, selector)
-	if err != nil {
-		return nil, err
-	}
-	return &PodStore{ObjectStore: objectStore}, nil
+	return newObjectStore(&v1.Pod{}, lw, selector)
 }
 
 // List returns list of pods (that satisfy conditions provided to NewPodStore).
@@ -106,6 +130,17 @@ func (s *PodStore) List() []*v1.Pod {
 	return pods
 }
 
+// Stop stops PodStore. Shared stores are stopped once they aren't used by anyone.
+func (s *PodStore) Stop() {
+	s.stopOnce.Do(func() {
+		if s.release != nil {
+			s.release()
+			return
+		}
+		s.ObjectStore.Stop()
+	})
+}
+
 // PVCStore is a convenient wrapper around cache.Store.
 type PVCStore struct {
 	*ObjectStore
@@ -177,3 +212,39 @@ func (s *PVStore) List() []*v1.PersistentVolume {
 	}
 	return pvs
 }
+
+// GenericStore is a convenient wrapper around cache.Store for objects of any resource.
+type GenericStore struct {
+	*ObjectStore
+}
+
+// NewGenericStore creates GenericStore of given resource based on a given object selector.
+func NewGenericStore(c dynamic.Interface, gvr schema.GroupVersionResource, selector *ObjectSelector) (*GenericStore, error) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.LabelSelector
+			options.FieldSelector = selector.FieldSelector
+			return c.Resource(gvr).Namespace(selector.Namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.LabelSelector
+			options.FieldSelector = selector.FieldSelector
+			return c.Resource(gvr).Namespace(selector.Namespace).Watch(options)
+		},
+	}
+	objectStore, err := newObjectStore(&unstructured.Unstructured{}, lw, selector)
+	if err != nil {
+		return nil, err
+	}
+	return &GenericStore{ObjectStore: objectStore}, nil
+}
+
+// List returns list of objects (that satisfy conditions provided to NewGenericStore).
+func (s *GenericStore) List() []*unstructured.Unstructured {
+	objects := s.Store.List()
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, o := range objects {
+		result = append(result, o.(*unstructured.Unstructured))
+	}
+	return result
+}
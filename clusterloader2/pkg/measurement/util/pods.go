@@ -18,6 +18,8 @@ package util
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -25,6 +27,28 @@ import (
 
 const (
 	nonExist = "NonExist"
+
+	// maxDiagnosedPodNames is the number of pod names listed for a single reason in PodsDiagnostics string.
+	maxDiagnosedPodNames = 5
+)
+
+// Reasons of pods not running, which are unlikely to change without an intervention.
+const (
+	// ReasonImagePullBackOff means that image of a container can't be pulled.
+	ReasonImagePullBackOff = "ImagePullBackOff"
+	// ReasonCrashLoopBackOff means that a container keeps crashing.
+	ReasonCrashLoopBackOff = "CrashLoopBackOff"
+	// ReasonCreateContainerError means that a container can't be created, e.g. due to invalid config.
+	ReasonCreateContainerError = "CreateContainerError"
+	// ReasonUnschedulable means that scheduler can't find a node for the pod.
+	ReasonUnschedulable = "Unschedulable"
+)
+
+// Other reasons of pods not running.
+const (
+	reasonTerminating  = "Terminating"
+	reasonNotScheduled = "NotScheduled"
+	reasonNotReady     = "NotReady"
 )
 
 // PodsStartupStatus represents status of a pods group.
@@ -215,3 +239,93 @@ func ComputePodsStatus(pods []*corev1.Pod, expected int) *PodsStatus {
 func (ps *PodsStatus) String() string {
 	return fmt.Sprintf("%v, expected %d", ps.info, ps.expected)
 }
+
+// PodsDiagnostics groups namespaced names of pods that are not running by the reason.
+type PodsDiagnostics map[string][]string
+
+// DiagnosePods computes PodsDiagnostics for a group of pods. Running and ready pods are omitted.
+func DiagnosePods(pods []*corev1.Pod) PodsDiagnostics {
+	diagnostics := make(PodsDiagnostics)
+	for _, pod := range pods {
+		if reason, _ := DiagnosePod(pod); reason != "" {
+			diagnostics[reason] = append(diagnostics[reason], pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return diagnostics
+}
+
+// DiagnosePod returns the reason why the pod isn't running (or empty string if it's running and ready)
+// and whether the pod is stuck, i.e. the reason is unlikely to go away without an intervention.
+func DiagnosePod(pod *corev1.Pod) (string, bool) {
+	if pod.DeletionTimestamp != nil {
+		return reasonTerminating, false
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown:
+		return string(pod.Status.Phase), false
+	}
+	if pod.Spec.NodeName == "" {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+				return ReasonUnschedulable, true
+			}
+		}
+		return reasonNotScheduled, false
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	waitingReason := ""
+	for _, status := range statuses {
+		if status.State.Waiting == nil || status.State.Waiting.Reason == "" {
+			continue
+		}
+		switch status.State.Waiting.Reason {
+		case "ImagePullBackOff", "ErrImagePull", "InvalidImageName", "ErrImageNeverPull":
+			return ReasonImagePullBackOff, true
+		case "CrashLoopBackOff":
+			return ReasonCrashLoopBackOff, true
+		case "CreateContainerConfigError", "CreateContainerError":
+			return ReasonCreateContainerError, true
+		}
+		if waitingReason == "" {
+			waitingReason = status.State.Waiting.Reason
+		}
+	}
+	if waitingReason != "" {
+		return waitingReason, false
+	}
+	if pod.Status.Phase == corev1.PodRunning {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				return "", false
+			}
+		}
+		return reasonNotReady, false
+	}
+	return string(pod.Status.Phase), false
+}
+
+// String returns string representation of PodsDiagnostics, with most frequent reasons first.
+func (d PodsDiagnostics) String() string {
+	reasons := make([]string, 0, len(d))
+	for reason := range d {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if len(d[reasons[i]]) != len(d[reasons[j]]) {
+			return len(d[reasons[i]]) > len(d[reasons[j]])
+		}
+		return reasons[i] < reasons[j]
+	})
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		names := append([]string{}, d[reason]...)
+		sort.Strings(names)
+		suffix := ""
+		if len(names) > maxDiagnosedPodNames {
+			suffix = fmt.Sprintf(" and %d more", len(names)-maxDiagnosedPodNames)
+			names = names[:maxDiagnosedPodNames]
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d (%s%s)", reason, len(d[reason]), strings.Join(names, ", "), suffix))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func waitingPod(name, reason string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec:       corev1.PodSpec{NodeName: "node"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}},
+			},
+		},
+	}
+}
+
+func TestDiagnosePod(t *testing.T) {
+	running := &corev1.Pod{
+		Spec: corev1.PodSpec{NodeName: "node"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	notReady := running.DeepCopy()
+	notReady.Status.Conditions = nil
+	unschedulable := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodPending,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable}},
+		},
+	}
+	notScheduled := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}
+
+	for _, tc := range []struct {
+		pod    *corev1.Pod
+		reason string
+		stuck  bool
+	}{
+		{pod: running, reason: ""},
+		{pod: notReady, reason: "NotReady"},
+		{pod: unschedulable, reason: ReasonUnschedulable, stuck: true},
+		{pod: notScheduled, reason: "NotScheduled"},
+		{pod: waitingPod("a", "ErrImagePull"), reason: ReasonImagePullBackOff, stuck: true},
+		{pod: waitingPod("b", "CrashLoopBackOff"), reason: ReasonCrashLoopBackOff, stuck: true},
+		{pod: waitingPod("c", "CreateContainerConfigError"), reason: ReasonCreateContainerError, stuck: true},
+		{pod: waitingPod("d", "ContainerCreating"), reason: "ContainerCreating"},
+	} {
+		reason, stuck := DiagnosePod(tc.pod)
+		assert.Equal(t, tc.reason, reason)
+		assert.Equal(t, tc.stuck, stuck)
+	}
+}
+
+func TestPodsDiagnosticsString(t *testing.T) {
+	pods := []*corev1.Pod{waitingPod("creating", "ContainerCreating")}
+	for _, name := range []string{"f", "e", "d", "c", "b", "a"} {
+		pods = append(pods, waitingPod(name, "CrashLoopBackOff"))
+	}
+	assert.Equal(t,
+		"CrashLoopBackOff: 6 (ns/a, ns/b, ns/c, ns/d, ns/e and 1 more), ContainerCreating: 1 (ns/creating)",
+		DiagnosePods(pods).String())
+}
+
+func TestUpdateStuckPods(t *testing.T) {
+	start := time.Now()
+	stuckSince := make(map[string]time.Time)
+	crashing := waitingPod("crashing", "CrashLoopBackOff")
+	creating := waitingPod("creating", "ContainerCreating")
+
+	assert.Empty(t, updateStuckPods(stuckSince, []*corev1.Pod{crashing, creating}, start, time.Minute))
+	assert.Empty(t, updateStuckPods(stuckSince, []*corev1.Pod{crashing, creating}, start.Add(30*time.Second), time.Minute))
+	assert.Equal(t,
+		PodsDiagnostics{ReasonCrashLoopBackOff: {"ns/crashing"}},
+		updateStuckPods(stuckSince, []*corev1.Pod{crashing, creating}, start.Add(time.Minute), time.Minute))
+
+	// Pod recovering from the crash loop starts over.
+	assert.Empty(t, updateStuckPods(stuckSince, []*corev1.Pod{creating}, start.Add(90*time.Second), time.Minute))
+	assert.Empty(t, updateStuckPods(stuckSince, []*corev1.Pod{crashing}, start.Add(2*time.Minute), time.Minute))
+}
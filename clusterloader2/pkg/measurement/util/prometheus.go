@@ -17,24 +17,50 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
-	"k8s.io/apimachinery/pkg/util/wait"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/net"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
-const (
-	queryTimeout  = 5 * time.Minute
-	queryInterval = 30 * time.Second
-)
+// QueryRetryPolicy describes how failed Prometheus queries are retried.
+// Only transient errors are retried (see IsRetryableQueryError), delays between retries grow exponentially.
+type QueryRetryPolicy struct {
+	// Retries is the maximum number of retries of a single query.
+	Retries int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between retries.
+	MaxBackoff time.Duration
+	// Factor is the multiplier applied to the delay after every retry.
+	Factor float64
+}
+
+// urlQueryTimeout is the timeout of a single query sent to Prometheus by url.
+const urlQueryTimeout = 5 * time.Minute
+
+// DefaultQueryRetryPolicy is the retry policy used by NewQueryExecutor.
+// Retries take about 5 minutes in total.
+var DefaultQueryRetryPolicy = QueryRetryPolicy{
+	Retries:        8,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     time.Minute,
+	Factor:         2,
+}
 
 // ExtractMetricSamples unpacks metric blob into prometheus model structures.
 func ExtractMetricSamples(metricsBlob string) ([]*model.Sample, error) {
@@ -74,6 +100,22 @@ func ExtractMetricSamples2(response []byte) ([]*model.Sample, error) {
 	return []*model.Sample(vector), nil
 }
 
+// ExtractMetricSampleStreams unpacks range query response into prometheus model structures.
+func ExtractMetricSampleStreams(response []byte) ([]*model.SampleStream, error) {
+	var pqr promQueryResponse
+	if err := json.Unmarshal(response, &pqr); err != nil {
+		return nil, err
+	}
+	if pqr.Status != "success" {
+		return nil, fmt.Errorf("non-success response status: %v", pqr.Status)
+	}
+	matrix, ok := pqr.Data.v.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("incorrect response type: %v", pqr.Data.v.Type())
+	}
+	return []*model.SampleStream(matrix), nil
+}
+
 type promQueryResponse struct {
 	Status string           `json:"status"`
 	Data   promResponseData `json:"data"`
@@ -85,41 +127,55 @@ type promResponseData struct {
 
 // NewQueryExecutor creates instance of PrometheusQueryExecutor.
 func NewQueryExecutor(c clientset.Interface) *PrometheusQueryExecutor {
-	return &PrometheusQueryExecutor{client: c}
+	return NewQueryExecutorWithRetryPolicy(c, DefaultQueryRetryPolicy)
 }
 
-// PrometheusQueryExecutor executes queries against Prometheus instance running inside test cluster.
+// NewQueryExecutorWithRetryPolicy creates instance of PrometheusQueryExecutor
+// retrying failed queries according to the given policy.
+func NewQueryExecutorWithRetryPolicy(c clientset.Interface, policy QueryRetryPolicy) *PrometheusQueryExecutor {
+	return &PrometheusQueryExecutor{client: c, retryPolicy: policy}
+}
+
+// NewURLQueryExecutor creates instance of PrometheusQueryExecutor querying Prometheus
+// (or any server implementing Prometheus HTTP API) available under the given URL,
+// instead of the one running inside test cluster.
+func NewURLQueryExecutor(prometheusURL string, policy QueryRetryPolicy) *PrometheusQueryExecutor {
+	return &PrometheusQueryExecutor{
+		url:         strings.TrimSuffix(prometheusURL, "/"),
+		httpClient:  &http.Client{Timeout: urlQueryTimeout},
+		retryPolicy: policy,
+	}
+}
+
+// PrometheusQueryExecutor executes queries against Prometheus instance running inside test cluster
+// or, if url is set, against Prometheus available under that url.
 type PrometheusQueryExecutor struct {
-	client clientset.Interface
+	client      clientset.Interface
+	url         string
+	httpClient  *http.Client
+	retryPolicy QueryRetryPolicy
 }
 
 // Query executes given prometheus query at given point in time.
 func (e *PrometheusQueryExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	return e.QueryContext(context.Background(), query, queryTime)
+}
+
+// QueryContext executes given prometheus query at given point in time. The request
+// (and retries) are cancelled once ctx is done.
+func (e *PrometheusQueryExecutor) QueryContext(ctx context.Context, query string, queryTime time.Time) ([]*model.Sample, error) {
 	if queryTime.IsZero() {
 		return nil, fmt.Errorf("query time can't be zero")
 	}
 
-	var body []byte
-	var queryErr error
 	params := map[string]string{
 		"query": query,
 		"time":  queryTime.Format(time.RFC3339),
 	}
 	logrus.Infof("Executing %q at %v", query, queryTime.Format(time.RFC3339))
-	if err := wait.PollImmediate(queryInterval, queryTimeout, func() (bool, error) {
-		body, queryErr = e.client.CoreV1().
-			Services("monitoring").
-			ProxyGet("http", "prometheus-k8s", "9090", "api/v1/query", params).
-			DoRaw()
-		if queryErr != nil {
-			return false, nil
-		}
-		return true, nil
-	}); err != nil {
-		if queryErr != nil {
-			return nil, fmt.Errorf("query error: %v", queryErr)
-		}
-		return nil, fmt.Errorf("query error: %v", err)
+	body, err := e.get(ctx, "api/v1/query", params)
+	if err != nil {
+		return nil, err
 	}
 
 	samples, err := ExtractMetricSamples2(body)
@@ -137,6 +193,198 @@ func (e *PrometheusQueryExecutor) Query(query string, queryTime time.Time) ([]*m
 	return resultSamples, nil
 }
 
+// QueryRange executes given prometheus query over given time range with given resolution step.
+func (e *PrometheusQueryExecutor) QueryRange(query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	return e.QueryRangeContext(context.Background(), query, start, end, step)
+}
+
+// QueryRangeContext executes given prometheus query over given time range with given resolution step.
+// The request (and retries) are cancelled once ctx is done.
+func (e *PrometheusQueryExecutor) QueryRangeContext(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]*model.SampleStream, error) {
+	if start.IsZero() || end.IsZero() {
+		return nil, fmt.Errorf("query range boundaries can't be zero")
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("query step has to be positive")
+	}
+
+	params := map[string]string{
+		"query": query,
+		"start": start.Format(time.RFC3339),
+		"end":   end.Format(time.RFC3339),
+		"step":  strconv.FormatFloat(step.Seconds(), 'f', -1, 64),
+	}
+	logrus.Infof("Executing %q from %v to %v with step %v", query, start.Format(time.RFC3339), end.Format(time.RFC3339), step)
+	body, err := e.get(ctx, "api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	streams, err := ExtractMetricSampleStreams(body)
+	if err != nil {
+		return nil, fmt.Errorf("exctracting error: %v", err)
+	}
+	for _, stream := range streams {
+		values := stream.Values[:0]
+		for _, value := range stream.Values {
+			if !math.IsNaN(float64(value.Value)) {
+				values = append(values, value)
+			}
+		}
+		stream.Values = values
+	}
+	logrus.Debugf("Got %d sample streams", len(streams))
+	return streams, nil
+}
+
+// RecordingRules returns names of recording rules loaded by Prometheus.
+func (e *PrometheusQueryExecutor) RecordingRules() (map[string]bool, error) {
+	body, err := e.get(context.Background(), "api/v1/rules", nil /*params*/)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Data struct {
+			Groups []struct {
+				Rules []struct {
+					Name string `json:"name"`
+					Type string `json:"type"`
+				} `json:"rules"`
+			} `json:"groups"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("rules unmarshalling error: %v", err)
+	}
+	rules := make(map[string]bool)
+	for _, group := range response.Data.Groups {
+		for _, rule := range group.Rules {
+			if rule.Type == "recording" {
+				rules[rule.Name] = true
+			}
+		}
+	}
+	return rules, nil
+}
+
+// ServerTime returns current time of the Prometheus server, i.e. the time at which
+// queries without explicit time are evaluated.
+func (e *PrometheusQueryExecutor) ServerTime() (time.Time, error) {
+	body, err := e.get(context.Background(), "api/v1/query", map[string]string{"query": "time()"})
+	if err != nil {
+		return time.Time{}, err
+	}
+	var pqr promQueryResponse
+	if err := json.Unmarshal(body, &pqr); err != nil {
+		return time.Time{}, err
+	}
+	if pqr.Status != "success" {
+		return time.Time{}, fmt.Errorf("non-success response status: %v", pqr.Status)
+	}
+	scalar, ok := pqr.Data.v.(*model.Scalar)
+	if !ok {
+		return time.Time{}, fmt.Errorf("incorrect response type: %v", pqr.Data.v.Type())
+	}
+	seconds := float64(scalar.Value)
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+func (e *PrometheusQueryExecutor) get(ctx context.Context, path string, params map[string]string) ([]byte, error) {
+	return retryQuery(ctx, e.retryPolicy, func() ([]byte, error) {
+		if e.url != "" {
+			return e.getURL(ctx, path, params)
+		}
+		// Equivalent of ProxyGet, which doesn't allow to set the context of the request.
+		request := e.client.CoreV1().RESTClient().Get().
+			Namespace("monitoring").
+			Resource("services").
+			SubResource("proxy").
+			Name(net.JoinSchemeNamePort("http", "prometheus-k8s", "9090")).
+			Suffix(path)
+		for k, v := range params {
+			request = request.Param(k, v)
+		}
+		return request.Context(ctx).DoRaw()
+	})
+}
+
+func (e *PrometheusQueryExecutor) getURL(ctx context.Context, path string, params map[string]string) ([]byte, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	request, err := http.NewRequest(http.MethodGet, e.url+"/"+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := e.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, &queryStatusError{code: response.StatusCode, body: string(body)}
+	}
+	return body, nil
+}
+
+// queryStatusError is returned when Prometheus queried by url responds with non-OK status.
+type queryStatusError struct {
+	code int
+	body string
+}
+
+func (e *queryStatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.code, e.body)
+}
+
+func retryQuery(ctx context.Context, policy QueryRetryPolicy, query func() ([]byte, error)) ([]byte, error) {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		body, err := query()
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil || !IsRetryableQueryError(err) || attempt > policy.Retries {
+			return nil, fmt.Errorf("query error after %d attempts: %v", attempt, err)
+		}
+		logrus.Warningf("Query attempt %d failed, retrying in %v: %v", attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("query error after %d attempts: %v", attempt, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = time.Duration(float64(backoff) * policy.Factor)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// IsRetryableQueryError verifies whether the query error is transient, i.e. server errors (5xx),
+// throttling (429) and query execution timeouts (422). Other errors returned by Prometheus,
+// e.g. bad_data (400) for malformed queries, fail the same way when retried.
+// Errors which aren't returned by a server (e.g. connection resets) are considered transient.
+func IsRetryableQueryError(err error) bool {
+	var code int
+	switch e := err.(type) {
+	case *queryStatusError:
+		code = e.code
+	case apierrs.APIStatus:
+		code = int(e.Status().Code)
+		if apierrs.IsTimeout(err) || apierrs.IsServerTimeout(err) {
+			return true
+		}
+	default:
+		return true
+	}
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests || code == http.StatusUnprocessableEntity
+}
+
 // UnmarshalJSON unmarshals json into promResponseData structure.
 func (qr *promResponseData) UnmarshalJSON(b []byte) error {
 	v := struct {
@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+// NewExternalQueryExecutor creates instance of PrometheusQueryExecutor querying the external
+// Prometheus server described by the given config. Requests are authenticated with bearer token,
+// basic auth and/or client certificate, if configured.
+func NewExternalQueryExecutor(prometheusConfig *config.PrometheusConfig, policy QueryRetryPolicy) (*PrometheusQueryExecutor, error) {
+	if prometheusConfig.Endpoint == "" {
+		return nil, fmt.Errorf("external prometheus endpoint not specified")
+	}
+	transport, err := newAuthTransport(&prometheusConfig.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("external prometheus auth error: %v", err)
+	}
+	executor := NewURLQueryExecutor(prometheusConfig.Endpoint, policy)
+	executor.httpClient.Transport = transport
+	return executor, nil
+}
+
+// GetQueryExecutor returns executor querying the external Prometheus server if it is configured
+// or Prometheus running inside the cluster of the given client otherwise.
+func GetQueryExecutor(c clientset.Interface, prometheusConfig *config.PrometheusConfig, policy QueryRetryPolicy) (*PrometheusQueryExecutor, error) {
+	if prometheusConfig != nil && prometheusConfig.Endpoint != "" {
+		return NewExternalQueryExecutor(prometheusConfig, policy)
+	}
+	return NewQueryExecutorWithRetryPolicy(c, policy), nil
+}
+
+// authTransport adds credentials to every request sent to the external Prometheus server.
+type authTransport struct {
+	auth *config.PrometheusAuthConfig
+	next http.RoundTripper
+}
+
+func newAuthTransport(auth *config.PrometheusAuthConfig) (http.RoundTripper, error) {
+	if (auth.CertFile == "") != (auth.KeyFile == "") {
+		return nil, fmt.Errorf("both client certificate and key have to be specified")
+	}
+	if auth.BearerTokenFile != "" && auth.Username != "" {
+		return nil, fmt.Errorf("bearer token and basic auth are mutually exclusive")
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: auth.InsecureSkipVerify}
+	if auth.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(auth.CertFile, auth.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate error: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if auth.CAFile != "" {
+		ca, err := ioutil.ReadFile(auth.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate error: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no CA certificates found in %s", auth.CAFile)
+		}
+	}
+	next := http.DefaultTransport.(*http.Transport).Clone()
+	next.TLSClientConfig = tlsConfig
+	return &authTransport{auth: auth, next: next}, nil
+}
+
+// RoundTrip implements http.RoundTripper. Credential files are read for every request,
+// so that rotated tokens and passwords are picked up during long tests.
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.auth.BearerTokenFile == "" && t.auth.Username == "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	if t.auth.BearerTokenFile != "" {
+		token, err := readSecretFile(t.auth.BearerTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		var password string
+		if t.auth.PasswordFile != "" {
+			var err error
+			if password, err = readSecretFile(t.auth.PasswordFile); err != nil {
+				return nil, err
+			}
+		}
+		req.SetBasicAuth(t.auth.Username, password)
+	}
+	return t.next.RoundTrip(req)
+}
+
+func readSecretFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading credentials error: %v", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
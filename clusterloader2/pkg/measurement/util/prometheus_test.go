@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func TestRetryQuery(t *testing.T) {
+	policy := QueryRetryPolicy{Retries: 2, Factor: 2}
+	unavailable := apierrs.NewServiceUnavailable("compaction in progress")
+	badRequest := apierrs.NewBadRequest("parse error")
+	cases := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "success", errs: nil, wantAttempts: 1},
+		{name: "transient error", errs: []error{unavailable, errors.New("connection reset")}, wantAttempts: 3},
+		{name: "retries exhausted", errs: []error{unavailable, unavailable, unavailable}, wantAttempts: 3, wantErr: true},
+		{name: "bad request", errs: []error{badRequest}, wantAttempts: 1, wantErr: true},
+		{name: "too many requests", errs: []error{apierrs.NewTooManyRequests("throttled", 1)}, wantAttempts: 2},
+		{name: "server timeout", errs: []error{apierrs.NewServerTimeout(schema.GroupResource{Resource: "services"}, "get", 1)}, wantAttempts: 2},
+		{name: "not found", errs: []error{apierrs.NewNotFound(schema.GroupResource{Resource: "services"}, "prometheus-k8s")}, wantAttempts: 1, wantErr: true},
+		{name: "url query unavailable", errs: []error{&queryStatusError{code: http.StatusServiceUnavailable}}, wantAttempts: 2},
+		{name: "url query execution timeout", errs: []error{&queryStatusError{code: http.StatusUnprocessableEntity, body: `{"status":"error","errorType":"execution"}`}}, wantAttempts: 2},
+		{name: "url query bad data", errs: []error{&queryStatusError{code: http.StatusBadRequest, body: `{"status":"error","errorType":"bad_data"}`}}, wantAttempts: 1, wantErr: true},
+	}
+	for _, tc := range cases {
+		attempts := 0
+		body, err := retryQuery(context.Background(), policy, func() ([]byte, error) {
+			attempts++
+			if attempts <= len(tc.errs) {
+				return nil, tc.errs[attempts-1]
+			}
+			return []byte("ok"), nil
+		})
+		assert.Equal(t, tc.wantAttempts, attempts, tc.name)
+		if tc.wantErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, "ok", string(body), tc.name)
+		}
+	}
+}
+
+func TestURLQueryExecutor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.URL.Query().Get("query") != "up" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {"job": "apiserver"}, "value": [%d, "1"]}]}}`, time.Now().Unix())
+	}))
+	defer server.Close()
+
+	executor := NewURLQueryExecutor(server.URL+"/", QueryRetryPolicy{})
+	samples, err := executor.Query("up", time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, samples, 1) {
+		assert.Equal(t, "apiserver", string(samples[0].Metric["job"]))
+	}
+	_, err = executor.Query("down", time.Now())
+	assert.Error(t, err)
+}
+
+func TestServerTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") != "time()" || r.URL.Query().Get("time") != "" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"status": "success", "data": {"resultType": "scalar", "result": [1546300800.5, "1546300800.5"]}}`)
+	}))
+	defer server.Close()
+
+	serverTime, err := NewURLQueryExecutor(server.URL, QueryRetryPolicy{}).ServerTime()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 500*int(time.Millisecond), time.UTC), serverTime.UTC())
+}
+
+func TestRecordingRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/rules" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"status": "success", "data": {"groups": [{"name": "apiserver.rules", "rules": [
+			{"name": "apiserver:latency:histogram_quantile", "type": "recording"},
+			{"name": "HighLatency", "type": "alerting"}]}]}}`)
+	}))
+	defer server.Close()
+
+	executor := NewURLQueryExecutor(server.URL+"/", QueryRetryPolicy{})
+	rules, err := executor.RecordingRules()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"apiserver:latency:histogram_quantile": true}, rules)
+}
+
+func TestExternalQueryExecutorAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	passwordFile := filepath.Join(dir, "password")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret-token\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(passwordFile, []byte("secret-password"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if r.Header.Get("Authorization") != "Bearer secret-token" && !(ok && user == "admin" && password == "secret-password") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [%d, "1"]}]}}`, time.Now().Unix())
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		auth    config.PrometheusAuthConfig
+		wantErr bool
+	}{
+		{name: "bearer token", auth: config.PrometheusAuthConfig{BearerTokenFile: tokenFile}},
+		{name: "basic auth", auth: config.PrometheusAuthConfig{Username: "admin", PasswordFile: passwordFile}},
+		{name: "wrong password", auth: config.PrometheusAuthConfig{Username: "admin"}, wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+	for _, tc := range cases {
+		executor, err := NewExternalQueryExecutor(&config.PrometheusConfig{Endpoint: server.URL, Auth: tc.auth}, QueryRetryPolicy{})
+		if !assert.NoError(t, err, tc.name) {
+			continue
+		}
+		_, err = executor.Query("vector(1)", time.Now())
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+	}
+
+	_, err = NewExternalQueryExecutor(&config.PrometheusConfig{Endpoint: server.URL, Auth: config.PrometheusAuthConfig{CertFile: "cert.pem"}}, QueryRetryPolicy{})
+	assert.Error(t, err)
+}
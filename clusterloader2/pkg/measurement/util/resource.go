@@ -18,8 +18,9 @@ package util
 
 import (
 	"math"
-	"sort"
 	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/stats"
 )
 
 // ContainerResourceUsage represents resource usage by a single container.
@@ -32,6 +33,18 @@ type ContainerResourceUsage struct {
 	MemoryRSSInBytes        uint64
 	// The interval used to calculate CPUUsageInCores.
 	CPUInterval time.Duration
+	// NetworkBytesPerSecond is the throughput (received and transmitted) of the pod of the container.
+	NetworkBytesPerSecond float64
+	// DiskBytesPerSecond is the throughput of reads and writes of the container.
+	DiskBytesPerSecond float64
+	// DiskIOPS is the number of reads and writes of the container per second.
+	DiskIOPS float64
+	// EphemeralStorageInBytes is the usage of the writable layer and logs of the container.
+	EphemeralStorageInBytes uint64
+	// NetworkBytes is the cumulative number of bytes received and transmitted by the pod of
+	// the container at NetworkTime, used to compute NetworkBytesPerSecond from consecutive samples.
+	NetworkBytes uint64
+	NetworkTime  time.Time
 }
 
 // ResourceUsagePerContainer is a map of ContainerResourceUsage for containers.
@@ -42,12 +55,38 @@ type UsageDataPerContainer struct {
 	CpuData        []float64
 	MemUseData     []uint64
 	MemWorkSetData []uint64
+	NetworkData    []float64
+	DiskData       []float64
+	IOPSData       []float64
+	StorageData    []uint64
 }
 
 // ResourceConstraint specifies constraint on resources.
 type ResourceConstraint struct {
-	CPUConstraint    float64 `json: cpuConstraint`
-	MemoryConstraint uint64  `json: memoryConstraint`
+	CPUConstraint    float64 `json:"cpuConstraint"`
+	MemoryConstraint uint64  `json:"memoryConstraint"`
+	// NetworkConstraint is the maximal throughput (received and transmitted) in bytes per second.
+	NetworkConstraint float64 `json:"networkConstraint,omitempty"`
+	// DiskConstraint is the maximal disk throughput (reads and writes) in bytes per second.
+	DiskConstraint float64 `json:"diskConstraint,omitempty"`
+	// IOPSConstraint is the maximal number of disk reads and writes per second.
+	IOPSConstraint float64 `json:"iopsConstraint,omitempty"`
+	// EphemeralStorageConstraint is the maximal usage of the writable layer and logs in bytes.
+	EphemeralStorageConstraint uint64 `json:"ephemeralStorageConstraint,omitempty"`
+	// RestartCountConstraint is the maximal number of restarts during the test. Unlike other
+	// constraints, zero means that the container can't restart, restarts aren't constrained if it's unset.
+	RestartCountConstraint *int32 `json:"restartCountConstraint,omitempty"`
+	// NodeGroups contain constraints of the container running on specific groups of nodes, e.g. masters
+	// or windows nodes. The first group matching the node of the container is used and constraints
+	// not set in the group are taken from the constraint of the container.
+	NodeGroups []NodeGroupConstraint `json:"nodeGroups,omitempty"`
+}
+
+// NodeGroupConstraint specifies constraint on resources of containers running on nodes matching the selector.
+type NodeGroupConstraint struct {
+	// NodeSelector is a label selector of nodes of the group, e.g. kubernetes.io/os=windows.
+	NodeSelector string `json:"nodeSelector"`
+	ResourceConstraint
 }
 
 // SingleContainerSummary is a resource usage summary for a single container.
@@ -55,14 +94,14 @@ type SingleContainerSummary struct {
 	Name string
 	Cpu  float64
 	Mem  uint64
+	// Network, disk and ephemeral storage usage are omitted if they weren't observed,
+	// e.g. disk usage isn't reported by kubelets.
+	Network          float64 `json:",omitempty"`
+	Disk             float64 `json:",omitempty"`
+	IOPS             float64 `json:",omitempty"`
+	EphemeralStorage uint64  `json:",omitempty"`
 }
 
-type uint64arr []uint64
-
-func (a uint64arr) Len() int           { return len(a) }
-func (a uint64arr) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a uint64arr) Less(i, j int) bool { return a[i] < a[j] }
-
 // ComputePercentiles calculates percentiles for given data series.
 func ComputePercentiles(timeSeries []ResourceUsagePerContainer, percentilesToCompute []int) map[int]ResourceUsagePerContainer {
 	if len(timeSeries) == 0 {
@@ -76,29 +115,51 @@ func ComputePercentiles(timeSeries []ResourceUsagePerContainer, percentilesToCom
 					CpuData:        make([]float64, 0, len(timeSeries)),
 					MemUseData:     make([]uint64, 0, len(timeSeries)),
 					MemWorkSetData: make([]uint64, 0, len(timeSeries)),
+					NetworkData:    make([]float64, 0, len(timeSeries)),
+					DiskData:       make([]float64, 0, len(timeSeries)),
+					IOPSData:       make([]float64, 0, len(timeSeries)),
+					StorageData:    make([]uint64, 0, len(timeSeries)),
 				}
 			}
 			dataMap[name].CpuData = append(dataMap[name].CpuData, data.CPUUsageInCores)
 			dataMap[name].MemUseData = append(dataMap[name].MemUseData, data.MemoryUsageInBytes)
 			dataMap[name].MemWorkSetData = append(dataMap[name].MemWorkSetData, data.MemoryWorkingSetInBytes)
+			dataMap[name].NetworkData = append(dataMap[name].NetworkData, data.NetworkBytesPerSecond)
+			dataMap[name].DiskData = append(dataMap[name].DiskData, data.DiskBytesPerSecond)
+			dataMap[name].IOPSData = append(dataMap[name].IOPSData, data.DiskIOPS)
+			dataMap[name].StorageData = append(dataMap[name].StorageData, data.EphemeralStorageInBytes)
 		}
 	}
-	for _, v := range dataMap {
-		sort.Float64s(v.CpuData)
-		sort.Sort(uint64arr(v.MemUseData))
-		sort.Sort(uint64arr(v.MemWorkSetData))
+	type sortedData struct {
+		cpu, memUse, memWorkSet, network, disk, iops, storage []float64
+	}
+	sortedDataMap := make(map[string]sortedData, len(dataMap))
+	for k, v := range dataMap {
+		sortedDataMap[k] = sortedData{
+			cpu:        stats.Sorted(v.CpuData),
+			memUse:     stats.Sorted(uint64sToFloat64s(v.MemUseData)),
+			memWorkSet: stats.Sorted(uint64sToFloat64s(v.MemWorkSetData)),
+			network:    stats.Sorted(v.NetworkData),
+			disk:       stats.Sorted(v.DiskData),
+			iops:       stats.Sorted(v.IOPSData),
+			storage:    stats.Sorted(uint64sToFloat64s(v.StorageData)),
+		}
 	}
 
 	result := make(map[int]ResourceUsagePerContainer)
 	for _, perc := range percentilesToCompute {
+		q := float64(perc) / 100
 		data := make(ResourceUsagePerContainer)
-		for k, v := range dataMap {
-			percentileIndex := int(math.Ceil(float64(len(v.CpuData)*perc)/100)) - 1
+		for k, v := range sortedDataMap {
 			data[k] = &ContainerResourceUsage{
 				Name:                    k,
-				CPUUsageInCores:         v.CpuData[percentileIndex],
-				MemoryUsageInBytes:      v.MemUseData[percentileIndex],
-				MemoryWorkingSetInBytes: v.MemWorkSetData[percentileIndex],
+				CPUUsageInCores:         stats.Quantile(v.cpu, q),
+				MemoryUsageInBytes:      uint64(math.Round(stats.Quantile(v.memUse, q))),
+				MemoryWorkingSetInBytes: uint64(math.Round(stats.Quantile(v.memWorkSet, q))),
+				NetworkBytesPerSecond:   stats.Quantile(v.network, q),
+				DiskBytesPerSecond:      stats.Quantile(v.disk, q),
+				DiskIOPS:                stats.Quantile(v.iops, q),
+				EphemeralStorageInBytes: uint64(math.Round(stats.Quantile(v.storage, q))),
 			}
 		}
 		result[perc] = data
@@ -106,6 +167,14 @@ func ComputePercentiles(timeSeries []ResourceUsagePerContainer, percentilesToCom
 	return result
 }
 
+func uint64sToFloat64s(values []uint64) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		result[i] = float64(values[i])
+	}
+	return result
+}
+
 // LeftMergeData merges two data structures.
 func LeftMergeData(left, right map[int]ResourceUsagePerContainer) map[int]ResourceUsagePerContainer {
 	result := make(map[int]ResourceUsagePerContainer)
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputePercentiles(t *testing.T) {
+	var timeSeries []ResourceUsagePerContainer
+	for _, v := range []uint64{300, 100, 200} {
+		timeSeries = append(timeSeries, ResourceUsagePerContainer{
+			"etcd": {Name: "etcd", CPUUsageInCores: float64(v) / 100, MemoryUsageInBytes: v, MemoryWorkingSetInBytes: 2 * v},
+		})
+	}
+	result := ComputePercentiles(timeSeries, []int{50, 90})
+	assert.Equal(t, &ContainerResourceUsage{Name: "etcd", CPUUsageInCores: 2, MemoryUsageInBytes: 200, MemoryWorkingSetInBytes: 400}, result[50]["etcd"])
+	assert.Equal(t, uint64(280), result[90]["etcd"].MemoryUsageInBytes)
+	assert.InDelta(t, 2.8, result[90]["etcd"].CPUUsageInCores, 1e-9)
+	assert.Empty(t, ComputePercentiles(nil, []int{50}))
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtimeobjects
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxOwnerChainLength limits the number of owner references followed from an object to its root owner.
+const maxOwnerChainLength = 10
+
+// OwnerResolver checks whether objects are owned by a given object, directly or through a chain
+// of owner references, e.g. pod -> ReplicaSet -> Deployment -> custom resource.
+// Owner references of intermediate owners are fetched once and cached.
+type OwnerResolver struct {
+	client dynamic.Interface
+	lock   sync.Mutex
+	// owners maps UIDs of intermediate owners to their owner references.
+	owners map[types.UID][]metav1.OwnerReference
+}
+
+// NewOwnerResolver creates new OwnerResolver.
+func NewOwnerResolver(c dynamic.Interface) *OwnerResolver {
+	return &OwnerResolver{
+		client: c,
+		owners: make(map[types.UID][]metav1.OwnerReference),
+	}
+}
+
+// IsOwnedBy returns whether object in given namespace with given owner references is owned by
+// the object with given UID. Owners that can't be fetched are treated as not owned by it.
+func (r *OwnerResolver) IsOwnedBy(namespace string, ownerReferences []metav1.OwnerReference, uid types.UID) bool {
+	visited := make(map[types.UID]bool)
+	refs := ownerReferences
+	for i := 0; i < maxOwnerChainLength && len(refs) > 0; i++ {
+		var next []metav1.OwnerReference
+		for _, ref := range refs {
+			if ref.UID == uid {
+				return true
+			}
+			if visited[ref.UID] {
+				continue
+			}
+			visited[ref.UID] = true
+			next = append(next, r.ownerReferencesOf(namespace, ref)...)
+		}
+		refs = next
+	}
+	return false
+}
+
+func (r *OwnerResolver) ownerReferencesOf(namespace string, ref metav1.OwnerReference) []metav1.OwnerReference {
+	r.lock.Lock()
+	refs, ok := r.owners[ref.UID]
+	r.lock.Unlock()
+	if ok {
+		return refs
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		logrus.Errorf("Parsing api version of owner %s %s error: %v", ref.Kind, ref.Name, err)
+		return nil
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gv.WithKind(ref.Kind))
+	// Owners are either in the same namespace or cluster scoped.
+	obj, err := r.client.Resource(gvr).Namespace(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		obj, err = r.client.Resource(gvr).Get(ref.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		// Owner may not exist yet or anymore, it's checked again next time.
+		logrus.Debugf("Getting owner %s %s/%s error: %v", ref.Kind, namespace, ref.Name, err)
+		return nil
+	}
+	if obj.GetUID() != ref.UID {
+		return nil
+	}
+	refs = obj.GetOwnerReferences()
+	r.lock.Lock()
+	r.owners[ref.UID] = refs
+	r.lock.Unlock()
+	return refs
+}
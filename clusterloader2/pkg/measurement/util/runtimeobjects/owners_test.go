@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtimeobjects
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func ownerRef(kind string, uid types.UID) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: "v1", Kind: kind, Name: string(uid), UID: uid}
+}
+
+func TestIsOwnedBy(t *testing.T) {
+	// Owner references of intermediate owners are already cached, so the client isn't used.
+	resolver := NewOwnerResolver(nil)
+	resolver.owners = map[types.UID][]metav1.OwnerReference{
+		"replicaset": {ownerRef("Deployment", "deployment")},
+		"deployment": {ownerRef("Workload", "workload")},
+		"workload":   nil,
+		// Cycles are not followed forever.
+		"cycle-a": {ownerRef("Cycle", "cycle-b")},
+		"cycle-b": {ownerRef("Cycle", "cycle-a")},
+	}
+	podRefs := []metav1.OwnerReference{ownerRef("ReplicaSet", "replicaset")}
+
+	for _, tc := range []struct {
+		name  string
+		refs  []metav1.OwnerReference
+		owner types.UID
+		want  bool
+	}{
+		{name: "direct owner", refs: podRefs, owner: "replicaset", want: true},
+		{name: "owner of owner", refs: podRefs, owner: "deployment", want: true},
+		{name: "root owner", refs: podRefs, owner: "workload", want: true},
+		{name: "other owner", refs: podRefs, owner: "other", want: false},
+		{name: "no owners", refs: nil, owner: "workload", want: false},
+		{name: "cycle", refs: []metav1.OwnerReference{ownerRef("Cycle", "cycle-a")}, owner: "other", want: false},
+	} {
+		if got := resolver.IsOwnedBy("ns", tc.refs, tc.owner); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
@@ -127,6 +128,29 @@ func ListRuntimeObjectsForKind(c clientset.Interface, kind, namespace, labelSele
 	return runtimeObjectsList, nil
 }
 
+// ListRuntimeObjectsForResource returns objects of given resource that satisfy given namespace, labelSelector and fieldSelector.
+func ListRuntimeObjectsForResource(c dynamic.Interface, gvr schema.GroupVersionResource, namespace, labelSelector, fieldSelector string) ([]runtime.Object, error) {
+	var runtimeObjectsList []runtime.Object
+	listFunc := func() error {
+		list, err := c.Resource(gvr).Namespace(namespace).List(metav1.ListOptions{
+			LabelSelector: labelSelector,
+			FieldSelector: fieldSelector,
+		})
+		if err != nil {
+			return err
+		}
+		runtimeObjectsList = make([]runtime.Object, len(list.Items))
+		for i := range list.Items {
+			runtimeObjectsList[i] = &list.Items[i]
+		}
+		return nil
+	}
+	if err := client.RetryWithExponentialBackOff(client.RetryFunction(listFunc)); err != nil {
+		return nil, err
+	}
+	return runtimeObjectsList, nil
+}
+
 // GetNameFromRuntimeObject returns name of given runtime object.
 func GetNameFromRuntimeObject(obj runtime.Object) (string, error) {
 	switch typed := obj.(type) {
@@ -141,6 +165,15 @@ func GetNameFromRuntimeObject(obj runtime.Object) (string, error) {
 	}
 }
 
+// GetUIDFromRuntimeObject returns UID of given runtime object.
+func GetUIDFromRuntimeObject(obj runtime.Object) (types.UID, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("accessor error: %v", err)
+	}
+	return accessor.GetUID(), nil
+}
+
 // GetResourceVersionFromRuntimeObject returns resource version of given runtime object.
 func GetResourceVersionFromRuntimeObject(obj runtime.Object) (uint64, error) {
 	accessor, err := meta.Accessor(obj)
@@ -168,6 +201,17 @@ func GetNamespaceFromRuntimeObject(obj runtime.Object) (string, error) {
 	}
 }
 
+// HasPodSelector returns whether objects of given kind select their pods with a label selector
+// in spec.selector, which is true for built-in controllers.
+func HasPodSelector(kind string) bool {
+	switch kind {
+	case "ReplicationController", "ReplicaSet", "Deployment", "StatefulSet", "DaemonSet", "Job":
+		return true
+	default:
+		return false
+	}
+}
+
 // GetSelectorFromRuntimeObject returns selector of given runtime object.
 func GetSelectorFromRuntimeObject(obj runtime.Object) (labels.Selector, error) {
 	switch typed := obj.(type) {
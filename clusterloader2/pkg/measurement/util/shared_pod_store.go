@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+	"time"
+
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// sharedPodStoreIdleTimeout is how long a shared pod store is kept after its last user stops,
+// so that consecutive users (e.g. WaitForPods called in a loop) don't relist pods.
+const sharedPodStoreIdleTimeout = time.Minute
+
+// SharedPodStores provides pod stores shared by all users of the same object selector,
+// so that measurements and waits for the same pods reuse a single list and watch.
+// It is owned by the framework, see framework.Framework.GetPodStores.
+type SharedPodStores struct {
+	newStore func(selector *ObjectSelector) (*ObjectStore, error)
+
+	lock   sync.Mutex
+	stores map[string]*sharedPodStore
+}
+
+// sharedPodStore is a reference counted pod store of a single selector.
+type sharedPodStore struct {
+	// synced is closed once the store is initialized (or failed to initialize).
+	synced chan struct{}
+	store  *ObjectStore
+	err    error
+	// users and idleTimer are guarded by the lock of SharedPodStores.
+	users     int
+	idleTimer *time.Timer
+}
+
+// NewSharedPodStores creates SharedPodStores listing and watching pods with the given client.
+func NewSharedPodStores(c clientset.Interface) *SharedPodStores {
+	return &SharedPodStores{
+		newStore: func(selector *ObjectSelector) (*ObjectStore, error) {
+			return newPodObjectStore(c, selector)
+		},
+		stores: make(map[string]*sharedPodStore),
+	}
+}
+
+// Get returns synced pod store of the selector, sharing it with other users of the same selector.
+// The store has to be stopped once it's no longer used.
+func (s *SharedPodStores) Get(selector *ObjectSelector) (*PodStore, error) {
+	key := selector.String()
+	s.lock.Lock()
+	shared, ok := s.stores[key]
+	if !ok {
+		shared = &sharedPodStore{synced: make(chan struct{})}
+		s.stores[key] = shared
+	}
+	shared.users++
+	if shared.idleTimer != nil {
+		shared.idleTimer.Stop()
+		shared.idleTimer = nil
+	}
+	s.lock.Unlock()
+
+	// Initial sync is done without holding the lock, so that stores of other selectors are not blocked by it.
+	if !ok {
+		shared.store, shared.err = s.newStore(selector)
+		close(shared.synced)
+	}
+	<-shared.synced
+	release := func() { s.release(key, shared) }
+	if shared.err != nil {
+		release()
+		return nil, shared.err
+	}
+	return &PodStore{ObjectStore: shared.store, release: release}, nil
+}
+
+// release stops the store if it isn't acquired again within idle timeout.
+func (s *SharedPodStores) release(key string, shared *sharedPodStore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	shared.users--
+	if shared.users > 0 {
+		return
+	}
+	remove := func() {
+		if s.stores[key] == shared {
+			delete(s.stores, key)
+		}
+		if shared.store != nil {
+			shared.store.Stop()
+		}
+	}
+	if shared.err != nil {
+		// Failed stores are removed right away, so that the next user retries.
+		remove()
+		return
+	}
+	shared.idleTimer = time.AfterFunc(sharedPodStoreIdleTimeout, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if shared.users == 0 {
+			remove()
+		}
+	})
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSharedPodStores(t *testing.T) {
+	created := make(map[string]int)
+	failing := true
+	stores := &SharedPodStores{
+		newStore: func(selector *ObjectSelector) (*ObjectStore, error) {
+			if selector.Namespace == "failing" && failing {
+				return nil, fmt.Errorf("list error")
+			}
+			created[selector.String()]++
+			return &ObjectStore{Store: cache.NewStore(cache.MetaNamespaceKeyFunc), stopCh: make(chan struct{})}, nil
+		},
+		stores: make(map[string]*sharedPodStore),
+	}
+	selector := &ObjectSelector{Namespace: "a", LabelSelector: "group=load"}
+
+	first, err := stores.Get(selector)
+	assert.NoError(t, err)
+	second, err := stores.Get(&ObjectSelector{Namespace: "a", LabelSelector: "group=load"})
+	assert.NoError(t, err)
+	other, err := stores.Get(&ObjectSelector{Namespace: "b"})
+	assert.NoError(t, err)
+	assert.True(t, first.ObjectStore == second.ObjectStore, "stores of the same selector should be shared")
+	assert.False(t, first.ObjectStore == other.ObjectStore, "stores of different selectors shouldn't be shared")
+
+	first.Stop()
+	first.Stop()
+	second.Stop()
+	// The store is kept for the next user within idle timeout.
+	third, err := stores.Get(selector)
+	assert.NoError(t, err)
+	assert.True(t, first.ObjectStore == third.ObjectStore, "idle store should be reused")
+	third.Stop()
+	other.Stop()
+	assert.Equal(t, 1, created[selector.String()])
+
+	_, err = stores.Get(&ObjectSelector{Namespace: "failing"})
+	assert.Error(t, err)
+	failing = false
+	// Failed stores are not cached.
+	recovered, err := stores.Get(&ObjectSelector{Namespace: "failing"})
+	assert.NoError(t, err)
+	recovered.Stop()
+}
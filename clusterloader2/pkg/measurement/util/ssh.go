@@ -24,6 +24,7 @@ import (
 
 	"golang.org/x/crypto/ssh"
 	sshutil "k8s.io/kubernetes/pkg/ssh"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
 )
 
 // GetMasterHost turns host name (without prefix and port).
@@ -73,6 +74,15 @@ func SSH(cmd, host, provider string) (SSHResult, error) {
 // getSigner returns an ssh.Signer for the provider ("gce", etc.) that can be
 // used to SSH to their nodes.
 func getSigner(provider string) (ssh.Signer, error) {
+	// prefer the key provided explicitly by credential sources
+	key, err := credentials.Get(credentials.SSHPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		return ssh.ParsePrivateKey([]byte(key))
+	}
+
 	// honor a consistent SSH key across all providers
 	if path := os.Getenv("KUBE_SSH_KEY_PATH"); len(path) > 0 {
 		return sshutil.MakePrivateKeySignerFromFile(path)
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Histogram counts values in buckets with given upper bounds. Values greater than
+// all bounds are counted in +Inf bucket.
+type Histogram struct {
+	// Buckets maps upper bounds of buckets to cumulative counts of values not greater than the bound.
+	Buckets map[float64]float64
+}
+
+// NewHistogram creates empty histogram with given upper bounds of buckets.
+func NewHistogram(upperBounds []float64) *Histogram {
+	h := &Histogram{Buckets: map[float64]float64{math.Inf(1): 0}}
+	for _, bound := range upperBounds {
+		h.Buckets[bound] = 0
+	}
+	return h
+}
+
+// Observe adds a value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	for bound := range h.Buckets {
+		if value <= bound {
+			h.Buckets[bound]++
+		}
+	}
+}
+
+// Quantile returns q-quantile of observed values, see HistogramQuantile.
+func (h *Histogram) Quantile(q float64) float64 {
+	return HistogramQuantile(q, h.Buckets)
+}
+
+// HistogramQuantile calculates quantile from cumulative histogram buckets keyed by upper bound,
+// interpolating linearly within the bucket, like histogram_quantile function of prometheus does.
+// If the quantile falls into +Inf bucket, the highest finite bound is returned.
+func HistogramQuantile(q float64, buckets map[float64]float64) float64 {
+	if len(buckets) == 0 {
+		return 0
+	}
+	var bounds []float64
+	for bound := range buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+	total := buckets[bounds[len(bounds)-1]]
+	if total == 0 {
+		return 0
+	}
+	rank := q * total
+	lowerBound, lowerCount := 0.0, 0.0
+	for _, bound := range bounds {
+		count := buckets[bound]
+		if count >= rank {
+			if math.IsInf(bound, 1) {
+				return lowerBound
+			}
+			if count == lowerCount {
+				return bound
+			}
+			return lowerBound + (bound-lowerBound)*(rank-lowerCount)/(count-lowerCount)
+		}
+		lowerBound, lowerCount = bound, count
+	}
+	return lowerBound
+}
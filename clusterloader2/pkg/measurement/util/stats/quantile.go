@@ -0,0 +1,45 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+)
+
+// Quantile returns q-quantile (0 <= q <= 1) of values sorted in increasing order.
+// The result is linearly interpolated between the two closest ranks, the same way
+// as in most statistical packages (e.g. numpy's default method), so it isn't biased
+// towards higher values for small number of samples. Quantile of no values is 0.
+func Quantile(sortedValues []float64, q float64) float64 {
+	length := len(sortedValues)
+	if length == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return sortedValues[0]
+	}
+	if q >= 1 {
+		return sortedValues[length-1]
+	}
+	rank := q * float64(length-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= length {
+		return sortedValues[lower]
+	}
+	fraction := rank - float64(lower)
+	return sortedValues[lower] + fraction*(sortedValues[lower+1]-sortedValues[lower])
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuantile(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []float64
+		q      float64
+		want   float64
+	}{
+		{name: "no values", values: nil, q: 0.5, want: 0},
+		{name: "single value", values: []float64{7}, q: 0.99, want: 7},
+		{name: "median of even count", values: []float64{1, 2, 3, 4}, q: 0.5, want: 2.5},
+		{name: "median of odd count", values: []float64{1, 2, 3}, q: 0.5, want: 2},
+		{name: "perc90 of two values", values: []float64{10, 20}, q: 0.9, want: 19},
+		{name: "perc99 of ten values", values: []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, q: 0.99, want: 9.91},
+		{name: "minimum", values: []float64{1, 2, 3}, q: 0, want: 1},
+		{name: "maximum", values: []float64{1, 2, 3}, q: 1, want: 3},
+	}
+	for _, tc := range cases {
+		assert.InDelta(t, tc.want, Quantile(tc.values, tc.q), 1e-9, tc.name)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultSketchAccuracy is the relative error of quantiles estimated by sketches,
+	// which is small enough for results of measurements to be compared across runs.
+	DefaultSketchAccuracy = 0.01
+
+	// minSketchValue is the smallest absolute value distinguished from zero by sketches.
+	minSketchValue = 1e-9
+)
+
+// Sketch summarizes a stream of values in bounded memory, so that measurements don't have to keep
+// all samples of long runs. Values are counted in buckets with exponentially growing bounds, like in
+// HDR histograms, so quantiles are estimated with relative error not greater than the accuracy.
+// Count, minimum, maximum, mean and standard deviation are exact.
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	// positive and negative count values by index of the bucket of their absolute value.
+	positive map[int]uint64
+	negative map[int]uint64
+	zeros    uint64
+
+	count    uint64
+	min, max float64
+	mean     float64
+	// m2 is the sum of squared differences from the mean, see Welford's algorithm.
+	m2 float64
+}
+
+// NewSketch creates empty sketch estimating quantiles with given relative accuracy, e.g. 0.01.
+func NewSketch(accuracy float64) *Sketch {
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &Sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: make(map[int]uint64),
+		negative: make(map[int]uint64),
+	}
+}
+
+// Add adds the value to the sketch.
+func (s *Sketch) Add(value float64) {
+	s.AddN(value, 1)
+}
+
+// AddN adds n occurrences of the value to the sketch.
+func (s *Sketch) AddN(value float64, n uint64) {
+	if n == 0 {
+		return
+	}
+	switch {
+	case value >= minSketchValue:
+		s.positive[s.index(value)] += n
+	case value <= -minSketchValue:
+		s.negative[s.index(-value)] += n
+	default:
+		s.zeros += n
+	}
+	if s.count == 0 || value < s.min {
+		s.min = value
+	}
+	if s.count == 0 || value > s.max {
+		s.max = value
+	}
+	count := s.count + n
+	delta := value - s.mean
+	s.mean += delta * float64(n) / float64(count)
+	s.m2 += delta * delta * float64(s.count) * float64(n) / float64(count)
+	s.count = count
+}
+
+// Merge adds values of the other sketch, which has to be created with the same accuracy.
+func (s *Sketch) Merge(other *Sketch) {
+	if other.count == 0 {
+		return
+	}
+	for index, count := range other.positive {
+		s.positive[index] += count
+	}
+	for index, count := range other.negative {
+		s.negative[index] += count
+	}
+	s.zeros += other.zeros
+	if s.count == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if s.count == 0 || other.max > s.max {
+		s.max = other.max
+	}
+	count := s.count + other.count
+	delta := other.mean - s.mean
+	s.mean += delta * float64(other.count) / float64(count)
+	s.m2 += other.m2 + delta*delta*float64(s.count)*float64(other.count)/float64(count)
+	s.count = count
+}
+
+// Count returns the number of added values.
+func (s *Sketch) Count() uint64 {
+	return s.count
+}
+
+// Quantile returns estimate of q-quantile (0 <= q <= 1) of added values, interpolated between
+// the two closest ranks, like Quantile. Quantile of no values is 0.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	rank := q * float64(s.count-1)
+	lower := uint64(math.Floor(rank))
+	fraction := rank - float64(lower)
+	lowerValue := s.valueAt(lower)
+	if fraction == 0 {
+		return lowerValue
+	}
+	return lowerValue + fraction*(s.valueAt(lower+1)-lowerValue)
+}
+
+// Summary returns summary of added values.
+func (s *Sketch) Summary() Summary {
+	if s.count == 0 {
+		return Summary{}
+	}
+	return Summary{
+		Count:  int(s.count),
+		Min:    s.min,
+		Max:    s.max,
+		Mean:   s.mean,
+		StdDev: math.Sqrt(s.m2 / float64(s.count)),
+		Perc50: s.Quantile(0.5),
+		Perc90: s.Quantile(0.9),
+		Perc99: s.Quantile(0.99),
+	}
+}
+
+// index returns index of the bucket of the positive value.
+func (s *Sketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// bucketValue returns the value representing the bucket, whose relative distance from all values
+// in the bucket is not greater than the accuracy.
+func (s *Sketch) bucketValue(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (s.gamma + 1)
+}
+
+// valueAt returns estimate of the value with given rank (starting from 0) among sorted values.
+func (s *Sketch) valueAt(rank uint64) float64 {
+	value := s.findValueAt(rank)
+	return math.Max(s.min, math.Min(s.max, value))
+}
+
+func (s *Sketch) findValueAt(rank uint64) float64 {
+	// Negative values are visited from the highest absolute value.
+	indexes := sortedIndexes(s.negative)
+	for i := len(indexes) - 1; i >= 0; i-- {
+		count := s.negative[indexes[i]]
+		if rank < count {
+			return -s.bucketValue(indexes[i])
+		}
+		rank -= count
+	}
+	if rank < s.zeros {
+		return 0
+	}
+	rank -= s.zeros
+	for _, index := range sortedIndexes(s.positive) {
+		count := s.positive[index]
+		if rank < count {
+			return s.bucketValue(index)
+		}
+		rank -= count
+	}
+	return s.max
+}
+
+func sortedIndexes(buckets map[int]uint64) []int {
+	indexes := make([]int, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchAccuracy(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sketch := NewSketch(DefaultSketchAccuracy)
+	var values []float64
+	for i := 0; i < 100000; i++ {
+		// Exponentially distributed latencies spanning a few orders of magnitude.
+		value := random.ExpFloat64() * 0.1
+		values = append(values, value)
+		sketch.Add(value)
+	}
+	exact := Summarize(values)
+	estimated := sketch.Summary()
+	assert.Equal(t, exact.Count, estimated.Count)
+	assert.Equal(t, exact.Min, estimated.Min)
+	assert.Equal(t, exact.Max, estimated.Max)
+	assert.InDelta(t, exact.Mean, estimated.Mean, 1e-9)
+	assert.InDelta(t, exact.StdDev, estimated.StdDev, 1e-9)
+	for _, pair := range [][2]float64{{exact.Perc50, estimated.Perc50}, {exact.Perc90, estimated.Perc90}, {exact.Perc99, estimated.Perc99}} {
+		assert.InEpsilon(t, pair[0], pair[1], DefaultSketchAccuracy)
+	}
+	// Memory is bounded by the range of values, not their number.
+	assert.True(t, len(sketch.positive) < 2000, "%d buckets", len(sketch.positive))
+}
+
+func TestSketchZerosAndNegativeValues(t *testing.T) {
+	sketch := NewSketch(DefaultSketchAccuracy)
+	assert.Equal(t, Summary{}, sketch.Summary())
+	sketch.AddN(0, 3)
+	sketch.Add(-10)
+	sketch.Add(5)
+	assert.Equal(t, uint64(5), sketch.Count())
+	assert.Equal(t, -10.0, sketch.Quantile(0))
+	assert.Equal(t, 0.0, sketch.Quantile(0.5))
+	assert.Equal(t, 5.0, sketch.Quantile(1))
+	assert.InEpsilon(t, -6, sketch.Quantile(0.1), DefaultSketchAccuracy)
+	assert.InDelta(t, -1, sketch.Summary().Mean, 1e-9)
+	assert.InDelta(t, math.Sqrt(24), sketch.Summary().StdDev, 1e-9)
+}
+
+func TestSketchMerge(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	merged, whole := NewSketch(DefaultSketchAccuracy), NewSketch(DefaultSketchAccuracy)
+	for i := 0; i < 10; i++ {
+		part := NewSketch(DefaultSketchAccuracy)
+		for j := 0; j < 1000; j++ {
+			value := random.NormFloat64() * float64(i+1)
+			part.Add(value)
+			whole.Add(value)
+		}
+		merged.Merge(part)
+	}
+	merged.Merge(NewSketch(DefaultSketchAccuracy))
+	expected, actual := whole.Summary(), merged.Summary()
+	assert.Equal(t, expected.Count, actual.Count)
+	assert.Equal(t, expected.Min, actual.Min)
+	assert.Equal(t, expected.Max, actual.Max)
+	assert.InDelta(t, expected.Mean, actual.Mean, 1e-9)
+	assert.InDelta(t, expected.StdDev, actual.StdDev, 1e-9)
+	assert.Equal(t, expected.Perc50, actual.Perc50)
+	assert.Equal(t, expected.Perc99, actual.Perc99)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package stats provides statistics computed by measurements from client-side samples,
+// so that all measurements compute them the same way.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes distribution of a set of values.
+type Summary struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+	Perc50 float64
+	Perc90 float64
+	Perc99 float64
+}
+
+// Summarize computes summary of given values. The values don't have to be sorted,
+// they are not modified. Summary of no values is zero.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := Sorted(values)
+	return Summary{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   Mean(sorted),
+		StdDev: StdDev(sorted),
+		Perc50: Quantile(sorted, 0.5),
+		Perc90: Quantile(sorted, 0.9),
+		Perc99: Quantile(sorted, 0.99),
+	}
+}
+
+// Sorted returns sorted copy of given values.
+func Sorted(values []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// Mean returns arithmetic mean of values. Mean of no values is 0.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev returns population standard deviation of values. Standard deviation of no values is 0.
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+// JainIndex returns Jain's fairness index of values, (sum x)^2 / (n * sum x^2). It ranges from 1/n,
+// if a single value is non-zero, to 1, if all values are equal. Index of no or only zero values is 1.
+func JainIndex(values []float64) float64 {
+	sum, sumOfSquares := 0.0, 0.0
+	for _, v := range values {
+		sum += v
+		sumOfSquares += v * v
+	}
+	if sumOfSquares == 0 {
+		return 1
+	}
+	return sum * sum / (float64(len(values)) * sumOfSquares)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	values := []float64{4, 2, 8, 6}
+	assert.Equal(t, Summary{}, Summarize(nil))
+	summary := Summarize(values)
+	assert.Equal(t, 4, summary.Count)
+	assert.Equal(t, 2.0, summary.Min)
+	assert.Equal(t, 8.0, summary.Max)
+	assert.Equal(t, 5.0, summary.Mean)
+	assert.InDelta(t, math.Sqrt(5), summary.StdDev, 1e-9)
+	assert.Equal(t, 5.0, summary.Perc50)
+	assert.InDelta(t, 7.4, summary.Perc90, 1e-9)
+	assert.InDelta(t, 7.94, summary.Perc99, 1e-9)
+	assert.Equal(t, []float64{4, 2, 8, 6}, values, "values shouldn't be modified")
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	assert.Equal(t, 0.0, Mean(nil))
+	assert.Equal(t, 0.0, StdDev(nil))
+	assert.Equal(t, 0.0, StdDev([]float64{3, 3, 3}))
+	assert.Equal(t, 2.0, StdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}))
+}
+
+func TestJainIndex(t *testing.T) {
+	assert.Equal(t, 1.0, JainIndex(nil))
+	assert.Equal(t, 1.0, JainIndex([]float64{0, 0}))
+	assert.Equal(t, 1.0, JainIndex([]float64{5, 5, 5, 5}))
+	assert.Equal(t, 0.25, JainIndex([]float64{8, 0, 0, 0}))
+	assert.InDelta(t, 0.8, JainIndex([]float64{1, 3}), 1e-9)
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := map[float64]float64{0.1: 50, 0.5: 90, 1: 100, math.Inf(1): 100}
+	assert.Equal(t, 0.1, HistogramQuantile(0.5, buckets))
+	assert.InDelta(t, 0.5, HistogramQuantile(0.9, buckets), 1e-9)
+	assert.InDelta(t, 0.95, HistogramQuantile(0.99, buckets), 1e-9)
+	assert.Equal(t, 1.0, HistogramQuantile(0.99, map[float64]float64{1: 0, math.Inf(1): 10}))
+	assert.Equal(t, 0.0, HistogramQuantile(0.99, nil))
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 4})
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		h.Observe(v)
+	}
+	assert.Equal(t, map[float64]float64{1: 2, 2: 3, 4: 4, math.Inf(1): 5}, h.Buckets)
+	assert.Equal(t, 1.0, h.Quantile(0.4))
+	assert.Equal(t, 4.0, h.Quantile(0.99))
+}
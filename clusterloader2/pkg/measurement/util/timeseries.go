@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// TimeSeriesPoint represents a single value of a time series.
+type TimeSeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// TimeSeries represents values of a single metric over time.
+type TimeSeries struct {
+	// Labels identify the metric.
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []TimeSeriesPoint `json:"points"`
+}
+
+// TimeSeriesSummary is a summary presenting a set of time series.
+type TimeSeriesSummary struct {
+	Version string        `json:"version"`
+	Series  []*TimeSeries `json:"series"`
+}
+
+// NewTimeSeries converts results of Prometheus range query into time series.
+// Time series are sorted by their labels.
+func NewTimeSeries(streams []*model.SampleStream) []*TimeSeries {
+	type labeledSeries struct {
+		key    string
+		series *TimeSeries
+	}
+	result := make([]labeledSeries, 0, len(streams))
+	for _, stream := range streams {
+		series := &TimeSeries{Points: make([]TimeSeriesPoint, 0, len(stream.Values))}
+		if len(stream.Metric) > 0 {
+			series.Labels = make(map[string]string)
+			for name, value := range stream.Metric {
+				series.Labels[string(name)] = string(value)
+			}
+		}
+		for _, value := range stream.Values {
+			series.Points = append(series.Points, TimeSeriesPoint{
+				Timestamp: value.Timestamp.Time().UTC(),
+				Value:     float64(value.Value),
+			})
+		}
+		result = append(result, labeledSeries{key: stream.Metric.String(), series: series})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key < result[j].key })
+	timeSeries := make([]*TimeSeries, 0, len(result))
+	for i := range result {
+		timeSeries = append(timeSeries, result[i].series)
+	}
+	return timeSeries
+}
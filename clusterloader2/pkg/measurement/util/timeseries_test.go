@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimeSeriesFromRangeQuery(t *testing.T) {
+	response := `{
+		"status": "success",
+		"data": {
+			"resultType": "matrix",
+			"result": [
+				{"metric": {"queue": "unschedulable"}, "values": [[1546300800, "2"]]},
+				{"metric": {"queue": "active"}, "values": [[1546300800, "10"], [1546300860, "20"]]}
+			]
+		}
+	}`
+	streams, err := ExtractMetricSampleStreams([]byte(response))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	series := NewTimeSeries(streams)
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []*TimeSeries{
+		{
+			Labels: map[string]string{"queue": "active"},
+			Points: []TimeSeriesPoint{{Timestamp: t0, Value: 10}, {Timestamp: t0.Add(time.Minute), Value: 20}},
+		},
+		{
+			Labels: map[string]string{"queue": "unschedulable"},
+			Points: []TimeSeriesPoint{{Timestamp: t0, Value: 2}},
+		},
+	}, series)
+
+	_, err = ExtractMetricSampleStreams([]byte(`{"status": "success", "data": {"resultType": "vector", "result": []}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// DefaultReadyPath is a JSONPath of the status of the Ready condition, reported by most resources.
+	DefaultReadyPath = `{.status.conditions[?(@.type=="Ready")].status}`
+	// DefaultReadyValue is a value of DefaultReadyPath of ready objects.
+	DefaultReadyValue = "True"
+
+	// maxReportedNotReadyObjects is the number of not ready objects listed in the timeout error.
+	maxReportedNotReadyObjects = 10
+)
+
+// ReadinessCheck verifies readiness of objects by comparing value of a JSONPath expression with the expected one.
+type ReadinessCheck struct {
+	path  *jsonpath.JSONPath
+	value string
+}
+
+// NewReadinessCheck creates ReadinessCheck for given JSONPath template (e.g. "{.status.phase}")
+// and value of the template rendered for ready objects (e.g. "Bound").
+func NewReadinessCheck(path, value string) (*ReadinessCheck, error) {
+	j := jsonpath.New("readiness").AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return nil, fmt.Errorf("readiness path %q parsing error: %v", path, err)
+	}
+	return &ReadinessCheck{path: j, value: value}, nil
+}
+
+// Evaluate returns value of the JSONPath of the object and whether the object is ready.
+func (r *ReadinessCheck) Evaluate(obj *unstructured.Unstructured) (string, bool, error) {
+	buf := &bytes.Buffer{}
+	if err := r.path.Execute(buf, obj.Object); err != nil {
+		return "", false, err
+	}
+	value := buf.String()
+	return value, value == r.value, nil
+}
+
+// WaitForGenericObjectsOptions is an options used by WaitForGenericObjects method.
+type WaitForGenericObjectsOptions struct {
+	GroupVersionResource schema.GroupVersionResource
+	Selector             *ObjectSelector
+	ReadinessCheck       *ReadinessCheck
+	DesiredObjectCount   int
+	EnableLogging        bool
+	CallerName           string
+	WaitInterval         time.Duration
+}
+
+// WaitForGenericObjects waits till desired number of objects of any resource is ready.
+// Objects are specified by resource, namespace, field and/or label selectors.
+// If stopCh is closed before all objects are ready, the error will be returned.
+func WaitForGenericObjects(dynamicClient dynamic.Interface, stopCh <-chan struct{}, options *WaitForGenericObjectsOptions) error {
+	store, err := NewGenericStore(dynamicClient, options.GroupVersionResource, options.Selector)
+	if err != nil {
+		return fmt.Errorf("%s store creation error: %v", options.GroupVersionResource.Resource, err)
+	}
+	defer store.Stop()
+
+	var status GenericObjectsStatus
+	for {
+		select {
+		case <-stopCh:
+			return fmt.Errorf("timeout while waiting for %d %s to be ready in namespace '%v' with labels '%v' and fields '%v' - only %d found ready, not ready: %s",
+				options.DesiredObjectCount, options.GroupVersionResource.Resource, options.Selector.Namespace, options.Selector.LabelSelector, options.Selector.FieldSelector,
+				status.Ready, status.NotReadyString())
+		case <-time.After(options.WaitInterval):
+			status = ComputeGenericObjectsStatus(store.List(), options.ReadinessCheck)
+			if options.EnableLogging {
+				logrus.Infof("%s: %s %s: %s", options.CallerName, options.GroupVersionResource.Resource, options.Selector.String(), status.String())
+			}
+			if status.NotReady() == 0 && status.Ready == options.DesiredObjectCount {
+				return nil
+			}
+		}
+	}
+}
+
+// GenericObjectsStatus represents readiness of a group of objects.
+type GenericObjectsStatus struct {
+	Ready int
+	// NotReadyValues maps namespaced names of not ready objects to the value of the readiness path
+	// or the error of its evaluation.
+	NotReadyValues map[string]string
+}
+
+// ComputeGenericObjectsStatus computes GenericObjectsStatus for a group of objects.
+func ComputeGenericObjectsStatus(objects []*unstructured.Unstructured, check *ReadinessCheck) GenericObjectsStatus {
+	status := GenericObjectsStatus{NotReadyValues: make(map[string]string)}
+	for _, obj := range objects {
+		value, ready, err := check.Evaluate(obj)
+		if err != nil {
+			value = fmt.Sprintf("error: %v", err)
+		}
+		if ready && obj.GetDeletionTimestamp() == nil {
+			status.Ready++
+			continue
+		}
+		key := obj.GetName()
+		if obj.GetNamespace() != "" {
+			key = obj.GetNamespace() + "/" + key
+		}
+		if obj.GetDeletionTimestamp() != nil {
+			value = "terminating"
+		}
+		status.NotReadyValues[key] = value
+	}
+	return status
+}
+
+// NotReady returns the number of not ready objects.
+func (s *GenericObjectsStatus) NotReady() int {
+	return len(s.NotReadyValues)
+}
+
+// String returns string representation of GenericObjectsStatus.
+func (s *GenericObjectsStatus) String() string {
+	return fmt.Sprintf("Objects: %d ready, %d not ready", s.Ready, s.NotReady())
+}
+
+// NotReadyString returns names of some not ready objects with their readiness values.
+func (s *GenericObjectsStatus) NotReadyString() string {
+	keys := make([]string, 0, len(s.NotReadyValues))
+	for key := range s.NotReadyValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	suffix := ""
+	if len(keys) > maxReportedNotReadyObjects {
+		suffix = fmt.Sprintf(" and %d more", len(keys)-maxReportedNotReadyObjects)
+		keys = keys[:maxReportedNotReadyObjects]
+	}
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s (%q)", key, s.NotReadyValues[key]))
+	}
+	return "[" + strings.Join(parts, ", ") + suffix + "]"
+}
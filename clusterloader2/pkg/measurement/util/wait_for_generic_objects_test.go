@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func objectWithStatus(name string, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+	obj.SetNamespace("ns")
+	obj.SetName(name)
+	return obj
+}
+
+func readyCondition(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Accepted", "status": "True"},
+			map[string]interface{}{"type": "Ready", "status": status},
+		},
+	}
+}
+
+func TestComputeGenericObjectsStatus(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		objectWithStatus("ready", readyCondition("True")),
+		objectWithStatus("not-ready", readyCondition("False")),
+		objectWithStatus("no-status", nil),
+	}
+	check, err := NewReadinessCheck(DefaultReadyPath, DefaultReadyValue)
+	assert.NoError(t, err)
+	status := ComputeGenericObjectsStatus(objects, check)
+	assert.Equal(t, 1, status.Ready)
+	assert.Equal(t, map[string]string{"ns/not-ready": "False", "ns/no-status": ""}, status.NotReadyValues)
+	assert.Equal(t, `[ns/no-status (""), ns/not-ready ("False")]`, status.NotReadyString())
+
+	check, err = NewReadinessCheck("{.status.phase}", "Bound")
+	assert.NoError(t, err)
+	status = ComputeGenericObjectsStatus([]*unstructured.Unstructured{
+		objectWithStatus("bound", map[string]interface{}{"phase": "Bound"}),
+		objectWithStatus("pending", map[string]interface{}{"phase": "Pending"}),
+	}, check)
+	assert.Equal(t, 1, status.Ready)
+	assert.Equal(t, map[string]string{"ns/pending": "Pending"}, status.NotReadyValues)
+}
+
+func TestNewReadinessCheckInvalidPath(t *testing.T) {
+	_, err := NewReadinessCheck("{.status.conditions[", "True")
+	assert.Error(t, err)
+}
@@ -21,7 +21,7 @@ import (
 	"strings"
 	"time"
 
-	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/api/core/v1"
 	"github.com/sirupsen/logrus"
 )
 
@@ -39,19 +39,26 @@ type WaitForPodOptions struct {
 	EnableLogging       bool
 	CallerName          string
 	WaitForPodsInterval time.Duration
+	// StuckPodTimeout is the time after which waiting fails if any pod is continuously stuck,
+	// e.g. in CrashLoopBackOff, instead of waiting until stopCh is closed. Zero disables it.
+	StuckPodTimeout time.Duration
+	// PodFilter, if set, selects pods taken into account among pods matching the selector,
+	// e.g. pods owned by a given object.
+	PodFilter func(*v1.Pod) bool
 }
 
 // WaitForPods waits till disire nuber of pods is running.
 // Pods are be specified by namespace, field and/or label selectors.
 // If stopCh is closed before all pods are running, the error will be returned.
-func WaitForPods(clientSet clientset.Interface, stopCh <-chan struct{}, options *WaitForPodOptions) error {
-	ps, err := NewPodStore(clientSet, options.Selector)
+func WaitForPods(podStores *SharedPodStores, stopCh <-chan struct{}, options *WaitForPodOptions) error {
+	ps, err := podStores.Get(options.Selector)
 	if err != nil {
 		return fmt.Errorf("pod store creation error: %v", err)
 	}
 	defer ps.Stop()
 
-	oldPods := ps.List()
+	oldPods := listPods(ps, options.PodFilter)
+	stuckSince := make(map[string]time.Time)
 	scaling := uninitialized
 	var podsStatus PodsStartupStatus
 
@@ -68,10 +75,10 @@ func WaitForPods(clientSet clientset.Interface, stopCh <-chan struct{}, options
 		select {
 		case <-stopCh:
 			logrus.Infof("%s: %s: pods status: %v", options.CallerName, options.Selector.String(), ComputePodsStatus(oldPods, options.DesiredPodCount))
-			return fmt.Errorf("timeout while waiting for %d pods to be running in namespace '%v' with labels '%v' and fields '%v' - only %d found running",
-				options.DesiredPodCount, options.Selector.Namespace, options.Selector.LabelSelector, options.Selector.FieldSelector, podsStatus.Running)
+			return fmt.Errorf("timeout while waiting for %d pods to be running in namespace '%v' with labels '%v' and fields '%v' - only %d found running, not running pods: %v",
+				options.DesiredPodCount, options.Selector.Namespace, options.Selector.LabelSelector, options.Selector.FieldSelector, podsStatus.Running, DiagnosePods(oldPods))
 		case <-time.After(options.WaitForPodsInterval):
-			pods := ps.List()
+			pods := listPods(ps, options.PodFilter)
 			podsStatus = ComputePodsStartupStatus(pods, options.DesiredPodCount)
 
 			diff := DiffPods(oldPods, pods)
@@ -91,7 +98,82 @@ func WaitForPods(clientSet clientset.Interface, stopCh <-chan struct{}, options
 			if len(pods) == (podsStatus.Running+podsStatus.Inactive) && podsStatus.Running == options.DesiredPodCount {
 				return nil
 			}
+			if options.StuckPodTimeout > 0 {
+				if stuck := updateStuckPods(stuckSince, pods, time.Now(), options.StuckPodTimeout); len(stuck) > 0 {
+					logrus.Errorf("%s: %s: pods stuck for %v: %v", options.CallerName, options.Selector.String(), options.StuckPodTimeout, stuck)
+					return &stuckPodsError{
+						message: fmt.Sprintf("%d pods in namespace '%v' with labels '%v' and fields '%v' stuck for %v: %v, not running pods: %v",
+							countPods(stuck), options.Selector.Namespace, options.Selector.LabelSelector, options.Selector.FieldSelector, options.StuckPodTimeout, stuck, DiagnosePods(pods)),
+					}
+				}
+			}
 			oldPods = pods
 		}
 	}
 }
+
+// updateStuckPods records since when pods are stuck and returns diagnostics of pods stuck for at least timeout.
+// Pods that are no longer stuck are forgotten, so that e.g. a pod recovering from crash loop starts over.
+func updateStuckPods(stuckSince map[string]time.Time, pods []*v1.Pod, now time.Time, timeout time.Duration) PodsDiagnostics {
+	stuck := make(PodsDiagnostics)
+	current := make(map[string]bool, len(stuckSince))
+	for _, pod := range pods {
+		reason, isStuck := DiagnosePod(pod)
+		if !isStuck {
+			continue
+		}
+		key := pod.Namespace + "/" + pod.Name
+		current[key] = true
+		since, ok := stuckSince[key]
+		if !ok {
+			stuckSince[key] = now
+			since = now
+		}
+		if now.Sub(since) >= timeout {
+			stuck[reason] = append(stuck[reason], key)
+		}
+	}
+	for key := range stuckSince {
+		if !current[key] {
+			delete(stuckSince, key)
+		}
+	}
+	return stuck
+}
+
+func countPods(diagnostics PodsDiagnostics) int {
+	count := 0
+	for _, pods := range diagnostics {
+		count += len(pods)
+	}
+	return count
+}
+
+// stuckPodsError is returned by WaitForPods when pods are stuck for longer than StuckPodTimeout.
+type stuckPodsError struct {
+	message string
+}
+
+func (e *stuckPodsError) Error() string {
+	return e.message
+}
+
+// IsStuckPodsError checks if given error was returned by WaitForPods because of stuck pods.
+func IsStuckPodsError(err error) bool {
+	_, ok := err.(*stuckPodsError)
+	return ok
+}
+
+func listPods(ps *PodStore, filter func(*v1.Pod) bool) []*v1.Pod {
+	pods := ps.List()
+	if filter == nil {
+		return pods
+	}
+	filtered := make([]*v1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if filter(pod) {
+			filtered = append(filtered, pod)
+		}
+	}
+	return filtered
+}
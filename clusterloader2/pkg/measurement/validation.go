@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package measurement
+
+import (
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// Validator is implemented by measurements that can check their params, and files referenced
+// by them, before the test is run. Validate is called without access to the cluster, so the config
+// has no frameworks and only Params, TemplateProvider, ClusterLoaderConfig, Identifier
+// and CloudProvider are set.
+type Validator interface {
+	Validate(config *MeasurementConfig) error
+}
+
+// Validate checks statically that the measurement call can be executed: the method is registered,
+// params handled by the measurement manager are valid, params match the declaration if the measurement
+// implements ParamsDeclarer and, if the measurement implements Validator, that its params are valid.
+func Validate(methodName string, config *MeasurementConfig) error {
+	instance, err := factory.createMeasurement(methodName)
+	if err != nil {
+		return err
+	}
+	if _, err := util.GetDurationOrDefault(config.Params, "callTimeout", 0); err != nil {
+		return err
+	}
+	if _, err := getGatherInterval(config.Params); err != nil {
+		return err
+	}
+	if declared := getDeclaredParams(instance); declared != nil {
+		if err := validateParams(declared, config.Params); err != nil {
+			return err
+		}
+	}
+	if validator, ok := instance.(Validator); ok {
+		return validator.Validate(config)
+	}
+	return nil
+}
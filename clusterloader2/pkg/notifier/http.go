@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	notifyTimeout = 30 * time.Second
+)
+
+// slackNotifier posts text of notifications to Slack incoming webhook.
+type slackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates notifier posting text of notifications to the Slack incoming webhook.
+func NewSlackNotifier(webhookURL string) Notifier {
+	return &slackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+func (s *slackNotifier) Notify(notification *Notification) error {
+	return post(s.client, s.webhookURL, map[string]string{"text": notification.Text()})
+}
+
+func (s *slackNotifier) String() string {
+	return "slack"
+}
+
+// webhookNotifier posts notifications as JSON to the webhook.
+type webhookNotifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewWebhookNotifier creates notifier posting notifications as JSON to the given URL.
+// Besides fields of the notification, the JSON contains its text.
+func NewWebhookNotifier(endpoint string) Notifier {
+	return &webhookNotifier{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+func (w *webhookNotifier) Notify(notification *Notification) error {
+	return post(w.client, w.endpoint, struct {
+		*Notification
+		Text string `json:"text"`
+	}{notification, notification.Text()})
+}
+
+func (w *webhookNotifier) String() string {
+	return "webhook"
+}
+
+// post posts the payload as JSON to the URL. The URL isn't included in errors, as it contains a secret.
+func post(client *http.Client, endpoint string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling notification error: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if err := util.SendHTTPRequest(client, request); err != nil {
+		return fmt.Errorf("posting notification error: %v", stripURL(err))
+	}
+	return nil
+}
+
+// stripURL returns the underlying error of url.Error, which contains the URL.
+func stripURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
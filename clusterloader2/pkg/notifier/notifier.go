@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+)
+
+// maxListedViolations limits the number of violations listed in the text of a notification.
+const maxListedViolations = 10
+
+// Notifier sends notifications about the run to an external service, e.g. Slack.
+type Notifier interface {
+	Notify(notification *Notification) error
+	String() string
+}
+
+// Event is the reason of the notification.
+type Event string
+
+const (
+	// RunFinished is sent once all tests of the run are finished.
+	RunFinished Event = "RunFinished"
+	// ViolationDetected is sent once an SLO violation is detected during the test,
+	// e.g. by continuous evaluation of a Prometheus-based measurement.
+	ViolationDetected Event = "ViolationDetected"
+)
+
+// Notification describes the outcome of the run or an SLO violation detected during a test.
+type Notification struct {
+	Event     Event     `json:"event"`
+	Run       string    `json:"run,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Category is the most severe failure category of tests of the run, empty if all of them passed.
+	Category string `json:"category,omitempty"`
+	// Tests are outcomes of tests of the run, set only for RunFinished.
+	Tests      []TestOutcome `json:"tests,omitempty"`
+	Violations []Violation   `json:"violations,omitempty"`
+	// Artifacts are links to files of the report directory, e.g. the violations file.
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// TestOutcome describes the outcome of a single test.
+type TestOutcome struct {
+	Test     string `json:"test"`
+	Category string `json:"category,omitempty"`
+	Errors   int    `json:"errors"`
+}
+
+// Violation describes SLO violation detected by a measurement.
+type Violation struct {
+	Test        string `json:"test"`
+	Measurement string `json:"measurement"`
+	Identifier  string `json:"identifier,omitempty"`
+	Metric      string `json:"metric"`
+	Message     string `json:"message"`
+}
+
+// InitFlags initializes notifier flags.
+func InitFlags(n *config.NotifierConfig) {
+	flags.StringEnvVar(&n.SlackWebhookURL, "notifier-slack-webhook-url", "NOTIFIER_SLACK_WEBHOOK_URL", "", "URL of Slack incoming webhook that summary of the run and SLO violations detected during tests should be posted to. If empty, Slack is not notified.")
+	flags.StringEnvVar(&n.WebhookURL, "notifier-webhook-url", "NOTIFIER_WEBHOOK_URL", "", "URL that summary of the run and SLO violations detected during tests should be posted to as JSON. If empty, the webhook is not notified.")
+	flags.StringEnvVar(&n.ArtifactsURL, "notifier-artifacts-url", "NOTIFIER_ARTIFACTS_URL", "", "URL the report directory is available at, e.g. in a bucket, used to link artifacts in notifications. If empty, paths in the report directory are given.")
+	flags.StringEnvVar(&n.RunName, "notifier-run-name", "NOTIFIER_RUN_NAME", "", "Name identifying the run in notifications, e.g. name of the job.")
+	flags.BoolEnvVar(&n.NotifyOnSuccess, "notify-on-success", "NOTIFY_ON_SUCCESS", false, "Whether summary of the run should be posted even if all tests passed.")
+}
+
+// NewNotifier creates notifier based on the provided config.
+// Nil is returned if notifications are disabled.
+func NewNotifier(n *config.NotifierConfig) (Notifier, error) {
+	var notifiers multiNotifier
+	if n.SlackWebhookURL != "" {
+		if err := validateURL(n.SlackWebhookURL); err != nil {
+			return nil, fmt.Errorf("slack webhook url: %v", err)
+		}
+		notifiers = append(notifiers, NewSlackNotifier(n.SlackWebhookURL))
+	}
+	if n.WebhookURL != "" {
+		if err := validateURL(n.WebhookURL); err != nil {
+			return nil, fmt.Errorf("webhook url: %v", err)
+		}
+		notifiers = append(notifiers, NewWebhookNotifier(n.WebhookURL))
+	}
+	switch len(notifiers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return notifiers[0], nil
+	default:
+		return notifiers, nil
+	}
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		// The URL isn't included in the error, as it contains a secret.
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// multiNotifier sends notifications with all notifiers.
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(notification *Notification) error {
+	var errs []string
+	for _, n := range m {
+		if err := n.Notify(notification); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", n, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m multiNotifier) String() string {
+	var names []string
+	for _, n := range m {
+		names = append(names, n.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// ArtifactLinks returns links to given files of the report directory. Files are linked
+// under artifactsURL if it's set and under the report directory otherwise.
+func ArtifactLinks(artifactsURL, reportDir string, files ...string) []string {
+	var links []string
+	for _, file := range files {
+		switch {
+		case artifactsURL != "":
+			links = append(links, strings.TrimSuffix(artifactsURL, "/")+"/"+file)
+		case reportDir != "":
+			links = append(links, filepath.Join(reportDir, file))
+		}
+	}
+	return links
+}
+
+// Text returns human readable summary of the notification.
+func (n *Notification) Text() string {
+	var b strings.Builder
+	run := "ClusterLoader run"
+	if n.Run != "" {
+		run = fmt.Sprintf("ClusterLoader run %s", n.Run)
+	}
+	switch n.Event {
+	case RunFinished:
+		failed := 0
+		for _, test := range n.Tests {
+			if test.Errors > 0 {
+				failed++
+			}
+		}
+		if failed == 0 {
+			fmt.Fprintf(&b, "%s passed: %d tests\n", run, len(n.Tests))
+		} else {
+			fmt.Fprintf(&b, "%s failed (%s): %d of %d tests failed\n", run, n.Category, failed, len(n.Tests))
+		}
+		for _, test := range n.Tests {
+			if test.Errors > 0 {
+				fmt.Fprintf(&b, "• %s: %s, %d errors\n", test.Test, test.Category, test.Errors)
+			}
+		}
+	case ViolationDetected:
+		fmt.Fprintf(&b, "%s: SLO violated during the test\n", run)
+	}
+	if len(n.Violations) > 0 {
+		fmt.Fprintf(&b, "Violations:\n")
+		for i, violation := range n.Violations {
+			if i == maxListedViolations {
+				fmt.Fprintf(&b, "• and %d more\n", len(n.Violations)-maxListedViolations)
+				break
+			}
+			measurement := violation.Measurement
+			if violation.Identifier != "" {
+				measurement = fmt.Sprintf("%s (%s)", violation.Measurement, violation.Identifier)
+			}
+			fmt.Fprintf(&b, "• %s: %s: %s\n", violation.Test, measurement, violation.Message)
+		}
+	}
+	if len(n.Artifacts) > 0 {
+		fmt.Fprintf(&b, "Artifacts:\n")
+		for _, artifact := range n.Artifacts {
+			fmt.Fprintf(&b, "• %s\n", artifact)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+var runFinished = &Notification{
+	Event:    RunFinished,
+	Run:      "nightly",
+	Category: "SLOViolation",
+	Tests: []TestOutcome{
+		{Test: "load", Category: "SLOViolation", Errors: 1},
+		{Test: "density"},
+	},
+	Violations: []Violation{
+		{Test: "load", Measurement: "APIResponsivenessPrometheus", Identifier: "APIResponsiveness", Metric: "API call latency", Message: "p99 too high"},
+	},
+	Artifacts: []string{"gs://bucket/run/violations.json"},
+}
+
+func TestText(t *testing.T) {
+	want := `ClusterLoader run nightly failed (SLOViolation): 1 of 2 tests failed
+• load: SLOViolation, 1 errors
+Violations:
+• load: APIResponsivenessPrometheus (APIResponsiveness): p99 too high
+Artifacts:
+• gs://bucket/run/violations.json`
+	assert.Equal(t, want, runFinished.Text())
+
+	violation := &Notification{Event: ViolationDetected}
+	for i := 0; i < maxListedViolations+2; i++ {
+		violation.Violations = append(violation.Violations, Violation{Test: "load", Measurement: "PodStartupLatency", Message: "too slow"})
+	}
+	text := violation.Text()
+	assert.True(t, strings.HasPrefix(text, "ClusterLoader run: SLO violated during the test\n"), text)
+	assert.Equal(t, maxListedViolations, strings.Count(text, "too slow"))
+	assert.True(t, strings.HasSuffix(text, "• and 2 more"), text)
+}
+
+func TestNotifiers(t *testing.T) {
+	received := make(map[string]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/rejected" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		received[r.URL.Path] = payload
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(&config.NotifierConfig{SlackWebhookURL: server.URL + "/slack", WebhookURL: server.URL + "/webhook"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.Notify(runFinished); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, map[string]interface{}{"text": runFinished.Text()}, received["/slack"])
+	assert.Equal(t, "RunFinished", received["/webhook"]["event"])
+	assert.Equal(t, "nightly", received["/webhook"]["run"])
+	assert.Equal(t, runFinished.Text(), received["/webhook"]["text"])
+	assert.Len(t, received["/webhook"]["violations"], 1)
+
+	err = NewWebhookNotifier(server.URL + "/rejected").Notify(runFinished)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "status 403")
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	n, err := NewNotifier(&config.NotifierConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+
+	_, err = NewNotifier(&config.NotifierConfig{SlackWebhookURL: "hooks.slack.com/services/secret"})
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "secret")
+	}
+}
+
+func TestArtifactLinks(t *testing.T) {
+	assert.Equal(t, []string{"https://storage/run/violations.json"}, ArtifactLinks("https://storage/run/", "/tmp/report", "violations.json"))
+	assert.Equal(t, []string{"/tmp/report/violations.json"}, ArtifactLinks("", "/tmp/report", "violations.json"))
+	assert.Nil(t, ArtifactLinks("", "", "violations.json"))
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	batchv1beta1listers "k8s.io/client-go/listers/batch/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// generationAnnotation is set on CronJobs to the generation of the ScaleTest they were built from.
+	generationAnnotation = "perf-tests.x-k8s.io/generation"
+
+	resyncPeriod = 5 * time.Minute
+)
+
+// Controller runs ScaleTests as Jobs (or CronJobs for tests with schedule) and reflects
+// state of the Jobs in status of the tests.
+type Controller struct {
+	client        clientset.Interface
+	dynamicClient dynamic.Interface
+	image         string
+
+	scaleTests cache.SharedIndexInformer
+	jobs       batchv1listers.JobLister
+	cronJobs   batchv1beta1listers.CronJobLister
+	synced     []cache.InformerSynced
+	start      func(stopCh <-chan struct{})
+	queue      workqueue.RateLimitingInterface
+}
+
+// NewController creates controller of ScaleTests in the namespace (all namespaces if empty).
+// Tests without image in their spec are run with the given image.
+func NewController(client clientset.Interface, dynamicClient dynamic.Interface, namespace, image string) *Controller {
+	c := &Controller{
+		client:        client,
+		dynamicClient: dynamicClient,
+		image:         image,
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "scaletests"),
+	}
+	dynamicFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, resyncPeriod, namespace, nil)
+	c.scaleTests = dynamicFactory.ForResource(ScaleTestResource).Informer()
+	c.scaleTests.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+	})
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithNamespace(namespace))
+	jobs := factory.Batch().V1().Jobs()
+	cronJobs := factory.Batch().V1beta1().CronJobs()
+	ownedHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueOwner,
+		UpdateFunc: func(_, obj interface{}) { c.enqueueOwner(obj) },
+		DeleteFunc: c.enqueueOwner,
+	}
+	jobs.Informer().AddEventHandler(ownedHandler)
+	cronJobs.Informer().AddEventHandler(ownedHandler)
+	c.jobs = jobs.Lister()
+	c.cronJobs = cronJobs.Lister()
+	c.synced = []cache.InformerSynced{c.scaleTests.HasSynced, jobs.Informer().HasSynced, cronJobs.Informer().HasSynced}
+	c.start = func(stopCh <-chan struct{}) {
+		dynamicFactory.Start(stopCh)
+		factory.Start(stopCh)
+	}
+	return c
+}
+
+// Run starts the controller and blocks until stopCh is closed.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) error {
+	defer c.queue.ShutDown()
+	c.start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.synced...) {
+		return fmt.Errorf("caches not synced")
+	}
+	logrus.Infof("ScaleTest controller: started with %d workers", workers)
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+	<-stopCh
+	return nil
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logrus.Errorf("ScaleTest controller: %v", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueOwner enqueues ScaleTest which the Job or CronJob runs.
+func (c *Controller) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+	if name, ok := meta.GetLabels()[ScaleTestLabel]; ok {
+		c.queue.Add(meta.GetNamespace() + "/" + name)
+	}
+}
+
+func (c *Controller) worker() {
+	for c.processNextItem() {
+	}
+}
+
+func (c *Controller) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.sync(key.(string)); err != nil {
+		logrus.Errorf("ScaleTest controller: syncing %s error: %v", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) sync(key string) error {
+	obj, exists, err := c.scaleTests.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// Jobs are garbage collected thanks to owner references.
+		return nil
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	st := &ScaleTest{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, st); err != nil {
+		return fmt.Errorf("decoding error: %v", err)
+	}
+	if st.DeletionTimestamp != nil {
+		return nil
+	}
+
+	cronJob, err := c.syncCronJob(st)
+	if err != nil {
+		return err
+	}
+	if st.Spec.Schedule == "" {
+		if err := c.syncJob(st); err != nil {
+			return err
+		}
+	}
+	jobs, err := c.jobs.Jobs(st.Namespace).List(labels.SelectorFromSet(labels.Set{ScaleTestLabel: st.Name}))
+	if err != nil {
+		return err
+	}
+	status := computeStatus(st, jobs, cronJob, metav1.Now().Rfc3339Copy())
+	if equality.Semantic.DeepEqual(status, st.Status) {
+		return nil
+	}
+	return c.updateStatus(u, status)
+}
+
+// syncJob creates Job running the current generation of the test, unless it already exists.
+func (c *Controller) syncJob(st *ScaleTest) error {
+	if _, err := c.jobs.Jobs(st.Namespace).Get(jobName(st)); !apierrs.IsNotFound(err) {
+		return err
+	}
+	logrus.Infof("ScaleTest controller: creating job %s/%s", st.Namespace, jobName(st))
+	_, err := c.client.BatchV1().Jobs(st.Namespace).Create(buildJob(st, c.image))
+	if apierrs.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// syncCronJob makes CronJob of the test up to date with its spec and returns it.
+// CronJob of a test without schedule is deleted.
+func (c *Controller) syncCronJob(st *ScaleTest) (*batchv1beta1.CronJob, error) {
+	existing, err := c.cronJobs.CronJobs(st.Namespace).Get(st.Name)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return nil, err
+	}
+	if existing != nil && !metav1.IsControlledBy(existing, st) {
+		return nil, fmt.Errorf("cronjob %s/%s is not controlled by the scale test", st.Namespace, st.Name)
+	}
+	if st.Spec.Schedule == "" {
+		if existing == nil {
+			return nil, nil
+		}
+		logrus.Infof("ScaleTest controller: deleting cronjob %s/%s", st.Namespace, st.Name)
+		if err := c.client.BatchV1beta1().CronJobs(st.Namespace).Delete(st.Name, nil); err != nil && !apierrs.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	generation := strconv.FormatInt(st.Generation, 10)
+	if existing != nil && existing.Annotations[generationAnnotation] == generation {
+		return existing, nil
+	}
+	cronJob := buildCronJob(st, c.image)
+	cronJob.Annotations = map[string]string{generationAnnotation: generation}
+	if existing == nil {
+		logrus.Infof("ScaleTest controller: creating cronjob %s/%s", st.Namespace, st.Name)
+		return c.client.BatchV1beta1().CronJobs(st.Namespace).Create(cronJob)
+	}
+	logrus.Infof("ScaleTest controller: updating cronjob %s/%s", st.Namespace, st.Name)
+	updated := existing.DeepCopy()
+	updated.Annotations = cronJob.Annotations
+	updated.Spec = cronJob.Spec
+	return c.client.BatchV1beta1().CronJobs(st.Namespace).Update(updated)
+}
+
+func (c *Controller) updateStatus(u *unstructured.Unstructured, status ScaleTestStatus) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("encoding error: %v", err)
+	}
+	updated := u.DeepCopy()
+	updated.Object["status"] = content
+	_, err = c.dynamicClient.Resource(ScaleTestResource).Namespace(u.GetNamespace()).UpdateStatus(updated, metav1.UpdateOptions{})
+	return err
+}
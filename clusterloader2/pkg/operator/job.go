@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ScaleTestLabel is the label with name of the ScaleTest set on Jobs running it.
+	ScaleTestLabel = "perf-tests.x-k8s.io/scaletest"
+
+	containerName   = "clusterloader"
+	configVolume    = "config"
+	configMountPath = "/etc/clusterloader"
+	reportVolume    = "reports"
+	reportMountPath = "/var/lib/clusterloader/reports"
+)
+
+// jobName returns name of the Job running the generation of the test.
+func jobName(st *ScaleTest) string {
+	return fmt.Sprintf("%s-%d", st.Name, st.Generation)
+}
+
+func ownerReference(st *ScaleTest) metav1.OwnerReference {
+	controller := true
+	return metav1.OwnerReference{
+		APIVersion: ScaleTestResource.GroupVersion().String(),
+		Kind:       "ScaleTest",
+		Name:       st.Name,
+		UID:        st.UID,
+		Controller: &controller,
+	}
+}
+
+func clusterloaderArgs(spec *ScaleTestSpec) []string {
+	var args []string
+	for _, testConfig := range spec.TestConfigs {
+		args = append(args, "--testconfig="+path.Join(configMountPath, testConfig))
+	}
+	for _, testOverride := range spec.TestOverrides {
+		args = append(args, "--testoverrides="+path.Join(configMountPath, testOverride))
+	}
+	if spec.Provider != "" {
+		args = append(args, "--provider="+spec.Provider)
+	}
+	if spec.Nodes > 0 {
+		args = append(args, fmt.Sprintf("--nodes=%d", spec.Nodes))
+	}
+	args = append(args, "--report-dir="+reportMountPath)
+	if len(spec.SummarySinkURLs) > 0 {
+		args = append(args, "--summary-sink-urls="+strings.Join(spec.SummarySinkURLs, ","))
+	}
+	return append(args, spec.ExtraArgs...)
+}
+
+// buildJobSpec returns spec of Jobs running the test. A failed run isn't retried,
+// as it is reported in status of the test instead.
+func buildJobSpec(st *ScaleTest, defaultImage string) batchv1.JobSpec {
+	image := st.Spec.Image
+	if image == "" {
+		image = defaultImage
+	}
+	backoffLimit := int32(0)
+	return batchv1.JobSpec{
+		BackoffLimit:          &backoffLimit,
+		ActiveDeadlineSeconds: st.Spec.ActiveDeadlineSeconds,
+		Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{ScaleTestLabel: st.Name},
+			},
+			Spec: corev1.PodSpec{
+				RestartPolicy:      corev1.RestartPolicyNever,
+				ServiceAccountName: st.Spec.ServiceAccountName,
+				Containers: []corev1.Container{{
+					Name:  containerName,
+					Image: image,
+					Args:  clusterloaderArgs(&st.Spec),
+					Env:   st.Spec.Env,
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: configVolume, MountPath: configMountPath, ReadOnly: true},
+						{Name: reportVolume, MountPath: reportMountPath},
+					},
+				}},
+				Volumes: []corev1.Volume{
+					{
+						Name: configVolume,
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: st.Spec.ConfigMap},
+							},
+						},
+					},
+					{
+						Name:         reportVolume,
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildJob returns Job running the current generation of the test once.
+func buildJob(st *ScaleTest, defaultImage string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            jobName(st),
+			Namespace:       st.Namespace,
+			Labels:          map[string]string{ScaleTestLabel: st.Name},
+			OwnerReferences: []metav1.OwnerReference{ownerReference(st)},
+		},
+		Spec: buildJobSpec(st, defaultImage),
+	}
+}
+
+// buildCronJob returns CronJob running the test according to its schedule.
+// Runs of the test never overlap.
+func buildCronJob(st *ScaleTest, defaultImage string) *batchv1beta1.CronJob {
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            st.Name,
+			Namespace:       st.Namespace,
+			Labels:          map[string]string{ScaleTestLabel: st.Name},
+			OwnerReferences: []metav1.OwnerReference{ownerReference(st)},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:          st.Spec.Schedule,
+			ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{ScaleTestLabel: st.Name},
+				},
+				Spec: buildJobSpec(st, defaultImage),
+			},
+		},
+	}
+}
+
+// jobPhase returns phase of the test run by the job.
+func jobPhase(job *batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return PhaseSucceeded
+		case batchv1.JobFailed:
+			return PhaseFailed
+		}
+	}
+	if job.Status.StartTime != nil || job.Status.Active > 0 {
+		return PhaseRunning
+	}
+	return PhasePending
+}
+
+// computeStatus returns status of the test based on jobs which ran it (in any order) and
+// its CronJob, which is nil for tests without schedule.
+func computeStatus(st *ScaleTest, jobs []*batchv1.Job, cronJob *batchv1beta1.CronJob, now metav1.Time) ScaleTestStatus {
+	status := ScaleTestStatus{
+		ObservedGeneration: st.Generation,
+		Phase:              PhasePending,
+		Conditions:         append([]ScaleTestCondition{}, st.Status.Conditions...),
+	}
+	if st.Spec.Schedule != "" {
+		status.Phase = PhaseScheduled
+	}
+	if cronJob != nil {
+		status.LastScheduleTime = cronJob.Status.LastScheduleTime
+	}
+	status.Conditions = setCondition(status.Conditions, ScaleTestCondition{Type: ConditionReady, Status: corev1.ConditionTrue}, now)
+
+	if len(jobs) == 0 {
+		return status
+	}
+	sorted := append([]*batchv1.Job{}, jobs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreationTimestamp.Before(&sorted[j].CreationTimestamp)
+	})
+	last := sorted[len(sorted)-1]
+	status.LastJob = last.Name
+	status.StartTime = last.Status.StartTime
+	status.CompletionTime = last.Status.CompletionTime
+	phase := jobPhase(last)
+	if phase != PhasePending || st.Spec.Schedule == "" {
+		status.Phase = phase
+	}
+
+	complete := ScaleTestCondition{Type: ConditionComplete, Status: corev1.ConditionFalse}
+	failed := ScaleTestCondition{Type: ConditionFailed, Status: corev1.ConditionFalse}
+	switch phase {
+	case PhaseSucceeded:
+		complete.Status = corev1.ConditionTrue
+	case PhaseFailed:
+		complete.Status = corev1.ConditionTrue
+		failed.Status = corev1.ConditionTrue
+	}
+	for _, condition := range last.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			failed.Reason, failed.Message = condition.Reason, condition.Message
+		}
+	}
+	status.Conditions = setCondition(status.Conditions, complete, now)
+	status.Conditions = setCondition(status.Conditions, failed, now)
+	return status
+}
+
+// setCondition sets the condition, preserving its transition time if its status didn't change.
+func setCondition(conditions []ScaleTestCondition, condition ScaleTestCondition, now metav1.Time) []ScaleTestCondition {
+	condition.LastTransitionTime = now
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		if conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
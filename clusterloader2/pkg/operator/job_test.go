@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newScaleTest(schedule string) *ScaleTest {
+	return &ScaleTest{
+		ObjectMeta: metav1.ObjectMeta{Name: "density", Namespace: "default", Generation: 3, UID: "uid"},
+		Spec: ScaleTestSpec{
+			ConfigMap:       "density",
+			TestConfigs:     []string{"config.yaml"},
+			TestOverrides:   []string{"overrides.yaml"},
+			Nodes:           100,
+			Schedule:        schedule,
+			SummarySinkURLs: []string{"gs://bucket/a", "s3://bucket/b"},
+			ExtraArgs:       []string{"--enable-exec-service"},
+		},
+	}
+}
+
+func newJob(name string, created time.Time, conditions ...batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+	for _, condition := range conditions {
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: condition, Status: corev1.ConditionTrue})
+	}
+	return job
+}
+
+func TestBuildJob(t *testing.T) {
+	job := buildJob(newScaleTest(""), "clusterloader:default")
+	assert.Equal(t, "density-3", job.Name)
+	assert.Equal(t, "uid", string(job.OwnerReferences[0].UID))
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "clusterloader:default", container.Image)
+	assert.Equal(t, []string{
+		"--testconfig=/etc/clusterloader/config.yaml",
+		"--testoverrides=/etc/clusterloader/overrides.yaml",
+		"--nodes=100",
+		"--report-dir=/var/lib/clusterloader/reports",
+		"--summary-sink-urls=gs://bucket/a,s3://bucket/b",
+		"--enable-exec-service",
+	}, container.Args)
+	assert.Equal(t, "density", job.Spec.Template.Labels[ScaleTestLabel])
+}
+
+func TestComputeStatus(t *testing.T) {
+	now := metav1.NewTime(time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC))
+	created := now.Add(-time.Hour)
+	testCases := []struct {
+		name     string
+		schedule string
+		jobs     []*batchv1.Job
+		phase    string
+		lastJob  string
+		failed   corev1.ConditionStatus
+	}{
+		{
+			name:  "no jobs",
+			phase: PhasePending,
+		},
+		{
+			name:     "scheduled",
+			schedule: "0 3 * * *",
+			phase:    PhaseScheduled,
+		},
+		{
+			name:    "running",
+			jobs:    []*batchv1.Job{{ObjectMeta: metav1.ObjectMeta{Name: "density-3"}, Status: batchv1.JobStatus{Active: 1}}},
+			phase:   PhaseRunning,
+			lastJob: "density-3",
+			failed:  corev1.ConditionFalse,
+		},
+		{
+			name:     "last job failed",
+			schedule: "0 3 * * *",
+			jobs:     []*batchv1.Job{newJob("b", created.Add(time.Minute), batchv1.JobFailed), newJob("a", created, batchv1.JobComplete)},
+			phase:    PhaseFailed,
+			lastJob:  "b",
+			failed:   corev1.ConditionTrue,
+		},
+		{
+			name:     "next job pending",
+			schedule: "0 3 * * *",
+			jobs:     []*batchv1.Job{newJob("a", created, batchv1.JobComplete), newJob("b", created.Add(time.Minute))},
+			phase:    PhaseScheduled,
+			lastJob:  "b",
+			failed:   corev1.ConditionFalse,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := computeStatus(newScaleTest(tc.schedule), tc.jobs, nil, now)
+			assert.Equal(t, tc.phase, status.Phase)
+			assert.Equal(t, tc.lastJob, status.LastJob)
+			assert.Equal(t, int64(3), status.ObservedGeneration)
+			var failed corev1.ConditionStatus
+			for _, condition := range status.Conditions {
+				if condition.Type == ConditionFailed {
+					failed = condition.Status
+				}
+			}
+			assert.Equal(t, tc.failed, failed)
+		})
+	}
+}
+
+func TestSetConditionPreservesTransitionTime(t *testing.T) {
+	before := metav1.NewTime(time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(before.Add(time.Hour))
+	conditions := []ScaleTestCondition{{Type: ConditionComplete, Status: corev1.ConditionTrue, LastTransitionTime: before}}
+	conditions = setCondition(conditions, ScaleTestCondition{Type: ConditionComplete, Status: corev1.ConditionTrue}, now)
+	assert.Equal(t, before, conditions[0].LastTransitionTime)
+	conditions = setCondition(conditions, ScaleTestCondition{Type: ConditionComplete, Status: corev1.ConditionFalse}, now)
+	assert.Equal(t, now, conditions[0].LastTransitionTime)
+	conditions = setCondition(conditions, ScaleTestCondition{Type: ConditionFailed, Status: corev1.ConditionFalse}, now)
+	assert.Len(t, conditions, 2)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ScaleTestResource is the resource of ScaleTest custom resource definition.
+var ScaleTestResource = schema.GroupVersionResource{
+	Group:    "perf-tests.x-k8s.io",
+	Version:  "v1alpha1",
+	Resource: "scaletests",
+}
+
+// ScaleTest phases.
+const (
+	PhasePending   = "Pending"
+	PhaseScheduled = "Scheduled"
+	PhaseRunning   = "Running"
+	PhaseSucceeded = "Succeeded"
+	PhaseFailed    = "Failed"
+)
+
+// ScaleTest condition types.
+const (
+	// ConditionReady is true if the Job or CronJob running the test is up to date with the spec.
+	ConditionReady = "Ready"
+	// ConditionComplete is true if the last run of the test has finished.
+	ConditionComplete = "Complete"
+	// ConditionFailed is true if the last run of the test has failed.
+	ConditionFailed = "Failed"
+)
+
+// ScaleTest runs clusterloader tests from inside the cluster.
+type ScaleTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScaleTestSpec   `json:"spec"`
+	Status ScaleTestStatus `json:"status,omitempty"`
+}
+
+// ScaleTestSpec describes how the tests should be run.
+type ScaleTestSpec struct {
+	// Image is clusterloader image. If empty, the default image of the operator is used.
+	Image string `json:"image,omitempty"`
+	// ConfigMap is the name of the config map with test configs, overrides and object templates.
+	ConfigMap string `json:"configMap"`
+	// TestConfigs are keys of the config map with test configs to run.
+	TestConfigs []string `json:"testConfigs"`
+	// TestOverrides are keys of the config map with overrides applied to all tests.
+	TestOverrides []string `json:"testOverrides,omitempty"`
+	// Provider is the cluster provider.
+	Provider string `json:"provider,omitempty"`
+	// Nodes is the number of nodes in the cluster. If 0, clusterloader counts schedulable nodes.
+	Nodes int `json:"nodes,omitempty"`
+	// Schedule is a cron schedule of recurring runs. If empty, the tests are run once
+	// for every generation of the spec.
+	Schedule string `json:"schedule,omitempty"`
+	// SummarySinkURLs are locations (e.g. gs://bucket/prefix) where summaries are stored.
+	SummarySinkURLs []string `json:"summarySinkURLs,omitempty"`
+	// ServiceAccountName is the service account clusterloader runs with.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// ExtraArgs are passed to clusterloader as they are.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	// Env is added to the clusterloader container, e.g. to provide sink credentials from secrets.
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// ActiveDeadlineSeconds limits duration of a single run.
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// ScaleTestStatus is the observed state of the tests.
+type ScaleTestStatus struct {
+	ObservedGeneration int64                `json:"observedGeneration,omitempty"`
+	Phase              string               `json:"phase,omitempty"`
+	LastJob            string               `json:"lastJob,omitempty"`
+	StartTime          *metav1.Time         `json:"startTime,omitempty"`
+	CompletionTime     *metav1.Time         `json:"completionTime,omitempty"`
+	LastScheduleTime   *metav1.Time         `json:"lastScheduleTime,omitempty"`
+	Conditions         []ScaleTestCondition `json:"conditions,omitempty"`
+}
+
+// ScaleTestCondition describes state of the tests at a certain point.
+type ScaleTestCondition struct {
+	Type               string                 `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	gcpDiskProvider   = "gcp"
+	awsDiskProvider   = "aws"
+	azureDiskProvider = "azure"
+
+	zoneLabel   = "failure-domain.beta.kubernetes.io/zone"
+	regionLabel = "failure-domain.beta.kubernetes.io/region"
+
+	ec2APIVersion             = "2016-11-15"
+	azureSnapshotAPIVersion   = "2019-07-01"
+	snapshotRequestTimeout    = time.Minute
+	prometheusSnapshotComment = "Prometheus disk snapshot created by ClusterLoader2"
+)
+
+// diskProvider returns cloud whose disks are used by the given provider or empty string
+// if snapshotting disks of the provider isn't supported.
+func diskProvider(provider string) string {
+	switch provider {
+	case "gce", "gke", "kubemark":
+		return gcpDiskProvider
+	case "aws", "eks":
+		return awsDiskProvider
+	case "azure", "aks":
+		return azureDiskProvider
+	default:
+		return ""
+	}
+}
+
+// diskOfPV returns name and zone of the disk backing the persistent volume. For AWS the name is
+// the EBS volume id, for Azure it's the managed disk resource id. For both of them the zone is
+// the region of the disk.
+func diskOfPV(pv *corev1.PersistentVolume) (name, zone string) {
+	switch {
+	case pv.Spec.GCEPersistentDisk != nil:
+		return pv.Spec.GCEPersistentDisk.PDName, pv.Labels[zoneLabel]
+	case pv.Spec.AWSElasticBlockStore != nil:
+		// Volume id is either vol-xxx or aws://zone/vol-xxx.
+		volumeID := pv.Spec.AWSElasticBlockStore.VolumeID
+		return volumeID[strings.LastIndex(volumeID, "/")+1:], pv.Labels[regionLabel]
+	case pv.Spec.AzureDisk != nil:
+		return pv.Spec.AzureDisk.DataDiskURI, pv.Labels[regionLabel]
+	default:
+		return "", ""
+	}
+}
+
+// snapshotEBSVolume creates snapshot of the EBS volume with EC2 CreateSnapshot API. The snapshot is tagged
+// with the given name, as EBS snapshots don't have names. AWS_* credentials are used.
+func snapshotEBSVolume(volumeID, snapshotName, region string) error {
+	awsCredentials, err := util.GetAWSCredentials()
+	if err != nil {
+		return err
+	}
+	endpoint, err := endpoints.DefaultResolver().EndpointFor(endpoints.Ec2ServiceID, region)
+	if err != nil {
+		return fmt.Errorf("resolving EC2 endpoint in region %s error: %v", region, err)
+	}
+	form := url.Values{}
+	form.Set("Action", "CreateSnapshot")
+	form.Set("Version", ec2APIVersion)
+	form.Set("VolumeId", volumeID)
+	form.Set("Description", prometheusSnapshotComment)
+	form.Set("TagSpecification.1.ResourceType", "snapshot")
+	form.Set("TagSpecification.1.Tag.1.Key", "Name")
+	form.Set("TagSpecification.1.Tag.1.Value", snapshotName)
+	body := strings.NewReader(form.Encode())
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, body)
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if _, err := v4.NewSigner(awsCredentials).Sign(request, body, endpoint.SigningName, endpoint.SigningRegion, time.Now()); err != nil {
+		return fmt.Errorf("signing request error: %v", err)
+	}
+	return doSnapshotRequest(request)
+}
+
+// snapshotAzureDisk creates snapshot of the managed disk with Azure Resource Manager API, in the resource
+// group of the disk. Service principal credentials are taken from AZURE_TENANT_ID, AZURE_CLIENT_ID
+// and AZURE_CLIENT_SECRET credentials.
+func snapshotAzureDisk(diskURI, snapshotName, location string) error {
+	subscription, resourceGroup, err := parseAzureDiskURI(diskURI)
+	if err != nil {
+		return err
+	}
+	env, err := azure.EnvironmentFromName(*prometheusDiskSnapshotAzureEnvironment)
+	if err != nil {
+		return err
+	}
+	var tenantID, clientID, clientSecret string
+	for name, value := range map[string]*string{
+		credentials.AzureTenantID:     &tenantID,
+		credentials.AzureClientID:     &clientID,
+		credentials.AzureClientSecret: &clientSecret,
+	} {
+		if *value, err = credentials.Get(name); err != nil {
+			return err
+		}
+	}
+	if tenantID == "" || clientID == "" || clientSecret == "" {
+		return fmt.Errorf("%s, %s and %s have to be provided", credentials.AzureTenantID, credentials.AzureClientID, credentials.AzureClientSecret)
+	}
+	oauthConfig, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return fmt.Errorf("azure oauth config error: %v", err)
+	}
+	token, err := adal.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, env.ResourceManagerEndpoint)
+	if err != nil {
+		return fmt.Errorf("azure token creation error: %v", err)
+	}
+	snapshot := map[string]interface{}{
+		"location": location,
+		"properties": map[string]interface{}{
+			"creationData": map[string]string{"createOption": "Copy", "sourceResourceId": diskURI},
+		},
+	}
+	request, err := autorest.Prepare(&http.Request{},
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.AsPut(),
+		autorest.WithBaseURL(env.ResourceManagerEndpoint),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/snapshots/{snapshotName}",
+			map[string]interface{}{
+				"subscriptionId":    subscription,
+				"resourceGroupName": resourceGroup,
+				"snapshotName":      snapshotName,
+			}),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": azureSnapshotAPIVersion}),
+		autorest.WithJSON(snapshot),
+		autorest.NewBearerAuthorizer(token).WithAuthorization())
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	response, err := autorest.SendWithSender(&http.Client{Timeout: snapshotRequestTimeout}, request)
+	if err != nil {
+		return fmt.Errorf("snapshot request error: %v", err)
+	}
+	if err := autorest.Respond(response,
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusCreated, http.StatusAccepted),
+		autorest.ByClosing()); err != nil {
+		return fmt.Errorf("snapshot request failed: %v", err)
+	}
+	logrus.Infof("Creating disk snapshot %s started", snapshotName)
+	return nil
+}
+
+// parseAzureDiskURI returns subscription and resource group of the managed disk with resource id
+// /subscriptions/<subscription>/resourceGroups/<resource group>/providers/Microsoft.Compute/disks/<name>.
+func parseAzureDiskURI(diskURI string) (subscription, resourceGroup string, err error) {
+	parts := strings.Split(strings.Trim(diskURI, "/"), "/")
+	if len(parts) != 8 || !strings.EqualFold(parts[0], "subscriptions") || !strings.EqualFold(parts[2], "resourceGroups") ||
+		!strings.EqualFold(parts[6], "disks") {
+		return "", "", fmt.Errorf("unsupported azure disk uri %q, only managed disks can be snapshotted", diskURI)
+	}
+	return parts[1], parts[3], nil
+}
+
+// doSnapshotRequest sends snapshot creation request. Snapshots are created asynchronously,
+// so success means only that the creation has started.
+func doSnapshotRequest(request *http.Request) error {
+	client := &http.Client{Timeout: snapshotRequestTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("snapshot request error: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("reading snapshot response error: %v", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("snapshot request failed with status %s: %s", response.Status, string(body))
+	}
+	logrus.Infof("Creating disk snapshot started: %s", string(body))
+	return nil
+}
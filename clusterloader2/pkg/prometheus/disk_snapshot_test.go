@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiskOfPV(t *testing.T) {
+	labels := map[string]string{zoneLabel: "us-east-1a", regionLabel: "us-east-1"}
+	cases := []struct {
+		name     string
+		labels   map[string]string
+		source   corev1.PersistentVolumeSource
+		wantName string
+		wantZone string
+	}{
+		{
+			name:     "gce",
+			source:   corev1.PersistentVolumeSource{GCEPersistentDisk: &corev1.GCEPersistentDiskVolumeSource{PDName: "pd-1"}},
+			wantName: "pd-1",
+			wantZone: "us-east-1a",
+		},
+		{
+			name:     "aws",
+			source:   corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://us-east-1a/vol-123"}},
+			wantName: "vol-123",
+			wantZone: "us-east-1",
+		},
+		{
+			name:     "aws local zone",
+			labels:   map[string]string{zoneLabel: "us-west-2-lax-1a", regionLabel: "us-west-2"},
+			source:   corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{VolumeID: "aws://us-west-2-lax-1a/vol-456"}},
+			wantName: "vol-456",
+			wantZone: "us-west-2",
+		},
+		{
+			name:     "azure",
+			source:   corev1.PersistentVolumeSource{AzureDisk: &corev1.AzureDiskVolumeSource{DataDiskURI: "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Compute/disks/d"}},
+			wantName: "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Compute/disks/d",
+			wantZone: "us-east-1",
+		},
+		{
+			name:   "unsupported",
+			source: corev1.PersistentVolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/tmp"}},
+		},
+	}
+	for _, tc := range cases {
+		if tc.labels == nil {
+			tc.labels = labels
+		}
+		pv := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Labels: tc.labels},
+			Spec:       corev1.PersistentVolumeSpec{PersistentVolumeSource: tc.source},
+		}
+		name, zone := diskOfPV(pv)
+		assert.Equal(t, tc.wantName, name, tc.name)
+		assert.Equal(t, tc.wantZone, zone, tc.name)
+	}
+}
+
+func TestParseAzureDiskURI(t *testing.T) {
+	subscription, resourceGroup, err := parseAzureDiskURI("/subscriptions/sub-1/resourceGroups/mc_rg/providers/Microsoft.Compute/disks/pvc-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "sub-1", subscription)
+	assert.Equal(t, "mc_rg", resourceGroup)
+
+	_, _, err = parseAzureDiskURI("https://account.blob.core.windows.net/vhds/pvc-1.vhd")
+	assert.Error(t, err)
+}
@@ -22,29 +22,35 @@ import (
 	"os/exec"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
+// prometheusDiskMetadata describes Prometheus persistent disk, see diskOfPV.
 type prometheusDiskMetadata struct {
 	name string
 	zone string
 }
 
 var (
-	shouldSnapshotPrometheusDisk = pflag.Bool("experimental-gcp-snapshot-prometheus-disk", false, "(experimental, provider=gce|gke only) whether to snapshot Prometheus disk before Prometheus stack is torn down")
+	shouldSnapshotPrometheusDisk = pflag.Bool("experimental-gcp-snapshot-prometheus-disk", false, "(experimental, provider=gce|gke|kubemark|aws|eks|azure|aks only) whether to snapshot Prometheus disk before Prometheus stack is torn down")
 	prometheusDiskSnapshotName   = pflag.String("experimental-prometheus-disk-snapshot-name", "", "Name of the prometheus disk snapshot that will be created if snapshots are enabled. If not set, the prometheus disk name will be used.")
+	// prometheusDiskSnapshotAzureEnvironment is the name of the Azure cloud, e.g. AzureUSGovernmentCloud or AzureChinaCloud.
+	prometheusDiskSnapshotAzureEnvironment = pflag.String("experimental-prometheus-disk-snapshot-azure-environment", azure.PublicCloud.Name, "(azure|aks provider only) Azure environment of the cluster, e.g. AzurePublicCloud, AzureUSGovernmentCloud or AzureChinaCloud.")
 )
 
 func (pc *PrometheusController) isEnabled() (bool, error) {
 	if !*shouldSnapshotPrometheusDisk {
 		return false, nil
 	}
-	if pc.provider != "gce" && pc.provider != "gke" && pc.provider != "kubemark" {
+	if diskProvider(pc.provider) == "" {
 		return false, fmt.Errorf(
-			"snapshotting Prometheus' disk only available for GCP providers (gce, gke, kubemark), provider is: %s", pc.provider)
+			"snapshotting Prometheus' disk only available for GCP (gce, gke, kubemark), AWS (aws, eks) and Azure (azure, aks) providers, provider is: %s", pc.provider)
 	}
 	return true, nil
 }
@@ -74,8 +80,13 @@ func (pc *PrometheusController) tryRetrievePrometheusDiskMetadata() (bool, error
 			continue
 		}
 		logrus.Infof("Found Prometheus' PV with name: %s", pv.Name)
-		pdName = pv.Spec.GCEPersistentDisk.PDName
-		zone = pv.ObjectMeta.Labels["failure-domain.beta.kubernetes.io/zone"]
+		pdName, zone = diskOfPV(&pv)
+		if pdName != "" && zone == "" && diskProvider(pc.provider) == awsDiskProvider {
+			// Volumes provisioned without the region label are snapshotted in the region of clusterloader.
+			if zone, err = util.LookupAWSRegion(); err != nil {
+				logrus.Errorf("Getting region of PV %s error: %v", pv.Name, err)
+			}
+		}
 		logrus.Infof("PD name=%s, zone=%s", pdName, zone)
 	}
 	if pdName == "" || zone == "" {
@@ -131,12 +142,33 @@ func (pc *PrometheusController) snapshotPrometheusDiskIfEnabled() error {
 func (pc *PrometheusController) trySnapshotPrometheusDisk(pdName, snapshotName, zone string) error {
 	logrus.Info("Trying to snapshot Prometheus' persistent disk...")
 	logrus.Infof("Snapshotting PD %q into snapshot %q in zone %q", pdName, snapshotName, zone)
+	var err error
+	switch diskProvider(pc.provider) {
+	case awsDiskProvider:
+		err = snapshotEBSVolume(pdName, snapshotName, zone)
+	case azureDiskProvider:
+		err = snapshotAzureDisk(pdName, snapshotName, zone)
+	default:
+		err = snapshotGCEDisk(pdName, snapshotName, zone)
+	}
+	if err != nil {
+		logrus.Errorf("Creating disk snapshot failed: %v", err)
+	}
+	return err
+}
+
+func snapshotGCEDisk(pdName, snapshotName, zone string) error {
+	env, cleanup, err := credentials.GCloudEnv()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	cmd := exec.Command("gcloud", "compute", "disks", "snapshot", pdName, "--zone", zone, "--snapshot-names", snapshotName)
+	cmd.Env = env
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		logrus.Errorf("Creating disk snapshot failed: %v\nCommand output: %q", err, string(output))
-	} else {
-		logrus.Infof("Creating disk snapshot finished with: %q", string(output))
+		return fmt.Errorf("%v, command output: %q", err, string(output))
 	}
-	return err
+	logrus.Infof("Creating disk snapshot finished with: %q", string(output))
+	return nil
 }
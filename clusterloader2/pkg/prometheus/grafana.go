@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	grafanaService     = "grafana:3000"
+	grafanaAnnotations = "api/annotations"
+
+	annotationTag = "clusterloader2"
+)
+
+// grafanaAnnotation is a body of grafana annotations API request. Times are in milliseconds since epoch.
+type grafanaAnnotation struct {
+	Time    int64    `json:"time"`
+	TimeEnd int64    `json:"timeEnd,omitempty"`
+	Tags    []string `json:"tags"`
+	Text    string   `json:"text"`
+}
+
+// GrafanaAnnotator pushes annotations of test events (steps, chaos injections, violations)
+// to grafana deployed with the prometheus stack, so that they are overlaid on dashboards.
+// Annotations are best-effort: failures are only logged. All methods of nil annotator are no-op.
+type GrafanaAnnotator struct {
+	client kubernetes.Interface
+}
+
+// NewGrafanaAnnotator creates annotator using client of the cluster where the prometheus stack is set up.
+func NewGrafanaAnnotator(client kubernetes.Interface) *GrafanaAnnotator {
+	return &GrafanaAnnotator{client: client}
+}
+
+// Annotate pushes annotation of an event that happened at given time.
+func (g *GrafanaAnnotator) Annotate(t time.Time, text string, tags ...string) {
+	g.AnnotateRange(t, time.Time{}, text, tags...)
+}
+
+// AnnotateRange pushes annotation of a time range. Zero end means a point in time.
+func (g *GrafanaAnnotator) AnnotateRange(start, end time.Time, text string, tags ...string) {
+	if g == nil {
+		return
+	}
+	annotation := newGrafanaAnnotation(start, end, text, tags)
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		logrus.Warningf("Grafana annotation %q encoding error: %v", text, err)
+		return
+	}
+	err = g.client.CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("services").
+		Name(grafanaService).
+		SubResource("proxy").
+		Suffix(grafanaAnnotations).
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		Do().
+		Error()
+	if err != nil {
+		logrus.Warningf("Pushing grafana annotation %q error: %v", text, err)
+	}
+}
+
+func newGrafanaAnnotation(start, end time.Time, text string, tags []string) *grafanaAnnotation {
+	annotation := &grafanaAnnotation{
+		Time: toMilliseconds(start),
+		Tags: append([]string{annotationTag}, tags...),
+		Text: text,
+	}
+	if !end.IsZero() {
+		annotation.TimeEnd = toMilliseconds(end)
+	}
+	return annotation
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// printGrafanaURL logs how grafana deployed with the prometheus stack can be accessed.
+func printGrafanaURL(client kubernetes.Interface) {
+	proxyURL := client.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource("services").
+		Name(grafanaService).
+		SubResource("proxy").
+		URL()
+	logrus.Infof("Grafana is available at %s/ (through the apiserver proxy), "+
+		"or at http://localhost:3000 after running: kubectl --namespace %s port-forward svc/grafana 3000", proxyURL, namespace)
+}
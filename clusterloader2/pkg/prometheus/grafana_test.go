@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGrafanaAnnotation(t *testing.T) {
+	start := time.Unix(1546300800, 0)
+	tests := []struct {
+		end  time.Time
+		want string
+	}{
+		{time.Time{}, `{"time":1546300800000,"tags":["clusterloader2","step"],"text":"Step \"load\""}`},
+		{start.Add(1500 * time.Millisecond), `{"time":1546300800000,"timeEnd":1546300801500,"tags":["clusterloader2","step"],"text":"Step \"load\""}`},
+	}
+
+	for _, test := range tests {
+		body, err := json.Marshal(newGrafanaAnnotation(start, test.end, `Step "load"`, []string{"step"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(body) != test.want {
+			t.Errorf("Incorrect annotation, got: %s, want: %s", body, test.want)
+		}
+	}
+
+	// Nil annotator is a no-op.
+	var annotator *GrafanaAnnotator
+	annotator.Annotate(start, "ignored")
+}
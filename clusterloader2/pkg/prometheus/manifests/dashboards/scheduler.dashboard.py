@@ -0,0 +1,92 @@
+#!/usr/bin/env python
+
+# Copyright 2019 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+from grafanalib import core as g
+import defaults as d
+
+
+def histogram_quantiles(title, metric):
+    return d.Graph(
+        title=title,
+        targets=d.show_quantiles(
+            "histogram_quantile({quantile}, sum(rate(%s_bucket[1m])) by (le))" % metric
+        ),
+        yAxes=g.single_y_axis(format=g.SECONDS_FORMAT),
+        nullPointMode="null",
+    )
+
+
+SCHEDULING_PANELS = [
+    d.Graph(
+        title="Scheduling throughput",
+        targets=[
+            g.Target(
+                expr="sum(rate(scheduler_schedule_attempts_total[1m])) by (result)",
+                legendFormat="{{result}}",
+            )
+        ],
+        nullPointMode="null",
+    ),
+    d.Graph(
+        title="Pending pods",
+        targets=[
+            g.Target(
+                expr="sum(scheduler_pending_pods) by (queue)",
+                legendFormat="{{queue}}",
+            )
+        ],
+        nullPointMode="null",
+    ),
+    histogram_quantiles(
+        "E2e scheduling latency", "scheduler_e2e_scheduling_duration_seconds"
+    ),
+    histogram_quantiles(
+        "Scheduling algorithm latency", "scheduler_scheduling_algorithm_duration_seconds"
+    ),
+    histogram_quantiles("Binding latency", "scheduler_binding_duration_seconds"),
+]
+
+PROCESS_PANELS = [
+    d.Graph(
+        title="kube-scheduler: CPU usage",
+        targets=[
+            g.Target(
+                expr='rate(process_cpu_seconds_total{job="kube-scheduler"}[1m])',
+                legendFormat="{{instance}}",
+            )
+        ],
+        nullPointMode="null",
+    ),
+    d.Graph(
+        title="kube-scheduler: memory usage",
+        targets=[
+            g.Target(
+                expr='process_resident_memory_bytes{job="kube-scheduler"}',
+                legendFormat="{{instance}}",
+            )
+        ],
+        nullPointMode="null",
+        yAxes=g.single_y_axis(format=g.BYTES_FORMAT),
+    ),
+]
+
+dashboard = d.Dashboard(
+    title="Scheduler",
+    rows=[
+        d.Row(title="Scheduling", panels=SCHEDULING_PANELS),
+        d.Row(title="kube-scheduler process", panels=PROCESS_PANELS),
+    ],
+).auto_panel_ids()
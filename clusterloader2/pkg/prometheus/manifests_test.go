@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"io/fs"
+	"testing"
+)
+
+func TestEmbeddedManifests(t *testing.T) {
+	manifests, err := manifestsFS("")
+	if err != nil {
+		t.Fatalf("manifestsFS() error: %v", err)
+	}
+	for _, manifestGlob := range []string{coreManifests, grafanaManifests, defaultServiceMonitors, masterIPServiceMonitors, kubemarkServiceMonitors, kubeStateMetricsManifests, nodeExporterPod} {
+		matches, err := fs.Glob(manifests, manifestGlob)
+		if err != nil {
+			t.Errorf("glob %s error: %v", manifestGlob, err)
+		}
+		if len(matches) == 0 {
+			t.Errorf("no embedded manifests match %s", manifestGlob)
+		}
+	}
+}
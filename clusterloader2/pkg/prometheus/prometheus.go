@@ -19,7 +19,7 @@ package prometheus
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/fs"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -34,19 +34,27 @@ import (
 	"k8s.io/perf-tests/clusterloader2/pkg/flags"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/selfmetrics"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
+// Namespace is the namespace where the prometheus stack is set up.
+const Namespace = "monitoring"
+
 const (
-	namespace                    = "monitoring"
-	coreManifests                = "/opt/manifests/*.yaml"
-	defaultServiceMonitors       = "/opt/manifests/default/*.yaml"
-	masterIPServiceMonitors      = "/opt/manifests/default/master-ip/*.yaml"
-	kubemarkServiceMonitors      = "/opt/manifests/kubemark/*.yaml"
+	namespace                    = Namespace
+	coreManifests                = "*.yaml"
+	grafanaManifests             = "grafana/*.yaml"
+	defaultServiceMonitors       = "default/*.yaml"
+	masterIPServiceMonitors      = "default/master-ip/*.yaml"
+	clusterLoaderServiceMonitors = "default/clusterloader/*.yaml"
+	kubemarkServiceMonitors      = "kubemark/*.yaml"
+	kubeStateMetricsManifests    = "kube-state-metrics/*.yaml"
 	checkPrometheusReadyInterval = 30 * time.Second
 	checkPrometheusReadyTimeout  = 15 * time.Minute
 	numK8sClients                = 1
-	nodeExporterPod              = "/opt/manifests/exporters/node-exporter.yaml"
+	nodeExporterPod              = "exporters/node-exporter.yaml"
 )
 
 // InitFlags initializes prometheus flags.
@@ -57,6 +65,28 @@ func InitFlags(p *config.PrometheusConfig) {
 	flags.BoolEnvVar(&p.ScrapeNodeExporter, "prometheus-scrape-node-exporter", "PROMETHEUS_SCRAPE_NODE_EXPORTER", false, "Whether to scrape node exporter metrics.")
 	flags.BoolEnvVar(&p.ScrapeKubelets, "prometheus-scrape-kubelets", "PROMETHEUS_SCRAPE_KUBELETS", false, "Whether to scrape kubelets. Experimental, may not work in larger clusters. Requires heapster node to be at least n1-standard-4, which needs to be provided manually.")
 	flags.BoolEnvVar(&p.ScrapeKubeProxy, "prometheus-scrape-kube-proxy", "PROMETHEUS_SCRAPE_KUBE_PROXY", true, "Whether to scrape kube proxy.")
+	flags.StringEnvVar(&p.ScrapeCNI, "prometheus-scrape-cni", "PROMETHEUS_SCRAPE_CNI", "", "CNI agent whose metrics should be scraped, one of: cilium, calico. If empty, CNI agents are not scraped.")
+	flags.StringEnvVar(&p.ScrapeIngressController, "prometheus-scrape-ingress-controller", "PROMETHEUS_SCRAPE_INGRESS_CONTROLLER", "", "Ingress controller whose metrics should be scraped, one of: nginx, contour. If empty, ingress controllers are not scraped.")
+	flags.BoolEnvVar(&p.EnableGrafana, "enable-grafana", "ENABLE_GRAFANA", true, "Whether to deploy grafana with pre-built dashboards (apiserver SLIs, etcd, scheduler, probes) together with the prometheus server.")
+	flags.BoolEnvVar(&p.EnableGrafanaAnnotations, "enable-grafana-annotations", "ENABLE_GRAFANA_ANNOTATIONS", true, "Whether to annotate grafana dashboards with test steps, chaos injections and violations (if the prometheus server is set-up).")
+	flags.BoolEnvVar(&p.EnableKubeStateMetrics, "enable-kube-state-metrics", "ENABLE_KUBE_STATE_METRICS", false, "Whether to deploy kube-state-metrics together with the prometheus server. Required by measurements based on object-state metrics, e.g. PodPhaseCounts.")
+	flags.IntEnvVar(&p.KubeStateMetricsShards, "kube-state-metrics-shards", "KUBE_STATE_METRICS_SHARDS", 0, "Number of kube-state-metrics shards. If not positive, one shard per 1000 nodes is deployed.")
+	flags.StringEnvVar(&p.ThanosObjstoreConfig, "prometheus-thanos-objstore-config", "PROMETHEUS_THANOS_OBJSTORE_CONFIG", "", "Path to Thanos object storage config (GCS, S3, Azure Blob...). If set, prometheus is deployed with Thanos sidecar uploading TSDB blocks to the configured bucket, so that prometheus data is archived on any provider.")
+	flags.StringEnvVar(&p.ManifestsDir, "prometheus-manifests-dir", "PROMETHEUS_MANIFESTS_DIR", "", "Directory with custom manifests of the prometheus stack, laid out as pkg/prometheus/manifests. If empty, manifests embedded in the binary are used.")
+	flags.StringEnvVar(&p.ProbesManifestsDir, "probes-manifests-dir", "PROBES_MANIFESTS_DIR", "", "Directory with custom manifests of probes, laid out as pkg/measurement/common/probes/manifests. If empty, manifests embedded in the binary are used.")
+	flags.StringSliceEnvVar(&p.AdditionalManifests, "prometheus-additional-manifests", "PROMETHEUS_ADDITIONAL_MANIFESTS", nil /*defaultValue*/, "Comma-separated list of globs of additional manifests (e.g. ServiceMonitors scraping a CNI or CSI driver, PrometheusRules with recording rules) applied together with the prometheus stack. Manifests are templated with the same mapping as the embedded ones.")
+	flags.StringEnvVar(&p.Sizing.CPURequest, "prometheus-cpu-request", "PROMETHEUS_CPU_REQUEST", "", "CPU request of the prometheus server (e.g. 4). If empty, it's computed based on the number of nodes.")
+	flags.StringEnvVar(&p.Sizing.MemoryRequest, "prometheus-memory-request", "PROMETHEUS_MEMORY_REQUEST", "", "Memory request of the prometheus server (e.g. 16Gi). If empty, it's computed based on the number of nodes and enabled scrape targets.")
+	flags.StringEnvVar(&p.Sizing.StorageSize, "prometheus-storage-size", "PROMETHEUS_STORAGE_SIZE", "", "Size of the prometheus server persistent volume (e.g. 100Gi). If empty, it's computed based on the number of nodes.")
+	flags.StringEnvVar(&p.Sizing.Retention, "prometheus-retention", "PROMETHEUS_RETENTION", "", "Retention of prometheus data (e.g. 12h). If empty, data is kept for 7 days.")
+	flags.StringEnvVar(&p.Endpoint, "prometheus-endpoint", "PROMETHEUS_ENDPOINT", "", "URL of an external, already running Prometheus server (e.g. https://prometheus.example.com). If set, the prometheus stack isn't deployed and all queries are sent to this server.")
+	flags.StringEnvVar(&p.Auth.BearerTokenFile, "prometheus-bearer-token-file", "PROMETHEUS_BEARER_TOKEN_FILE", "", "Path to the file with the bearer token used to authenticate to the external Prometheus server.")
+	flags.StringEnvVar(&p.Auth.Username, "prometheus-basic-auth-username", "PROMETHEUS_BASIC_AUTH_USERNAME", "", "Username used to authenticate to the external Prometheus server with basic auth.")
+	flags.StringEnvVar(&p.Auth.PasswordFile, "prometheus-basic-auth-password-file", "PROMETHEUS_BASIC_AUTH_PASSWORD_FILE", "", "Path to the file with the password used to authenticate to the external Prometheus server with basic auth.")
+	flags.StringEnvVar(&p.Auth.CertFile, "prometheus-client-cert-file", "PROMETHEUS_CLIENT_CERT_FILE", "", "Path to the client certificate used to authenticate to the external Prometheus server with mTLS.")
+	flags.StringEnvVar(&p.Auth.KeyFile, "prometheus-client-key-file", "PROMETHEUS_CLIENT_KEY_FILE", "", "Path to the client key used to authenticate to the external Prometheus server with mTLS.")
+	flags.StringEnvVar(&p.Auth.CAFile, "prometheus-ca-file", "PROMETHEUS_CA_FILE", "", "Path to the CA certificate used to verify the external Prometheus server.")
+	flags.BoolEnvVar(&p.Auth.InsecureSkipVerify, "prometheus-insecure-skip-verify", "PROMETHEUS_INSECURE_SKIP_VERIFY", false, "Whether to skip verification of the external Prometheus server certificate.")
 }
 
 // PrometheusController is a util for managing (setting up / tearing down) the prometheus stack in
@@ -70,6 +100,8 @@ type PrometheusController struct {
 	framework *framework.Framework
 	// templateMapping is a mapping defining placeholders used in manifest templates.
 	templateMapping map[string]interface{}
+	// manifests is the filesystem with manifests of the prometheus stack.
+	manifests fs.FS
 	// diskMetadata store name and zone of Prometheus persistent disk.
 	diskMetadata prometheusDiskMetadata
 }
@@ -84,6 +116,9 @@ func NewPrometheusController(clusterLoaderConfig *config.ClusterLoaderConfig) (p
 	if pc.framework, err = framework.NewRootFramework(&clusterLoaderConfig.ClusterConfig, numK8sClients); err != nil {
 		return nil, err
 	}
+	if pc.manifests, err = manifestsFS(clusterLoaderConfig.PrometheusConfig.ManifestsDir); err != nil {
+		return nil, fmt.Errorf("prometheus manifests error: %v", err)
+	}
 
 	mapping, errList := config.GetMapping(clusterLoaderConfig)
 	if errList != nil {
@@ -94,6 +129,14 @@ func NewPrometheusController(clusterLoaderConfig *config.ClusterLoaderConfig) (p
 		logrus.Warningf("Couldn't get master ip, will ignore manifests requiring it: %v", err)
 		delete(mapping, "MasterIps")
 	}
+	if clusterLoaderConfig.SelfMetricsConfig.Scrape {
+		port, err := selfmetrics.Port(&clusterLoaderConfig.SelfMetricsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("self-metrics address error: %v", err)
+		}
+		mapping["ClusterLoaderIP"] = clusterLoaderConfig.SelfMetricsConfig.IP
+		mapping["ClusterLoaderPort"] = port
+	}
 	// TODO: Change to pure assignments when overrides are not used.
 	if _, exists := mapping["PROMETHEUS_SCRAPE_ETCD"]; !exists {
 		mapping["PROMETHEUS_SCRAPE_ETCD"] = clusterLoaderConfig.PrometheusConfig.ScrapeEtcd
@@ -114,6 +157,21 @@ func NewPrometheusController(clusterLoaderConfig *config.ClusterLoaderConfig) (p
 		clusterLoaderConfig.PrometheusConfig.ScrapeKubeProxy = mapping["PROMETHEUS_SCRAPE_KUBE_PROXY"].(bool)
 	}
 	mapping["PROMETHEUS_SCRAPE_KUBELETS"] = clusterLoaderConfig.PrometheusConfig.ScrapeKubelets
+	mapping["PROMETHEUS_SCRAPE_CNI"] = clusterLoaderConfig.PrometheusConfig.ScrapeCNI
+	mapping["PROMETHEUS_SCRAPE_INGRESS_CONTROLLER"] = clusterLoaderConfig.PrometheusConfig.ScrapeIngressController
+	mapping["PROMETHEUS_THANOS_ENABLED"] = pc.isThanosEnabled()
+	sizing, err := computePrometheusSizing(clusterLoaderConfig.ClusterConfig.Nodes, &clusterLoaderConfig.PrometheusConfig, pc.isKubemark())
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Prometheus server sizing: %+v", *sizing)
+	mapping["PROMETHEUS_CPU_REQUEST"] = sizing.cpuRequest
+	mapping["PROMETHEUS_MEMORY_REQUEST"] = sizing.memoryRequest
+	mapping["PROMETHEUS_STORAGE_SIZE"] = sizing.storageSize
+	mapping["PROMETHEUS_RETENTION"] = sizing.retention
+	if clusterLoaderConfig.PrometheusConfig.EnableKubeStateMetrics {
+		mapping["KUBE_STATE_METRICS_SHARDS"] = pc.kubeStateMetricsShards()
+	}
 	pc.templateMapping = mapping
 
 	return pc, nil
@@ -123,15 +181,33 @@ func NewPrometheusController(clusterLoaderConfig *config.ClusterLoaderConfig) (p
 // This method is idempotent, if the prometheus stack is already set up applying the manifests
 // again will be no-op.
 func (pc *PrometheusController) SetUpPrometheusStack() error {
+	if pc.isExternal() {
+		return pc.verifyExternalPrometheus()
+	}
 	k8sClient := pc.framework.GetClientSets().GetClient()
 
 	logrus.Info("Setting up prometheus stack")
-	if err := client.CreateNamespace(k8sClient, namespace); err != nil {
+	if err := client.CreateNamespace(k8sClient, namespace, pc.clusterLoaderConfig.ClusterConfig.RunID); err != nil {
 		return err
 	}
+	if pc.isThanosEnabled() {
+		if err := pc.createThanosObjstoreSecret(k8sClient); err != nil {
+			return err
+		}
+	}
 	if err := pc.applyManifests(coreManifests); err != nil {
 		return err
 	}
+	if pc.clusterLoaderConfig.PrometheusConfig.EnableGrafana {
+		if err := pc.applyManifests(grafanaManifests); err != nil {
+			return err
+		}
+	}
+	if pc.clusterLoaderConfig.PrometheusConfig.EnableKubeStateMetrics {
+		if err := pc.applyManifests(kubeStateMetricsManifests); err != nil {
+			return err
+		}
+	}
 	if pc.clusterLoaderConfig.PrometheusConfig.ScrapeNodeExporter {
 		if err := pc.runNodeExporter(); err != nil {
 			return err
@@ -154,11 +230,24 @@ func (pc *PrometheusController) SetUpPrometheusStack() error {
 			}
 		}
 	}
+	if pc.clusterLoaderConfig.SelfMetricsConfig.Scrape {
+		if err := pc.applyManifests(clusterLoaderServiceMonitors); err != nil {
+			return err
+		}
+	}
+	for _, manifestGlob := range pc.clusterLoaderConfig.PrometheusConfig.AdditionalManifests {
+		if err := pc.framework.ApplyTemplatedManifests(manifestGlob, pc.templateMapping, client.Retry(apierrs.IsNotFound)); err != nil {
+			return fmt.Errorf("applying additional manifests %s error: %v", manifestGlob, err)
+		}
+	}
 	if err := pc.waitForPrometheusToBeHealthy(); err != nil {
 		dumpAdditionalLogsOnPrometheusSetupFailure(k8sClient)
 		return err
 	}
 	logrus.Info("Prometheus stack set up successfully")
+	if pc.clusterLoaderConfig.PrometheusConfig.EnableGrafana {
+		printGrafanaURL(k8sClient)
+	}
 	if err := pc.cachePrometheusDiskMetadataIfEnabled(); err != nil {
 		logrus.Warningf("Error while caching prometheus disk metadata: %v", err)
 	}
@@ -167,9 +256,18 @@ func (pc *PrometheusController) SetUpPrometheusStack() error {
 
 // TearDownPrometheusStack tears down prometheus stack, releasing all prometheus resources.
 func (pc *PrometheusController) TearDownPrometheusStack() error {
+	if pc.isExternal() {
+		logrus.Info("External prometheus server is used, skipping tear down")
+		return nil
+	}
 	if err := pc.snapshotPrometheusDiskIfEnabled(); err != nil {
 		logrus.Warningf("Error while snapshotting prometheus disk: %v", err)
 	}
+	if pc.isThanosEnabled() {
+		if err := pc.uploadThanosSnapshot(); err != nil {
+			logrus.Warningf("Error while uploading prometheus snapshot, samples from the last (up to 2h) block aren't archived: %v", err)
+		}
+	}
 	logrus.Info("Tearing down prometheus stack")
 	k8sClient := pc.framework.GetClientSets().GetClient()
 	if err := client.DeleteNamespace(k8sClient, namespace); err != nil {
@@ -186,9 +284,27 @@ func (pc *PrometheusController) GetFramework() *framework.Framework {
 	return pc.framework
 }
 
+func (pc *PrometheusController) isExternal() bool {
+	return pc.clusterLoaderConfig.PrometheusConfig.Endpoint != ""
+}
+
+// verifyExternalPrometheus checks that the external Prometheus server is reachable
+// with the configured credentials.
+func (pc *PrometheusController) verifyExternalPrometheus() error {
+	logrus.Infof("Using external prometheus server %s", pc.clusterLoaderConfig.PrometheusConfig.Endpoint)
+	executor, err := measurementutil.NewExternalQueryExecutor(&pc.clusterLoaderConfig.PrometheusConfig, measurementutil.DefaultQueryRetryPolicy)
+	if err != nil {
+		return err
+	}
+	if _, err := executor.Query("vector(1)", time.Now()); err != nil {
+		return fmt.Errorf("external prometheus server query error: %v", err)
+	}
+	return nil
+}
+
 func (pc *PrometheusController) applyManifests(manifestGlob string) error {
-	return pc.framework.ApplyTemplatedManifests(
-		manifestGlob, pc.templateMapping, client.Retry(apierrs.IsNotFound))
+	return pc.framework.ApplyTemplatedManifestsFS(
+		pc.manifests, manifestGlob, pc.templateMapping, client.Retry(apierrs.IsNotFound))
 }
 
 // exposeKubemarkApiServerMetrics configures anonymous access to the apiserver metrics in the
@@ -197,7 +313,7 @@ func (pc *PrometheusController) exposeKubemarkApiServerMetrics() error {
 	logrus.Info("Exposing kube-apiserver metrics in kubemark cluster")
 	// This has to be done in the kubemark cluster, thus we need to create a new client.
 	clientSet, err := framework.NewMultiClientSet(
-		pc.clusterLoaderConfig.ClusterConfig.KubeConfigPath, numK8sClients)
+		pc.clusterLoaderConfig.ClusterConfig.KubeConfigPath, numK8sClients, pc.clusterLoaderConfig.ClusterConfig.ClientConfig)
 	if err != nil {
 		return err
 	}
@@ -252,7 +368,7 @@ func (pc *PrometheusController) runNodeExporter() error {
 		if system.IsMasterNode(node.Name) {
 			numMasters++
 			g.Go(func() error {
-				f, err := os.Open(os.ExpandEnv(nodeExporterPod))
+				f, err := pc.manifests.Open(nodeExporterPod)
 				if err != nil {
 					return fmt.Errorf("Unable to open manifest file: %v", err)
 				}
@@ -281,8 +397,14 @@ func (pc *PrometheusController) isPrometheusReady() (bool, error) {
 	// TODO(mm4tt): Re-enable kube-proxy monitoring and expect more targets.
 	// This is a safeguard from a race condition where the prometheus server is started before
 	// targets are registered. These 4 targets are always expected, in all possible configurations:
-	// prometheus, prometheus-operator, grafana, apiserver
+	// prometheus, prometheus-operator, grafana (unless disabled), apiserver
 	expectedTargets := 4
+	if !pc.clusterLoaderConfig.PrometheusConfig.EnableGrafana {
+		expectedTargets--
+	}
+	if pc.clusterLoaderConfig.PrometheusConfig.EnableKubeStateMetrics {
+		expectedTargets += pc.kubeStateMetricsShards()
+	}
 	if pc.clusterLoaderConfig.PrometheusConfig.ScrapeEtcd || pc.isKubemark() {
 		// If scraping etcd is enabled (or it's kubemark where we scrape etcd unconditionally) we need
 		// a bit more complicated logic to asses whether all targets are ready. Etcd metric port has
@@ -308,6 +430,16 @@ func (pc *PrometheusController) isPrometheusReady() (bool, error) {
 		expectedTargets)
 }
 
+// kubeStateMetricsShards returns the number of kube-state-metrics shards. Unless set with a flag,
+// one shard per 1000 nodes is deployed, so that a single shard doesn't have to keep
+// and expose metrics of too many objects.
+func (pc *PrometheusController) kubeStateMetricsShards() int {
+	if shards := pc.clusterLoaderConfig.PrometheusConfig.KubeStateMetricsShards; shards > 0 {
+		return shards
+	}
+	return 1 + (pc.clusterLoaderConfig.ClusterConfig.Nodes-1)/1000
+}
+
 func (pc *PrometheusController) isKubemark() bool {
 	return pc.provider == "kubemark"
 }
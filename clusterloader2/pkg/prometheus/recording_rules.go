@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+const (
+	checkRecordingRulesInterval = 10 * time.Second
+	// Prometheus operator reloads the prometheus configuration asynchronously,
+	// so installed rules may not be loaded immediately.
+	checkRecordingRulesTimeout = 5 * time.Minute
+)
+
+// RecordingRule is a Prometheus recording rule.
+type RecordingRule struct {
+	// Record is the name of the time series produced by the rule.
+	Record string
+	// Expr is the PromQL expression evaluated by the rule.
+	Expr string
+	// Labels are added to the produced time series.
+	Labels map[string]string
+}
+
+// EnsureRecordingRules verifies that given recording rules are loaded by Prometheus.
+// Missing rules are installed as PrometheusRules, unless an external Prometheus server is used,
+// in which case an error listing missing rules is returned.
+func EnsureRecordingRules(f *framework.Framework, prometheusConfig *config.PrometheusConfig, rules []RecordingRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	executor, err := measurementutil.GetQueryExecutor(f.GetClientSets().GetClient(), prometheusConfig, measurementutil.DefaultQueryRetryPolicy)
+	if err != nil {
+		return err
+	}
+	missing, err := missingRecordingRules(executor, rules)
+	if err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if prometheusConfig.Endpoint != "" {
+		return fmt.Errorf("recording rules %v are not loaded by external prometheus server", recordNames(missing))
+	}
+
+	logrus.Infof("Installing recording rules %v", recordNames(missing))
+	for record, recordRules := range groupByRecord(missing) {
+		obj := newPrometheusRule(record, recordRules)
+		if err := f.CreateObject(obj.GetNamespace(), obj.GetName(), obj); err != nil {
+			return fmt.Errorf("recording rule %s creation error: %v", record, err)
+		}
+	}
+	err = wait.Poll(checkRecordingRulesInterval, checkRecordingRulesTimeout, func() (bool, error) {
+		if missing, err = missingRecordingRules(executor, rules); err != nil {
+			return false, err
+		}
+		return len(missing) == 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for recording rules %v to be loaded error: %v", recordNames(missing), err)
+	}
+	return nil
+}
+
+func missingRecordingRules(executor *measurementutil.PrometheusQueryExecutor, rules []RecordingRule) ([]RecordingRule, error) {
+	loaded, err := executor.RecordingRules()
+	if err != nil {
+		return nil, fmt.Errorf("listing recording rules error: %v", err)
+	}
+	var missing []RecordingRule
+	for _, rule := range rules {
+		if !loaded[rule.Record] {
+			missing = append(missing, rule)
+		}
+	}
+	return missing, nil
+}
+
+func groupByRecord(rules []RecordingRule) map[string][]RecordingRule {
+	grouped := make(map[string][]RecordingRule)
+	for _, rule := range rules {
+		grouped[rule.Record] = append(grouped[rule.Record], rule)
+	}
+	return grouped
+}
+
+func recordNames(rules []RecordingRule) []string {
+	var names []string
+	for record := range groupByRecord(rules) {
+		names = append(names, record)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newPrometheusRule creates PrometheusRule with rules recording given time series.
+// Labels of the object match the ruleSelector of the deployed prometheus server.
+func newPrometheusRule(record string, rules []RecordingRule) *unstructured.Unstructured {
+	var specRules []interface{}
+	for _, rule := range rules {
+		specRule := map[string]interface{}{
+			"record": rule.Record,
+			"expr":   rule.Expr,
+		}
+		if len(rule.Labels) > 0 {
+			labels := make(map[string]interface{})
+			for name, value := range rule.Labels {
+				labels[name] = value
+			}
+			specRule["labels"] = labels
+		}
+		specRules = append(specRules, specRule)
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "monitoring.coreos.com/v1",
+			"kind":       "PrometheusRule",
+			"metadata": map[string]interface{}{
+				"name":      "clusterloader-" + strings.NewReplacer(":", "-", "_", "-").Replace(record),
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"prometheus": "k8s",
+					"role":       "alert-rules",
+				},
+			},
+			"spec": map[string]interface{}{
+				"groups": []interface{}{
+					map[string]interface{}{
+						"name":  record,
+						"rules": specRules,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+const (
+	defaultPrometheusRetention = "7d"
+
+	baseCPUMillis = 500
+	// Master components (apiserver, scheduler, controller-manager, etcd) export series
+	// per resource and per node, so cpu and memory grow with the cluster size even if
+	// no per-node targets are scraped.
+	cpuMillisPerNode = 1
+	baseMemoryMiB    = 1024
+	memoryMiBPerNode = 2
+	// Additional memory per node for every scraped per-node target.
+	nodeExporterMemoryMiBPerNode = 1
+	kubeProxyMemoryMiBPerNode    = 1
+	cniMemoryMiBPerNode          = 1
+	// Kubelets export cadvisor metrics of every container, which dominate the series count.
+	kubeletMemoryMiBPerNode = 8
+
+	baseStorageGi             = 10
+	storageGiPerThousandNodes = 10
+)
+
+// prometheusSizing describes resources of the prometheus server.
+type prometheusSizing struct {
+	cpuRequest    string
+	memoryRequest string
+	storageSize   string
+	retention     string
+}
+
+// computePrometheusSizing computes resources of the prometheus server based on the number of nodes
+// and enabled scrape targets. Values set in the sizing config take precedence over computed ones.
+// In kubemark, hollow nodes aren't scraped, so only the master components' series are accounted for.
+func computePrometheusSizing(nodes int, prometheusConfig *config.PrometheusConfig, isKubemark bool) (*prometheusSizing, error) {
+	memoryMiBPerNodeTotal := memoryMiBPerNode
+	if !isKubemark {
+		if prometheusConfig.ScrapeNodeExporter {
+			memoryMiBPerNodeTotal += nodeExporterMemoryMiBPerNode
+		}
+		if prometheusConfig.ScrapeKubeProxy {
+			memoryMiBPerNodeTotal += kubeProxyMemoryMiBPerNode
+		}
+		if prometheusConfig.ScrapeCNI != "" {
+			memoryMiBPerNodeTotal += cniMemoryMiBPerNode
+		}
+		if prometheusConfig.ScrapeKubelets {
+			memoryMiBPerNodeTotal += kubeletMemoryMiBPerNode
+		}
+	}
+	sizing := &prometheusSizing{
+		cpuRequest:    fmt.Sprintf("%dm", baseCPUMillis+cpuMillisPerNode*nodes),
+		memoryRequest: fmt.Sprintf("%dMi", baseMemoryMiB+memoryMiBPerNodeTotal*nodes),
+		storageSize:   fmt.Sprintf("%dGi", baseStorageGi+storageGiPerThousandNodes*(nodes/1000)),
+		retention:     defaultPrometheusRetention,
+	}
+
+	overrides := prometheusConfig.Sizing
+	for _, quantity := range []struct {
+		name     string
+		override string
+		value    *string
+	}{
+		{"cpu request", overrides.CPURequest, &sizing.cpuRequest},
+		{"memory request", overrides.MemoryRequest, &sizing.memoryRequest},
+		{"storage size", overrides.StorageSize, &sizing.storageSize},
+	} {
+		if quantity.override == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(quantity.override); err != nil {
+			return nil, fmt.Errorf("prometheus %s parsing error: %v", quantity.name, err)
+		}
+		*quantity.value = quantity.override
+	}
+	if overrides.Retention != "" {
+		if err := verifyRetention(overrides.Retention); err != nil {
+			return nil, err
+		}
+		sizing.retention = overrides.Retention
+	}
+	return sizing, nil
+}
+
+// verifyRetention checks that retention is a duration in a format accepted by prometheus,
+// e.g. 12h or 7d.
+func verifyRetention(retention string) error {
+	value := retention
+	// Days, weeks and years are supported by prometheus, but not by time.ParseDuration.
+	if last := retention[len(retention)-1]; last == 'd' || last == 'w' || last == 'y' {
+		value = retention[:len(retention)-1] + "h"
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("prometheus retention %q parsing error: %v", retention, err)
+	}
+	return nil
+}
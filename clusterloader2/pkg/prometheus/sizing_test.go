@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func TestComputePrometheusSizing(t *testing.T) {
+	tests := []struct {
+		name             string
+		nodes            int
+		prometheusConfig config.PrometheusConfig
+		isKubemark       bool
+		want             *prometheusSizing
+		wantErr          bool
+	}{
+		{
+			name:             "small cluster",
+			nodes:            50,
+			prometheusConfig: config.PrometheusConfig{ScrapeKubeProxy: true},
+			want:             &prometheusSizing{cpuRequest: "550m", memoryRequest: "1174Mi", storageSize: "10Gi", retention: "7d"},
+		},
+		{
+			name:             "kubemark ignores per-node targets",
+			nodes:            5000,
+			prometheusConfig: config.PrometheusConfig{ScrapeKubeProxy: true, ScrapeKubelets: true},
+			isKubemark:       true,
+			want:             &prometheusSizing{cpuRequest: "5500m", memoryRequest: "11024Mi", storageSize: "60Gi", retention: "7d"},
+		},
+		{
+			name:             "kubelets",
+			nodes:            100,
+			prometheusConfig: config.PrometheusConfig{ScrapeKubelets: true, ScrapeNodeExporter: true},
+			want:             &prometheusSizing{cpuRequest: "600m", memoryRequest: "2124Mi", storageSize: "10Gi", retention: "7d"},
+		},
+		{
+			name:  "overrides",
+			nodes: 100,
+			prometheusConfig: config.PrometheusConfig{Sizing: config.PrometheusSizingConfig{
+				CPURequest: "4", MemoryRequest: "16Gi", StorageSize: "100Gi", Retention: "12h"}},
+			want: &prometheusSizing{cpuRequest: "4", memoryRequest: "16Gi", storageSize: "100Gi", retention: "12h"},
+		},
+		{
+			name:             "invalid memory request",
+			prometheusConfig: config.PrometheusConfig{Sizing: config.PrometheusSizingConfig{MemoryRequest: "16GB"}},
+			wantErr:          true,
+		},
+		{
+			name:             "invalid retention",
+			prometheusConfig: config.PrometheusConfig{Sizing: config.PrometheusSizingConfig{Retention: "1d12h"}},
+			wantErr:          true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := computePrometheusSizing(test.nodes, &test.prometheusConfig, test.isKubemark)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+)
+
+const (
+	// thanosObjstoreSecret is the secret with Thanos object storage config, referenced by prometheus-prometheus.yaml.
+	thanosObjstoreSecret    = "thanos-objstore-config"
+	thanosObjstoreSecretKey = "objstore.yml"
+
+	prometheusPod          = "prometheus-k8s-0"
+	prometheusService      = "prometheus-k8s:9090"
+	thanosSidecarContainer = "thanos-sidecar"
+	// prometheusSnapshotsDir is the directory of TSDB snapshots, the data volume is mounted in thanos sidecar as well.
+	prometheusSnapshotsDir = "/prometheus/snapshots"
+)
+
+func (pc *PrometheusController) isThanosEnabled() bool {
+	return pc.clusterLoaderConfig.PrometheusConfig.ThanosObjstoreConfig != ""
+}
+
+// createThanosObjstoreSecret stores Thanos object storage config in the secret mounted by Thanos sidecar.
+func (pc *PrometheusController) createThanosObjstoreSecret(k8sClient kubernetes.Interface) error {
+	path := pc.clusterLoaderConfig.PrometheusConfig.ThanosObjstoreConfig
+	objstoreConfig, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading thanos object storage config error: %v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: thanosObjstoreSecret, Namespace: namespace},
+		Data:       map[string][]byte{thanosObjstoreSecretKey: objstoreConfig},
+	}
+	logrus.Infof("Creating %s secret from %s", thanosObjstoreSecret, path)
+	return client.RetryWithExponentialBackOff(client.RetryFunction(func() error {
+		_, err := k8sClient.CoreV1().Secrets(namespace).Create(secret)
+		if apierrs.IsAlreadyExists(err) {
+			_, err = k8sClient.CoreV1().Secrets(namespace).Update(secret)
+		}
+		return err
+	}))
+}
+
+// uploadThanosSnapshot snapshots prometheus TSDB, including the head block, with the admin API and uploads
+// the snapshot to the bucket with thanos in the sidecar container. Thanos sidecar uploads only blocks cut
+// every 2h, so without the snapshot nothing would be archived from runs shorter than that.
+func (pc *PrometheusController) uploadThanosSnapshot() error {
+	k8sClient := pc.framework.GetClientSets().GetClient()
+	body, err := k8sClient.CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("services").
+		Name(prometheusService).
+		SubResource("proxy").
+		Suffix("api/v1/admin/tsdb/snapshot").
+		DoRaw()
+	if err != nil {
+		return fmt.Errorf("creating snapshot error: %v", err)
+	}
+	snapshot, err := parseSnapshotResponse(body)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Uploading prometheus snapshot %s with thanos", snapshot)
+	// OBJSTORE_CONFIG is set by prometheus operator in the sidecar container from thanos-objstore-config secret.
+	command := fmt.Sprintf(`thanos tools bucket upload-blocks --objstore.config="$OBJSTORE_CONFIG" --path=%s/%s`, prometheusSnapshotsDir, snapshot)
+	if _, stderr, err := pc.framework.ExecInPod(namespace, prometheusPod, thanosSidecarContainer, []string{"/bin/sh", "-c", command}); err != nil {
+		return fmt.Errorf("uploading snapshot error: %v, stderr: %s", err, stderr)
+	}
+	return nil
+}
+
+// parseSnapshotResponse returns name of the snapshot created by prometheus admin API.
+func parseSnapshotResponse(body []byte) (string, error) {
+	var response struct {
+		Status string `json:"status"`
+		Data   struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("parsing snapshot response error: %v", err)
+	}
+	if response.Status != "success" || response.Data.Name == "" {
+		return "", fmt.Errorf("unexpected snapshot response: %s", string(body))
+	}
+	return response.Data.Name, nil
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshotResponse(t *testing.T) {
+	name, err := parseSnapshotResponse([]byte(`{"status":"success","data":{"name":"20171210T211224Z-2be650b6d019eb54"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "20171210T211224Z-2be650b6d019eb54", name)
+
+	_, err = parseSnapshotResponse([]byte(`{"status":"error","error":"admin APIs disabled"}`))
+	assert.Error(t, err)
+	_, err = parseSnapshotResponse([]byte(`404 page not found`))
+	assert.Error(t, err)
+}
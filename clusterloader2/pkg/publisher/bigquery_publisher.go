@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	bigqueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	bigqueryScope    = "https://www.googleapis.com/auth/bigquery"
+	// maxBigqueryRowsPerRequest limits the number of rows inserted by a single streaming insert request.
+	maxBigqueryRowsPerRequest = 500
+)
+
+type bigqueryPublisher struct {
+	endpoint string
+	project  string
+	dataset  string
+	table    string
+	// client is created on the first publish, so that missing credentials don't prevent the test from running.
+	client *http.Client
+}
+
+// NewBigqueryPublisher creates publisher inserting flattened test results into BigQuery table,
+// given as project.dataset.table. The table is created if it doesn't exist.
+// Application default credentials are used.
+func NewBigqueryPublisher(table string) (Publisher, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("incorrect BigQuery table %q, expected project.dataset.table", table)
+	}
+	return &bigqueryPublisher{endpoint: bigqueryEndpoint, project: parts[0], dataset: parts[1], table: parts[2]}, nil
+}
+
+// Publish inserts a row for every data bucket of every data item of the test result.
+func (b *bigqueryPublisher) Publish(result *TestResult) error {
+	rows, err := flattenResult(result)
+	if err != nil {
+		return err
+	}
+	if b.client == nil {
+		if b.client, err = credentials.GoogleClient(context.Background(), bigqueryScope); err != nil {
+			return fmt.Errorf("creating BigQuery client error: %v", err)
+		}
+		b.client.Timeout = httpPublisherTimeout
+	}
+	if err := b.ensureTable(); err != nil {
+		return err
+	}
+	for start := 0; start < len(rows); start += maxBigqueryRowsPerRequest {
+		end := start + maxBigqueryRowsPerRequest
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := b.insertRows(rows[start:end]); err != nil {
+			return err
+		}
+	}
+	logrus.Infof("Inserted %d rows of %s into BigQuery table %s.%s.%s", len(rows), result.Test, b.project, b.dataset, b.table)
+	return nil
+}
+
+func (b *bigqueryPublisher) tablesURL() string {
+	return fmt.Sprintf("%s/projects/%s/datasets/%s/tables", b.endpoint, b.project, b.dataset)
+}
+
+// bigqueryField is a field of BigQuery table schema.
+type bigqueryField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+type bigquerySchema struct {
+	Fields []bigqueryField `json:"fields"`
+}
+
+// ensureTable creates the table with the current schema, unless it already exists.
+// Columns missing in the existing table are added to it.
+func (b *bigqueryPublisher) ensureTable() error {
+	response, err := b.do(http.MethodGet, b.tablesURL()+"/"+b.table, nil)
+	if err != nil {
+		return fmt.Errorf("getting BigQuery table error: %v", err)
+	}
+	if response.StatusCode != http.StatusNotFound {
+		defer response.Body.Close()
+		if err := checkStatus(response, "getting BigQuery table error"); err != nil {
+			return err
+		}
+		var table struct {
+			Schema bigquerySchema `json:"schema"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&table); err != nil {
+			return fmt.Errorf("decoding BigQuery table error: %v", err)
+		}
+		return b.updateSchema(table.Schema)
+	}
+	response.Body.Close()
+	var fields []bigqueryField
+	for _, column := range resultColumns {
+		fields = append(fields, bigqueryField{Name: column.name, Type: column.bigqueryType})
+	}
+	table := map[string]interface{}{
+		"tableReference": map[string]string{"projectId": b.project, "datasetId": b.dataset, "tableId": b.table},
+		"schema":         bigquerySchema{Fields: fields},
+		"description":    fmt.Sprintf("Results of clusterloader2 tests, schema version %d.", resultsSchemaVersion),
+	}
+	response, err = b.do(http.MethodPost, b.tablesURL(), table)
+	if err != nil {
+		return fmt.Errorf("creating BigQuery table error: %v", err)
+	}
+	return checkResponse(response, "creating BigQuery table error")
+}
+
+// updateSchema adds columns missing in the schema of existing table. BigQuery allows only adding
+// nullable columns, so columns of different types have to be migrated manually.
+func (b *bigqueryPublisher) updateSchema(schema bigquerySchema) error {
+	existing := make(map[string]bigqueryField)
+	for _, field := range schema.Fields {
+		existing[strings.ToLower(field.Name)] = field
+	}
+	fields := schema.Fields
+	for _, column := range resultColumns {
+		field, ok := existing[column.name]
+		if !ok {
+			fields = append(fields, bigqueryField{Name: column.name, Type: column.bigqueryType, Mode: "NULLABLE"})
+			continue
+		}
+		if !strings.EqualFold(field.Type, column.bigqueryType) {
+			return fmt.Errorf("BigQuery table %s.%s.%s column %s has type %s, expected %s", b.project, b.dataset, b.table, column.name, field.Type, column.bigqueryType)
+		}
+	}
+	if len(fields) == len(schema.Fields) {
+		return nil
+	}
+	logrus.Infof("Adding %d columns to BigQuery table %s.%s.%s", len(fields)-len(schema.Fields), b.project, b.dataset, b.table)
+	response, err := b.do(http.MethodPatch, b.tablesURL()+"/"+b.table, map[string]interface{}{"schema": bigquerySchema{Fields: fields}})
+	if err != nil {
+		return fmt.Errorf("updating BigQuery table schema error: %v", err)
+	}
+	return checkResponse(response, "updating BigQuery table schema error")
+}
+
+func (b *bigqueryPublisher) insertRows(rows []*resultRow) error {
+	type insertRow struct {
+		JSON *resultRow `json:"json"`
+	}
+	insertRows := make([]insertRow, 0, len(rows))
+	for _, row := range rows {
+		insertRows = append(insertRows, insertRow{JSON: row})
+	}
+	response, err := b.do(http.MethodPost, b.tablesURL()+"/"+b.table+"/insertAll", map[string]interface{}{"rows": insertRows})
+	if err != nil {
+		return fmt.Errorf("inserting rows into BigQuery error: %v", err)
+	}
+	defer response.Body.Close()
+	if err := checkStatus(response, "inserting rows into BigQuery error"); err != nil {
+		return err
+	}
+	var insertResponse struct {
+		InsertErrors []json.RawMessage `json:"insertErrors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&insertResponse); err != nil && err != io.EOF {
+		return fmt.Errorf("decoding BigQuery response error: %v", err)
+	}
+	if len(insertResponse.InsertErrors) > 0 {
+		return fmt.Errorf("inserting rows into BigQuery error: %d rows rejected, first error: %s", len(insertResponse.InsertErrors), string(insertResponse.InsertErrors[0]))
+	}
+	return nil
+}
+
+func (b *bigqueryPublisher) do(method, url string, body interface{}) (*http.Response, error) {
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("marshalling request error: %v", err)
+		}
+	}
+	request, err := http.NewRequest(method, url, bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("request creation error: %v", err)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return b.client.Do(request)
+}
+
+// checkResponse closes the response and returns error if its status isn't successful.
+func checkResponse(response *http.Response, message string) error {
+	defer response.Body.Close()
+	return checkStatus(response, message)
+}
+
+func checkStatus(response *http.Response, message string) error {
+	if err := util.CheckHTTPResponse(response); err != nil {
+		return fmt.Errorf("%s: %v", message, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	httpPublisherTimeout = time.Minute
+)
+
+type httpPublisher struct {
+	endpoint   string
+	authHeader string
+	client     *http.Client
+}
+
+// NewHTTPPublisher creates publisher posting test results as JSON to the given endpoint.
+// If authHeader is not empty, it is sent as the Authorization header.
+func NewHTTPPublisher(endpoint, authHeader string) Publisher {
+	return &httpPublisher{
+		endpoint:   endpoint,
+		authHeader: authHeader,
+		client:     &http.Client{Timeout: httpPublisherTimeout},
+	}
+}
+
+// Publish posts test result to the endpoint.
+func (h *httpPublisher) Publish(result *TestResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshalling test result error: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if h.authHeader != "" {
+		request.Header.Set("Authorization", h.authHeader)
+	}
+	if err := util.SendHTTPRequest(h.client, request); err != nil {
+		return fmt.Errorf("posting test result error: %v", err)
+	}
+	logrus.Infof("Published %d results of %s to %s", len(result.Results), result.Test, h.endpoint)
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+// Publisher uploads results of a test to an external storage.
+type Publisher interface {
+	Publish(result *TestResult) error
+}
+
+// TestResult contains performance data gathered during a single test.
+type TestResult struct {
+	Test       string            `json:"test"`
+	Identifier string            `json:"identifier,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Results    []*Result         `json:"results"`
+	Violations []*Violation      `json:"violations,omitempty"`
+}
+
+// Result contains performance data of a single summary.
+type Result struct {
+	Name string `json:"name"`
+	measurementutil.PerfData
+}
+
+// Violation describes SLO violation detected by a measurement.
+type Violation struct {
+	Measurement string `json:"measurement"`
+	Identifier  string `json:"identifier,omitempty"`
+	Metric      string `json:"metric"`
+	Message     string `json:"message"`
+}
+
+// InitFlags initializes results publisher flags.
+func InitFlags(p *config.PublisherConfig) {
+	flags.StringEnvVar(&p.Endpoint, "results-publisher-endpoint", "RESULTS_PUBLISHER_ENDPOINT", "", "URL of the benchmark service that test results (PerfData summaries) should be posted to. If empty, results are not published.")
+	flags.StringEnvVar(&p.AuthHeader, "results-publisher-auth-header", "RESULTS_PUBLISHER_AUTH_HEADER", "", "Value of the Authorization header sent to the benchmark service, e.g. 'Bearer <token>'.")
+	flags.StringSliceEnvVar(&p.Labels, "results-publisher-labels", "RESULTS_PUBLISHER_LABELS", nil /*defaultValue*/, "Labels attached to published results, in key=value format, supports multiple values when separated by commas")
+	flags.StringEnvVar(&p.PushgatewayURL, "results-pushgateway-url", "RESULTS_PUSHGATEWAY_URL", "", "URL of Prometheus Pushgateway that test results should be pushed to as metrics. If empty, results are not pushed.")
+	flags.StringEnvVar(&p.BigqueryTable, "results-bigquery-table", "RESULTS_BIGQUERY_TABLE", "", "BigQuery table (project.dataset.table) that flattened test results should be inserted into. If empty, results are not inserted.")
+	flags.StringEnvVar(&p.SQLDriver, "results-sql-driver", "RESULTS_SQL_DRIVER", "", "Name of the SQL driver (postgres or mysql) used to insert flattened test results into a database. If empty, results are not inserted.")
+	flags.StringEnvVar(&p.SQLDataSource, "results-sql-dsn", "RESULTS_SQL_DSN", "", "Data source name of the SQL database that flattened test results should be inserted into.")
+	flags.StringEnvVar(&p.SQLTable, "results-sql-table", "RESULTS_SQL_TABLE", defaultSQLTable, "Name of the SQL table that flattened test results should be inserted into.")
+}
+
+// NewPublisher creates publisher based on the provided config.
+// Nil is returned if publishing is disabled.
+func NewPublisher(p *config.PublisherConfig) (Publisher, error) {
+	var publishers multiPublisher
+	if p.Endpoint != "" {
+		publishers = append(publishers, NewHTTPPublisher(p.Endpoint, p.AuthHeader))
+	}
+	if p.PushgatewayURL != "" {
+		publishers = append(publishers, NewPushgatewayPublisher(p.PushgatewayURL))
+	}
+	if p.BigqueryTable != "" {
+		bigqueryPublisher, err := NewBigqueryPublisher(p.BigqueryTable)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, bigqueryPublisher)
+	}
+	if p.SQLDriver != "" {
+		sqlPublisher, err := NewSQLPublisher(p.SQLDriver, p.SQLDataSource, p.SQLTable)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, sqlPublisher)
+	}
+	switch len(publishers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return publishers[0], nil
+	default:
+		return publishers, nil
+	}
+}
+
+// multiPublisher publishes results with all publishers.
+type multiPublisher []Publisher
+
+func (m multiPublisher) Publish(result *TestResult) error {
+	var errs []string
+	for _, p := range m {
+		if err := p.Publish(result); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// NewTestResult creates test result from summaries containing PerfData or flat maps
+// of numbers (e.g. throughput), which are converted to PerfData with a single data item.
+// Other summaries are skipped.
+func NewTestResult(test, identifier string, labels []string, summaries []measurement.Summary) (*TestResult, error) {
+	result := &TestResult{
+		Test:       test,
+		Identifier: identifier,
+		Timestamp:  time.Now(),
+		Results:    []*Result{},
+	}
+	for _, label := range labels {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("incorrect label %q, expected key=value", label)
+		}
+		if result.Labels == nil {
+			result.Labels = make(map[string]string)
+		}
+		result.Labels[kv[0]] = kv[1]
+	}
+	for _, summary := range summaries {
+		if summary.SummaryExt() != "json" {
+			continue
+		}
+		var perfData measurementutil.PerfData
+		if err := json.Unmarshal([]byte(summary.SummaryContent()), &perfData); err != nil || len(perfData.DataItems) == 0 {
+			var values map[string]float64
+			if err := json.Unmarshal([]byte(summary.SummaryContent()), &values); err != nil || len(values) == 0 {
+				continue
+			}
+			perfData = measurementutil.PerfData{Version: "v1", DataItems: []measurementutil.DataItem{{Data: values}}}
+		}
+		result.Results = append(result.Results, &Result{Name: summary.SummaryName(), PerfData: perfData})
+	}
+	return result, nil
+}
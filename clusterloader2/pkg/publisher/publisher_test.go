@@ -0,0 +1,257 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+func TestNewTestResult(t *testing.T) {
+	summaries := []measurement.Summary{
+		measurement.CreateSummary("PodStartupLatency", "json", `{"version": "v1", "dataItems": [{"data": {"Perc99": 1.5}, "unit": "s"}]}`),
+		measurement.CreateSummary("ResourceUsageSummary", "json", `{"99": []}`),
+		measurement.CreateSummary("Profile", "txt", "not a json"),
+		measurement.CreateSummary("SchedulingThroughput", "json", `{"average": 90, "perc50": 100}`),
+	}
+	result, err := NewTestResult("density", "id", []string{"version=1.15"}, summaries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, map[string]string{"version": "1.15"}, result.Labels)
+	assert.Len(t, result.Results, 2)
+	assert.Equal(t, "PodStartupLatency", result.Results[0].Name)
+	assert.Equal(t, 1.5, result.Results[0].DataItems[0].Data["Perc99"])
+	assert.Equal(t, "SchedulingThroughput", result.Results[1].Name)
+	assert.Equal(t, map[string]float64{"average": 90, "perc50": 100}, result.Results[1].DataItems[0].Data)
+
+	_, err = NewTestResult("density", "", []string{"version"}, summaries)
+	assert.Error(t, err)
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	var received TestResult
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if received.Test == "rejected" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	publisher := NewHTTPPublisher(server.URL, "Bearer token")
+	result := &TestResult{Test: "density", Results: []*Result{{Name: "PodStartupLatency"}}}
+	if err := publisher.Publish(result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "Bearer token", authHeader)
+	assert.Equal(t, "density", received.Test)
+	assert.Equal(t, "PodStartupLatency", received.Results[0].Name)
+
+	assert.Error(t, publisher.Publish(&TestResult{Test: "rejected"}))
+}
+
+func TestPushgatewayPublisher(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		path, body = r.URL.EscapedPath(), string(content)
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	result := &TestResult{
+		Test:      "load",
+		Timestamp: time.Unix(1556704800, 0),
+		Labels:    map[string]string{"build": "1234", "branch": "release/1.15"},
+		Results: []*Result{{
+			Name: "PodStartupLatency",
+			PerfData: measurementutil.PerfData{DataItems: []measurementutil.DataItem{
+				{Data: map[string]float64{"Perc50": 1.5, "Perc99": 3}, Unit: "s", Labels: map[string]string{"Metric": "pod_startup", "unit": "x"}},
+			}},
+		}},
+		Violations: []*Violation{{Measurement: "APIResponsivenessPrometheus", Metric: "top latency metric", Message: "too high"}},
+	}
+	if err := NewPushgatewayPublisher(server.URL + "/").Publish(result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "/metrics/job/clusterloader2/test/load/branch@base64/cmVsZWFzZS8xLjE1/build/1234", path)
+	for _, expected := range []string{
+		`clusterloader2_result{summary="PodStartupLatency",bucket="Perc50",unit="s",Metric="pod_startup",exported_unit="x"} 1.5`,
+		`clusterloader2_result{summary="PodStartupLatency",bucket="Perc99",unit="s",Metric="pod_startup",exported_unit="x"} 3`,
+		`clusterloader2_slo_violation{measurement="APIResponsivenessPrometheus",measurement_identifier="",metric="top latency metric"} 1`,
+		`clusterloader2_result_timestamp_seconds 1.5567048e+09`,
+	} {
+		assert.Contains(t, body, expected)
+	}
+}
+
+func TestBigqueryPublisher(t *testing.T) {
+	var requests []string
+	var inserted struct {
+		Rows []struct {
+			JSON resultRow `json:"json"`
+		} `json:"rows"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasSuffix(r.URL.Path, "/insertAll"):
+			json.NewDecoder(r.Body).Decode(&inserted)
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewBigqueryPublisher("project.dataset.results")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.(*bigqueryPublisher).endpoint = server.URL
+	p.(*bigqueryPublisher).client = server.Client()
+	result := &TestResult{
+		Test:      "load",
+		Timestamp: time.Unix(1556704800, 0),
+		Labels:    map[string]string{"build": "1234"},
+		Results: []*Result{{
+			Name: "PodStartupLatency",
+			PerfData: measurementutil.PerfData{DataItems: []measurementutil.DataItem{
+				{Data: map[string]float64{"Perc50": 1.5, "Perc99": 3}, Unit: "s", Labels: map[string]string{"Metric": "pod_startup"}},
+			}},
+		}},
+	}
+	if err := p.Publish(result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, []string{
+		"GET /projects/project/datasets/dataset/tables/results",
+		"POST /projects/project/datasets/dataset/tables",
+		"POST /projects/project/datasets/dataset/tables/results/insertAll",
+	}, requests)
+	assert.Len(t, inserted.Rows, 2)
+	assert.Equal(t, resultRow{
+		SchemaVersion: resultsSchemaVersion,
+		Test:          "load",
+		Timestamp:     time.Unix(1556704800, 0).UTC(),
+		Labels:        `{"build":"1234"}`,
+		Summary:       "PodStartupLatency",
+		Bucket:        "Perc99",
+		Unit:          "s",
+		ItemLabels:    `{"Metric":"pod_startup"}`,
+		Value:         3,
+	}, inserted.Rows[1].JSON)
+
+	_, err = NewBigqueryPublisher("dataset.results")
+	assert.Error(t, err)
+}
+
+func TestBigqueryPublisherUpdatesSchema(t *testing.T) {
+	var requests []string
+	var patched struct {
+		Schema bigquerySchema `json:"schema"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"schema": {"fields": [{"name": "schema_version", "type": "INTEGER"}, {"name": "test", "type": "STRING"}]}}`))
+		case http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&patched)
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewBigqueryPublisher("project.dataset.results")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := p.(*bigqueryPublisher)
+	b.endpoint = server.URL
+	b.client = server.Client()
+	if err := b.ensureTable(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, []string{
+		"GET /projects/project/datasets/dataset/tables/results",
+		"PATCH /projects/project/datasets/dataset/tables/results",
+	}, requests)
+	if assert.Len(t, patched.Schema.Fields, len(resultColumns)) {
+		assert.Equal(t, bigqueryField{Name: "schema_version", Type: "INTEGER"}, patched.Schema.Fields[0])
+		assert.Equal(t, bigqueryField{Name: "value", Type: "FLOAT", Mode: "NULLABLE"}, patched.Schema.Fields[len(resultColumns)-1])
+	}
+
+	requests = nil
+	err = b.updateSchema(bigquerySchema{Fields: []bigqueryField{{Name: "schema_version", Type: "STRING"}}})
+	assert.Error(t, err)
+	assert.Empty(t, requests)
+}
+
+func TestSQLStatements(t *testing.T) {
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS results (schema_version INTEGER, test VARCHAR(255), identifier VARCHAR(255), "+
+		"timestamp TIMESTAMP, labels TEXT, summary VARCHAR(255), bucket VARCHAR(255), unit VARCHAR(255), item_labels TEXT, value DOUBLE PRECISION)",
+		createTableStatement("results"))
+	assert.Equal(t, "INSERT INTO results (schema_version, test, identifier, timestamp, labels, summary, bucket, unit, item_labels, value) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", insertStatement("mysql", "results"))
+	assert.Contains(t, insertStatement("postgres", "results"), "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)")
+
+	_, err := NewSQLPublisher("mysql", "", "results; DROP TABLE results")
+	assert.Error(t, err)
+	_, err = NewSQLPublisher("sqlite3", "", "results")
+	assert.Error(t, err)
+}
+
+func TestNewPublisher(t *testing.T) {
+	for _, tc := range []struct {
+		config  config.PublisherConfig
+		want    Publisher
+		wantErr bool
+	}{
+		{config: config.PublisherConfig{}, want: nil},
+		{config: config.PublisherConfig{PushgatewayURL: "http://pushgateway:9091"}, want: &pushgatewayPublisher{}},
+		{config: config.PublisherConfig{SQLDriver: "postgres"}, want: &sqlPublisher{}},
+		{config: config.PublisherConfig{Endpoint: "http://benchmarks", PushgatewayURL: "http://pushgateway:9091"}, want: multiPublisher{}},
+		{config: config.PublisherConfig{BigqueryTable: "results"}, wantErr: true},
+	} {
+		p, err := NewPublisher(&tc.config)
+		if tc.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.IsType(t, tc.want, p)
+	}
+}
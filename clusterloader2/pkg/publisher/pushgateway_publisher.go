@@ -0,0 +1,187 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	pushgatewayJob = "clusterloader2"
+
+	resultMetricName    = "clusterloader2_result"
+	violationMetricName = "clusterloader2_slo_violation"
+	timestampMetricName = "clusterloader2_result_timestamp_seconds"
+)
+
+var invalidLabelCharacters = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+type pushgatewayPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewPushgatewayPublisher creates publisher pushing test results as metrics to Prometheus Pushgateway.
+// Results are grouped by test, identifier and labels of the run, so that results of different runs
+// don't overwrite each other.
+func NewPushgatewayPublisher(endpoint string) Publisher {
+	return &pushgatewayPublisher{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: httpPublisherTimeout},
+	}
+}
+
+// Publish replaces metrics of the run in the Pushgateway with metrics created from the test result.
+func (p *pushgatewayPublisher) Publish(result *TestResult) error {
+	var body bytes.Buffer
+	for _, family := range newMetricFamilies(result) {
+		if _, err := expfmt.MetricFamilyToText(&body, family); err != nil {
+			return fmt.Errorf("encoding metrics error: %v", err)
+		}
+	}
+	request, err := http.NewRequest(http.MethodPut, p.endpoint+groupingKeyPath(result), &body)
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", string(expfmt.FmtText))
+	if err := util.SendHTTPRequest(p.client, request); err != nil {
+		return fmt.Errorf("pushing test result error: %v", err)
+	}
+	logrus.Infof("Pushed %d results of %s to %s", len(result.Results), result.Test, p.endpoint)
+	return nil
+}
+
+// groupingKeyPath returns path identifying the run in the Pushgateway.
+func groupingKeyPath(result *TestResult) string {
+	path := "/metrics/job/" + pushgatewayJob + groupingKeyElement("test", result.Test)
+	if result.Identifier != "" {
+		path += groupingKeyElement("identifier", result.Identifier)
+	}
+	for _, name := range sortedKeys(result.Labels) {
+		path += groupingKeyElement(labelName(name), result.Labels[name])
+	}
+	return path
+}
+
+// groupingKeyElement encodes label of the grouping key. Values that can't be
+// a part of the path are base64-encoded, as supported by the Pushgateway.
+func groupingKeyElement(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+// newMetricFamilies converts the test result into metrics. Every data bucket of every data item
+// is a sample of clusterloader2_result metric, labeled with the summary name, the bucket, the unit
+// and labels of the data item. Every violation is a sample of clusterloader2_slo_violation metric.
+func newMetricFamilies(result *TestResult) []*dto.MetricFamily {
+	resultFamily := newGaugeFamily(resultMetricName, "Results of measurements of clusterloader2 tests.")
+	for _, r := range result.Results {
+		for _, item := range r.DataItems {
+			for _, bucket := range sortedFloatKeys(item.Data) {
+				labels := []*dto.LabelPair{labelPair("summary", r.Name), labelPair("bucket", bucket), labelPair("unit", item.Unit)}
+				for _, name := range sortedKeys(item.Labels) {
+					labels = append(labels, labelPair(itemLabelName(name), item.Labels[name]))
+				}
+				resultFamily.Metric = append(resultFamily.Metric, newGauge(labels, item.Data[bucket]))
+			}
+		}
+	}
+	violationFamily := newGaugeFamily(violationMetricName, "SLO violations detected by measurements of clusterloader2 tests.")
+	for _, v := range result.Violations {
+		labels := []*dto.LabelPair{labelPair("measurement", v.Measurement), labelPair("measurement_identifier", v.Identifier), labelPair("metric", v.Metric)}
+		violationFamily.Metric = append(violationFamily.Metric, newGauge(labels, 1))
+	}
+	timestampFamily := newGaugeFamily(timestampMetricName, "Time when results of clusterloader2 test were published.")
+	timestampFamily.Metric = append(timestampFamily.Metric, newGauge(nil, float64(result.Timestamp.Unix())))
+
+	var families []*dto.MetricFamily
+	for _, family := range []*dto.MetricFamily{resultFamily, violationFamily, timestampFamily} {
+		if len(family.Metric) > 0 {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+func newGaugeFamily(name, help string) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name: proto.String(name),
+		Help: proto.String(help),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+}
+
+func newGauge(labels []*dto.LabelPair, value float64) *dto.Metric {
+	return &dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: proto.Float64(value)}}
+}
+
+func labelPair(name, value string) *dto.LabelPair {
+	return &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)}
+}
+
+// labelName converts arbitrary string into a valid label name.
+func labelName(name string) string {
+	name = invalidLabelCharacters.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// itemLabelName converts name of data item label into a label name that doesn't clash
+// with labels set by the publisher and labels of the grouping key.
+func itemLabelName(name string) string {
+	name = labelName(name)
+	switch name {
+	case "summary", "bucket", "unit", "job", "test", "identifier", "instance":
+		return "exported_" + name
+	}
+	return name
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFloatKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
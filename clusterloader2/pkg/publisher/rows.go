@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// resultsSchemaVersion is the version of the schema of flattened results. It is stored in every row,
+// so that rows written by older versions of clusterloader can be told apart once the schema changes.
+// It should be bumped whenever meaning of any column changes.
+const resultsSchemaVersion = 1
+
+// resultRow is a single data bucket of a single data item of a summary, i.e. a single number.
+type resultRow struct {
+	SchemaVersion int       `json:"schema_version"`
+	Test          string    `json:"test"`
+	Identifier    string    `json:"identifier"`
+	Timestamp     time.Time `json:"timestamp"`
+	// Labels are labels of the test run, encoded as JSON object.
+	Labels  string `json:"labels"`
+	Summary string `json:"summary"`
+	Bucket  string `json:"bucket"`
+	Unit    string `json:"unit"`
+	// ItemLabels are labels of the data item, encoded as JSON object.
+	ItemLabels string  `json:"item_labels"`
+	Value      float64 `json:"value"`
+}
+
+// resultColumn describes a column of the flattened results table.
+type resultColumn struct {
+	name string
+	// bigqueryType is the type of the column in BigQuery.
+	bigqueryType string
+	// sqlType is the type of the column in generic SQL database.
+	sqlType string
+}
+
+// resultColumns are columns of the flattened results table, in the order of resultRow.values.
+var resultColumns = []resultColumn{
+	{name: "schema_version", bigqueryType: "INTEGER", sqlType: "INTEGER"},
+	{name: "test", bigqueryType: "STRING", sqlType: "VARCHAR(255)"},
+	{name: "identifier", bigqueryType: "STRING", sqlType: "VARCHAR(255)"},
+	{name: "timestamp", bigqueryType: "TIMESTAMP", sqlType: "TIMESTAMP"},
+	{name: "labels", bigqueryType: "STRING", sqlType: "TEXT"},
+	{name: "summary", bigqueryType: "STRING", sqlType: "VARCHAR(255)"},
+	{name: "bucket", bigqueryType: "STRING", sqlType: "VARCHAR(255)"},
+	{name: "unit", bigqueryType: "STRING", sqlType: "VARCHAR(255)"},
+	{name: "item_labels", bigqueryType: "STRING", sqlType: "TEXT"},
+	{name: "value", bigqueryType: "FLOAT", sqlType: "DOUBLE PRECISION"},
+}
+
+func (r *resultRow) values() []interface{} {
+	return []interface{}{r.SchemaVersion, r.Test, r.Identifier, r.Timestamp, r.Labels, r.Summary, r.Bucket, r.Unit, r.ItemLabels, r.Value}
+}
+
+// flattenResult converts the test result into rows, one for every data bucket of every data item.
+func flattenResult(result *TestResult) ([]*resultRow, error) {
+	labels, err := encodeLabels(result.Labels)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*resultRow
+	for _, r := range result.Results {
+		for _, item := range r.DataItems {
+			itemLabels, err := encodeLabels(item.Labels)
+			if err != nil {
+				return nil, err
+			}
+			for _, bucket := range sortedFloatKeys(item.Data) {
+				rows = append(rows, &resultRow{
+					SchemaVersion: resultsSchemaVersion,
+					Test:          result.Test,
+					Identifier:    result.Identifier,
+					Timestamp:     result.Timestamp.UTC(),
+					Labels:        labels,
+					Summary:       r.Name,
+					Bucket:        bucket,
+					Unit:          item.Unit,
+					ItemLabels:    itemLabels,
+					Value:         item.Data[bucket],
+				})
+			}
+		}
+	}
+	return rows, nil
+}
+
+// encodeLabels encodes labels as JSON object. Keys are sorted, so equal labels are always encoded the same way.
+func encodeLabels(labels map[string]string) (string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	encoded, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("encoding labels error: %v", err)
+	}
+	return string(encoded), nil
+}
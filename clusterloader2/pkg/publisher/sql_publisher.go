@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	// Drivers of the supported databases.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const defaultSQLTable = "clusterloader2_results"
+
+var sqlTableName = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_.]*$")
+
+type sqlPublisher struct {
+	driver string
+	dsn    string
+	table  string
+}
+
+// NewSQLPublisher creates publisher inserting flattened test results into a table of SQL database.
+// The table is created if it doesn't exist. Supported drivers are postgres and mysql.
+func NewSQLPublisher(driver, dsn, table string) (Publisher, error) {
+	if !isSQLDriverRegistered(driver) {
+		return nil, fmt.Errorf("unsupported SQL driver %q, supported drivers are %v", driver, sql.Drivers())
+	}
+	if table == "" {
+		table = defaultSQLTable
+	}
+	if !sqlTableName.MatchString(table) {
+		return nil, fmt.Errorf("incorrect SQL table name %q", table)
+	}
+	return &sqlPublisher{driver: driver, dsn: dsn, table: table}, nil
+}
+
+// Publish inserts a row for every data bucket of every data item of the test result in a single transaction.
+func (s *sqlPublisher) Publish(result *TestResult) error {
+	rows, err := flattenResult(result)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open(s.driver, s.dsn)
+	if err != nil {
+		return fmt.Errorf("opening %s database error: %v", s.driver, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(createTableStatement(s.table)); err != nil {
+		return fmt.Errorf("creating table %s error: %v", s.table, err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction error: %v", err)
+	}
+	statement, err := tx.Prepare(insertStatement(s.driver, s.table))
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("preparing insert statement error: %v", err)
+	}
+	for _, row := range rows {
+		if _, err := statement.Exec(row.values()...); err != nil {
+			statement.Close()
+			tx.Rollback()
+			return fmt.Errorf("inserting row into %s error: %v", s.table, err)
+		}
+	}
+	statement.Close()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction error: %v", err)
+	}
+	logrus.Infof("Inserted %d rows of %s into %s table %s", len(rows), result.Test, s.driver, s.table)
+	return nil
+}
+
+func isSQLDriverRegistered(driver string) bool {
+	for _, registered := range sql.Drivers() {
+		if registered == driver {
+			return true
+		}
+	}
+	return false
+}
+
+func createTableStatement(table string) string {
+	columns := make([]string, 0, len(resultColumns))
+	for _, column := range resultColumns {
+		columns = append(columns, column.name+" "+column.sqlType)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(columns, ", "))
+}
+
+// insertStatement returns insert statement with placeholders in the style used by the driver.
+func insertStatement(driver, table string) string {
+	columns := make([]string, 0, len(resultColumns))
+	placeholders := make([]string, 0, len(resultColumns))
+	for i, column := range resultColumns {
+		columns = append(columns, column.name)
+		switch driver {
+		case "postgres", "pgx", "cloudsqlpostgres":
+			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		default:
+			placeholders = append(placeholders, "?")
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+const (
+	summaryName = "report"
+	// maxBars is the maximum number of bars in a single chart, the highest values are shown.
+	maxBars = 20
+	// maxRawLength limits the size of summaries included in the report as plain text.
+	maxRawLength = 1 << 20
+
+	statusPassed  = "Passed"
+	statusFailed  = "Failed"
+	statusSkipped = "Skipped"
+)
+
+type page struct {
+	TestName     string
+	Time         string
+	Measurements []measurementRow
+	Sections     []section
+}
+
+type measurementRow struct {
+	Name     string
+	Status   string
+	Duration string
+	Messages []string
+}
+
+// section presents a single summary.
+type section struct {
+	Name   string
+	Tables []table
+	Charts []chart
+	// Raw contains content of summaries that can't be presented as tables.
+	Raw string
+}
+
+type table struct {
+	Header []string
+	Rows   [][]string
+}
+
+type chart struct {
+	Title string
+	Bars  []bar
+}
+
+type bar struct {
+	Label string
+	Value string
+	// Width is the width of the bar in percents of the widest one.
+	Width float64
+}
+
+// CreateSummary renders summaries and outcomes of measurements of the test into
+// a single self-contained HTML report. Known summary formats (PerfData, resource usage
+// and flat maps of numbers) are presented as tables and charts, other ones as plain text.
+func CreateSummary(testName string, summaries []measurement.Summary, results []measurement.MeasurementResult) (measurement.Summary, error) {
+	p := page{
+		TestName: testName,
+		Time:     time.Now().Format(time.RFC3339),
+	}
+	for _, result := range results {
+		p.Measurements = append(p.Measurements, newMeasurementRow(result))
+	}
+	for _, summary := range summaries {
+		if s, ok := newSection(summary); ok {
+			p.Sections = append(p.Sections, s)
+		}
+	}
+	var b bytes.Buffer
+	if err := pageTemplate.Execute(&b, p); err != nil {
+		return nil, fmt.Errorf("report rendering error: %v", err)
+	}
+	return measurement.CreateSummary(summaryName, "html", b.String()), nil
+}
+
+func newMeasurementRow(result measurement.MeasurementResult) measurementRow {
+	row := measurementRow{
+		Name:     result.Method,
+		Status:   statusPassed,
+		Duration: result.Duration.Round(time.Millisecond).String(),
+	}
+	if result.Identifier != "" {
+		row.Name = fmt.Sprintf("%s (%s)", result.Method, result.Identifier)
+	}
+	switch {
+	case result.SkipReason != "":
+		row.Status = statusSkipped
+		row.Messages = []string{result.SkipReason}
+	case len(result.Errors) > 0 || len(result.Violations) > 0:
+		row.Status = statusFailed
+	}
+	for _, err := range result.Violations {
+		row.Messages = append(row.Messages, err.Error())
+	}
+	for _, err := range result.Errors {
+		row.Messages = append(row.Messages, err.Error())
+	}
+	return row
+}
+
+func newSection(summary measurement.Summary) (section, bool) {
+	s := section{Name: summary.SummaryName()}
+	content := summary.SummaryContent()
+	switch summary.SummaryExt() {
+	case "html", "xml":
+		// Reports and JUnit results are already presented in the measurements table.
+		return s, false
+	case "pprof", "tar.gz":
+		// Binary profiles can't be presented as text.
+		return s, false
+	case "json":
+		if perfData, ok := parsePerfData(content); ok {
+			s.Tables, s.Charts = perfDataTables(perfData), perfDataCharts(perfData)
+			return s, true
+		}
+		if usage, ok := parseResourceUsage(content); ok {
+			s.Tables, s.Charts = resourceUsagePresentation(usage)
+			return s, true
+		}
+		if values, ok := parseNumbers(content); ok {
+			s.Tables, s.Charts = numbersPresentation(values)
+			return s, true
+		}
+	}
+	if len(content) > maxRawLength {
+		content = content[:maxRawLength] + "\n..."
+	}
+	s.Raw = content
+	return s, true
+}
+
+func parsePerfData(content string) (*measurementutil.PerfData, bool) {
+	var perfData measurementutil.PerfData
+	if err := json.Unmarshal([]byte(content), &perfData); err != nil || perfData.Version == "" || perfData.DataItems == nil {
+		return nil, false
+	}
+	return &perfData, true
+}
+
+// perfDataTables presents all data items in a single table, with a column per label and data bucket.
+func perfDataTables(perfData *measurementutil.PerfData) []table {
+	labelSet, dataSet := make(map[string]bool), make(map[string]bool)
+	for _, item := range perfData.DataItems {
+		for k := range item.Labels {
+			labelSet[k] = true
+		}
+		for k := range item.Data {
+			dataSet[k] = true
+		}
+	}
+	labels, buckets := sortedKeys(labelSet), sortedKeys(dataSet)
+	t := table{Header: append(append(append([]string{}, labels...), buckets...), "Unit")}
+	for _, item := range perfData.DataItems {
+		row := make([]string, 0, len(t.Header))
+		for _, k := range labels {
+			row = append(row, item.Labels[k])
+		}
+		for _, k := range buckets {
+			if v, ok := item.Data[k]; ok {
+				row = append(row, formatFloat(v))
+			} else {
+				row = append(row, "")
+			}
+		}
+		t.Rows = append(t.Rows, append(row, item.Unit))
+	}
+	return []table{t}
+}
+
+// perfDataCharts presents Perc99 (or the first data bucket if there is no Perc99) of data items.
+func perfDataCharts(perfData *measurementutil.PerfData) []chart {
+	dataSet := make(map[string]bool)
+	for _, item := range perfData.DataItems {
+		for k := range item.Data {
+			dataSet[k] = true
+		}
+	}
+	buckets := sortedKeys(dataSet)
+	if len(buckets) == 0 {
+		return nil
+	}
+	bucket := buckets[0]
+	if dataSet["Perc99"] {
+		bucket = "Perc99"
+	}
+	var bars []bar
+	unit := ""
+	for _, item := range perfData.DataItems {
+		v, ok := item.Data[bucket]
+		if !ok {
+			continue
+		}
+		unit = item.Unit
+		bars = append(bars, bar{Label: formatLabels(item.Labels), Value: formatFloat(v), Width: v})
+	}
+	title := bucket
+	if unit != "" {
+		title = fmt.Sprintf("%s [%s]", bucket, unit)
+	}
+	return []chart{newChart(title, bars)}
+}
+
+func parseResourceUsage(content string) (map[string][]measurementutil.SingleContainerSummary, bool) {
+	var usage map[string][]measurementutil.SingleContainerSummary
+	if err := json.Unmarshal([]byte(content), &usage); err != nil || len(usage) == 0 {
+		return nil, false
+	}
+	for perc := range usage {
+		if _, err := strconv.Atoi(perc); err != nil {
+			return nil, false
+		}
+	}
+	return usage, true
+}
+
+// resourceUsagePresentation presents usage of containers at the highest percentile.
+func resourceUsagePresentation(usage map[string][]measurementutil.SingleContainerSummary) ([]table, []chart) {
+	highest := -1
+	for perc := range usage {
+		if p, _ := strconv.Atoi(perc); p > highest {
+			highest = p
+		}
+	}
+	containers := usage[strconv.Itoa(highest)]
+	t := table{Header: []string{"Container", fmt.Sprintf("CPU Perc%d [cores]", highest), fmt.Sprintf("Memory Perc%d [MiB]", highest)}}
+	var cpuBars, memBars []bar
+	for _, c := range containers {
+		memMiB := float64(c.Mem) / (1 << 20)
+		t.Rows = append(t.Rows, []string{c.Name, formatFloat(c.Cpu), formatFloat(memMiB)})
+		cpuBars = append(cpuBars, bar{Label: c.Name, Value: formatFloat(c.Cpu), Width: c.Cpu})
+		memBars = append(memBars, bar{Label: c.Name, Value: formatFloat(memMiB), Width: memMiB})
+	}
+	charts := []chart{
+		newChart(fmt.Sprintf("CPU Perc%d [cores]", highest), cpuBars),
+		newChart(fmt.Sprintf("Memory Perc%d [MiB]", highest), memBars),
+	}
+	return []table{t}, charts
+}
+
+func parseNumbers(content string) (map[string]float64, bool) {
+	var values map[string]float64
+	if err := json.Unmarshal([]byte(content), &values); err != nil || len(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+func numbersPresentation(values map[string]float64) ([]table, []chart) {
+	t := table{Header: []string{"Name", "Value"}}
+	var bars []bar
+	keys := make(map[string]bool)
+	for k := range values {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		t.Rows = append(t.Rows, []string{k, formatFloat(values[k])})
+		bars = append(bars, bar{Label: k, Value: formatFloat(values[k]), Width: values[k]})
+	}
+	return []table{t}, []chart{newChart("", bars)}
+}
+
+// newChart keeps maxBars highest bars and scales them, so that the widest one has 100% width.
+// Bars are expected to have their values set as widths.
+func newChart(title string, bars []bar) chart {
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Width > bars[j].Width })
+	if len(bars) > maxBars {
+		bars = bars[:maxBars]
+	}
+	max := 0.0
+	for i := range bars {
+		if bars[i].Width > max {
+			max = bars[i].Width
+		}
+	}
+	for i := range bars {
+		if max > 0 && bars[i].Width > 0 {
+			bars[i].Width = 100 * bars[i].Width / max
+		} else {
+			bars[i].Width = 0
+		}
+	}
+	return chart{Title: title, Bars: bars}
+}
+
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if labels[k] != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, labels[k]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestCreateSummary(t *testing.T) {
+	summaries := []measurement.Summary{
+		measurement.CreateSummary("APIResponsiveness", "json", `{"version": "v1", "dataItems": [
+			{"data": {"Perc50": 10, "Perc99": 200.12345}, "unit": "ms", "labels": {"Resource": "pods", "Verb": "LIST"}},
+			{"data": {"Perc50": 5, "Perc99": 50}, "unit": "ms", "labels": {"Resource": "nodes", "Verb": "GET"}}]}`),
+		measurement.CreateSummary("ResourceUsageSummary", "json", `{"50": [{"Name": "kube-apiserver", "Cpu": 0.5, "Mem": 1048576}],
+			"99": [{"Name": "kube-apiserver", "Cpu": 2, "Mem": 2097152}, {"Name": "etcd", "Cpu": 1, "Mem": 1048576}]}`),
+		measurement.CreateSummary("SchedulingThroughput", "json", `{"average": 90, "perc50": 100}`),
+		measurement.CreateSummary("Profile", "txt", "<profile>"),
+		measurement.CreateSummary("junit", "xml", "<testsuite></testsuite>"),
+	}
+	results := []measurement.MeasurementResult{
+		{Method: "APIResponsiveness", Identifier: "APIResponsiveness", Duration: time.Minute, Violations: []error{errors.NewMetricViolationError("top latency metric", "too high")}},
+		{Method: "WaitForControlledPodsRunning", Errors: []error{fmt.Errorf("timeout")}},
+		{Method: "EtcdMetrics", SkipReason: "missing SSH access"},
+	}
+	summary, err := CreateSummary("load", summaries, results)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "report", summary.SummaryName())
+	assert.Equal(t, "html", summary.SummaryExt())
+
+	content := summary.SummaryContent()
+	for _, expected := range []string{
+		"<h1>load</h1>",
+		`<td class="Failed">Failed</td>`,
+		"<div>top latency metric: too high</div>",
+		`<td class="Skipped">Skipped</td>`,
+		"<h4>Perc99 [ms]</h4>",
+		"Resource=pods Verb=LIST",
+		`style="width: 100.00%"`,
+		"<td>200.123</td>",
+		"<h4>CPU Perc99 [cores]</h4>",
+		`style="width: 50.00%"`,
+		"<td>perc50</td><td>100</td>",
+		"<pre>&lt;profile&gt;</pre>",
+	} {
+		assert.Contains(t, content, expected)
+	}
+	assert.False(t, strings.Contains(content, "<h2>junit</h2>"), "junit summary shouldn't be included")
+}
+
+func TestNewChart(t *testing.T) {
+	var bars []bar
+	for i := 0; i < 2*maxBars; i++ {
+		bars = append(bars, bar{Label: fmt.Sprint(i), Width: float64(i)})
+	}
+	c := newChart("title", bars)
+	assert.Len(t, c.Bars, maxBars)
+	assert.Equal(t, fmt.Sprint(2*maxBars-1), c.Bars[0].Label)
+	assert.Equal(t, 100.0, c.Bars[0].Width)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"html/template"
+)
+
+// pageTemplate renders the report. Charts are drawn with plain CSS, so that the report
+// doesn't depend on any external resources.
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ClusterLoader2 report: {{.TestName}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 0.2em; margin-top: 2em; }
+table { border-collapse: collapse; margin: 1em 0; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+.Passed { color: #1a7f37; }
+.Failed { color: #cf222e; font-weight: bold; }
+.Skipped { color: #9a6700; }
+.chart { margin: 1em 0; max-width: 60em; }
+.bar-row { display: flex; align-items: center; font-size: 0.8em; margin: 2px 0; }
+.bar-label { width: 40%; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; padding-right: 0.5em; }
+.bar-area { flex: 1; }
+.bar { background: #4c78a8; height: 1em; display: inline-block; vertical-align: middle; }
+.bar-value { padding-left: 0.4em; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; max-height: 40em; }
+</style>
+</head>
+<body>
+<h1>{{.TestName}}</h1>
+<p>Generated at {{.Time}}</p>
+{{- if .Measurements}}
+<h2>Measurements</h2>
+<table>
+<tr><th>Measurement</th><th>Status</th><th>Duration</th><th>Details</th></tr>
+{{- range .Measurements}}
+<tr><td>{{.Name}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{.Duration}}</td><td>{{range .Messages}}<div>{{.}}</div>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Name}}</h2>
+{{- range .Charts}}
+<div class="chart">
+{{- if .Title}}<h4>{{.Title}}</h4>{{end}}
+{{- range .Bars}}
+<div class="bar-row"><div class="bar-label" title="{{.Label}}">{{.Label}}</div><div class="bar-area"><span class="bar" style="width: {{printf "%.2f" .Width}}%"></span><span class="bar-value">{{.Value}}</span></div></div>
+{{- end}}
+</div>
+{{- end}}
+{{- range .Tables}}
+<details><summary>Table</summary>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</details>
+{{- end}}
+{{- if .Raw}}
+<details><summary>Content</summary><pre>{{.Raw}}</pre></details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
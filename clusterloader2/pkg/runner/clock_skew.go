@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+)
+
+const (
+	// dateHeaderResolution is the precision of time in the Date header of HTTP responses.
+	dateHeaderResolution = time.Second
+	// prometheusTimeResolution is the precision of time returned by Prometheus.
+	prometheusTimeResolution = time.Millisecond
+)
+
+// clockSkew is the difference between clock of a server and the local clock.
+type clockSkew struct {
+	// skew is positive if the server clock is ahead of the local one.
+	skew time.Duration
+	// uncertainty is the maximal error of skew, caused by the request duration and
+	// the resolution of time returned by the server.
+	uncertainty time.Duration
+}
+
+// exceeds returns whether the skew is certainly larger than max.
+func (c clockSkew) exceeds(max time.Duration) bool {
+	skew := c.skew
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew-c.uncertainty > max
+}
+
+// measureClockSkew compares time returned by serverTime with the local time in the middle of the request.
+// Server time is expected to be truncated to the given resolution.
+func measureClockSkew(serverTime func() (time.Time, error), resolution time.Duration, now func() time.Time) (clockSkew, error) {
+	start := now()
+	t, err := serverTime()
+	if err != nil {
+		return clockSkew{}, err
+	}
+	halfDuration := now().Sub(start) / 2
+	return clockSkew{
+		skew:        t.Add(resolution / 2).Sub(start.Add(halfDuration)),
+		uncertainty: halfDuration + resolution/2,
+	}, nil
+}
+
+// checkClockSkew verifies that clock of the server doesn't differ from the local clock by more than
+// allowed by options. Measurements compute durations from timestamps taken by clusterloader, apiserver
+// and Prometheus, so the skew silently corrupts their results. According to the clock skew policy,
+// the run fails or only a warning is logged if the skew is too large or can't be measured.
+func checkClockSkew(name string, serverTime func() (time.Time, error), resolution time.Duration, opts Options) error {
+	if opts.ClockSkewPolicy != ClockSkewPolicyWarn && opts.ClockSkewPolicy != ClockSkewPolicyFail {
+		return nil
+	}
+	var err error
+	skew, measureErr := measureClockSkew(serverTime, resolution, time.Now)
+	switch {
+	case measureErr != nil:
+		err = fmt.Errorf("measuring clock skew of %s error: %v", name, measureErr)
+	case skew.exceeds(opts.MaxClockSkew):
+		err = fmt.Errorf("clock of %s is skewed by %v (+/- %v), more than allowed %v", name, skew.skew, skew.uncertainty, opts.MaxClockSkew)
+	default:
+		logrus.Infof("Clock of %s is skewed by %v (+/- %v)", name, skew.skew, skew.uncertainty)
+		return nil
+	}
+	if opts.ClockSkewPolicy == ClockSkewPolicyFail {
+		return err
+	}
+	logrus.Warningf("%v", err)
+	return nil
+}
+
+// apiserverTime returns function reading time of apiserver from the Date header of the response to /version.
+func apiserverTime(c kubernetes.Interface) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		restClient, ok := c.CoreV1().RESTClient().(*rest.RESTClient)
+		if !ok {
+			return time.Time{}, fmt.Errorf("unsupported REST client %T", c.CoreV1().RESTClient())
+		}
+		httpClient := restClient.Client
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		// Any response, even unauthorized, contains the date.
+		response, err := httpClient.Get(restClient.Get().AbsPath("/version").URL().String())
+		if err != nil {
+			return time.Time{}, err
+		}
+		response.Body.Close()
+		return http.ParseTime(response.Header.Get("Date"))
+	}
+}
+
+// prometheusTime returns function querying time of Prometheus server used by measurements.
+func prometheusTime(prometheusFramework *framework.Framework, prometheusConfig *config.PrometheusConfig) (func() (time.Time, error), error) {
+	// Retries would distort the measurement, failed query is reported instead.
+	executor, err := measurementutil.GetQueryExecutor(prometheusFramework.GetClientSets().GetClient(), prometheusConfig, measurementutil.QueryRetryPolicy{})
+	if err != nil {
+		return nil, err
+	}
+	return executor.ServerTime, nil
+}
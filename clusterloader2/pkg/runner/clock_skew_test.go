@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureClockSkew(t *testing.T) {
+	local := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := func() time.Time {
+		local = local.Add(100 * time.Millisecond)
+		return local
+	}
+	// The request takes from 00:00:00.1 to 00:00:00.2, the server responds with 00:00:10 truncated to seconds.
+	serverTime := func() (time.Time, error) { return time.Date(2019, 1, 1, 0, 0, 10, 0, time.UTC), nil }
+	skew, err := measureClockSkew(serverTime, time.Second, now)
+	assert.NoError(t, err)
+	assert.Equal(t, clockSkew{skew: 10350 * time.Millisecond, uncertainty: 550 * time.Millisecond}, skew)
+	assert.True(t, skew.exceeds(9*time.Second))
+	assert.False(t, skew.exceeds(10*time.Second))
+	assert.True(t, clockSkew{skew: -2 * time.Second, uncertainty: time.Second}.exceeds(500*time.Millisecond))
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	skewed := func() (time.Time, error) { return time.Now().Add(time.Hour), nil }
+	failing := func() (time.Time, error) { return time.Time{}, fmt.Errorf("unavailable") }
+	synchronized := func() (time.Time, error) { return time.Now(), nil }
+
+	fail := Options{ClockSkewPolicy: ClockSkewPolicyFail, MaxClockSkew: time.Minute}
+	assert.Error(t, checkClockSkew("server", skewed, time.Millisecond, fail))
+	assert.Error(t, checkClockSkew("server", failing, time.Millisecond, fail))
+	assert.NoError(t, checkClockSkew("server", synchronized, time.Millisecond, fail))
+
+	warn := Options{ClockSkewPolicy: ClockSkewPolicyWarn, MaxClockSkew: time.Minute}
+	assert.NoError(t, checkClockSkew("server", skewed, time.Millisecond, warn))
+	assert.NoError(t, checkClockSkew("server", failing, time.Millisecond, warn))
+}
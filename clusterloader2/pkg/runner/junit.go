@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"time"
+
+	ginkgoconfig "github.com/onsi/ginkgo/config"
+	ginkgoreporters "github.com/onsi/ginkgo/reporters"
+	ginkgotypes "github.com/onsi/ginkgo/types"
+	"k8s.io/perf-tests/clusterloader2/pkg/test"
+)
+
+const suiteDescription = "ClusterLoaderV2"
+
+// junitReporter writes JUnit report of the run.
+type junitReporter struct {
+	reporter     *ginkgoreporters.JUnitReporter
+	suiteSummary *ginkgotypes.SuiteSummary
+	start        time.Time
+}
+
+func newJUnitReporter(path string, tests int) *junitReporter {
+	r := &junitReporter{
+		reporter: ginkgoreporters.NewJUnitReporter(path),
+		suiteSummary: &ginkgotypes.SuiteSummary{
+			SuiteDescription:           suiteDescription,
+			NumberOfSpecsThatWillBeRun: tests,
+		},
+		start: time.Now(),
+	}
+	r.reporter.SpecSuiteWillBegin(ginkgoconfig.GinkgoConfig, r.suiteSummary)
+	return r
+}
+
+// TestStarted does nothing, tests are reported once they're finished.
+func (r *junitReporter) TestStarted(testID string) {}
+
+// TestFinished adds the test to the report.
+func (r *junitReporter) TestFinished(testID string, result *test.Result, duration time.Duration) {
+	specSummary := &ginkgotypes.SpecSummary{
+		ComponentTexts: []string{suiteDescription, testID},
+		State:          ginkgotypes.SpecStatePassed,
+		RunTime:        duration,
+	}
+	if errList := result.Errors; !errList.IsEmpty() {
+		r.suiteSummary.NumberOfFailedSpecs++
+		specSummary.State = ginkgotypes.SpecStateFailed
+		specSummary.Failure = ginkgotypes.SpecFailure{
+			Message: errList.String(),
+		}
+	}
+	r.reporter.SpecDidComplete(specSummary)
+}
+
+// finish writes the report.
+func (r *junitReporter) finish() {
+	r.suiteSummary.RunTime = time.Since(r.start)
+	r.reporter.SpecSuiteDidEnd(r.suiteSummary)
+}
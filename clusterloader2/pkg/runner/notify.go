@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
+	"k8s.io/perf-tests/clusterloader2/pkg/test"
+)
+
+// notifyRunFinished posts summary of the run with configured notifiers, if any test failed
+// or notifying about successful runs is enabled.
+func notifyRunFinished(n notifier.Notifier, clusterLoaderConfig *config.ClusterLoaderConfig, result *Result) {
+	if n == nil || (result.Failed == 0 && !clusterLoaderConfig.NotifierConfig.NotifyOnSuccess) {
+		return
+	}
+	if err := n.Notify(newRunNotification(clusterLoaderConfig, result)); err != nil {
+		logrus.Errorf("Error while sending notification to %v: %v", n, err)
+		return
+	}
+	logrus.Infof("Summary of the run sent to %v", n)
+}
+
+func newRunNotification(clusterLoaderConfig *config.ClusterLoaderConfig, result *Result) *notifier.Notification {
+	notification := &notifier.Notification{
+		Event:     notifier.RunFinished,
+		Run:       clusterLoaderConfig.NotifierConfig.RunName,
+		Timestamp: time.Now(),
+		Category:  string(test.GetCategory(result.Tests)),
+	}
+	for _, testResult := range result.Tests {
+		notification.Tests = append(notification.Tests, notifier.TestOutcome{
+			Test:     testResult.Test,
+			Category: string(testResult.Category),
+			Errors:   testResult.Errors.Len(),
+		})
+		for _, violation := range testResult.Violations {
+			notification.Violations = append(notification.Violations, notifier.Violation{
+				Test:        violation.Test,
+				Measurement: violation.Measurement,
+				Identifier:  violation.Identifier,
+				Metric:      violation.Metric,
+				Message:     violation.Message,
+			})
+		}
+	}
+	if clusterLoaderConfig.ReportDir != "" {
+		notification.Artifacts = notifier.ArtifactLinks(clusterLoaderConfig.NotifierConfig.ArtifactsURL, clusterLoaderConfig.ReportDir,
+			violationsFileName, junitFileName)
+	}
+	return notification
+}
@@ -0,0 +1,531 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runner runs clusterloader tests, so that they can be executed programmatically
+// by programs embedding clusterloader, not only with the clusterloader command.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/control"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/execservice"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
+	"k8s.io/perf-tests/clusterloader2/pkg/test"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/virtualnodes"
+
+	// Measurements are registered, so that they are available to tests run by embedding programs.
+	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common"
+	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/bundle"
+	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/probes"
+	_ "k8s.io/perf-tests/clusterloader2/pkg/measurement/common/slos"
+)
+
+const (
+	dashLine        = "--------------------------------------------------------------------------------"
+	nodesPerClients = 100
+
+	// Policies of handling namespaces left by previous (e.g. crashed) runs.
+	StaleNamespacePolicyDelete = "delete"
+	StaleNamespacePolicyFail   = "fail"
+	StaleNamespacePolicyIgnore = "ignore"
+
+	// DefaultStaleNamespaceTTL is the default minimal age of namespaces created by other runs to consider
+	// them stale. It's well above the duration of the longest tests, so that namespaces of concurrent runs
+	// against the same cluster are not considered stale.
+	DefaultStaleNamespaceTTL = 24 * time.Hour
+
+	// Policies of handling skew between local clock and clocks of apiserver and Prometheus.
+	ClockSkewPolicyFail   = "fail"
+	ClockSkewPolicyWarn   = "warn"
+	ClockSkewPolicyIgnore = "ignore"
+
+	// violationsFileName is the name of the file in the report directory listing SLO violations of all tests.
+	violationsFileName = "violations.json"
+	// junitFileName is the name of the JUnit report in the report directory.
+	junitFileName = "junit.xml"
+)
+
+// Options customize the run.
+type Options struct {
+	// Scenarios are tests to run, in order.
+	Scenarios []api.TestScenario
+	// StaleNamespacePolicy determines what to do with namespaces left by previous runs.
+	// Namespaces are deleted if it's StaleNamespacePolicyDelete, the run fails if it's
+	// StaleNamespacePolicyFail and they are ignored otherwise.
+	StaleNamespacePolicy string
+	// StaleNamespaceTTL is minimal age of namespaces created by other runs to consider them stale.
+	// Non-positive value means DefaultStaleNamespaceTTL.
+	StaleNamespaceTTL time.Duration
+	// ClockSkewPolicy determines what to do if clock of apiserver or Prometheus is skewed by
+	// more than MaxClockSkew from the local clock. The run fails if it's ClockSkewPolicyFail,
+	// a warning is logged if it's ClockSkewPolicyWarn and the clocks aren't checked otherwise.
+	ClockSkewPolicy string
+	// MaxClockSkew is the maximal allowed skew of clocks.
+	MaxClockSkew time.Duration
+	// Logger, if set, receives logs of the run instead of the standard logrus logger.
+	// Clusterloader logs with the standard logger, so its configuration is replaced for the run
+	// and only one run can be executed at a time.
+	Logger *logrus.Logger
+	// Reporters are notified about every test, in addition to the JUnit report.
+	Reporters []Reporter
+	// Controller allows pausing and resuming load phases of tests. If nil, the load can't be paused.
+	Controller *control.Controller
+}
+
+// Reporter is notified about tests executed by the run.
+type Reporter interface {
+	// TestStarted is called before the test is executed.
+	TestStarted(testID string)
+	// TestFinished is called with the result of the test once it's finished.
+	TestFinished(testID string, result *test.Result, duration time.Duration)
+}
+
+// Result is the outcome of the run.
+type Result struct {
+	// Tests contains results of executed tests, in order.
+	Tests []*test.Result
+	// Failed is the number of failed tests.
+	Failed int
+}
+
+// ExitCode returns exit code of the clusterloader command corresponding to the most severe failure of tests.
+func (r *Result) ExitCode() int {
+	if r.Failed == 0 {
+		return 0
+	}
+	return test.GetCategory(r.Tests).ExitCode()
+}
+
+// Run sets up the cluster (virtual nodes, prometheus stack, exec service) described by the config,
+// runs given tests and tears down what was set up. The config is completed with values discovered
+// from the cluster, e.g. number of nodes. Error is returned if the cluster couldn't be set up;
+// failures of tests are reported in the result. If ctx is done, the running test is cancelled
+// (see test.RunTest), remaining tests are not started and ctx error is returned together with
+// results of already executed tests. What was set up is torn down in any case.
+// Credential sources have to be set up with credentials.Init beforehand.
+func Run(ctx context.Context, clusterLoaderConfig *config.ClusterLoaderConfig, opts Options) (*Result, error) {
+	if opts.Logger != nil {
+		defer redirectLogs(opts.Logger)()
+	}
+	// Test configs are validated before touching the cluster (including setting up virtual nodes),
+	// so that errors in them are not discovered in the middle of the run. As in the validate command,
+	// templates are rendered with the number of nodes from the config, which may be completed later.
+	if err := validateTests(clusterLoaderConfig, opts.Scenarios); err != nil {
+		return nil, fmt.Errorf("test config validation error: %v", err)
+	}
+	mclient, err := framework.NewMultiClientSet(clusterLoaderConfig.ClusterConfig.KubeConfigPath, 1, clusterLoaderConfig.ClusterConfig.ClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("client creation error: %v", err)
+	}
+
+	// Progress is opened before any namespace is created (and before handling stale namespaces),
+	// so that namespaces are labeled with the id of the run and namespaces of the resumed run aren't stale.
+	progress, runID, err := test.OpenProgress(clusterLoaderConfig.StateFile, clusterLoaderConfig.Resume)
+	if err != nil {
+		return nil, fmt.Errorf("state file error: %v", err)
+	}
+	clusterLoaderConfig.ClusterConfig.RunID = runID
+
+	// Virtual nodes are set up before completing the config, so that they are included
+	// in the number of nodes if it is not provided.
+	var virtualNodesFramework *framework.Framework
+	if clusterLoaderConfig.VirtualNodesConfig.Enable {
+		if clusterLoaderConfig.VirtualNodesConfig.Count == 0 {
+			clusterLoaderConfig.VirtualNodesConfig.Count = clusterLoaderConfig.ClusterConfig.Nodes
+		}
+		if virtualNodesFramework, err = framework.NewFramework(&clusterLoaderConfig.ClusterConfig, 1); err != nil {
+			return nil, fmt.Errorf("framework creation error: %v", err)
+		}
+		if err = virtualnodes.SetUpVirtualNodes(virtualNodesFramework, &clusterLoaderConfig.VirtualNodesConfig); err != nil {
+			return nil, fmt.Errorf("error while setting up virtual nodes: %v", err)
+		}
+		if clusterLoaderConfig.VirtualNodesConfig.TearDown {
+			defer func() {
+				if err := virtualnodes.TearDownVirtualNodes(virtualNodesFramework); err != nil {
+					logrus.Errorf("Error while tearing down virtual nodes: %v", err)
+				}
+			}()
+		}
+	}
+
+	if err = completeConfig(clusterLoaderConfig, mclient); err != nil {
+		return nil, fmt.Errorf("config completing error: %v", err)
+	}
+
+	logrus.Infof("Using config: %+v", *clusterLoaderConfig)
+
+	n, err := notifier.NewNotifier(&clusterLoaderConfig.NotifierConfig)
+	if err != nil {
+		return nil, fmt.Errorf("notifier creation error: %v", err)
+	}
+
+	if err = CreateReportDir(clusterLoaderConfig.ReportDir); err != nil {
+		return nil, fmt.Errorf("cannot create report directory: %v", err)
+	}
+
+	if err = util.LogClusterNodes(mclient.GetClient()); err != nil {
+		logrus.Errorf("Nodes info logging error: %v", err)
+	}
+
+	if err = verifyCluster(mclient.GetClient()); err != nil {
+		return nil, fmt.Errorf("cluster verification error: %v", err)
+	}
+
+	if err = checkClockSkew("apiserver", apiserverTime(mclient.GetClient()), dateHeaderResolution, opts); err != nil {
+		return nil, fmt.Errorf("clock skew error: %v", err)
+	}
+
+	f, err := framework.NewFramework(
+		&clusterLoaderConfig.ClusterConfig,
+		GetClientsNumber(clusterLoaderConfig.ClusterConfig.Nodes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("framework creation error: %v", err)
+	}
+
+	clusterFrameworks, err := framework.NewClusterFrameworks(
+		&clusterLoaderConfig.ClusterConfig,
+		GetClientsNumber(clusterLoaderConfig.ClusterConfig.Nodes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cluster frameworks creation error: %v", err)
+	}
+
+	clients := map[string]kubernetes.Interface{"": mclient.GetClient()}
+	for name, clusterFramework := range clusterFrameworks {
+		clients[name] = clusterFramework.GetClientSets().GetClient()
+	}
+	if err = handleStaleNamespaces(clients, clusterLoaderConfig, opts); err != nil {
+		return nil, fmt.Errorf("stale namespaces error: %v", err)
+	}
+
+	var prometheusFramework *framework.Framework
+	if clusterLoaderConfig.PrometheusConfig.EnableServer || clusterLoaderConfig.PrometheusConfig.Endpoint != "" {
+		// Overrides of the config test scenario are passed to prometheus controller.
+		prometheusController, err := prometheus.NewPrometheusController(clusterLoaderConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error while creating Prometheus Controller: %v", err)
+		}
+		prometheusFramework = prometheusController.GetFramework()
+		if err := prometheusController.SetUpPrometheusStack(); err != nil {
+			return nil, fmt.Errorf("error while setting up prometheus stack: %v", err)
+		}
+		if clusterLoaderConfig.PrometheusConfig.TearDownServer {
+			defer func() {
+				if err := prometheusController.TearDownPrometheusStack(); err != nil {
+					logrus.Errorf("Error while tearing down prometheus stack: %v", err)
+				}
+			}()
+		}
+		serverTime, err := prometheusTime(prometheusFramework, &clusterLoaderConfig.PrometheusConfig)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query executor creation error: %v", err)
+		}
+		if err := checkClockSkew("prometheus", serverTime, prometheusTimeResolution, opts); err != nil {
+			return nil, fmt.Errorf("clock skew error: %v", err)
+		}
+	}
+	if clusterLoaderConfig.EnableExecService {
+		if err := execservice.SetUpExecService(f); err != nil {
+			return nil, fmt.Errorf("error while setting up exec service: %v", err)
+		}
+		defer func() {
+			if err := execservice.TearDownExecService(f); err != nil {
+				logrus.Errorf("Error while tearing down exec service: %v", err)
+			}
+		}()
+	}
+
+	junitReporter := newJUnitReporter(path.Join(clusterLoaderConfig.ReportDir, junitFileName), len(opts.Scenarios))
+	reporters := append([]Reporter{junitReporter}, opts.Reporters...)
+	result := &Result{}
+	for i := range opts.Scenarios {
+		if err = ctx.Err(); err != nil {
+			logrus.Errorf("Not running remaining tests: %v", err)
+			break
+		}
+		if testID := GetTestID(opts.Scenarios[i]); progress.IsCompleted(testID) {
+			logrus.Infof("Skipping test %s completed by the resumed run", testID)
+			continue
+		}
+		clusterLoaderConfig.TestScenario = opts.Scenarios[i]
+		testResult := runSingleTest(ctx, f, prometheusFramework, clusterFrameworks, clusterLoaderConfig, reporters, test.RunOptions{Controller: opts.Controller, Notifier: n})
+		if !testResult.Errors.IsEmpty() {
+			result.Failed++
+		}
+		result.Tests = append(result.Tests, testResult)
+	}
+	junitReporter.finish()
+
+	if clusterLoaderConfig.ReportDir != "" {
+		if err := test.WriteViolations(path.Join(clusterLoaderConfig.ReportDir, violationsFileName), result.Tests); err != nil {
+			logrus.Errorf("Error while writing violations: %v", err)
+		}
+	}
+	notifyRunFinished(n, clusterLoaderConfig, result)
+	return result, ctx.Err()
+}
+
+func runSingleTest(
+	ctx context.Context,
+	f *framework.Framework,
+	prometheusFramework *framework.Framework,
+	clusterFrameworks map[string]*framework.Framework,
+	clusterLoaderConfig *config.ClusterLoaderConfig,
+	reporters []Reporter,
+	runOptions test.RunOptions,
+) *test.Result {
+	testID := GetTestID(clusterLoaderConfig.TestScenario)
+	testStart := time.Now()
+	printTestStart(testID)
+	for _, reporter := range reporters {
+		reporter.TestStarted(testID)
+	}
+	result := test.RunTest(ctx, f, prometheusFramework, clusterFrameworks, clusterLoaderConfig, runOptions)
+	if result.Test == "" {
+		result.Test = testID
+	}
+	if errList := result.Errors; !errList.IsEmpty() {
+		printTestResult(testID, "Fail", errList.String())
+	} else {
+		printTestResult(testID, "Success", "")
+	}
+	duration := time.Since(testStart)
+	for _, reporter := range reporters {
+		reporter.TestFinished(testID, result, duration)
+	}
+	return result
+}
+
+// validateTests statically validates configs of all tests, see test.Validate.
+func validateTests(clusterLoaderConfig *config.ClusterLoaderConfig, scenarios []api.TestScenario) error {
+	testScenario := clusterLoaderConfig.TestScenario
+	defer func() { clusterLoaderConfig.TestScenario = testScenario }()
+	errList := errors.NewErrorList()
+	for i := range scenarios {
+		clusterLoaderConfig.TestScenario = scenarios[i]
+		if testErrList := test.Validate(clusterLoaderConfig); !testErrList.IsEmpty() {
+			errList.Append(fmt.Errorf("%s: %v", GetTestID(scenarios[i]), testErrList.String()))
+		}
+	}
+	if !errList.IsEmpty() {
+		return errList
+	}
+	return nil
+}
+
+// GetTestID returns identifier of the test used in logs and reports.
+func GetTestID(ts api.TestScenario) string {
+	return test.GetTestID(ts)
+}
+
+// GetClientsNumber returns number of clients used by the framework for the cluster of given size.
+func GetClientsNumber(nodesNumber int) int {
+	return (nodesNumber + nodesPerClients - 1) / nodesPerClients
+}
+
+// CreateReportDir creates the report directory if it's set and doesn't exist.
+func CreateReportDir(reportDir string) error {
+	if reportDir != "" {
+		if _, err := os.Stat(reportDir); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			if err = os.MkdirAll(reportDir, 0755); err != nil {
+				return fmt.Errorf("report directory creation error: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func completeConfig(clusterLoaderConfig *config.ClusterLoaderConfig, m *framework.MultiClientSet) error {
+	if clusterLoaderConfig.ClusterConfig.Nodes == 0 {
+		nodes, err := util.GetSchedulableUntainedNodesNumber(m.GetClient())
+		if err != nil {
+			return fmt.Errorf("getting number of nodes error: %v", err)
+		}
+		clusterLoaderConfig.ClusterConfig.Nodes = nodes
+		logrus.Infof("ClusterConfig.Nodes set to %v", nodes)
+	}
+	if clusterLoaderConfig.ClusterConfig.MasterName == "" {
+		masterName, err := util.GetMasterName(m.GetClient())
+		if err == nil {
+			clusterLoaderConfig.ClusterConfig.MasterName = masterName
+			logrus.Infof("ClusterConfig.MasterName set to %v", masterName)
+		} else {
+			logrus.Errorf("Getting master name error: %v", err)
+		}
+	}
+	if len(clusterLoaderConfig.ClusterConfig.MasterIPs) == 0 {
+		masterIPs, err := util.GetMasterIPs(m.GetClient(), corev1.NodeExternalIP)
+		if err == nil {
+			clusterLoaderConfig.ClusterConfig.MasterIPs = masterIPs
+			logrus.Infof("ClusterConfig.MasterIP set to %v", masterIPs)
+		} else {
+			logrus.Errorf("Getting master external ip error: %v", err)
+		}
+	}
+	if len(clusterLoaderConfig.ClusterConfig.MasterInternalIPs) == 0 {
+		masterIPs, err := util.GetMasterIPs(m.GetClient(), corev1.NodeInternalIP)
+		if err == nil {
+			clusterLoaderConfig.ClusterConfig.MasterInternalIPs = masterIPs
+			logrus.Infof("ClusterConfig.MasterInternalIP set to %v", masterIPs)
+		} else {
+			logrus.Errorf("Getting master internal ip error: %v", err)
+		}
+	}
+	return nil
+}
+
+func verifyCluster(c kubernetes.Interface) error {
+	numSchedulableNodes, err := util.GetSchedulableUntainedNodesNumber(c)
+	if err != nil {
+		return err
+	}
+	if numSchedulableNodes == 0 {
+		return fmt.Errorf("no schedulable nodes in the cluster")
+	}
+	return nil
+}
+
+// handleStaleNamespaces detects namespaces created by previous runs in all clusters, e.g. probes namespace
+// or automanaged namespaces left by a crashed run, and deletes them or refuses to proceed according to
+// the stale namespace policy. Clusters are keyed by their names, the tested cluster has an empty name.
+func handleStaleNamespaces(clients map[string]kubernetes.Interface, clusterLoaderConfig *config.ClusterLoaderConfig, opts Options) error {
+	if opts.StaleNamespacePolicy != StaleNamespacePolicyDelete && opts.StaleNamespacePolicy != StaleNamespacePolicyFail {
+		return nil
+	}
+	ttl := opts.StaleNamespaceTTL
+	if ttl <= 0 {
+		ttl = DefaultStaleNamespaceTTL
+	}
+	automanagedPattern, err := automanagedNamespacePattern(&clusterLoaderConfig.NamespaceConfig)
+	if err != nil {
+		return err
+	}
+	stale := make(map[string][]string)
+	var staleCount int
+	for cluster, c := range clients {
+		namespaces, err := client.ListStaleNamespaces(c, clusterLoaderConfig.ClusterConfig.RunID, ttl, automanagedPattern)
+		if err != nil {
+			return fmt.Errorf("listing stale namespaces of cluster %q error: %v", cluster, err)
+		}
+		for _, namespace := range namespaces {
+			// Prometheus stack that wasn't torn down is intentionally reused.
+			if cluster == "" && namespace.Name == prometheus.Namespace && !clusterLoaderConfig.PrometheusConfig.TearDownServer {
+				continue
+			}
+			logrus.Warningf("Namespace %s of cluster %q was created %v (more than %v ago) by run %q", namespace.Name, cluster, namespace.CreationTimestamp, ttl, namespace.Labels[client.RunIDLabel])
+			stale[cluster] = append(stale[cluster], namespace.Name)
+			staleCount++
+		}
+	}
+	if staleCount == 0 {
+		return nil
+	}
+	if opts.StaleNamespacePolicy == StaleNamespacePolicyFail {
+		return fmt.Errorf("namespaces %v were left by previous runs more than %v ago, delete them or pass --stale-namespace-policy=%s", stale, ttl, StaleNamespacePolicyDelete)
+	}
+	for cluster, namespaces := range stale {
+		for _, namespace := range namespaces {
+			logrus.Infof("Deleting stale namespace %s of cluster %q", namespace, cluster)
+			if err := client.DeleteNamespace(clients[cluster], namespace); err != nil {
+				return err
+			}
+		}
+	}
+	for cluster, namespaces := range stale {
+		for _, namespace := range namespaces {
+			if err := client.WaitForDeleteNamespace(clients[cluster], namespace); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// automanagedNamespacePattern returns pattern matching automanaged namespaces of any run.
+func automanagedNamespacePattern(namespaceConfig *config.NamespaceConfig) (*regexp.Regexp, error) {
+	nameTemplate := namespaceConfig.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = framework.DefaultNamespaceNameTemplate
+	}
+	naming, err := framework.NewNamespaceNaming(nameTemplate, namespaceConfig.Prefix, namespaceConfig.IndexWidth, namespaceConfig.StartIndex)
+	if err != nil {
+		return nil, fmt.Errorf("namespace naming error: %v", err)
+	}
+	pattern, err := naming.AnyPrefixPattern()
+	if err != nil {
+		return nil, fmt.Errorf("automanaged namespace pattern error: %v", err)
+	}
+	return pattern, nil
+}
+
+// redirectLogs configures the standard logger to log as given logger and returns function restoring
+// the previous configuration.
+func redirectLogs(logger *logrus.Logger) func() {
+	std := logrus.StandardLogger()
+	out, formatter, level, hooks := std.Out, std.Formatter, std.GetLevel(), std.Hooks
+	std.SetOutput(logger.Out)
+	std.SetFormatter(logger.Formatter)
+	std.SetLevel(logger.GetLevel())
+	std.ReplaceHooks(logger.Hooks)
+	return func() {
+		std.SetOutput(out)
+		std.SetFormatter(formatter)
+		std.SetLevel(level)
+		std.ReplaceHooks(hooks)
+	}
+}
+
+func printTestStart(name string) {
+	logrus.Infof(dashLine)
+	logrus.Infof("Running %v", name)
+	logrus.Infof(dashLine)
+}
+
+func printTestResult(name, status, errors string) {
+	logf := logrus.Infof
+	if errors != "" {
+		logf = logrus.Errorf
+	}
+	logf(dashLine)
+	logf("Test Finished")
+	logf("  Test: %v", name)
+	logf("  Status: %v", status)
+	if errors != "" {
+		logf("  Errors: %v", errors)
+	}
+	logf(dashLine)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/test"
+)
+
+func TestResultExitCode(t *testing.T) {
+	result := &Result{Tests: []*test.Result{{Test: "a", Errors: errors.NewErrorList()}}}
+	assert.Equal(t, 0, result.ExitCode())
+
+	result.Tests = append(result.Tests, &test.Result{Test: "b", Errors: errors.NewErrorList(fmt.Errorf("error")), Category: test.SLOViolation})
+	result.Failed = 1
+	assert.Equal(t, test.SLOViolation.ExitCode(), result.ExitCode())
+}
+
+func TestRedirectLogs(t *testing.T) {
+	std := logrus.StandardLogger()
+	out := std.Out
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+
+	restore := redirectLogs(logger)
+	logrus.Infof("redirected")
+	restore()
+
+	assert.Contains(t, buf.String(), "redirected")
+	assert.Equal(t, out, std.Out)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selfmetrics exposes metrics of clusterloader itself (API calls of its clients,
+// queued and in-flight operations, memory), so that bottlenecks of the test driver
+// can be distinguished from bottlenecks of the cluster.
+package selfmetrics
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+)
+
+const (
+	namespace = "clusterloader"
+	// errorCode is the code label of API requests failed without a response, e.g. on timeouts.
+	errorCode = "error"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Number of API requests sent by clusterloader clients, by verb and response code. Requests failed without a response have code \"error\".",
+	}, []string{"verb", "code"})
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of API requests sent by clusterloader clients, as seen by the driver, by verb.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"verb"})
+	phaseOperationsQueued = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "phase_operations_queued",
+		Help:      "Number of object operations of running phases not started yet, e.g. waiting for the tuning set or paused load.",
+	})
+	phaseOperationsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "phase_operations_in_flight",
+		Help:      "Number of object operations of running phases in progress.",
+	})
+	measurementCallsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "measurement_calls_in_flight",
+		Help:      "Number of measurement calls in progress, e.g. waits for pods to be running, by measurement method and action.",
+	}, []string{"method", "action"})
+	apiRetries = &retriesCollector{
+		desc: prometheus.NewDesc(namespace+"_api_retries_total", "Number of retries of API calls failed with retryable errors, by category of the error.", []string{"category"}, nil),
+	}
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		apiRequests,
+		apiRequestDuration,
+		phaseOperationsQueued,
+		phaseOperationsInFlight,
+		measurementCallsInFlight,
+		apiRetries,
+	)
+}
+
+// InitFlags initializes self-metrics flags.
+func InitFlags(s *config.SelfMetricsConfig) {
+	flags.StringEnvVar(&s.Address, "self-metrics-address", "SELF_METRICS_ADDRESS", "", "Address (e.g. :8089) metrics of clusterloader itself (API calls of its clients, queued and in-flight operations, memory) are served on at /metrics. If empty, the metrics are not served.")
+	flags.BoolEnvVar(&s.Scrape, "prometheus-scrape-clusterloader", "PROMETHEUS_SCRAPE_CLUSTERLOADER", false, "Whether the prometheus server set up in the cluster should scrape metrics of clusterloader itself. Requires self-metrics-address and self-metrics-ip.")
+	flags.StringEnvVar(&s.IP, "self-metrics-ip", "SELF_METRICS_IP", "", "IP of the machine running clusterloader, reachable from the cluster, used by the prometheus server to scrape metrics of clusterloader.")
+}
+
+// Port returns the port of the self-metrics address.
+func Port(s *config.SelfMetricsConfig) (int, error) {
+	_, port, err := net.SplitHostPort(s.Address)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(port)
+}
+
+// Handler returns handler serving self-metrics in the format negotiated with the scraper.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metricFamilies, err := registry.Gather()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		format := expfmt.Negotiate(r.Header)
+		w.Header().Set("Content-Type", string(format))
+		encoder := expfmt.NewEncoder(w, format)
+		for _, metricFamily := range metricFamilies {
+			if err := encoder.Encode(metricFamily); err != nil {
+				logrus.Errorf("Writing self-metrics response error: %v", err)
+				return
+			}
+		}
+	})
+}
+
+// Serve serves self-metrics at /metrics on the given address in the background.
+func Serve(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go func() {
+		logrus.Infof("Serving self-metrics on %s", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			logrus.Errorf("Self-metrics server error: %v", err)
+		}
+	}()
+}
+
+// InstrumentRoundTripper wraps the round tripper of a client, recording count and latency of its requests.
+func InstrumentRoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &instrumentedRoundTripper{next: rt}
+}
+
+type instrumentedRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (i *instrumentedRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := i.next.RoundTrip(request)
+	apiRequestDuration.WithLabelValues(request.Method).Observe(time.Since(start).Seconds())
+	code := errorCode
+	if err == nil {
+		code = strconv.Itoa(response.StatusCode)
+	}
+	apiRequests.WithLabelValues(request.Method, code).Inc()
+	return response, err
+}
+
+// PhaseOperationsQueued records that n operations of a phase were queued (or unqueued if n is negative).
+func PhaseOperationsQueued(n int) {
+	phaseOperationsQueued.Add(float64(n))
+}
+
+// PhaseOperationStarted records that a queued operation of a phase was started.
+// The returned function should be called once the operation is finished.
+func PhaseOperationStarted() func() {
+	phaseOperationsQueued.Dec()
+	phaseOperationsInFlight.Inc()
+	return phaseOperationsInFlight.Dec
+}
+
+// MeasurementCallStarted records that a call of the measurement was started.
+// The returned function should be called once the call is finished.
+func MeasurementCallStarted(method, action string) func() {
+	gauge := measurementCallsInFlight.WithLabelValues(method, action)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// retriesCollector exposes retry counts of API calls recorded by the client package.
+type retriesCollector struct {
+	desc *prometheus.Desc
+}
+
+func (r *retriesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.desc
+}
+
+func (r *retriesCollector) Collect(ch chan<- prometheus.Metric) {
+	for category, count := range client.GetRetryCounts() {
+		ch <- prometheus.MustNewConstMetric(r.desc, prometheus.CounterValue, float64(count), category)
+	}
+}
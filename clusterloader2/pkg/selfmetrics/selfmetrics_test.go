@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfmetrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+func TestHandler(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer apiServer.Close()
+	client := &http.Client{Transport: InstrumentRoundTripper(http.DefaultTransport)}
+	response, err := client.Get(apiServer.URL)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	response.Body.Close()
+
+	PhaseOperationsQueued(2)
+	finishOperation := PhaseOperationStarted()
+	finishCall := MeasurementCallStarted("WaitForControlledPodsRunning", "gather")
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	response, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("scraping error: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("reading response error: %v", err)
+	}
+
+	finishOperation()
+	finishCall()
+	PhaseOperationsQueued(-1)
+
+	for _, metric := range []string{
+		`clusterloader_api_requests_total{code="429",verb="GET"} 1`,
+		`clusterloader_api_request_duration_seconds_count{verb="GET"} 1`,
+		`clusterloader_phase_operations_queued 1`,
+		`clusterloader_phase_operations_in_flight 1`,
+		`clusterloader_measurement_calls_in_flight{action="gather",method="WaitForControlledPodsRunning"} 1`,
+		`go_memstats_alloc_bytes`,
+	} {
+		assert.Contains(t, string(body), metric)
+	}
+}
+
+func TestPort(t *testing.T) {
+	port, err := Port(&config.SelfMetricsConfig{Address: ":8089"})
+	assert.NoError(t, err)
+	assert.Equal(t, 8089, port)
+
+	_, err = Port(&config.SelfMetricsConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+const (
+	gcsEndpoint = "https://storage.googleapis.com"
+	gcsScope    = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcsSink uploads summaries to a GCS bucket using JSON API. GCP_SERVICE_ACCOUNT_KEY credential
+// or, if it isn't provided, application default credentials are used.
+type gcsSink struct {
+	endpoint string
+	bucket   string
+	prefix   string
+	client   *http.Client
+}
+
+func newGCSSink(bucket, prefix string) (*gcsSink, error) {
+	client, err := credentials.GoogleClient(context.Background(), gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCS client error: %v", err)
+	}
+	client.Timeout = uploadTimeout
+	return &gcsSink{endpoint: gcsEndpoint, bucket: bucket, prefix: prefix, client: client}, nil
+}
+
+func (g *gcsSink) Write(fileName string, summary measurement.Summary) error {
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", objectName(g.prefix, fileName))
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", g.endpoint, url.PathEscape(g.bucket), query.Encode())
+	request, err := http.NewRequest(http.MethodPost, uploadURL, strings.NewReader(summary.SummaryContent()))
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", contentType(summary))
+	return doUpload(g.client, request, fileName)
+}
+
+func (g *gcsSink) String() string {
+	return "gs://" + objectName(g.bucket, g.prefix)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	uploadTimeout = time.Minute
+)
+
+// httpSink uploads summaries with PUT requests to <endpoint>/<file name>.
+type httpSink struct {
+	endpoint   string
+	authHeader string
+	client     *http.Client
+}
+
+func newHTTPSink(endpoint, authHeader string) *httpSink {
+	return &httpSink{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		authHeader: authHeader,
+		client:     &http.Client{Timeout: uploadTimeout},
+	}
+}
+
+func (h *httpSink) Write(fileName string, summary measurement.Summary) error {
+	request, err := http.NewRequest(http.MethodPut, h.endpoint+"/"+url.PathEscape(fileName), strings.NewReader(summary.SummaryContent()))
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", contentType(summary))
+	if h.authHeader != "" {
+		request.Header.Set("Authorization", h.authHeader)
+	}
+	return doUpload(h.client, request, fileName)
+}
+
+func (h *httpSink) String() string {
+	return h.endpoint
+}
+
+// doUpload sends the request and verifies that the upload succeeded.
+func doUpload(client *http.Client, request *http.Request, fileName string) error {
+	if err := util.SendHTTPRequest(client, request); err != nil {
+		return fmt.Errorf("uploading %s error: %v", fileName, err)
+	}
+	return nil
+}
+
+func contentType(summary measurement.Summary) string {
+	switch summary.SummaryExt() {
+	case "json":
+		return "application/json"
+	case "csv":
+		return "text/csv"
+	case "xml":
+		return "application/xml"
+	case "html":
+		return "text/html"
+	case "tar.gz":
+		return "application/gzip"
+	default:
+		return "text/plain"
+	}
+}
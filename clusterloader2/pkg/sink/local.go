@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+// localSink writes summaries to files in the report directory.
+type localSink struct {
+	dir string
+}
+
+func (l *localSink) Write(fileName string, summary measurement.Summary) error {
+	filePath := path.Join(l.dir, fileName)
+	if err := ioutil.WriteFile(filePath, []byte(summary.SummaryContent()), 0644); err != nil {
+		return fmt.Errorf("writing to file %v error: %v", filePath, err)
+	}
+	return nil
+}
+
+func (l *localSink) String() string {
+	return l.dir
+}
+
+// logSink prints summaries to the log.
+type logSink struct{}
+
+func (*logSink) Write(fileName string, summary measurement.Summary) error {
+	logrus.Infof("%v: %v", summary.SummaryName(), summary.SummaryContent())
+	return nil
+}
+
+func (*logSink) String() string {
+	return "log"
+}
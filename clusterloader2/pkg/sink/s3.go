@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/rest"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	s3Service       = "s3"
+)
+
+// s3Sink uploads summaries to a S3 bucket. Requests are signed with AWS Signature Version 4
+// using credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and (optional) AWS_SESSION_TOKEN
+// environment variables. The region is taken from AWS_REGION or AWS_DEFAULT_REGION.
+type s3Sink struct {
+	endpoint string
+	region   string
+	bucket   string
+	prefix   string
+	signer   *v4.Signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newS3Sink(bucket, prefix string) (*s3Sink, error) {
+	credentials, err := util.GetAWSCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("S3 sink credentials error: %v", err)
+	}
+	region := util.GetAWSRegion(defaultS3Region)
+	return &s3Sink{
+		endpoint: fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region),
+		region:   region,
+		bucket:   bucket,
+		prefix:   prefix,
+		signer:   newS3Signer(credentials),
+		client:   &http.Client{Timeout: uploadTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// newS3Signer creates signer of S3 requests. Object names are escaped once, when the URL is built,
+// so the signer mustn't escape the path again.
+func newS3Signer(credentials *awscredentials.Credentials) *v4.Signer {
+	return v4.NewSigner(credentials, func(s *v4.Signer) {
+		s.DisableURIPathEscaping = true
+	})
+}
+
+func (s *s3Sink) Write(fileName string, summary measurement.Summary) error {
+	body := strings.NewReader(summary.SummaryContent())
+	path := "/" + rest.EscapePath(objectName(s.prefix, fileName), false)
+	request, err := http.NewRequest(http.MethodPut, s.endpoint+path, body)
+	if err != nil {
+		return fmt.Errorf("request creation error: %v", err)
+	}
+	request.Header.Set("Content-Type", contentType(summary))
+	if _, err := s.signer.Sign(request, body, s3Service, s.region, s.now()); err != nil {
+		return fmt.Errorf("signing request error: %v", err)
+	}
+	return doUpload(s.client, request, fileName)
+}
+
+func (s *s3Sink) String() string {
+	return "s3://" + objectName(s.bucket, s.prefix)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+// Sink stores summaries created by measurements.
+type Sink interface {
+	// Write stores the summary under given file name.
+	Write(fileName string, summary measurement.Summary) error
+	String() string
+}
+
+// InitFlags initializes summary sink flags.
+func InitFlags(s *config.SummarySinkConfig) {
+	flags.StringSliceEnvVar(&s.URLs, "summary-sink-urls", "SUMMARY_SINK_URLS", nil /*defaultValue*/, "URLs of locations where summaries should be uploaded, besides the report directory. Supported schemes are gs://bucket/prefix, s3://bucket/prefix and http(s)://host/path. Supports multiple values when separated by commas")
+	flags.StringEnvVar(&s.AuthHeader, "summary-sink-auth-header", "SUMMARY_SINK_AUTH_HEADER", "", "Value of the Authorization header sent to http(s) summary sinks, e.g. 'Bearer <token>'.")
+}
+
+// NewSinks creates sinks based on the provided config. Summaries are always written
+// to the report directory or, if it's not set, to the log.
+func NewSinks(reportDir string, s *config.SummarySinkConfig) ([]Sink, error) {
+	var sinks []Sink
+	if reportDir == "" {
+		sinks = append(sinks, &logSink{})
+	} else {
+		sinks = append(sinks, &localSink{dir: reportDir})
+	}
+	for _, rawURL := range s.URLs {
+		sink, err := newSink(rawURL, s.AuthHeader)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func newSink(rawURL, authHeader string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing summary sink url %q error: %v", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("summary sink url %q: missing bucket", rawURL)
+		}
+		return newGCSSink(u.Host, prefix)
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("summary sink url %q: missing bucket", rawURL)
+		}
+		return newS3Sink(u.Host, prefix)
+	case "http", "https":
+		if authHeader == "" {
+			if authHeader, err = credentials.Get(credentials.SummarySinkAuthHeader); err != nil {
+				return nil, err
+			}
+		}
+		return newHTTPSink(rawURL, authHeader), nil
+	default:
+		return nil, fmt.Errorf("summary sink url %q: unsupported scheme %q", rawURL, u.Scheme)
+	}
+}
+
+// objectName joins prefix and file name into name of an object in a bucket.
+func objectName(prefix, fileName string) string {
+	if prefix == "" {
+		return fileName
+	}
+	return prefix + "/" + fileName
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sink
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestNewSinks(t *testing.T) {
+	sinks, err := NewSinks("", &config.SummarySinkConfig{})
+	assert.NoError(t, err)
+	assert.Len(t, sinks, 1)
+	assert.IsType(t, &logSink{}, sinks[0])
+
+	sinks, err = NewSinks("/tmp/reports", &config.SummarySinkConfig{URLs: []string{"https://example.com/results"}})
+	assert.NoError(t, err)
+	assert.Len(t, sinks, 2)
+	assert.IsType(t, &localSink{}, sinks[0])
+	assert.IsType(t, &httpSink{}, sinks[1])
+
+	for _, rawURL := range []string{"ftp://host/path", "gs:///prefix", "s3:///prefix"} {
+		if _, err := NewSinks("", &config.SummarySinkConfig{URLs: []string{rawURL}}); err == nil {
+			t.Errorf("%s: expected error", rawURL)
+		}
+	}
+}
+
+func TestHTTPSinkWrite(t *testing.T) {
+	var gotPath, gotContentType, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotPath, gotContentType, gotAuth, gotBody = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), string(body)
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	sink := newHTTPSink(server.URL+"/results/", "Bearer token")
+	err := sink.Write("PodStartupLatency_density.json", measurement.CreateSummary("PodStartupLatency", "json", `{"version": "v1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "/results/PodStartupLatency_density.json", gotPath)
+	assert.Equal(t, "application/json", gotContentType)
+	assert.Equal(t, "Bearer token", gotAuth)
+	assert.Equal(t, `{"version": "v1"}`, gotBody)
+}
+
+func TestHTTPSinkWriteFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "access denied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := newHTTPSink(server.URL, "").Write("summary.txt", measurement.CreateSummary("Profile", "txt", "data"))
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected error containing response body, got: %v", err)
+	}
+}
+
+func TestS3SinkWrite(t *testing.T) {
+	var gotPath, gotAuth, gotDate, gotHash string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		gotDate, gotHash = r.Header.Get("x-amz-date"), r.Header.Get("x-amz-content-sha256")
+	}))
+	defer server.Close()
+
+	sink := &s3Sink{
+		endpoint: server.URL,
+		region:   "eu-west-1",
+		bucket:   "bucket",
+		prefix:   "runs/1",
+		signer:   newS3Signer(awscredentials.NewStaticCredentials("AKID", "secret", "")),
+		client:   server.Client(),
+		now:      func() time.Time { return time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC) },
+	}
+	err := sink.Write("Profile_2019-05-01T10:00:00Z.txt", measurement.CreateSummary("Profile", "txt", "data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/runs/1/Profile_2019-05-01T10%3A00%3A00Z.txt", gotPath)
+	assert.Equal(t, "20190501T100000Z", gotDate)
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", gotHash)
+	assert.True(t, strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/20190501/eu-west-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="), gotAuth)
+	assert.Equal(t, "s3://bucket/runs/1", sink.String())
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// PhaseRecord describes a single executed phase.
+type PhaseRecord struct {
+	// Step is the name of the step the phase belongs to.
+	Step string
+	// Phase is the index of the phase within the step.
+	Phase int
+	// Namespaces are namespaces in which objects were reconciled.
+	Namespaces []string
+	// Objects maps object basename to the number of its replicas per namespace.
+	Objects map[string]int32
+	Start   time.Time
+	End     time.Time
+}
+
+// phasesState represents history of executed phases.
+type phasesState struct {
+	lock    sync.Mutex
+	records []*PhaseRecord
+}
+
+// newPhasesState creates new phases state.
+func newPhasesState() *phasesState {
+	return &phasesState{}
+}
+
+// Add stores information about executed phase.
+func (ps *phasesState) Add(record *PhaseRecord) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	ps.records = append(ps.records, record)
+}
+
+// List returns all executed phases in the order of their completion.
+func (ps *phasesState) List() []*PhaseRecord {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return append([]*PhaseRecord(nil), ps.records...)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"sort"
+)
+
+// Snapshot is a serializable copy of the state, so that the state can be restored
+// by a test resumed after the test driver was interrupted.
+type Snapshot struct {
+	Instances []InstancesSnapshot `json:"instances"`
+	Phases    []*PhaseRecord      `json:"phases"`
+}
+
+// InstancesSnapshot is a state of object replicas in a single namespace.
+type InstancesSnapshot struct {
+	Namespace  string              `json:"namespace"`
+	Identifier InstancesIdentifier `json:"identifier"`
+	State      InstancesState      `json:"state"`
+}
+
+// Snapshot returns a copy of namespaces state and history of executed phases.
+func (s *State) Snapshot() *Snapshot {
+	return &Snapshot{
+		Instances: s.namespacesState.snapshot(),
+		Phases:    s.phasesState.List(),
+	}
+}
+
+// Restore adds namespaces state and history of executed phases of the snapshot to the state.
+// It's meant to be called on a new state.
+func (s *State) Restore(snapshot *Snapshot) {
+	for i := range snapshot.Instances {
+		instances := snapshot.Instances[i].State
+		s.namespacesState.Set(snapshot.Instances[i].Namespace, snapshot.Instances[i].Identifier, &instances)
+	}
+	for _, record := range snapshot.Phases {
+		s.phasesState.Add(record)
+	}
+}
+
+func (ns *namespacesState) snapshot() []InstancesSnapshot {
+	ns.lock.RLock()
+	defer ns.lock.RUnlock()
+	instances := make([]InstancesSnapshot, 0)
+	for namespace, namespaceState := range ns.namespaceStates {
+		for identifier, state := range namespaceState {
+			instances = append(instances, InstancesSnapshot{Namespace: namespace, Identifier: identifier, State: *state})
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Namespace != instances[j].Namespace {
+			return instances[i].Namespace < instances[j].Namespace
+		}
+		if instances[i].Identifier.Basename != instances[j].Identifier.Basename {
+			return instances[i].Identifier.Basename < instances[j].Identifier.Basename
+		}
+		return instances[i].Identifier.ObjectKind < instances[j].Identifier.ObjectKind
+	})
+	return instances
+}
@@ -17,10 +17,11 @@ limitations under the License.
 package state
 
 // State is a state of the cluster.
-// It is composed of namespaces state and resources versions state.
+// It is composed of namespaces state, resources versions state and history of executed phases.
 type State struct {
 	namespacesState       *namespacesState
 	resourcesVersionState *resourcesVersionsState
+	phasesState           *phasesState
 }
 
 // NewState creates new State instance.
@@ -28,6 +29,7 @@ func NewState() *State {
 	return &State{
 		namespacesState:       newNamespacesState(),
 		resourcesVersionState: newResourcesVersionsState(),
+		phasesState:           newPhasesState(),
 	}
 }
 
@@ -40,3 +42,8 @@ func (s *State) GetNamespacesState() *namespacesState {
 func (s *State) GetResourcesVersionState() *resourcesVersionsState {
 	return s.resourcesVersionState
 }
+
+// GetPhasesState returns history of executed phases.
+func (s *State) GetPhasesState() *phasesState {
+	return s.phasesState
+}
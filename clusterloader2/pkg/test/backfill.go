@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement/common/slos"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// Backfill regenerates summaries of Prometheus-based measurements of the test without running it.
+// Every measurement gathered by the test is evaluated with its current params against data
+// available to the executor in time window between startTime and endTime. Markers recorded
+// by the original run can be provided, so that measurements using them evaluate the same windows.
+// Other measurements are skipped.
+func Backfill(clusterLoaderConfig *config.ClusterLoaderConfig, executor slos.QueryExecutor, startTime, endTime time.Time, markers *measurement.Markers) *errors.ErrorList {
+	mapping, errList := config.GetMapping(clusterLoaderConfig)
+	if errList != nil {
+		return errList
+	}
+	templateProvider := config.NewTemplateProvider(filepath.Dir(clusterLoaderConfig.TestScenario.ConfigPath))
+	testConfig, err := templateProvider.TemplateToConfig(filepath.Base(clusterLoaderConfig.TestScenario.ConfigPath), mapping)
+	if err != nil {
+		return errors.NewErrorList(fmt.Errorf("config reading error: %v", err))
+	}
+
+	errList = errors.NewErrorList()
+	var summaries []measurement.Summary
+	for i := range testConfig.Steps {
+		for _, m := range testConfig.Steps[i].Measurements {
+			action, err := util.GetStringOrDefault(m.Params, "action", "")
+			if err != nil {
+				errList.Append(fmt.Errorf("%s: %v", m.Identifier, err))
+				continue
+			}
+			if action != "gather" {
+				continue
+			}
+			if !slos.IsPrometheusMeasurement(m.Method) {
+				logrus.Infof("%s: %s is not a Prometheus-based measurement, skipping", m.Identifier, m.Method)
+				continue
+			}
+			measurementConfig := &measurement.MeasurementConfig{
+				Params:              m.Params,
+				TemplateProvider:    templateProvider,
+				ClusterLoaderConfig: clusterLoaderConfig,
+				Identifier:          m.Identifier,
+				CloudProvider:       clusterLoaderConfig.ClusterConfig.Provider,
+				Markers:             markers,
+			}
+			summary, err := slos.Backfill(m.Method, executor, startTime, endTime, measurementConfig)
+			if err != nil {
+				enableViolations, _ := util.GetBoolOrDefault(m.Params, "enableViolations", false)
+				if !errors.IsMetricViolationError(err) || enableViolations {
+					errList.Append(fmt.Errorf("%s: %v", m.Identifier, err))
+				}
+			}
+			if summary != nil {
+				summaries = append(summaries, summary)
+			}
+		}
+	}
+	errList.Concat(writeSummaries(clusterLoaderConfig, testConfig.Name, summaries))
+	return errList
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/capacity"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// podTemplate describes pods created by a single object.
+type podTemplate struct {
+	spec     corev1.PodSpec
+	replicas int
+	perNode  bool
+}
+
+type plannedObject struct {
+	object   *api.Object
+	replicas int32
+}
+
+// checkCapacity verifies that pods created by the test fit into the cluster.
+// Test steps are replayed without creating any objects and after every step
+// pods that are expected to exist are placed on schedulable nodes.
+// Objects are templated once, with replica index 0, so templates
+// that vary resource requests between replicas are approximated.
+func checkCapacity(ctx Context, conf *api.Config) error {
+	c := ctx.GetClusterFramework().GetClientSets().GetClient()
+	// Tainted nodes are included, pods tolerating their taints can be placed on them.
+	nodes, err := util.GetSchedulableNodes(c)
+	if err != nil {
+		return fmt.Errorf("listing nodes error: %v", err)
+	}
+	pods, err := client.ListPodsWithOptions(c, metav1.NamespaceAll, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing pods error: %v", err)
+	}
+
+	templates := make(map[*api.Object]*podTemplate)
+	planned := make(map[string]plannedObject)
+	for i := range conf.Steps {
+		step := &conf.Steps[i]
+		if len(step.Phases) == 0 {
+			continue
+		}
+		for j := range step.Phases {
+			phase := &step.Phases[j]
+			for _, namespace := range createNamespacesList(ctx, phase.NamespaceRange) {
+				for k := range phase.ObjectBundle {
+					object := &phase.ObjectBundle[k]
+					planned[namespace+"/"+object.Basename] = plannedObject{object: object, replicas: phase.ReplicasPerNamespace}
+				}
+			}
+		}
+		groups, err := createPodGroups(ctx, planned, templates)
+		if err != nil {
+			return err
+		}
+		if result := capacity.Estimate(nodes, pods, groups); !result.Fits() {
+			return fmt.Errorf("step %d (%q) does not fit into the cluster: %v", i, step.Name, result)
+		}
+	}
+	logrus.Infof("Capacity check passed: test pods fit into %d schedulable nodes", len(nodes))
+	return nil
+}
+
+func createPodGroups(ctx Context, planned map[string]plannedObject, templates map[*api.Object]*podTemplate) ([]capacity.PodGroup, error) {
+	counts := make(map[*api.Object]int)
+	for _, p := range planned {
+		counts[p.object] += int(p.replicas)
+	}
+	groups := make([]capacity.PodGroup, 0, len(counts))
+	for object, count := range counts {
+		if count == 0 {
+			continue
+		}
+		template, ok := templates[object]
+		if !ok {
+			var err error
+			if template, err = getPodTemplate(ctx, object); err != nil {
+				return nil, err
+			}
+			templates[object] = template
+		}
+		if template == nil {
+			continue
+		}
+		groups = append(groups, capacity.PodGroup{
+			Name:         fmt.Sprintf("%s (%s)", object.Basename, object.ObjectTemplatePath),
+			Count:        count * template.replicas,
+			PerNode:      template.perNode,
+			Requests:     capacity.GetPodRequests(&template.spec),
+			NodeSelector: template.spec.NodeSelector,
+			Tolerations:  template.spec.Tolerations,
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// getPodTemplate returns pod template of the given object or nil if the object does not create pods.
+func getPodTemplate(ctx Context, object *api.Object) (*podTemplate, error) {
+	mapping := ctx.GetTemplateMappingCopy()
+	if object.TemplateFillMap != nil {
+		util.CopyMap(object.TemplateFillMap, mapping)
+	}
+	mapping[baseNamePlaceholder] = object.Basename
+	mapping[namePlaceholder] = fmt.Sprintf("%v-%d", object.Basename, 0)
+	mapping[indexPlaceholder] = int32(0)
+	obj, err := ctx.GetTemplateProvider().TemplateToObject(object.ObjectTemplatePath, mapping)
+	if err == config.ErrorEmptyFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading template (%v) error: %v", object.ObjectTemplatePath, err)
+	}
+
+	template := &podTemplate{replicas: 1}
+	specPath := []string{"spec", "template", "spec"}
+	switch obj.GetKind() {
+	case "Pod":
+		specPath = []string{"spec"}
+	case "DaemonSet":
+		template.perNode = true
+	case "Job":
+		if err := getDefaultedInt(obj, &template.replicas, "spec", "parallelism"); err != nil {
+			return nil, err
+		}
+	case "ReplicationController", "ReplicaSet", "Deployment", "StatefulSet":
+		if err := getDefaultedInt(obj, &template.replicas, "spec", "replicas"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	spec, found, err := unstructured.NestedMap(obj.Object, specPath...)
+	if err != nil || !found {
+		return nil, fmt.Errorf("%s: getting pod spec error: %v", object.ObjectTemplatePath, err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &template.spec); err != nil {
+		return nil, fmt.Errorf("%s: pod spec conversion error: %v", object.ObjectTemplatePath, err)
+	}
+	return template, nil
+}
+
+// getDefaultedInt sets value to the int field under given path, if it exists.
+func getDefaultedInt(obj *unstructured.Unstructured, value *int, fields ...string) error {
+	v, found, err := unstructured.NestedInt64(obj.Object, fields...)
+	if err != nil {
+		return fmt.Errorf("%s: getting %v error: %v", obj.GetKind(), fields, err)
+	}
+	if found {
+		*value = int(v)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const eventsDumpName = "EventsDump"
+
+// eventsDump contains events collected during the failed test.
+type eventsDump struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Dropped is the number of events that didn't fit into the buffer of the collector.
+	Dropped int               `json:"dropped"`
+	Events  []eventsDumpEntry `json:"events"`
+}
+
+type eventsDumpEntry struct {
+	Namespace      string    `json:"namespace"`
+	InvolvedObject string    `json:"involvedObject"`
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Source         string    `json:"source"`
+	Message        string    `json:"message"`
+	Count          int32     `json:"count"`
+	LastSeen       time.Time `json:"lastSeen"`
+}
+
+func newEventsDumpEntry(event *v1.Event) eventsDumpEntry {
+	return eventsDumpEntry{
+		Namespace:      event.Namespace,
+		InvolvedObject: event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name,
+		Type:           event.Type,
+		Reason:         event.Reason,
+		Source:         event.Source.Component,
+		Message:        event.Message,
+		Count:          event.Count,
+		LastSeen:       framework.EventLastSeen(event),
+	}
+}
+
+// createEventsDumpSummary creates summary with all events collected by the collector.
+func createEventsDumpSummary(collector *framework.EventCollector) (measurement.Summary, error) {
+	dump := &eventsDump{
+		Namespace: collector.GetNamespace(),
+		Dropped:   collector.GetDropped(),
+		Events:    []eventsDumpEntry{},
+	}
+	for _, event := range collector.Query(framework.EventQuery{}) {
+		dump.Events = append(dump.Events, newEventsDumpEntry(event))
+	}
+	content, err := util.PrettyPrintJSON(dump)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(eventsDumpName, "json", content), nil
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package test
 
 import (
+	"context"
+
 	"k8s.io/perf-tests/clusterloader2/api"
 	"k8s.io/perf-tests/clusterloader2/pkg/chaos"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/state"
 	"k8s.io/perf-tests/clusterloader2/pkg/tuningset"
 )
@@ -46,6 +50,8 @@ const (
 // Context is an interface for test context.
 // Test context provides framework client and cluster state.
 type Context interface {
+	// GetContext returns context of the test, which is done once the test is cancelled.
+	GetContext() context.Context
 	GetClusterLoaderConfig() *config.ClusterLoaderConfig
 	GetClusterFramework() *framework.Framework
 	GetPrometheusFramework() *framework.Framework
@@ -55,6 +61,14 @@ type Context interface {
 	GetTuningSetFactory() tuningset.TuningSetFactory
 	GetMeasurementManager() *measurement.MeasurementManager
 	GetChaosMonkey() *chaos.Monkey
+	GetGrafanaAnnotator() *prometheus.GrafanaAnnotator
+	// GetNotifier returns notifier of the run, nil if notifications are disabled.
+	GetNotifier() notifier.Notifier
+	// GetClusterContext returns context in which the cluster framework and the state are these
+	// of the named cluster. Empty name means the tested cluster.
+	GetClusterContext(name string) (Context, error)
+	// GetClusterNames returns sorted names of additional clusters available to the test.
+	GetClusterNames() []string
 }
 
 // TestExecutor is an interface for test executing object.
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	ginkgoreporters "github.com/onsi/ginkgo/reporters"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+const (
+	// junitSummaryName is chosen so that files written by sinks match junit*.xml pattern used by CI systems.
+	junitSummaryName = "junit"
+
+	junitErrorType     = "MeasurementError"
+	junitViolationType = "SLOViolation"
+)
+
+// createJUnitSummary creates JUnit XML report, in which every measurement and every SLO violation
+// is a separate test case.
+func createJUnitSummary(testName string, results []measurement.MeasurementResult) (measurement.Summary, error) {
+	suite := ginkgoreporters.JUnitTestSuite{TestCases: []ginkgoreporters.JUnitTestCase{}}
+	for _, result := range results {
+		name := result.Method
+		if result.Identifier != "" {
+			name = fmt.Sprintf("%s (%s)", result.Method, result.Identifier)
+		}
+		testCase := ginkgoreporters.JUnitTestCase{
+			Name:      name,
+			ClassName: testName,
+			Time:      result.Duration.Seconds(),
+		}
+		switch {
+		case result.SkipReason != "":
+			testCase.Skipped = &ginkgoreporters.JUnitSkipped{}
+		case len(result.Errors) > 0:
+			messages := make([]string, 0, len(result.Errors))
+			for _, err := range result.Errors {
+				messages = append(messages, err.Error())
+			}
+			testCase.FailureMessage = &ginkgoreporters.JUnitFailureMessage{Type: junitErrorType, Message: strings.Join(messages, "\n")}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		for _, violation := range result.Violations {
+			suite.TestCases = append(suite.TestCases, ginkgoreporters.JUnitTestCase{
+				Name:           fmt.Sprintf("%s SLO %s", name, errors.GetViolatedMetric(violation)),
+				ClassName:      testName,
+				FailureMessage: &ginkgoreporters.JUnitFailureMessage{Type: junitViolationType, Message: violation.Error()},
+			})
+		}
+	}
+	for _, testCase := range suite.TestCases {
+		suite.Time += testCase.Time
+		if testCase.FailureMessage != nil {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.TestCases)
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("junit report marshaling error: %v", err)
+	}
+	return measurement.CreateSummary(junitSummaryName, "xml", xml.Header+string(content)), nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	ginkgoreporters "github.com/onsi/ginkgo/reporters"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestCreateJUnitSummary(t *testing.T) {
+	results := []measurement.MeasurementResult{
+		{Method: "APIResponsivenessPrometheus", Duration: time.Minute, Violations: []error{errors.NewMetricViolationError("top latency metric", "too high")}},
+		{Method: "WaitForControlledPodsRunning", Identifier: "WaitForRunningDeployments", Duration: 2 * time.Minute, Errors: []error{fmt.Errorf("timeout")}},
+		{Method: "PodStartupLatency", Identifier: "PodStartupLatency", Duration: time.Second},
+		{Method: "EtcdMetrics", Identifier: "EtcdMetrics", SkipReason: "missing SSH access"},
+	}
+	summary, err := createJUnitSummary("load", results)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "junit", summary.SummaryName())
+	assert.Equal(t, "xml", summary.SummaryExt())
+
+	var suite ginkgoreporters.JUnitTestSuite
+	if err := xml.Unmarshal([]byte(summary.SummaryContent()), &suite); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+	assert.Equal(t, 5, suite.Tests)
+	assert.Equal(t, 2, suite.Failures)
+	names := make([]string, 0, len(suite.TestCases))
+	for _, testCase := range suite.TestCases {
+		names = append(names, testCase.Name)
+		assert.Equal(t, "load", testCase.ClassName)
+	}
+	assert.Equal(t, []string{
+		"APIResponsivenessPrometheus",
+		"APIResponsivenessPrometheus SLO top latency metric",
+		"WaitForControlledPodsRunning (WaitForRunningDeployments)",
+		"PodStartupLatency (PodStartupLatency)",
+		"EtcdMetrics (EtcdMetrics)",
+	}, names)
+	assert.Nil(t, suite.TestCases[0].FailureMessage)
+	assert.Equal(t, "top latency metric: too high", suite.TestCases[1].FailureMessage.Message)
+	assert.Equal(t, "timeout", suite.TestCases[2].FailureMessage.Message)
+	assert.NotNil(t, suite.TestCases[4].Skipped)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// String returns name of the operation.
+func (o OperationType) String() string {
+	switch o {
+	case CREATE_OBJECT:
+		return "create"
+	case PATCH_OBJECT:
+		return "patch"
+	case DELETE_OBJECT:
+		return "delete"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(o))
+	}
+}
+
+// journalEntry describes a single object operation performed by a phase.
+type journalEntry struct {
+	Test       string    `json:"test"`
+	Operation  string    `json:"operation"`
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	Timestamp  time.Time `json:"timestamp"`
+	// LatencySeconds is the duration of the api call, including retries.
+	LatencySeconds float64 `json:"latencySeconds"`
+	// Error is empty if the operation succeeded.
+	Error string `json:"error,omitempty"`
+	// Object is the object sent to the apiserver, so that creations and patches can be replayed exactly.
+	Object map[string]interface{} `json:"object,omitempty"`
+}
+
+// operationJournal writes every object operation as a single line of JSON. All methods of nil journal are no-op.
+type operationJournal struct {
+	test    string
+	lock    sync.Mutex
+	writer  io.WriteCloser
+	encoder *json.Encoder
+	failed  bool
+}
+
+// openOperationJournal opens journal appending operations of the test to the file at the given path.
+// Nil journal is returned if the path is empty.
+func openOperationJournal(path, test string) (*operationJournal, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening operation journal error: %v", err)
+	}
+	return newOperationJournal(file, test), nil
+}
+
+func newOperationJournal(writer io.WriteCloser, test string) *operationJournal {
+	return &operationJournal{test: test, writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+// record adds operation that started at given time to the journal.
+func (j *operationJournal) record(operation OperationType, obj *unstructured.Unstructured, namespace, name string, start time.Time, err error) {
+	if j == nil {
+		return
+	}
+	latency := time.Since(start)
+	entry := &journalEntry{
+		Test:           j.test,
+		Operation:      operation.String(),
+		APIVersion:     obj.GetAPIVersion(),
+		Kind:           obj.GetKind(),
+		Namespace:      namespace,
+		Name:           name,
+		Timestamp:      start,
+		LatencySeconds: latency.Seconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if operation != DELETE_OBJECT {
+		entry.Object = obj.Object
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if err := j.encoder.Encode(entry); err != nil && !j.failed {
+		// Only the first error is logged, so that logs are not flooded.
+		j.failed = true
+		logrus.Errorf("Writing operation journal error: %v", err)
+	}
+}
+
+func (j *operationJournal) close() error {
+	if j == nil {
+		return nil
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.writer.Close()
+}
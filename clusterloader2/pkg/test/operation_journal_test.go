@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestOperationJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.jsonl")
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("apps/v1")
+	obj.SetKind("Deployment")
+	obj.SetName("deployment-1")
+	start := time.Now()
+
+	journal, err := openOperationJournal(path, "load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.record(CREATE_OBJECT, obj, "test-ns-1", "deployment-1", start, nil)
+	journal.record(DELETE_OBJECT, obj, "test-ns-1", "deployment-1", start, fmt.Errorf("not found"))
+	assert.NoError(t, journal.close())
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry journalEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "load", entries[0].Test)
+		assert.Equal(t, "create", entries[0].Operation)
+		assert.Equal(t, "Deployment", entries[0].Kind)
+		assert.Equal(t, "test-ns-1", entries[0].Namespace)
+		assert.Equal(t, "", entries[0].Error)
+		assert.Equal(t, "deployment-1", entries[0].Object["metadata"].(map[string]interface{})["name"])
+		assert.Equal(t, "delete", entries[1].Operation)
+		assert.Equal(t, "not found", entries[1].Error)
+		assert.Nil(t, entries[1].Object)
+	}
+}
+
+func TestOperationJournalDisabled(t *testing.T) {
+	journal, err := openOperationJournal("", "load")
+	assert.NoError(t, err)
+	assert.Nil(t, journal)
+	journal.record(CREATE_OBJECT, &unstructured.Unstructured{}, "", "", time.Now(), nil)
+	assert.NoError(t, journal.close())
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	measurementutil "k8s.io/perf-tests/clusterloader2/pkg/measurement/util"
+	"k8s.io/perf-tests/clusterloader2/pkg/state"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	phaseFootprintName = "PhaseResourceFootprint"
+
+	// %v should be replaced with the duration of the phase.
+	phaseRequestsQuery = "sum by (verb, resource) (increase(apiserver_request_total[%v]))"
+	etcdObjectsQuery   = "max by (resource) (etcd_object_counts)"
+)
+
+type queryExecutor interface {
+	Query(query string, queryTime time.Time) ([]*model.Sample, error)
+}
+
+// phaseFootprint describes load generated during a single phase.
+// Phases of the same step are executed in parallel, so the apiserver requests
+// and etcd object growth of concurrent phases cannot be told apart.
+type phaseFootprint struct {
+	Step  string    `json:"step"`
+	Phase int       `json:"phase"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Namespaces is the number of namespaces in which objects were reconciled.
+	Namespaces int `json:"namespaces"`
+	// Objects maps object basename to the number of its replicas per namespace.
+	Objects map[string]int32 `json:"objects"`
+	// ConcurrentPhases is the number of other phases that were running at the same time.
+	ConcurrentPhases int `json:"concurrentPhases"`
+	// ApiserverRequests maps verb and resource to the number of requests.
+	ApiserverRequests map[string]float64 `json:"apiserverRequests"`
+	// EtcdObjectGrowth maps resource to the change of the number of objects stored in etcd.
+	EtcdObjectGrowth map[string]float64 `json:"etcdObjectGrowth"`
+}
+
+func createPhaseRecord(ctx Context, stepName string, index int, phase *api.Phase, start time.Time) *state.PhaseRecord {
+	objects := make(map[string]int32)
+	for j := range phase.ObjectBundle {
+		objects[phase.ObjectBundle[j].Basename] = phase.ReplicasPerNamespace
+	}
+	return &state.PhaseRecord{
+		Step:       stepName,
+		Phase:      index,
+		Namespaces: createNamespacesList(ctx, phase.NamespaceRange),
+		Objects:    objects,
+		Start:      start,
+		End:        time.Now(),
+	}
+}
+
+// createPhaseFootprintSummary attributes apiserver requests and etcd object growth
+// observed by Prometheus to executed phases.
+func createPhaseFootprintSummary(ctx Context) (measurement.Summary, error) {
+	if ctx.GetPrometheusFramework() == nil {
+		return nil, fmt.Errorf("phase footprint requires Prometheus server")
+	}
+	executor, err := measurementutil.GetQueryExecutor(ctx.GetPrometheusFramework().GetClientSets().GetClient(),
+		&ctx.GetClusterLoaderConfig().PrometheusConfig, measurementutil.DefaultQueryRetryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	footprints, err := computePhaseFootprints(executor, ctx.GetState().GetPhasesState().List())
+	if err != nil {
+		return nil, err
+	}
+	content, err := util.PrettyPrintJSON(footprints)
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(phaseFootprintName, "json", content), nil
+}
+
+func computePhaseFootprints(executor queryExecutor, records []*state.PhaseRecord) ([]*phaseFootprint, error) {
+	footprints := make([]*phaseFootprint, 0, len(records))
+	for _, record := range records {
+		footprint := &phaseFootprint{
+			Step:              record.Step,
+			Phase:             record.Phase,
+			Start:             record.Start,
+			End:               record.End,
+			Namespaces:        len(record.Namespaces),
+			Objects:           record.Objects,
+			ApiserverRequests: make(map[string]float64),
+			EtcdObjectGrowth:  make(map[string]float64),
+		}
+		for _, other := range records {
+			if other != record && other.Start.Before(record.End) && record.Start.Before(other.End) {
+				footprint.ConcurrentPhases++
+			}
+		}
+
+		duration := record.End.Sub(record.Start)
+		if duration < time.Second {
+			duration = time.Second
+		}
+		samples, err := executor.Query(fmt.Sprintf(phaseRequestsQuery, measurementutil.ToPrometheusTime(duration)), record.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			footprint.ApiserverRequests[fmt.Sprintf("%s %s", sample.Metric["verb"], sample.Metric["resource"])] = float64(sample.Value)
+		}
+
+		before, err := executor.Query(etcdObjectsQuery, record.Start)
+		if err != nil {
+			return nil, err
+		}
+		after, err := executor.Query(etcdObjectsQuery, record.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range after {
+			footprint.EtcdObjectGrowth[string(sample.Metric["resource"])] += float64(sample.Value)
+		}
+		for _, sample := range before {
+			footprint.EtcdObjectGrowth[string(sample.Metric["resource"])] -= float64(sample.Value)
+		}
+		for resource, growth := range footprint.EtcdObjectGrowth {
+			if growth == 0 {
+				delete(footprint.EtcdObjectGrowth, resource)
+			}
+		}
+		footprints = append(footprints, footprint)
+	}
+	return footprints, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/state"
+)
+
+// fakeEtcdExecutor returns etcd object counts growing by 10 every minute since base time.
+type fakeEtcdExecutor struct {
+	base time.Time
+}
+
+func (f *fakeEtcdExecutor) Query(query string, queryTime time.Time) ([]*model.Sample, error) {
+	if query == etcdObjectsQuery {
+		minutes := queryTime.Sub(f.base).Minutes()
+		return []*model.Sample{
+			{Metric: model.Metric{"resource": "pods"}, Value: model.SampleValue(10 * minutes)},
+			{Metric: model.Metric{"resource": "nodes"}, Value: 100},
+		}, nil
+	}
+	return []*model.Sample{
+		{Metric: model.Metric{"verb": "POST", "resource": "pods"}, Value: 42},
+	}, nil
+}
+
+func TestComputePhaseFootprints(t *testing.T) {
+	base := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*state.PhaseRecord{
+		{Step: "create", Phase: 0, Namespaces: []string{"ns-1", "ns-2"}, Start: base, End: base.Add(2 * time.Minute)},
+		{Step: "create", Phase: 1, Start: base.Add(time.Minute), End: base.Add(3 * time.Minute)},
+		{Step: "delete", Phase: 0, Start: base.Add(4 * time.Minute), End: base.Add(5 * time.Minute)},
+	}
+	footprints, err := computePhaseFootprints(&fakeEtcdExecutor{base: base}, records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Len(t, footprints, 3)
+	assert.Equal(t, 2, footprints[0].Namespaces)
+	assert.Equal(t, []int{1, 1, 0}, []int{footprints[0].ConcurrentPhases, footprints[1].ConcurrentPhases, footprints[2].ConcurrentPhases})
+	assert.Equal(t, map[string]float64{"pods": 20}, footprints[0].EtcdObjectGrowth)
+	assert.Equal(t, map[string]float64{"POST pods": 42}, footprints[2].ApiserverRequests)
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/state"
+)
+
+// Progress is the progress of the run persisted in the state file, so that the run can be
+// resumed after the test driver crashed or was preempted. All methods of nil progress are no-op.
+type Progress struct {
+	// RunID identifies the run, namespaces created by the run are labeled with it.
+	RunID string `json:"runID"`
+	// CompletedTests are identifiers of finished tests of the run.
+	CompletedTests []string `json:"completedTests,omitempty"`
+	// Test is the progress of the test being executed, nil if there is none.
+	Test *TestProgress `json:"test,omitempty"`
+
+	path string
+}
+
+// TestProgress is the progress of a single test as of the end of its last completed step.
+type TestProgress struct {
+	ID              string `json:"id"`
+	NamespacePrefix string `json:"namespacePrefix"`
+	CompletedSteps  int    `json:"completedSteps"`
+	// Clusters are states of the tested cluster (empty name) and additional clusters by their names.
+	Clusters map[string]*state.Snapshot `json:"clusters"`
+	Markers  []measurement.Marker       `json:"markers,omitempty"`
+	// Measurements are measurements started, but not gathered yet.
+	Measurements []measurement.StartedMeasurement `json:"measurements,omitempty"`
+	UpdateTime   time.Time                        `json:"updateTime"`
+}
+
+// OpenProgress opens the state file at the given path and returns the progress and the id of the run.
+// If resume is set, progress persisted by the interrupted run is read and the id of that run is returned,
+// so that its namespaces are not considered stale. Otherwise, the state file is overwritten with
+// the progress of a new run. Nil progress (and the id of a new run) is returned if the path is empty.
+func OpenProgress(path string, resume bool) (*Progress, string, error) {
+	runID := client.NewRunID()
+	if path == "" {
+		return nil, runID, nil
+	}
+	if resume {
+		p, err := readProgress(path)
+		switch {
+		case err == nil:
+			logrus.Infof("Resuming run %s, completed tests: %v", p.RunID, p.CompletedTests)
+			return p, p.RunID, nil
+		case os.IsNotExist(err):
+			logrus.Warningf("State file %s doesn't exist, starting new run", path)
+		default:
+			return nil, "", err
+		}
+	}
+	p := &Progress{RunID: runID, path: path}
+	return p, runID, p.write()
+}
+
+func readProgress(path string) (*Progress, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Progress{path: path}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parsing state file error: %v", err)
+	}
+	return p, nil
+}
+
+// loadProgress reads progress of the run from the state file at the given path. If it doesn't exist,
+// e.g. the test isn't run by the runner, progress of the run with given id is returned. Nil progress
+// is returned if the path is empty.
+func loadProgress(path, runID string) (*Progress, error) {
+	if path == "" {
+		return nil, nil
+	}
+	p, err := readProgress(path)
+	if os.IsNotExist(err) {
+		return &Progress{RunID: runID, path: path}, nil
+	}
+	return p, err
+}
+
+// IsCompleted returns true if the test has already finished in the run.
+func (p *Progress) IsCompleted(testID string) bool {
+	if p == nil {
+		return false
+	}
+	for _, id := range p.CompletedTests {
+		if id == testID {
+			return true
+		}
+	}
+	return false
+}
+
+// getTestProgress returns progress of the test to resume it from, nil if the test wasn't started.
+func (p *Progress) getTestProgress(testID string) *TestProgress {
+	if p == nil || p.Test == nil || p.Test.ID != testID {
+		return nil
+	}
+	return p.Test
+}
+
+// updateTest persists progress of the test being executed.
+func (p *Progress) updateTest(test *TestProgress) error {
+	if p == nil {
+		return nil
+	}
+	test.UpdateTime = time.Now()
+	p.Test = test
+	return p.write()
+}
+
+// completeTest persists that the test has finished.
+func (p *Progress) completeTest(testID string) error {
+	if p == nil {
+		return nil
+	}
+	p.CompletedTests = append(p.CompletedTests, testID)
+	p.Test = nil
+	return p.write()
+}
+
+// write replaces the state file atomically, so that it's not corrupted if the run crashes while writing.
+func (p *Progress) write() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("state file marshaling error: %v", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("state file writing error: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("state file writing error: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("state file writing error: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return fmt.Errorf("state file writing error: %v", err)
+	}
+	return nil
+}
+
+// createTestProgress captures progress of the test after given number of completed steps.
+func createTestProgress(ctx Context, testID, namespacePrefix string, completedSteps int) *TestProgress {
+	test := &TestProgress{
+		ID:              testID,
+		NamespacePrefix: namespacePrefix,
+		CompletedSteps:  completedSteps,
+		Clusters:        map[string]*state.Snapshot{"": ctx.GetState().Snapshot()},
+		Markers:         ctx.GetMeasurementManager().GetMarkers().List(),
+		Measurements:    ctx.GetMeasurementManager().GetStartedMeasurements(),
+	}
+	for _, name := range ctx.GetClusterNames() {
+		if clusterCtx, err := ctx.GetClusterContext(name); err == nil {
+			test.Clusters[name] = clusterCtx.GetState().Snapshot()
+		}
+	}
+	return test
+}
+
+// restoreTestProgress restores states of clusters and markers of the interrupted test
+// and resumes measurements it started, but didn't gather.
+func restoreTestProgress(ctx Context, test *TestProgress) error {
+	for name, snapshot := range test.Clusters {
+		clusterCtx, err := ctx.GetClusterContext(name)
+		if err != nil {
+			return err
+		}
+		clusterCtx.GetState().Restore(snapshot)
+	}
+	for _, marker := range test.Markers {
+		if err := ctx.GetMeasurementManager().GetMarkers().Add(marker.Name, marker.Time); err != nil {
+			return err
+		}
+	}
+	for _, started := range test.Measurements {
+		if err := ctx.GetMeasurementManager().ResumeMeasurement(started); err != nil {
+			return fmt.Errorf("resuming measurement %s - %s error: %v", started.Method, started.Identifier, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/state"
+)
+
+func TestProgressResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	p, runID, err := OpenProgress(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := state.NewState()
+	identifier := state.InstancesIdentifier{Basename: "deployment", ObjectKind: "Deployment", ApiGroup: "apps"}
+	s.GetNamespacesState().Set("test-abc-1", identifier, &state.InstancesState{DesiredReplicaCount: 3, CurrentReplicaCount: 2})
+	start := time.Now().Add(-time.Hour).UTC().Round(time.Second)
+	assert.NoError(t, p.completeTest("first"))
+	assert.NoError(t, p.updateTest(&TestProgress{
+		ID:              "second",
+		NamespacePrefix: "test-abc",
+		CompletedSteps:  2,
+		Clusters:        map[string]*state.Snapshot{"": s.Snapshot()},
+		Markers:         []measurement.Marker{{Name: "scale", Time: start}},
+		Measurements:    []measurement.StartedMeasurement{{Method: "APIResponsivenessPrometheus", Identifier: "APIResponsiveness", StartTime: start}},
+	}))
+
+	resumed, resumedRunID, err := OpenProgress(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, runID, resumedRunID)
+	assert.True(t, resumed.IsCompleted("first"))
+	assert.False(t, resumed.IsCompleted("second"))
+	assert.Nil(t, resumed.getTestProgress("first"))
+	test := resumed.getTestProgress("second")
+	if assert.NotNil(t, test) {
+		assert.Equal(t, "test-abc", test.NamespacePrefix)
+		assert.Equal(t, 2, test.CompletedSteps)
+		assert.Equal(t, p.Test.Markers, test.Markers)
+		assert.Equal(t, p.Test.Measurements, test.Measurements)
+
+		restored := state.NewState()
+		restored.Restore(test.Clusters[""])
+		instances, ok := restored.GetNamespacesState().Get("test-abc-1", identifier)
+		if assert.True(t, ok) {
+			assert.Equal(t, int32(3), instances.DesiredReplicaCount)
+			assert.Equal(t, int32(2), instances.CurrentReplicaCount)
+		}
+	}
+
+	// Nil progress, i.e. no state file, is no-op.
+	var none *Progress
+	assert.False(t, none.IsCompleted("first"))
+	assert.NoError(t, none.completeTest("first"))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// reconfiguredObject represents an object applied by a reconfiguration.
+type reconfiguredObject struct {
+	namespace string
+	name      string
+	applied   *unstructured.Unstructured
+	// original is the object before the reconfiguration, nil if it didn't exist.
+	original *unstructured.Unstructured
+}
+
+// appliedReconfiguration represents a reconfiguration that hasn't been reverted yet.
+type appliedReconfiguration struct {
+	name    string
+	objects []reconfiguredObject
+}
+
+// executeReconfiguration applies or reverts given reconfiguration.
+func (ste *simpleTestExecutor) executeReconfiguration(ctx Context, reconfiguration *api.Reconfiguration) *errors.ErrorList {
+	if reconfiguration.Name == "" {
+		return errors.NewErrorList(fmt.Errorf("reconfiguration name is required"))
+	}
+	if reconfiguration.Revert {
+		return ste.revertReconfiguration(ctx, reconfiguration.Name)
+	}
+	if ste.findReconfiguration(reconfiguration.Name) != -1 {
+		return errors.NewErrorList(fmt.Errorf("reconfiguration %q already applied", reconfiguration.Name))
+	}
+	logrus.Infof("Applying reconfiguration %q", reconfiguration.Name)
+	// Objects are recorded as soon as they are applied, so that partially applied reconfiguration can be reverted.
+	ste.reconfigurations = append(ste.reconfigurations, &appliedReconfiguration{name: reconfiguration.Name})
+	applied := ste.reconfigurations[len(ste.reconfigurations)-1]
+	for _, path := range reconfiguration.ObjectTemplatePaths {
+		objects, err := templateObjects(ctx, path, reconfiguration.TemplateFillMap)
+		if err != nil {
+			return errors.NewErrorList(fmt.Errorf("reading template (%v) error: %v", path, err))
+		}
+		for _, obj := range objects {
+			object, err := ste.applyReconfigurationObject(ctx, obj)
+			applied.objects = append(applied.objects, object)
+			if err != nil {
+				return errors.NewErrorList(fmt.Errorf("reconfiguration %q: applying %s %s/%s error: %v",
+					reconfiguration.Name, obj.GetKind(), obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+	return errors.NewErrorList()
+}
+
+func templateObjects(ctx Context, path string, templateFillMap map[string]interface{}) ([]*unstructured.Unstructured, error) {
+	mapping := ctx.GetTemplateMappingCopy()
+	if templateFillMap != nil {
+		util.CopyMap(templateFillMap, mapping)
+	}
+	obj, err := ctx.GetTemplateProvider().TemplateToObject(path, mapping)
+	if err == config.ErrorEmptyFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !obj.IsList() {
+		return []*unstructured.Unstructured{obj}, nil
+	}
+	list, err := obj.ToList()
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+	return objects, nil
+}
+
+// applyReconfigurationObject creates given object or replaces the existing one.
+func (ste *simpleTestExecutor) applyReconfigurationObject(ctx Context, obj *unstructured.Unstructured) (reconfiguredObject, error) {
+	f := ctx.GetClusterFramework()
+	applied := reconfiguredObject{namespace: obj.GetNamespace(), name: obj.GetName(), applied: obj}
+	current, err := f.GetObject(obj.GroupVersionKind(), applied.namespace, applied.name)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return applied, err
+	}
+	start := time.Now()
+	if current == nil {
+		err = f.CreateObject(applied.namespace, applied.name, obj)
+		ste.journal.record(CREATE_OBJECT, obj, applied.namespace, applied.name, start, err)
+		return applied, err
+	}
+	applied.original = current.DeepCopy()
+	obj.SetResourceVersion(current.GetResourceVersion())
+	err = f.UpdateObject(applied.namespace, applied.name, obj)
+	ste.journal.record(PATCH_OBJECT, obj, applied.namespace, applied.name, start, err)
+	return applied, err
+}
+
+// revertReconfiguration restores objects applied by the reconfiguration with given name
+// to their state from before the reconfiguration, in reverse order.
+func (ste *simpleTestExecutor) revertReconfiguration(ctx Context, name string) *errors.ErrorList {
+	index := ste.findReconfiguration(name)
+	if index == -1 {
+		return errors.NewErrorList(fmt.Errorf("reconfiguration %q has not been applied", name))
+	}
+	logrus.Infof("Reverting reconfiguration %q", name)
+	objects := ste.reconfigurations[index].objects
+	ste.reconfigurations = append(ste.reconfigurations[:index], ste.reconfigurations[index+1:]...)
+	errList := errors.NewErrorList()
+	f := ctx.GetClusterFramework()
+	for i := len(objects) - 1; i >= 0; i-- {
+		object := objects[i]
+		gvk := object.applied.GroupVersionKind()
+		start := time.Now()
+		if object.original == nil {
+			err := f.DeleteObject(gvk, object.namespace, object.name)
+			ste.journal.record(DELETE_OBJECT, object.applied, object.namespace, object.name, start, err)
+			if err != nil {
+				errList.Append(fmt.Errorf("reconfiguration %q: deleting %s %s/%s error: %v", name, gvk.Kind, object.namespace, object.name, err))
+			}
+			continue
+		}
+		original := object.original.DeepCopy()
+		current, err := f.GetObject(gvk, object.namespace, object.name)
+		switch {
+		case apierrs.IsNotFound(err):
+			original.SetResourceVersion("")
+			err = f.CreateObject(object.namespace, object.name, original)
+			ste.journal.record(CREATE_OBJECT, original, object.namespace, object.name, start, err)
+		case err == nil:
+			original.SetResourceVersion(current.GetResourceVersion())
+			err = f.UpdateObject(object.namespace, object.name, original)
+			ste.journal.record(PATCH_OBJECT, original, object.namespace, object.name, start, err)
+		}
+		if err != nil {
+			errList.Append(fmt.Errorf("reconfiguration %q: restoring %s %s/%s error: %v", name, gvk.Kind, object.namespace, object.name, err))
+		}
+	}
+	return errList
+}
+
+func (ste *simpleTestExecutor) findReconfiguration(name string) int {
+	for i := range ste.reconfigurations {
+		if ste.reconfigurations[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// revertAllReconfigurations reverts reconfigurations that were not reverted explicitly,
+// starting with the most recent one.
+func (ste *simpleTestExecutor) revertAllReconfigurations(ctx Context) {
+	for len(ste.reconfigurations) > 0 {
+		name := ste.reconfigurations[len(ste.reconfigurations)-1].name
+		if errList := ste.revertReconfiguration(ctx, name); !errList.IsEmpty() {
+			logrus.Errorf("Reverting reconfiguration %q error: %v", name, errList)
+		}
+	}
+}
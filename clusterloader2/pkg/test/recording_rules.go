@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
+)
+
+// ensureRecordingRules verifies that Prometheus recording rules required by measurements
+// of the test are loaded, installing missing ones if possible.
+func ensureRecordingRules(ctx Context, conf *api.Config) error {
+	if ctx.GetPrometheusFramework() == nil {
+		return nil
+	}
+	rules, err := requiredRecordingRules(ctx, conf)
+	if err != nil {
+		return err
+	}
+	return prometheus.EnsureRecordingRules(ctx.GetPrometheusFramework(), &ctx.GetClusterLoaderConfig().PrometheusConfig, rules)
+}
+
+// requiredRecordingRules returns deduplicated recording rules required by measurements of the test.
+func requiredRecordingRules(ctx Context, conf *api.Config) ([]prometheus.RecordingRule, error) {
+	var rules []prometheus.RecordingRule
+	seen := make(map[string]bool)
+	for i := range conf.Steps {
+		for _, m := range conf.Steps[i].Measurements {
+			measurementRules, err := ctx.GetMeasurementManager().RequiredRecordingRules(m.Method, m.Identifier, m.Params)
+			if err != nil {
+				return nil, fmt.Errorf("measurement %s (%s) error: %v", m.Method, m.Identifier, err)
+			}
+			for _, rule := range measurementRules {
+				if key := recordingRuleKey(rule); !seen[key] {
+					seen[key] = true
+					rules = append(rules, rule)
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+func recordingRuleKey(rule prometheus.RecordingRule) string {
+	var labels []string
+	for name, value := range rule.Labels {
+		labels = append(labels, name+"="+value)
+	}
+	sort.Strings(labels)
+	return rule.Record + "{" + strings.Join(labels, ",") + "}"
+}
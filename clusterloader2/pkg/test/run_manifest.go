@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const runManifestName = "RunManifest"
+
+// runManifest describes the run of the test itself, rather than the tested cluster.
+type runManifest struct {
+	Test                       string                 `json:"test"`
+	Identifier                 string                 `json:"identifier,omitempty"`
+	AutomanagedNamespacePrefix string                 `json:"automanagedNamespacePrefix"`
+	Start                      time.Time              `json:"start"`
+	End                        time.Time              `json:"end"`
+	NamespaceCreation          namespaceCreationStats `json:"namespaceCreation"`
+	// APIRetries are numbers of API calls retried during the test, by category of the error.
+	APIRetries map[string]int `json:"apiRetries,omitempty"`
+}
+
+type namespaceCreationStats struct {
+	Namespaces      int     `json:"namespaces"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// NamespacesPerSecond is the throughput of automanaged namespaces creation.
+	NamespacesPerSecond float64 `json:"namespacesPerSecond"`
+}
+
+func newRunManifest(ctx Context, testName string, start time.Time, startRetries map[string]int) *runManifest {
+	stats := ctx.GetClusterFramework().GetNamespaceCreationStats()
+	return &runManifest{
+		Test:                       testName,
+		Identifier:                 ctx.GetClusterLoaderConfig().TestScenario.Identifier,
+		AutomanagedNamespacePrefix: ctx.GetClusterFramework().GetAutomanagedNamespacePrefix(),
+		Start:                      start,
+		End:                        time.Now(),
+		NamespaceCreation:          newNamespaceCreationStats(stats),
+		APIRetries:                 retriesSince(startRetries, client.GetRetryCounts()),
+	}
+}
+
+// retriesSince returns numbers of retries performed between the two snapshots of retry counts.
+func retriesSince(start, end map[string]int) map[string]int {
+	retries := make(map[string]int)
+	for category, count := range end {
+		if delta := count - start[category]; delta > 0 {
+			retries[category] = delta
+		}
+	}
+	return retries
+}
+
+func newNamespaceCreationStats(stats framework.NamespaceCreationStats) namespaceCreationStats {
+	return namespaceCreationStats{
+		Namespaces:          stats.Count,
+		DurationSeconds:     stats.Duration.Seconds(),
+		NamespacesPerSecond: stats.Throughput,
+	}
+}
+
+// createRunManifestSummary creates summary describing the run of the test.
+func createRunManifestSummary(ctx Context, testName string, start time.Time, startRetries map[string]int) (measurement.Summary, error) {
+	content, err := util.PrettyPrintJSON(newRunManifest(ctx, testName, start, startRetries))
+	if err != nil {
+		return nil, err
+	}
+	return measurement.CreateSummary(runManifestName, "json", content), nil
+}
@@ -17,18 +17,26 @@ limitations under the License.
 package test
 
 import (
+	"context"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"k8s.io/perf-tests/clusterloader2/pkg/chaos"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/control"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
+	"k8s.io/perf-tests/clusterloader2/pkg/prometheus"
 	"k8s.io/perf-tests/clusterloader2/pkg/state"
 	"k8s.io/perf-tests/clusterloader2/pkg/tuningset"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
 type simpleContext struct {
+	ctx                 context.Context
 	clusterLoaderConfig *config.ClusterLoaderConfig
 	clusterFramework    *framework.Framework
 	prometheusFramework *framework.Framework
@@ -38,21 +46,61 @@ type simpleContext struct {
 	tuningSetFactory    tuningset.TuningSetFactory
 	measurementManager  *measurement.MeasurementManager
 	chaosMonkey         *chaos.Monkey
+	annotator           *prometheus.GrafanaAnnotator
+	notifier            notifier.Notifier
+	// clusterContexts are contexts of additional clusters by their names.
+	clusterContexts map[string]*clusterContext
 }
 
-func createSimpleContext(c *config.ClusterLoaderConfig, f, p *framework.Framework, s *state.State, templateMapping map[string]interface{}) Context {
+// clusterContext is a context of an additional cluster. It shares everything but the cluster
+// framework and the state (e.g. of namespaces, which are independent in each cluster) with the
+// context of the tested cluster.
+type clusterContext struct {
+	Context
+	clusterFramework *framework.Framework
+	state            *state.State
+}
+
+// GetClusterFramework returns framework of the cluster.
+func (cc *clusterContext) GetClusterFramework() *framework.Framework {
+	return cc.clusterFramework
+}
+
+// GetState returns current test state of the cluster.
+func (cc *clusterContext) GetState() *state.State {
+	return cc.state
+}
+
+func createSimpleContext(ctx context.Context, c *config.ClusterLoaderConfig, f, p *framework.Framework, clusterFrameworks map[string]*framework.Framework, s *state.State, templateMapping map[string]interface{}, options RunOptions) Context {
 	templateProvider := config.NewTemplateProvider(filepath.Dir(c.TestScenario.ConfigPath))
-	return &simpleContext{
+	var annotator *prometheus.GrafanaAnnotator
+	if p != nil && c.PrometheusConfig.Endpoint == "" && c.PrometheusConfig.EnableGrafana && c.PrometheusConfig.EnableGrafanaAnnotations {
+		annotator = prometheus.NewGrafanaAnnotator(p.GetClientSets().GetClient())
+	}
+	sc := &simpleContext{
+		ctx:                 ctx,
 		clusterLoaderConfig: c,
 		clusterFramework:    f,
 		prometheusFramework: p,
 		state:               s,
 		templateMapping:     util.CloneMap(templateMapping),
 		templateProvider:    templateProvider,
-		tuningSetFactory:    tuningset.NewTuningSetFactory(),
-		measurementManager:  measurement.CreateMeasurementManager(f, p, templateProvider, c),
-		chaosMonkey:         chaos.NewMonkey(f.GetClientSets().GetClient(), c.ClusterConfig.Provider),
+		tuningSetFactory:    newTuningSetFactory(options.Controller),
+		measurementManager:  measurement.CreateMeasurementManager(ctx, f, p, clusterFrameworks, templateProvider, c, annotator),
+		chaosMonkey:         chaos.NewMonkey(f.GetClientSets().GetClient(), c.ClusterConfig.Provider, annotator),
+		annotator:           annotator,
+		notifier:            options.Notifier,
+		clusterContexts:     make(map[string]*clusterContext, len(clusterFrameworks)),
+	}
+	for name, clusterFramework := range clusterFrameworks {
+		sc.clusterContexts[name] = &clusterContext{Context: sc, clusterFramework: clusterFramework, state: state.NewState()}
 	}
+	return sc
+}
+
+// GetContext returns context of the test.
+func (sc *simpleContext) GetContext() context.Context {
+	return sc.ctx
 }
 
 // GetClusterConfig return cluster config.
@@ -99,3 +147,43 @@ func (sc *simpleContext) GetMeasurementManager() *measurement.MeasurementManager
 func (sc *simpleContext) GetChaosMonkey() *chaos.Monkey {
 	return sc.chaosMonkey
 }
+
+// GetGrafanaAnnotator returns grafana annotator, nil if annotations are disabled.
+func (sc *simpleContext) GetGrafanaAnnotator() *prometheus.GrafanaAnnotator {
+	return sc.annotator
+}
+
+// GetNotifier returns notifier of the run, nil if notifications are disabled.
+func (sc *simpleContext) GetNotifier() notifier.Notifier {
+	return sc.notifier
+}
+
+// GetClusterContext returns context of the named cluster, the context itself for empty name.
+func (sc *simpleContext) GetClusterContext(name string) (Context, error) {
+	if name == "" {
+		return sc, nil
+	}
+	cc, ok := sc.clusterContexts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown cluster %q, available clusters: [%s]", name, strings.Join(sc.GetClusterNames(), ", "))
+	}
+	return cc, nil
+}
+
+// GetClusterNames returns sorted names of additional clusters.
+func (sc *simpleContext) GetClusterNames() []string {
+	names := make([]string, 0, len(sc.clusterContexts))
+	for name := range sc.clusterContexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newTuningSetFactory creates tuning set factory starting actions only when the load isn't paused by the controller.
+func newTuningSetFactory(controller *control.Controller) tuningset.TuningSetFactory {
+	if controller == nil {
+		return tuningset.NewTuningSetFactory(nil)
+	}
+	return tuningset.NewTuningSetFactory(controller)
+}
@@ -17,10 +17,10 @@ limitations under the License.
 package test
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"path"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -31,8 +31,19 @@ import (
 	"k8s.io/perf-tests/clusterloader2/api"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	frameworkconfig "k8s.io/perf-tests/clusterloader2/pkg/framework/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/lint"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
 	"k8s.io/perf-tests/clusterloader2/pkg/measurement/util/runtimeobjects"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
+	"k8s.io/perf-tests/clusterloader2/pkg/publisher"
+	"k8s.io/perf-tests/clusterloader2/pkg/report"
+	"k8s.io/perf-tests/clusterloader2/pkg/selfmetrics"
+	"k8s.io/perf-tests/clusterloader2/pkg/sink"
 	"k8s.io/perf-tests/clusterloader2/pkg/state"
+	"k8s.io/perf-tests/clusterloader2/pkg/upgrade"
 	"k8s.io/perf-tests/clusterloader2/pkg/util"
 )
 
@@ -42,82 +53,406 @@ const (
 	namePlaceholder     = "Name"
 )
 
-type simpleTestExecutor struct{}
+type simpleTestExecutor struct {
+	// journal records object operations of the currently executed test. It is nil if journal is disabled.
+	journal *operationJournal
+	// reconfigurations are applied reconfigurations of the currently executed test, in order of application.
+	reconfigurations []*appliedReconfiguration
+}
 
 func createSimpleTestExecutor() TestExecutor {
 	return &simpleTestExecutor{}
 }
 
 // ExecuteTest executes test based on provided configuration.
-func (ste *simpleTestExecutor) ExecuteTest(ctx Context, conf *api.Config) *errors.ErrorList {
-	ctx.GetClusterFramework().SetAutomanagedNamespacePrefix(fmt.Sprintf("test-%s", util.RandomDNS1123String(6)))
-	logrus.Infof("AutomanagedNamespacePrefix: %s", ctx.GetClusterFramework().GetAutomanagedNamespacePrefix())
-	defer cleanupResources(ctx)
+func (ste *simpleTestExecutor) ExecuteTest(ctx Context, conf *api.Config) (errList *errors.ErrorList) {
+	start := time.Now()
+	startRetries := client.GetRetryCounts()
+	testID := GetTestID(ctx.GetClusterLoaderConfig().TestScenario)
+	progress, err := loadProgress(ctx.GetClusterLoaderConfig().StateFile, ctx.GetClusterLoaderConfig().ClusterConfig.RunID)
+	if err != nil {
+		return errors.NewErrorList(err)
+	}
+	// Test interrupted by the previous run is resumed in its namespaces after its last completed step.
+	resumed := progress.getTestProgress(testID)
+	prefix := framework.NewNamespacePrefix(ctx.GetClusterLoaderConfig().NamespaceConfig.Prefix)
+	if resumed != nil {
+		logrus.Infof("Resuming test interrupted after %d completed steps", resumed.CompletedSteps)
+		prefix = resumed.NamespacePrefix
+	}
+	defer func() {
+		// Cancelled test hasn't finished, so that it can be resumed.
+		if ctx.GetContext().Err() == nil {
+			if err := progress.completeTest(testID); err != nil {
+				logrus.Errorf("Persisting progress error: %v", err)
+			}
+		}
+	}()
+	logrus.Infof("AutomanagedNamespacePrefix: %s", prefix)
+	if err := configureAutomanagedNamespaces(ctx, ctx.GetClusterFramework(), prefix); err != nil {
+		return errors.NewErrorList(err)
+	}
+	journal, err := openOperationJournal(ctx.GetClusterLoaderConfig().OperationJournalPath, conf.Name)
+	if err != nil {
+		return errors.NewErrorList(err)
+	}
+	ste.journal = journal
+	defer func() {
+		if err := journal.close(); err != nil {
+			logrus.Errorf("Closing operation journal error: %v", err)
+		}
+		ste.journal = nil
+	}()
+	clusterContexts, err := getPhaseClusterContexts(ctx, conf)
+	if err != nil {
+		return errors.NewErrorList(err)
+	}
+	defer func() {
+		resumable := progress != nil && ctx.GetContext().Err() != nil
+		cleanupResources(ctx, clusterContexts, !errList.IsEmpty(), resumable)
+	}()
+	if eventsConfig := ctx.GetClusterLoaderConfig().EventsConfig; eventsConfig.BufferSize > 0 {
+		f := ctx.GetClusterFramework()
+		if err := f.StartEventCollector(eventsConfig.Namespace, eventsConfig.BufferSize); err != nil {
+			return errors.NewErrorList(fmt.Errorf("events collector error: %v", err))
+		}
+		defer func() {
+			if eventsConfig.DumpOnFailure && !errList.IsEmpty() {
+				errList.Concat(dumpEvents(ctx, conf.Name, f.GetEventCollector()))
+			}
+			f.StopEventCollector()
+		}()
+	}
+	defer ste.revertAllReconfigurations(ctx)
+	ctx.GetMeasurementManager().SetCheckpointWriter(func(summaries []measurement.Summary) error {
+		if errs := writeSummaries(ctx.GetClusterLoaderConfig(), conf.Name, summaries); !errs.IsEmpty() {
+			return errs
+		}
+		return nil
+	})
+	if n := ctx.GetNotifier(); n != nil {
+		ctx.GetMeasurementManager().SetViolationNotifier(func(methodName, identifier string, err error) {
+			notifyViolation(n, ctx.GetClusterLoaderConfig(), conf.Name, methodName, identifier, err)
+		})
+	}
 	ctx.GetTuningSetFactory().Init(conf.TuningSets)
-	stopCh := make(chan struct{})
-	defer close(stopCh)
-	if err := ctx.GetChaosMonkey().Init(conf.ChaosMonkey, stopCh); err != nil {
+	// Simulated failures are stopped and repaired once the test ends or is cancelled.
+	chaosCtx, stopChaos := context.WithCancel(ctx.GetContext())
+	defer func() {
+		stopChaos()
+		ctx.GetChaosMonkey().Wait()
+	}()
+	if err := ctx.GetChaosMonkey().Init(conf.ChaosMonkey, chaosCtx.Done()); err != nil {
 		return errors.NewErrorList(fmt.Errorf("error while creating chaos monkey: %v", err))
 	}
-	automanagedNamespacesList, err := ctx.GetClusterFramework().ListAutomanagedNamespaces()
-	if err != nil {
-		return errors.NewErrorList(fmt.Errorf("automanaged namespaces listing failed: %v", err))
+	clientConfig := ctx.GetClusterLoaderConfig().ClusterConfig.ClientConfig
+	maxQPS := frameworkconfig.MaxQPS(ctx.GetClusterFramework().GetDynamicClients().GetClientsNumber(), clientConfig.QPS, clientConfig.TotalQPS)
+	for _, issue := range lint.Lint(conf, maxQPS) {
+		logrus.Warningf("Test config issue: %v", issue)
+	}
+	if ctx.GetClusterLoaderConfig().EnableCapacityCheck {
+		if err := checkCapacity(ctx, conf); err != nil {
+			return errors.NewErrorList(fmt.Errorf("capacity check failed: %v", err))
+		}
 	}
-	if len(automanagedNamespacesList) > 0 {
-		return errors.NewErrorList(fmt.Errorf("pre-existing automanaged namespaces found"))
+	if err := ensureRecordingRules(ctx, conf); err != nil {
+		return errors.NewErrorList(fmt.Errorf("recording rules verification failed: %v", err))
 	}
-	err = ctx.GetClusterFramework().CreateAutomanagedNamespaces(int(conf.AutomanagedNamespaces))
+	namespaceTemplate, err := createNamespaceTemplate(ctx, conf.NamespaceTemplate)
 	if err != nil {
-		return errors.NewErrorList(fmt.Errorf("automanaged namespaces creation failed: %v", err))
+		return errors.NewErrorList(fmt.Errorf("namespace template error: %v", err))
+	}
+	if err := createAutomanagedNamespaces(ctx, ctx.GetClusterFramework(), int(conf.AutomanagedNamespaces), namespaceTemplate, resumed != nil); err != nil {
+		return errors.NewErrorList(err)
+	}
+	for _, clusterCtx := range clusterContexts {
+		clusterFramework := clusterCtx.GetClusterFramework()
+		if err := configureAutomanagedNamespaces(ctx, clusterFramework, prefix); err != nil {
+			return errors.NewErrorList(err)
+		}
+		if err := createAutomanagedNamespaces(ctx, clusterFramework, int(conf.AutomanagedNamespaces), namespaceTemplate, resumed != nil); err != nil {
+			return errors.NewErrorList(err)
+		}
+	}
+	completedSteps := 0
+	if resumed != nil {
+		if err := restoreTestProgress(ctx, resumed); err != nil {
+			return errors.NewErrorList(fmt.Errorf("resuming test error: %v", err))
+		}
+		completedSteps = resumed.CompletedSteps
 	}
 
-	errList := errors.NewErrorList()
-	for i := range conf.Steps {
+	errList = errors.NewErrorList()
+	for i := completedSteps; i < len(conf.Steps); i++ {
+		if ctx.GetContext().Err() != nil {
+			break
+		}
 		if stepErrList := ste.ExecuteStep(ctx, &conf.Steps[i]); !stepErrList.IsEmpty() {
 			errList.Concat(stepErrList)
 			if isErrsCritical(stepErrList) {
 				return errList
 			}
 		}
+		// Step interrupted by cancellation is repeated by the resumed test.
+		if ctx.GetContext().Err() == nil {
+			if err := progress.updateTest(createTestProgress(ctx, testID, prefix, i+1)); err != nil {
+				logrus.Errorf("Persisting progress error: %v", err)
+			}
+		}
+		if failures := ctx.GetMeasurementManager().GetFailures(); !failures.IsEmpty() {
+			logrus.Errorf("Measurements reported failures, skipping remaining steps: %v", failures)
+			errList.Concat(failures)
+			break
+		}
+	}
+
+	// Results of the cancelled test are reported, as far as they were collected.
+	if err := ctx.GetContext().Err(); err != nil {
+		logrus.Errorf("Test cancelled, remaining steps skipped: %v", err)
+		errList.Append(fmt.Errorf("test cancelled: %v", err))
+		ctx.GetMeasurementManager().GatherPartialSummaries()
+	}
+	summaries := ctx.GetMeasurementManager().GetSummaries()
+	if ctx.GetClusterLoaderConfig().EnablePhaseFootprint {
+		if summary, err := createPhaseFootprintSummary(ctx); err != nil {
+			errList.Append(fmt.Errorf("phase footprint error: %v", err))
+		} else {
+			summaries = append(summaries, summary)
+		}
+	}
+	if summary, err := createRunManifestSummary(ctx, conf.Name, start, startRetries); err != nil {
+		errList.Append(fmt.Errorf("run manifest error: %v", err))
+	} else {
+		summaries = append(summaries, summary)
+	}
+	if summary, err := createJUnitSummary(conf.Name, ctx.GetMeasurementManager().GetResults()); err != nil {
+		errList.Append(err)
+	} else {
+		summaries = append(summaries, summary)
+	}
+	if summary, err := report.CreateSummary(conf.Name, summaries, ctx.GetMeasurementManager().GetResults()); err != nil {
+		errList.Append(err)
+	} else {
+		summaries = append(summaries, summary)
+	}
+	errList.Concat(writeSummaries(ctx.GetClusterLoaderConfig(), conf.Name, summaries))
+	if p, err := publisher.NewPublisher(&ctx.GetClusterLoaderConfig().PublisherConfig); err != nil {
+		errList.Append(fmt.Errorf("publisher creation error: %v", err))
+	} else if p != nil {
+		if err := publishResults(p, ctx, conf, summaries); err != nil {
+			errList.Append(fmt.Errorf("publishing results error: %v", err))
+		}
+	}
+	return errList
+}
+
+// dumpEvents writes events collected during the failed test to all configured sinks.
+func dumpEvents(ctx Context, testName string, collector *framework.EventCollector) *errors.ErrorList {
+	summary, err := createEventsDumpSummary(collector)
+	if err != nil {
+		return errors.NewErrorList(fmt.Errorf("events dump error: %v", err))
+	}
+	logrus.Infof("Dumping events collected during the failed test %s", testName)
+	return writeSummaries(ctx.GetClusterLoaderConfig(), testName, []measurement.Summary{summary})
+}
+
+// writeSummaries writes summaries of the test to all configured sinks.
+func writeSummaries(clusterLoaderConfig *config.ClusterLoaderConfig, testName string, summaries []measurement.Summary) *errors.ErrorList {
+	errList := errors.NewErrorList()
+	sinks, err := sink.NewSinks(clusterLoaderConfig.ReportDir, &clusterLoaderConfig.SummarySinkConfig)
+	if err != nil {
+		errList.Append(fmt.Errorf("summary sinks creation error: %v", err))
+	}
+	for _, summary := range summaries {
+		testDistinctor := ""
+		if clusterLoaderConfig.TestScenario.Identifier != "" {
+			testDistinctor = "_" + clusterLoaderConfig.TestScenario.Identifier
+		}
+		// TODO(krzysied): Remember to keep original filename style for backward compatibility.
+		fileName := strings.Join([]string{summary.SummaryName(), testName + testDistinctor, summary.SummaryTime().Format(time.RFC3339)}, "_")
+		for _, s := range sinks {
+			if err := s.Write(strings.Join([]string{fileName, summary.SummaryExt()}, "."), summary); err != nil {
+				errList.Append(fmt.Errorf("writing summary %s to %v error: %v", summary.SummaryName(), s, err))
+			}
+		}
+	}
+	return errList
+}
+
+// notifyViolation notifies about an SLO violation detected by the measurement during the test.
+func notifyViolation(n notifier.Notifier, clusterLoaderConfig *config.ClusterLoaderConfig, testName, methodName, identifier string, err error) {
+	notification := &notifier.Notification{
+		Event:     notifier.ViolationDetected,
+		Run:       clusterLoaderConfig.NotifierConfig.RunName,
+		Timestamp: time.Now(),
+		Violations: []notifier.Violation{{
+			Test:        testName,
+			Measurement: methodName,
+			Identifier:  identifier,
+			Metric:      errors.GetViolatedMetric(err),
+			Message:     err.Error(),
+		}},
+	}
+	if err := n.Notify(notification); err != nil {
+		logrus.Errorf("Notifying about violation of %s error: %v", methodName, err)
+	}
+}
+
+func publishResults(p publisher.Publisher, ctx Context, conf *api.Config, summaries []measurement.Summary) error {
+	clusterLoaderConfig := ctx.GetClusterLoaderConfig()
+	result, err := publisher.NewTestResult(conf.Name, clusterLoaderConfig.TestScenario.Identifier, clusterLoaderConfig.PublisherConfig.Labels, summaries)
+	if err != nil {
+		return err
+	}
+	for _, r := range ctx.GetMeasurementManager().GetResults() {
+		for _, violation := range r.Violations {
+			result.Violations = append(result.Violations, &publisher.Violation{
+				Measurement: r.Method,
+				Identifier:  r.Identifier,
+				Metric:      errors.GetViolatedMetric(violation),
+				Message:     violation.Error(),
+			})
+		}
 	}
+	return p.Publish(result)
+}
 
-	for _, summary := range ctx.GetMeasurementManager().GetSummaries() {
+// getPhaseClusterContexts verifies that all clusters targeted by steps and measurements of the test exist
+// and returns contexts of additional clusters phases are executed against.
+func getPhaseClusterContexts(ctx Context, conf *api.Config) ([]Context, error) {
+	var clusterContexts []Context
+	found := make(map[string]bool)
+	for i := range conf.Steps {
+		step := &conf.Steps[i]
+		clusterCtx, err := ctx.GetClusterContext(step.Cluster)
 		if err != nil {
-			errList.Append(fmt.Errorf("printing summary %s error: %v", summary.SummaryName(), err))
-			continue
+			return nil, fmt.Errorf("step %d error: %v", i, err)
 		}
-		if ctx.GetClusterLoaderConfig().ReportDir == "" {
-			logrus.Infof("%v: %v", summary.SummaryName(), summary.SummaryContent())
-		} else {
-			testDistinctor := ""
-			if ctx.GetClusterLoaderConfig().TestScenario.Identifier != "" {
-				testDistinctor = "_" + ctx.GetClusterLoaderConfig().TestScenario.Identifier
+		for _, m := range step.Measurements {
+			if _, err := ctx.GetClusterContext(m.Cluster); err != nil {
+				return nil, fmt.Errorf("measurement %s - %s error: %v", m.Method, m.Identifier, err)
 			}
-			// TODO(krzysied): Remember to keep original filename style for backward compatibility.
-			fileName := strings.Join([]string{summary.SummaryName(), conf.Name + testDistinctor, summary.SummaryTime().Format(time.RFC3339)}, "_")
-			filePath := path.Join(ctx.GetClusterLoaderConfig().ReportDir, strings.Join([]string{fileName, summary.SummaryExt()}, "."))
-			if err := ioutil.WriteFile(filePath, []byte(summary.SummaryContent()), 0644); err != nil {
-				errList.Append(fmt.Errorf("writing to file %v error: %v", filePath, err))
-				continue
+		}
+		if step.Cluster != "" && len(step.Phases) > 0 && !found[step.Cluster] {
+			found[step.Cluster] = true
+			clusterContexts = append(clusterContexts, clusterCtx)
+		}
+	}
+	return clusterContexts, nil
+}
+
+// configureAutomanagedNamespaces sets prefix, naming and deletion options of automanaged namespaces of the framework.
+func configureAutomanagedNamespaces(ctx Context, f *framework.Framework, prefix string) error {
+	namespaceConfig := ctx.GetClusterLoaderConfig().NamespaceConfig
+	f.SetAutomanagedNamespacePrefix(prefix)
+	if namespaceConfig.NameTemplate != "" {
+		naming, err := framework.NewNamespaceNaming(namespaceConfig.NameTemplate, namespaceConfig.Prefix, namespaceConfig.IndexWidth, namespaceConfig.StartIndex)
+		if err != nil {
+			return fmt.Errorf("automanaged namespaces naming error: %v", err)
+		}
+		f.SetAutomanagedNamespaceNaming(naming)
+	}
+	f.SetNamespaceDeletionOptions(framework.NamespaceDeletionOptions{
+		BatchSize:   namespaceConfig.DeletionBatchSize,
+		Parallelism: namespaceConfig.DeletionParallelism,
+	})
+	return nil
+}
+
+// createNamespaceTemplate reads objects of the namespace template of the test, nil template means bare namespaces.
+func createNamespaceTemplate(ctx Context, template *api.NamespaceTemplate) (*framework.NamespaceTemplate, error) {
+	if template == nil {
+		return nil, nil
+	}
+	namespaceTemplate := &framework.NamespaceTemplate{
+		Labels:      template.Labels,
+		Annotations: template.Annotations,
+	}
+	for _, path := range template.ObjectTemplatePaths {
+		objects, err := templateObjects(ctx, path, template.TemplateFillMap)
+		if err != nil {
+			return nil, fmt.Errorf("reading template (%v) error: %v", path, err)
+		}
+		for _, obj := range objects {
+			if obj.GetName() == "" {
+				return nil, fmt.Errorf("%s from template (%v) has no name", obj.GetKind(), path)
 			}
 		}
+		namespaceTemplate.Objects = append(namespaceTemplate.Objects, objects...)
 	}
-	return errList
+	return namespaceTemplate, nil
+}
+
+// createAutomanagedNamespaces creates automanaged namespaces of the framework. If the test is resumed,
+// namespaces created by the interrupted run are used instead. Leftovers of previous runs are handled
+// by the runner according to the stale namespace policy.
+func createAutomanagedNamespaces(ctx Context, f *framework.Framework, namespaceCount int, namespaceTemplate *framework.NamespaceTemplate, resume bool) error {
+	if resume {
+		if err := f.ResumeAutomanagedNamespaces(namespaceCount); err != nil {
+			return fmt.Errorf("automanaged namespaces resuming failed: %v", err)
+		}
+		return nil
+	}
+	namespaces, err := f.ListAutomanagedNamespaces()
+	if err != nil {
+		return fmt.Errorf("automanaged namespaces listing failed: %v", err)
+	}
+	if len(namespaces) > 0 {
+		return fmt.Errorf("pre-existing automanaged namespaces found")
+	}
+	if err := f.CreateAutomanagedNamespaces(ctx.GetContext(), namespaceCount, namespaceTemplate); err != nil {
+		return fmt.Errorf("automanaged namespaces creation failed: %v", err)
+	}
+	return nil
 }
 
 // ExecuteStep executes single test step based on provided step configuration.
+// Phases and measurements of the step are executed against the cluster of the step.
 func (ste *simpleTestExecutor) ExecuteStep(ctx Context, step *api.Step) *errors.ErrorList {
 	if step.Name != "" {
 		logrus.Infof("Step %q started", step.Name)
 	}
+	stepCtx, err := ctx.GetClusterContext(step.Cluster)
+	if err != nil {
+		return errors.NewErrorList(err)
+	}
+	stepStart := time.Now()
 	var wg wait.Group
 	errList := errors.NewErrorList()
+	if step.Marker != "" {
+		logrus.Infof("Recording marker %q", step.Marker)
+		if err := ctx.GetMeasurementManager().GetMarkers().Add(step.Marker, stepStart); err != nil {
+			errList.Append(err)
+		}
+		ctx.GetGrafanaAnnotator().Annotate(stepStart, fmt.Sprintf("Marker %q", step.Marker), "marker")
+	}
+	if step.Reconfiguration != nil {
+		if reconfigurationErrList := ste.executeReconfiguration(ctx, step.Reconfiguration); !reconfigurationErrList.IsEmpty() {
+			errList.Concat(reconfigurationErrList)
+			logrus.Warningf("Got errors during step execution: %v", errList)
+			return errList
+		}
+	}
+	if step.Upgrade != nil {
+		wg.Start(func() {
+			start := time.Now()
+			if err := upgrade.Run(step.Upgrade, &ctx.GetClusterLoaderConfig().ClusterConfig); err != nil {
+				errList.Append(fmt.Errorf("upgrade error: %v", err))
+			}
+			ctx.GetGrafanaAnnotator().AnnotateRange(start, time.Now(), fmt.Sprintf("Upgrade to %q", step.Upgrade.Version), "upgrade")
+		})
+	}
 	if len(step.Measurements) > 0 {
 		for i := range step.Measurements {
 			// index is created to make i value unchangeable during thread execution.
 			index := i
 			wg.Start(func() {
-				err := ctx.GetMeasurementManager().Execute(step.Measurements[index].Method,
+				cluster := step.Measurements[index].Cluster
+				if cluster == "" {
+					cluster = step.Cluster
+				}
+				err := ctx.GetMeasurementManager().ExecuteOnCluster(cluster,
+					step.Measurements[index].Method,
 					step.Measurements[index].Identifier,
 					step.Measurements[index].Params)
 				if err != nil {
@@ -128,16 +463,20 @@ func (ste *simpleTestExecutor) ExecuteStep(ctx Context, step *api.Step) *errors.
 	} else {
 		for i := range step.Phases {
 			phase := &step.Phases[i]
+			index := i
 			wg.Start(func() {
-				if phaseErrList := ste.ExecutePhase(ctx, phase); !phaseErrList.IsEmpty() {
+				start := time.Now()
+				if phaseErrList := ste.ExecutePhase(stepCtx, phase); !phaseErrList.IsEmpty() {
 					errList.Concat(phaseErrList)
 				}
+				stepCtx.GetState().GetPhasesState().Add(createPhaseRecord(stepCtx, step.Name, index, phase, start))
 			})
 		}
 	}
 	wg.Wait()
 	if step.Name != "" {
 		logrus.Infof("Step %q ended", step.Name)
+		ctx.GetGrafanaAnnotator().AnnotateRange(stepStart, time.Now(), fmt.Sprintf("Step %q", step.Name), "step")
 	}
 	if !errList.IsEmpty() {
 		logrus.Warningf("Got errors during step execution: %v", errList)
@@ -244,7 +583,20 @@ func (ste *simpleTestExecutor) ExecutePhase(ctx Context, phase *api.Phase) *erro
 		}()
 
 	}
-	tuningSet.Execute(actions)
+	// Operations are issued by the tuning set only when the load is not paused and the test is not cancelled.
+	var started int32
+	for i := range actions {
+		action := actions[i]
+		actions[i] = func() {
+			atomic.AddInt32(&started, 1)
+			defer selfmetrics.PhaseOperationStarted()()
+			action()
+		}
+	}
+	selfmetrics.PhaseOperationsQueued(len(actions))
+	tuningSet.Execute(ctx.GetContext(), actions)
+	// Operations not started, e.g. because the test was cancelled, are no longer queued.
+	selfmetrics.PhaseOperationsQueued(int(started) - len(actions))
 	return errList
 }
 
@@ -279,20 +631,33 @@ func (ste *simpleTestExecutor) ExecuteObject(ctx Context, object *api.Object, na
 		return errList
 	}
 	gvk := obj.GroupVersionKind()
+	start := time.Now()
 	switch operation {
 	case CREATE_OBJECT:
-		if err := ctx.GetClusterFramework().CreateObject(namespace, objName, obj); err != nil {
+		err = ctx.GetClusterFramework().CreateObject(namespace, objName, obj)
+		if err != nil {
 			errList.Append(fmt.Errorf("namespace %v object %v creation error: %v", namespace, objName, err))
 		}
 	case PATCH_OBJECT:
-		if err := ctx.GetClusterFramework().PatchObject(namespace, objName, obj); err != nil {
+		err = ctx.GetClusterFramework().PatchObject(namespace, objName, obj)
+		if err != nil {
 			errList.Append(fmt.Errorf("namespace %v object %v updating error: %v", namespace, objName, err))
 		}
 	case DELETE_OBJECT:
-		if err := ctx.GetClusterFramework().DeleteObject(gvk, namespace, objName); err != nil {
+		err = ctx.GetClusterFramework().DeleteObject(gvk, namespace, objName)
+		if err != nil {
 			errList.Append(fmt.Errorf("namespace %v object %v deletion error: %v", namespace, objName, err))
 		}
 	}
+	ste.journal.record(operation, obj, namespace, objName, start, err)
+	ctx.GetMeasurementManager().GetAPICallObservers().Observe(measurement.APICall{
+		Operation: operation.String(),
+		Kind:      gvk.Kind,
+		Namespace: namespace,
+		Start:     start,
+		Latency:   time.Since(start),
+		Failed:    err != nil,
+	})
 	return errList
 }
 
@@ -333,13 +698,12 @@ func createNamespacesList(ctx Context, namespaceRange *api.NamespaceRange) []str
 	}
 
 	nsList := make([]string, 0)
-	nsBasename := ctx.GetClusterFramework().GetAutomanagedNamespacePrefix()
-	if namespaceRange.Basename != nil {
-		nsBasename = *namespaceRange.Basename
-	}
-
 	for i := namespaceRange.Min; i <= namespaceRange.Max; i++ {
-		nsList = append(nsList, fmt.Sprintf("%v-%d", nsBasename, i))
+		if namespaceRange.Basename != nil {
+			nsList = append(nsList, fmt.Sprintf("%v-%d", *namespaceRange.Basename, i))
+		} else {
+			nsList = append(nsList, ctx.GetClusterFramework().GetAutomanagedNamespaceName(int(i)))
+		}
 	}
 	return nsList
 }
@@ -349,10 +713,32 @@ func isErrsCritical(*errors.ErrorList) bool {
 	return false
 }
 
-func cleanupResources(ctx Context) {
+func cleanupResources(ctx Context, clusterContexts []Context, failed, resumable bool) {
 	cleanupStartTime := time.Now()
 	ctx.GetMeasurementManager().Dispose()
-	if errList := ctx.GetClusterFramework().DeleteAutomanagedNamespaces(); !errList.IsEmpty() {
+	frameworks := []*framework.Framework{ctx.GetClusterFramework()}
+	for _, clusterCtx := range clusterContexts {
+		frameworks = append(frameworks, clusterCtx.GetClusterFramework())
+	}
+	if resumable {
+		for _, f := range frameworks {
+			logrus.Warningf("Test cancelled, keeping automanaged namespaces, so that the test can be resumed: %v", f.GetAutomanagedNamespaceNames())
+			f.ForgetAutomanagedNamespaces()
+		}
+		return
+	}
+	if failed && ctx.GetClusterLoaderConfig().NamespaceConfig.KeepOnFailure {
+		for _, f := range frameworks {
+			logrus.Warningf("Test failed, keeping automanaged namespaces: %v", f.GetAutomanagedNamespaceNames())
+			f.ForgetAutomanagedNamespaces()
+		}
+		return
+	}
+	errList := errors.NewErrorList()
+	for _, f := range frameworks {
+		errList.Concat(f.DeleteAutomanagedNamespaces())
+	}
+	if !errList.IsEmpty() {
 		logrus.Errorf("Resource cleanup error: %v", errList.String())
 		return
 	}
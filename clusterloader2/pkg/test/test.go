@@ -17,12 +17,16 @@ limitations under the License.
 package test
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
+	"k8s.io/perf-tests/clusterloader2/api"
 	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/control"
 	"k8s.io/perf-tests/clusterloader2/pkg/errors"
 	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/notifier"
 	"k8s.io/perf-tests/clusterloader2/pkg/state"
 )
 
@@ -36,30 +40,54 @@ var (
 	Test = createSimpleTestExecutor()
 )
 
+// RunOptions are services of the run shared by all of its tests.
+type RunOptions struct {
+	// Controller allows pausing and resuming load phases. If nil, the load can't be paused.
+	Controller *control.Controller
+	// Notifier posts notifications about SLO violations. If nil, notifications are disabled.
+	Notifier notifier.Notifier
+}
+
 // RunTest runs test based on provided test configuration.
-func RunTest(clusterFramework, prometheusFramework *framework.Framework, clusterLoaderConfig *config.ClusterLoaderConfig) *errors.ErrorList {
+// Cluster frameworks are frameworks of additional clusters by their names, they can be nil.
+// Once ctx is done, the test is cancelled: load and waits are stopped, remaining steps are skipped
+// and partial results of started measurements are gathered before the test is cleaned up.
+func RunTest(ctx context.Context, clusterFramework, prometheusFramework *framework.Framework, clusterFrameworks map[string]*framework.Framework, clusterLoaderConfig *config.ClusterLoaderConfig, options RunOptions) *Result {
 	if clusterFramework == nil {
-		return errors.NewErrorList(fmt.Errorf("framework must be provided"))
+		return newExecutionFailure(fmt.Errorf("framework must be provided"))
 	}
 	if clusterLoaderConfig == nil {
-		return errors.NewErrorList(fmt.Errorf("cluster loader config must be provided"))
+		return newExecutionFailure(fmt.Errorf("cluster loader config must be provided"))
 	}
 	if CreateContext == nil {
-		return errors.NewErrorList(fmt.Errorf("no CreateContext function installed"))
+		return newExecutionFailure(fmt.Errorf("no CreateContext function installed"))
 	}
 	if Test == nil {
-		return errors.NewErrorList(fmt.Errorf("no Test installed"))
+		return newExecutionFailure(fmt.Errorf("no Test installed"))
 	}
 
 	mapping, errList := config.GetMapping(clusterLoaderConfig)
 	if errList != nil {
-		return errList
+		return &Result{Errors: errList, Category: ExecutionFailure}
 	}
-	ctx := CreateContext(clusterLoaderConfig, clusterFramework, prometheusFramework, state.NewState(), mapping)
+	testCtx := CreateContext(ctx, clusterLoaderConfig, clusterFramework, prometheusFramework, clusterFrameworks, state.NewState(), mapping, options)
 	testConfigFilename := filepath.Base(clusterLoaderConfig.TestScenario.ConfigPath)
-	testConfig, err := ctx.GetTemplateProvider().TemplateToConfig(testConfigFilename, mapping)
+	testConfig, err := testCtx.GetTemplateProvider().TemplateToConfig(testConfigFilename, mapping)
 	if err != nil {
-		return errors.NewErrorList(fmt.Errorf("config reading error: %v", err))
+		return newExecutionFailure(fmt.Errorf("config reading error: %v", err))
 	}
-	return Test.ExecuteTest(ctx, testConfig)
+	errList = Test.ExecuteTest(testCtx, testConfig)
+	return newResult(testConfig.Name, errList, testCtx.GetMeasurementManager().GetResults())
+}
+
+// GetTestID returns identifier of the test used in logs, reports and the state file.
+func GetTestID(ts api.TestScenario) string {
+	if ts.Identifier != "" {
+		return fmt.Sprintf("%s(%s)", ts.Identifier, ts.ConfigPath)
+	}
+	return ts.ConfigPath
+}
+
+func newExecutionFailure(err error) *Result {
+	return &Result{Errors: errors.NewErrorList(err), Category: ExecutionFailure}
 }
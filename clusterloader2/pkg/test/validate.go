@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/tuningset"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// Validate checks the test config without running it and without access to the cluster:
+// the config and all templates it references are rendered, tuning sets and clusters referenced
+// by steps have to exist, and measurements have to be registered and accept their params
+// (see measurement.Validate). Values discovered from the cluster, e.g. number of nodes,
+// are taken from the config.
+func Validate(clusterLoaderConfig *config.ClusterLoaderConfig) *errors.ErrorList {
+	mapping, errList := config.GetMapping(clusterLoaderConfig)
+	if errList != nil {
+		return errList
+	}
+	templateProvider := config.NewTemplateProvider(filepath.Dir(clusterLoaderConfig.TestScenario.ConfigPath))
+	testConfig, err := templateProvider.TemplateToConfig(filepath.Base(clusterLoaderConfig.TestScenario.ConfigPath), mapping)
+	if err != nil {
+		return errors.NewErrorList(fmt.Errorf("config reading error: %v", err))
+	}
+
+	errList = errors.NewErrorList()
+	clusters, err := getClusterNames(&clusterLoaderConfig.ClusterConfig)
+	if err != nil {
+		errList.Append(err)
+	}
+	tuningSetFactory := tuningset.NewTuningSetFactory(nil)
+	tuningSetFactory.Init(testConfig.TuningSets)
+	if template := testConfig.NamespaceTemplate; template != nil {
+		for _, path := range template.ObjectTemplatePaths {
+			if err := validateTemplate(templateProvider, path, mapping, template.TemplateFillMap); err != nil {
+				errList.Append(fmt.Errorf("namespace template: %v", err))
+			}
+		}
+	}
+	for i := range testConfig.Steps {
+		step := &testConfig.Steps[i]
+		stepName := fmt.Sprintf("step %d", i)
+		if step.Name != "" {
+			stepName = fmt.Sprintf("step %d (%s)", i, step.Name)
+		}
+		if clusters != nil && !clusters[step.Cluster] {
+			errList.Append(fmt.Errorf("%s: unknown cluster %q", stepName, step.Cluster))
+		}
+		if step.Reconfiguration != nil {
+			for _, path := range step.Reconfiguration.ObjectTemplatePaths {
+				if err := validateTemplate(templateProvider, path, mapping, step.Reconfiguration.TemplateFillMap); err != nil {
+					errList.Append(fmt.Errorf("%s: reconfiguration: %v", stepName, err))
+				}
+			}
+		}
+		for j := range step.Phases {
+			for _, err := range validatePhase(templateProvider, tuningSetFactory, &step.Phases[j], mapping) {
+				errList.Append(fmt.Errorf("%s: phase %d: %v", stepName, j, err))
+			}
+		}
+		for _, m := range step.Measurements {
+			if m.Cluster != "" && clusters != nil && !clusters[m.Cluster] {
+				errList.Append(fmt.Errorf("%s: measurement %s (%s): unknown cluster %q", stepName, m.Method, m.Identifier, m.Cluster))
+			}
+			measurementConfig := &measurement.MeasurementConfig{
+				Params:              m.Params,
+				TemplateProvider:    templateProvider,
+				ClusterLoaderConfig: clusterLoaderConfig,
+				Identifier:          m.Identifier,
+				CloudProvider:       clusterLoaderConfig.ClusterConfig.Provider,
+			}
+			if err := measurement.Validate(m.Method, measurementConfig); err != nil {
+				errList.Append(fmt.Errorf("%s: measurement %s (%s): %v", stepName, m.Method, m.Identifier, err))
+			}
+		}
+	}
+	return errList
+}
+
+// getClusterNames returns names of clusters steps and measurements can be targeted at,
+// the tested cluster has an empty name.
+func getClusterNames(clusterConfig *config.ClusterConfig) (map[string]bool, error) {
+	paths, err := clusterConfig.GetClusterKubeConfigPaths()
+	if err != nil {
+		return nil, err
+	}
+	clusters := map[string]bool{"": true}
+	for name := range paths {
+		clusters[name] = true
+	}
+	if clusterConfig.Provider == "kubemark" && clusterConfig.KubemarkRootKubeConfigPath != "" {
+		clusters[config.KubemarkRootClusterName] = true
+	}
+	return clusters, nil
+}
+
+// validatePhase checks that the tuning set of the phase exists and that its objects can be templated.
+func validatePhase(templateProvider *config.TemplateProvider, tuningSetFactory tuningset.TuningSetFactory, phase *api.Phase, mapping map[string]interface{}) []error {
+	var errs []error
+	if _, err := tuningSetFactory.CreateTuningSet(phase.TuningSet); err != nil {
+		errs = append(errs, fmt.Errorf("tuning set creation error: %v", err))
+	}
+	for i := range phase.ObjectBundle {
+		object := &phase.ObjectBundle[i]
+		if _, err := templateProvider.RawToObject(object.ObjectTemplatePath); err != nil {
+			errs = append(errs, fmt.Errorf("reading template (%v) error: %v", object.ObjectTemplatePath, err))
+			continue
+		}
+		if phase.ReplicasPerNamespace == 0 {
+			continue
+		}
+		// Objects are templated the same way as when they are created by the test executor.
+		objectMapping := util.CloneMap(mapping)
+		if object.TemplateFillMap != nil {
+			util.CopyMap(object.TemplateFillMap, objectMapping)
+		}
+		objectMapping[baseNamePlaceholder] = object.Basename
+		objectMapping[namePlaceholder] = fmt.Sprintf("%v-%d", object.Basename, 0)
+		objectMapping[indexPlaceholder] = int32(0)
+		if _, err := templateProvider.TemplateToObject(object.ObjectTemplatePath, objectMapping); err != nil && err != config.ErrorEmptyFile {
+			errs = append(errs, fmt.Errorf("reading template (%v) error: %v", object.ObjectTemplatePath, err))
+		}
+	}
+	return errs
+}
+
+// validateTemplate checks that objects of the template can be templated.
+func validateTemplate(templateProvider *config.TemplateProvider, path string, mapping, templateFillMap map[string]interface{}) error {
+	templateMapping := util.CloneMap(mapping)
+	if templateFillMap != nil {
+		util.CopyMap(templateFillMap, templateMapping)
+	}
+	if _, err := templateProvider.TemplateToObject(path, templateMapping); err != nil && err != config.ErrorEmptyFile {
+		return fmt.Errorf("reading template (%v) error: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// validatedMeasurement requires threshold param to be a duration.
+type validatedMeasurement struct{}
+
+func (v *validatedMeasurement) Execute(config *measurement.MeasurementConfig) ([]measurement.Summary, error) {
+	return nil, nil
+}
+
+func (v *validatedMeasurement) Validate(config *measurement.MeasurementConfig) error {
+	_, err := util.GetDuration(config.Params, "threshold")
+	return err
+}
+
+func (v *validatedMeasurement) Dispose() {}
+
+func (v *validatedMeasurement) String() string {
+	return "Validated"
+}
+
+func init() {
+	if err := measurement.Register("Validated", func() measurement.Measurement { return &validatedMeasurement{} }); err != nil {
+		panic(err)
+	}
+}
+
+const validatedConfig = `name: validated
+tuningSets:
+- name: Uniform
+  qpsLoad:
+    qps: 10
+steps:
+- phases:
+  - namespaceRange:
+      min: 1
+      max: 1
+    replicasPerNamespace: 1
+    tuningSet: %s
+    objectBundle:
+    - basename: pod
+      objectTemplatePath: pod.yaml
+- measurements:
+  - method: %s
+    identifier: Validated
+    params:
+      threshold: %s
+`
+
+const validatedPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{.Name}}
+spec:
+  containers:
+  - name: pause
+    image: {{.Image}}
+`
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "pod.yaml"), []byte(validatedPod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		tuningSet string
+		method    string
+		threshold string
+		wantErr   string
+	}{
+		{name: "valid", tuningSet: "Uniform", method: "Validated", threshold: "1m"},
+		{name: "unknown tuning set", tuningSet: "Unifrom", method: "Validated", threshold: "1m", wantErr: "tuningset Unifrom not found"},
+		{name: "unknown measurement", tuningSet: "Uniform", method: "Validate", threshold: "1m", wantErr: "unknown measurement method Validate"},
+		{name: "invalid param", tuningSet: "Uniform", method: "Validated", threshold: "1 minute", wantErr: "unknown unit"},
+	}
+	for _, tc := range testCases {
+		path := filepath.Join(dir, "config.yaml")
+		if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(validatedConfig, tc.tuningSet, tc.method, tc.threshold)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		clusterLoaderConfig := &config.ClusterLoaderConfig{}
+		clusterLoaderConfig.TestScenario.ConfigPath = path
+		errList := Validate(clusterLoaderConfig)
+		if tc.wantErr == "" {
+			assert.True(t, errList.IsEmpty(), "%s: %v", tc.name, errList)
+			continue
+		}
+		if assert.Equal(t, 1, errList.Len(), "%s: %v", tc.name, errList) {
+			assert.True(t, strings.Contains(errList.String(), tc.wantErr), "%s: %v", tc.name, errList)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+// FailureCategory classifies why a test failed.
+type FailureCategory string
+
+const (
+	// NoFailure means that the test passed.
+	NoFailure FailureCategory = ""
+	// SLOViolation means that the only failures of the test were SLO violations detected by measurements.
+	SLOViolation FailureCategory = "SLOViolation"
+	// MeasurementFailure means that some measurements failed, but the test itself was executed.
+	MeasurementFailure FailureCategory = "MeasurementFailure"
+	// ExecutionFailure means that the test couldn't be executed as defined, e.g. objects couldn't be created.
+	ExecutionFailure FailureCategory = "ExecutionFailure"
+)
+
+// failureCategories lists categories from the least to the most severe.
+var failureCategories = []FailureCategory{NoFailure, SLOViolation, MeasurementFailure, ExecutionFailure}
+
+// exitCodes maps failure categories to exit codes of the process. Exit code 1 is reserved
+// for failures that prevented running tests at all, e.g. incorrect flags.
+var exitCodes = map[FailureCategory]int{
+	NoFailure:          0,
+	ExecutionFailure:   2,
+	MeasurementFailure: 3,
+	SLOViolation:       4,
+}
+
+// ExitCode returns exit code of the process for the category.
+func (c FailureCategory) ExitCode() int {
+	return exitCodes[c]
+}
+
+func (c FailureCategory) severity() int {
+	for i, category := range failureCategories {
+		if category == c {
+			return i
+		}
+	}
+	return len(failureCategories)
+}
+
+// Result is the outcome of a test.
+type Result struct {
+	// Test is the name of the test, empty if the test config couldn't be read.
+	Test string
+	// Errors contains all errors of the test, including SLO violations.
+	Errors *errors.ErrorList
+	// Violations lists SLO violations detected by measurements.
+	Violations []Violation
+	// Category classifies the failure of the test, the most severe failure determines it.
+	Category FailureCategory
+}
+
+// Violation describes SLO violation detected by a measurement of a test.
+type Violation struct {
+	Test        string `json:"test"`
+	Measurement string `json:"measurement"`
+	Identifier  string `json:"identifier,omitempty"`
+	Metric      string `json:"metric"`
+	Message     string `json:"message"`
+	// Observed and Threshold are set only if the violation is a single value exceeding a threshold.
+	Observed  *float64 `json:"observed,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+}
+
+// newResult creates result of the test from its errors and outcomes of its measurements.
+func newResult(testName string, errList *errors.ErrorList, results []measurement.MeasurementResult) *Result {
+	result := &Result{Test: testName, Errors: errList}
+	measurementErrors := 0
+	for _, r := range results {
+		measurementErrors += len(r.Errors)
+		for _, err := range r.Violations {
+			violation := Violation{
+				Test:        testName,
+				Measurement: r.Method,
+				Identifier:  r.Identifier,
+				Metric:      errors.GetViolatedMetric(err),
+				Message:     err.Error(),
+			}
+			if observed, threshold, ok := errors.GetViolationThreshold(err); ok {
+				violation.Observed, violation.Threshold = &observed, &threshold
+			}
+			result.Violations = append(result.Violations, violation)
+		}
+	}
+	// Every error of a measurement is reported in the error list of the test once, other errors
+	// (e.g. of objects creation) aren't attributed to measurements.
+	switch {
+	case errList.IsEmpty():
+		result.Category = NoFailure
+	case errList.Len() > measurementErrors+len(result.Violations):
+		result.Category = ExecutionFailure
+	case measurementErrors > 0:
+		result.Category = MeasurementFailure
+	default:
+		result.Category = SLOViolation
+	}
+	return result
+}
+
+// violationsFile is the content of the file listing violations of all tests.
+type violationsFile struct {
+	// Category is the most severe failure category of all tests.
+	Category   FailureCategory `json:"category"`
+	Tests      []testOutcome   `json:"tests"`
+	Violations []Violation     `json:"violations"`
+}
+
+type testOutcome struct {
+	Test     string          `json:"test"`
+	Category FailureCategory `json:"category"`
+	Errors   int             `json:"errors"`
+}
+
+// GetCategory returns the most severe failure category of given results.
+func GetCategory(results []*Result) FailureCategory {
+	category := NoFailure
+	for _, result := range results {
+		if result.Category.severity() > category.severity() {
+			category = result.Category
+		}
+	}
+	return category
+}
+
+// WriteViolations writes JSON file listing violations and failure categories of given results.
+func WriteViolations(path string, results []*Result) error {
+	file := violationsFile{Category: GetCategory(results), Tests: []testOutcome{}, Violations: []Violation{}}
+	for _, result := range results {
+		file.Tests = append(file.Tests, testOutcome{Test: result.Test, Category: result.Category, Errors: result.Errors.Len()})
+		file.Violations = append(file.Violations, result.Violations...)
+	}
+	content, err := util.PrettyPrintJSON(file)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing violations to %s error: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/perf-tests/clusterloader2/pkg/errors"
+	"k8s.io/perf-tests/clusterloader2/pkg/measurement"
+)
+
+func TestNewResult(t *testing.T) {
+	violation := errors.NewMetricViolationErrorWithThreshold("pod startup", "too high", 7, 5)
+	measurementErr := fmt.Errorf("timeout")
+	results := []measurement.MeasurementResult{
+		{Method: "PodStartupLatency", Identifier: "PodStartupLatency", Violations: []error{violation}},
+		{Method: "APIResponsiveness", Violations: []error{errors.NewMetricViolationError("top latency metric", "too high")}},
+	}
+
+	result := newResult("load", errors.NewErrorList(), results[:0])
+	assert.Equal(t, NoFailure, result.Category)
+
+	result = newResult("load", errors.NewErrorList(violation, results[1].Violations[0]), results)
+	assert.Equal(t, SLOViolation, result.Category)
+	assert.Len(t, result.Violations, 2)
+	assert.Equal(t, "load", result.Violations[0].Test)
+	assert.Equal(t, "pod startup", result.Violations[0].Metric)
+	assert.Equal(t, 7.0, *result.Violations[0].Observed)
+	assert.Equal(t, 5.0, *result.Violations[0].Threshold)
+	assert.Nil(t, result.Violations[1].Observed)
+
+	withError := append(results, measurement.MeasurementResult{Method: "WaitForControlledPodsRunning", Errors: []error{measurementErr}})
+	result = newResult("load", errors.NewErrorList(violation, results[1].Violations[0], measurementErr), withError)
+	assert.Equal(t, MeasurementFailure, result.Category)
+
+	result = newResult("load", errors.NewErrorList(violation, fmt.Errorf("object creation error")), results[:1])
+	assert.Equal(t, ExecutionFailure, result.Category)
+}
+
+func TestWriteViolations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "violations")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	results := []*Result{
+		{Test: "density", Errors: errors.NewErrorList(), Category: NoFailure},
+		{Test: "load", Errors: errors.NewErrorList(fmt.Errorf("violation")), Category: SLOViolation,
+			Violations: []Violation{{Test: "load", Measurement: "APIResponsiveness", Metric: "top latency metric"}}},
+	}
+	assert.Equal(t, SLOViolation, GetCategory(results))
+	assert.Equal(t, 4, GetCategory(results).ExitCode())
+	assert.Equal(t, 0, GetCategory(results[:1]).ExitCode())
+	assert.Equal(t, ExecutionFailure, GetCategory(append(results, &Result{Category: ExecutionFailure})))
+
+	path := filepath.Join(dir, "violations.json")
+	if err := WriteViolations(path, results); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var file violationsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, SLOViolation, file.Category)
+	assert.Equal(t, []testOutcome{{Test: "density"}, {Test: "load", Category: SLOViolation, Errors: 1}}, file.Tests)
+	assert.Equal(t, results[1].Violations, file.Violations)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tuningset
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/control"
+)
+
+func TestGatedQpsLoad(t *testing.T) {
+	controller := control.NewController()
+	controller.Pause()
+	factory := NewTuningSetFactory(controller)
+	factory.Init([]api.TuningSet{{Name: "qps", QpsLoad: &api.QpsLoad{Qps: 100}}})
+	tuningSet, err := factory.CreateTuningSet("qps")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var started int32
+	actions := make([]func(), 5)
+	for i := range actions {
+		actions[i] = func() { atomic.AddInt32(&started, 1) }
+	}
+	done := make(chan struct{})
+	go func() {
+		tuningSet.Execute(context.Background(), actions)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&started); n != 0 {
+		t.Fatalf("%d actions started while the load is paused", n)
+	}
+	controller.Resume()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("actions not finished after the load was resumed")
+	}
+	if n := atomic.LoadInt32(&started); n != int32(len(actions)) {
+		t.Errorf("got %d started actions, want %d", n, len(actions))
+	}
+}
+
+func TestGatedLoadCancelled(t *testing.T) {
+	controller := control.NewController()
+	controller.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	tuningSet := newParallelismLimitedLoad(&api.ParallelismLimitedLoad{ParallelismLimit: 2}, controller)
+
+	var started int32
+	actions := make([]func(), 5)
+	for i := range actions {
+		actions[i] = func() { atomic.AddInt32(&started, 1) }
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	tuningSet.Execute(ctx, actions)
+	if n := atomic.LoadInt32(&started); n != 0 {
+		t.Errorf("%d actions started after the test was cancelled", n)
+	}
+}
@@ -17,12 +17,21 @@ limitations under the License.
 package tuningset
 
 import (
+	"context"
+
 	"k8s.io/perf-tests/clusterloader2/api"
 )
 
 // TuningSet executes action sets.
 type TuningSet interface {
-	Execute(actions []func())
+	// Execute executes actions and waits for them. Once ctx is done, remaining actions are not started.
+	Execute(ctx context.Context, actions []func())
+}
+
+// Gate decides when actions can be started, e.g. it blocks them while the load is paused.
+type Gate interface {
+	// WaitIfPaused blocks while actions shouldn't be started. It returns ctx error if ctx is done.
+	WaitIfPaused(ctx context.Context) error
 }
 
 // TuningSetFactory is a factory that creates tuning sets.
@@ -25,17 +25,21 @@ import (
 
 type parallelismLimitedLoad struct {
 	params *api.ParallelismLimitedLoad
+	gate   Gate
 }
 
-func newParallelismLimitedLoad(params *api.ParallelismLimitedLoad) TuningSet {
+func newParallelismLimitedLoad(params *api.ParallelismLimitedLoad, gate Gate) TuningSet {
 	return &parallelismLimitedLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (p *parallelismLimitedLoad) Execute(actions []func()) {
+func (p *parallelismLimitedLoad) Execute(ctx context.Context, actions []func()) {
 	executeAction := func(i int) {
-		actions[i]()
+		if waitForGate(ctx, p.gate) {
+			actions[i]()
+		}
 	}
-	workqueue.ParallelizeUntil(context.TODO(), int(p.params.ParallelismLimit), len(actions), executeAction)
+	workqueue.ParallelizeUntil(ctx, int(p.params.ParallelismLimit), len(actions), executeAction)
 }
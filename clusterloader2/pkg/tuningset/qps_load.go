@@ -17,6 +17,7 @@ limitations under the License.
 package tuningset
 
 import (
+	"context"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -25,20 +26,27 @@ import (
 
 type qpsLoad struct {
 	params *api.QpsLoad
+	gate   Gate
 }
 
-func newQpsLoad(params *api.QpsLoad) TuningSet {
+func newQpsLoad(params *api.QpsLoad, gate Gate) TuningSet {
 	return &qpsLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (ql *qpsLoad) Execute(actions []func()) {
+func (ql *qpsLoad) Execute(ctx context.Context, actions []func()) {
 	sleepDuration := time.Duration(int(float64(time.Second) / ql.params.Qps))
 	var wg wait.Group
 	for i := range actions {
+		if !waitForGate(ctx, ql.gate) {
+			break
+		}
 		wg.Start(actions[i])
-		time.Sleep(sleepDuration)
+		if !sleep(ctx, sleepDuration) {
+			break
+		}
 	}
 	wg.Wait()
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package tuningset
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
@@ -26,19 +27,26 @@ import (
 
 type randomizedLoad struct {
 	params *api.RandomizedLoad
+	gate   Gate
 }
 
-func newRandomizedLoad(params *api.RandomizedLoad) TuningSet {
+func newRandomizedLoad(params *api.RandomizedLoad, gate Gate) TuningSet {
 	return &randomizedLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (rl *randomizedLoad) Execute(actions []func()) {
+func (rl *randomizedLoad) Execute(ctx context.Context, actions []func()) {
 	var wg wait.Group
 	for i := range actions {
+		if !waitForGate(ctx, rl.gate) {
+			break
+		}
 		wg.Start(actions[i])
-		time.Sleep(sleepDuration(rl.params.AverageQps))
+		if !sleep(ctx, sleepDuration(rl.params.AverageQps)) {
+			break
+		}
 	}
 	wg.Wait()
 }
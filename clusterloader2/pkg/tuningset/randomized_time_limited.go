@@ -17,6 +17,7 @@ limitations under the License.
 package tuningset
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
@@ -26,22 +27,25 @@ import (
 
 type randomizedTimeLimitedLoad struct {
 	params *api.RandomizedTimeLimitedLoad
+	gate   Gate
 }
 
-func newRandomizedTimeLimitedLoad(params *api.RandomizedTimeLimitedLoad) TuningSet {
+func newRandomizedTimeLimitedLoad(params *api.RandomizedTimeLimitedLoad, gate Gate) TuningSet {
 	return &randomizedTimeLimitedLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (r *randomizedTimeLimitedLoad) Execute(actions []func()) {
+func (r *randomizedTimeLimitedLoad) Execute(ctx context.Context, actions []func()) {
 	var wg wait.Group
 	for i := range actions {
 		index := i
 		wg.Start(func() {
 			// Sleeps for random duration in [0, TimeLimit].
-			time.Sleep(time.Duration(rand.Int63n(r.params.TimeLimit.ToTimeDuration().Nanoseconds())))
-			actions[index]()
+			if sleep(ctx, time.Duration(rand.Int63n(r.params.TimeLimit.ToTimeDuration().Nanoseconds()))) && waitForGate(ctx, r.gate) {
+				actions[index]()
+			}
 		})
 	}
 	wg.Wait()
@@ -24,12 +24,15 @@ import (
 
 type simpleTuningSetFactory struct {
 	tuningSetMap map[string]*api.TuningSet
+	gate         Gate
 }
 
-// NewTuningSetFactory creates new ticker factory.
-func NewTuningSetFactory() TuningSetFactory {
+// NewTuningSetFactory creates new ticker factory. Tuning sets start actions only when the gate
+// lets them, so that e.g. the rate of a paused load is kept once it's resumed. Gate can be nil.
+func NewTuningSetFactory(gate Gate) TuningSetFactory {
 	return &simpleTuningSetFactory{
 		tuningSetMap: make(map[string]*api.TuningSet),
+		gate:         gate,
 	}
 }
 
@@ -49,17 +52,17 @@ func (tf *simpleTuningSetFactory) CreateTuningSet(name string) (TuningSet, error
 	}
 	switch {
 	case tuningSet.QpsLoad != nil:
-		return newQpsLoad(tuningSet.QpsLoad), nil
+		return newQpsLoad(tuningSet.QpsLoad, tf.gate), nil
 	case tuningSet.RandomizedLoad != nil:
-		return newRandomizedLoad(tuningSet.RandomizedLoad), nil
+		return newRandomizedLoad(tuningSet.RandomizedLoad, tf.gate), nil
 	case tuningSet.SteppedLoad != nil:
-		return newSteppedLoad(tuningSet.SteppedLoad), nil
+		return newSteppedLoad(tuningSet.SteppedLoad, tf.gate), nil
 	case tuningSet.TimeLimitedLoad != nil:
-		return newTimeLimitedLoad(tuningSet.TimeLimitedLoad), nil
+		return newTimeLimitedLoad(tuningSet.TimeLimitedLoad, tf.gate), nil
 	case tuningSet.RandomizedTimeLimitedLoad != nil:
-		return newRandomizedTimeLimitedLoad(tuningSet.RandomizedTimeLimitedLoad), nil
+		return newRandomizedTimeLimitedLoad(tuningSet.RandomizedTimeLimitedLoad, tf.gate), nil
 	case tuningSet.ParallelismLimitedLoad != nil:
-		return newParallelismLimitedLoad(tuningSet.ParallelismLimitedLoad), nil
+		return newParallelismLimitedLoad(tuningSet.ParallelismLimitedLoad, tf.gate), nil
 	default:
 		return nil, fmt.Errorf("incorrect tuning set: %v", tuningSet)
 	}
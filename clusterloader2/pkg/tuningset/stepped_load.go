@@ -17,7 +17,7 @@ limitations under the License.
 package tuningset
 
 import (
-	"time"
+	"context"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/perf-tests/clusterloader2/api"
@@ -25,21 +25,26 @@ import (
 
 type steppedLoad struct {
 	params *api.SteppedLoad
+	gate   Gate
 }
 
-func newSteppedLoad(params *api.SteppedLoad) TuningSet {
+func newSteppedLoad(params *api.SteppedLoad, gate Gate) TuningSet {
 	return &steppedLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (sl *steppedLoad) Execute(actions []func()) {
+func (sl *steppedLoad) Execute(ctx context.Context, actions []func()) {
 	sleepDuration := sl.params.StepDelay.ToTimeDuration()
 	var wg wait.Group
 	for i := range actions {
+		if !waitForGate(ctx, sl.gate) {
+			break
+		}
 		wg.Start(actions[i])
-		if (i+1)%int(sl.params.BurstSize) == 0 {
-			time.Sleep(sleepDuration)
+		if (i+1)%int(sl.params.BurstSize) == 0 && !sleep(ctx, sleepDuration) {
+			break
 		}
 	}
 	wg.Wait()
@@ -17,6 +17,7 @@ limitations under the License.
 package tuningset
 
 import (
+	"context"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -25,20 +26,27 @@ import (
 
 type timeLimitedLoad struct {
 	params *api.TimeLimitedLoad
+	gate   Gate
 }
 
-func newTimeLimitedLoad(params *api.TimeLimitedLoad) TuningSet {
+func newTimeLimitedLoad(params *api.TimeLimitedLoad, gate Gate) TuningSet {
 	return &timeLimitedLoad{
 		params: params,
+		gate:   gate,
 	}
 }
 
-func (t *timeLimitedLoad) Execute(actions []func()) {
+func (t *timeLimitedLoad) Execute(ctx context.Context, actions []func()) {
 	sleepDuration := time.Duration(t.params.TimeLimit.ToTimeDuration().Nanoseconds() / int64(len(actions)))
 	var wg wait.Group
 	for i := range actions {
+		if !waitForGate(ctx, t.gate) {
+			break
+		}
 		wg.Start(actions[i])
-		time.Sleep(sleepDuration)
+		if !sleep(ctx, sleepDuration) {
+			break
+		}
 	}
 	wg.Wait()
 }
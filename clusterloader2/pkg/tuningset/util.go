@@ -0,0 +1,43 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tuningset
+
+import (
+	"context"
+	"time"
+)
+
+// waitForGate waits until the gate lets the next action be started, returning false if ctx is done earlier.
+// Nil gate never blocks.
+func waitForGate(ctx context.Context, gate Gate) bool {
+	if gate == nil {
+		return true
+	}
+	return gate.WaitIfPaused(ctx) == nil
+}
+
+// sleep waits for given duration, returning false if ctx is done earlier.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
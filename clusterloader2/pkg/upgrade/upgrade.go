@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/perf-tests/clusterloader2/api"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+)
+
+// Upgrader upgrades the cluster.
+type Upgrader interface {
+	// Upgrade upgrades the cluster to given version. It should return once the upgrade
+	// is finished or ctx is done.
+	Upgrade(ctx context.Context, version string) error
+}
+
+// UpgraderFactory creates an upgrader of the cluster described by given config.
+type UpgraderFactory func(clusterConfig *config.ClusterConfig) (Upgrader, error)
+
+var (
+	lock      sync.Mutex
+	upgraders = make(map[string]UpgraderFactory)
+)
+
+// Register registers upgrader factory for given provider.
+func Register(provider string, factory UpgraderFactory) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, exists := upgraders[provider]; exists {
+		return fmt.Errorf("upgrader for provider %s is already registered", provider)
+	}
+	upgraders[provider] = factory
+	return nil
+}
+
+// Run upgrades the cluster with the external command or, if the command is not set,
+// with the upgrader registered for the cluster provider.
+func Run(upgrade *api.Upgrade, clusterConfig *config.ClusterConfig) error {
+	upgrader, err := newUpgrader(upgrade, clusterConfig)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	timeout := time.Duration(upgrade.Timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	logrus.Infof("Upgrading cluster to version %q", upgrade.Version)
+	start := time.Now()
+	if err := upgrader.Upgrade(ctx, upgrade.Version); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("upgrade timed out after %v: %v", timeout, err)
+		}
+		return err
+	}
+	logrus.Infof("Cluster upgraded to version %q in %v", upgrade.Version, time.Since(start))
+	return nil
+}
+
+func newUpgrader(upgrade *api.Upgrade, clusterConfig *config.ClusterConfig) (Upgrader, error) {
+	if len(upgrade.Command) > 0 {
+		return &commandUpgrader{command: upgrade.Command, kubeConfigPath: clusterConfig.KubeConfigPath}, nil
+	}
+	lock.Lock()
+	factory, exists := upgraders[clusterConfig.Provider]
+	lock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("no upgrader registered for provider %s, upgrade command should be set", clusterConfig.Provider)
+	}
+	return factory(clusterConfig)
+}
+
+// commandUpgrader upgrades the cluster with an external command.
+type commandUpgrader struct {
+	command        []string
+	kubeConfigPath string
+}
+
+// Upgrade runs the command with UPGRADE_VERSION and KUBECONFIG environment variables set.
+// Output of the command is logged.
+func (c *commandUpgrader) Upgrade(ctx context.Context, version string) error {
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Env = append(os.Environ(), "UPGRADE_VERSION="+version)
+	if c.kubeConfigPath != "" {
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+c.kubeConfigPath)
+	}
+	output := logrus.WithField("upgrade", c.command[0]).Writer()
+	defer output.Close()
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("upgrade command %v error: %v", c.command, err)
+	}
+	return nil
+}
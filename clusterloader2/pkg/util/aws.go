@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"os"
+
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
+)
+
+// GetAWSCredentials returns AWS credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and (optional) AWS_SESSION_TOKEN credentials (by default, environment variables).
+func GetAWSCredentials() (*awscredentials.Credentials, error) {
+	var accessKeyID, secretAccessKey, sessionToken string
+	for name, value := range map[string]*string{
+		credentials.AWSAccessKeyID:     &accessKeyID,
+		credentials.AWSSecretAccessKey: &secretAccessKey,
+		credentials.AWSSessionToken:    &sessionToken,
+	} {
+		var err error
+		if *value, err = credentials.Get(name); err != nil {
+			return nil, err
+		}
+	}
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("%s and %s have to be provided", credentials.AWSAccessKeyID, credentials.AWSSecretAccessKey)
+	}
+	return awscredentials.NewStaticCredentials(accessKeyID, secretAccessKey, sessionToken), nil
+}
+
+// GetAWSRegion returns AWS region from AWS_REGION or AWS_DEFAULT_REGION environment variables.
+// If none of them is set, defaultRegion is returned.
+func GetAWSRegion(defaultRegion string) string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	if region := os.Getenv("AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return defaultRegion
+}
+
+// LookupAWSRegion returns AWS region from environment variables (see GetAWSRegion) or,
+// if none of them is set, region of the EC2 instance clusterloader is running on.
+func LookupAWSRegion() (string, error) {
+	if region := GetAWSRegion(""); region != "" {
+		return region, nil
+	}
+	endpoint, err := endpoints.DefaultResolver().EndpointFor(endpoints.Ec2metadataServiceID, "")
+	if err != nil {
+		return "", err
+	}
+	metadata := ec2metadata.NewClient(*defaults.Config(), defaults.Handlers(), endpoint.URL, endpoint.SigningRegion)
+	region, err := metadata.Region()
+	if err != nil {
+		return "", fmt.Errorf("AWS region isn't set and getting it from instance metadata failed: %v", err)
+	}
+	return region, nil
+}
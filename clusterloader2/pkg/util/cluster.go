@@ -47,6 +47,21 @@ func GetSchedulableUntainedNodes(c clientset.Interface) ([]corev1.Node, error) {
 	return filtered, err
 }
 
+// GetSchedulableNodes returns schedulable nodes in the cluster, including tainted ones.
+func GetSchedulableNodes(c clientset.Interface) ([]corev1.Node, error) {
+	nodeList, err := client.ListNodes(c)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []corev1.Node
+	for i := range nodeList {
+		if isNodeSchedulable(&nodeList[i]) {
+			filtered = append(filtered, nodeList[i])
+		}
+	}
+	return filtered, err
+}
+
 // LogClusterNodes prints nodes information (name, internal ip, external ip) to log.
 func LogClusterNodes(c clientset.Interface) error {
 	nodeList, err := client.ListNodes(c)
@@ -75,6 +90,11 @@ func IsNodeSchedulableAndUntainted(node *corev1.Node) bool {
 	return isNodeSchedulable(node) && isNodeUntainted(node)
 }
 
+// IsNodeReady returns true if node's Ready condition is set to true.
+func IsNodeReady(node *corev1.Node) bool {
+	return isNodeConditionSetAsExpected(node, corev1.NodeReady, true, true)
+}
+
 // Node is schedulable if:
 // 1) doesn't have "unschedulable" field set
 // 2) it's Ready condition is set to true
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// maxErrorBodyLength limits the part of the response body included in the error message.
+const maxErrorBodyLength = 512
+
+// SendHTTPRequest sends the request and closes the response.
+// Error is returned if the request fails or its status isn't successful.
+// Error returned by the client is passed through unchanged.
+func SendHTTPRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return CheckHTTPResponse(response)
+}
+
+// CheckHTTPResponse returns error if status of the response isn't successful.
+// The error contains the beginning of the response body. The response isn't closed.
+func CheckHTTPResponse(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	if len(responseBody) > maxErrorBodyLength {
+		responseBody = responseBody[:maxErrorBodyLength]
+	}
+	return fmt.Errorf("status %d: %s", response.StatusCode, string(bytes.TrimSpace(responseBody)))
+}
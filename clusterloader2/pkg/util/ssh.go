@@ -23,6 +23,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	"k8s.io/perf-tests/clusterloader2/pkg/credentials"
 )
 
 // SSH executes command on a given node with stdin provided.
@@ -32,7 +33,13 @@ func SSH(command string, node *v1.Node, stdin io.Reader) error {
 	if !ok {
 		return fmt.Errorf("unknown zone for %q node: no failure-domain.beta.kubernetes.io/zone label", node.Name)
 	}
+	env, cleanup, err := credentials.GCloudEnv()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	cmd := exec.Command("gcloud", "compute", "ssh", "--zone", zone, "--command", command, node.Name)
+	cmd.Env = env
 	cmd.Stdin = stdin
 	output, err := cmd.CombinedOutput()
 	logrus.Infof("ssh to %q finished with %q: %v", node.Name, string(output), err)
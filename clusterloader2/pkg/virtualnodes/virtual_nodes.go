@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualnodes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/perf-tests/clusterloader2/pkg/config"
+	"k8s.io/perf-tests/clusterloader2/pkg/flags"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework"
+	"k8s.io/perf-tests/clusterloader2/pkg/framework/client"
+	"k8s.io/perf-tests/clusterloader2/pkg/util"
+)
+
+const (
+	namespace         = "virtual-nodes"
+	manifests         = "pkg/virtualnodes/manifest/*.yaml"
+	nodeLabelSelector = "type=virtual-kubelet"
+
+	checkNodesReadyInterval = 10 * time.Second
+	// Registering many nodes takes a while, so the timeout grows with the number of nodes.
+	checkNodesReadyTimeoutPerNode = 5 * time.Second
+	checkNodesReadyMinTimeout     = 5 * time.Minute
+)
+
+// InitFlags initializes virtual nodes flags.
+func InitFlags(c *config.VirtualNodesConfig) {
+	flags.BoolEnvVar(&c.Enable, "enable-virtual-nodes", "ENABLE_VIRTUAL_NODES", false, "Whether to register simulated, virtual-kubelet based nodes in the cluster before running tests.")
+	flags.BoolEnvVar(&c.TearDown, "tear-down-virtual-nodes", "TEAR_DOWN_VIRTUAL_NODES", true, "Whether to remove virtual nodes after tests (if set-up).")
+	flags.IntEnvVar(&c.Count, "virtual-nodes", "VIRTUAL_NODES", 0, "Number of virtual nodes. If not set, the value of the nodes flag is used.")
+	flags.StringEnvVar(&c.Image, "virtual-kubelet-image", "VIRTUAL_KUBELET_IMAGE", "virtualkubelet/virtual-kubelet:1.2.1", "Virtual kubelet image, has to contain the mock provider.")
+	flags.StringEnvVar(&c.CPU, "virtual-node-cpu", "VIRTUAL_NODE_CPU", "4", "CPU capacity reported by a single virtual node.")
+	flags.StringEnvVar(&c.Memory, "virtual-node-memory", "VIRTUAL_NODE_MEMORY", "16Gi", "Memory capacity reported by a single virtual node.")
+	flags.IntEnvVar(&c.MaxPods, "virtual-node-max-pods", "VIRTUAL_NODE_MAX_PODS", 110, "Pods capacity reported by a single virtual node.")
+}
+
+// SetUpVirtualNodes starts virtual kubelets in the cluster and waits until
+// all of them register as ready nodes.
+// Virtual kubelets use the mock provider, i.e. pods scheduled to virtual nodes
+// are reported as running, but no containers are started. This allows testing
+// the control plane at scale without provisioning machines.
+func SetUpVirtualNodes(f *framework.Framework, c *config.VirtualNodesConfig) error {
+	logrus.Infof("Setting up %d virtual nodes", c.Count)
+	k8sClient := f.GetClientSets().GetClient()
+	if err := client.CreateNamespace(k8sClient, namespace, f.GetClusterConfig().RunID); err != nil {
+		return fmt.Errorf("namespace %s creation error: %v", namespace, err)
+	}
+	mapping := map[string]interface{}{
+		"Namespace": namespace,
+		"Nodes":     c.Count,
+		"Image":     c.Image,
+		"CPU":       c.CPU,
+		"Memory":    c.Memory,
+		"MaxPods":   c.MaxPods,
+	}
+	if err := f.ApplyTemplatedManifests(manifests, mapping, client.Retry(apierrs.IsNotFound)); err != nil {
+		return fmt.Errorf("virtual kubelet creation error: %v", err)
+	}
+
+	timeout := time.Duration(c.Count) * checkNodesReadyTimeoutPerNode
+	if timeout < checkNodesReadyMinTimeout {
+		timeout = checkNodesReadyMinTimeout
+	}
+	if err := wait.Poll(checkNodesReadyInterval, timeout, func() (bool, error) {
+		ready, err := countReadyVirtualNodes(f)
+		if err != nil {
+			logrus.Warningf("Listing virtual nodes error: %v", err)
+			return false, nil
+		}
+		logrus.Infof("Virtual nodes: %d out of %d ready", ready, c.Count)
+		return ready >= c.Count, nil
+	}); err != nil {
+		return fmt.Errorf("waiting for virtual nodes error: %v", err)
+	}
+	logrus.Info("Virtual nodes set up successfully")
+	return nil
+}
+
+// TearDownVirtualNodes stops virtual kubelets and removes virtual nodes from the cluster.
+func TearDownVirtualNodes(f *framework.Framework) error {
+	logrus.Info("Tearing down virtual nodes")
+	k8sClient := f.GetClientSets().GetClient()
+	if err := client.DeleteNamespace(k8sClient, namespace); err != nil {
+		return fmt.Errorf("deleting %s namespace error: %v", namespace, err)
+	}
+	if err := client.WaitForDeleteNamespace(k8sClient, namespace); err != nil {
+		return err
+	}
+	// Nodes are not removed when virtual kubelet is stopped.
+	deleteNodes := func() error {
+		return k8sClient.CoreV1().Nodes().DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: nodeLabelSelector})
+	}
+	if err := client.RetryWithExponentialBackOff(client.RetryFunction(deleteNodes)); err != nil {
+		return fmt.Errorf("deleting virtual nodes error: %v", err)
+	}
+	return nil
+}
+
+func countReadyVirtualNodes(f *framework.Framework) (int, error) {
+	nodes, err := client.ListNodesWithOptions(f.GetClientSets().GetClient(), metav1.ListOptions{LabelSelector: nodeLabelSelector})
+	if err != nil {
+		return 0, err
+	}
+	ready := 0
+	for i := range nodes {
+		if util.IsNodeSchedulableAndUntainted(&nodes[i]) {
+			ready++
+		}
+	}
+	return ready, nil
+}
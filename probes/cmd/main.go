@@ -25,13 +25,15 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/klog"
 	"k8s.io/perf-tests/probes/pkg/dns"
+	"k8s.io/perf-tests/probes/pkg/egress"
+	"k8s.io/perf-tests/probes/pkg/nodelatency"
 	pingclient "k8s.io/perf-tests/probes/pkg/ping/client"
 	pingserver "k8s.io/perf-tests/probes/pkg/ping/server"
 )
 
 var (
 	metricAddress = flag.String("metric-bind-address", "0.0.0.0:8080", "The address to serve the Prometheus metrics on.")
-	mode          = flag.String("mode", "", "Mode that should be run. Supported values: ping-server, ping-client, dns")
+	mode          = flag.String("mode", "", "Mode that should be run. Supported values: ping-server, ping-client, dns, node-latency, egress")
 )
 
 func main() {
@@ -51,6 +53,10 @@ func main() {
 		pingserver.Run(pingserver.NewDefaultPingServerConfig())
 	case "dns":
 		dns.Run()
+	case "node-latency":
+		nodelatency.Run(nodelatency.NewDefaultNodeLatencyConfig())
+	case "egress":
+		egress.Run()
 	default:
 		klog.Fatalf("Unrecognized mode: %q", *mode)
 	}
@@ -4,5 +4,8 @@ go 1.12
 
 require (
 	github.com/prometheus/client_golang v0.9.2
-	k8s.io/klog v0.2.0
+	k8s.io/api v0.17.0
+	k8s.io/apimachinery v0.17.0
+	k8s.io/client-go v0.17.0
+	k8s.io/klog v1.0.0
 )
@@ -0,0 +1,48 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/perf-tests/probes/pkg/common"
+)
+
+var (
+	// egressLatency is the latency of requests from the cluster to the external endpoint.
+	egressLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "egress_latency_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 17), // from 0.5ms up to ~33s
+		Help:      "Histogram of the time (in seconds) it took to send a request to the external endpoint.",
+	})
+	// egressRequestCount counts requests sent to the external endpoint.
+	egressRequestCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "egress_request_count",
+		Help:      "Counter of requests to the external endpoint made by egress-prober.",
+	})
+	// egressError counts failed requests to the external endpoint.
+	egressError = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "egress_error",
+		Help:      "Counter of requests to the external endpoint made by egress-prober that failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(egressLatency, egressRequestCount, egressError)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/klog"
+)
+
+var (
+	target   = flag.String("egress-target", "", "The external endpoint to probe, either an HTTP(S) URL (e.g. http://example.com/) or tcp://host:port to only connect")
+	interval = flag.Duration("egress-interval", 1*time.Second, "Interval between requests to the external endpoint")
+	timeout  = flag.Duration("egress-timeout", 10*time.Second, "Timeout of a single request to the external endpoint")
+)
+
+// Run periodically sends requests to the external endpoint.
+// Endpoint, interval and timeout are configurable via flags.
+func Run() {
+	if *target == "" {
+		klog.Fatal("--egress-target has not been set")
+	}
+	probe, err := newProbeFunc(*target, *timeout)
+	if err != nil {
+		klog.Fatalf("Incorrect --egress-target: %v", err)
+	}
+	run(probe, *interval)
+}
+
+func run(probe func() error, interval time.Duration) {
+	klog.Infof("Starting egress-prober...")
+	for {
+		time.Sleep(interval)
+		startTime := time.Now()
+		egressRequestCount.Inc()
+		if err := probe(); err != nil {
+			klog.Warningf("got error: %v", err)
+			egressError.Inc()
+			continue
+		}
+		latency := time.Since(startTime)
+		klog.V(4).Infof("egress request took %v", latency)
+		egressLatency.Observe(latency.Seconds())
+	}
+}
+
+// newProbeFunc returns a function sending a single request to the target.
+func newProbeFunc(target string, timeout time.Duration) (func() error, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "tcp":
+		return func() error {
+			conn, err := net.DialTimeout("tcp", u.Host, timeout)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, nil
+	case "http", "https":
+		// Connections are not reused, so that every request goes through the whole egress path.
+		client := &http.Client{
+			Transport: &http.Transport{DisableKeepAlives: true, Proxy: http.ProxyFromEnvironment},
+			Timeout:   timeout,
+		}
+		return func() error {
+			resp, err := client.Get(target)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+				return err
+			}
+			// Any response of the endpoint proves the connectivity, unless the endpoint (or a proxy) is failing.
+			if resp.StatusCode >= http.StatusInternalServerError {
+				return fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodelatency
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+var (
+	nodeName        = flag.String("node-latency-node-name", "", "Name of the node the agent is running on")
+	trackImagePulls = flag.Bool("node-latency-track-image-pulls", false, "Whether to track image pulls. Kubelet events of pods are watched and filtered by the node")
+)
+
+// Config configures the "node-latency" probe.
+type Config struct {
+	nodeName        string
+	trackImagePulls bool
+}
+
+// NewDefaultNodeLatencyConfig creates a default "node-latency" config.
+func NewDefaultNodeLatencyConfig() *Config {
+	if *nodeName == "" {
+		klog.Fatal("--node-latency-node-name not set!")
+	}
+	return &Config{
+		nodeName:        *nodeName,
+		trackImagePulls: *trackImagePulls,
+	}
+}
+
+// Run runs the node latency agent that watches pods bound to the node and exports
+// latencies of their startup operations.
+// Pods are observed through the apiserver, so latencies include pod status updates.
+// Only pods scheduled after the agent has started are taken into account.
+func Run(config *Config) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		klog.Fatalf("Error while getting in-cluster config: %v", err)
+	}
+	client := kubernetes.NewForConfigOrDie(restConfig)
+	t := newTracker(config.nodeName, time.Now())
+	stopCh := make(chan struct{})
+
+	podSelector := fields.OneTermEqualSelector("spec.nodeName", config.nodeName).String()
+	podListWatch := cache.NewFilteredListWatchFromClient(client.CoreV1().RESTClient(), "pods", v1.NamespaceAll,
+		func(options *metav1.ListOptions) { options.FieldSelector = podSelector })
+	podInformer := cache.NewSharedInformer(podListWatch, &v1.Pod{}, 0)
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    t.observePod,
+		UpdateFunc: func(_, obj interface{}) { t.observePod(obj) },
+		DeleteFunc: t.forgetPod,
+	})
+	go podInformer.Run(stopCh)
+	if config.trackImagePulls {
+		watchKubeletEvents(client, stopCh, t.observeEvent)
+	}
+
+	klog.Infof("Tracking pods on node %s...", config.nodeName)
+	<-stopCh
+}
+
+// watchKubeletEvents watches kubelet events of pods until stopCh is closed.
+// Events can't be selected by node on the server side, so events of other nodes
+// are filtered out by the handler.
+func watchKubeletEvents(client kubernetes.Interface, stopCh <-chan struct{}, handler func(obj interface{})) {
+	eventSelector := fields.AndSelectors(
+		fields.OneTermEqualSelector("involvedObject.kind", "Pod"),
+		fields.OneTermEqualSelector("source", "kubelet"),
+	).String()
+	eventListWatch := cache.NewFilteredListWatchFromClient(client.CoreV1().RESTClient(), "events", v1.NamespaceAll,
+		func(options *metav1.ListOptions) { options.FieldSelector = eventSelector })
+	eventInformer := cache.NewSharedInformer(eventListWatch, &v1.Event{}, 0)
+	eventInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    handler,
+		UpdateFunc: func(_, obj interface{}) { handler(obj) },
+	})
+	go eventInformer.Run(stopCh)
+}
+
+type podState struct {
+	// ipObserved is the time when the IP of the pod was first observed.
+	ipObserved time.Time
+	running    bool
+	// pullStarts maps container field path to the time when its image pull has started.
+	pullStarts map[string]time.Time
+}
+
+type tracker struct {
+	nodeName  string
+	startTime time.Time
+
+	lock sync.Mutex
+	// pods maps pod key (namespace/name) to its state.
+	pods map[string]*podState
+}
+
+func newTracker(nodeName string, startTime time.Time) *tracker {
+	return &tracker{
+		nodeName:  nodeName,
+		startTime: startTime,
+		pods:      make(map[string]*podState),
+	}
+}
+
+func (t *tracker) getState(key string) *podState {
+	state, ok := t.pods[key]
+	if !ok {
+		state = &podState{pullStarts: make(map[string]time.Time)}
+		t.pods[key] = state
+	}
+	return state
+}
+
+func (t *tracker) observePod(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	scheduled := getPodScheduledTime(pod)
+	// Pods with host network get node IP, so it doesn't indicate that the sandbox is set up.
+	if scheduled.IsZero() || scheduled.Before(t.startTime) || pod.Spec.HostNetwork {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(pod)
+	if err != nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	state := t.getState(key)
+	if state.ipObserved.IsZero() && pod.Status.PodIP != "" {
+		state.ipObserved = now
+		latency := now.Sub(scheduled)
+		klog.V(4).Infof("%s: IP observed in %v", key, latency)
+		nodePodIPObservedLatency.Observe(latency.Seconds())
+	}
+	if !state.running && !state.ipObserved.IsZero() && areAllContainersRunning(pod) {
+		state.running = true
+		latency := now.Sub(state.ipObserved)
+		klog.V(4).Infof("%s: containers started in %v", key, latency)
+		nodeContainerStartLatency.Observe(latency.Seconds())
+	}
+}
+
+func (t *tracker) forgetPod(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.pods, key)
+}
+
+func (t *tracker) observeEvent(obj interface{}) {
+	event, ok := obj.(*v1.Event)
+	if !ok || event.Source.Host != t.nodeName {
+		return
+	}
+	timestamp := event.LastTimestamp.Time
+	if timestamp.Before(t.startTime) {
+		return
+	}
+	key := event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name
+	container := event.InvolvedObject.FieldPath
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch event.Reason {
+	case "Pulling":
+		t.getState(key).pullStarts[container] = timestamp
+	case "Pulled":
+		state, ok := t.pods[key]
+		if !ok {
+			return
+		}
+		// Images already present on the node are reported as pulled without pulling.
+		start, ok := state.pullStarts[container]
+		if !ok {
+			return
+		}
+		delete(state.pullStarts, container)
+		latency := timestamp.Sub(start)
+		klog.V(4).Infof("%s: image of %s pulled in %v", key, container, latency)
+		nodeImagePullLatency.Observe(latency.Seconds())
+	}
+}
+
+func getPodScheduledTime(pod *v1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionTrue {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func areAllContainersRunning(pod *v1.Pod) bool {
+	if len(pod.Status.ContainerStatuses) < len(pod.Spec.Containers) {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running == nil {
+			return false
+		}
+	}
+	return true
+}
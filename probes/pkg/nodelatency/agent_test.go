@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodelatency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testNodeName = "node-1"
+
+func sampleCount(t *testing.T, histogram prometheus.Histogram) uint64 {
+	var metric dto.Metric
+	if err := histogram.Write(&metric); err != nil {
+		t.Fatalf("reading histogram error: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func newPod(name string, scheduled time.Time, podIP string, running ...bool) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       v1.PodSpec{NodeName: testNodeName},
+		Status:     v1.PodStatus{PodIP: podIP},
+	}
+	if !scheduled.IsZero() {
+		pod.Status.Conditions = []v1.PodCondition{{
+			Type:               v1.PodScheduled,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(scheduled),
+		}}
+	}
+	for i, isRunning := range running {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: string('a' + rune(i))})
+		status := v1.ContainerStatus{Name: string('a' + rune(i))}
+		if isRunning {
+			status.State.Running = &v1.ContainerStateRunning{}
+		} else {
+			status.State.Waiting = &v1.ContainerStateWaiting{}
+		}
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, status)
+	}
+	return pod
+}
+
+func TestObservePod(t *testing.T) {
+	startTime := time.Now().Add(-time.Minute)
+	hostNetworkPod := newPod("host-network", startTime.Add(time.Second), "10.0.0.1", true)
+	hostNetworkPod.Spec.HostNetwork = true
+
+	testCases := []struct {
+		name                string
+		pod                 interface{}
+		wantIPsObserved     uint64
+		wantContainerStarts uint64
+	}{
+		{
+			name: "not a pod",
+			pod:  &v1.Node{},
+		},
+		{
+			name: "unscheduled pod",
+			pod:  newPod("unscheduled", time.Time{}, ""),
+		},
+		{
+			name: "pod scheduled before start",
+			pod:  newPod("old", startTime.Add(-time.Second), "10.0.0.1", true),
+		},
+		{
+			name: "host network pod",
+			pod:  hostNetworkPod,
+		},
+		{
+			name: "pod without IP",
+			pod:  newPod("pending", startTime.Add(time.Second), "", false),
+		},
+		{
+			name:            "pod with IP",
+			pod:             newPod("starting", startTime.Add(time.Second), "10.0.0.1", true, false),
+			wantIPsObserved: 1,
+		},
+		{
+			name:                "running pod",
+			pod:                 newPod("running", startTime.Add(time.Second), "10.0.0.1", true, true),
+			wantIPsObserved:     1,
+			wantContainerStarts: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newTracker(testNodeName, startTime)
+			ipsObserved, containerStarts := sampleCount(t, nodePodIPObservedLatency), sampleCount(t, nodeContainerStartLatency)
+
+			tracker.observePod(tc.pod)
+			// Subsequent updates of the pod are not reported again.
+			tracker.observePod(tc.pod)
+
+			if got := sampleCount(t, nodePodIPObservedLatency) - ipsObserved; got != tc.wantIPsObserved {
+				t.Errorf("IPs observed: got %d, want %d", got, tc.wantIPsObserved)
+			}
+			if got := sampleCount(t, nodeContainerStartLatency) - containerStarts; got != tc.wantContainerStarts {
+				t.Errorf("container starts: got %d, want %d", got, tc.wantContainerStarts)
+			}
+		})
+	}
+}
+
+func newEvent(pod, container, reason, host string, timestamp time.Time) *v1.Event {
+	return &v1.Event{
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: pod, FieldPath: container},
+		Reason:         reason,
+		Source:         v1.EventSource{Component: "kubelet", Host: host},
+		LastTimestamp:  metav1.NewTime(timestamp),
+	}
+}
+
+func TestObserveEvent(t *testing.T) {
+	startTime := time.Now().Add(-time.Minute)
+	testCases := []struct {
+		name      string
+		events    []interface{}
+		wantPulls uint64
+	}{
+		{
+			name:   "not an event",
+			events: []interface{}{&v1.Pod{}},
+		},
+		{
+			name: "image pull",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulling", testNodeName, startTime.Add(time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(3*time.Second)),
+			},
+			wantPulls: 1,
+		},
+		{
+			name: "image pulls of all containers",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulling", testNodeName, startTime.Add(time.Second)),
+				newEvent("pod", "spec.containers{b}", "Pulling", testNodeName, startTime.Add(time.Second)),
+				newEvent("pod", "spec.containers{b}", "Pulled", testNodeName, startTime.Add(2*time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(3*time.Second)),
+			},
+			wantPulls: 2,
+		},
+		{
+			name: "image already present",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(time.Second)),
+			},
+		},
+		{
+			name: "events of other node",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulling", "node-2", startTime.Add(time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", "node-2", startTime.Add(3*time.Second)),
+			},
+		},
+		{
+			name: "pull started before start",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulling", testNodeName, startTime.Add(-time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(time.Second)),
+			},
+		},
+		{
+			name: "pulled event repeated",
+			events: []interface{}{
+				newEvent("pod", "spec.containers{a}", "Pulling", testNodeName, startTime.Add(time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(3*time.Second)),
+				newEvent("pod", "spec.containers{a}", "Pulled", testNodeName, startTime.Add(3*time.Second)),
+			},
+			wantPulls: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newTracker(testNodeName, startTime)
+			pulls := sampleCount(t, nodeImagePullLatency)
+			for _, event := range tc.events {
+				tracker.observeEvent(event)
+			}
+			if got := sampleCount(t, nodeImagePullLatency) - pulls; got != tc.wantPulls {
+				t.Errorf("image pulls: got %d, want %d", got, tc.wantPulls)
+			}
+		})
+	}
+}
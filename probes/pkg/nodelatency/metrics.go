@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodelatency
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/perf-tests/probes/pkg/common"
+)
+
+var (
+	// nodePodIPObservedLatency measures time between scheduling a pod and observing its IP through the apiserver.
+	// It's an upper bound of the sandbox creation (including network set-up), which isn't measured on the node,
+	// as it includes kubelet's pod status update.
+	nodePodIPObservedLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "node_pod_ip_observed_latency_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14), // from 50ms up to ~7min
+		Help:      "Histogram of the time (in seconds) between scheduling a pod and observing its IP through the apiserver.",
+	})
+	// nodeImagePullLatency measures time between Pulling and Pulled events reported by kubelet.
+	nodeImagePullLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "node_image_pull_latency_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12), // from 500ms up to ~17min
+		Help:      "Histogram of the time (in seconds) it took to pull a container image.",
+	})
+	// nodeContainerStartLatency measures time between observing an IP of the pod and observing
+	// all containers of the pod running through the apiserver, i.e. it includes image pulls.
+	nodeContainerStartLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "node_container_start_latency_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14), // from 50ms up to ~7min
+		Help:      "Histogram of the time (in seconds) between observing an IP of a pod and all of its containers running.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(nodePodIPObservedLatency, nodeImagePullLatency, nodeContainerStartLatency)
+}
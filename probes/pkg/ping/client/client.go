@@ -25,14 +25,20 @@ import (
 )
 
 var (
-	pingServerAddress = flag.String("ping-server-address", "", "The address of the ping server")
-	pingSleepDuration = flag.Duration("ping-sleep-duration", 1*time.Second, "Duration of the sleep between pings")
+	pingServerAddress    = flag.String("ping-server-address", "", "The address of the ping server")
+	pingSleepDuration    = flag.Duration("ping-sleep-duration", 1*time.Second, "Duration of the sleep between pings")
+	pingProtocols        = flag.String("ping-protocols", "", "Comma-separated list of protocols (http, tcp, udp) of the protocol matrix. If empty, only the default HTTP ping is run.")
+	pingPayloadSizes     = flag.String("ping-payload-sizes", "64", "Comma-separated list of payload sizes in bytes of HTTP responses and UDP datagrams of the protocol matrix")
+	pingServerPodAddress = flag.String("ping-server-pod-address", "", "The address resolving to pod IPs of ping servers (e.g. of a headless service). If set, the protocol matrix is run against pod IPs as well as against the ping server address.")
 )
 
 // Config configures the "ping-client" probe.
 type Config struct {
-	pingServerAddress string
-	pingSleepDuration time.Duration
+	pingServerAddress    string
+	pingSleepDuration    time.Duration
+	protocols            []string
+	payloadSizes         []int
+	pingServerPodAddress string
 }
 
 // NewDefaultPingClientConfig creates a default "ping-client" config.
@@ -40,14 +46,29 @@ func NewDefaultPingClientConfig() *Config {
 	if *pingServerAddress == "" {
 		klog.Fatal("--ping-server-address not set!")
 	}
+	protocols, err := parseProtocols(*pingProtocols)
+	if err != nil {
+		klog.Fatalf("Incorrect --ping-protocols: %v", err)
+	}
+	payloadSizes, err := parsePayloadSizes(*pingPayloadSizes)
+	if err != nil {
+		klog.Fatalf("Incorrect --ping-payload-sizes: %v", err)
+	}
 	return &Config{
-		pingServerAddress: *pingServerAddress,
-		pingSleepDuration: *pingSleepDuration,
+		pingServerAddress:    *pingServerAddress,
+		pingSleepDuration:    *pingSleepDuration,
+		protocols:            protocols,
+		payloadSizes:         payloadSizes,
+		pingServerPodAddress: *pingServerPodAddress,
 	}
 }
 
 // Run runs the ping client probe that periodically pings the ping server and exports latency metric.
 func Run(config *Config) {
+	for _, probe := range getMatrixProbes(config) {
+		probe := probe
+		go probe.run(config.pingSleepDuration)
+	}
 	for {
 		time.Sleep(config.pingSleepDuration)
 		klog.V(4).Infof("ping -> %s...\n", config.pingServerAddress)
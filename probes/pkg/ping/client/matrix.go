@@ -0,0 +1,210 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pingclient
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog"
+)
+
+// Protocols of the protocol matrix.
+const (
+	protocolHTTP = "http"
+	protocolTCP  = "tcp"
+	protocolUDP  = "udp"
+)
+
+// Targets of the protocol matrix: ping-servers are reached either through the IP of the service
+// (i.e. through kube-proxy) or directly through their pod IPs.
+const (
+	targetService = "service"
+	targetPod     = "pod"
+)
+
+const pingTimeout = 10 * time.Second
+
+// pingFuncs send a single ping of the given payload size to the address (ip:port) using the protocol.
+var pingFuncs = map[string]func(address string, payloadSize int) error{
+	protocolHTTP: pingHTTP,
+	protocolTCP:  pingTCP,
+	protocolUDP:  pingUDP,
+}
+
+// httpClient doesn't reuse connections, so that every request is load balanced by the service.
+var httpClient = &http.Client{
+	Transport: &http.Transport{DisableKeepAlives: true},
+	Timeout:   pingTimeout,
+}
+
+// matrixProbe pings ping-servers with a single protocol, target and payload size.
+type matrixProbe struct {
+	protocol    string
+	target      string
+	address     string
+	payloadSize int
+}
+
+// parseProtocols parses comma-separated list of protocols.
+func parseProtocols(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var protocols []string
+	for _, protocol := range strings.Split(value, ",") {
+		if _, ok := pingFuncs[protocol]; !ok {
+			return nil, fmt.Errorf("unknown protocol %q", protocol)
+		}
+		protocols = append(protocols, protocol)
+	}
+	return protocols, nil
+}
+
+// parsePayloadSizes parses comma-separated list of payload sizes in bytes.
+func parsePayloadSizes(value string) ([]int, error) {
+	var sizes []int
+	for _, s := range strings.Split(value, ",") {
+		size, err := strconv.Atoi(s)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("incorrect payload size %q", s)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// getMatrixProbes returns probes of all combinations of protocols, targets and payload sizes.
+// Payload size doesn't apply to TCP connect, it's probed once per target.
+func getMatrixProbes(config *Config) []matrixProbe {
+	targets := map[string]string{targetService: config.pingServerAddress}
+	if config.pingServerPodAddress != "" {
+		targets[targetPod] = config.pingServerPodAddress
+	}
+	var probes []matrixProbe
+	for target, address := range targets {
+		for _, protocol := range config.protocols {
+			if protocol == protocolTCP {
+				probes = append(probes, matrixProbe{protocol: protocol, target: target, address: address})
+				continue
+			}
+			for _, size := range config.payloadSizes {
+				probes = append(probes, matrixProbe{protocol: protocol, target: target, address: address, payloadSize: size})
+			}
+		}
+	}
+	return probes
+}
+
+func (m *matrixProbe) run(sleepDuration time.Duration) {
+	labels := prometheus.Labels{"protocol": m.protocol, "target": m.target, "payload_size": strconv.Itoa(m.payloadSize)}
+	latency := inClusterNetworkLatencyByProtocol.With(labels)
+	errors := inClusterNetworkLatencyByProtocolError.With(labels)
+	for {
+		time.Sleep(sleepDuration)
+		// Names are resolved before measuring, so that DNS latency isn't included.
+		address, err := resolve(m.address)
+		if err != nil {
+			klog.Warningf("Resolving %s failed: %v", m.address, err)
+			errors.Inc()
+			continue
+		}
+		klog.V(4).Infof("%s ping (%d bytes) -> %s...\n", m.protocol, m.payloadSize, address)
+		startTime := time.Now()
+		if err := pingFuncs[m.protocol](address, m.payloadSize); err != nil {
+			klog.Warningf("Got %s error: %v", m.protocol, err)
+			errors.Inc()
+			continue
+		}
+		latency.Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// resolve returns the address with the host replaced by one of its IPs, chosen randomly.
+func resolve(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("no addresses of %s", host)
+	}
+	return net.JoinHostPort(ips[rand.Intn(len(ips))], port), nil
+}
+
+func pingHTTP(address string, payloadSize int) error {
+	resp, err := httpClient.Get(fmt.Sprintf("http://%s/?size=%d", address, payloadSize))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if len(body) != payloadSize {
+		return fmt.Errorf("got %d bytes, expected %d", len(body), payloadSize)
+	}
+	return nil
+}
+
+func pingTCP(address string, _ int) error {
+	conn, err := net.DialTimeout("tcp", address, pingTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func pingUDP(address string, payloadSize int) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(pingTimeout)); err != nil {
+		return err
+	}
+	payload := bytes.Repeat([]byte("p"), payloadSize)
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+	buf := make([]byte, payloadSize+1)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	if n != payloadSize {
+		return fmt.Errorf("got %d bytes, expected %d", n, payloadSize)
+	}
+	return nil
+}
@@ -47,8 +47,28 @@ var (
 		Name:      "in_cluster_network_latency_error",
 		Help:      "Counter of pings by ping-client  that failed",
 	})
+	// inClusterNetworkLatencyByProtocol is the latency of pings of the protocol matrix.
+	inClusterNetworkLatencyByProtocol = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "in_cluster_network_latency_by_protocol_seconds",
+		Buckets: merge(
+			prometheus.ExponentialBuckets(0.0001, 2, 8), // 0.1ms, 0.2ms, 0.4ms... 12.8ms
+			prometheus.LinearBuckets(0.025, 0.025, 3),   // 25ms, 50ms, 75ms
+			prometheus.LinearBuckets(0.1, 0.05, 18),     // 100ms, 150ms, 200ms... 950ms
+			prometheus.LinearBuckets(1, 1, 5),           // 1s, 2s, 3s, 4s, 5s
+			prometheus.LinearBuckets(10, 5, 5),          // 10s, 15s, 20s, 25s, 30s
+		),
+		Help: "Histogram of the time (in seconds) it took to ping a ping-server instance, by protocol, target (service or pod IP) and payload size.",
+	}, []string{"protocol", "target", "payload_size"})
+	// inClusterNetworkLatencyByProtocolError counts failed pings of the protocol matrix.
+	inClusterNetworkLatencyByProtocolError = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: common.ProbeNamespace,
+		Name:      "in_cluster_network_latency_by_protocol_error",
+		Help:      "Counter of pings of the protocol matrix that failed, by protocol, target (service or pod IP) and payload size.",
+	}, []string{"protocol", "target", "payload_size"})
 )
 
 func init() {
-	prometheus.MustRegister(inClusterNetworkLatency, inClusterNetworkLatencyPingCount, inClusterNetworkLatencyError)
+	prometheus.MustRegister(inClusterNetworkLatency, inClusterNetworkLatencyPingCount, inClusterNetworkLatencyError,
+		inClusterNetworkLatencyByProtocol, inClusterNetworkLatencyByProtocolError)
 }
@@ -17,19 +17,30 @@ limitations under the License.
 package pingserver
 
 import (
+	"bytes"
 	"flag"
+	"net"
 	"net/http"
+	"strconv"
 
 	"k8s.io/klog"
 )
 
+const (
+	// maxPayloadSize limits the size of responses requested by ping-clients.
+	maxPayloadSize = 1 << 20
+	udpBufferSize  = 64 * 1024
+)
+
 var (
 	pingServerBindAddress = flag.String("ping-server-bind-address", "", "The address to bind for the ping server")
+	pingServerUDP         = flag.Bool("ping-server-udp", false, "Whether to echo UDP datagrams received on the port of the ping server")
 )
 
 // PingServerConfig configures the "ping-server" probe.
 type PingServerConfig struct {
 	pingServerBindAddress string
+	pingServerUDP         bool
 }
 
 // NewDefaultPingServerConfig creates a default "ping-server" config.
@@ -39,18 +50,52 @@ func NewDefaultPingServerConfig() *PingServerConfig {
 	}
 	return &PingServerConfig{
 		pingServerBindAddress: *pingServerBindAddress,
+		pingServerUDP:         *pingServerUDP,
 	}
 }
 
 // Run runs the ping server.
 func Run(config *PingServerConfig) {
 	klog.Infof("Listening on %s \n", config.pingServerBindAddress)
+	if config.pingServerUDP {
+		conn, err := net.ListenPacket("udp", config.pingServerBindAddress)
+		if err != nil {
+			klog.Fatalf("Listening on UDP %s failed: %v", config.pingServerBindAddress, err)
+		}
+		go echoUDP(conn)
+	}
 	http.HandleFunc("/", pong)
 	klog.Fatal(http.ListenAndServe(config.pingServerBindAddress, nil))
 }
 
 func pong(w http.ResponseWriter, r *http.Request) {
 	klog.V(4).Infof("pong -> %s\n", r.RemoteAddr)
+	// Payload of the requested size (size query parameter) is sent instead of "pong", if requested.
+	size, err := strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || size <= 0 {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("pong"))
+		return
+	}
+	if size > maxPayloadSize {
+		size = maxPayloadSize
+	}
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("pong"))
+	w.Write(bytes.Repeat([]byte("p"), size))
+}
+
+// echoUDP sends every received datagram back to its sender.
+func echoUDP(conn net.PacketConn) {
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			klog.Warningf("Reading UDP datagram failed: %v", err)
+			continue
+		}
+		klog.V(4).Infof("udp pong -> %s\n", addr)
+		if _, err := conn.WriteTo(buf[:n], addr); err != nil {
+			klog.Warningf("Sending UDP datagram to %s failed: %v", addr, err)
+		}
+	}
 }